@@ -41,6 +41,10 @@ var DefaultBuiltins = [...]*Builtin{
 	NotEqual,
 	Equal,
 
+	// Membership ("in")
+	Member,
+	MemberWithKey,
+
 	// Arithmetic
 	Plus,
 	Minus,
@@ -114,11 +118,14 @@ var DefaultBuiltins = [...]*Builtin{
 
 	// Encoding
 	JSONMarshal,
+	JSONMarshalCanonical,
 	JSONUnmarshal,
 	Base64Encode,
 	Base64Decode,
 	Base64UrlEncode,
 	Base64UrlDecode,
+	Base64UrlEncodeNoPad,
+	PEMDecode,
 	URLQueryDecode,
 	URLQueryEncode,
 	URLQueryEncodeObject,
@@ -149,9 +156,20 @@ var DefaultBuiltins = [...]*Builtin{
 
 	// Crypto
 	CryptoX509ParseCertificates,
+	CryptoX509ParseCertificateRequest,
+	CryptoMd5,
+	CryptoSha1,
+	CryptoSha256,
+	CryptoHmacMd5,
+	CryptoHmacSha1,
+	CryptoHmacSha256,
+	CryptoHmacSha512,
+	CryptoHmacEqual,
 
 	// Graphs
 	WalkBuiltin,
+	GraphReachable,
+	GraphTransitiveClosure,
 
 	// Sort
 	Sort,
@@ -174,6 +192,7 @@ var DefaultBuiltins = [...]*Builtin{
 
 	// OPA
 	OPARuntime,
+	OPAStoreRevision,
 
 	// Tracing
 	Trace,
@@ -190,6 +209,15 @@ var DefaultBuiltins = [...]*Builtin{
 
 	// Units
 	UnitsParseBytes,
+	UnitsParse,
+
+	// SemVers
+	SemVerIsValid,
+	SemVerCompare,
+
+	// Random
+	RandIntn,
+	UUIDRFC4122,
 }
 
 // BuiltinMap provides a convenient mapping of built-in names to
@@ -202,6 +230,8 @@ var BuiltinMap map[string]*Builtin
 var IgnoreDuringPartialEval = []*Builtin{
 	NowNanos,
 	HTTPSend,
+	RandIntn,
+	UUIDRFC4122,
 }
 
 /**
@@ -296,6 +326,32 @@ var Equal = &Builtin{
 	),
 }
 
+/**
+ * Membership
+ */
+
+// Member represents the "in" membership operator. It checks if the value is
+// an element of an array or set or a value of an object.
+var Member = &Builtin{
+	Name:  "internal.member_2",
+	Infix: "in",
+	Decl: types.NewFunction(
+		types.Args(types.A, types.A),
+		types.B,
+	),
+}
+
+// MemberWithKey represents the "in" membership operator with a key, e.g.,
+// "some k, v in xs". It checks if the value is stored under the key in an
+// array, object, or set.
+var MemberWithKey = &Builtin{
+	Name: "internal.member_3",
+	Decl: types.NewFunction(
+		types.Args(types.A, types.A, types.A),
+		types.B,
+	),
+}
+
 /**
  * Arithmetic
  */
@@ -903,6 +959,79 @@ var UnitsParseBytes = &Builtin{
 	),
 }
 
+// UnitsParse converts quantities with SI (e.g., 250m, 2G) or binary (e.g.,
+// 512Mi) suffixes as used by Kubernetes into numbers.
+var UnitsParse = &Builtin{
+	Name: "units.parse",
+	Decl: types.NewFunction(
+		types.Args(
+			types.S,
+		),
+		types.N,
+	),
+}
+
+/**
+ * SemVers
+ */
+
+// SemVerIsValid returns true if the operand is a string containing a valid
+// SemVer and false for all other input.
+var SemVerIsValid = &Builtin{
+	Name: "semver.is_valid",
+	Decl: types.NewFunction(
+		types.Args(
+			types.A,
+		),
+		types.B,
+	),
+}
+
+// SemVerCompare compares valid SemVer formatted version strings. Given two
+// version strings, if A < B returns -1, if A > B returns 1. If A == B, returns
+// 0.
+var SemVerCompare = &Builtin{
+	Name: "semver.compare",
+	Decl: types.NewFunction(
+		types.Args(
+			types.S,
+			types.S,
+		),
+		types.N,
+	),
+}
+
+/**
+ * Random
+ */
+
+// RandIntn returns a random number 0 - n. The first operand identifies the
+// number so that the same number is returned if the function is called again
+// with the same operands during the same query.
+var RandIntn = &Builtin{
+	Name: "rand.intn",
+	Decl: types.NewFunction(
+		types.Args(
+			types.S,
+			types.N,
+		),
+		types.N,
+	),
+}
+
+// UUIDRFC4122 returns a version 4 UUID. The operand identifies the UUID so
+// that the same UUID is returned if the function is called again with the
+// same operand during the same query.
+var UUIDRFC4122 = &Builtin{
+	Name: "uuid.rfc4122",
+	Decl: types.NewFunction(
+		types.Args(
+			types.S,
+		),
+		types.S,
+	),
+}
+
 /**
  * JSON
  */
@@ -916,6 +1045,16 @@ var JSONMarshal = &Builtin{
 	),
 }
 
+// JSONMarshalCanonical serializes the input term in the canonical JSON format
+// (RFC 8785) so that equal documents produce identical strings.
+var JSONMarshalCanonical = &Builtin{
+	Name: "json.marshal_canonical",
+	Decl: types.NewFunction(
+		types.Args(types.A),
+		types.S,
+	),
+}
+
 // JSONUnmarshal deserializes the input string.
 var JSONUnmarshal = &Builtin{
 	Name: "json.unmarshal",
@@ -996,6 +1135,32 @@ var Base64UrlDecode = &Builtin{
 	),
 }
 
+// Base64UrlEncodeNoPad serializes the input string into base64url encoding
+// without padding.
+var Base64UrlEncodeNoPad = &Builtin{
+	Name: "base64url.encode_no_pad",
+	Decl: types.NewFunction(
+		types.Args(types.S),
+		types.S,
+	),
+}
+
+// PEMDecode returns the PEM blocks contained in the input string.
+var PEMDecode = &Builtin{
+	Name: "pem.decode",
+	Decl: types.NewFunction(
+		types.Args(types.S),
+		types.NewArray(nil, types.NewObject(
+			[]*types.StaticProperty{
+				types.NewStaticProperty("type", types.S),
+				types.NewStaticProperty("headers", types.NewObject(nil, types.NewDynamicProperty(types.S, types.S))),
+				types.NewStaticProperty("bytes", types.S),
+			},
+			nil,
+		)),
+	),
+}
+
 // URLQueryDecode decodes a URL encoded input string.
 var URLQueryDecode = &Builtin{
 	Name: "urlquery.decode",
@@ -1250,7 +1415,7 @@ var Weekday = &Builtin{
 
 // CryptoX509ParseCertificates returns one or more certificates from the given
 // base64 encoded string containing DER encoded certificates that have been
-// concatenated.
+// concatenated or from a string containing PEM encoded certificates.
 var CryptoX509ParseCertificates = &Builtin{
 	Name: "crypto.x509.parse_certificates",
 	Decl: types.NewFunction(
@@ -1259,6 +1424,89 @@ var CryptoX509ParseCertificates = &Builtin{
 	),
 }
 
+// CryptoX509ParseCertificateRequest returns a PKCS #10 certificate signing
+// request from a base64 encoded DER or PEM string.
+var CryptoX509ParseCertificateRequest = &Builtin{
+	Name: "crypto.x509.parse_certificate_request",
+	Decl: types.NewFunction(
+		types.Args(types.S),
+		types.NewObject(nil, types.NewDynamicProperty(types.S, types.A)),
+	),
+}
+
+// CryptoMd5 returns a string representing the input string hashed with the md5 function
+var CryptoMd5 = &Builtin{
+	Name: "crypto.md5",
+	Decl: types.NewFunction(
+		types.Args(types.S),
+		types.S,
+	),
+}
+
+// CryptoSha1 returns a string representing the input string hashed with the sha1 function
+var CryptoSha1 = &Builtin{
+	Name: "crypto.sha1",
+	Decl: types.NewFunction(
+		types.Args(types.S),
+		types.S,
+	),
+}
+
+// CryptoSha256 returns a string representing the input string hashed with the sha256 function
+var CryptoSha256 = &Builtin{
+	Name: "crypto.sha256",
+	Decl: types.NewFunction(
+		types.Args(types.S),
+		types.S,
+	),
+}
+
+// CryptoHmacMd5 returns a string representing the MD5 HMAC of the input message using the input key
+var CryptoHmacMd5 = &Builtin{
+	Name: "crypto.hmac.md5",
+	Decl: types.NewFunction(
+		types.Args(types.S, types.S),
+		types.S,
+	),
+}
+
+// CryptoHmacSha1 returns a string representing the SHA1 HMAC of the input message using the input key
+var CryptoHmacSha1 = &Builtin{
+	Name: "crypto.hmac.sha1",
+	Decl: types.NewFunction(
+		types.Args(types.S, types.S),
+		types.S,
+	),
+}
+
+// CryptoHmacSha256 returns a string representing the SHA256 HMAC of the input message using the input key
+var CryptoHmacSha256 = &Builtin{
+	Name: "crypto.hmac.sha256",
+	Decl: types.NewFunction(
+		types.Args(types.S, types.S),
+		types.S,
+	),
+}
+
+// CryptoHmacSha512 returns a string representing the SHA512 HMAC of the input message using the input key
+var CryptoHmacSha512 = &Builtin{
+	Name: "crypto.hmac.sha512",
+	Decl: types.NewFunction(
+		types.Args(types.S, types.S),
+		types.S,
+	),
+}
+
+// CryptoHmacEqual returns true if two MACs are equal. The comparison is
+// performed in constant time.
+var CryptoHmacEqual = &Builtin{
+	Name: "crypto.hmac.equal",
+	Decl: types.NewFunction(
+		types.Args(types.S, types.S),
+		types.B,
+	),
+}
+
 /**
  * Graphs.
  */
@@ -1280,6 +1528,49 @@ var WalkBuiltin = &Builtin{
 	),
 }
 
+// GraphReachable computes the set of nodes reachable from the initial nodes
+// in a directed graph represented as an adjacency object.
+var GraphReachable = &Builtin{
+	Name: "graph.reachable",
+	Decl: types.NewFunction(
+		types.Args(
+			types.NewObject(
+				nil,
+				types.NewDynamicProperty(
+					types.A,
+					types.NewAny(
+						types.NewSet(types.A),
+						types.NewArray(nil, types.A),
+						types.NewNull(),
+					)),
+			),
+			types.NewAny(types.NewSet(types.A), types.NewArray(nil, types.A)),
+		),
+		types.NewSet(types.A),
+	),
+}
+
+// GraphTransitiveClosure maps each node in a directed graph represented as an
+// adjacency object to the set of nodes reachable from it.
+var GraphTransitiveClosure = &Builtin{
+	Name: "graph.transitive_closure",
+	Decl: types.NewFunction(
+		types.Args(
+			types.NewObject(
+				nil,
+				types.NewDynamicProperty(
+					types.A,
+					types.NewAny(
+						types.NewSet(types.A),
+						types.NewArray(nil, types.A),
+						types.NewNull(),
+					)),
+			),
+		),
+		types.NewObject(nil, types.NewDynamicProperty(types.A, types.NewSet(types.A))),
+	),
+}
+
 /**
  * Sorting
  */
@@ -1442,6 +1733,16 @@ var OPARuntime = &Builtin{
 	),
 }
 
+// OPAStoreRevision returns the revision of the store that the query is
+// evaluated against.
+var OPAStoreRevision = &Builtin{
+	Name: "opa.store_revision",
+	Decl: types.NewFunction(
+		nil,
+		types.N,
+	),
+}
+
 /**
  * Trace
  */
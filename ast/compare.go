@@ -21,7 +21,7 @@ import (
 //
 // nil < Null < Boolean < Number < String < Var < Ref < Array < Object < Set <
 // ArrayComprehension < ObjectComprehension < SetComprehension < Expr < SomeDecl
-// < With < Body < Rule < Import < Package < Module.
+// < Every < With < Body < Rule < Import < Package < Module.
 //
 // Arrays and Refs are equal iff both a and b have the same length and all
 // corresponding elements are equal. If one element is not equal, the return
@@ -166,6 +166,9 @@ func Compare(a, b interface{}) int {
 	case *SomeDecl:
 		b := b.(*SomeDecl)
 		return a.Compare(b)
+	case *Every:
+		b := b.(*Every)
+		return a.Compare(b)
 	case *With:
 		b := b.(*With)
 		return a.Compare(b)
@@ -194,6 +197,66 @@ func Compare(a, b interface{}) int {
 	panic(fmt.Sprintf("illegal value: %T", a))
 }
 
+// ValueEqual returns true if a and b are equal. ValueEqual is equivalent to
+// Compare(a, b) == 0, however, composite values are compared without sorting
+// their elements: arrays are compared element-wise and objects and sets of the
+// same length are compared by looking up the keys (or elements) of one value
+// in the other.
+func ValueEqual(a, b Value) bool {
+	switch a := a.(type) {
+	case Null:
+		return a.Equal(b)
+	case Boolean:
+		return a.Equal(b)
+	case Number:
+		return a.Equal(b)
+	case String:
+		return a.Equal(b)
+	case Var:
+		return a.Equal(b)
+	case Array:
+		if b, ok := b.(Array); ok {
+			return termSliceEqual(a, b)
+		}
+		return false
+	case Object:
+		if b, ok := b.(Object); ok {
+			if a.Len() != b.Len() {
+				return false
+			}
+			return objectContains(a, b)
+		}
+		return false
+	case Set:
+		if b, ok := b.(Set); ok {
+			if a.Len() != b.Len() {
+				return false
+			}
+			// Elements rewritten in place (e.g., by Walk) are no longer
+			// found by hash in their own set, so the elements of b are
+			// looked up in a as well.
+			return setContains(a, b) || setContains(b, a)
+		}
+		return false
+	}
+	return Compare(a, b) == 0
+}
+
+// objectContains returns true if all key-value pairs of a are contained in b.
+func objectContains(a, b Object) bool {
+	return !a.Until(func(k, v *Term) bool {
+		other := b.Get(k)
+		return other == nil || !v.Equal(other)
+	})
+}
+
+// setContains returns true if all elements of a are contained in b.
+func setContains(a, b Set) bool {
+	return !a.Until(func(x *Term) bool {
+		return !b.Contains(x)
+	})
+}
+
 type termSlice []*Term
 
 func (s termSlice) Less(i, j int) bool { return Compare(s[i].Value, s[j].Value) < 0 }
@@ -234,6 +297,8 @@ func sortOrder(x interface{}) int {
 		return 100
 	case *SomeDecl:
 		return 101
+	case *Every:
+		return 102
 	case *With:
 		return 110
 	case *Head:
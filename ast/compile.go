@@ -20,7 +20,10 @@ const CompileErrorLimitDefault = 10
 
 var errLimitReached = NewError(CompileErr, nil, "error limit reached")
 
-// Compiler contains the state of a compilation process.
+// Compiler contains the state of a compilation process. Once Compile has
+// returned, the compiler (including its modules, rule tree, and indices) is not
+// modified and may be shared by concurrent queries. Compile itself must not be
+// called concurrently with other operations on the same compiler.
 type Compiler struct {
 
 	// Errors contains errors that occurred during the compilation process.
@@ -97,6 +100,7 @@ type Compiler struct {
 	metrics           metrics.Metrics
 	builtins          map[string]*Builtin
 	unsafeBuiltinsMap map[string]struct{}
+	strict            bool
 }
 
 // CompilerStage defines the interface for stages in the compiler.
@@ -281,6 +285,17 @@ func (c *Compiler) WithPathConflictsCheck(fn func([]string) (bool, error)) *Comp
 	return c
 }
 
+// WithStrict enables strict mode in the compiler. In strict mode, the
+// compiler reports variables that refer to rules or imports when used as
+// reference operands (instead of being iterated over), assignments that shadow
+// imports, and declarations that shadow root documents. Variables can be
+// declared with the "some" keyword to make them local. Assignments that shadow
+// rules are reported regardless of this setting.
+func (c *Compiler) WithStrict(strict bool) *Compiler {
+	c.strict = strict
+	return c
+}
+
 // WithStageAfter registers a stage to run during compilation after
 // the named stage.
 func (c *Compiler) WithStageAfter(after string, stage CompilerStageDefinition) *Compiler {
@@ -659,6 +674,9 @@ func (c *Compiler) checkSelfPath(loc *Location, eq func(a, b util.T) bool, a, b
 func astNodeToString(x interface{}) string {
 	switch x := x.(type) {
 	case *Rule:
+		if len(x.Head.Reference) > 0 {
+			return x.Head.Reference.String()
+		}
 		return string(x.Head.Name)
 	default:
 		panic("not reached")
@@ -699,6 +717,22 @@ func (c *Compiler) checkRuleConflicts() {
 			c.err(NewError(TypeErr, node.Values[0].(*Rule).Loc(), "multiple default rules named %s found", name))
 		}
 
+		// Rules cannot define a document that also contains documents
+		// defined by rules with dotted heads.
+		for _, child := range node.Children {
+			var nested *Rule
+			child.DepthFirst(func(n *TreeNode) bool {
+				if len(n.Values) > 0 {
+					nested = n.Values[0].(*Rule)
+				}
+				return nested != nil
+			})
+			if nested != nil {
+				c.err(NewError(TypeErr, node.Values[0].(*Rule).Loc(), "rule %v conflicts with rule %v defined at %v", name, nested.Head.Reference, nested.Loc()))
+				break
+			}
+		}
+
 		return false
 	})
 
@@ -780,6 +814,7 @@ func (c *Compiler) checkBodySafety(safe VarSet, m *Module, b Body) Body {
 var safetyCheckVarVisitorParams = VarVisitorParams{
 	SkipRefCallHead: true,
 	SkipClosures:    true,
+	SkipWithTarget:  true,
 }
 
 // checkSafetyRuleHeads ensures that variables appearing in the head of a
@@ -919,7 +954,20 @@ func (c *Compiler) resolveAllRefs() {
 
 		globals := getGlobals(mod.Package, ruleExports, mod.Imports)
 
+		for _, err := range checkImports(mod, ruleExports, c.strict) {
+			c.err(err)
+		}
+
 		WalkRules(mod, func(rule *Rule) bool {
+			if c.strict {
+				for _, err := range checkShadowing(globals, rule) {
+					c.err(err)
+				}
+			} else {
+				for _, err := range checkRuleShadowing(globals, ruleExports, rule) {
+					c.err(err)
+				}
+			}
 			err := resolveRefsInRule(globals, rule)
 			if err != nil {
 				c.err(NewError(CompileErr, rule.Location, err.Error()))
@@ -1459,6 +1507,7 @@ type TreeNode struct {
 	Key      Value
 	Values   []util.T
 	Children map[Value]*TreeNode
+	Sorted   []Value // sorted keys of Children
 	Hide     bool
 }
 
@@ -1471,6 +1520,9 @@ func NewRuleTree(mtree *ModuleTreeNode) *TreeNode {
 	// Build rule sets for this package.
 	for _, mod := range mtree.Modules {
 		for _, rule := range mod.Rules {
+			if len(rule.Head.Reference) > 0 {
+				continue
+			}
 			key := String(rule.Head.Name)
 			ruleSets[key] = append(ruleSets[key], rule)
 		}
@@ -1487,17 +1539,50 @@ func NewRuleTree(mtree *ModuleTreeNode) *TreeNode {
 		}
 	}
 
+	// Rules with dotted heads become leaf nodes at the end of the path
+	// described by the head.
+	for _, mod := range mtree.Modules {
+		for _, rule := range mod.Rules {
+			if len(rule.Head.Reference) == 0 {
+				continue
+			}
+			key := String(rule.Head.Name)
+			node, ok := children[key]
+			if !ok {
+				node = &TreeNode{Key: key}
+				children[key] = node
+			}
+			for _, x := range rule.Head.Reference[1:] {
+				if node.Children == nil {
+					node.Children = map[Value]*TreeNode{}
+				}
+				child, ok := node.Children[x.Value]
+				if !ok {
+					child = &TreeNode{Key: x.Value}
+					node.Children[x.Value] = child
+					node.sort()
+				}
+				node = child
+			}
+			node.Values = append(node.Values, rule)
+		}
+	}
+
 	// Each module in subpackage becomes child node.
 	for _, child := range mtree.Children {
 		children[child.Key] = NewRuleTree(child)
 	}
 
-	return &TreeNode{
+	root := &TreeNode{
 		Key:      mtree.Key,
 		Values:   nil,
 		Children: children,
 		Hide:     mtree.Hide,
 	}
+
+	root.sort()
+
+	return root
 }
 
 // Size returns the number of rules in the tree.
@@ -1518,6 +1603,18 @@ func (n *TreeNode) Child(k Value) *TreeNode {
 	return nil
 }
 
+// sort updates the sorted keys of n's children. Subpackage nodes are sorted
+// when they are built.
+func (n *TreeNode) sort() {
+	n.Sorted = make([]Value, 0, len(n.Children))
+	for k := range n.Children {
+		n.Sorted = append(n.Sorted, k)
+	}
+	sort.Slice(n.Sorted, func(i, j int) bool {
+		return n.Sorted[i].Compare(n.Sorted[j]) < 0
+	})
+}
+
 // DepthFirst performs a depth-first traversal of the rule tree rooted at n. If
 // f returns true, traversal will not continue to the children of n.
 func (n *TreeNode) DepthFirst(f func(node *TreeNode) bool) {
@@ -1700,8 +1797,9 @@ type unsafePair struct {
 }
 
 type unsafeVarLoc struct {
-	Var Var
-	Loc *Location
+	Var  Var
+	Loc  *Location
+	Expr *Expr
 }
 
 type unsafeVars map[*Expr]VarSet
@@ -1729,21 +1827,22 @@ func (vs unsafeVars) Update(o unsafeVars) {
 
 func (vs unsafeVars) Vars() (result []unsafeVarLoc) {
 
-	locs := map[Var]*Location{}
+	exprs := map[Var]*Expr{}
 
 	// If var appears in multiple sets then pick first by location.
 	for expr, vars := range vs {
 		for v := range vars {
-			if locs[v].Compare(expr.Location) > 0 {
-				locs[v] = expr.Location
+			if first, ok := exprs[v]; !ok || first.Location.Compare(expr.Location) > 0 {
+				exprs[v] = expr
 			}
 		}
 	}
 
-	for v, loc := range locs {
+	for v, expr := range exprs {
 		result = append(result, unsafeVarLoc{
-			Var: v,
-			Loc: loc,
+			Var:  v,
+			Loc:  expr.Location,
+			Expr: expr,
 		})
 	}
 
@@ -1971,7 +2070,7 @@ func outputVarsForExpr(expr *Expr, builtins map[string]*Builtin, arity func(Ref)
 	// With modifier inputs must be safe.
 	for _, with := range expr.With {
 		unsafe := false
-		WalkVars(with, func(v Var) bool {
+		WalkVars(with.Value, func(v Var) bool {
 			if !safe.Contains(v) {
 				unsafe = true
 				return true
@@ -2118,9 +2217,8 @@ func newEqualityFactory(gen *localVarGenerator) *equalityFactory {
 
 func (f *equalityFactory) Generate(other *Term) *Expr {
 	term := NewTerm(f.gen.Generate()).SetLocation(other.Location)
-	expr := Equality.Expr(term, other)
+	expr := Equality.Expr(term, other).SetLocation(other.Location)
 	expr.Generated = true
-	expr.Location = other.Location
 	return expr
 }
 
@@ -2167,8 +2265,11 @@ func getGlobals(pkg *Package, rules []Var, imports []*Import) map[Var]Ref {
 		globals[v] = global
 	}
 
-	// Populate globals with imports.
+	// Populate globals with imports. Future keyword imports only affect parsing.
 	for _, i := range imports {
+		if i.FutureKeywords() != nil {
+			continue
+		}
 		if len(i.Alias) > 0 {
 			path := i.Path.Value.(Ref)
 			globals[i.Alias] = path
@@ -2296,6 +2397,25 @@ func resolveRefsInBody(globals map[Var]Ref, ignore *declaredVarStack, body Body)
 func resolveRefsInExpr(globals map[Var]Ref, ignore *declaredVarStack, expr *Expr) *Expr {
 	cpy := *expr
 	switch ts := expr.Terms.(type) {
+	case *SomeDecl:
+		// Only the collection in "some ... in ..." statements may refer to
+		// other documents.
+		if call, ok := ts.MembershipCall(); ok {
+			call = call.Copy()
+			call[len(call)-1] = resolveRefsInTerm(globals, ignore, call[len(call)-1])
+			decl := ts.Copy()
+			decl.Symbols = []*Term{NewTerm(call).SetLocation(ts.Symbols[0].Location)}
+			cpy.Terms = decl
+		}
+	case *Every:
+		every := ts.Copy()
+		every.Domain = resolveRefsInTerm(globals, ignore, every.Domain)
+		vars := every.KeyValueVars()
+		vars.Update(declaredVars(every.Body))
+		ignore.Push(vars)
+		every.Body = resolveRefsInBody(globals, ignore, every.Body)
+		ignore.Pop()
+		cpy.Terms = every
 	case *Term:
 		cpy.Terms = resolveRefsInTerm(globals, ignore, ts)
 	case []*Term:
@@ -2392,6 +2512,195 @@ func resolveRefsInTermSlice(globals map[Var]Ref, ignore *declaredVarStack, terms
 	return cpy
 }
 
+// checkImports returns errors for imports in the module that conflict with
+// other imports. Import aliases must also not conflict with rules defined in
+// the module's package or with vars declared in the module's rules.
+// Assignments that shadow imports are reported by checkShadowing in strict
+// mode.
+func checkImports(mod *Module, rules []Var, strict bool) Errors {
+
+	var errs Errors
+
+	exports := NewVarSet(rules...)
+	imports := map[Var]*Import{}
+	aliases := map[Var]*Import{}
+
+	for _, imp := range mod.Imports {
+		if imp.FutureKeywords() != nil {
+			continue
+		}
+		name := imp.Name()
+		if prev, ok := imports[name]; ok {
+			if !prev.Path.Equal(imp.Path) {
+				errs = append(errs, NewError(CompileErr, prev.Location, "import named %v redeclared at %v", name, imp.Location))
+			}
+			continue
+		}
+		imports[name] = imp
+		if len(imp.Alias) == 0 {
+			continue
+		}
+		aliases[name] = imp
+		if exports.Contains(name) {
+			rule := append(mod.Package.Path.Copy(), StringTerm(string(name)))
+			errs = append(errs, NewError(CompileErr, imp.Location, "import alias %v conflicts with rule %v", name, rule))
+		}
+	}
+
+	if len(aliases) == 0 {
+		return errs
+	}
+
+	WalkExprs(mod, func(expr *Expr) bool {
+
+		var declared []*Term
+
+		switch terms := expr.Terms.(type) {
+		case *SomeDecl:
+			if call, ok := terms.MembershipCall(); ok {
+				declared = call[1 : len(call)-1]
+			} else {
+				declared = terms.Symbols
+			}
+		default:
+			if expr.IsAssignment() && !strict {
+				declared = []*Term{expr.Operand(0)}
+			}
+		}
+
+		for _, t := range declared {
+			WalkVars(t, func(v Var) bool {
+				if imp, ok := aliases[v]; ok {
+					errs = append(errs, NewError(CompileErr, t.Location, "declared var %v shadows import alias %v for %v", v, v, imp.Path))
+				}
+				return false
+			})
+		}
+
+		return false
+	})
+
+	return errs
+}
+
+// checkShadowing returns errors for vars in the rule that would accidentally
+// shadow other documents. Vars used as reference operands must not refer to
+// rules or imports unless they are declared locally, assigned vars must not
+// shadow rules or imports, and declared vars must not shadow root documents.
+func checkShadowing(globals map[Var]Ref, rule *Rule) Errors {
+	ignore := &declaredVarStack{rule.Head.Args.Vars(), declaredVars(rule.Body)}
+	return checkShadowingInBody(globals, ignore, rule.Body)
+}
+
+// checkRuleShadowing returns errors for vars assigned with := in the rule that
+// shadow rules in the same package. Unlike the checks performed by
+// checkShadowing, this check is performed in all modes.
+func checkRuleShadowing(globals map[Var]Ref, rules []Var, rule *Rule) Errors {
+
+	var errs Errors
+
+	WalkExprs(rule, func(expr *Expr) bool {
+		t := expr.Operand(0)
+		if !expr.IsAssignment() || t == nil {
+			return false
+		}
+		WalkVars(t, func(v Var) bool {
+			for _, r := range rules {
+				if r.Equal(v) {
+					errs = append(errs, NewError(CompileErr, t.Location, "assigned var %v shadows %v (use a different variable name)", v, globals[v]))
+					break
+				}
+			}
+			return false
+		})
+		return false
+	})
+
+	return errs
+}
+
+func checkShadowingInBody(globals map[Var]Ref, ignore *declaredVarStack, body Body) Errors {
+
+	var errs Errors
+
+	for _, expr := range body {
+
+		var declared []*Term
+
+		switch terms := expr.Terms.(type) {
+		case *SomeDecl:
+			if call, ok := terms.MembershipCall(); ok {
+				declared = call[1 : len(call)-1]
+			} else {
+				declared = terms.Symbols
+			}
+		default:
+			if expr.IsAssignment() {
+				declared = []*Term{expr.Operand(0)}
+			}
+		}
+
+		for _, t := range declared {
+			WalkVars(t, func(v Var) bool {
+				if RootDocumentNames.Contains(VarTerm(string(v))) {
+					errs = append(errs, NewError(CompileErr, t.Location, "declared var %v shadows root document %v", v, v))
+				} else if g, ok := globals[v]; ok && expr.IsAssignment() {
+					errs = append(errs, NewError(CompileErr, t.Location, "assigned var %v shadows %v (use a different variable name)", v, g))
+				}
+				return false
+			})
+		}
+
+		var vis *GenericVisitor
+		vis = NewGenericVisitor(func(x interface{}) bool {
+			switch x := x.(type) {
+			case *Every:
+				Walk(vis, x.Domain)
+				vars := x.KeyValueVars()
+				vars.Update(declaredVars(x.Body))
+				ignore.Push(vars)
+				errs = append(errs, checkShadowingInBody(globals, ignore, x.Body)...)
+				ignore.Pop()
+				return true
+			case Ref:
+				for _, t := range x[1:] {
+					v, ok := t.Value.(Var)
+					if !ok {
+						continue
+					}
+					if g, ok := globals[v]; ok && !ignore.Contains(v) {
+						errs = append(errs, NewError(CompileErr, t.Location, "var %v refers to %v (declare with some %v to iterate)", v, g, v))
+					}
+				}
+			case *ArrayComprehension:
+				ignore.Push(declaredVars(x.Body))
+				Walk(vis, x.Term)
+				errs = append(errs, checkShadowingInBody(globals, ignore, x.Body)...)
+				ignore.Pop()
+				return true
+			case *SetComprehension:
+				ignore.Push(declaredVars(x.Body))
+				Walk(vis, x.Term)
+				errs = append(errs, checkShadowingInBody(globals, ignore, x.Body)...)
+				ignore.Pop()
+				return true
+			case *ObjectComprehension:
+				ignore.Push(declaredVars(x.Body))
+				Walk(vis, x.Key)
+				Walk(vis, x.Value)
+				errs = append(errs, checkShadowingInBody(globals, ignore, x.Body)...)
+				ignore.Pop()
+				return true
+			}
+			return false
+		})
+
+		Walk(vis, expr)
+	}
+
+	return errs
+}
+
 type declaredVarStack []VarSet
 
 func (s declaredVarStack) Contains(v Var) bool {
@@ -2427,11 +2736,20 @@ func declaredVars(x interface{}) VarSet {
 					return false
 				})
 			} else if decl, ok := x.Terms.(*SomeDecl); ok {
-				for i := range decl.Symbols {
-					vars.Add(decl.Symbols[i].Value.(Var))
+				if call, ok := decl.MembershipCall(); ok {
+					for _, t := range call[1 : len(call)-1] {
+						WalkVars(t, func(v Var) bool {
+							vars.Add(v)
+							return false
+						})
+					}
+				} else {
+					for i := range decl.Symbols {
+						vars.Add(decl.Symbols[i].Value.(Var))
+					}
 				}
 			}
-		case *ArrayComprehension, *SetComprehension, *ObjectComprehension:
+		case *ArrayComprehension, *SetComprehension, *ObjectComprehension, *Every:
 			return true
 		}
 		return false
@@ -2500,12 +2818,12 @@ func rewriteComprehensionTerms(f *equalityFactory, node interface{}) (interface{
 // result.
 func rewriteEquals(x interface{}) {
 	doubleEq := Equal.Ref()
-	unifyOp := Equality.Ref()
 	WalkExprs(x, func(x *Expr) bool {
 		if x.IsCall() {
 			operator := x.Operator()
 			if operator.Equal(doubleEq) && len(x.Operands()) == 2 {
-				x.SetOperator(NewTerm(unifyOp))
+				loc := x.Terms.([]*Term)[0].Location
+				x.SetOperator(RefTerm(VarTerm(Equality.Name).SetLocation(loc)).SetLocation(loc))
 			}
 		}
 		return false
@@ -2887,7 +3205,17 @@ func rewriteDeclaredVarsInBody(g *localVarGenerator, stack *localDeclaredVars, u
 		if body[i].IsAssignment() {
 			expr, errs = rewriteDeclaredAssignment(g, stack, body[i], errs)
 		} else if decl, ok := body[i].Terms.(*SomeDecl); ok {
-			errs = rewriteSomeDeclStatement(g, stack, decl, errs)
+			var exprs Body
+			exprs, errs = rewriteSomeDeclStatement(g, stack, decl, errs)
+			for j := range exprs {
+				cpy.Append(exprs[j])
+			}
+		} else if every, ok := body[i].Terms.(*Every); ok {
+			var exprs Body
+			exprs, errs = rewriteEvery(g, stack, every, errs)
+			for j := range exprs {
+				cpy.Append(exprs[j])
+			}
 		} else {
 			expr, errs = rewriteDeclaredVarsInExpr(g, stack, body[i], errs)
 		}
@@ -2942,14 +3270,150 @@ func checkUnusedDeclaredVars(loc *Location, stack *localDeclaredVars, used VarSe
 	return errs
 }
 
-func rewriteSomeDeclStatement(g *localVarGenerator, stack *localDeclaredVars, decl *SomeDecl, errs Errors) Errors {
+func rewriteSomeDeclStatement(g *localVarGenerator, stack *localDeclaredVars, decl *SomeDecl, errs Errors) (Body, Errors) {
+	if call, ok := decl.MembershipCall(); ok {
+		return rewriteSomeDeclIn(g, stack, decl, call, errs)
+	}
 	for i := range decl.Symbols {
 		v := decl.Symbols[i].Value.(Var)
 		if _, err := rewriteDeclaredVar(g, stack, v, declaredVar); err != nil {
 			errs = append(errs, NewError(CompileErr, decl.Loc(), err.Error()))
 		}
 	}
-	return errs
+	return nil, errs
+}
+
+// rewriteSomeDeclIn declares the vars in the key and value of a "some ... in
+// ..." statement and rewrites the statement into a ref that iterates over the
+// collection. For example, "some k, v in xs" becomes "xs[k] = v" and
+// "some x in [1, 2]" becomes "__local0__ = [1, 2]; __local0__[__local1__] = x".
+func rewriteSomeDeclIn(g *localVarGenerator, stack *localDeclaredVars, decl *SomeDecl, call Call, errs Errors) (Body, Errors) {
+
+	var result Body
+	loc := decl.Loc()
+	operands := call[1:]
+
+	// The collection is rewritten before the vars are declared so that it
+	// cannot refer to them.
+	coll := operands[len(operands)-1].Copy()
+	errs = rewriteDeclaredVarsInTermRecursive(g, stack, coll, errs)
+
+	switch coll.Value.(type) {
+	case Var, Ref:
+	default:
+		tmp := NewTerm(g.Generate()).SetLocation(coll.Location)
+		result.Append(Equality.Expr(tmp, coll).SetLocation(loc))
+		coll = tmp
+	}
+
+	var key, value *Term
+
+	if len(operands) == 3 {
+		key, value = operands[0].Copy(), operands[1].Copy()
+	} else {
+		key, value = NewTerm(g.Generate()).SetLocation(loc), operands[0].Copy()
+	}
+
+	var vis func(t *Term) bool
+
+	vis = func(t *Term) bool {
+		switch v := t.Value.(type) {
+		case Var:
+			if v.IsWildcard() || v.IsGenerated() {
+				return true
+			}
+			if gv, err := rewriteDeclaredVar(g, stack, v, declaredVar); err != nil {
+				errs = append(errs, NewError(CompileErr, t.Location, err.Error()))
+			} else {
+				t.Value = gv
+			}
+			return true
+		case Array:
+			return false
+		case Object:
+			v.Foreach(func(_, v *Term) {
+				WalkTerms(v, vis)
+			})
+			return true
+		case Null, Boolean, Number, String:
+			return true
+		}
+		errs = append(errs, NewError(CompileErr, t.Location, "cannot declare %v", TypeName(t.Value)))
+		return true
+	}
+
+	WalkTerms(key, vis)
+	WalkTerms(value, vis)
+
+	var ref Ref
+
+	switch v := coll.Value.(type) {
+	case Ref:
+		ref = v.Append(key)
+	case Var:
+		ref = Ref{coll, key}
+	}
+
+	result.Append(Equality.Expr(NewTerm(ref).SetLocation(coll.Location), value).SetLocation(loc))
+
+	return result, errs
+}
+
+// rewriteEvery rewrites an "every" statement into a comparison of the keys in
+// the domain with the keys for which the body is satisfied. The key and value
+// are declared in a new scope so that they cannot be referred to outside of
+// the statement. For example, "every x in xs { x > 0 }" becomes:
+//
+//	__local0__ = xs
+//	__local3__ = {__local1__ | __local0__[__local1__]}
+//	__local4__ = {__local1__ | __local0__[__local1__] = __local2__; __local2__ > 0}
+//	__local3__ == __local4__
+func rewriteEvery(g *localVarGenerator, stack *localDeclaredVars, every *Every, errs Errors) (Body, Errors) {
+
+	var result Body
+	loc := every.Loc()
+
+	domain := every.Domain.Copy()
+	errs = rewriteDeclaredVarsInTermRecursive(g, stack, domain, errs)
+
+	dom := NewTerm(g.Generate()).SetLocation(domain.Location)
+	result.Append(Equality.Expr(dom, domain).SetLocation(loc))
+
+	stack.Push()
+
+	declare := func(t *Term) *Term {
+		gv := g.Generate()
+		if t != nil {
+			if v := t.Value.(Var); !v.IsWildcard() {
+				stack.Insert(v, gv, assignedVar)
+			}
+		}
+		return NewTerm(gv).SetLocation(loc)
+	}
+
+	key, value := declare(every.Key), declare(every.Value)
+
+	var body Body
+	body, errs = rewriteDeclaredVarsInBody(g, stack, nil, every.Body.Copy(), errs)
+
+	stack.Pop()
+
+	elem := NewTerm(Ref{dom, key}).SetLocation(loc)
+	all := SetComprehensionTerm(key, NewBody(NewExpr(elem).SetLocation(loc))).SetLocation(loc)
+
+	satisfied := NewBody(Equality.Expr(elem.Copy(), value).SetLocation(loc))
+	for _, expr := range body {
+		satisfied.Append(expr)
+	}
+
+	// The comprehensions are bound to vars (instead of being compared directly)
+	// so that partial evaluation can save them if necessary.
+	x, y := NewTerm(g.Generate()).SetLocation(loc), NewTerm(g.Generate()).SetLocation(loc)
+	result.Append(Equality.Expr(x, all).SetLocation(loc))
+	result.Append(Equality.Expr(y, SetComprehensionTerm(key.Copy(), satisfied).SetLocation(loc)).SetLocation(loc))
+	result.Append(Equal.Expr(x, y).SetLocation(loc))
+
+	return result, errs
 }
 
 func rewriteDeclaredVarsInExpr(g *localVarGenerator, stack *localDeclaredVars, expr *Expr, errs Errors) (*Expr, Errors) {
@@ -3157,12 +3621,20 @@ func rewriteWithModifiersInBody(c *Compiler, f *equalityFactory, body Body) (Bod
 func rewriteWithModifier(c *Compiler, f *equalityFactory, expr *Expr) ([]*Expr, *Error) {
 
 	var result []*Expr
+	replacements := map[string]*With{}
 	for i := range expr.With {
-		err := validateTarget(c, expr.With[i].Target)
+		isFunc, err := validateWith(c, expr.With[i])
 		if err != nil {
 			return nil, err
 		}
 
+		// Functions used as replacements are called instead of the target so
+		// they must not be evaluated here.
+		if isFunc {
+			replacements[expr.With[i].Target.String()] = expr.With[i]
+			continue
+		}
+
 		if requiresEval(expr.With[i].Value) {
 			eq := f.Generate(expr.With[i].Value)
 			result = append(result, eq)
@@ -3170,6 +3642,10 @@ func rewriteWithModifier(c *Compiler, f *equalityFactory, expr *Expr) ([]*Expr,
 		}
 	}
 
+	if err := checkWithFunctionCycles(replacements); err != nil {
+		return nil, err
+	}
+
 	// If any of the with modifiers in this expression were rewritten then result
 	// will be non-empty. In this case, the expression will have been modified and
 	// it should also be added to the result.
@@ -3179,9 +3655,77 @@ func rewriteWithModifier(c *Compiler, f *equalityFactory, expr *Expr) ([]*Expr,
 	return result, nil
 }
 
+// validateWith checks the target of the with modifier and returns true if the
+// target is a function that is replaced by another function. Built-in
+// function targets referred to by a single var (e.g., count) are rewritten to
+// refs so that they can be matched against calls during evaluation.
+func validateWith(c *Compiler, with *With) (bool, *Error) {
+
+	if v, ok := with.Target.Value.(Var); ok {
+		if _, ok := c.builtins[string(v)]; ok {
+			with.Target = NewTerm(Ref{with.Target}).SetLocation(with.Target.Location)
+		}
+	}
+
+	if err := validateTarget(c, with.Target); err != nil {
+		return false, err
+	}
+
+	target := with.Target.Value.(Ref)
+	arity := c.GetArity(target)
+
+	if isInputRef(with.Target) || arity <= 0 {
+		return false, nil
+	}
+
+	ref, ok := with.Value.Value.(Ref)
+	if !ok || !isDataRef(with.Value) || c.GetArity(ref) <= 0 {
+		return false, nil
+	}
+
+	if n := c.GetArity(ref); n != arity {
+		return false, NewError(TypeErr, with.Value.Loc(), "with keyword value %v must take %d argument(s) to replace %v but takes %d", ref, arity, target, n)
+	}
+
+	return true, nil
+}
+
+// checkWithFunctionCycles returns an error if the functions replaced by the
+// with modifiers of an expression replace each other, e.g., with f as g with g
+// as f.
+func checkWithFunctionCycles(replacements map[string]*With) *Error {
+
+	keys := make([]string, 0, len(replacements))
+	for k := range replacements {
+		keys = append(keys, k)
+	}
+
+	sort.Strings(keys)
+
+	for _, k := range keys {
+		with := replacements[k]
+		visited := map[string]bool{k: true}
+		for next, ok := replacements[with.Value.String()]; ok; next, ok = replacements[next.Value.String()] {
+			if visited[next.Target.String()] {
+				return NewError(CompileErr, with.Loc(), "with keyword replacements of %v form a cycle", with.Target)
+			}
+			visited[next.Target.String()] = true
+		}
+	}
+
+	return nil
+}
+
 func validateTarget(c *Compiler, term *Term) *Error {
+
+	if ref, ok := term.Value.(Ref); ok {
+		if _, ok := c.builtins[ref.String()]; ok {
+			return nil
+		}
+	}
+
 	if !isInputRef(term) && !isDataRef(term) {
-		return NewError(TypeErr, term.Location, "with keyword target must start with %v or %v", InputRootDocument, DefaultRootDocument)
+		return NewError(TypeErr, term.Location, "with keyword target must start with %v or %v or refer to a built-in function", InputRootDocument, DefaultRootDocument)
 	}
 
 	if isDataRef(term) {
@@ -3196,17 +3740,6 @@ func validateTarget(c *Compiler, term *Term) *Error {
 			}
 			node = child
 		}
-
-		if node != nil {
-			if child := node.Child(ref[len(ref)-1].Value); child != nil {
-				for _, value := range child.Values {
-					if len(value.(*Rule).Head.Args) > 0 {
-						return NewError(CompileErr, term.Loc(), "with keyword cannot replace functions")
-					}
-				}
-			}
-		}
-
 	}
 	return nil
 }
@@ -3242,6 +3775,21 @@ func isVirtual(node *TreeNode, ref Ref) bool {
 	return true
 }
 
+// isComparisonOperand returns true if v is an operand of an == expression.
+// Unlike unification, comparison never binds variables so the var was most
+// likely meant to be assigned.
+func isComparisonOperand(expr *Expr, v Var) bool {
+	if expr == nil || expr.Negated || !expr.IsCall() || !expr.Operator().Equal(Equal.Ref()) {
+		return false
+	}
+	for _, operand := range expr.Operands() {
+		if operand.Equal(NewTerm(v)) {
+			return true
+		}
+	}
+	return false
+}
+
 func safetyErrorSlice(unsafe unsafeVars) (result Errors) {
 
 	if len(unsafe) == 0 {
@@ -3250,7 +3798,11 @@ func safetyErrorSlice(unsafe unsafeVars) (result Errors) {
 
 	for _, pair := range unsafe.Vars() {
 		if !pair.Var.IsGenerated() {
-			result = append(result, NewError(UnsafeVarErr, pair.Loc, "var %v is unsafe", pair.Var))
+			if isComparisonOperand(pair.Expr, pair.Var) {
+				result = append(result, NewError(UnsafeVarErr, pair.Loc, "var %v is unsafe (use := or = instead of == to bind %v)", pair.Var, pair.Var))
+			} else {
+				result = append(result, NewError(UnsafeVarErr, pair.Loc, "var %v is unsafe", pair.Var))
+			}
 		}
 	}
 
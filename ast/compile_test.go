@@ -102,6 +102,19 @@ s[2] { true }`,
 	if !isVirtual(tree, MustParseRef("data.a.b.empty")) {
 		t.Fatal("Expected data.a.b.empty to be virtual")
 	}
+
+	// Check that child keys are sorted.
+	tree.DepthFirst(func(node *TreeNode) bool {
+		if len(node.Sorted) != len(node.Children) {
+			t.Fatalf("Expected %d sorted keys for %v but got: %v", len(node.Children), node.Key, node.Sorted)
+		}
+		for i := 1; i < len(node.Sorted); i++ {
+			if node.Sorted[i-1].Compare(node.Sorted[i]) >= 0 {
+				t.Fatalf("Expected sorted keys for %v but got: %v", node.Key, node.Sorted)
+			}
+		}
+		return false
+	})
 }
 
 func TestCompilerEmpty(t *testing.T) {
@@ -546,7 +559,7 @@ func TestCompilerCheckSafetyBodyErrors(t *testing.T) {
 		{"with-value", `p { data.a.b.d.t with input as x }`, `{x,}`},
 		{"with-value-2", `p { x = data.a.b.d.t with input as x }`, `{x,}`},
 		{"else-kw", "p { false } else { count(x, 1) }", `{x,}`},
-		{"function", "foo(x) = [y, z] { split(x, y, z) }", `{y,z}`},
+		{"function", "fn(x) = [y, z] { split(x, y, z) }", `{y,z}`},
 		{"call-vars-input", "p { f(x, x) } f(x) = x { true }", `{x,}`},
 		{"call-no-output", "p { f(x) } f(x) = x { true }", `{x,}`},
 		{"call-too-few", "p { f(1,x) } f(x,y) { true }", "{x,}"},
@@ -625,6 +638,71 @@ p {
 	}
 }
 
+func TestCompilerCheckSafetyComparison(t *testing.T) {
+
+	tests := []struct {
+		note string
+		body string
+		exp  string
+	}{
+		{"compare", `x == 1`, "var x is unsafe (use := or = instead of == to bind x)"},
+		{"compare-rhs", `input.x == x`, "var x is unsafe (use := or = instead of == to bind x)"},
+		{"compare-nested", `[x] == [1]`, "var x is unsafe"},
+		{"compare-negated", `not x == 1`, "var x is unsafe"},
+		{"other-builtin", `x > 1`, "var x is unsafe"},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.note, func(t *testing.T) {
+			_, err := CompileModules(map[string]string{"test.rego": "package test\n\np { " + tc.body + " }"})
+			if err == nil {
+				t.Fatal("expected error")
+			}
+			errs := err.(Errors)
+			if len(errs) != 1 || errs[0].Message != tc.exp {
+				t.Fatalf("expected %q but got: %v", tc.exp, err)
+			}
+		})
+	}
+}
+
+func TestCompilerLocations(t *testing.T) {
+
+	c := NewCompiler()
+	c.Compile(map[string]*Module{"test.rego": MustParseModule(`package test
+import future.keywords.every
+p[x] = y {
+	x := input.xs[_]
+	y := count([z | z := x[_]])
+	data.q[x] with input as {"a": 1}
+	f(x) == sum([1, 2])
+	every v in x { v > 0 }
+}
+
+f(x) = y { y := x + 1 }
+
+q[x] { x := 1 }`)})
+
+	if c.Failed() {
+		t.Fatal(c.Errors)
+	}
+
+	// Generated terms and exprs are located at the source they were generated from.
+	WalkTerms(c.Modules["test.rego"], func(x *Term) bool {
+		if x.Location == nil {
+			t.Errorf("Expected location on term %v", x)
+		}
+		return false
+	})
+
+	WalkExprs(c.Modules["test.rego"], func(x *Expr) bool {
+		if x.Location == nil {
+			t.Errorf("Expected location on expr %v", x)
+		}
+		return false
+	})
+}
+
 func TestCompilerCheckTypes(t *testing.T) {
 	c := NewCompiler()
 	modules := getCompilerTestModules()
@@ -673,11 +751,19 @@ p1 := 1
 p1 := 2
 
 p2 = 1
-p2 := 2`})
+p2 := 2`,
+		"mod8.rego": `package badrules.refheads
+
+fruit.apple.color = "red"
+fruit.apple.color = "green" { false }
+fruit = 1
+veg.carrot.color = "orange"`})
 
 	c.WithPathConflictsCheck(func(path []string) (bool, error) {
 		if reflect.DeepEqual(path, []string{"badrules", "dataoverlap", "p"}) {
 			return true, nil
+		} else if reflect.DeepEqual(path, []string{"badrules", "refheads", "veg", "carrot", "color"}) {
+			return true, nil
 		} else if reflect.DeepEqual(path, []string{"badrules", "existserr", "p"}) {
 			return false, fmt.Errorf("unexpected error")
 		}
@@ -689,6 +775,7 @@ p2 := 2`})
 	expected := []string{
 		"rego_compile_error: conflict check for data path badrules/existserr/p: unexpected error",
 		"rego_compile_error: conflicting rule for data path badrules/dataoverlap/p found",
+		"rego_compile_error: conflicting rule for data path badrules/refheads/veg/carrot/color found",
 		"rego_type_error: conflicting rules named f found",
 		"rego_type_error: conflicting rules named g found",
 		"rego_type_error: conflicting rules named p found",
@@ -696,6 +783,7 @@ p2 := 2`})
 		"rego_type_error: multiple default rules named foo found",
 		"rego_type_error: package badrules.r conflicts with rule defined at mod1.rego:7",
 		"rego_type_error: package badrules.r conflicts with rule defined at mod1.rego:8",
+		"rego_type_error: rule fruit conflicts with rule fruit.apple.color defined at mod8.rego:3",
 		"rego_type_error: rule named p1 redeclared at mod7.rego:4",
 		"rego_type_error: rule named p2 redeclared at mod7.rego:7",
 	}
@@ -993,7 +1081,7 @@ p[foo[bar[i]]] = {"baz": baz} { true }`)
 
 	c.Modules["assign"] = MustParseModule(`package assign
 
-		x = 1
+		z = 1
 		y = 1
 
 		p {
@@ -1134,6 +1222,187 @@ func TestCompilerResolveErrors(t *testing.T) {
 	assertCompilerErrorStrings(t, c, expected)
 }
 
+func TestCompilerStrictShadowing(t *testing.T) {
+
+	module := `
+		package test
+
+		import future.keywords
+		import input.roles
+
+		x = "a"
+		xs = {"a": 1, "b": 2}
+
+		global[y] { y := xs[x] }
+		import_operand { roles[_] = xs[roles] }
+		declared[x] { some x; xs[x] }
+		assigned { x := "b"; xs[x] }
+		args(x) { xs[x] }
+		nested { ys := [y | y := xs[x]]; ys[_] }
+		comprehension { [x | some x; xs[x]] }
+		root_some { some input; input = 1 }
+		root_assign { data := 1 }
+		root_in { some input in [1] }
+		every_local { every x in xs { xs[x] } }
+		every_global { every y in xs { xs[x] } }
+		assign_rule { xs := 1; xs > 0 }
+		assign_import { [roles, _] := [1, 2]; roles > 0 }
+		assign_comprehension { ys := [1 | x := 1] }
+		declare_rule { some xs; input[xs] }
+		head_ref = xs[x]
+	`
+
+	c := NewCompiler().WithStrict(true)
+	c.Modules = map[string]*Module{
+		"test.rego": MustParseModule(module),
+	}
+
+	compileStages(c, c.resolveAllRefs)
+
+	expected := []string{
+		`assigned var roles shadows input.roles (use a different variable name)`,
+		`assigned var x shadows data.test.x (use a different variable name)`,
+		`assigned var x shadows data.test.x (use a different variable name)`,
+		`assigned var xs shadows data.test.xs (use a different variable name)`,
+		`declared var data shadows root document data`,
+		`declared var input shadows root document input`,
+		`declared var input shadows root document input`,
+		`var roles refers to input.roles (declare with some roles to iterate)`,
+		`var x refers to data.test.x (declare with some x to iterate)`,
+		`var x refers to data.test.x (declare with some x to iterate)`,
+		`var x refers to data.test.x (declare with some x to iterate)`,
+	}
+
+	assertCompilerErrorStrings(t, c, expected)
+
+	// Assignments that shadow rules are reported in all modes.
+	c = NewCompiler()
+	c.Modules = map[string]*Module{
+		"test.rego": MustParseModule(module),
+	}
+
+	compileStages(c, c.resolveAllRefs)
+
+	expected = []string{
+		`assigned var x shadows data.test.x (use a different variable name)`,
+		`assigned var x shadows data.test.x (use a different variable name)`,
+		`assigned var xs shadows data.test.xs (use a different variable name)`,
+	}
+
+	assertCompilerErrorStrings(t, c, expected)
+}
+
+func TestCompilerAssignShadowsRule(t *testing.T) {
+
+	tests := []struct {
+		note    string
+		module  string
+		wantErr string
+	}{
+		{
+			note: "rule key",
+			module: `package test
+				x = 2
+				shadow_globals[x] { x := 1 }`,
+			wantErr: "assigned var x shadows data.test.x (use a different variable name)",
+		},
+		{
+			note: "same rule",
+			module: `package test
+				shadow_rule[shadow_rule] { shadow_rule := 1 }`,
+			wantErr: "assigned var shadow_rule shadows data.test.shadow_rule (use a different variable name)",
+		},
+		{
+			note: "ref operand",
+			module: `package test
+				global = {}
+				ref_shadowed { global := {"a": 1}; global.a > 0 }`,
+			wantErr: "assigned var global shadows data.test.global (use a different variable name)",
+		},
+		{
+			note: "comprehension",
+			module: `package test
+				x = 2
+				p { [y | x := 1; y := x] }`,
+			wantErr: "assigned var x shadows data.test.x (use a different variable name)",
+		},
+		{
+			note: "import",
+			module: `package test
+				import input.x
+				p { x := 1 }`,
+		},
+		{
+			note: "other package",
+			module: `package test
+				import data.other.x
+				p { y := x; y > 0 }`,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.note, func(t *testing.T) {
+			c := NewCompiler()
+			c.Modules = map[string]*Module{
+				"test.rego": MustParseModule(tc.module),
+			}
+			compileStages(c, c.resolveAllRefs)
+			if tc.wantErr == "" {
+				assertNotFailed(t, c)
+			} else {
+				assertCompilerErrorStrings(t, c, []string{tc.wantErr})
+			}
+		})
+	}
+}
+
+func TestCompilerCheckImports(t *testing.T) {
+
+	mod, err := ParseModule("test.rego", `package test
+
+		import future.keywords
+		import data.foo.bar as fb
+		import data.foo.baz as fb
+		import data.foo.qux as q
+		import data.foo.corge as r
+		import input.x
+		import input.x
+
+		ok { fb.a = 1; q.b = 2; x = 3 }
+		assigned { q := 1; q > 0 }
+		declared { some q; input[q] }
+		declared_in { some _, q in input }
+		comprehension { [1 | q := 1] }
+		every_body { every y in input { q := y } }
+		`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	c := NewCompiler()
+	c.Modules = map[string]*Module{
+		"test.rego": mod,
+		"test2.rego": MustParseModule(`package test
+
+		r = true
+		`),
+	}
+
+	compileStages(c, c.resolveAllRefs)
+
+	expected := []string{
+		`declared var q shadows import alias q for data.foo.qux`,
+		`declared var q shadows import alias q for data.foo.qux`,
+		`declared var q shadows import alias q for data.foo.qux`,
+		`declared var q shadows import alias q for data.foo.qux`,
+		`declared var q shadows import alias q for data.foo.qux`,
+		`import alias r conflicts with rule data.test.r`,
+		`import named fb redeclared at test.rego:5`,
+	}
+
+	assertCompilerErrorStrings(t, c, expected)
+}
+
 func TestCompilerRewriteTermsInHead(t *testing.T) {
 	c := NewCompiler()
 	c.Modules["head"] = MustParseModule(`package head
@@ -1279,34 +1548,6 @@ func TestCompilerRewriteLocalAssignments(t *testing.T) {
 				Var("__local1__"): Var("x"),
 			},
 		},
-		{
-			module: `
-				package test
-				x = 2
-				shadow_globals[x] { x := 1 }
-			`,
-			exp: `
-				package test
-				x = 2 { true }
-				shadow_globals[__local0__] { __local0__ = 1 }
-			`,
-			expRewrittenMap: map[Var]Var{
-				Var("__local0__"): Var("x"),
-			},
-		},
-		{
-			module: `
-				package test
-				shadow_rule[shadow_rule] { shadow_rule := 1 }
-			`,
-			exp: `
-				package test
-				shadow_rule[__local0__] { __local0__ = 1 }
-			`,
-			expRewrittenMap: map[Var]Var{
-				Var("__local0__"): Var("shadow_rule"),
-			},
-		},
 		{
 			module: `
 				package test
@@ -1501,24 +1742,6 @@ func TestCompilerRewriteLocalAssignments(t *testing.T) {
 				Var("__local1__"): Var("b"),
 			},
 		},
-		{
-			module: `
-				package test
-				global = {}
-				ref_shadowed {
-					global := {"a": 1}
-					global.a > 0
-				}
-			`,
-			exp: `
-				package test
-				global = {} { true }
-				ref_shadowed = true { __local0__ = {"a": 1}; gt(__local0__.a, 0) }
-			`,
-			expRewrittenMap: map[Var]Var{
-				Var("__local0__"): Var("global"),
-			},
-		},
 		{
 			module: `
 				package test
@@ -1870,6 +2093,111 @@ func TestRewriteDeclaredVars(t *testing.T) {
 			`,
 			wantErr: errors.New("arg a redeclared"),
 		},
+		{
+			note: "rewrite some x in",
+			module: `
+				package test
+				import future.keywords
+				xs = [1, 2]
+				x = 3
+				p { some x in xs; x > 1 }
+			`,
+			exp: `
+				package test
+				xs = [1, 2]
+				x = 3
+				p { data.test.xs[__local0__] = __local1__; __local1__ > 1 }
+			`,
+		},
+		{
+			note: "rewrite some k, v in",
+			module: `
+				package test
+				import future.keywords
+				p[k] { some k, [v, _] in input.xs; v = 1 }
+			`,
+			exp: `
+				package test
+				p[__local0__] { input.xs[__local0__] = [__local1__, __local2__]; __local1__ = 1 }
+			`,
+		},
+		{
+			note: "rewrite some x in composite",
+			module: `
+				package test
+				import future.keywords
+				p { y := 1; some x in [y, 2]; x = 2 }
+			`,
+			exp: `
+				package test
+				p { __local0__ = 1; __local1__ = [__local0__, 2]; __local1__[__local2__] = __local3__; __local3__ = 2 }
+			`,
+		},
+		{
+			note: "rewrite every",
+			module: `
+				package test
+				import future.keywords
+				x = 3
+				p { every x in input.xs { x > 1 } }
+			`,
+			exp: `
+				package test
+				x = 3
+				p { __local0__ = input.xs; __local3__ = {__local1__ | __local0__[__local1__]}; __local4__ = {__local1__ | __local0__[__local1__] = __local2__; __local2__ > 1}; __local3__ = __local4__ }
+			`,
+		},
+		{
+			note: "rewrite every with key and locals",
+			module: `
+				package test
+				import future.keywords
+				p { y := 1; every k, v in [y] { z := k; v = z } }
+			`,
+			exp: `
+				package test
+				p { __local0__ = 1; __local1__ = [__local0__]; __local5__ = {__local2__ | __local1__[__local2__]}; __local6__ = {__local2__ | __local1__[__local2__] = __local3__; __local4__ = __local2__; __local3__ = __local4__}; __local5__ = __local6__ }
+			`,
+		},
+		{
+			note: "every key and value are local",
+			module: `
+				package test
+				import future.keywords
+				p { every x in input.xs { x > 0 }; x = 1 }
+			`,
+			exp: `
+				package test
+				p { __local0__ = input.xs; __local3__ = {__local1__ | __local0__[__local1__]}; __local4__ = {__local1__ | __local0__[__local1__] = __local2__; __local2__ > 0}; __local3__ = __local4__; x = 1 }
+			`,
+		},
+		{
+			note: "every redeclare err",
+			module: `
+				package test
+				import future.keywords
+				p { every x in input.xs { x := 1 } }
+			`,
+			wantErr: errors.New("var x assigned above"),
+		},
+		{
+			note: "declare ref in err",
+			module: `
+				package test
+				import future.keywords
+				p { some input.x in [1] }
+			`,
+			wantErr: errors.New("cannot declare ref"),
+		},
+		{
+			note: "redeclare in err",
+			module: `
+				package test
+				import future.keywords
+				p { x := 1; some x in [1] }
+			`,
+			wantErr: errors.New("var x assigned above"),
+		},
 	}
 
 	for _, tc := range tests {
@@ -2112,7 +2440,7 @@ func TestCompilerRewriteWithValue(t *testing.T) {
 		{
 			note:    "invalid target",
 			input:   `p { true with foo.q as 1 }`,
-			wantErr: fmt.Errorf("rego_type_error: with keyword target must start with input or data"),
+			wantErr: fmt.Errorf("rego_type_error: with keyword target must start with input or data or refer to a built-in function"),
 		},
 	}
 
@@ -2137,18 +2465,71 @@ func TestCompilerRewriteWithValue(t *testing.T) {
 }
 
 func TestCompilerMockFunction(t *testing.T) {
-	c := NewCompiler()
-	c.Modules["test"] = MustParseModule(`
-	package test
 
-	is_allowed(label) {
-	    label == "test_label"
+	tests := []struct {
+		note    string
+		module  string
+		wantErr string
+	}{
+		{
+			note: "value",
+			module: `package test
+
+			is_allowed(label) { label == "test_label" }
+
+			p { true with data.test.is_allowed as "blah" }`,
+		},
+		{
+			note: "function",
+			module: `package test
+
+			is_allowed(label) { label == "test_label" }
+			mock_allowed(label) { true }
+
+			p { true with is_allowed as mock_allowed }`,
+		},
+		{
+			note: "built-in",
+			module: `package test
+
+			mock_send(req) = {"status_code": 200}
+
+			p { true with http.send as mock_send with count as 1 }`,
+		},
+		{
+			note: "arity mismatch",
+			module: `package test
+
+			is_allowed(label) { label == "test_label" }
+			mock_allowed(label, x) { true }
+
+			p { true with is_allowed as mock_allowed }`,
+			wantErr: "rego_type_error: with keyword value data.test.mock_allowed must take 1 argument(s) to replace data.test.is_allowed but takes 2",
+		},
+		{
+			note: "cycle",
+			module: `package test
+
+			f(x) { true }
+			g(x) { true }
+
+			p { f(1) with f as g with g as f }`,
+			wantErr: "rego_compile_error: with keyword replacements of data.test.f form a cycle",
+		},
 	}
 
-	p {true with data.test.is_allowed as "blah" }
-	`)
-	compileStages(c, c.rewriteWithModifiers)
-	assertCompilerErrorStrings(t, c, []string{"rego_compile_error: with keyword cannot replace functions"})
+	for _, tc := range tests {
+		t.Run(tc.note, func(t *testing.T) {
+			c := NewCompiler()
+			c.Modules["test"] = MustParseModule(tc.module)
+			compileStages(c, c.rewriteWithModifiers)
+			if tc.wantErr == "" {
+				assertNotFailed(t, c)
+			} else {
+				assertCompilerErrorStrings(t, c, []string{tc.wantErr})
+			}
+		})
+	}
 }
 
 func TestCompilerMockVirtualDocumentPartially(t *testing.T) {
@@ -3069,7 +3450,7 @@ func TestQueryCompiler(t *testing.T) {
 			q:        "x = 1 with foo.p as null",
 			pkg:      "",
 			imports:  nil,
-			expected: fmt.Errorf("1 error occurred: 1:12: rego_type_error: with keyword target must start with input or data"),
+			expected: fmt.Errorf("1 error occurred: 1:12: rego_type_error: with keyword target must start with input or data or refer to a built-in function"),
 		},
 		{
 			note:     "rewrite with value",
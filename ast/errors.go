@@ -126,6 +126,7 @@ var (
 	errPartialRuleAssignOperator = fmt.Errorf("partial rules must use = operator (not := operator)")
 	errElseAssignOperator        = fmt.Errorf("else keyword cannot be used on rule declared with := operator")
 	errFunctionAssignOperator    = fmt.Errorf("functions must use = operator (not := operator)")
+	errRefHeadArgsOrKey          = fmt.Errorf("rules with dotted heads cannot take arguments or keys")
 )
 
 func errTermAssignOperator(x interface{}) error {
@@ -38,6 +38,7 @@ func TestBaseDocEqIndexing(t *testing.T) {
 
 	module := MustParseModule(`
 	package test
+	import future.keywords
 
 	exact {
 		input.x = 1
@@ -222,16 +222,16 @@ var g = &grammar{
 		},
 		{
 			name: "Rules",
-			pos:  position{line: 21, col: 1, offset: 394},
+			pos:  position{line: 21, col: 1, offset: 397},
 			expr: &choiceExpr{
-				pos: position{line: 21, col: 10, offset: 403},
+				pos: position{line: 21, col: 10, offset: 406},
 				alternatives: []interface{}{
 					&ruleRefExpr{
-						pos:  position{line: 21, col: 10, offset: 403},
+						pos:  position{line: 21, col: 10, offset: 406},
 						name: "DefaultRules",
 					},
 					&ruleRefExpr{
-						pos:  position{line: 21, col: 25, offset: 418},
+						pos:  position{line: 21, col: 25, offset: 421},
 						name: "NormalRules",
 					},
 				},
@@ -239,47 +239,47 @@ var g = &grammar{
 		},
 		{
 			name: "DefaultRules",
-			pos:  position{line: 23, col: 1, offset: 431},
+			pos:  position{line: 23, col: 1, offset: 434},
 			expr: &actionExpr{
-				pos: position{line: 23, col: 17, offset: 447},
+				pos: position{line: 23, col: 17, offset: 450},
 				run: (*parser).callonDefaultRules1,
 				expr: &seqExpr{
-					pos: position{line: 23, col: 17, offset: 447},
+					pos: position{line: 23, col: 17, offset: 450},
 					exprs: []interface{}{
 						&litMatcher{
-							pos:        position{line: 23, col: 17, offset: 447},
+							pos:        position{line: 23, col: 17, offset: 450},
 							val:        "default",
 							ignoreCase: false,
 						},
 						&ruleRefExpr{
-							pos:  position{line: 23, col: 27, offset: 457},
+							pos:  position{line: 23, col: 27, offset: 460},
 							name: "ws",
 						},
 						&labeledExpr{
-							pos:   position{line: 23, col: 30, offset: 460},
+							pos:   position{line: 23, col: 30, offset: 463},
 							label: "name",
 							expr: &ruleRefExpr{
-								pos:  position{line: 23, col: 35, offset: 465},
+								pos:  position{line: 23, col: 35, offset: 468},
 								name: "Var",
 							},
 						},
 						&ruleRefExpr{
-							pos:  position{line: 23, col: 39, offset: 469},
+							pos:  position{line: 23, col: 39, offset: 472},
 							name: "_",
 						},
 						&labeledExpr{
-							pos:   position{line: 23, col: 41, offset: 471},
+							pos:   position{line: 23, col: 41, offset: 474},
 							label: "operator",
 							expr: &choiceExpr{
-								pos: position{line: 23, col: 52, offset: 482},
+								pos: position{line: 23, col: 52, offset: 485},
 								alternatives: []interface{}{
 									&litMatcher{
-										pos:        position{line: 23, col: 52, offset: 482},
+										pos:        position{line: 23, col: 52, offset: 485},
 										val:        ":=",
 										ignoreCase: false,
 									},
 									&litMatcher{
-										pos:        position{line: 23, col: 59, offset: 489},
+										pos:        position{line: 23, col: 59, offset: 492},
 										val:        "=",
 										ignoreCase: false,
 									},
@@ -287,14 +287,14 @@ var g = &grammar{
 							},
 						},
 						&ruleRefExpr{
-							pos:  position{line: 23, col: 65, offset: 495},
+							pos:  position{line: 23, col: 65, offset: 498},
 							name: "_",
 						},
 						&labeledExpr{
-							pos:   position{line: 23, col: 67, offset: 497},
+							pos:   position{line: 23, col: 67, offset: 500},
 							label: "value",
 							expr: &ruleRefExpr{
-								pos:  position{line: 23, col: 73, offset: 503},
+								pos:  position{line: 23, col: 73, offset: 506},
 								name: "Term",
 							},
 						},
@@ -304,111 +304,267 @@ var g = &grammar{
 		},
 		{
 			name: "NormalRules",
-			pos:  position{line: 27, col: 1, offset: 583},
+			pos:  position{line: 27, col: 1, offset: 586},
+			expr: &choiceExpr{
+				pos: position{line: 27, col: 16, offset: 601},
+				alternatives: []interface{}{
+					&actionExpr{
+						pos: position{line: 27, col: 16, offset: 601},
+						run: (*parser).callonNormalRules2,
+						expr: &seqExpr{
+							pos: position{line: 27, col: 16, offset: 601},
+							exprs: []interface{}{
+								&labeledExpr{
+									pos:   position{line: 27, col: 16, offset: 601},
+									label: "head",
+									expr: &choiceExpr{
+										pos: position{line: 27, col: 23, offset: 608},
+										alternatives: []interface{}{
+											&ruleRefExpr{
+												pos:  position{line: 27, col: 23, offset: 608},
+												name: "ContainsRuleHead",
+											},
+											&ruleRefExpr{
+												pos:  position{line: 27, col: 42, offset: 627},
+												name: "RuleHead",
+											},
+										},
+									},
+								},
+								&ruleRefExpr{
+									pos:  position{line: 27, col: 53, offset: 638},
+									name: "_",
+								},
+								&labeledExpr{
+									pos:   position{line: 27, col: 55, offset: 640},
+									label: "kw",
+									expr: &zeroOrOneExpr{
+										pos: position{line: 27, col: 58, offset: 643},
+										expr: &ruleRefExpr{
+											pos:  position{line: 27, col: 58, offset: 643},
+											name: "IfKeyword",
+										},
+									},
+								},
+								&labeledExpr{
+									pos:   position{line: 27, col: 69, offset: 654},
+									label: "rest",
+									expr: &seqExpr{
+										pos: position{line: 27, col: 75, offset: 660},
+										exprs: []interface{}{
+											&ruleRefExpr{
+												pos:  position{line: 27, col: 75, offset: 660},
+												name: "NonEmptyBraceEnclosedBody",
+											},
+											&zeroOrMoreExpr{
+												pos: position{line: 27, col: 101, offset: 686},
+												expr: &seqExpr{
+													pos: position{line: 27, col: 103, offset: 688},
+													exprs: []interface{}{
+														&ruleRefExpr{
+															pos:  position{line: 27, col: 103, offset: 688},
+															name: "_",
+														},
+														&ruleRefExpr{
+															pos:  position{line: 27, col: 105, offset: 690},
+															name: "RuleExt",
+														},
+													},
+												},
+											},
+										},
+									},
+								},
+							},
+						},
+					},
+					&actionExpr{
+						pos: position{line: 29, col: 5, offset: 771},
+						run: (*parser).callonNormalRules19,
+						expr: &seqExpr{
+							pos: position{line: 29, col: 5, offset: 771},
+							exprs: []interface{}{
+								&labeledExpr{
+									pos:   position{line: 29, col: 5, offset: 771},
+									label: "head",
+									expr: &choiceExpr{
+										pos: position{line: 29, col: 12, offset: 778},
+										alternatives: []interface{}{
+											&ruleRefExpr{
+												pos:  position{line: 29, col: 12, offset: 778},
+												name: "ContainsRuleHead",
+											},
+											&ruleRefExpr{
+												pos:  position{line: 29, col: 31, offset: 797},
+												name: "RuleHead",
+											},
+										},
+									},
+								},
+								&ruleRefExpr{
+									pos:  position{line: 29, col: 42, offset: 808},
+									name: "_",
+								},
+								&ruleRefExpr{
+									pos:  position{line: 29, col: 44, offset: 810},
+									name: "IfKeyword",
+								},
+								&labeledExpr{
+									pos:   position{line: 29, col: 54, offset: 820},
+									label: "val",
+									expr: &ruleRefExpr{
+										pos:  position{line: 29, col: 58, offset: 824},
+										name: "Literal",
+									},
+								},
+							},
+						},
+					},
+				},
+			},
+		},
+		{
+			name: "ContainsRuleHead",
+			pos:  position{line: 33, col: 1, offset: 943},
 			expr: &actionExpr{
-				pos: position{line: 27, col: 16, offset: 598},
-				run: (*parser).callonNormalRules1,
+				pos: position{line: 33, col: 21, offset: 963},
+				run: (*parser).callonContainsRuleHead1,
 				expr: &seqExpr{
-					pos: position{line: 27, col: 16, offset: 598},
+					pos: position{line: 33, col: 21, offset: 963},
 					exprs: []interface{}{
 						&labeledExpr{
-							pos:   position{line: 27, col: 16, offset: 598},
-							label: "head",
+							pos:   position{line: 33, col: 21, offset: 963},
+							label: "name",
 							expr: &ruleRefExpr{
-								pos:  position{line: 27, col: 21, offset: 603},
-								name: "RuleHead",
+								pos:  position{line: 33, col: 26, offset: 968},
+								name: "Var",
 							},
 						},
 						&ruleRefExpr{
-							pos:  position{line: 27, col: 30, offset: 612},
-							name: "_",
+							pos:  position{line: 33, col: 30, offset: 972},
+							name: "ws",
+						},
+						&andCodeExpr{
+							pos: position{line: 33, col: 33, offset: 975},
+							run: (*parser).callonContainsRuleHead6,
+						},
+						&litMatcher{
+							pos:        position{line: 33, col: 86, offset: 1028},
+							val:        "contains",
+							ignoreCase: false,
+						},
+						&ruleRefExpr{
+							pos:  position{line: 33, col: 97, offset: 1039},
+							name: "ws",
 						},
 						&labeledExpr{
-							pos:   position{line: 27, col: 32, offset: 614},
-							label: "rest",
-							expr: &seqExpr{
-								pos: position{line: 27, col: 38, offset: 620},
-								exprs: []interface{}{
-									&ruleRefExpr{
-										pos:  position{line: 27, col: 38, offset: 620},
-										name: "NonEmptyBraceEnclosedBody",
-									},
-									&zeroOrMoreExpr{
-										pos: position{line: 27, col: 64, offset: 646},
-										expr: &seqExpr{
-											pos: position{line: 27, col: 66, offset: 648},
-											exprs: []interface{}{
-												&ruleRefExpr{
-													pos:  position{line: 27, col: 66, offset: 648},
-													name: "_",
-												},
-												&ruleRefExpr{
-													pos:  position{line: 27, col: 68, offset: 650},
-													name: "RuleExt",
-												},
-											},
-										},
-									},
-								},
+							pos:   position{line: 33, col: 100, offset: 1042},
+							label: "key",
+							expr: &ruleRefExpr{
+								pos:  position{line: 33, col: 104, offset: 1046},
+								name: "ExprTerm",
 							},
 						},
 					},
 				},
 			},
 		},
+		{
+			name: "IfKeyword",
+			pos:  position{line: 37, col: 1, offset: 1123},
+			expr: &seqExpr{
+				pos: position{line: 37, col: 14, offset: 1136},
+				exprs: []interface{}{
+					&andCodeExpr{
+						pos: position{line: 37, col: 14, offset: 1136},
+						run: (*parser).callonIfKeyword2,
+					},
+					&litMatcher{
+						pos:        position{line: 37, col: 61, offset: 1183},
+						val:        "if",
+						ignoreCase: false,
+					},
+					&ruleRefExpr{
+						pos:  position{line: 37, col: 66, offset: 1188},
+						name: "_",
+					},
+				},
+			},
+		},
 		{
 			name: "RuleHead",
-			pos:  position{line: 31, col: 1, offset: 719},
+			pos:  position{line: 39, col: 1, offset: 1191},
 			expr: &actionExpr{
-				pos: position{line: 31, col: 13, offset: 731},
+				pos: position{line: 39, col: 13, offset: 1203},
 				run: (*parser).callonRuleHead1,
 				expr: &seqExpr{
-					pos: position{line: 31, col: 13, offset: 731},
+					pos: position{line: 39, col: 13, offset: 1203},
 					exprs: []interface{}{
 						&labeledExpr{
-							pos:   position{line: 31, col: 13, offset: 731},
+							pos:   position{line: 39, col: 13, offset: 1203},
 							label: "name",
 							expr: &ruleRefExpr{
-								pos:  position{line: 31, col: 18, offset: 736},
+								pos:  position{line: 39, col: 18, offset: 1208},
 								name: "Var",
 							},
 						},
 						&labeledExpr{
-							pos:   position{line: 31, col: 22, offset: 740},
+							pos:   position{line: 39, col: 22, offset: 1212},
+							label: "path",
+							expr: &zeroOrMoreExpr{
+								pos: position{line: 39, col: 27, offset: 1217},
+								expr: &seqExpr{
+									pos: position{line: 39, col: 29, offset: 1219},
+									exprs: []interface{}{
+										&litMatcher{
+											pos:        position{line: 39, col: 29, offset: 1219},
+											val:        ".",
+											ignoreCase: false,
+										},
+										&ruleRefExpr{
+											pos:  position{line: 39, col: 33, offset: 1223},
+											name: "Var",
+										},
+									},
+								},
+							},
+						},
+						&labeledExpr{
+							pos:   position{line: 39, col: 40, offset: 1230},
 							label: "args",
 							expr: &zeroOrOneExpr{
-								pos: position{line: 31, col: 27, offset: 745},
+								pos: position{line: 39, col: 45, offset: 1235},
 								expr: &seqExpr{
-									pos: position{line: 31, col: 29, offset: 747},
+									pos: position{line: 39, col: 47, offset: 1237},
 									exprs: []interface{}{
 										&ruleRefExpr{
-											pos:  position{line: 31, col: 29, offset: 747},
+											pos:  position{line: 39, col: 47, offset: 1237},
 											name: "_",
 										},
 										&litMatcher{
-											pos:        position{line: 31, col: 31, offset: 749},
+											pos:        position{line: 39, col: 49, offset: 1239},
 											val:        "(",
 											ignoreCase: false,
 										},
 										&ruleRefExpr{
-											pos:  position{line: 31, col: 35, offset: 753},
+											pos:  position{line: 39, col: 53, offset: 1243},
 											name: "_",
 										},
 										&ruleRefExpr{
-											pos:  position{line: 31, col: 37, offset: 755},
+											pos:  position{line: 39, col: 55, offset: 1245},
 											name: "Args",
 										},
 										&ruleRefExpr{
-											pos:  position{line: 31, col: 42, offset: 760},
+											pos:  position{line: 39, col: 60, offset: 1250},
 											name: "_",
 										},
 										&litMatcher{
-											pos:        position{line: 31, col: 44, offset: 762},
+											pos:        position{line: 39, col: 62, offset: 1252},
 											val:        ")",
 											ignoreCase: false,
 										},
 										&ruleRefExpr{
-											pos:  position{line: 31, col: 48, offset: 766},
+											pos:  position{line: 39, col: 66, offset: 1256},
 											name: "_",
 										},
 									},
@@ -416,41 +572,41 @@ var g = &grammar{
 							},
 						},
 						&labeledExpr{
-							pos:   position{line: 31, col: 53, offset: 771},
+							pos:   position{line: 39, col: 71, offset: 1261},
 							label: "key",
 							expr: &zeroOrOneExpr{
-								pos: position{line: 31, col: 57, offset: 775},
+								pos: position{line: 39, col: 75, offset: 1265},
 								expr: &seqExpr{
-									pos: position{line: 31, col: 59, offset: 777},
+									pos: position{line: 39, col: 77, offset: 1267},
 									exprs: []interface{}{
 										&ruleRefExpr{
-											pos:  position{line: 31, col: 59, offset: 777},
+											pos:  position{line: 39, col: 77, offset: 1267},
 											name: "_",
 										},
 										&litMatcher{
-											pos:        position{line: 31, col: 61, offset: 779},
+											pos:        position{line: 39, col: 79, offset: 1269},
 											val:        "[",
 											ignoreCase: false,
 										},
 										&ruleRefExpr{
-											pos:  position{line: 31, col: 65, offset: 783},
+											pos:  position{line: 39, col: 83, offset: 1273},
 											name: "_",
 										},
 										&ruleRefExpr{
-											pos:  position{line: 31, col: 67, offset: 785},
+											pos:  position{line: 39, col: 85, offset: 1275},
 											name: "ExprTerm",
 										},
 										&ruleRefExpr{
-											pos:  position{line: 31, col: 76, offset: 794},
+											pos:  position{line: 39, col: 94, offset: 1284},
 											name: "_",
 										},
 										&litMatcher{
-											pos:        position{line: 31, col: 78, offset: 796},
+											pos:        position{line: 39, col: 96, offset: 1286},
 											val:        "]",
 											ignoreCase: false,
 										},
 										&ruleRefExpr{
-											pos:  position{line: 31, col: 82, offset: 800},
+											pos:  position{line: 39, col: 100, offset: 1290},
 											name: "_",
 										},
 									},
@@ -458,38 +614,38 @@ var g = &grammar{
 							},
 						},
 						&labeledExpr{
-							pos:   position{line: 31, col: 87, offset: 805},
+							pos:   position{line: 39, col: 105, offset: 1295},
 							label: "value",
 							expr: &zeroOrOneExpr{
-								pos: position{line: 31, col: 93, offset: 811},
+								pos: position{line: 39, col: 111, offset: 1301},
 								expr: &seqExpr{
-									pos: position{line: 31, col: 95, offset: 813},
+									pos: position{line: 39, col: 113, offset: 1303},
 									exprs: []interface{}{
 										&ruleRefExpr{
-											pos:  position{line: 31, col: 95, offset: 813},
+											pos:  position{line: 39, col: 113, offset: 1303},
 											name: "_",
 										},
 										&choiceExpr{
-											pos: position{line: 31, col: 99, offset: 817},
+											pos: position{line: 39, col: 117, offset: 1307},
 											alternatives: []interface{}{
 												&litMatcher{
-													pos:        position{line: 31, col: 99, offset: 817},
+													pos:        position{line: 39, col: 117, offset: 1307},
 													val:        ":=",
 													ignoreCase: false,
 												},
 												&litMatcher{
-													pos:        position{line: 31, col: 106, offset: 824},
+													pos:        position{line: 39, col: 124, offset: 1314},
 													val:        "=",
 													ignoreCase: false,
 												},
 											},
 										},
 										&ruleRefExpr{
-											pos:  position{line: 31, col: 112, offset: 830},
+											pos:  position{line: 39, col: 130, offset: 1320},
 											name: "_",
 										},
 										&ruleRefExpr{
-											pos:  position{line: 31, col: 114, offset: 832},
+											pos:  position{line: 39, col: 132, offset: 1322},
 											name: "ExprTerm",
 										},
 									},
@@ -502,15 +658,15 @@ var g = &grammar{
 		},
 		{
 			name: "Args",
-			pos:  position{line: 35, col: 1, offset: 917},
+			pos:  position{line: 43, col: 1, offset: 1413},
 			expr: &actionExpr{
-				pos: position{line: 35, col: 9, offset: 925},
+				pos: position{line: 43, col: 9, offset: 1421},
 				run: (*parser).callonArgs1,
 				expr: &labeledExpr{
-					pos:   position{line: 35, col: 9, offset: 925},
+					pos:   position{line: 43, col: 9, offset: 1421},
 					label: "list",
 					expr: &ruleRefExpr{
-						pos:  position{line: 35, col: 14, offset: 930},
+						pos:  position{line: 43, col: 14, offset: 1426},
 						name: "ExprTermList",
 					},
 				},
@@ -518,41 +674,41 @@ var g = &grammar{
 		},
 		{
 			name: "Else",
-			pos:  position{line: 39, col: 1, offset: 974},
+			pos:  position{line: 47, col: 1, offset: 1470},
 			expr: &actionExpr{
-				pos: position{line: 39, col: 9, offset: 982},
+				pos: position{line: 47, col: 9, offset: 1478},
 				run: (*parser).callonElse1,
 				expr: &seqExpr{
-					pos: position{line: 39, col: 9, offset: 982},
+					pos: position{line: 47, col: 9, offset: 1478},
 					exprs: []interface{}{
 						&litMatcher{
-							pos:        position{line: 39, col: 9, offset: 982},
+							pos:        position{line: 47, col: 9, offset: 1478},
 							val:        "else",
 							ignoreCase: false,
 						},
 						&labeledExpr{
-							pos:   position{line: 39, col: 16, offset: 989},
+							pos:   position{line: 47, col: 16, offset: 1485},
 							label: "value",
 							expr: &zeroOrOneExpr{
-								pos: position{line: 39, col: 22, offset: 995},
+								pos: position{line: 47, col: 22, offset: 1491},
 								expr: &seqExpr{
-									pos: position{line: 39, col: 24, offset: 997},
+									pos: position{line: 47, col: 24, offset: 1493},
 									exprs: []interface{}{
 										&ruleRefExpr{
-											pos:  position{line: 39, col: 24, offset: 997},
+											pos:  position{line: 47, col: 24, offset: 1493},
 											name: "_",
 										},
 										&litMatcher{
-											pos:        position{line: 39, col: 26, offset: 999},
+											pos:        position{line: 47, col: 26, offset: 1495},
 											val:        "=",
 											ignoreCase: false,
 										},
 										&ruleRefExpr{
-											pos:  position{line: 39, col: 30, offset: 1003},
+											pos:  position{line: 47, col: 30, offset: 1499},
 											name: "_",
 										},
 										&ruleRefExpr{
-											pos:  position{line: 39, col: 32, offset: 1005},
+											pos:  position{line: 47, col: 32, offset: 1501},
 											name: "Term",
 										},
 									},
@@ -560,17 +716,24 @@ var g = &grammar{
 							},
 						},
 						&labeledExpr{
-							pos:   position{line: 39, col: 40, offset: 1013},
+							pos:   position{line: 47, col: 40, offset: 1509},
 							label: "body",
 							expr: &seqExpr{
-								pos: position{line: 39, col: 47, offset: 1020},
+								pos: position{line: 47, col: 47, offset: 1516},
 								exprs: []interface{}{
 									&ruleRefExpr{
-										pos:  position{line: 39, col: 47, offset: 1020},
+										pos:  position{line: 47, col: 47, offset: 1516},
 										name: "_",
 									},
+									&zeroOrOneExpr{
+										pos: position{line: 47, col: 49, offset: 1518},
+										expr: &ruleRefExpr{
+											pos:  position{line: 47, col: 49, offset: 1518},
+											name: "IfKeyword",
+										},
+									},
 									&ruleRefExpr{
-										pos:  position{line: 39, col: 49, offset: 1022},
+										pos:  position{line: 47, col: 60, offset: 1529},
 										name: "NonEmptyBraceEnclosedBody",
 									},
 								},
@@ -582,15 +745,15 @@ var g = &grammar{
 		},
 		{
 			name: "RuleDup",
-			pos:  position{line: 43, col: 1, offset: 1111},
+			pos:  position{line: 51, col: 1, offset: 1618},
 			expr: &actionExpr{
-				pos: position{line: 43, col: 12, offset: 1122},
+				pos: position{line: 51, col: 12, offset: 1629},
 				run: (*parser).callonRuleDup1,
 				expr: &labeledExpr{
-					pos:   position{line: 43, col: 12, offset: 1122},
+					pos:   position{line: 51, col: 12, offset: 1629},
 					label: "b",
 					expr: &ruleRefExpr{
-						pos:  position{line: 43, col: 14, offset: 1124},
+						pos:  position{line: 51, col: 14, offset: 1631},
 						name: "NonEmptyBraceEnclosedBody",
 					},
 				},
@@ -598,16 +761,16 @@ var g = &grammar{
 		},
 		{
 			name: "RuleExt",
-			pos:  position{line: 47, col: 1, offset: 1220},
+			pos:  position{line: 55, col: 1, offset: 1727},
 			expr: &choiceExpr{
-				pos: position{line: 47, col: 12, offset: 1231},
+				pos: position{line: 55, col: 12, offset: 1738},
 				alternatives: []interface{}{
 					&ruleRefExpr{
-						pos:  position{line: 47, col: 12, offset: 1231},
+						pos:  position{line: 55, col: 12, offset: 1738},
 						name: "Else",
 					},
 					&ruleRefExpr{
-						pos:  position{line: 47, col: 19, offset: 1238},
+						pos:  position{line: 55, col: 19, offset: 1745},
 						name: "RuleDup",
 					},
 				},
@@ -615,16 +778,16 @@ var g = &grammar{
 		},
 		{
 			name: "Body",
-			pos:  position{line: 49, col: 1, offset: 1247},
+			pos:  position{line: 57, col: 1, offset: 1754},
 			expr: &choiceExpr{
-				pos: position{line: 49, col: 9, offset: 1255},
+				pos: position{line: 57, col: 9, offset: 1762},
 				alternatives: []interface{}{
 					&ruleRefExpr{
-						pos:  position{line: 49, col: 9, offset: 1255},
+						pos:  position{line: 57, col: 9, offset: 1762},
 						name: "NonWhitespaceBody",
 					},
 					&ruleRefExpr{
-						pos:  position{line: 49, col: 29, offset: 1275},
+						pos:  position{line: 57, col: 29, offset: 1782},
 						name: "BraceEnclosedBody",
 					},
 				},
@@ -632,39 +795,39 @@ var g = &grammar{
 		},
 		{
 			name: "NonEmptyBraceEnclosedBody",
-			pos:  position{line: 51, col: 1, offset: 1294},
+			pos:  position{line: 59, col: 1, offset: 1801},
 			expr: &actionExpr{
-				pos: position{line: 51, col: 30, offset: 1323},
+				pos: position{line: 59, col: 30, offset: 1830},
 				run: (*parser).callonNonEmptyBraceEnclosedBody1,
 				expr: &seqExpr{
-					pos: position{line: 51, col: 30, offset: 1323},
+					pos: position{line: 59, col: 30, offset: 1830},
 					exprs: []interface{}{
 						&litMatcher{
-							pos:        position{line: 51, col: 30, offset: 1323},
+							pos:        position{line: 59, col: 30, offset: 1830},
 							val:        "{",
 							ignoreCase: false,
 						},
 						&ruleRefExpr{
-							pos:  position{line: 51, col: 34, offset: 1327},
+							pos:  position{line: 59, col: 34, offset: 1834},
 							name: "_",
 						},
 						&labeledExpr{
-							pos:   position{line: 51, col: 36, offset: 1329},
+							pos:   position{line: 59, col: 36, offset: 1836},
 							label: "val",
 							expr: &zeroOrOneExpr{
-								pos: position{line: 51, col: 40, offset: 1333},
+								pos: position{line: 59, col: 40, offset: 1840},
 								expr: &ruleRefExpr{
-									pos:  position{line: 51, col: 40, offset: 1333},
+									pos:  position{line: 59, col: 40, offset: 1840},
 									name: "WhitespaceBody",
 								},
 							},
 						},
 						&ruleRefExpr{
-							pos:  position{line: 51, col: 56, offset: 1349},
+							pos:  position{line: 59, col: 56, offset: 1856},
 							name: "_",
 						},
 						&litMatcher{
-							pos:        position{line: 51, col: 58, offset: 1351},
+							pos:        position{line: 59, col: 58, offset: 1858},
 							val:        "}",
 							ignoreCase: false,
 						},
@@ -674,39 +837,39 @@ var g = &grammar{
 		},
 		{
 			name: "BraceEnclosedBody",
-			pos:  position{line: 58, col: 1, offset: 1463},
+			pos:  position{line: 66, col: 1, offset: 1970},
 			expr: &actionExpr{
-				pos: position{line: 58, col: 22, offset: 1484},
+				pos: position{line: 66, col: 22, offset: 1991},
 				run: (*parser).callonBraceEnclosedBody1,
 				expr: &seqExpr{
-					pos: position{line: 58, col: 22, offset: 1484},
+					pos: position{line: 66, col: 22, offset: 1991},
 					exprs: []interface{}{
 						&litMatcher{
-							pos:        position{line: 58, col: 22, offset: 1484},
+							pos:        position{line: 66, col: 22, offset: 1991},
 							val:        "{",
 							ignoreCase: false,
 						},
 						&ruleRefExpr{
-							pos:  position{line: 58, col: 26, offset: 1488},
+							pos:  position{line: 66, col: 26, offset: 1995},
 							name: "_",
 						},
 						&labeledExpr{
-							pos:   position{line: 58, col: 28, offset: 1490},
+							pos:   position{line: 66, col: 28, offset: 1997},
 							label: "val",
 							expr: &zeroOrOneExpr{
-								pos: position{line: 58, col: 32, offset: 1494},
+								pos: position{line: 66, col: 32, offset: 2001},
 								expr: &ruleRefExpr{
-									pos:  position{line: 58, col: 32, offset: 1494},
+									pos:  position{line: 66, col: 32, offset: 2001},
 									name: "WhitespaceBody",
 								},
 							},
 						},
 						&ruleRefExpr{
-							pos:  position{line: 58, col: 48, offset: 1510},
+							pos:  position{line: 66, col: 48, offset: 2017},
 							name: "_",
 						},
 						&litMatcher{
-							pos:        position{line: 58, col: 50, offset: 1512},
+							pos:        position{line: 66, col: 50, offset: 2019},
 							val:        "}",
 							ignoreCase: false,
 						},
@@ -716,39 +879,39 @@ var g = &grammar{
 		},
 		{
 			name: "WhitespaceBody",
-			pos:  position{line: 62, col: 1, offset: 1579},
+			pos:  position{line: 70, col: 1, offset: 2086},
 			expr: &actionExpr{
-				pos: position{line: 62, col: 19, offset: 1597},
+				pos: position{line: 70, col: 19, offset: 2104},
 				run: (*parser).callonWhitespaceBody1,
 				expr: &seqExpr{
-					pos: position{line: 62, col: 19, offset: 1597},
+					pos: position{line: 70, col: 19, offset: 2104},
 					exprs: []interface{}{
 						&labeledExpr{
-							pos:   position{line: 62, col: 19, offset: 1597},
+							pos:   position{line: 70, col: 19, offset: 2104},
 							label: "head",
 							expr: &ruleRefExpr{
-								pos:  position{line: 62, col: 24, offset: 1602},
+								pos:  position{line: 70, col: 24, offset: 2109},
 								name: "Literal",
 							},
 						},
 						&labeledExpr{
-							pos:   position{line: 62, col: 32, offset: 1610},
+							pos:   position{line: 70, col: 32, offset: 2117},
 							label: "tail",
 							expr: &zeroOrMoreExpr{
-								pos: position{line: 62, col: 37, offset: 1615},
+								pos: position{line: 70, col: 37, offset: 2122},
 								expr: &seqExpr{
-									pos: position{line: 62, col: 38, offset: 1616},
+									pos: position{line: 70, col: 38, offset: 2123},
 									exprs: []interface{}{
 										&ruleRefExpr{
-											pos:  position{line: 62, col: 38, offset: 1616},
+											pos:  position{line: 70, col: 38, offset: 2123},
 											name: "WhitespaceLiteralSeparator",
 										},
 										&ruleRefExpr{
-											pos:  position{line: 62, col: 65, offset: 1643},
+											pos:  position{line: 70, col: 65, offset: 2150},
 											name: "_",
 										},
 										&ruleRefExpr{
-											pos:  position{line: 62, col: 67, offset: 1645},
+											pos:  position{line: 70, col: 67, offset: 2152},
 											name: "Literal",
 										},
 									},
@@ -761,43 +924,43 @@ var g = &grammar{
 		},
 		{
 			name: "NonWhitespaceBody",
-			pos:  position{line: 66, col: 1, offset: 1695},
+			pos:  position{line: 74, col: 1, offset: 2202},
 			expr: &actionExpr{
-				pos: position{line: 66, col: 22, offset: 1716},
+				pos: position{line: 74, col: 22, offset: 2223},
 				run: (*parser).callonNonWhitespaceBody1,
 				expr: &seqExpr{
-					pos: position{line: 66, col: 22, offset: 1716},
+					pos: position{line: 74, col: 22, offset: 2223},
 					exprs: []interface{}{
 						&labeledExpr{
-							pos:   position{line: 66, col: 22, offset: 1716},
+							pos:   position{line: 74, col: 22, offset: 2223},
 							label: "head",
 							expr: &ruleRefExpr{
-								pos:  position{line: 66, col: 27, offset: 1721},
+								pos:  position{line: 74, col: 27, offset: 2228},
 								name: "Literal",
 							},
 						},
 						&labeledExpr{
-							pos:   position{line: 66, col: 35, offset: 1729},
+							pos:   position{line: 74, col: 35, offset: 2236},
 							label: "tail",
 							expr: &zeroOrMoreExpr{
-								pos: position{line: 66, col: 40, offset: 1734},
+								pos: position{line: 74, col: 40, offset: 2241},
 								expr: &seqExpr{
-									pos: position{line: 66, col: 42, offset: 1736},
+									pos: position{line: 74, col: 42, offset: 2243},
 									exprs: []interface{}{
 										&ruleRefExpr{
-											pos:  position{line: 66, col: 42, offset: 1736},
+											pos:  position{line: 74, col: 42, offset: 2243},
 											name: "_",
 										},
 										&ruleRefExpr{
-											pos:  position{line: 66, col: 44, offset: 1738},
+											pos:  position{line: 74, col: 44, offset: 2245},
 											name: "NonWhitespaceLiteralSeparator",
 										},
 										&ruleRefExpr{
-											pos:  position{line: 66, col: 74, offset: 1768},
+											pos:  position{line: 74, col: 74, offset: 2275},
 											name: "_",
 										},
 										&ruleRefExpr{
-											pos:  position{line: 66, col: 76, offset: 1770},
+											pos:  position{line: 74, col: 76, offset: 2277},
 											name: "Literal",
 										},
 									},
@@ -810,14 +973,14 @@ var g = &grammar{
 		},
 		{
 			name: "WhitespaceLiteralSeparator",
-			pos:  position{line: 70, col: 1, offset: 1820},
+			pos:  position{line: 78, col: 1, offset: 2327},
 			expr: &seqExpr{
-				pos: position{line: 70, col: 31, offset: 1850},
+				pos: position{line: 78, col: 31, offset: 2357},
 				exprs: []interface{}{
 					&zeroOrMoreExpr{
-						pos: position{line: 70, col: 31, offset: 1850},
+						pos: position{line: 78, col: 31, offset: 2357},
 						expr: &charClassMatcher{
-							pos:        position{line: 70, col: 31, offset: 1850},
+							pos:        position{line: 78, col: 31, offset: 2357},
 							val:        "[ \\t]",
 							chars:      []rune{' ', '\t'},
 							ignoreCase: false,
@@ -825,36 +988,36 @@ var g = &grammar{
 						},
 					},
 					&choiceExpr{
-						pos: position{line: 70, col: 39, offset: 1858},
+						pos: position{line: 78, col: 39, offset: 2365},
 						alternatives: []interface{}{
 							&seqExpr{
-								pos: position{line: 70, col: 40, offset: 1859},
+								pos: position{line: 78, col: 40, offset: 2366},
 								exprs: []interface{}{
 									&ruleRefExpr{
-										pos:  position{line: 70, col: 40, offset: 1859},
+										pos:  position{line: 78, col: 40, offset: 2366},
 										name: "NonWhitespaceLiteralSeparator",
 									},
 									&zeroOrOneExpr{
-										pos: position{line: 70, col: 70, offset: 1889},
+										pos: position{line: 78, col: 70, offset: 2396},
 										expr: &ruleRefExpr{
-											pos:  position{line: 70, col: 70, offset: 1889},
+											pos:  position{line: 78, col: 70, offset: 2396},
 											name: "Comment",
 										},
 									},
 								},
 							},
 							&seqExpr{
-								pos: position{line: 70, col: 83, offset: 1902},
+								pos: position{line: 78, col: 83, offset: 2409},
 								exprs: []interface{}{
 									&zeroOrOneExpr{
-										pos: position{line: 70, col: 83, offset: 1902},
+										pos: position{line: 78, col: 83, offset: 2409},
 										expr: &ruleRefExpr{
-											pos:  position{line: 70, col: 83, offset: 1902},
+											pos:  position{line: 78, col: 83, offset: 2409},
 											name: "Comment",
 										},
 									},
 									&charClassMatcher{
-										pos:        position{line: 70, col: 92, offset: 1911},
+										pos:        position{line: 78, col: 92, offset: 2418},
 										val:        "[\\r\\n]",
 										chars:      []rune{'\r', '\n'},
 										ignoreCase: false,
@@ -869,54 +1032,250 @@ var g = &grammar{
 		},
 		{
 			name: "NonWhitespaceLiteralSeparator",
-			pos:  position{line: 72, col: 1, offset: 1921},
-			expr: &litMatcher{
-				pos:        position{line: 72, col: 34, offset: 1954},
-				val:        ";",
-				ignoreCase: false,
+			pos:  position{line: 80, col: 1, offset: 2428},
+			expr: &choiceExpr{
+				pos: position{line: 80, col: 34, offset: 2461},
+				alternatives: []interface{}{
+					&litMatcher{
+						pos:        position{line: 80, col: 34, offset: 2461},
+						val:        ";",
+						ignoreCase: false,
+					},
+					&litMatcher{
+						pos:        position{line: 80, col: 40, offset: 2467},
+						val:        ",",
+						ignoreCase: false,
+					},
+				},
 			},
 		},
 		{
 			name: "Literal",
-			pos:  position{line: 74, col: 1, offset: 1959},
+			pos:  position{line: 82, col: 1, offset: 2472},
 			expr: &choiceExpr{
-				pos: position{line: 74, col: 12, offset: 1970},
+				pos: position{line: 82, col: 12, offset: 2483},
 				alternatives: []interface{}{
 					&ruleRefExpr{
-						pos:  position{line: 74, col: 12, offset: 1970},
+						pos:  position{line: 82, col: 12, offset: 2483},
+						name: "Every",
+					},
+					&ruleRefExpr{
+						pos:  position{line: 82, col: 20, offset: 2491},
 						name: "TermExpr",
 					},
 					&ruleRefExpr{
-						pos:  position{line: 74, col: 23, offset: 1981},
+						pos:  position{line: 82, col: 31, offset: 2502},
 						name: "SomeDecl",
 					},
 				},
 			},
 		},
+		{
+			name: "Every",
+			pos:  position{line: 84, col: 1, offset: 2512},
+			expr: &actionExpr{
+				pos: position{line: 84, col: 10, offset: 2521},
+				run: (*parser).callonEvery1,
+				expr: &seqExpr{
+					pos: position{line: 84, col: 10, offset: 2521},
+					exprs: []interface{}{
+						&andCodeExpr{
+							pos: position{line: 84, col: 10, offset: 2521},
+							run: (*parser).callonEvery3,
+						},
+						&litMatcher{
+							pos:        position{line: 84, col: 60, offset: 2571},
+							val:        "every",
+							ignoreCase: false,
+						},
+						&ruleRefExpr{
+							pos:  position{line: 84, col: 68, offset: 2579},
+							name: "ws",
+						},
+						&labeledExpr{
+							pos:   position{line: 84, col: 71, offset: 2582},
+							label: "key",
+							expr: &zeroOrOneExpr{
+								pos: position{line: 84, col: 75, offset: 2586},
+								expr: &seqExpr{
+									pos: position{line: 84, col: 77, offset: 2588},
+									exprs: []interface{}{
+										&ruleRefExpr{
+											pos:  position{line: 84, col: 77, offset: 2588},
+											name: "Var",
+										},
+										&ruleRefExpr{
+											pos:  position{line: 84, col: 81, offset: 2592},
+											name: "_",
+										},
+										&litMatcher{
+											pos:        position{line: 84, col: 83, offset: 2594},
+											val:        ",",
+											ignoreCase: false,
+										},
+										&ruleRefExpr{
+											pos:  position{line: 84, col: 87, offset: 2598},
+											name: "_",
+										},
+									},
+								},
+							},
+						},
+						&labeledExpr{
+							pos:   position{line: 84, col: 92, offset: 2603},
+							label: "value",
+							expr: &ruleRefExpr{
+								pos:  position{line: 84, col: 98, offset: 2609},
+								name: "Var",
+							},
+						},
+						&ruleRefExpr{
+							pos:  position{line: 84, col: 102, offset: 2613},
+							name: "_",
+						},
+						&litMatcher{
+							pos:        position{line: 84, col: 104, offset: 2615},
+							val:        "in",
+							ignoreCase: false,
+						},
+						&notExpr{
+							pos: position{line: 84, col: 109, offset: 2620},
+							expr: &ruleRefExpr{
+								pos:  position{line: 84, col: 110, offset: 2621},
+								name: "VarChar",
+							},
+						},
+						&ruleRefExpr{
+							pos:  position{line: 84, col: 118, offset: 2629},
+							name: "_",
+						},
+						&labeledExpr{
+							pos:   position{line: 84, col: 120, offset: 2631},
+							label: "domain",
+							expr: &ruleRefExpr{
+								pos:  position{line: 84, col: 127, offset: 2638},
+								name: "RelationTerm",
+							},
+						},
+						&ruleRefExpr{
+							pos:  position{line: 84, col: 140, offset: 2651},
+							name: "_",
+						},
+						&labeledExpr{
+							pos:   position{line: 84, col: 142, offset: 2653},
+							label: "body",
+							expr: &ruleRefExpr{
+								pos:  position{line: 84, col: 147, offset: 2658},
+								name: "NonEmptyBraceEnclosedBody",
+							},
+						},
+					},
+				},
+			},
+		},
 		{
 			name: "SomeDecl",
-			pos:  position{line: 76, col: 1, offset: 1991},
+			pos:  position{line: 88, col: 1, offset: 2756},
 			expr: &actionExpr{
-				pos: position{line: 76, col: 13, offset: 2003},
+				pos: position{line: 88, col: 13, offset: 2768},
 				run: (*parser).callonSomeDecl1,
 				expr: &seqExpr{
-					pos: position{line: 76, col: 13, offset: 2003},
+					pos: position{line: 88, col: 13, offset: 2768},
 					exprs: []interface{}{
 						&litMatcher{
-							pos:        position{line: 76, col: 13, offset: 2003},
+							pos:        position{line: 88, col: 13, offset: 2768},
 							val:        "some",
 							ignoreCase: false,
 						},
 						&ruleRefExpr{
-							pos:  position{line: 76, col: 20, offset: 2010},
+							pos:  position{line: 88, col: 20, offset: 2775},
 							name: "ws",
 						},
 						&labeledExpr{
-							pos:   position{line: 76, col: 23, offset: 2013},
+							pos:   position{line: 88, col: 23, offset: 2778},
 							label: "symbols",
+							expr: &choiceExpr{
+								pos: position{line: 88, col: 33, offset: 2788},
+								alternatives: []interface{}{
+									&ruleRefExpr{
+										pos:  position{line: 88, col: 33, offset: 2788},
+										name: "SomeDeclIn",
+									},
+									&ruleRefExpr{
+										pos:  position{line: 88, col: 46, offset: 2801},
+										name: "SomeDeclList",
+									},
+								},
+							},
+						},
+					},
+				},
+			},
+		},
+		{
+			name: "SomeDeclIn",
+			pos:  position{line: 92, col: 1, offset: 2881},
+			expr: &actionExpr{
+				pos: position{line: 92, col: 15, offset: 2895},
+				run: (*parser).callonSomeDeclIn1,
+				expr: &seqExpr{
+					pos: position{line: 92, col: 15, offset: 2895},
+					exprs: []interface{}{
+						&labeledExpr{
+							pos:   position{line: 92, col: 15, offset: 2895},
+							label: "key",
+							expr: &zeroOrOneExpr{
+								pos: position{line: 92, col: 19, offset: 2899},
+								expr: &seqExpr{
+									pos: position{line: 92, col: 21, offset: 2901},
+									exprs: []interface{}{
+										&ruleRefExpr{
+											pos:  position{line: 92, col: 21, offset: 2901},
+											name: "Term",
+										},
+										&ruleRefExpr{
+											pos:  position{line: 92, col: 26, offset: 2906},
+											name: "_",
+										},
+										&litMatcher{
+											pos:        position{line: 92, col: 28, offset: 2908},
+											val:        ",",
+											ignoreCase: false,
+										},
+										&ruleRefExpr{
+											pos:  position{line: 92, col: 32, offset: 2912},
+											name: "_",
+										},
+									},
+								},
+							},
+						},
+						&labeledExpr{
+							pos:   position{line: 92, col: 37, offset: 2917},
+							label: "value",
+							expr: &ruleRefExpr{
+								pos:  position{line: 92, col: 43, offset: 2923},
+								name: "Term",
+							},
+						},
+						&ruleRefExpr{
+							pos:  position{line: 92, col: 48, offset: 2928},
+							name: "_",
+						},
+						&ruleRefExpr{
+							pos:  position{line: 92, col: 50, offset: 2930},
+							name: "MembershipOperator",
+						},
+						&ruleRefExpr{
+							pos:  position{line: 92, col: 69, offset: 2949},
+							name: "_",
+						},
+						&labeledExpr{
+							pos:   position{line: 92, col: 71, offset: 2951},
+							label: "collection",
 							expr: &ruleRefExpr{
-								pos:  position{line: 76, col: 31, offset: 2021},
-								name: "SomeDeclList",
+								pos:  position{line: 92, col: 82, offset: 2962},
+								name: "RelationTerm",
 							},
 						},
 					},
@@ -925,44 +1284,44 @@ var g = &grammar{
 		},
 		{
 			name: "SomeDeclList",
-			pos:  position{line: 80, col: 1, offset: 2099},
+			pos:  position{line: 96, col: 1, offset: 3050},
 			expr: &actionExpr{
-				pos: position{line: 80, col: 17, offset: 2115},
+				pos: position{line: 96, col: 17, offset: 3066},
 				run: (*parser).callonSomeDeclList1,
 				expr: &seqExpr{
-					pos: position{line: 80, col: 17, offset: 2115},
+					pos: position{line: 96, col: 17, offset: 3066},
 					exprs: []interface{}{
 						&labeledExpr{
-							pos:   position{line: 80, col: 17, offset: 2115},
+							pos:   position{line: 96, col: 17, offset: 3066},
 							label: "head",
 							expr: &ruleRefExpr{
-								pos:  position{line: 80, col: 22, offset: 2120},
+								pos:  position{line: 96, col: 22, offset: 3071},
 								name: "Var",
 							},
 						},
 						&labeledExpr{
-							pos:   position{line: 80, col: 26, offset: 2124},
+							pos:   position{line: 96, col: 26, offset: 3075},
 							label: "rest",
 							expr: &zeroOrMoreExpr{
-								pos: position{line: 80, col: 31, offset: 2129},
+								pos: position{line: 96, col: 31, offset: 3080},
 								expr: &seqExpr{
-									pos: position{line: 80, col: 33, offset: 2131},
+									pos: position{line: 96, col: 33, offset: 3082},
 									exprs: []interface{}{
 										&ruleRefExpr{
-											pos:  position{line: 80, col: 33, offset: 2131},
+											pos:  position{line: 96, col: 33, offset: 3082},
 											name: "_",
 										},
 										&litMatcher{
-											pos:        position{line: 80, col: 35, offset: 2133},
+											pos:        position{line: 96, col: 35, offset: 3084},
 											val:        ",",
 											ignoreCase: false,
 										},
 										&ruleRefExpr{
-											pos:  position{line: 80, col: 39, offset: 2137},
+											pos:  position{line: 96, col: 39, offset: 3088},
 											name: "_",
 										},
 										&ruleRefExpr{
-											pos:  position{line: 80, col: 41, offset: 2139},
+											pos:  position{line: 96, col: 41, offset: 3090},
 											name: "Var",
 										},
 									},
@@ -975,39 +1334,39 @@ var g = &grammar{
 		},
 		{
 			name: "TermExpr",
-			pos:  position{line: 84, col: 1, offset: 2193},
+			pos:  position{line: 100, col: 1, offset: 3144},
 			expr: &actionExpr{
-				pos: position{line: 84, col: 13, offset: 2205},
+				pos: position{line: 100, col: 13, offset: 3156},
 				run: (*parser).callonTermExpr1,
 				expr: &seqExpr{
-					pos: position{line: 84, col: 13, offset: 2205},
+					pos: position{line: 100, col: 13, offset: 3156},
 					exprs: []interface{}{
 						&labeledExpr{
-							pos:   position{line: 84, col: 13, offset: 2205},
+							pos:   position{line: 100, col: 13, offset: 3156},
 							label: "negated",
 							expr: &zeroOrOneExpr{
-								pos: position{line: 84, col: 21, offset: 2213},
+								pos: position{line: 100, col: 21, offset: 3164},
 								expr: &ruleRefExpr{
-									pos:  position{line: 84, col: 21, offset: 2213},
+									pos:  position{line: 100, col: 21, offset: 3164},
 									name: "NotKeyword",
 								},
 							},
 						},
 						&labeledExpr{
-							pos:   position{line: 84, col: 33, offset: 2225},
+							pos:   position{line: 100, col: 33, offset: 3176},
 							label: "value",
 							expr: &ruleRefExpr{
-								pos:  position{line: 84, col: 39, offset: 2231},
+								pos:  position{line: 100, col: 39, offset: 3182},
 								name: "LiteralExpr",
 							},
 						},
 						&labeledExpr{
-							pos:   position{line: 84, col: 51, offset: 2243},
+							pos:   position{line: 100, col: 51, offset: 3194},
 							label: "with",
 							expr: &zeroOrOneExpr{
-								pos: position{line: 84, col: 56, offset: 2248},
+								pos: position{line: 100, col: 56, offset: 3199},
 								expr: &ruleRefExpr{
-									pos:  position{line: 84, col: 56, offset: 2248},
+									pos:  position{line: 100, col: 56, offset: 3199},
 									name: "WithKeywordList",
 								},
 							},
@@ -1018,43 +1377,43 @@ var g = &grammar{
 		},
 		{
 			name: "LiteralExpr",
-			pos:  position{line: 88, col: 1, offset: 2315},
+			pos:  position{line: 104, col: 1, offset: 3266},
 			expr: &actionExpr{
-				pos: position{line: 88, col: 16, offset: 2330},
+				pos: position{line: 104, col: 16, offset: 3281},
 				run: (*parser).callonLiteralExpr1,
 				expr: &seqExpr{
-					pos: position{line: 88, col: 16, offset: 2330},
+					pos: position{line: 104, col: 16, offset: 3281},
 					exprs: []interface{}{
 						&labeledExpr{
-							pos:   position{line: 88, col: 16, offset: 2330},
+							pos:   position{line: 104, col: 16, offset: 3281},
 							label: "lhs",
 							expr: &ruleRefExpr{
-								pos:  position{line: 88, col: 20, offset: 2334},
+								pos:  position{line: 104, col: 20, offset: 3285},
 								name: "ExprTerm",
 							},
 						},
 						&labeledExpr{
-							pos:   position{line: 88, col: 29, offset: 2343},
+							pos:   position{line: 104, col: 29, offset: 3294},
 							label: "rest",
 							expr: &zeroOrOneExpr{
-								pos: position{line: 88, col: 34, offset: 2348},
+								pos: position{line: 104, col: 34, offset: 3299},
 								expr: &seqExpr{
-									pos: position{line: 88, col: 36, offset: 2350},
+									pos: position{line: 104, col: 36, offset: 3301},
 									exprs: []interface{}{
 										&ruleRefExpr{
-											pos:  position{line: 88, col: 36, offset: 2350},
+											pos:  position{line: 104, col: 36, offset: 3301},
 											name: "_",
 										},
 										&ruleRefExpr{
-											pos:  position{line: 88, col: 38, offset: 2352},
+											pos:  position{line: 104, col: 38, offset: 3303},
 											name: "LiteralExprOperator",
 										},
 										&ruleRefExpr{
-											pos:  position{line: 88, col: 58, offset: 2372},
+											pos:  position{line: 104, col: 58, offset: 3323},
 											name: "_",
 										},
 										&ruleRefExpr{
-											pos:  position{line: 88, col: 60, offset: 2374},
+											pos:  position{line: 104, col: 60, offset: 3325},
 											name: "ExprTerm",
 										},
 									},
@@ -1067,23 +1426,23 @@ var g = &grammar{
 		},
 		{
 			name: "LiteralExprOperator",
-			pos:  position{line: 92, col: 1, offset: 2448},
+			pos:  position{line: 108, col: 1, offset: 3399},
 			expr: &actionExpr{
-				pos: position{line: 92, col: 24, offset: 2471},
+				pos: position{line: 108, col: 24, offset: 3422},
 				run: (*parser).callonLiteralExprOperator1,
 				expr: &labeledExpr{
-					pos:   position{line: 92, col: 24, offset: 2471},
+					pos:   position{line: 108, col: 24, offset: 3422},
 					label: "val",
 					expr: &choiceExpr{
-						pos: position{line: 92, col: 30, offset: 2477},
+						pos: position{line: 108, col: 30, offset: 3428},
 						alternatives: []interface{}{
 							&litMatcher{
-								pos:        position{line: 92, col: 30, offset: 2477},
+								pos:        position{line: 108, col: 30, offset: 3428},
 								val:        ":=",
 								ignoreCase: false,
 							},
 							&litMatcher{
-								pos:        position{line: 92, col: 37, offset: 2484},
+								pos:        position{line: 108, col: 37, offset: 3435},
 								val:        "=",
 								ignoreCase: false,
 							},
@@ -1094,25 +1453,25 @@ var g = &grammar{
 		},
 		{
 			name: "NotKeyword",
-			pos:  position{line: 96, col: 1, offset: 2552},
+			pos:  position{line: 112, col: 1, offset: 3503},
 			expr: &actionExpr{
-				pos: position{line: 96, col: 15, offset: 2566},
+				pos: position{line: 112, col: 15, offset: 3517},
 				run: (*parser).callonNotKeyword1,
 				expr: &labeledExpr{
-					pos:   position{line: 96, col: 15, offset: 2566},
+					pos:   position{line: 112, col: 15, offset: 3517},
 					label: "val",
 					expr: &zeroOrOneExpr{
-						pos: position{line: 96, col: 19, offset: 2570},
+						pos: position{line: 112, col: 19, offset: 3521},
 						expr: &seqExpr{
-							pos: position{line: 96, col: 20, offset: 2571},
+							pos: position{line: 112, col: 20, offset: 3522},
 							exprs: []interface{}{
 								&litMatcher{
-									pos:        position{line: 96, col: 20, offset: 2571},
+									pos:        position{line: 112, col: 20, offset: 3522},
 									val:        "not",
 									ignoreCase: false,
 								},
 								&ruleRefExpr{
-									pos:  position{line: 96, col: 26, offset: 2577},
+									pos:  position{line: 112, col: 26, offset: 3528},
 									name: "ws",
 								},
 							},
@@ -1123,39 +1482,39 @@ var g = &grammar{
 		},
 		{
 			name: "WithKeywordList",
-			pos:  position{line: 100, col: 1, offset: 2614},
+			pos:  position{line: 116, col: 1, offset: 3565},
 			expr: &actionExpr{
-				pos: position{line: 100, col: 20, offset: 2633},
+				pos: position{line: 116, col: 20, offset: 3584},
 				run: (*parser).callonWithKeywordList1,
 				expr: &seqExpr{
-					pos: position{line: 100, col: 20, offset: 2633},
+					pos: position{line: 116, col: 20, offset: 3584},
 					exprs: []interface{}{
 						&ruleRefExpr{
-							pos:  position{line: 100, col: 20, offset: 2633},
+							pos:  position{line: 116, col: 20, offset: 3584},
 							name: "ws",
 						},
 						&labeledExpr{
-							pos:   position{line: 100, col: 23, offset: 2636},
+							pos:   position{line: 116, col: 23, offset: 3587},
 							label: "head",
 							expr: &ruleRefExpr{
-								pos:  position{line: 100, col: 28, offset: 2641},
+								pos:  position{line: 116, col: 28, offset: 3592},
 								name: "WithKeyword",
 							},
 						},
 						&labeledExpr{
-							pos:   position{line: 100, col: 40, offset: 2653},
+							pos:   position{line: 116, col: 40, offset: 3604},
 							label: "rest",
 							expr: &zeroOrMoreExpr{
-								pos: position{line: 100, col: 45, offset: 2658},
+								pos: position{line: 116, col: 45, offset: 3609},
 								expr: &seqExpr{
-									pos: position{line: 100, col: 47, offset: 2660},
+									pos: position{line: 116, col: 47, offset: 3611},
 									exprs: []interface{}{
 										&ruleRefExpr{
-											pos:  position{line: 100, col: 47, offset: 2660},
+											pos:  position{line: 116, col: 47, offset: 3611},
 											name: "ws",
 										},
 										&ruleRefExpr{
-											pos:  position{line: 100, col: 50, offset: 2663},
+											pos:  position{line: 116, col: 50, offset: 3614},
 											name: "WithKeyword",
 										},
 									},
@@ -1168,48 +1527,48 @@ var g = &grammar{
 		},
 		{
 			name: "WithKeyword",
-			pos:  position{line: 104, col: 1, offset: 2726},
+			pos:  position{line: 120, col: 1, offset: 3677},
 			expr: &actionExpr{
-				pos: position{line: 104, col: 16, offset: 2741},
+				pos: position{line: 120, col: 16, offset: 3692},
 				run: (*parser).callonWithKeyword1,
 				expr: &seqExpr{
-					pos: position{line: 104, col: 16, offset: 2741},
+					pos: position{line: 120, col: 16, offset: 3692},
 					exprs: []interface{}{
 						&litMatcher{
-							pos:        position{line: 104, col: 16, offset: 2741},
+							pos:        position{line: 120, col: 16, offset: 3692},
 							val:        "with",
 							ignoreCase: false,
 						},
 						&ruleRefExpr{
-							pos:  position{line: 104, col: 23, offset: 2748},
+							pos:  position{line: 120, col: 23, offset: 3699},
 							name: "ws",
 						},
 						&labeledExpr{
-							pos:   position{line: 104, col: 26, offset: 2751},
+							pos:   position{line: 120, col: 26, offset: 3702},
 							label: "target",
 							expr: &ruleRefExpr{
-								pos:  position{line: 104, col: 33, offset: 2758},
+								pos:  position{line: 120, col: 33, offset: 3709},
 								name: "ExprTerm",
 							},
 						},
 						&ruleRefExpr{
-							pos:  position{line: 104, col: 42, offset: 2767},
+							pos:  position{line: 120, col: 42, offset: 3718},
 							name: "ws",
 						},
 						&litMatcher{
-							pos:        position{line: 104, col: 45, offset: 2770},
+							pos:        position{line: 120, col: 45, offset: 3721},
 							val:        "as",
 							ignoreCase: false,
 						},
 						&ruleRefExpr{
-							pos:  position{line: 104, col: 50, offset: 2775},
+							pos:  position{line: 120, col: 50, offset: 3726},
 							name: "ws",
 						},
 						&labeledExpr{
-							pos:   position{line: 104, col: 53, offset: 2778},
+							pos:   position{line: 120, col: 53, offset: 3729},
 							label: "value",
 							expr: &ruleRefExpr{
-								pos:  position{line: 104, col: 59, offset: 2784},
+								pos:  position{line: 120, col: 59, offset: 3735},
 								name: "ExprTerm",
 							},
 						},
@@ -1219,43 +1578,92 @@ var g = &grammar{
 		},
 		{
 			name: "ExprTerm",
-			pos:  position{line: 108, col: 1, offset: 2860},
+			pos:  position{line: 124, col: 1, offset: 3811},
 			expr: &actionExpr{
-				pos: position{line: 108, col: 13, offset: 2872},
+				pos: position{line: 124, col: 13, offset: 3823},
 				run: (*parser).callonExprTerm1,
 				expr: &seqExpr{
-					pos: position{line: 108, col: 13, offset: 2872},
+					pos: position{line: 124, col: 13, offset: 3823},
 					exprs: []interface{}{
 						&labeledExpr{
-							pos:   position{line: 108, col: 13, offset: 2872},
+							pos:   position{line: 124, col: 13, offset: 3823},
 							label: "lhs",
 							expr: &ruleRefExpr{
-								pos:  position{line: 108, col: 17, offset: 2876},
+								pos:  position{line: 124, col: 17, offset: 3827},
+								name: "RelationTerm",
+							},
+						},
+						&labeledExpr{
+							pos:   position{line: 124, col: 30, offset: 3840},
+							label: "rest",
+							expr: &zeroOrMoreExpr{
+								pos: position{line: 124, col: 35, offset: 3845},
+								expr: &seqExpr{
+									pos: position{line: 124, col: 37, offset: 3847},
+									exprs: []interface{}{
+										&ruleRefExpr{
+											pos:  position{line: 124, col: 37, offset: 3847},
+											name: "_",
+										},
+										&ruleRefExpr{
+											pos:  position{line: 124, col: 39, offset: 3849},
+											name: "MembershipOperator",
+										},
+										&ruleRefExpr{
+											pos:  position{line: 124, col: 58, offset: 3868},
+											name: "_",
+										},
+										&ruleRefExpr{
+											pos:  position{line: 124, col: 60, offset: 3870},
+											name: "RelationTerm",
+										},
+									},
+								},
+							},
+						},
+					},
+				},
+			},
+		},
+		{
+			name: "RelationTerm",
+			pos:  position{line: 128, col: 1, offset: 3946},
+			expr: &actionExpr{
+				pos: position{line: 128, col: 17, offset: 3962},
+				run: (*parser).callonRelationTerm1,
+				expr: &seqExpr{
+					pos: position{line: 128, col: 17, offset: 3962},
+					exprs: []interface{}{
+						&labeledExpr{
+							pos:   position{line: 128, col: 17, offset: 3962},
+							label: "lhs",
+							expr: &ruleRefExpr{
+								pos:  position{line: 128, col: 21, offset: 3966},
 								name: "RelationExpr",
 							},
 						},
 						&labeledExpr{
-							pos:   position{line: 108, col: 30, offset: 2889},
+							pos:   position{line: 128, col: 34, offset: 3979},
 							label: "rest",
 							expr: &zeroOrMoreExpr{
-								pos: position{line: 108, col: 35, offset: 2894},
+								pos: position{line: 128, col: 39, offset: 3984},
 								expr: &seqExpr{
-									pos: position{line: 108, col: 37, offset: 2896},
+									pos: position{line: 128, col: 41, offset: 3986},
 									exprs: []interface{}{
 										&ruleRefExpr{
-											pos:  position{line: 108, col: 37, offset: 2896},
+											pos:  position{line: 128, col: 41, offset: 3986},
 											name: "_",
 										},
 										&ruleRefExpr{
-											pos:  position{line: 108, col: 39, offset: 2898},
+											pos:  position{line: 128, col: 43, offset: 3988},
 											name: "RelationOperator",
 										},
 										&ruleRefExpr{
-											pos:  position{line: 108, col: 56, offset: 2915},
+											pos:  position{line: 128, col: 60, offset: 4005},
 											name: "_",
 										},
 										&ruleRefExpr{
-											pos:  position{line: 108, col: 58, offset: 2917},
+											pos:  position{line: 128, col: 62, offset: 4007},
 											name: "RelationExpr",
 										},
 									},
@@ -1268,47 +1676,47 @@ var g = &grammar{
 		},
 		{
 			name: "ExprTermPairList",
-			pos:  position{line: 112, col: 1, offset: 2993},
+			pos:  position{line: 132, col: 1, offset: 4083},
 			expr: &actionExpr{
-				pos: position{line: 112, col: 21, offset: 3013},
+				pos: position{line: 132, col: 21, offset: 4103},
 				run: (*parser).callonExprTermPairList1,
 				expr: &seqExpr{
-					pos: position{line: 112, col: 21, offset: 3013},
+					pos: position{line: 132, col: 21, offset: 4103},
 					exprs: []interface{}{
 						&labeledExpr{
-							pos:   position{line: 112, col: 21, offset: 3013},
+							pos:   position{line: 132, col: 21, offset: 4103},
 							label: "head",
 							expr: &zeroOrOneExpr{
-								pos: position{line: 112, col: 26, offset: 3018},
+								pos: position{line: 132, col: 26, offset: 4108},
 								expr: &ruleRefExpr{
-									pos:  position{line: 112, col: 26, offset: 3018},
+									pos:  position{line: 132, col: 26, offset: 4108},
 									name: "ExprTermPair",
 								},
 							},
 						},
 						&labeledExpr{
-							pos:   position{line: 112, col: 40, offset: 3032},
+							pos:   position{line: 132, col: 40, offset: 4122},
 							label: "tail",
 							expr: &zeroOrMoreExpr{
-								pos: position{line: 112, col: 45, offset: 3037},
+								pos: position{line: 132, col: 45, offset: 4127},
 								expr: &seqExpr{
-									pos: position{line: 112, col: 47, offset: 3039},
+									pos: position{line: 132, col: 47, offset: 4129},
 									exprs: []interface{}{
 										&ruleRefExpr{
-											pos:  position{line: 112, col: 47, offset: 3039},
+											pos:  position{line: 132, col: 47, offset: 4129},
 											name: "_",
 										},
 										&litMatcher{
-											pos:        position{line: 112, col: 49, offset: 3041},
+											pos:        position{line: 132, col: 49, offset: 4131},
 											val:        ",",
 											ignoreCase: false,
 										},
 										&ruleRefExpr{
-											pos:  position{line: 112, col: 53, offset: 3045},
+											pos:  position{line: 132, col: 53, offset: 4135},
 											name: "_",
 										},
 										&ruleRefExpr{
-											pos:  position{line: 112, col: 55, offset: 3047},
+											pos:  position{line: 132, col: 55, offset: 4137},
 											name: "ExprTermPair",
 										},
 									},
@@ -1316,13 +1724,13 @@ var g = &grammar{
 							},
 						},
 						&ruleRefExpr{
-							pos:  position{line: 112, col: 71, offset: 3063},
+							pos:  position{line: 132, col: 71, offset: 4153},
 							name: "_",
 						},
 						&zeroOrOneExpr{
-							pos: position{line: 112, col: 73, offset: 3065},
+							pos: position{line: 132, col: 73, offset: 4155},
 							expr: &litMatcher{
-								pos:        position{line: 112, col: 73, offset: 3065},
+								pos:        position{line: 132, col: 73, offset: 4155},
 								val:        ",",
 								ignoreCase: false,
 							},
@@ -1333,47 +1741,47 @@ var g = &grammar{
 		},
 		{
 			name: "ExprTermList",
-			pos:  position{line: 116, col: 1, offset: 3119},
+			pos:  position{line: 136, col: 1, offset: 4209},
 			expr: &actionExpr{
-				pos: position{line: 116, col: 17, offset: 3135},
+				pos: position{line: 136, col: 17, offset: 4225},
 				run: (*parser).callonExprTermList1,
 				expr: &seqExpr{
-					pos: position{line: 116, col: 17, offset: 3135},
+					pos: position{line: 136, col: 17, offset: 4225},
 					exprs: []interface{}{
 						&labeledExpr{
-							pos:   position{line: 116, col: 17, offset: 3135},
+							pos:   position{line: 136, col: 17, offset: 4225},
 							label: "head",
 							expr: &zeroOrOneExpr{
-								pos: position{line: 116, col: 22, offset: 3140},
+								pos: position{line: 136, col: 22, offset: 4230},
 								expr: &ruleRefExpr{
-									pos:  position{line: 116, col: 22, offset: 3140},
+									pos:  position{line: 136, col: 22, offset: 4230},
 									name: "ExprTerm",
 								},
 							},
 						},
 						&labeledExpr{
-							pos:   position{line: 116, col: 32, offset: 3150},
+							pos:   position{line: 136, col: 32, offset: 4240},
 							label: "tail",
 							expr: &zeroOrMoreExpr{
-								pos: position{line: 116, col: 37, offset: 3155},
+								pos: position{line: 136, col: 37, offset: 4245},
 								expr: &seqExpr{
-									pos: position{line: 116, col: 39, offset: 3157},
+									pos: position{line: 136, col: 39, offset: 4247},
 									exprs: []interface{}{
 										&ruleRefExpr{
-											pos:  position{line: 116, col: 39, offset: 3157},
+											pos:  position{line: 136, col: 39, offset: 4247},
 											name: "_",
 										},
 										&litMatcher{
-											pos:        position{line: 116, col: 41, offset: 3159},
+											pos:        position{line: 136, col: 41, offset: 4249},
 											val:        ",",
 											ignoreCase: false,
 										},
 										&ruleRefExpr{
-											pos:  position{line: 116, col: 45, offset: 3163},
+											pos:  position{line: 136, col: 45, offset: 4253},
 											name: "_",
 										},
 										&ruleRefExpr{
-											pos:  position{line: 116, col: 47, offset: 3165},
+											pos:  position{line: 136, col: 47, offset: 4255},
 											name: "ExprTerm",
 										},
 									},
@@ -1381,13 +1789,13 @@ var g = &grammar{
 							},
 						},
 						&ruleRefExpr{
-							pos:  position{line: 116, col: 59, offset: 3177},
+							pos:  position{line: 136, col: 59, offset: 4267},
 							name: "_",
 						},
 						&zeroOrOneExpr{
-							pos: position{line: 116, col: 61, offset: 3179},
+							pos: position{line: 136, col: 61, offset: 4269},
 							expr: &litMatcher{
-								pos:        position{line: 116, col: 61, offset: 3179},
+								pos:        position{line: 136, col: 61, offset: 4269},
 								val:        ",",
 								ignoreCase: false,
 							},
@@ -1398,39 +1806,39 @@ var g = &grammar{
 		},
 		{
 			name: "ExprTermPair",
-			pos:  position{line: 120, col: 1, offset: 3230},
+			pos:  position{line: 140, col: 1, offset: 4320},
 			expr: &actionExpr{
-				pos: position{line: 120, col: 17, offset: 3246},
+				pos: position{line: 140, col: 17, offset: 4336},
 				run: (*parser).callonExprTermPair1,
 				expr: &seqExpr{
-					pos: position{line: 120, col: 17, offset: 3246},
+					pos: position{line: 140, col: 17, offset: 4336},
 					exprs: []interface{}{
 						&labeledExpr{
-							pos:   position{line: 120, col: 17, offset: 3246},
+							pos:   position{line: 140, col: 17, offset: 4336},
 							label: "key",
 							expr: &ruleRefExpr{
-								pos:  position{line: 120, col: 21, offset: 3250},
+								pos:  position{line: 140, col: 21, offset: 4340},
 								name: "ExprTerm",
 							},
 						},
 						&ruleRefExpr{
-							pos:  position{line: 120, col: 30, offset: 3259},
+							pos:  position{line: 140, col: 30, offset: 4349},
 							name: "_",
 						},
 						&litMatcher{
-							pos:        position{line: 120, col: 32, offset: 3261},
+							pos:        position{line: 140, col: 32, offset: 4351},
 							val:        ":",
 							ignoreCase: false,
 						},
 						&ruleRefExpr{
-							pos:  position{line: 120, col: 36, offset: 3265},
+							pos:  position{line: 140, col: 36, offset: 4355},
 							name: "_",
 						},
 						&labeledExpr{
-							pos:   position{line: 120, col: 38, offset: 3267},
+							pos:   position{line: 140, col: 38, offset: 4357},
 							label: "value",
 							expr: &ruleRefExpr{
-								pos:  position{line: 120, col: 44, offset: 3273},
+								pos:  position{line: 140, col: 44, offset: 4363},
 								name: "ExprTerm",
 							},
 						},
@@ -1438,45 +1846,78 @@ var g = &grammar{
 				},
 			},
 		},
+		{
+			name: "MembershipOperator",
+			pos:  position{line: 144, col: 1, offset: 4417},
+			expr: &actionExpr{
+				pos: position{line: 144, col: 23, offset: 4439},
+				run: (*parser).callonMembershipOperator1,
+				expr: &seqExpr{
+					pos: position{line: 144, col: 23, offset: 4439},
+					exprs: []interface{}{
+						&andCodeExpr{
+							pos: position{line: 144, col: 23, offset: 4439},
+							run: (*parser).callonMembershipOperator3,
+						},
+						&labeledExpr{
+							pos:   position{line: 144, col: 70, offset: 4486},
+							label: "val",
+							expr: &litMatcher{
+								pos:        position{line: 144, col: 74, offset: 4490},
+								val:        "in",
+								ignoreCase: false,
+							},
+						},
+						&notExpr{
+							pos: position{line: 144, col: 79, offset: 4495},
+							expr: &ruleRefExpr{
+								pos:  position{line: 144, col: 80, offset: 4496},
+								name: "VarChar",
+							},
+						},
+					},
+				},
+			},
+		},
 		{
 			name: "RelationOperator",
-			pos:  position{line: 124, col: 1, offset: 3327},
+			pos:  position{line: 148, col: 1, offset: 4566},
 			expr: &actionExpr{
-				pos: position{line: 124, col: 21, offset: 3347},
+				pos: position{line: 148, col: 21, offset: 4586},
 				run: (*parser).callonRelationOperator1,
 				expr: &labeledExpr{
-					pos:   position{line: 124, col: 21, offset: 3347},
+					pos:   position{line: 148, col: 21, offset: 4586},
 					label: "val",
 					expr: &choiceExpr{
-						pos: position{line: 124, col: 26, offset: 3352},
+						pos: position{line: 148, col: 26, offset: 4591},
 						alternatives: []interface{}{
 							&litMatcher{
-								pos:        position{line: 124, col: 26, offset: 3352},
+								pos:        position{line: 148, col: 26, offset: 4591},
 								val:        "==",
 								ignoreCase: false,
 							},
 							&litMatcher{
-								pos:        position{line: 124, col: 33, offset: 3359},
+								pos:        position{line: 148, col: 33, offset: 4598},
 								val:        "!=",
 								ignoreCase: false,
 							},
 							&litMatcher{
-								pos:        position{line: 124, col: 40, offset: 3366},
+								pos:        position{line: 148, col: 40, offset: 4605},
 								val:        "<=",
 								ignoreCase: false,
 							},
 							&litMatcher{
-								pos:        position{line: 124, col: 47, offset: 3373},
+								pos:        position{line: 148, col: 47, offset: 4612},
 								val:        ">=",
 								ignoreCase: false,
 							},
 							&litMatcher{
-								pos:        position{line: 124, col: 54, offset: 3380},
+								pos:        position{line: 148, col: 54, offset: 4619},
 								val:        ">",
 								ignoreCase: false,
 							},
 							&litMatcher{
-								pos:        position{line: 124, col: 60, offset: 3386},
+								pos:        position{line: 148, col: 60, offset: 4625},
 								val:        "<",
 								ignoreCase: false,
 							},
@@ -1487,43 +1928,43 @@ var g = &grammar{
 		},
 		{
 			name: "RelationExpr",
-			pos:  position{line: 128, col: 1, offset: 3453},
+			pos:  position{line: 152, col: 1, offset: 4692},
 			expr: &actionExpr{
-				pos: position{line: 128, col: 17, offset: 3469},
+				pos: position{line: 152, col: 17, offset: 4708},
 				run: (*parser).callonRelationExpr1,
 				expr: &seqExpr{
-					pos: position{line: 128, col: 17, offset: 3469},
+					pos: position{line: 152, col: 17, offset: 4708},
 					exprs: []interface{}{
 						&labeledExpr{
-							pos:   position{line: 128, col: 17, offset: 3469},
+							pos:   position{line: 152, col: 17, offset: 4708},
 							label: "lhs",
 							expr: &ruleRefExpr{
-								pos:  position{line: 128, col: 21, offset: 3473},
+								pos:  position{line: 152, col: 21, offset: 4712},
 								name: "BitwiseOrExpr",
 							},
 						},
 						&labeledExpr{
-							pos:   position{line: 128, col: 35, offset: 3487},
+							pos:   position{line: 152, col: 35, offset: 4726},
 							label: "rest",
 							expr: &zeroOrMoreExpr{
-								pos: position{line: 128, col: 40, offset: 3492},
+								pos: position{line: 152, col: 40, offset: 4731},
 								expr: &seqExpr{
-									pos: position{line: 128, col: 42, offset: 3494},
+									pos: position{line: 152, col: 42, offset: 4733},
 									exprs: []interface{}{
 										&ruleRefExpr{
-											pos:  position{line: 128, col: 42, offset: 3494},
+											pos:  position{line: 152, col: 42, offset: 4733},
 											name: "_",
 										},
 										&ruleRefExpr{
-											pos:  position{line: 128, col: 44, offset: 3496},
+											pos:  position{line: 152, col: 44, offset: 4735},
 											name: "BitwiseOrOperator",
 										},
 										&ruleRefExpr{
-											pos:  position{line: 128, col: 62, offset: 3514},
+											pos:  position{line: 152, col: 62, offset: 4753},
 											name: "_",
 										},
 										&ruleRefExpr{
-											pos:  position{line: 128, col: 64, offset: 3516},
+											pos:  position{line: 152, col: 64, offset: 4755},
 											name: "BitwiseOrExpr",
 										},
 									},
@@ -1536,15 +1977,15 @@ var g = &grammar{
 		},
 		{
 			name: "BitwiseOrOperator",
-			pos:  position{line: 132, col: 1, offset: 3592},
+			pos:  position{line: 156, col: 1, offset: 4831},
 			expr: &actionExpr{
-				pos: position{line: 132, col: 22, offset: 3613},
+				pos: position{line: 156, col: 22, offset: 4852},
 				run: (*parser).callonBitwiseOrOperator1,
 				expr: &labeledExpr{
-					pos:   position{line: 132, col: 22, offset: 3613},
+					pos:   position{line: 156, col: 22, offset: 4852},
 					label: "val",
 					expr: &litMatcher{
-						pos:        position{line: 132, col: 26, offset: 3617},
+						pos:        position{line: 156, col: 26, offset: 4856},
 						val:        "|",
 						ignoreCase: false,
 					},
@@ -1553,43 +1994,43 @@ var g = &grammar{
 		},
 		{
 			name: "BitwiseOrExpr",
-			pos:  position{line: 136, col: 1, offset: 3683},
+			pos:  position{line: 160, col: 1, offset: 4922},
 			expr: &actionExpr{
-				pos: position{line: 136, col: 18, offset: 3700},
+				pos: position{line: 160, col: 18, offset: 4939},
 				run: (*parser).callonBitwiseOrExpr1,
 				expr: &seqExpr{
-					pos: position{line: 136, col: 18, offset: 3700},
+					pos: position{line: 160, col: 18, offset: 4939},
 					exprs: []interface{}{
 						&labeledExpr{
-							pos:   position{line: 136, col: 18, offset: 3700},
+							pos:   position{line: 160, col: 18, offset: 4939},
 							label: "lhs",
 							expr: &ruleRefExpr{
-								pos:  position{line: 136, col: 22, offset: 3704},
+								pos:  position{line: 160, col: 22, offset: 4943},
 								name: "BitwiseAndExpr",
 							},
 						},
 						&labeledExpr{
-							pos:   position{line: 136, col: 37, offset: 3719},
+							pos:   position{line: 160, col: 37, offset: 4958},
 							label: "rest",
 							expr: &zeroOrMoreExpr{
-								pos: position{line: 136, col: 42, offset: 3724},
+								pos: position{line: 160, col: 42, offset: 4963},
 								expr: &seqExpr{
-									pos: position{line: 136, col: 44, offset: 3726},
+									pos: position{line: 160, col: 44, offset: 4965},
 									exprs: []interface{}{
 										&ruleRefExpr{
-											pos:  position{line: 136, col: 44, offset: 3726},
+											pos:  position{line: 160, col: 44, offset: 4965},
 											name: "_",
 										},
 										&ruleRefExpr{
-											pos:  position{line: 136, col: 46, offset: 3728},
+											pos:  position{line: 160, col: 46, offset: 4967},
 											name: "BitwiseAndOperator",
 										},
 										&ruleRefExpr{
-											pos:  position{line: 136, col: 65, offset: 3747},
+											pos:  position{line: 160, col: 65, offset: 4986},
 											name: "_",
 										},
 										&ruleRefExpr{
-											pos:  position{line: 136, col: 67, offset: 3749},
+											pos:  position{line: 160, col: 67, offset: 4988},
 											name: "BitwiseAndExpr",
 										},
 									},
@@ -1602,15 +2043,15 @@ var g = &grammar{
 		},
 		{
 			name: "BitwiseAndOperator",
-			pos:  position{line: 140, col: 1, offset: 3826},
+			pos:  position{line: 164, col: 1, offset: 5065},
 			expr: &actionExpr{
-				pos: position{line: 140, col: 23, offset: 3848},
+				pos: position{line: 164, col: 23, offset: 5087},
 				run: (*parser).callonBitwiseAndOperator1,
 				expr: &labeledExpr{
-					pos:   position{line: 140, col: 23, offset: 3848},
+					pos:   position{line: 164, col: 23, offset: 5087},
 					label: "val",
 					expr: &litMatcher{
-						pos:        position{line: 140, col: 27, offset: 3852},
+						pos:        position{line: 164, col: 27, offset: 5091},
 						val:        "&",
 						ignoreCase: false,
 					},
@@ -1619,43 +2060,43 @@ var g = &grammar{
 		},
 		{
 			name: "BitwiseAndExpr",
-			pos:  position{line: 144, col: 1, offset: 3918},
+			pos:  position{line: 168, col: 1, offset: 5157},
 			expr: &actionExpr{
-				pos: position{line: 144, col: 19, offset: 3936},
+				pos: position{line: 168, col: 19, offset: 5175},
 				run: (*parser).callonBitwiseAndExpr1,
 				expr: &seqExpr{
-					pos: position{line: 144, col: 19, offset: 3936},
+					pos: position{line: 168, col: 19, offset: 5175},
 					exprs: []interface{}{
 						&labeledExpr{
-							pos:   position{line: 144, col: 19, offset: 3936},
+							pos:   position{line: 168, col: 19, offset: 5175},
 							label: "lhs",
 							expr: &ruleRefExpr{
-								pos:  position{line: 144, col: 23, offset: 3940},
+								pos:  position{line: 168, col: 23, offset: 5179},
 								name: "ArithExpr",
 							},
 						},
 						&labeledExpr{
-							pos:   position{line: 144, col: 33, offset: 3950},
+							pos:   position{line: 168, col: 33, offset: 5189},
 							label: "rest",
 							expr: &zeroOrMoreExpr{
-								pos: position{line: 144, col: 38, offset: 3955},
+								pos: position{line: 168, col: 38, offset: 5194},
 								expr: &seqExpr{
-									pos: position{line: 144, col: 40, offset: 3957},
+									pos: position{line: 168, col: 40, offset: 5196},
 									exprs: []interface{}{
 										&ruleRefExpr{
-											pos:  position{line: 144, col: 40, offset: 3957},
+											pos:  position{line: 168, col: 40, offset: 5196},
 											name: "_",
 										},
 										&ruleRefExpr{
-											pos:  position{line: 144, col: 42, offset: 3959},
+											pos:  position{line: 168, col: 42, offset: 5198},
 											name: "ArithOperator",
 										},
 										&ruleRefExpr{
-											pos:  position{line: 144, col: 56, offset: 3973},
+											pos:  position{line: 168, col: 56, offset: 5212},
 											name: "_",
 										},
 										&ruleRefExpr{
-											pos:  position{line: 144, col: 58, offset: 3975},
+											pos:  position{line: 168, col: 58, offset: 5214},
 											name: "ArithExpr",
 										},
 									},
@@ -1668,23 +2109,23 @@ var g = &grammar{
 		},
 		{
 			name: "ArithOperator",
-			pos:  position{line: 148, col: 1, offset: 4047},
+			pos:  position{line: 172, col: 1, offset: 5286},
 			expr: &actionExpr{
-				pos: position{line: 148, col: 18, offset: 4064},
+				pos: position{line: 172, col: 18, offset: 5303},
 				run: (*parser).callonArithOperator1,
 				expr: &labeledExpr{
-					pos:   position{line: 148, col: 18, offset: 4064},
+					pos:   position{line: 172, col: 18, offset: 5303},
 					label: "val",
 					expr: &choiceExpr{
-						pos: position{line: 148, col: 23, offset: 4069},
+						pos: position{line: 172, col: 23, offset: 5308},
 						alternatives: []interface{}{
 							&litMatcher{
-								pos:        position{line: 148, col: 23, offset: 4069},
+								pos:        position{line: 172, col: 23, offset: 5308},
 								val:        "+",
 								ignoreCase: false,
 							},
 							&litMatcher{
-								pos:        position{line: 148, col: 29, offset: 4075},
+								pos:        position{line: 172, col: 29, offset: 5314},
 								val:        "-",
 								ignoreCase: false,
 							},
@@ -1695,43 +2136,43 @@ var g = &grammar{
 		},
 		{
 			name: "ArithExpr",
-			pos:  position{line: 152, col: 1, offset: 4142},
+			pos:  position{line: 176, col: 1, offset: 5381},
 			expr: &actionExpr{
-				pos: position{line: 152, col: 14, offset: 4155},
+				pos: position{line: 176, col: 14, offset: 5394},
 				run: (*parser).callonArithExpr1,
 				expr: &seqExpr{
-					pos: position{line: 152, col: 14, offset: 4155},
+					pos: position{line: 176, col: 14, offset: 5394},
 					exprs: []interface{}{
 						&labeledExpr{
-							pos:   position{line: 152, col: 14, offset: 4155},
+							pos:   position{line: 176, col: 14, offset: 5394},
 							label: "lhs",
 							expr: &ruleRefExpr{
-								pos:  position{line: 152, col: 18, offset: 4159},
+								pos:  position{line: 176, col: 18, offset: 5398},
 								name: "FactorExpr",
 							},
 						},
 						&labeledExpr{
-							pos:   position{line: 152, col: 29, offset: 4170},
+							pos:   position{line: 176, col: 29, offset: 5409},
 							label: "rest",
 							expr: &zeroOrMoreExpr{
-								pos: position{line: 152, col: 34, offset: 4175},
+								pos: position{line: 176, col: 34, offset: 5414},
 								expr: &seqExpr{
-									pos: position{line: 152, col: 36, offset: 4177},
+									pos: position{line: 176, col: 36, offset: 5416},
 									exprs: []interface{}{
 										&ruleRefExpr{
-											pos:  position{line: 152, col: 36, offset: 4177},
+											pos:  position{line: 176, col: 36, offset: 5416},
 											name: "_",
 										},
 										&ruleRefExpr{
-											pos:  position{line: 152, col: 38, offset: 4179},
+											pos:  position{line: 176, col: 38, offset: 5418},
 											name: "FactorOperator",
 										},
 										&ruleRefExpr{
-											pos:  position{line: 152, col: 53, offset: 4194},
+											pos:  position{line: 176, col: 53, offset: 5433},
 											name: "_",
 										},
 										&ruleRefExpr{
-											pos:  position{line: 152, col: 55, offset: 4196},
+											pos:  position{line: 176, col: 55, offset: 5435},
 											name: "FactorExpr",
 										},
 									},
@@ -1744,28 +2185,28 @@ var g = &grammar{
 		},
 		{
 			name: "FactorOperator",
-			pos:  position{line: 156, col: 1, offset: 4270},
+			pos:  position{line: 180, col: 1, offset: 5509},
 			expr: &actionExpr{
-				pos: position{line: 156, col: 19, offset: 4288},
+				pos: position{line: 180, col: 19, offset: 5527},
 				run: (*parser).callonFactorOperator1,
 				expr: &labeledExpr{
-					pos:   position{line: 156, col: 19, offset: 4288},
+					pos:   position{line: 180, col: 19, offset: 5527},
 					label: "val",
 					expr: &choiceExpr{
-						pos: position{line: 156, col: 24, offset: 4293},
+						pos: position{line: 180, col: 24, offset: 5532},
 						alternatives: []interface{}{
 							&litMatcher{
-								pos:        position{line: 156, col: 24, offset: 4293},
+								pos:        position{line: 180, col: 24, offset: 5532},
 								val:        "*",
 								ignoreCase: false,
 							},
 							&litMatcher{
-								pos:        position{line: 156, col: 30, offset: 4299},
+								pos:        position{line: 180, col: 30, offset: 5538},
 								val:        "/",
 								ignoreCase: false,
 							},
 							&litMatcher{
-								pos:        position{line: 156, col: 36, offset: 4305},
+								pos:        position{line: 180, col: 36, offset: 5544},
 								val:        "%",
 								ignoreCase: false,
 							},
@@ -1776,39 +2217,39 @@ var g = &grammar{
 		},
 		{
 			name: "FactorExpr",
-			pos:  position{line: 160, col: 1, offset: 4371},
+			pos:  position{line: 184, col: 1, offset: 5610},
 			expr: &choiceExpr{
-				pos: position{line: 160, col: 15, offset: 4385},
+				pos: position{line: 184, col: 15, offset: 5624},
 				alternatives: []interface{}{
 					&actionExpr{
-						pos: position{line: 160, col: 15, offset: 4385},
+						pos: position{line: 184, col: 15, offset: 5624},
 						run: (*parser).callonFactorExpr2,
 						expr: &seqExpr{
-							pos: position{line: 160, col: 17, offset: 4387},
+							pos: position{line: 184, col: 17, offset: 5626},
 							exprs: []interface{}{
 								&litMatcher{
-									pos:        position{line: 160, col: 17, offset: 4387},
+									pos:        position{line: 184, col: 17, offset: 5626},
 									val:        "(",
 									ignoreCase: false,
 								},
 								&ruleRefExpr{
-									pos:  position{line: 160, col: 21, offset: 4391},
+									pos:  position{line: 184, col: 21, offset: 5630},
 									name: "_",
 								},
 								&labeledExpr{
-									pos:   position{line: 160, col: 23, offset: 4393},
+									pos:   position{line: 184, col: 23, offset: 5632},
 									label: "expr",
 									expr: &ruleRefExpr{
-										pos:  position{line: 160, col: 28, offset: 4398},
+										pos:  position{line: 184, col: 28, offset: 5637},
 										name: "ExprTerm",
 									},
 								},
 								&ruleRefExpr{
-									pos:  position{line: 160, col: 37, offset: 4407},
+									pos:  position{line: 184, col: 37, offset: 5646},
 									name: "_",
 								},
 								&litMatcher{
-									pos:        position{line: 160, col: 39, offset: 4409},
+									pos:        position{line: 184, col: 39, offset: 5648},
 									val:        ")",
 									ignoreCase: false,
 								},
@@ -1816,13 +2257,13 @@ var g = &grammar{
 						},
 					},
 					&actionExpr{
-						pos: position{line: 162, col: 5, offset: 4442},
+						pos: position{line: 186, col: 5, offset: 5681},
 						run: (*parser).callonFactorExpr10,
 						expr: &labeledExpr{
-							pos:   position{line: 162, col: 5, offset: 4442},
+							pos:   position{line: 186, col: 5, offset: 5681},
 							label: "term",
 							expr: &ruleRefExpr{
-								pos:  position{line: 162, col: 10, offset: 4447},
+								pos:  position{line: 186, col: 10, offset: 5686},
 								name: "Term",
 							},
 						},
@@ -1832,53 +2273,53 @@ var g = &grammar{
 		},
 		{
 			name: "Call",
-			pos:  position{line: 166, col: 1, offset: 4478},
+			pos:  position{line: 190, col: 1, offset: 5717},
 			expr: &actionExpr{
-				pos: position{line: 166, col: 9, offset: 4486},
+				pos: position{line: 190, col: 9, offset: 5725},
 				run: (*parser).callonCall1,
 				expr: &seqExpr{
-					pos: position{line: 166, col: 9, offset: 4486},
+					pos: position{line: 190, col: 9, offset: 5725},
 					exprs: []interface{}{
 						&labeledExpr{
-							pos:   position{line: 166, col: 9, offset: 4486},
+							pos:   position{line: 190, col: 9, offset: 5725},
 							label: "operator",
 							expr: &choiceExpr{
-								pos: position{line: 166, col: 19, offset: 4496},
+								pos: position{line: 190, col: 19, offset: 5735},
 								alternatives: []interface{}{
 									&ruleRefExpr{
-										pos:  position{line: 166, col: 19, offset: 4496},
+										pos:  position{line: 190, col: 19, offset: 5735},
 										name: "Ref",
 									},
 									&ruleRefExpr{
-										pos:  position{line: 166, col: 25, offset: 4502},
-										name: "Var",
+										pos:  position{line: 190, col: 25, offset: 5741},
+										name: "FutureKeywordOrVar",
 									},
 								},
 							},
 						},
 						&litMatcher{
-							pos:        position{line: 166, col: 30, offset: 4507},
+							pos:        position{line: 190, col: 45, offset: 5761},
 							val:        "(",
 							ignoreCase: false,
 						},
 						&ruleRefExpr{
-							pos:  position{line: 166, col: 34, offset: 4511},
+							pos:  position{line: 190, col: 49, offset: 5765},
 							name: "_",
 						},
 						&labeledExpr{
-							pos:   position{line: 166, col: 36, offset: 4513},
+							pos:   position{line: 190, col: 51, offset: 5767},
 							label: "args",
 							expr: &ruleRefExpr{
-								pos:  position{line: 166, col: 41, offset: 4518},
+								pos:  position{line: 190, col: 56, offset: 5772},
 								name: "ExprTermList",
 							},
 						},
 						&ruleRefExpr{
-							pos:  position{line: 166, col: 54, offset: 4531},
+							pos:  position{line: 190, col: 69, offset: 5785},
 							name: "_",
 						},
 						&litMatcher{
-							pos:        position{line: 166, col: 56, offset: 4533},
+							pos:        position{line: 190, col: 71, offset: 5787},
 							val:        ")",
 							ignoreCase: false,
 						},
@@ -1888,38 +2329,38 @@ var g = &grammar{
 		},
 		{
 			name: "Term",
-			pos:  position{line: 170, col: 1, offset: 4598},
+			pos:  position{line: 194, col: 1, offset: 5852},
 			expr: &actionExpr{
-				pos: position{line: 170, col: 9, offset: 4606},
+				pos: position{line: 194, col: 9, offset: 5860},
 				run: (*parser).callonTerm1,
 				expr: &labeledExpr{
-					pos:   position{line: 170, col: 9, offset: 4606},
+					pos:   position{line: 194, col: 9, offset: 5860},
 					label: "val",
 					expr: &choiceExpr{
-						pos: position{line: 170, col: 15, offset: 4612},
+						pos: position{line: 194, col: 15, offset: 5866},
 						alternatives: []interface{}{
 							&ruleRefExpr{
-								pos:  position{line: 170, col: 15, offset: 4612},
+								pos:  position{line: 194, col: 15, offset: 5866},
 								name: "Comprehension",
 							},
 							&ruleRefExpr{
-								pos:  position{line: 170, col: 31, offset: 4628},
+								pos:  position{line: 194, col: 31, offset: 5882},
 								name: "Composite",
 							},
 							&ruleRefExpr{
-								pos:  position{line: 170, col: 43, offset: 4640},
+								pos:  position{line: 194, col: 43, offset: 5894},
 								name: "Scalar",
 							},
 							&ruleRefExpr{
-								pos:  position{line: 170, col: 52, offset: 4649},
+								pos:  position{line: 194, col: 52, offset: 5903},
 								name: "Call",
 							},
 							&ruleRefExpr{
-								pos:  position{line: 170, col: 59, offset: 4656},
+								pos:  position{line: 194, col: 59, offset: 5910},
 								name: "Ref",
 							},
 							&ruleRefExpr{
-								pos:  position{line: 170, col: 65, offset: 4662},
+								pos:  position{line: 194, col: 65, offset: 5916},
 								name: "Var",
 							},
 						},
@@ -1929,39 +2370,39 @@ var g = &grammar{
 		},
 		{
 			name: "TermPair",
-			pos:  position{line: 174, col: 1, offset: 4693},
+			pos:  position{line: 198, col: 1, offset: 5947},
 			expr: &actionExpr{
-				pos: position{line: 174, col: 13, offset: 4705},
+				pos: position{line: 198, col: 13, offset: 5959},
 				run: (*parser).callonTermPair1,
 				expr: &seqExpr{
-					pos: position{line: 174, col: 13, offset: 4705},
+					pos: position{line: 198, col: 13, offset: 5959},
 					exprs: []interface{}{
 						&labeledExpr{
-							pos:   position{line: 174, col: 13, offset: 4705},
+							pos:   position{line: 198, col: 13, offset: 5959},
 							label: "key",
 							expr: &ruleRefExpr{
-								pos:  position{line: 174, col: 17, offset: 4709},
+								pos:  position{line: 198, col: 17, offset: 5963},
 								name: "Term",
 							},
 						},
 						&ruleRefExpr{
-							pos:  position{line: 174, col: 22, offset: 4714},
+							pos:  position{line: 198, col: 22, offset: 5968},
 							name: "_",
 						},
 						&litMatcher{
-							pos:        position{line: 174, col: 24, offset: 4716},
+							pos:        position{line: 198, col: 24, offset: 5970},
 							val:        ":",
 							ignoreCase: false,
 						},
 						&ruleRefExpr{
-							pos:  position{line: 174, col: 28, offset: 4720},
+							pos:  position{line: 198, col: 28, offset: 5974},
 							name: "_",
 						},
 						&labeledExpr{
-							pos:   position{line: 174, col: 30, offset: 4722},
+							pos:   position{line: 198, col: 30, offset: 5976},
 							label: "value",
 							expr: &ruleRefExpr{
-								pos:  position{line: 174, col: 36, offset: 4728},
+								pos:  position{line: 198, col: 36, offset: 5982},
 								name: "Term",
 							},
 						},
@@ -1971,20 +2412,20 @@ var g = &grammar{
 		},
 		{
 			name: "Comprehension",
-			pos:  position{line: 178, col: 1, offset: 4778},
+			pos:  position{line: 202, col: 1, offset: 6032},
 			expr: &choiceExpr{
-				pos: position{line: 178, col: 18, offset: 4795},
+				pos: position{line: 202, col: 18, offset: 6049},
 				alternatives: []interface{}{
 					&ruleRefExpr{
-						pos:  position{line: 178, col: 18, offset: 4795},
+						pos:  position{line: 202, col: 18, offset: 6049},
 						name: "ArrayComprehension",
 					},
 					&ruleRefExpr{
-						pos:  position{line: 178, col: 39, offset: 4816},
+						pos:  position{line: 202, col: 39, offset: 6070},
 						name: "ObjectComprehension",
 					},
 					&ruleRefExpr{
-						pos:  position{line: 178, col: 61, offset: 4838},
+						pos:  position{line: 202, col: 61, offset: 6092},
 						name: "SetComprehension",
 					},
 				},
@@ -1992,57 +2433,57 @@ var g = &grammar{
 		},
 		{
 			name: "ArrayComprehension",
-			pos:  position{line: 180, col: 1, offset: 4856},
+			pos:  position{line: 204, col: 1, offset: 6110},
 			expr: &actionExpr{
-				pos: position{line: 180, col: 23, offset: 4878},
+				pos: position{line: 204, col: 23, offset: 6132},
 				run: (*parser).callonArrayComprehension1,
 				expr: &seqExpr{
-					pos: position{line: 180, col: 23, offset: 4878},
+					pos: position{line: 204, col: 23, offset: 6132},
 					exprs: []interface{}{
 						&litMatcher{
-							pos:        position{line: 180, col: 23, offset: 4878},
+							pos:        position{line: 204, col: 23, offset: 6132},
 							val:        "[",
 							ignoreCase: false,
 						},
 						&ruleRefExpr{
-							pos:  position{line: 180, col: 27, offset: 4882},
+							pos:  position{line: 204, col: 27, offset: 6136},
 							name: "_",
 						},
 						&labeledExpr{
-							pos:   position{line: 180, col: 29, offset: 4884},
+							pos:   position{line: 204, col: 29, offset: 6138},
 							label: "head",
 							expr: &ruleRefExpr{
-								pos:  position{line: 180, col: 34, offset: 4889},
+								pos:  position{line: 204, col: 34, offset: 6143},
 								name: "Term",
 							},
 						},
 						&ruleRefExpr{
-							pos:  position{line: 180, col: 39, offset: 4894},
+							pos:  position{line: 204, col: 39, offset: 6148},
 							name: "_",
 						},
 						&litMatcher{
-							pos:        position{line: 180, col: 41, offset: 4896},
+							pos:        position{line: 204, col: 41, offset: 6150},
 							val:        "|",
 							ignoreCase: false,
 						},
 						&ruleRefExpr{
-							pos:  position{line: 180, col: 45, offset: 4900},
+							pos:  position{line: 204, col: 45, offset: 6154},
 							name: "_",
 						},
 						&labeledExpr{
-							pos:   position{line: 180, col: 47, offset: 4902},
+							pos:   position{line: 204, col: 47, offset: 6156},
 							label: "body",
 							expr: &ruleRefExpr{
-								pos:  position{line: 180, col: 52, offset: 4907},
+								pos:  position{line: 204, col: 52, offset: 6161},
 								name: "WhitespaceBody",
 							},
 						},
 						&ruleRefExpr{
-							pos:  position{line: 180, col: 67, offset: 4922},
+							pos:  position{line: 204, col: 67, offset: 6176},
 							name: "_",
 						},
 						&litMatcher{
-							pos:        position{line: 180, col: 69, offset: 4924},
+							pos:        position{line: 204, col: 69, offset: 6178},
 							val:        "]",
 							ignoreCase: false,
 						},
@@ -2052,57 +2493,57 @@ var g = &grammar{
 		},
 		{
 			name: "ObjectComprehension",
-			pos:  position{line: 184, col: 1, offset: 4999},
+			pos:  position{line: 208, col: 1, offset: 6253},
 			expr: &actionExpr{
-				pos: position{line: 184, col: 24, offset: 5022},
+				pos: position{line: 208, col: 24, offset: 6276},
 				run: (*parser).callonObjectComprehension1,
 				expr: &seqExpr{
-					pos: position{line: 184, col: 24, offset: 5022},
+					pos: position{line: 208, col: 24, offset: 6276},
 					exprs: []interface{}{
 						&litMatcher{
-							pos:        position{line: 184, col: 24, offset: 5022},
+							pos:        position{line: 208, col: 24, offset: 6276},
 							val:        "{",
 							ignoreCase: false,
 						},
 						&ruleRefExpr{
-							pos:  position{line: 184, col: 28, offset: 5026},
+							pos:  position{line: 208, col: 28, offset: 6280},
 							name: "_",
 						},
 						&labeledExpr{
-							pos:   position{line: 184, col: 30, offset: 5028},
+							pos:   position{line: 208, col: 30, offset: 6282},
 							label: "head",
 							expr: &ruleRefExpr{
-								pos:  position{line: 184, col: 35, offset: 5033},
+								pos:  position{line: 208, col: 35, offset: 6287},
 								name: "TermPair",
 							},
 						},
 						&ruleRefExpr{
-							pos:  position{line: 184, col: 45, offset: 5043},
+							pos:  position{line: 208, col: 45, offset: 6297},
 							name: "_",
 						},
 						&litMatcher{
-							pos:        position{line: 184, col: 47, offset: 5045},
+							pos:        position{line: 208, col: 47, offset: 6299},
 							val:        "|",
 							ignoreCase: false,
 						},
 						&ruleRefExpr{
-							pos:  position{line: 184, col: 51, offset: 5049},
+							pos:  position{line: 208, col: 51, offset: 6303},
 							name: "_",
 						},
 						&labeledExpr{
-							pos:   position{line: 184, col: 53, offset: 5051},
+							pos:   position{line: 208, col: 53, offset: 6305},
 							label: "body",
 							expr: &ruleRefExpr{
-								pos:  position{line: 184, col: 58, offset: 5056},
+								pos:  position{line: 208, col: 58, offset: 6310},
 								name: "WhitespaceBody",
 							},
 						},
 						&ruleRefExpr{
-							pos:  position{line: 184, col: 73, offset: 5071},
+							pos:  position{line: 208, col: 73, offset: 6325},
 							name: "_",
 						},
 						&litMatcher{
-							pos:        position{line: 184, col: 75, offset: 5073},
+							pos:        position{line: 208, col: 75, offset: 6327},
 							val:        "}",
 							ignoreCase: false,
 						},
@@ -2112,57 +2553,57 @@ var g = &grammar{
 		},
 		{
 			name: "SetComprehension",
-			pos:  position{line: 188, col: 1, offset: 5149},
+			pos:  position{line: 212, col: 1, offset: 6403},
 			expr: &actionExpr{
-				pos: position{line: 188, col: 21, offset: 5169},
+				pos: position{line: 212, col: 21, offset: 6423},
 				run: (*parser).callonSetComprehension1,
 				expr: &seqExpr{
-					pos: position{line: 188, col: 21, offset: 5169},
+					pos: position{line: 212, col: 21, offset: 6423},
 					exprs: []interface{}{
 						&litMatcher{
-							pos:        position{line: 188, col: 21, offset: 5169},
+							pos:        position{line: 212, col: 21, offset: 6423},
 							val:        "{",
 							ignoreCase: false,
 						},
 						&ruleRefExpr{
-							pos:  position{line: 188, col: 25, offset: 5173},
+							pos:  position{line: 212, col: 25, offset: 6427},
 							name: "_",
 						},
 						&labeledExpr{
-							pos:   position{line: 188, col: 27, offset: 5175},
+							pos:   position{line: 212, col: 27, offset: 6429},
 							label: "head",
 							expr: &ruleRefExpr{
-								pos:  position{line: 188, col: 32, offset: 5180},
+								pos:  position{line: 212, col: 32, offset: 6434},
 								name: "Term",
 							},
 						},
 						&ruleRefExpr{
-							pos:  position{line: 188, col: 37, offset: 5185},
+							pos:  position{line: 212, col: 37, offset: 6439},
 							name: "_",
 						},
 						&litMatcher{
-							pos:        position{line: 188, col: 39, offset: 5187},
+							pos:        position{line: 212, col: 39, offset: 6441},
 							val:        "|",
 							ignoreCase: false,
 						},
 						&ruleRefExpr{
-							pos:  position{line: 188, col: 43, offset: 5191},
+							pos:  position{line: 212, col: 43, offset: 6445},
 							name: "_",
 						},
 						&labeledExpr{
-							pos:   position{line: 188, col: 45, offset: 5193},
+							pos:   position{line: 212, col: 45, offset: 6447},
 							label: "body",
 							expr: &ruleRefExpr{
-								pos:  position{line: 188, col: 50, offset: 5198},
+								pos:  position{line: 212, col: 50, offset: 6452},
 								name: "WhitespaceBody",
 							},
 						},
 						&ruleRefExpr{
-							pos:  position{line: 188, col: 65, offset: 5213},
+							pos:  position{line: 212, col: 65, offset: 6467},
 							name: "_",
 						},
 						&litMatcher{
-							pos:        position{line: 188, col: 67, offset: 5215},
+							pos:        position{line: 212, col: 67, offset: 6469},
 							val:        "}",
 							ignoreCase: false,
 						},
@@ -2172,20 +2613,20 @@ var g = &grammar{
 		},
 		{
 			name: "Composite",
-			pos:  position{line: 192, col: 1, offset: 5288},
+			pos:  position{line: 216, col: 1, offset: 6542},
 			expr: &choiceExpr{
-				pos: position{line: 192, col: 14, offset: 5301},
+				pos: position{line: 216, col: 14, offset: 6555},
 				alternatives: []interface{}{
 					&ruleRefExpr{
-						pos:  position{line: 192, col: 14, offset: 5301},
+						pos:  position{line: 216, col: 14, offset: 6555},
 						name: "Object",
 					},
 					&ruleRefExpr{
-						pos:  position{line: 192, col: 23, offset: 5310},
+						pos:  position{line: 216, col: 23, offset: 6564},
 						name: "Array",
 					},
 					&ruleRefExpr{
-						pos:  position{line: 192, col: 31, offset: 5318},
+						pos:  position{line: 216, col: 31, offset: 6572},
 						name: "Set",
 					},
 				},
@@ -2193,24 +2634,24 @@ var g = &grammar{
 		},
 		{
 			name: "Scalar",
-			pos:  position{line: 194, col: 1, offset: 5323},
+			pos:  position{line: 218, col: 1, offset: 6577},
 			expr: &choiceExpr{
-				pos: position{line: 194, col: 11, offset: 5333},
+				pos: position{line: 218, col: 11, offset: 6587},
 				alternatives: []interface{}{
 					&ruleRefExpr{
-						pos:  position{line: 194, col: 11, offset: 5333},
+						pos:  position{line: 218, col: 11, offset: 6587},
 						name: "Number",
 					},
 					&ruleRefExpr{
-						pos:  position{line: 194, col: 20, offset: 5342},
+						pos:  position{line: 218, col: 20, offset: 6596},
 						name: "String",
 					},
 					&ruleRefExpr{
-						pos:  position{line: 194, col: 29, offset: 5351},
+						pos:  position{line: 218, col: 29, offset: 6605},
 						name: "Bool",
 					},
 					&ruleRefExpr{
-						pos:  position{line: 194, col: 36, offset: 5358},
+						pos:  position{line: 218, col: 36, offset: 6612},
 						name: "Null",
 					},
 				},
@@ -2218,36 +2659,36 @@ var g = &grammar{
 		},
 		{
 			name: "Object",
-			pos:  position{line: 196, col: 1, offset: 5364},
+			pos:  position{line: 220, col: 1, offset: 6618},
 			expr: &actionExpr{
-				pos: position{line: 196, col: 11, offset: 5374},
+				pos: position{line: 220, col: 11, offset: 6628},
 				run: (*parser).callonObject1,
 				expr: &seqExpr{
-					pos: position{line: 196, col: 11, offset: 5374},
+					pos: position{line: 220, col: 11, offset: 6628},
 					exprs: []interface{}{
 						&litMatcher{
-							pos:        position{line: 196, col: 11, offset: 5374},
+							pos:        position{line: 220, col: 11, offset: 6628},
 							val:        "{",
 							ignoreCase: false,
 						},
 						&ruleRefExpr{
-							pos:  position{line: 196, col: 15, offset: 5378},
+							pos:  position{line: 220, col: 15, offset: 6632},
 							name: "_",
 						},
 						&labeledExpr{
-							pos:   position{line: 196, col: 17, offset: 5380},
+							pos:   position{line: 220, col: 17, offset: 6634},
 							label: "list",
 							expr: &ruleRefExpr{
-								pos:  position{line: 196, col: 22, offset: 5385},
+								pos:  position{line: 220, col: 22, offset: 6639},
 								name: "ExprTermPairList",
 							},
 						},
 						&ruleRefExpr{
-							pos:  position{line: 196, col: 39, offset: 5402},
+							pos:  position{line: 220, col: 39, offset: 6656},
 							name: "_",
 						},
 						&litMatcher{
-							pos:        position{line: 196, col: 41, offset: 5404},
+							pos:        position{line: 220, col: 41, offset: 6658},
 							val:        "}",
 							ignoreCase: false,
 						},
@@ -2257,36 +2698,36 @@ var g = &grammar{
 		},
 		{
 			name: "Array",
-			pos:  position{line: 200, col: 1, offset: 5461},
+			pos:  position{line: 224, col: 1, offset: 6715},
 			expr: &actionExpr{
-				pos: position{line: 200, col: 10, offset: 5470},
+				pos: position{line: 224, col: 10, offset: 6724},
 				run: (*parser).callonArray1,
 				expr: &seqExpr{
-					pos: position{line: 200, col: 10, offset: 5470},
+					pos: position{line: 224, col: 10, offset: 6724},
 					exprs: []interface{}{
 						&litMatcher{
-							pos:        position{line: 200, col: 10, offset: 5470},
+							pos:        position{line: 224, col: 10, offset: 6724},
 							val:        "[",
 							ignoreCase: false,
 						},
 						&ruleRefExpr{
-							pos:  position{line: 200, col: 14, offset: 5474},
+							pos:  position{line: 224, col: 14, offset: 6728},
 							name: "_",
 						},
 						&labeledExpr{
-							pos:   position{line: 200, col: 16, offset: 5476},
+							pos:   position{line: 224, col: 16, offset: 6730},
 							label: "list",
 							expr: &ruleRefExpr{
-								pos:  position{line: 200, col: 21, offset: 5481},
+								pos:  position{line: 224, col: 21, offset: 6735},
 								name: "ExprTermList",
 							},
 						},
 						&ruleRefExpr{
-							pos:  position{line: 200, col: 34, offset: 5494},
+							pos:  position{line: 224, col: 34, offset: 6748},
 							name: "_",
 						},
 						&litMatcher{
-							pos:        position{line: 200, col: 36, offset: 5496},
+							pos:        position{line: 224, col: 36, offset: 6750},
 							val:        "]",
 							ignoreCase: false,
 						},
@@ -2296,16 +2737,16 @@ var g = &grammar{
 		},
 		{
 			name: "Set",
-			pos:  position{line: 204, col: 1, offset: 5552},
+			pos:  position{line: 228, col: 1, offset: 6806},
 			expr: &choiceExpr{
-				pos: position{line: 204, col: 8, offset: 5559},
+				pos: position{line: 228, col: 8, offset: 6813},
 				alternatives: []interface{}{
 					&ruleRefExpr{
-						pos:  position{line: 204, col: 8, offset: 5559},
+						pos:  position{line: 228, col: 8, offset: 6813},
 						name: "SetEmpty",
 					},
 					&ruleRefExpr{
-						pos:  position{line: 204, col: 19, offset: 5570},
+						pos:  position{line: 228, col: 19, offset: 6824},
 						name: "SetNonEmpty",
 					},
 				},
@@ -2313,24 +2754,24 @@ var g = &grammar{
 		},
 		{
 			name: "SetEmpty",
-			pos:  position{line: 206, col: 1, offset: 5583},
+			pos:  position{line: 230, col: 1, offset: 6837},
 			expr: &actionExpr{
-				pos: position{line: 206, col: 13, offset: 5595},
+				pos: position{line: 230, col: 13, offset: 6849},
 				run: (*parser).callonSetEmpty1,
 				expr: &seqExpr{
-					pos: position{line: 206, col: 13, offset: 5595},
+					pos: position{line: 230, col: 13, offset: 6849},
 					exprs: []interface{}{
 						&litMatcher{
-							pos:        position{line: 206, col: 13, offset: 5595},
+							pos:        position{line: 230, col: 13, offset: 6849},
 							val:        "set(",
 							ignoreCase: false,
 						},
 						&ruleRefExpr{
-							pos:  position{line: 206, col: 20, offset: 5602},
+							pos:  position{line: 230, col: 20, offset: 6856},
 							name: "_",
 						},
 						&litMatcher{
-							pos:        position{line: 206, col: 22, offset: 5604},
+							pos:        position{line: 230, col: 22, offset: 6858},
 							val:        ")",
 							ignoreCase: false,
 						},
@@ -2340,36 +2781,36 @@ var g = &grammar{
 		},
 		{
 			name: "SetNonEmpty",
-			pos:  position{line: 211, col: 1, offset: 5681},
+			pos:  position{line: 235, col: 1, offset: 6935},
 			expr: &actionExpr{
-				pos: position{line: 211, col: 16, offset: 5696},
+				pos: position{line: 235, col: 16, offset: 6950},
 				run: (*parser).callonSetNonEmpty1,
 				expr: &seqExpr{
-					pos: position{line: 211, col: 16, offset: 5696},
+					pos: position{line: 235, col: 16, offset: 6950},
 					exprs: []interface{}{
 						&litMatcher{
-							pos:        position{line: 211, col: 16, offset: 5696},
+							pos:        position{line: 235, col: 16, offset: 6950},
 							val:        "{",
 							ignoreCase: false,
 						},
 						&ruleRefExpr{
-							pos:  position{line: 211, col: 20, offset: 5700},
+							pos:  position{line: 235, col: 20, offset: 6954},
 							name: "_",
 						},
 						&labeledExpr{
-							pos:   position{line: 211, col: 22, offset: 5702},
+							pos:   position{line: 235, col: 22, offset: 6956},
 							label: "list",
 							expr: &ruleRefExpr{
-								pos:  position{line: 211, col: 27, offset: 5707},
+								pos:  position{line: 235, col: 27, offset: 6961},
 								name: "ExprTermList",
 							},
 						},
 						&ruleRefExpr{
-							pos:  position{line: 211, col: 40, offset: 5720},
+							pos:  position{line: 235, col: 40, offset: 6974},
 							name: "_",
 						},
 						&litMatcher{
-							pos:        position{line: 211, col: 42, offset: 5722},
+							pos:        position{line: 235, col: 42, offset: 6976},
 							val:        "}",
 							ignoreCase: false,
 						},
@@ -2379,28 +2820,28 @@ var g = &grammar{
 		},
 		{
 			name: "Ref",
-			pos:  position{line: 215, col: 1, offset: 5776},
+			pos:  position{line: 239, col: 1, offset: 7030},
 			expr: &actionExpr{
-				pos: position{line: 215, col: 8, offset: 5783},
+				pos: position{line: 239, col: 8, offset: 7037},
 				run: (*parser).callonRef1,
 				expr: &seqExpr{
-					pos: position{line: 215, col: 8, offset: 5783},
+					pos: position{line: 239, col: 8, offset: 7037},
 					exprs: []interface{}{
 						&labeledExpr{
-							pos:   position{line: 215, col: 8, offset: 5783},
+							pos:   position{line: 239, col: 8, offset: 7037},
 							label: "head",
 							expr: &ruleRefExpr{
-								pos:  position{line: 215, col: 13, offset: 5788},
+								pos:  position{line: 239, col: 13, offset: 7042},
 								name: "Var",
 							},
 						},
 						&labeledExpr{
-							pos:   position{line: 215, col: 17, offset: 5792},
+							pos:   position{line: 239, col: 17, offset: 7046},
 							label: "rest",
 							expr: &oneOrMoreExpr{
-								pos: position{line: 215, col: 22, offset: 5797},
+								pos: position{line: 239, col: 22, offset: 7051},
 								expr: &ruleRefExpr{
-									pos:  position{line: 215, col: 22, offset: 5797},
+									pos:  position{line: 239, col: 22, offset: 7051},
 									name: "RefOperand",
 								},
 							},
@@ -2411,16 +2852,16 @@ var g = &grammar{
 		},
 		{
 			name: "RefOperand",
-			pos:  position{line: 219, col: 1, offset: 5865},
+			pos:  position{line: 243, col: 1, offset: 7119},
 			expr: &choiceExpr{
-				pos: position{line: 219, col: 15, offset: 5879},
+				pos: position{line: 243, col: 15, offset: 7133},
 				alternatives: []interface{}{
 					&ruleRefExpr{
-						pos:  position{line: 219, col: 15, offset: 5879},
+						pos:  position{line: 243, col: 15, offset: 7133},
 						name: "RefOperandDot",
 					},
 					&ruleRefExpr{
-						pos:  position{line: 219, col: 31, offset: 5895},
+						pos:  position{line: 243, col: 31, offset: 7149},
 						name: "RefOperandCanonical",
 					},
 				},
@@ -2428,24 +2869,58 @@ var g = &grammar{
 		},
 		{
 			name: "RefOperandDot",
-			pos:  position{line: 221, col: 1, offset: 5916},
+			pos:  position{line: 245, col: 1, offset: 7170},
 			expr: &actionExpr{
-				pos: position{line: 221, col: 18, offset: 5933},
+				pos: position{line: 245, col: 18, offset: 7187},
 				run: (*parser).callonRefOperandDot1,
 				expr: &seqExpr{
-					pos: position{line: 221, col: 18, offset: 5933},
+					pos: position{line: 245, col: 18, offset: 7187},
 					exprs: []interface{}{
 						&litMatcher{
-							pos:        position{line: 221, col: 18, offset: 5933},
+							pos:        position{line: 245, col: 18, offset: 7187},
 							val:        ".",
 							ignoreCase: false,
 						},
 						&labeledExpr{
-							pos:   position{line: 221, col: 22, offset: 5937},
+							pos:   position{line: 245, col: 22, offset: 7191},
 							label: "val",
 							expr: &ruleRefExpr{
-								pos:  position{line: 221, col: 26, offset: 5941},
-								name: "Var",
+								pos:  position{line: 245, col: 26, offset: 7195},
+								name: "FutureKeywordOrVar",
+							},
+						},
+					},
+				},
+			},
+		},
+		{
+			name: "FutureKeywordOrVar",
+			pos:  position{line: 252, col: 1, offset: 7510},
+			expr: &choiceExpr{
+				pos: position{line: 252, col: 23, offset: 7532},
+				alternatives: []interface{}{
+					&ruleRefExpr{
+						pos:  position{line: 252, col: 23, offset: 7532},
+						name: "Var",
+					},
+					&actionExpr{
+						pos: position{line: 252, col: 29, offset: 7538},
+						run: (*parser).callonFutureKeywordOrVar3,
+						expr: &seqExpr{
+							pos: position{line: 252, col: 29, offset: 7538},
+							exprs: []interface{}{
+								&labeledExpr{
+									pos:   position{line: 252, col: 29, offset: 7538},
+									label: "val",
+									expr: &ruleRefExpr{
+										pos:  position{line: 252, col: 33, offset: 7542},
+										name: "VarUnchecked",
+									},
+								},
+								&andCodeExpr{
+									pos: position{line: 252, col: 46, offset: 7555},
+									run: (*parser).callonFutureKeywordOrVar7,
+								},
 							},
 						},
 					},
@@ -2454,28 +2929,28 @@ var g = &grammar{
 		},
 		{
 			name: "RefOperandCanonical",
-			pos:  position{line: 225, col: 1, offset: 6004},
+			pos:  position{line: 258, col: 1, offset: 7650},
 			expr: &actionExpr{
-				pos: position{line: 225, col: 24, offset: 6027},
+				pos: position{line: 258, col: 24, offset: 7673},
 				run: (*parser).callonRefOperandCanonical1,
 				expr: &seqExpr{
-					pos: position{line: 225, col: 24, offset: 6027},
+					pos: position{line: 258, col: 24, offset: 7673},
 					exprs: []interface{}{
 						&litMatcher{
-							pos:        position{line: 225, col: 24, offset: 6027},
+							pos:        position{line: 258, col: 24, offset: 7673},
 							val:        "[",
 							ignoreCase: false,
 						},
 						&labeledExpr{
-							pos:   position{line: 225, col: 28, offset: 6031},
+							pos:   position{line: 258, col: 28, offset: 7677},
 							label: "val",
 							expr: &ruleRefExpr{
-								pos:  position{line: 225, col: 32, offset: 6035},
+								pos:  position{line: 258, col: 32, offset: 7681},
 								name: "ExprTerm",
 							},
 						},
 						&litMatcher{
-							pos:        position{line: 225, col: 41, offset: 6044},
+							pos:        position{line: 258, col: 41, offset: 7690},
 							val:        "]",
 							ignoreCase: false,
 						},
@@ -2485,15 +2960,15 @@ var g = &grammar{
 		},
 		{
 			name: "Var",
-			pos:  position{line: 229, col: 1, offset: 6073},
+			pos:  position{line: 262, col: 1, offset: 7719},
 			expr: &actionExpr{
-				pos: position{line: 229, col: 8, offset: 6080},
+				pos: position{line: 262, col: 8, offset: 7726},
 				run: (*parser).callonVar1,
 				expr: &labeledExpr{
-					pos:   position{line: 229, col: 8, offset: 6080},
+					pos:   position{line: 262, col: 8, offset: 7726},
 					label: "val",
 					expr: &ruleRefExpr{
-						pos:  position{line: 229, col: 12, offset: 6084},
+						pos:  position{line: 262, col: 12, offset: 7730},
 						name: "VarChecked",
 					},
 				},
@@ -2501,20 +2976,20 @@ var g = &grammar{
 		},
 		{
 			name: "VarChecked",
-			pos:  position{line: 233, col: 1, offset: 6139},
+			pos:  position{line: 266, col: 1, offset: 7785},
 			expr: &seqExpr{
-				pos: position{line: 233, col: 15, offset: 6153},
+				pos: position{line: 266, col: 15, offset: 7799},
 				exprs: []interface{}{
 					&labeledExpr{
-						pos:   position{line: 233, col: 15, offset: 6153},
+						pos:   position{line: 266, col: 15, offset: 7799},
 						label: "val",
 						expr: &ruleRefExpr{
-							pos:  position{line: 233, col: 19, offset: 6157},
+							pos:  position{line: 266, col: 19, offset: 7803},
 							name: "VarUnchecked",
 						},
 					},
 					&notCodeExpr{
-						pos: position{line: 233, col: 32, offset: 6170},
+						pos: position{line: 266, col: 32, offset: 7816},
 						run: (*parser).callonVarChecked4,
 					},
 				},
@@ -2522,21 +2997,21 @@ var g = &grammar{
 		},
 		{
 			name: "VarUnchecked",
-			pos:  position{line: 237, col: 1, offset: 6235},
+			pos:  position{line: 270, col: 1, offset: 7885},
 			expr: &actionExpr{
-				pos: position{line: 237, col: 17, offset: 6251},
+				pos: position{line: 270, col: 17, offset: 7901},
 				run: (*parser).callonVarUnchecked1,
 				expr: &seqExpr{
-					pos: position{line: 237, col: 17, offset: 6251},
+					pos: position{line: 270, col: 17, offset: 7901},
 					exprs: []interface{}{
 						&ruleRefExpr{
-							pos:  position{line: 237, col: 17, offset: 6251},
+							pos:  position{line: 270, col: 17, offset: 7901},
 							name: "VarStart",
 						},
 						&zeroOrMoreExpr{
-							pos: position{line: 237, col: 26, offset: 6260},
+							pos: position{line: 270, col: 26, offset: 7910},
 							expr: &ruleRefExpr{
-								pos:  position{line: 237, col: 26, offset: 6260},
+								pos:  position{line: 270, col: 26, offset: 7910},
 								name: "VarChar",
 							},
 						},
@@ -2546,30 +3021,34 @@ var g = &grammar{
 		},
 		{
 			name: "Number",
-			pos:  position{line: 241, col: 1, offset: 6321},
+			pos:  position{line: 274, col: 1, offset: 7971},
 			expr: &actionExpr{
-				pos: position{line: 241, col: 11, offset: 6331},
+				pos: position{line: 274, col: 11, offset: 7981},
 				run: (*parser).callonNumber1,
 				expr: &seqExpr{
-					pos: position{line: 241, col: 11, offset: 6331},
+					pos: position{line: 274, col: 11, offset: 7981},
 					exprs: []interface{}{
 						&zeroOrOneExpr{
-							pos: position{line: 241, col: 11, offset: 6331},
+							pos: position{line: 274, col: 11, offset: 7981},
 							expr: &litMatcher{
-								pos:        position{line: 241, col: 11, offset: 6331},
+								pos:        position{line: 274, col: 11, offset: 7981},
 								val:        "-",
 								ignoreCase: false,
 							},
 						},
 						&choiceExpr{
-							pos: position{line: 241, col: 18, offset: 6338},
+							pos: position{line: 274, col: 18, offset: 7988},
 							alternatives: []interface{}{
 								&ruleRefExpr{
-									pos:  position{line: 241, col: 18, offset: 6338},
+									pos:  position{line: 274, col: 18, offset: 7988},
+									name: "HexInteger",
+								},
+								&ruleRefExpr{
+									pos:  position{line: 274, col: 31, offset: 8001},
 									name: "Float",
 								},
 								&ruleRefExpr{
-									pos:  position{line: 241, col: 26, offset: 6346},
+									pos:  position{line: 274, col: 39, offset: 8009},
 									name: "Integer",
 								},
 							},
@@ -2580,16 +3059,16 @@ var g = &grammar{
 		},
 		{
 			name: "Float",
-			pos:  position{line: 245, col: 1, offset: 6411},
+			pos:  position{line: 278, col: 1, offset: 8074},
 			expr: &choiceExpr{
-				pos: position{line: 245, col: 10, offset: 6420},
+				pos: position{line: 278, col: 10, offset: 8083},
 				alternatives: []interface{}{
 					&ruleRefExpr{
-						pos:  position{line: 245, col: 10, offset: 6420},
+						pos:  position{line: 278, col: 10, offset: 8083},
 						name: "ExponentFloat",
 					},
 					&ruleRefExpr{
-						pos:  position{line: 245, col: 26, offset: 6436},
+						pos:  position{line: 278, col: 26, offset: 8099},
 						name: "PointFloat",
 					},
 				},
@@ -2597,25 +3076,25 @@ var g = &grammar{
 		},
 		{
 			name: "ExponentFloat",
-			pos:  position{line: 247, col: 1, offset: 6448},
+			pos:  position{line: 280, col: 1, offset: 8111},
 			expr: &seqExpr{
-				pos: position{line: 247, col: 18, offset: 6465},
+				pos: position{line: 280, col: 18, offset: 8128},
 				exprs: []interface{}{
 					&choiceExpr{
-						pos: position{line: 247, col: 20, offset: 6467},
+						pos: position{line: 280, col: 20, offset: 8130},
 						alternatives: []interface{}{
 							&ruleRefExpr{
-								pos:  position{line: 247, col: 20, offset: 6467},
+								pos:  position{line: 280, col: 20, offset: 8130},
 								name: "PointFloat",
 							},
 							&ruleRefExpr{
-								pos:  position{line: 247, col: 33, offset: 6480},
+								pos:  position{line: 280, col: 33, offset: 8143},
 								name: "Integer",
 							},
 						},
 					},
 					&ruleRefExpr{
-						pos:  position{line: 247, col: 43, offset: 6490},
+						pos:  position{line: 280, col: 43, offset: 8153},
 						name: "Exponent",
 					},
 				},
@@ -2623,19 +3102,19 @@ var g = &grammar{
 		},
 		{
 			name: "PointFloat",
-			pos:  position{line: 249, col: 1, offset: 6500},
+			pos:  position{line: 282, col: 1, offset: 8163},
 			expr: &seqExpr{
-				pos: position{line: 249, col: 15, offset: 6514},
+				pos: position{line: 282, col: 15, offset: 8177},
 				exprs: []interface{}{
 					&zeroOrOneExpr{
-						pos: position{line: 249, col: 15, offset: 6514},
+						pos: position{line: 282, col: 15, offset: 8177},
 						expr: &ruleRefExpr{
-							pos:  position{line: 249, col: 15, offset: 6514},
+							pos:  position{line: 282, col: 15, offset: 8177},
 							name: "Integer",
 						},
 					},
 					&ruleRefExpr{
-						pos:  position{line: 249, col: 24, offset: 6523},
+						pos:  position{line: 282, col: 24, offset: 8186},
 						name: "Fraction",
 					},
 				},
@@ -2643,19 +3122,19 @@ var g = &grammar{
 		},
 		{
 			name: "Fraction",
-			pos:  position{line: 251, col: 1, offset: 6533},
+			pos:  position{line: 284, col: 1, offset: 8196},
 			expr: &seqExpr{
-				pos: position{line: 251, col: 13, offset: 6545},
+				pos: position{line: 284, col: 13, offset: 8208},
 				exprs: []interface{}{
 					&litMatcher{
-						pos:        position{line: 251, col: 13, offset: 6545},
+						pos:        position{line: 284, col: 13, offset: 8208},
 						val:        ".",
 						ignoreCase: false,
 					},
 					&oneOrMoreExpr{
-						pos: position{line: 251, col: 17, offset: 6549},
+						pos: position{line: 284, col: 17, offset: 8212},
 						expr: &ruleRefExpr{
-							pos:  position{line: 251, col: 17, offset: 6549},
+							pos:  position{line: 284, col: 17, offset: 8212},
 							name: "DecimalDigit",
 						},
 					},
@@ -2664,19 +3143,19 @@ var g = &grammar{
 		},
 		{
 			name: "Exponent",
-			pos:  position{line: 253, col: 1, offset: 6564},
+			pos:  position{line: 286, col: 1, offset: 8227},
 			expr: &seqExpr{
-				pos: position{line: 253, col: 13, offset: 6576},
+				pos: position{line: 286, col: 13, offset: 8239},
 				exprs: []interface{}{
 					&litMatcher{
-						pos:        position{line: 253, col: 13, offset: 6576},
+						pos:        position{line: 286, col: 13, offset: 8239},
 						val:        "e",
 						ignoreCase: true,
 					},
 					&zeroOrOneExpr{
-						pos: position{line: 253, col: 18, offset: 6581},
+						pos: position{line: 286, col: 18, offset: 8244},
 						expr: &charClassMatcher{
-							pos:        position{line: 253, col: 18, offset: 6581},
+							pos:        position{line: 286, col: 18, offset: 8244},
 							val:        "[+-]",
 							chars:      []rune{'+', '-'},
 							ignoreCase: false,
@@ -2684,9 +3163,9 @@ var g = &grammar{
 						},
 					},
 					&oneOrMoreExpr{
-						pos: position{line: 253, col: 24, offset: 6587},
+						pos: position{line: 286, col: 24, offset: 8250},
 						expr: &ruleRefExpr{
-							pos:  position{line: 253, col: 24, offset: 6587},
+							pos:  position{line: 286, col: 24, offset: 8250},
 							name: "DecimalDigit",
 						},
 					},
@@ -2695,26 +3174,26 @@ var g = &grammar{
 		},
 		{
 			name: "Integer",
-			pos:  position{line: 255, col: 1, offset: 6602},
+			pos:  position{line: 288, col: 1, offset: 8265},
 			expr: &choiceExpr{
-				pos: position{line: 255, col: 12, offset: 6613},
+				pos: position{line: 288, col: 12, offset: 8276},
 				alternatives: []interface{}{
 					&litMatcher{
-						pos:        position{line: 255, col: 12, offset: 6613},
+						pos:        position{line: 288, col: 12, offset: 8276},
 						val:        "0",
 						ignoreCase: false,
 					},
 					&seqExpr{
-						pos: position{line: 255, col: 20, offset: 6621},
+						pos: position{line: 288, col: 20, offset: 8284},
 						exprs: []interface{}{
 							&ruleRefExpr{
-								pos:  position{line: 255, col: 20, offset: 6621},
+								pos:  position{line: 288, col: 20, offset: 8284},
 								name: "NonZeroDecimalDigit",
 							},
 							&zeroOrMoreExpr{
-								pos: position{line: 255, col: 40, offset: 6641},
+								pos: position{line: 288, col: 40, offset: 8304},
 								expr: &ruleRefExpr{
-									pos:  position{line: 255, col: 40, offset: 6641},
+									pos:  position{line: 288, col: 40, offset: 8304},
 									name: "DecimalDigit",
 								},
 							},
@@ -2723,18 +3202,46 @@ var g = &grammar{
 				},
 			},
 		},
+		{
+			name: "HexInteger",
+			pos:  position{line: 290, col: 1, offset: 8321},
+			expr: &seqExpr{
+				pos: position{line: 290, col: 15, offset: 8335},
+				exprs: []interface{}{
+					&litMatcher{
+						pos:        position{line: 290, col: 15, offset: 8335},
+						val:        "0",
+						ignoreCase: false,
+					},
+					&charClassMatcher{
+						pos:        position{line: 290, col: 19, offset: 8339},
+						val:        "[xX]",
+						chars:      []rune{'x', 'X'},
+						ignoreCase: false,
+						inverted:   false,
+					},
+					&oneOrMoreExpr{
+						pos: position{line: 290, col: 24, offset: 8344},
+						expr: &ruleRefExpr{
+							pos:  position{line: 290, col: 24, offset: 8344},
+							name: "HexDigit",
+						},
+					},
+				},
+			},
+		},
 		{
 			name: "String",
-			pos:  position{line: 257, col: 1, offset: 6658},
+			pos:  position{line: 292, col: 1, offset: 8355},
 			expr: &choiceExpr{
-				pos: position{line: 257, col: 11, offset: 6668},
+				pos: position{line: 292, col: 11, offset: 8365},
 				alternatives: []interface{}{
 					&ruleRefExpr{
-						pos:  position{line: 257, col: 11, offset: 6668},
+						pos:  position{line: 292, col: 11, offset: 8365},
 						name: "QuotedString",
 					},
 					&ruleRefExpr{
-						pos:  position{line: 257, col: 26, offset: 6683},
+						pos:  position{line: 292, col: 26, offset: 8380},
 						name: "RawString",
 					},
 				},
@@ -2742,30 +3249,30 @@ var g = &grammar{
 		},
 		{
 			name: "QuotedString",
-			pos:  position{line: 259, col: 1, offset: 6694},
+			pos:  position{line: 294, col: 1, offset: 8391},
 			expr: &choiceExpr{
-				pos: position{line: 259, col: 17, offset: 6710},
+				pos: position{line: 294, col: 17, offset: 8407},
 				alternatives: []interface{}{
 					&actionExpr{
-						pos: position{line: 259, col: 17, offset: 6710},
+						pos: position{line: 294, col: 17, offset: 8407},
 						run: (*parser).callonQuotedString2,
 						expr: &seqExpr{
-							pos: position{line: 259, col: 17, offset: 6710},
+							pos: position{line: 294, col: 17, offset: 8407},
 							exprs: []interface{}{
 								&litMatcher{
-									pos:        position{line: 259, col: 17, offset: 6710},
+									pos:        position{line: 294, col: 17, offset: 8407},
 									val:        "\"",
 									ignoreCase: false,
 								},
 								&zeroOrMoreExpr{
-									pos: position{line: 259, col: 21, offset: 6714},
+									pos: position{line: 294, col: 21, offset: 8411},
 									expr: &ruleRefExpr{
-										pos:  position{line: 259, col: 21, offset: 6714},
+										pos:  position{line: 294, col: 21, offset: 8411},
 										name: "Char",
 									},
 								},
 								&litMatcher{
-									pos:        position{line: 259, col: 27, offset: 6720},
+									pos:        position{line: 294, col: 27, offset: 8417},
 									val:        "\"",
 									ignoreCase: false,
 								},
@@ -2773,27 +3280,27 @@ var g = &grammar{
 						},
 					},
 					&actionExpr{
-						pos: position{line: 261, col: 5, offset: 6780},
+						pos: position{line: 296, col: 5, offset: 8477},
 						run: (*parser).callonQuotedString8,
 						expr: &seqExpr{
-							pos: position{line: 261, col: 5, offset: 6780},
+							pos: position{line: 296, col: 5, offset: 8477},
 							exprs: []interface{}{
 								&litMatcher{
-									pos:        position{line: 261, col: 5, offset: 6780},
+									pos:        position{line: 296, col: 5, offset: 8477},
 									val:        "\"",
 									ignoreCase: false,
 								},
 								&zeroOrMoreExpr{
-									pos: position{line: 261, col: 9, offset: 6784},
+									pos: position{line: 296, col: 9, offset: 8481},
 									expr: &ruleRefExpr{
-										pos:  position{line: 261, col: 9, offset: 6784},
+										pos:  position{line: 296, col: 9, offset: 8481},
 										name: "Char",
 									},
 								},
 								&notExpr{
-									pos: position{line: 261, col: 15, offset: 6790},
+									pos: position{line: 296, col: 15, offset: 8487},
 									expr: &litMatcher{
-										pos:        position{line: 261, col: 16, offset: 6791},
+										pos:        position{line: 296, col: 16, offset: 8488},
 										val:        "\"",
 										ignoreCase: false,
 									},
@@ -2806,22 +3313,22 @@ var g = &grammar{
 		},
 		{
 			name: "RawString",
-			pos:  position{line: 265, col: 1, offset: 6871},
+			pos:  position{line: 300, col: 1, offset: 8568},
 			expr: &actionExpr{
-				pos: position{line: 265, col: 14, offset: 6884},
+				pos: position{line: 300, col: 14, offset: 8581},
 				run: (*parser).callonRawString1,
 				expr: &seqExpr{
-					pos: position{line: 265, col: 14, offset: 6884},
+					pos: position{line: 300, col: 14, offset: 8581},
 					exprs: []interface{}{
 						&litMatcher{
-							pos:        position{line: 265, col: 14, offset: 6884},
+							pos:        position{line: 300, col: 14, offset: 8581},
 							val:        "`",
 							ignoreCase: false,
 						},
 						&zeroOrMoreExpr{
-							pos: position{line: 265, col: 18, offset: 6888},
+							pos: position{line: 300, col: 18, offset: 8585},
 							expr: &charClassMatcher{
-								pos:        position{line: 265, col: 18, offset: 6888},
+								pos:        position{line: 300, col: 18, offset: 8585},
 								val:        "[^`]",
 								chars:      []rune{'`'},
 								ignoreCase: false,
@@ -2829,7 +3336,7 @@ var g = &grammar{
 							},
 						},
 						&litMatcher{
-							pos:        position{line: 265, col: 24, offset: 6894},
+							pos:        position{line: 300, col: 24, offset: 8591},
 							val:        "`",
 							ignoreCase: false,
 						},
@@ -2839,26 +3346,26 @@ var g = &grammar{
 		},
 		{
 			name: "Bool",
-			pos:  position{line: 269, col: 1, offset: 6956},
+			pos:  position{line: 304, col: 1, offset: 8653},
 			expr: &actionExpr{
-				pos: position{line: 269, col: 9, offset: 6964},
+				pos: position{line: 304, col: 9, offset: 8661},
 				run: (*parser).callonBool1,
 				expr: &seqExpr{
-					pos: position{line: 269, col: 9, offset: 6964},
+					pos: position{line: 304, col: 9, offset: 8661},
 					exprs: []interface{}{
 						&labeledExpr{
-							pos:   position{line: 269, col: 9, offset: 6964},
+							pos:   position{line: 304, col: 9, offset: 8661},
 							label: "val",
 							expr: &choiceExpr{
-								pos: position{line: 269, col: 14, offset: 6969},
+								pos: position{line: 304, col: 14, offset: 8666},
 								alternatives: []interface{}{
 									&litMatcher{
-										pos:        position{line: 269, col: 14, offset: 6969},
+										pos:        position{line: 304, col: 14, offset: 8666},
 										val:        "true",
 										ignoreCase: false,
 									},
 									&litMatcher{
-										pos:        position{line: 269, col: 23, offset: 6978},
+										pos:        position{line: 304, col: 23, offset: 8675},
 										val:        "false",
 										ignoreCase: false,
 									},
@@ -2866,9 +3373,9 @@ var g = &grammar{
 							},
 						},
 						&notExpr{
-							pos: position{line: 269, col: 32, offset: 6987},
+							pos: position{line: 304, col: 32, offset: 8684},
 							expr: &ruleRefExpr{
-								pos:  position{line: 269, col: 33, offset: 6988},
+								pos:  position{line: 304, col: 33, offset: 8685},
 								name: "VarChar",
 							},
 						},
@@ -2878,22 +3385,22 @@ var g = &grammar{
 		},
 		{
 			name: "Null",
-			pos:  position{line: 273, col: 1, offset: 7049},
+			pos:  position{line: 308, col: 1, offset: 8746},
 			expr: &actionExpr{
-				pos: position{line: 273, col: 9, offset: 7057},
+				pos: position{line: 308, col: 9, offset: 8754},
 				run: (*parser).callonNull1,
 				expr: &seqExpr{
-					pos: position{line: 273, col: 9, offset: 7057},
+					pos: position{line: 308, col: 9, offset: 8754},
 					exprs: []interface{}{
 						&litMatcher{
-							pos:        position{line: 273, col: 9, offset: 7057},
+							pos:        position{line: 308, col: 9, offset: 8754},
 							val:        "null",
 							ignoreCase: false,
 						},
 						&notExpr{
-							pos: position{line: 273, col: 16, offset: 7064},
+							pos: position{line: 308, col: 16, offset: 8761},
 							expr: &ruleRefExpr{
-								pos:  position{line: 273, col: 17, offset: 7065},
+								pos:  position{line: 308, col: 17, offset: 8762},
 								name: "VarChar",
 							},
 						},
@@ -2903,34 +3410,51 @@ var g = &grammar{
 		},
 		{
 			name: "VarStart",
-			pos:  position{line: 277, col: 1, offset: 7118},
-			expr: &ruleRefExpr{
-				pos:  position{line: 277, col: 13, offset: 7130},
-				name: "AsciiLetter",
+			pos:  position{line: 312, col: 1, offset: 8815},
+			expr: &choiceExpr{
+				pos: position{line: 312, col: 13, offset: 8827},
+				alternatives: []interface{}{
+					&ruleRefExpr{
+						pos:  position{line: 312, col: 13, offset: 8827},
+						name: "AsciiLetter",
+					},
+					&ruleRefExpr{
+						pos:  position{line: 312, col: 27, offset: 8841},
+						name: "UnicodeLetter",
+					},
+				},
 			},
 		},
 		{
 			name: "VarChar",
-			pos:  position{line: 279, col: 1, offset: 7143},
+			pos:  position{line: 314, col: 1, offset: 8856},
 			expr: &choiceExpr{
-				pos: position{line: 279, col: 12, offset: 7154},
+				pos: position{line: 314, col: 12, offset: 8867},
 				alternatives: []interface{}{
 					&ruleRefExpr{
-						pos:  position{line: 279, col: 12, offset: 7154},
+						pos:  position{line: 314, col: 12, offset: 8867},
 						name: "AsciiLetter",
 					},
 					&ruleRefExpr{
-						pos:  position{line: 279, col: 26, offset: 7168},
+						pos:  position{line: 314, col: 26, offset: 8881},
 						name: "DecimalDigit",
 					},
+					&ruleRefExpr{
+						pos:  position{line: 314, col: 41, offset: 8896},
+						name: "UnicodeLetter",
+					},
+					&ruleRefExpr{
+						pos:  position{line: 314, col: 57, offset: 8912},
+						name: "UnicodeDigit",
+					},
 				},
 			},
 		},
 		{
 			name: "AsciiLetter",
-			pos:  position{line: 281, col: 1, offset: 7182},
+			pos:  position{line: 316, col: 1, offset: 8926},
 			expr: &charClassMatcher{
-				pos:        position{line: 281, col: 16, offset: 7197},
+				pos:        position{line: 316, col: 16, offset: 8941},
 				val:        "[A-Za-z_]",
 				chars:      []rune{'_'},
 				ranges:     []rune{'A', 'Z', 'a', 'z'},
@@ -2938,37 +3462,59 @@ var g = &grammar{
 				inverted:   false,
 			},
 		},
+		{
+			name: "UnicodeLetter",
+			pos:  position{line: 318, col: 1, offset: 8952},
+			expr: &charClassMatcher{
+				pos:        position{line: 318, col: 18, offset: 8969},
+				val:        "[\\pL]",
+				classes:    []*unicode.RangeTable{rangeTable("L")},
+				ignoreCase: false,
+				inverted:   false,
+			},
+		},
+		{
+			name: "UnicodeDigit",
+			pos:  position{line: 320, col: 1, offset: 8976},
+			expr: &charClassMatcher{
+				pos:        position{line: 320, col: 17, offset: 8992},
+				val:        "[\\p{Nd}]",
+				classes:    []*unicode.RangeTable{rangeTable("Nd")},
+				ignoreCase: false,
+				inverted:   false,
+			},
+		},
 		{
 			name: "Char",
-			pos:  position{line: 283, col: 1, offset: 7208},
+			pos:  position{line: 322, col: 1, offset: 9002},
 			expr: &choiceExpr{
-				pos: position{line: 283, col: 9, offset: 7216},
+				pos: position{line: 322, col: 9, offset: 9010},
 				alternatives: []interface{}{
 					&seqExpr{
-						pos: position{line: 283, col: 11, offset: 7218},
+						pos: position{line: 322, col: 11, offset: 9012},
 						exprs: []interface{}{
 							&notExpr{
-								pos: position{line: 283, col: 11, offset: 7218},
+								pos: position{line: 322, col: 11, offset: 9012},
 								expr: &ruleRefExpr{
-									pos:  position{line: 283, col: 12, offset: 7219},
+									pos:  position{line: 322, col: 12, offset: 9013},
 									name: "EscapedChar",
 								},
 							},
 							&anyMatcher{
-								line: 283, col: 24, offset: 7231,
+								line: 322, col: 24, offset: 9025,
 							},
 						},
 					},
 					&seqExpr{
-						pos: position{line: 283, col: 32, offset: 7239},
+						pos: position{line: 322, col: 32, offset: 9033},
 						exprs: []interface{}{
 							&litMatcher{
-								pos:        position{line: 283, col: 32, offset: 7239},
+								pos:        position{line: 322, col: 32, offset: 9033},
 								val:        "\\",
 								ignoreCase: false,
 							},
 							&ruleRefExpr{
-								pos:  position{line: 283, col: 37, offset: 7244},
+								pos:  position{line: 322, col: 37, offset: 9038},
 								name: "EscapeSequence",
 							},
 						},
@@ -2978,9 +3524,9 @@ var g = &grammar{
 		},
 		{
 			name: "EscapedChar",
-			pos:  position{line: 285, col: 1, offset: 7262},
+			pos:  position{line: 324, col: 1, offset: 9056},
 			expr: &charClassMatcher{
-				pos:        position{line: 285, col: 16, offset: 7277},
+				pos:        position{line: 324, col: 16, offset: 9071},
 				val:        "[\\x00-\\x1f\"\\\\]",
 				chars:      []rune{'"', '\\'},
 				ranges:     []rune{'\x00', '\x1f'},
@@ -2990,16 +3536,16 @@ var g = &grammar{
 		},
 		{
 			name: "EscapeSequence",
-			pos:  position{line: 287, col: 1, offset: 7293},
+			pos:  position{line: 326, col: 1, offset: 9087},
 			expr: &choiceExpr{
-				pos: position{line: 287, col: 19, offset: 7311},
+				pos: position{line: 326, col: 19, offset: 9105},
 				alternatives: []interface{}{
 					&ruleRefExpr{
-						pos:  position{line: 287, col: 19, offset: 7311},
+						pos:  position{line: 326, col: 19, offset: 9105},
 						name: "SingleCharEscape",
 					},
 					&ruleRefExpr{
-						pos:  position{line: 287, col: 38, offset: 7330},
+						pos:  position{line: 326, col: 38, offset: 9124},
 						name: "UnicodeEscape",
 					},
 				},
@@ -3007,9 +3553,9 @@ var g = &grammar{
 		},
 		{
 			name: "SingleCharEscape",
-			pos:  position{line: 289, col: 1, offset: 7345},
+			pos:  position{line: 328, col: 1, offset: 9139},
 			expr: &charClassMatcher{
-				pos:        position{line: 289, col: 21, offset: 7365},
+				pos:        position{line: 328, col: 21, offset: 9159},
 				val:        "[ \" \\\\ / b f n r t ]",
 				chars:      []rune{' ', '"', ' ', '\\', ' ', '/', ' ', 'b', ' ', 'f', ' ', 'n', ' ', 'r', ' ', 't', ' '},
 				ignoreCase: false,
@@ -3018,29 +3564,29 @@ var g = &grammar{
 		},
 		{
 			name: "UnicodeEscape",
-			pos:  position{line: 291, col: 1, offset: 7387},
+			pos:  position{line: 330, col: 1, offset: 9181},
 			expr: &seqExpr{
-				pos: position{line: 291, col: 18, offset: 7404},
+				pos: position{line: 330, col: 18, offset: 9198},
 				exprs: []interface{}{
 					&litMatcher{
-						pos:        position{line: 291, col: 18, offset: 7404},
+						pos:        position{line: 330, col: 18, offset: 9198},
 						val:        "u",
 						ignoreCase: false,
 					},
 					&ruleRefExpr{
-						pos:  position{line: 291, col: 22, offset: 7408},
+						pos:  position{line: 330, col: 22, offset: 9202},
 						name: "HexDigit",
 					},
 					&ruleRefExpr{
-						pos:  position{line: 291, col: 31, offset: 7417},
+						pos:  position{line: 330, col: 31, offset: 9211},
 						name: "HexDigit",
 					},
 					&ruleRefExpr{
-						pos:  position{line: 291, col: 40, offset: 7426},
+						pos:  position{line: 330, col: 40, offset: 9220},
 						name: "HexDigit",
 					},
 					&ruleRefExpr{
-						pos:  position{line: 291, col: 49, offset: 7435},
+						pos:  position{line: 330, col: 49, offset: 9229},
 						name: "HexDigit",
 					},
 				},
@@ -3048,9 +3594,9 @@ var g = &grammar{
 		},
 		{
 			name: "DecimalDigit",
-			pos:  position{line: 293, col: 1, offset: 7445},
+			pos:  position{line: 332, col: 1, offset: 9239},
 			expr: &charClassMatcher{
-				pos:        position{line: 293, col: 17, offset: 7461},
+				pos:        position{line: 332, col: 17, offset: 9255},
 				val:        "[0-9]",
 				ranges:     []rune{'0', '9'},
 				ignoreCase: false,
@@ -3059,9 +3605,9 @@ var g = &grammar{
 		},
 		{
 			name: "NonZeroDecimalDigit",
-			pos:  position{line: 295, col: 1, offset: 7468},
+			pos:  position{line: 334, col: 1, offset: 9262},
 			expr: &charClassMatcher{
-				pos:        position{line: 295, col: 24, offset: 7491},
+				pos:        position{line: 334, col: 24, offset: 9285},
 				val:        "[1-9]",
 				ranges:     []rune{'1', '9'},
 				ignoreCase: false,
@@ -3070,9 +3616,9 @@ var g = &grammar{
 		},
 		{
 			name: "HexDigit",
-			pos:  position{line: 297, col: 1, offset: 7498},
+			pos:  position{line: 336, col: 1, offset: 9292},
 			expr: &charClassMatcher{
-				pos:        position{line: 297, col: 13, offset: 7510},
+				pos:        position{line: 336, col: 13, offset: 9304},
 				val:        "[0-9a-fA-F]",
 				ranges:     []rune{'0', '9', 'a', 'f', 'A', 'F'},
 				ignoreCase: false,
@@ -3082,11 +3628,11 @@ var g = &grammar{
 		{
 			name:        "ws",
 			displayName: "\"whitespace\"",
-			pos:         position{line: 299, col: 1, offset: 7523},
+			pos:         position{line: 338, col: 1, offset: 9317},
 			expr: &oneOrMoreExpr{
-				pos: position{line: 299, col: 20, offset: 7542},
+				pos: position{line: 338, col: 20, offset: 9336},
 				expr: &charClassMatcher{
-					pos:        position{line: 299, col: 20, offset: 7542},
+					pos:        position{line: 338, col: 20, offset: 9336},
 					val:        "[ \\t\\r\\n]",
 					chars:      []rune{' ', '\t', '\r', '\n'},
 					ignoreCase: false,
@@ -3097,21 +3643,21 @@ var g = &grammar{
 		{
 			name:        "_",
 			displayName: "\"whitespace\"",
-			pos:         position{line: 301, col: 1, offset: 7554},
+			pos:         position{line: 340, col: 1, offset: 9348},
 			expr: &zeroOrMoreExpr{
-				pos: position{line: 301, col: 19, offset: 7572},
+				pos: position{line: 340, col: 19, offset: 9366},
 				expr: &choiceExpr{
-					pos: position{line: 301, col: 21, offset: 7574},
+					pos: position{line: 340, col: 21, offset: 9368},
 					alternatives: []interface{}{
 						&charClassMatcher{
-							pos:        position{line: 301, col: 21, offset: 7574},
+							pos:        position{line: 340, col: 21, offset: 9368},
 							val:        "[ \\t\\r\\n]",
 							chars:      []rune{' ', '\t', '\r', '\n'},
 							ignoreCase: false,
 							inverted:   false,
 						},
 						&ruleRefExpr{
-							pos:  position{line: 301, col: 33, offset: 7586},
+							pos:  position{line: 340, col: 33, offset: 9380},
 							name: "Comment",
 						},
 					},
@@ -3120,17 +3666,17 @@ var g = &grammar{
 		},
 		{
 			name: "Comment",
-			pos:  position{line: 303, col: 1, offset: 7598},
+			pos:  position{line: 342, col: 1, offset: 9392},
 			expr: &actionExpr{
-				pos: position{line: 303, col: 12, offset: 7609},
+				pos: position{line: 342, col: 12, offset: 9403},
 				run: (*parser).callonComment1,
 				expr: &seqExpr{
-					pos: position{line: 303, col: 12, offset: 7609},
+					pos: position{line: 342, col: 12, offset: 9403},
 					exprs: []interface{}{
 						&zeroOrMoreExpr{
-							pos: position{line: 303, col: 12, offset: 7609},
+							pos: position{line: 342, col: 12, offset: 9403},
 							expr: &charClassMatcher{
-								pos:        position{line: 303, col: 12, offset: 7609},
+								pos:        position{line: 342, col: 12, offset: 9403},
 								val:        "[ \\t]",
 								chars:      []rune{' ', '\t'},
 								ignoreCase: false,
@@ -3138,17 +3684,17 @@ var g = &grammar{
 							},
 						},
 						&litMatcher{
-							pos:        position{line: 303, col: 19, offset: 7616},
+							pos:        position{line: 342, col: 19, offset: 9410},
 							val:        "#",
 							ignoreCase: false,
 						},
 						&labeledExpr{
-							pos:   position{line: 303, col: 23, offset: 7620},
+							pos:   position{line: 342, col: 23, offset: 9414},
 							label: "text",
 							expr: &zeroOrMoreExpr{
-								pos: position{line: 303, col: 28, offset: 7625},
+								pos: position{line: 342, col: 28, offset: 9419},
 								expr: &charClassMatcher{
-									pos:        position{line: 303, col: 28, offset: 7625},
+									pos:        position{line: 342, col: 28, offset: 9419},
 									val:        "[^\\r\\n]",
 									chars:      []rune{'\r', '\n'},
 									ignoreCase: false,
@@ -3162,11 +3708,11 @@ var g = &grammar{
 		},
 		{
 			name: "EOF",
-			pos:  position{line: 307, col: 1, offset: 7672},
+			pos:  position{line: 346, col: 1, offset: 9466},
 			expr: &notExpr{
-				pos: position{line: 307, col: 8, offset: 7679},
+				pos: position{line: 346, col: 8, offset: 9473},
 				expr: &anyMatcher{
-					line: 307, col: 9, offset: 7680,
+					line: 346, col: 9, offset: 9474,
 				},
 			},
 		},
@@ -3204,7 +3750,7 @@ func (p *parser) callonPackage1() (interface{}, error) {
 }
 
 func (c *current) onImport1(path, alias interface{}) (interface{}, error) {
-	return makeImport(currentLocation(c), path, alias)
+	return makeImport(c, currentLocation(c), path, alias)
 }
 
 func (p *parser) callonImport1() (interface{}, error) {
@@ -3223,24 +3769,64 @@ func (p *parser) callonDefaultRules1() (interface{}, error) {
 	return p.cur.onDefaultRules1(stack["name"], stack["operator"], stack["value"])
 }
 
-func (c *current) onNormalRules1(head, rest interface{}) (interface{}, error) {
-	return makeRule(currentLocation(c), head, rest)
+func (c *current) onNormalRules2(head, kw, rest interface{}) (interface{}, error) {
+	return makeRule(currentLocation(c), head, kw != nil, rest)
+}
+
+func (p *parser) callonNormalRules2() (interface{}, error) {
+	stack := p.vstack[len(p.vstack)-1]
+	_ = stack
+	return p.cur.onNormalRules2(stack["head"], stack["kw"], stack["rest"])
+}
+
+func (c *current) onNormalRules19(head, val interface{}) (interface{}, error) {
+	return makeRule(currentLocation(c), head, true, []interface{}{NewBody(val.(*Expr)), []interface{}{}})
+}
+
+func (p *parser) callonNormalRules19() (interface{}, error) {
+	stack := p.vstack[len(p.vstack)-1]
+	_ = stack
+	return p.cur.onNormalRules19(stack["head"], stack["val"])
+}
+
+func (c *current) onContainsRuleHead6(name interface{}) (bool, error) {
+	return futureKeywordEnabled(c, "contains"), nil
+}
+
+func (p *parser) callonContainsRuleHead6() (bool, error) {
+	stack := p.vstack[len(p.vstack)-1]
+	_ = stack
+	return p.cur.onContainsRuleHead6(stack["name"])
+}
+
+func (c *current) onContainsRuleHead1(name, key interface{}) (interface{}, error) {
+	return makeContainsRuleHead(currentLocation(c), name, key)
+}
+
+func (p *parser) callonContainsRuleHead1() (interface{}, error) {
+	stack := p.vstack[len(p.vstack)-1]
+	_ = stack
+	return p.cur.onContainsRuleHead1(stack["name"], stack["key"])
+}
+
+func (c *current) onIfKeyword2() (bool, error) {
+	return futureKeywordEnabled(c, "if"), nil
 }
 
-func (p *parser) callonNormalRules1() (interface{}, error) {
+func (p *parser) callonIfKeyword2() (bool, error) {
 	stack := p.vstack[len(p.vstack)-1]
 	_ = stack
-	return p.cur.onNormalRules1(stack["head"], stack["rest"])
+	return p.cur.onIfKeyword2()
 }
 
-func (c *current) onRuleHead1(name, args, key, value interface{}) (interface{}, error) {
-	return makeRuleHead(currentLocation(c), name, args, key, value)
+func (c *current) onRuleHead1(name, path, args, key, value interface{}) (interface{}, error) {
+	return makeRuleHead(currentLocation(c), name, path, args, key, value)
 }
 
 func (p *parser) callonRuleHead1() (interface{}, error) {
 	stack := p.vstack[len(p.vstack)-1]
 	_ = stack
-	return p.cur.onRuleHead1(stack["name"], stack["args"], stack["key"], stack["value"])
+	return p.cur.onRuleHead1(stack["name"], stack["path"], stack["args"], stack["key"], stack["value"])
 }
 
 func (c *current) onArgs1(list interface{}) (interface{}, error) {
@@ -3316,6 +3902,26 @@ func (p *parser) callonNonWhitespaceBody1() (interface{}, error) {
 	return p.cur.onNonWhitespaceBody1(stack["head"], stack["tail"])
 }
 
+func (c *current) onEvery3() (bool, error) {
+	return futureKeywordEnabled(c, "every"), nil
+}
+
+func (p *parser) callonEvery3() (bool, error) {
+	stack := p.vstack[len(p.vstack)-1]
+	_ = stack
+	return p.cur.onEvery3()
+}
+
+func (c *current) onEvery1(key, value, domain, body interface{}) (interface{}, error) {
+	return makeEvery(currentLocation(c), key, value, domain, body)
+}
+
+func (p *parser) callonEvery1() (interface{}, error) {
+	stack := p.vstack[len(p.vstack)-1]
+	_ = stack
+	return p.cur.onEvery1(stack["key"], stack["value"], stack["domain"], stack["body"])
+}
+
 func (c *current) onSomeDecl1(symbols interface{}) (interface{}, error) {
 	return makeSomeDeclLiteral(currentLocation(c), symbols)
 }
@@ -3326,6 +3932,16 @@ func (p *parser) callonSomeDecl1() (interface{}, error) {
 	return p.cur.onSomeDecl1(stack["symbols"])
 }
 
+func (c *current) onSomeDeclIn1(key, value, collection interface{}) (interface{}, error) {
+	return makeSomeDeclIn(currentLocation(c), key, value, collection)
+}
+
+func (p *parser) callonSomeDeclIn1() (interface{}, error) {
+	stack := p.vstack[len(p.vstack)-1]
+	_ = stack
+	return p.cur.onSomeDeclIn1(stack["key"], stack["value"], stack["collection"])
+}
+
 func (c *current) onSomeDeclList1(head, rest interface{}) (interface{}, error) {
 	return makeSomeDeclSymbols(head, rest)
 }
@@ -3406,6 +4022,16 @@ func (p *parser) callonExprTerm1() (interface{}, error) {
 	return p.cur.onExprTerm1(stack["lhs"], stack["rest"])
 }
 
+func (c *current) onRelationTerm1(lhs, rest interface{}) (interface{}, error) {
+	return makeExprTerm(currentLocation(c), lhs, rest)
+}
+
+func (p *parser) callonRelationTerm1() (interface{}, error) {
+	stack := p.vstack[len(p.vstack)-1]
+	_ = stack
+	return p.cur.onRelationTerm1(stack["lhs"], stack["rest"])
+}
+
 func (c *current) onExprTermPairList1(head, tail interface{}) (interface{}, error) {
 	return makeExprTermPairList(head, tail)
 }
@@ -3436,6 +4062,26 @@ func (p *parser) callonExprTermPair1() (interface{}, error) {
 	return p.cur.onExprTermPair1(stack["key"], stack["value"])
 }
 
+func (c *current) onMembershipOperator3() (bool, error) {
+	return futureKeywordEnabled(c, "in"), nil
+}
+
+func (p *parser) callonMembershipOperator3() (bool, error) {
+	stack := p.vstack[len(p.vstack)-1]
+	_ = stack
+	return p.cur.onMembershipOperator3()
+}
+
+func (c *current) onMembershipOperator1(val interface{}) (interface{}, error) {
+	return makeInfixOperator(currentLocation(c), c.text)
+}
+
+func (p *parser) callonMembershipOperator1() (interface{}, error) {
+	stack := p.vstack[len(p.vstack)-1]
+	_ = stack
+	return p.cur.onMembershipOperator1(stack["val"])
+}
+
 func (c *current) onRelationOperator1(val interface{}) (interface{}, error) {
 	return makeInfixOperator(currentLocation(c), c.text)
 }
@@ -3667,6 +4313,26 @@ func (p *parser) callonRefOperandDot1() (interface{}, error) {
 	return p.cur.onRefOperandDot1(stack["val"])
 }
 
+func (c *current) onFutureKeywordOrVar7(val interface{}) (bool, error) {
+	return IsFutureKeyword(string(val.(*Term).Value.(Var))), nil
+}
+
+func (p *parser) callonFutureKeywordOrVar7() (bool, error) {
+	stack := p.vstack[len(p.vstack)-1]
+	_ = stack
+	return p.cur.onFutureKeywordOrVar7(stack["val"])
+}
+
+func (c *current) onFutureKeywordOrVar3(val interface{}) (interface{}, error) {
+	return val, nil
+}
+
+func (p *parser) callonFutureKeywordOrVar3() (interface{}, error) {
+	stack := p.vstack[len(p.vstack)-1]
+	_ = stack
+	return p.cur.onFutureKeywordOrVar3(stack["val"])
+}
+
 func (c *current) onRefOperandCanonical1(val interface{}) (interface{}, error) {
 	return val, nil
 }
@@ -3688,7 +4354,7 @@ func (p *parser) callonVar1() (interface{}, error) {
 }
 
 func (c *current) onVarChecked4(val interface{}) (bool, error) {
-	return IsKeyword(string(val.(*Term).Value.(Var))), nil
+	return isReserved(c, string(val.(*Term).Value.(Var))), nil
 }
 
 func (p *parser) callonVarChecked4() (bool, error) {
@@ -3835,18 +4501,17 @@ func Entrypoint(ruleName string) Option {
 //
 // Example usage:
 //
-//     input := "input"
-//     stats := Stats{}
-//     _, err := Parse("input-file", []byte(input), Statistics(&stats, "no match"))
-//     if err != nil {
-//         log.Panicln(err)
-//     }
-//     b, err := json.MarshalIndent(stats.ChoiceAltCnt, "", "  ")
-//     if err != nil {
-//         log.Panicln(err)
-//     }
-//     fmt.Println(string(b))
-//
+//	input := "input"
+//	stats := Stats{}
+//	_, err := Parse("input-file", []byte(input), Statistics(&stats, "no match"))
+//	if err != nil {
+//	    log.Panicln(err)
+//	}
+//	b, err := json.MarshalIndent(stats.ChoiceAltCnt, "", "  ")
+//	if err != nil {
+//	    log.Panicln(err)
+//	}
+//	fmt.Println(string(b))
 func Statistics(stats *Stats, choiceNoMatch string) Option {
 	return func(p *parser) Option {
 		oldStats := p.Stats
@@ -5051,3 +5716,18 @@ func (p *parser) parseZeroOrOneExpr(expr *zeroOrOneExpr) (interface{}, bool) {
 	// whether it matched or not, consider it a match
 	return val, true
 }
+
+func rangeTable(class string) *unicode.RangeTable {
+	if rt, ok := unicode.Categories[class]; ok {
+		return rt
+	}
+	if rt, ok := unicode.Properties[class]; ok {
+		return rt
+	}
+	if rt, ok := unicode.Scripts[class]; ok {
+		return rt
+	}
+
+	// cannot happen
+	panic(fmt.Sprintf("invalid Unicode class: %s", class))
+}
@@ -29,6 +29,16 @@ func MustParseBody(input string) Body {
 	return parsed
 }
 
+// MustParseBodyWithOpts returns a parsed body like MustParseBody. The parser
+// is configured with opts.
+func MustParseBodyWithOpts(input string, opts ParserOptions) Body {
+	parsed, err := ParseBodyWithOpts(input, opts)
+	if err != nil {
+		panic(err)
+	}
+	return parsed
+}
+
 // MustParseExpr returns a parsed expression.
 // If an error occurs during parsing, panic.
 func MustParseExpr(input string) *Expr {
@@ -59,6 +69,16 @@ func MustParseModule(input string) *Module {
 	return parsed
 }
 
+// MustParseModuleWithOpts returns a parsed module like MustParseModule. The
+// parser is configured with opts.
+func MustParseModuleWithOpts(input string, opts ParserOptions) *Module {
+	parsed, err := ParseModuleWithOpts("", input, opts)
+	if err != nil {
+		panic(err)
+	}
+	return parsed
+}
+
 // MustParsePackage returns a Package.
 // If an error occurs during parsing, panic.
 func MustParsePackage(input string) *Package {
@@ -109,6 +129,16 @@ func MustParseRule(input string) *Rule {
 	return parsed
 }
 
+// MustParseRuleWithOpts returns a parsed rule like MustParseRule. The parser
+// is configured with opts.
+func MustParseRuleWithOpts(input string, opts ParserOptions) *Rule {
+	parsed, err := ParseRuleWithOpts(input, opts)
+	if err != nil {
+		panic(err)
+	}
+	return parsed
+}
+
 // MustParseTerm returns a parsed term.
 // If an error occurs during parsing, panic.
 func MustParseTerm(input string) *Term {
@@ -158,6 +188,8 @@ func ParseRuleFromExpr(module *Module, expr *Expr) (*Rule, error) {
 
 		if err == nil {
 			return rule, nil
+		} else if rule, err := parseRefHeadRuleFromExpr(module, lhs, rhs, true); err == nil {
+			return rule, nil
 		} else if _, ok := lhs.Value.(Call); ok {
 			return nil, errFunctionAssignOperator
 		} else if _, ok := lhs.Value.(Ref); ok {
@@ -181,6 +213,11 @@ func ParseRuleFromExpr(module *Module, expr *Expr) (*Rule, error) {
 			return rule, nil
 		}
 
+		rule, err = parseRefHeadRuleFromExpr(module, lhs, rhs, false)
+		if err == nil {
+			return rule, nil
+		}
+
 		return ParsePartialObjectDocRuleFromEqExpr(module, lhs, rhs)
 	}
 
@@ -236,6 +273,44 @@ func ParseCompleteDocRuleFromEqExpr(module *Module, lhs, rhs *Term) (*Rule, erro
 	return rule, nil
 }
 
+// parseRefHeadRuleFromExpr returns a complete rule that defines the nested
+// document referred to by lhs, e.g., fruit.apple.color = "red".
+func parseRefHeadRuleFromExpr(module *Module, lhs, rhs *Term, assign bool) (*Rule, error) {
+
+	ref, ok := lhs.Value.(Ref)
+	if !ok || len(ref) < 3 {
+		return nil, fmt.Errorf("%v cannot be used for rule name", TypeName(lhs.Value))
+	}
+
+	name, ok := ref[0].Value.(Var)
+	if !ok {
+		return nil, fmt.Errorf("%v cannot be used for rule name", TypeName(ref[0].Value))
+	}
+
+	for _, x := range ref[1:] {
+		if _, ok := x.Value.(String); !ok {
+			return nil, fmt.Errorf("rule head reference %v must only contain strings", ref)
+		}
+	}
+
+	rule := &Rule{
+		Location: lhs.Location,
+		Head: &Head{
+			Location:  lhs.Location,
+			Name:      name,
+			Reference: ref.Copy(),
+			Value:     rhs,
+			Assign:    assign,
+		},
+		Body: NewBody(
+			NewExpr(BooleanTerm(true).SetLocation(rhs.Location)).SetLocation(rhs.Location),
+		),
+		Module: module,
+	}
+
+	return rule, nil
+}
+
 // ParsePartialObjectDocRuleFromEqExpr returns a rule if the expression can be
 // interpreted as a partial object document definition.
 func ParsePartialObjectDocRuleFromEqExpr(module *Module, lhs, rhs *Term) (*Rule, error) {
@@ -361,11 +436,38 @@ func ParseImports(input string) ([]*Import, error) {
 	return result, nil
 }
 
+// ParserOptions defines the options for parsing Rego statements.
+type ParserOptions struct {
+	// FutureKeywords contains the future keywords to enable in addition to
+	// the keywords imported by the parsed statements (e.g., "in").
+	FutureKeywords []string
+
+	// AllFutureKeywords enables all future keywords.
+	AllFutureKeywords bool
+}
+
+// ParserOptionsFromImports returns parser options that enable the future
+// keywords imported by imports, e.g., to parse a query in the context of a
+// module.
+func ParserOptionsFromImports(imports []*Import) ParserOptions {
+	var opts ParserOptions
+	for _, imp := range imports {
+		opts.FutureKeywords = append(opts.FutureKeywords, imp.FutureKeywords()...)
+	}
+	return opts
+}
+
 // ParseModule returns a parsed Module object.
 // For details on Module objects and their fields, see policy.go.
 // Empty input will return nil, nil.
 func ParseModule(filename, input string) (*Module, error) {
-	stmts, comments, err := ParseStatements(filename, input)
+	return ParseModuleWithOpts(filename, input, ParserOptions{})
+}
+
+// ParseModuleWithOpts returns a parsed Module object like ParseModule. The
+// parser is configured with opts.
+func ParseModuleWithOpts(filename, input string, opts ParserOptions) (*Module, error) {
+	stmts, comments, err := ParseStatementsWithOpts(filename, input, opts)
 	if err != nil {
 		return nil, err
 	}
@@ -375,7 +477,13 @@ func ParseModule(filename, input string) (*Module, error) {
 // ParseBody returns exactly one body.
 // If multiple bodies are parsed, an error is returned.
 func ParseBody(input string) (Body, error) {
-	stmts, _, err := ParseStatements("", input)
+	return ParseBodyWithOpts(input, ParserOptions{})
+}
+
+// ParseBodyWithOpts returns exactly one body like ParseBody. The parser is
+// configured with opts.
+func ParseBodyWithOpts(input string, opts ParserOptions) (Body, error) {
+	stmts, _, err := ParseStatementsWithOpts("", input, opts)
 	if err != nil {
 		return nil, err
 	}
@@ -458,7 +566,13 @@ func ParseRef(input string) (Ref, error) {
 // ParseRule returns exactly one rule.
 // If multiple rules are parsed, an error is returned.
 func ParseRule(input string) (*Rule, error) {
-	stmts, _, err := ParseStatements("", input)
+	return ParseRuleWithOpts(input, ParserOptions{})
+}
+
+// ParseRuleWithOpts returns exactly one rule like ParseRule. The parser is
+// configured with opts.
+func ParseRuleWithOpts(input string, opts ParserOptions) (*Rule, error) {
+	stmts, _, err := ParseStatementsWithOpts("", input, opts)
 	if err != nil {
 		return nil, err
 	}
@@ -516,15 +630,45 @@ func (a commentKey) Compare(other commentKey) int {
 	return 0
 }
 
+// enabledFutureKeywords returns the set of future keywords enabled by opts. The
+// parser adds the keywords enabled by imports to the set as it goes.
+func enabledFutureKeywords(opts ParserOptions) (map[string]bool, error) {
+	enabled := map[string]bool{}
+	if opts.AllFutureKeywords {
+		for _, kw := range FutureKeywords {
+			enabled[kw] = true
+		}
+	}
+	for _, kw := range opts.FutureKeywords {
+		if !IsFutureKeyword(kw) {
+			return nil, fmt.Errorf("unknown future keyword %q: must be one of %v", kw, FutureKeywords)
+		}
+		enabled[kw] = true
+	}
+	return enabled, nil
+}
+
 // ParseStatements returns a slice of parsed statements.
 // This is the default return value from the parser.
 func ParseStatements(filename, input string) ([]Statement, []*Comment, error) {
+	return ParseStatementsWithOpts(filename, input, ParserOptions{})
+}
+
+// ParseStatementsWithOpts returns a slice of parsed statements like
+// ParseStatements. The parser is configured with opts.
+func ParseStatementsWithOpts(filename, input string, opts ParserOptions) ([]Statement, []*Comment, error) {
+
+	enabled, err := enabledFutureKeywords(opts)
+	if err != nil {
+		return nil, nil, err
+	}
 
 	bs := []byte(input)
+	fk := GlobalStore(futureKeywordsKey, enabled)
 
-	parsed, err := Parse(filename, bs, GlobalStore(filenameKey, filename), CommentsOption())
+	parsed, err := Parse(filename, bs, GlobalStore(filenameKey, filename), CommentsOption(), fk)
 	if err != nil {
-		return nil, nil, formatParserErrors(filename, bs, err)
+		return nil, nil, recoverParserErrors(filename, bs, enabled, fk, err)
 	}
 
 	var comments []*Comment
@@ -562,19 +706,151 @@ func ParseStatements(filename, input string) ([]Statement, []*Comment, error) {
 	return stmts, comments, postProcess(filename, stmts)
 }
 
-func formatParserErrors(filename string, bs []byte, err error) error {
+// maxParseErrors is the maximum number of syntax errors reported for a single
+// input.
+const maxParseErrors = 10
+
+// recoverParserErrors returns the syntax errors in bs. The parser stops at the
+// first syntax error so after each error, the statement containing the error
+// is blanked out and the input is parsed again. This way errors in subsequent
+// statements are reported as well. The enabled future keywords are not
+// suggested in hints.
+func recoverParserErrors(filename string, bs []byte, enabled map[string]bool, fk Option, err error) error {
+
+	errs := formatParserErrors(filename, bs, enabled, err)
+	buf := make([]byte, len(bs))
+	copy(buf, bs)
+
+	for len(errs) < maxParseErrors {
+
+		last := errs[len(errs)-1].Location.Row
+
+		if !blankStatement(buf, last) {
+			break
+		}
+
+		_, err := Parse(filename, buf, GlobalStore(filenameKey, filename), CommentsOption(), fk)
+		if err == nil {
+			break
+		}
+
+		next := formatParserErrors(filename, bs, enabled, err)
+
+		// Errors before the blanked out statement indicate that the statement
+		// boundaries were guessed incorrectly. Stop to avoid reporting bogus
+		// errors.
+		if next[0].Location.Row <= last {
+			break
+		}
+
+		errs = append(errs, next...)
+	}
+
+	if len(errs) > maxParseErrors {
+		errs = errs[:maxParseErrors]
+	}
+
+	return errs
+}
+
+// blankStatement replaces the statement spanning row with whitespace.
+// Statements are assumed to begin on lines indented the same as the first
+// statement in the input (typically the package declaration) that do not begin
+// with a closing bracket or comment. Line breaks are kept so that the
+// locations of subsequent errors remain valid. Returns false if there was
+// nothing to blank out.
+func blankStatement(bs []byte, row int) bool {
+
+	starts := []int{0}
+	for i := range bs {
+		if bs[i] == '\n' {
+			starts = append(starts, i+1)
+		}
+	}
+
+	if row < 1 || row > len(starts) {
+		return false
+	}
+
+	// indent returns the offset of the first non-whitespace character on the
+	// line or -1 if the line is blank.
+	indent := func(line int) int {
+		for i := starts[line]; i < len(bs) && bs[i] != '\n'; i++ {
+			if bs[i] != ' ' && bs[i] != '\t' && bs[i] != '\r' {
+				return i - starts[line]
+			}
+		}
+		return -1
+	}
+
+	base := -1
+	for line := range starts {
+		if n := indent(line); n >= 0 && bs[starts[line]+n] != '#' {
+			base = n
+			break
+		}
+	}
+
+	isStart := func(line int) bool {
+		n := indent(line)
+		if n < 0 || n != base {
+			return false
+		}
+		switch bs[starts[line]+n] {
+		case '#', '}', ']', ')':
+			return false
+		}
+		return true
+	}
+
+	first := row - 1
+	for first > 0 && !isStart(first) {
+		first--
+	}
+
+	last := row
+	for last < len(starts) && !isStart(last) {
+		last++
+	}
+
+	end := len(bs)
+	if last < len(starts) {
+		end = starts[last]
+	}
+
+	var blanked bool
+
+	for i := starts[first]; i < end; i++ {
+		if bs[i] != '\n' && bs[i] != ' ' {
+			bs[i] = ' '
+			blanked = true
+		}
+	}
+
+	return blanked
+}
+
+func formatParserErrors(filename string, bs []byte, enabled map[string]bool, err error) Errors {
 	// Errors returned by the parser are always of type errList and the errList
 	// always contains *parserError.
-	// https://godoc.org/github.com/mna/pigeon#hdr-Error_reporting.
-	errs := err.(errList)
+	// https://godoc.org/github.com/mna/pigeon#hdr-Error_reporting. If that
+	// changes, report the error without location details instead of panicking.
+	errs, ok := err.(errList)
+	if !ok || len(errs) == 0 {
+		return Errors{NewError(ParseErr, NewLocation(nil, filename, 1, 1), err.Error())}
+	}
 	r := make(Errors, len(errs))
 	for i, e := range errs {
-		r[i] = formatParserError(filename, bs, e.(*parserError))
+		if pe, ok := e.(*parserError); ok {
+			r[i] = formatParserError(filename, bs, enabled, pe)
+		} else {
+			r[i] = NewError(ParseErr, NewLocation(nil, filename, 1, 1), e.Error())
+		}
 	}
 	return r
 }
 
-func formatParserError(filename string, bs []byte, e *parserError) *Error {
+func formatParserError(filename string, bs []byte, enabled map[string]bool, e *parserError) *Error {
 	loc := NewLocation(nil, filename, e.pos.line, e.pos.col)
 	inner := e.Inner.Error()
 	idx := strings.Index(inner, "no match found")
@@ -583,12 +859,98 @@ func formatParserError(filename string, bs []byte, e *parserError) *Error {
 		// include ", expected: ..." as it does not provide any value, so truncate the
 		// string here.
 		inner = inner[:idx+14]
+		if kw := futureKeywordNear(bs, e.pos.offset); kw != "" && !enabled[kw] {
+			inner += fmt.Sprintf(" (hint: import future.keywords.%v to use the %v keyword)", kw, kw)
+		}
 	}
 	err := NewError(ParseErr, loc, inner)
 	err.Details = newParserErrorDetail(bs, e.pos)
 	return err
 }
 
+// futureKeywordNear returns the future keyword at offset or the future keyword
+// preceding offset if the parser failed because the keyword is not enabled.
+// Otherwise, the result is empty.
+func futureKeywordNear(bs []byte, offset int) string {
+
+	isVarChar := func(b byte) bool {
+		return b == '_' || (b >= 'a' && b <= 'z') || (b >= 'A' && b <= 'Z') || (b >= '0' && b <= '9')
+	}
+
+	word := func(start int) string {
+		end := start
+		for end < len(bs) && isVarChar(bs[end]) {
+			end++
+		}
+		return string(bs[start:end])
+	}
+
+	if offset > len(bs) {
+		return ""
+	}
+
+	if w := word(offset); IsFutureKeyword(w) {
+		return w
+	}
+
+	i := offset - 1
+	for i >= 0 && (bs[i] == ' ' || bs[i] == '\t') {
+		i--
+	}
+
+	end := i + 1
+	for i >= 0 && isVarChar(bs[i]) {
+		i--
+	}
+
+	if w := string(bs[i+1 : end]); IsFutureKeyword(w) {
+		return w
+	}
+
+	return ""
+}
+
+// futureKeywordHints returns the future keywords that appear as standalone
+// statements, rule bodies, or rule names keyed by row. Statements like these
+// are parsed when future keywords are used in rule definitions without being
+// enabled. Keywords imported by the statements are not included.
+func futureKeywordHints(stmts []Statement) map[int]string {
+	imported := map[string]bool{}
+	for _, stmt := range stmts {
+		if imp, ok := stmt.(*Import); ok {
+			for _, kw := range imp.FutureKeywords() {
+				imported[kw] = true
+			}
+		}
+	}
+	hints := map[int]string{}
+	add := func(loc *Location, v Var) {
+		if _, ok := hints[loc.Row]; !ok && IsFutureKeyword(string(v)) && !imported[string(v)] {
+			hints[loc.Row] = string(v)
+		}
+	}
+	addBody := func(body Body) {
+		if len(body) != 1 {
+			return
+		}
+		if term, ok := body[0].Terms.(*Term); ok {
+			if v, ok := term.Value.(Var); ok {
+				add(body[0].Location, v)
+			}
+		}
+	}
+	for _, stmt := range stmts {
+		switch stmt := stmt.(type) {
+		case Body:
+			addBody(stmt)
+		case *Rule:
+			addBody(stmt.Body)
+			add(stmt.Location, stmt.Head.Name)
+		}
+	}
+	return hints
+}
+
 func parseModule(stmts []Statement, comments []*Comment) (*Module, error) {
 
 	if len(stmts) == 0 {
@@ -610,6 +972,8 @@ func parseModule(stmts []Statement, comments []*Comment) (*Module, error) {
 	// The comments slice only holds comments that were not their own statements.
 	mod.Comments = append(mod.Comments, comments...)
 
+	hints := futureKeywordHints(stmts)
+
 	for _, stmt := range stmts[1:] {
 		switch stmt := stmt.(type) {
 		case *Import:
@@ -620,7 +984,11 @@ func parseModule(stmts []Statement, comments []*Comment) (*Module, error) {
 		case Body:
 			rule, err := ParseRuleFromBody(mod, stmt)
 			if err != nil {
-				errs = append(errs, NewError(ParseErr, stmt[0].Location, err.Error()))
+				msg := err.Error()
+				if kw, ok := hints[stmt[0].Location.Row]; ok {
+					msg += fmt.Sprintf(" (hint: import future.keywords.%v to use the %v keyword)", kw, kw)
+				}
+				errs = append(errs, NewError(ParseErr, stmt[0].Location, msg))
 			} else {
 				mod.Rules = append(mod.Rules, rule)
 			}
@@ -657,11 +1025,12 @@ func postProcess(filename string, stmts []Statement) error {
 
 func mangleDataVars(stmts []Statement) error {
 	for i := range stmts {
-		vt := newVarToRefTransformer(DefaultRootDocument.Value.(Var), DefaultRootRef.Copy())
+		vt := newVarToRefTransformer(DefaultRootDocument.Value.(Var), DefaultRootRef)
 		stmt, err := Transform(vt, stmts[i])
 		if err != nil {
 			return err
 		}
+		setRefHeadLocations(stmt)
 		stmts[i] = stmt.(Statement)
 	}
 	return nil
@@ -669,16 +1038,29 @@ func mangleDataVars(stmts []Statement) error {
 
 func mangleInputVars(stmts []Statement) error {
 	for i := range stmts {
-		vt := newVarToRefTransformer(InputRootDocument.Value.(Var), InputRootRef.Copy())
+		vt := newVarToRefTransformer(InputRootDocument.Value.(Var), InputRootRef)
 		stmt, err := Transform(vt, stmts[i])
 		if err != nil {
 			return err
 		}
+		setRefHeadLocations(stmt)
 		stmts[i] = stmt.(Statement)
 	}
 	return nil
 }
 
+// setRefHeadLocations sets the location of ref heads that were introduced by
+// rewriting vars to refs (e.g., input => input) to the location of the ref
+// term itself.
+func setRefHeadLocations(x interface{}) {
+	WalkTerms(x, func(t *Term) bool {
+		if r, ok := t.Value.(Ref); ok && r[0].Location == nil {
+			r[0].Location = t.Location
+		}
+		return false
+	})
+}
+
 func mangleExprIndices(stmts []Statement) {
 	for _, stmt := range stmts {
 		setExprIndices(stmt)
@@ -757,7 +1139,7 @@ func (vt *varToRefTransformer) Transform(x interface{}) (interface{}, error) {
 	case Var:
 		if x.Equal(vt.orig) {
 			vt.skip = true
-			return vt.target, nil
+			return vt.target.Copy(), nil
 		}
 	}
 	return x, nil
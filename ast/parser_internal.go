@@ -9,6 +9,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"math/big"
+	"strings"
 )
 
 const (
@@ -17,6 +18,10 @@ const (
 
 	// filenameKey is the global map key for the filename.
 	filenameKey = "filename"
+
+	// futureKeywordsKey is the global map key for the set of enabled future
+	// keywords.
+	futureKeywordsKey = "future_keywords"
 )
 
 type program struct {
@@ -24,6 +29,11 @@ type program struct {
 	comments interface{}
 }
 
+// containsHead is a rule head defined with the "contains" keyword.
+type containsHead struct {
+	head *Head
+}
+
 type ruleExt struct {
 	loc  *Location
 	term *Term
@@ -35,6 +45,18 @@ func currentLocation(c *current) *Location {
 	return NewLocation(c.text, c.globalStore[filenameKey].(string), c.pos.line, c.pos.col)
 }
 
+// futureKeywordEnabled returns true if the future keyword kw has been enabled
+// by the parser options or an import that precedes the current statement.
+func futureKeywordEnabled(c *current, kw string) bool {
+	enabled, _ := c.globalStore[futureKeywordsKey].(map[string]bool)
+	return enabled[kw]
+}
+
+// isReserved returns true if s cannot be used as a variable name.
+func isReserved(c *current, s string) bool {
+	return IsKeyword(s) || futureKeywordEnabled(c, s)
+}
+
 func makeProgram(c *current, vals interface{}) (interface{}, error) {
 	var buf []interface{}
 	if vals == nil {
@@ -84,13 +106,27 @@ func makePackage(loc *Location, value interface{}) (interface{}, error) {
 	return pkg, nil
 }
 
-func makeImport(loc *Location, path, alias interface{}) (interface{}, error) {
+func makeImport(c *current, loc *Location, path, alias interface{}) (interface{}, error) {
 	imp := &Import{}
 	imp.Location = loc
 	imp.Path = path.(*Term)
 	if err := IsValidImportPath(imp.Path.Value); err != nil {
 		return nil, err
 	}
+	if kws := imp.FutureKeywords(); kws != nil {
+		if alias != nil {
+			return nil, fmt.Errorf("future keyword imports cannot be aliased")
+		}
+		enabled, ok := c.globalStore[futureKeywordsKey].(map[string]bool)
+		if !ok {
+			enabled = map[string]bool{}
+			c.globalStore[futureKeywordsKey] = enabled
+		}
+		for _, kw := range kws {
+			enabled[kw] = true
+		}
+		return imp, nil
+	}
 	if alias == nil {
 		return imp, nil
 	}
@@ -146,7 +182,7 @@ func makeDefaultRule(loc *Location, name, operator, value interface{}) (interfac
 	return []*Rule{rule}, nil
 }
 
-func makeRule(loc *Location, head, rest interface{}) (interface{}, error) {
+func makeRule(loc *Location, head interface{}, ifSyntax bool, rest interface{}) (interface{}, error) {
 
 	if head == nil {
 		return nil, nil
@@ -154,11 +190,20 @@ func makeRule(loc *Location, head, rest interface{}) (interface{}, error) {
 
 	sl := rest.([]interface{})
 
+	if ch, ok := head.(containsHead); ok {
+		head, ifSyntax = ch.head, true
+	}
+
+	if err := checkRuleHeadRef(head.(*Head)); err != nil {
+		return nil, err
+	}
+
 	rules := []*Rule{
 		{
 			Location: loc,
 			Head:     head.(*Head),
 			Body:     sl[0].(Body),
+			IfSyntax: ifSyntax,
 		},
 	}
 
@@ -182,6 +227,7 @@ func makeRule(loc *Location, head, rest interface{}) (interface{}, error) {
 				Location: re.loc,
 				Head:     prev.Head.Copy(),
 				Body:     re.body,
+				IfSyntax: ifSyntax,
 			})
 		} else {
 			if (rules[0].Head.DocKind() != CompleteDoc) || (i != 0 && !ordered) {
@@ -191,12 +237,14 @@ func makeRule(loc *Location, head, rest interface{}) (interface{}, error) {
 			curr := &Rule{
 				Location: re.loc,
 				Head: &Head{
-					Name:     prev.Head.Name,
-					Args:     prev.Head.Args.Copy(),
-					Value:    re.term,
-					Location: re.term.Location,
+					Name:      prev.Head.Name,
+					Reference: prev.Head.Reference.Copy(),
+					Args:      prev.Head.Args.Copy(),
+					Value:     re.term,
+					Location:  re.term.Location,
 				},
-				Body: re.body,
+				Body:     re.body,
+				IfSyntax: ifSyntax,
 			}
 			prev.Else = curr
 			prev = curr
@@ -206,13 +254,69 @@ func makeRule(loc *Location, head, rest interface{}) (interface{}, error) {
 	return rules, nil
 }
 
-func makeRuleHead(loc *Location, name, args, key, value interface{}) (interface{}, error) {
+// checkRuleHeadRef validates dotted rule heads. Heads like p.q are equivalent
+// to p["q"] and define partial objects. Longer heads define the document at
+// the nested path.
+func checkRuleHeadRef(head *Head) error {
+
+	if len(head.Reference) == 0 {
+		return nil
+	}
+
+	if head.Args != nil || head.Key != nil {
+		return errRefHeadArgsOrKey
+	}
+
+	if len(head.Reference) == 2 {
+		if head.Assign {
+			return errPartialRuleAssignOperator
+		}
+		head.Key = head.Reference[1]
+		head.Reference = nil
+	}
+
+	return nil
+}
+
+func makeContainsRuleHead(loc *Location, name, key interface{}) (interface{}, error) {
+	head := &Head{
+		Location: loc,
+		Name:     name.(*Term).Value.(Var),
+		Key:      key.(*Term),
+	}
+	return containsHead{head}, nil
+}
+
+func makeRuleHead(loc *Location, name, path, args, key, value interface{}) (interface{}, error) {
 
 	head := &Head{}
 
 	head.Location = loc
 	head.Name = name.(*Term).Value.(Var)
 
+	// Dotted heads are validated once the rule has been parsed because they
+	// may also match the start of other statements (e.g., a.b[0] = 1).
+	if segments, ok := path.([]interface{}); ok && len(segments) > 0 {
+		head.Reference = Ref{name.(*Term)}
+		for _, segment := range segments {
+			v := segment.([]interface{})[1].(*Term)
+			head.Reference = append(head.Reference, StringTerm(string(v.Value.(Var))).SetLocation(v.Location))
+		}
+		if args != nil || key != nil {
+			// Mark the head so that checkRuleHeadRef reports the error.
+			head.Args = Args{}
+			return head, nil
+		}
+		if value != nil {
+			valueSlice := value.([]interface{})
+			head.Assign = string(valueSlice[1].([]uint8)) == Assign.Infix
+			head.Value = valueSlice[len(valueSlice)-1].(*Term)
+		} else {
+			head.Value = BooleanTerm(true).SetLocation(head.Location)
+		}
+		return head, nil
+	}
+
 	if args != nil && key != nil {
 		return nil, fmt.Errorf("partial rules cannot take arguments")
 	}
@@ -271,7 +375,7 @@ func makeArgs(list interface{}) (interface{}, error) {
 
 func makeRuleExt(loc *Location, val, b interface{}) (interface{}, error) {
 	bs := b.([]interface{})
-	body := bs[1].(Body)
+	body := bs[2].(Body)
 
 	if val == nil {
 		term := BooleanTerm(true)
@@ -323,6 +427,43 @@ func makeSomeDeclLiteral(loc *Location, sl interface{}) (interface{}, error) {
 	return NewExpr(&SomeDecl{Location: loc, Symbols: symbols}).SetLocation(loc), nil
 }
 
+func makeSomeDeclIn(loc *Location, key, value, collection interface{}) (interface{}, error) {
+
+	ref := Member.Ref()
+	var operands []*Term
+
+	if key != nil {
+		ref = MemberWithKey.Ref()
+		operands = append(operands, key.([]interface{})[0].(*Term))
+	}
+
+	operands = append(operands, value.(*Term), collection.(*Term))
+
+	for i := range ref {
+		ref[i].SetLocation(loc)
+	}
+
+	call := append(Call{NewTerm(ref).SetLocation(loc)}, operands...)
+
+	return []*Term{NewTerm(call).SetLocation(loc)}, nil
+}
+
+func makeEvery(loc *Location, key, value, domain, body interface{}) (interface{}, error) {
+
+	every := &Every{
+		Location: loc,
+		Value:    value.(*Term),
+		Domain:   domain.(*Term),
+		Body:     body.(Body),
+	}
+
+	if key != nil {
+		every.Key = key.([]interface{})[0].(*Term)
+	}
+
+	return NewExpr(every).SetLocation(loc), nil
+}
+
 func makeSomeDeclSymbols(head interface{}, rest interface{}) (interface{}, error) {
 
 	var symbols []*Term
@@ -481,12 +622,16 @@ func makeExprTermPair(key, value interface{}) (interface{}, error) {
 
 func makeInfixOperator(loc *Location, text []byte) (interface{}, error) {
 	op := string(text)
+	ref := Ref{VarTerm(op)}
 	for _, b := range Builtins {
 		if string(b.Infix) == op {
-			op = string(b.Name)
+			ref = b.Ref()
 		}
 	}
-	operator := RefTerm(VarTerm(op).SetLocation(loc)).SetLocation(loc)
+	for i := range ref {
+		ref[i].SetLocation(loc)
+	}
+	operator := NewTerm(ref).SetLocation(loc)
 	return operator, nil
 }
 
@@ -543,8 +688,17 @@ func makeVar(loc *Location, text interface{}) (interface{}, error) {
 }
 
 func makeNumber(loc *Location, text interface{}) (interface{}, error) {
-	f, ok := new(big.Float).SetString(string(text.([]byte)))
-	if !ok {
+	str := string(text.([]byte))
+
+	if strings.HasPrefix(str, "0x") || strings.HasPrefix(str, "-0x") || strings.HasPrefix(str, "0X") || strings.HasPrefix(str, "-0X") {
+		return makeHexNumber(loc, str)
+	}
+
+	// Use enough precision to represent all of the significant digits in the
+	// literal so that large integers and long fractions are not rounded.
+	digits := significantDigits(str)
+	f, _, err := big.ParseFloat(str, 10, uint(digits)*4+64, big.ToNearestEven)
+	if err != nil {
 		// This indicates the grammar is out-of-sync with what the string
 		// representation of floating point numbers. This should not be
 		// possible.
@@ -563,7 +717,52 @@ func makeNumber(loc *Location, text interface{}) (interface{}, error) {
 		return nil, fmt.Errorf("number too big")
 	}
 
-	return NumberTerm(json.Number(f.String())).SetLocation(loc), nil
+	if digits < 10 {
+		digits = 10
+	}
+
+	return NumberTerm(json.Number(f.Text('g', digits))).SetLocation(loc), nil
+}
+
+func makeHexNumber(loc *Location, str string) (interface{}, error) {
+	neg := strings.HasPrefix(str, "-")
+	if neg {
+		str = str[1:]
+	}
+
+	i, ok := new(big.Int).SetString(str[2:], 16)
+	if !ok {
+		panic("illegal value") // Indicates grammar is out-of-sync with code.
+	}
+
+	// Apply the same limit as for decimal numbers.
+	if i.BitLen() > 1e5 {
+		return nil, fmt.Errorf("number too big")
+	}
+
+	if neg {
+		i.Neg(i)
+	}
+
+	return NumberTerm(json.Number(i.String())).SetLocation(loc), nil
+}
+
+// significantDigits returns the number of digits in the mantissa of the
+// decimal number literal str, excluding leading zeros.
+func significantDigits(str string) int {
+	var n int
+	var leading = true
+	for _, r := range str {
+		if r == 'e' || r == 'E' {
+			break
+		}
+		if r < '0' || r > '9' || (leading && r == '0') {
+			continue
+		}
+		leading = false
+		n++
+	}
+	return n
 }
 
 func makeString(loc *Location, text interface{}) (interface{}, error) {
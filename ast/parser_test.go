@@ -5,6 +5,7 @@
 package ast
 
 import (
+	"bytes"
 	"encoding/json"
 	"fmt"
 	"reflect"
@@ -64,6 +65,9 @@ func TestNumberTerms(t *testing.T) {
 		{"-.1", "-0.1"},
 		{"-0.0001", "-0.0001"},
 		{"1e1000", "1e1000"},
+		{"0x1f", "31"},
+		{"0XFF", "255"},
+		{"-0x10", "-16"},
 	}
 
 	for _, tc := range tests {
@@ -79,6 +83,37 @@ func TestNumberTerms(t *testing.T) {
 	}
 }
 
+func TestNumberTermsPrecision(t *testing.T) {
+
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"1e6", "1000000"},
+		{"-0.5", "-0.5"},
+		{"1.0", "1"},
+		{"12345678901234567890", "12345678901234567890"},
+		{"-98765432109876543210987654321", "-98765432109876543210987654321"},
+		{"3.14159265358979323846264338327950288", "3.14159265358979323846264338327950288"},
+		{"0.000000000000000000012345678901234567", "1.2345678901234567e-20"},
+		{"1e20", "1e+20"},
+		{"0x7fffffffffffffffffff", "604462909807314587353087"},
+	}
+
+	for _, tc := range tests {
+		result, err := ParseTerm(tc.input)
+		if err != nil {
+			t.Errorf("Unexpected error for %v: %v", tc.input, err)
+		} else if result.String() != tc.expected {
+			t.Errorf("Expected %v for %v but got: %v", tc.expected, tc.input, result)
+		}
+	}
+
+	if _, err := ParseTerm("0x" + strings.Repeat("f", 30000)); err == nil {
+		t.Fatal("Expected error for large hex number")
+	}
+}
+
 func TestStringTerms(t *testing.T) {
 	tests := []struct {
 		input    string
@@ -123,6 +158,9 @@ func TestScalarTerms(t *testing.T) {
 	assertParseOneTerm(t, "string", "\"a string\"", StringTerm("a string"))
 	assertParseOneTerm(t, "string", "\"a string u6abc7def8abc0def with unicode\"", StringTerm("a string u6abc7def8abc0def with unicode"))
 	assertParseError(t, "hex", "6abc")
+	assertParseError(t, "hex2", "0x")
+	assertParseError(t, "hex3", "0xfg")
+	assertParseError(t, "hex4", "0x1.5")
 	assertParseError(t, "non-terminated", "\"foo")
 	assertParseError(t, "non-terminated-raw", "`foo")
 	assertParseError(t, "non-string", "'a string'")
@@ -145,6 +183,13 @@ func TestVarTerms(t *testing.T) {
 	assertParseOneTerm(t, `true prefix`, "trueish", VarTerm("trueish"))
 	assertParseOneTerm(t, `false prefix`, "false_flag", VarTerm("false_flag"))
 	assertParseOneTerm(t, `null prefix`, "nullable", VarTerm("nullable"))
+	assertParseOneTerm(t, "unicode", "größe", VarTerm("größe"))
+	assertParseOneTerm(t, "unicode start", "ñame", VarTerm("ñame"))
+	assertParseOneTerm(t, "unicode non-latin", "名前", VarTerm("名前"))
+	assertParseOneTerm(t, "unicode digits", "x١٢", VarTerm("x١٢"))
+	assertParseOneTerm(t, "unicode ref", "input.größe", RefTerm(VarTerm("input"), StringTerm("größe")))
+	assertParseError(t, "unicode digit start", "١x")
+	assertParseError(t, "unicode symbol", "x€")
 	assertParseError(t, "not keyword", "not")
 	assertParseError(t, `package keyword`, "package")
 	assertParseError(t, "import keyword", "import")
@@ -421,6 +466,15 @@ func TestInfixExpr(t *testing.T) {
 
 	assertParseOneExpr(t, "call", "count([true, false]) = x", Equality.Expr(Count.Call(ArrayTerm(BooleanTerm(true), BooleanTerm(false))), VarTerm("x")))
 	assertParseOneExpr(t, "call-reverse", "x = count([true, false])", Equality.Expr(VarTerm("x"), Count.Call(ArrayTerm(BooleanTerm(true), BooleanTerm(false)))))
+
+	assertParseOneExpr(t, "in", "x in xs", Member.Expr(VarTerm("x"), VarTerm("xs")), futureKeywords)
+	assertParseOneExpr(t, "in composite", `"a" in {"a", "b"}`, Member.Expr(StringTerm("a"), SetTerm(StringTerm("a"), StringTerm("b"))), futureKeywords)
+	assertParseOneExpr(t, "in precedence", "x in xs == y", Member.Expr(VarTerm("x"), Equal.Call(VarTerm("xs"), VarTerm("y"))), futureKeywords)
+	assertParseOneExpr(t, "in assigned", "y := x in xs", Assign.Expr(VarTerm("y"), Member.Call(VarTerm("x"), VarTerm("xs"))), futureKeywords)
+	assertParseOneExpr(t, "in var", "in = 1", Equality.Expr(VarTerm("in"), IntNumberTerm(1)))
+	assertParseOneExpr(t, "in ref", "x in input.in", Member.Expr(VarTerm("x"), MustParseTerm("input.in")), futureKeywords)
+	assertParseOneExprNegated(t, "not in", "not x in xs", Member.Expr(VarTerm("x"), VarTerm("xs")), futureKeywords)
+	assertParseError(t, "in missing collection", "x in")
 }
 
 func TestNegatedExpr(t *testing.T) {
@@ -474,8 +528,91 @@ func TestExprWith(t *testing.T) {
 	})
 }
 
+// futureKeywords enables all future keywords when parsing test inputs.
+var futureKeywords = ParserOptions{AllFutureKeywords: true}
+
+func TestEveryExpr(t *testing.T) {
+
+	assertParseOneExpr(t, "value", "every x in xs { x }", &Expr{
+		Terms: &Every{
+			Value:  VarTerm("x"),
+			Domain: VarTerm("xs"),
+			Body:   NewBody(NewExpr(VarTerm("x"))),
+		},
+	}, futureKeywords)
+
+	assertParseOneExpr(t, "key and value", `every k, v in input.xs { k != "a"; v > 0 }`, &Expr{
+		Terms: &Every{
+			Key:    VarTerm("k"),
+			Value:  VarTerm("v"),
+			Domain: MustParseTerm("input.xs"),
+			Body: NewBody(
+				NotEqual.Expr(VarTerm("k"), StringTerm("a")),
+				GreaterThan.Expr(VarTerm("v"), IntNumberTerm(0)),
+			),
+		},
+	}, futureKeywords)
+
+	assertParseOneExpr(t, "composite domain", "every x in {1, 2} { x }", &Expr{
+		Terms: &Every{
+			Value:  VarTerm("x"),
+			Domain: SetTerm(IntNumberTerm(1), IntNumberTerm(2)),
+			Body:   NewBody(NewExpr(VarTerm("x"))),
+		},
+	}, futureKeywords)
+
+	assertParseRule(t, "whitespace separated", `
+
+		p {
+			every x in xs {
+				x > 0
+
+				x < 10
+			}
+			q
+		}
+	`, &Rule{
+		Head: NewHead(Var("p"), nil, BooleanTerm(true)),
+		Body: NewBody(
+			NewExpr(&Every{
+				Value:  VarTerm("x"),
+				Domain: VarTerm("xs"),
+				Body: NewBody(
+					GreaterThan.Expr(VarTerm("x"), IntNumberTerm(0)),
+					LessThan.Expr(VarTerm("x"), IntNumberTerm(10)),
+				),
+			}),
+			NewExpr(VarTerm("q")),
+		),
+	}, futureKeywords)
+
+	assertParseOneExpr(t, "var", "every = 1", Equality.Expr(VarTerm("every"), IntNumberTerm(1)))
+	assertParseOneExpr(t, "ref", "every[x]", NewExpr(RefTerm(VarTerm("every"), VarTerm("x"))))
+	assertParseError(t, "empty body", "every x in xs { }")
+	assertParseError(t, "non-var value", "every [x] in xs { x }")
+	assertParseError(t, "missing domain", "every x { x }")
+}
+
 func TestSomeDeclExpr(t *testing.T) {
 
+	assertParseOneExpr(t, "in", "some x in xs", &Expr{
+		Terms: &SomeDecl{
+			Symbols: []*Term{
+				Member.Call(VarTerm("x"), VarTerm("xs")),
+			},
+		},
+	}, futureKeywords)
+
+	assertParseOneExpr(t, "in with key", "some k, [v, 1] in input.xs", &Expr{
+		Terms: &SomeDecl{
+			Symbols: []*Term{
+				MemberWithKey.Call(VarTerm("k"), ArrayTerm(VarTerm("v"), IntNumberTerm(1)), MustParseTerm("input.xs")),
+			},
+		},
+	}, futureKeywords)
+
+	assertParseError(t, "in with multiple keys", "some a, b, c in xs")
+
 	assertParseOneExpr(t, "one", "some x", &Expr{
 		Terms: &SomeDecl{
 			Symbols: []*Term{
@@ -682,6 +819,25 @@ func TestMultiLineBody(t *testing.T) {
 	if !body2.Equal(expected1) {
 		t.Errorf("Expected unenclosed body to equal %v but got: %v", expected1, body1)
 	}
+
+	// Check that expressions may also be separated by commas.
+	input3 := `x = 1, y = 2, z = [ i | [x,y] = arr, arr[_] = i]`
+
+	body3, err := ParseBody(input3)
+	if err != nil {
+		t.Fatalf("Unexpected parse error on comma separated body: %v", err)
+	}
+
+	if !body3.Equal(expected1) {
+		t.Errorf("Expected comma separated body to equal %v but got: %v", expected1, body3)
+	}
+
+	body4, err := ParseBody(`some x, y; f(x, y), {x, y} = z`)
+	if err != nil {
+		t.Fatalf("Unexpected parse error on comma separated body: %v", err)
+	} else if len(body4) != 3 {
+		t.Errorf("Expected 3 expressions but got: %v", body4)
+	}
 }
 
 func TestPackage(t *testing.T) {
@@ -938,6 +1094,243 @@ func TestRule(t *testing.T) {
 	assertParseErrorContains(t, "else assignment", `p := y { true } else = 2 { true } `, "else keyword cannot be used on rule declared with := operator")
 }
 
+func TestRuleIfSyntax(t *testing.T) {
+
+	mod := MustParseModule(`package test
+	import future.keywords
+
+	p contains x if { x := input.xs[_] }
+	q[x] if { x := input.ys[_] }
+	r contains "a" { true }
+	s := 1 if { input.s }
+	t if { true }
+	f(x) = y if { y := x + 1 }
+	g = 1 if { false } else = 2 if { true }
+	u if input.u
+	v if every x in input.xs { x > 0 }
+	w if { every x in input.xs { x > 0 } }
+	h { true }
+	`)
+
+	classic := MustParseModule(`package test
+	import future.keywords
+
+	p[x] { x := input.xs[_] }
+	q[x] { x := input.ys[_] }
+	r["a"] { true }
+	s := 1 { input.s }
+	t { true }
+	f(x) = y { y := x + 1 }
+	g = 1 { false } else = 2 { true }
+	u { input.u }
+	v { every x in input.xs { x > 0 } }
+	w { every x in input.xs { x > 0 } }
+	h { true }
+	`)
+
+	if !mod.Equal(classic) {
+		t.Fatalf("Expected:\n%v\n\nGot:\n%v", classic, mod)
+	}
+
+	for i, rule := range mod.Rules {
+		exp := i < 10
+		if rule.IfSyntax != exp {
+			t.Errorf("Expected rule %v to have IfSyntax %v", rule.Head.Name, exp)
+		}
+		if rule.Else != nil && rule.Else.IfSyntax != exp {
+			t.Errorf("Expected else of rule %v to have IfSyntax %v", rule.Head.Name, exp)
+		}
+	}
+
+	assertParseError(t, "contains with value", "p contains x = 1 if { true }")
+	assertParseError(t, "if without body", "p if")
+}
+
+func TestFutureKeywords(t *testing.T) {
+
+	// Future keywords can be used as names until they are enabled.
+	mod := MustParseModule(`package test
+
+	contains = 1
+	if { contains = 1 }
+	every[x] { x := input.every[_] }
+	in(x) = x
+	p { in(1); f(every) }
+	`)
+
+	if len(mod.Rules) != 5 {
+		t.Fatalf("Expected 5 rules but got: %v", mod.Rules)
+	}
+
+	tests := []struct {
+		note   string
+		module string
+		opts   ParserOptions
+		err    string
+	}{
+		{
+			note:   "import all",
+			module: "package test\nimport future.keywords\np if { every x in input { x in input } }",
+		},
+		{
+			note:   "import one",
+			module: "package test\nimport future.keywords.in\np { 1 in input }",
+		},
+		{
+			note:   "import enables subsequent statements only",
+			module: "package test\nin = 1\nimport future.keywords.in\np { in = 1 }",
+			err:    "test.rego:4: rego_parse_error",
+		},
+		{
+			note:   "import does not enable other keywords",
+			module: "package test\nimport future.keywords.in\np { every x in input { x } }",
+			err:    "hint: import future.keywords.every to use the every keyword",
+		},
+		{
+			note:   "if every without every import",
+			module: "package test\nimport future.keywords.if\np if every x in input { x }",
+			err:    "hint: import future.keywords.every to use the every keyword",
+		},
+		{
+			note:   "reserved after import",
+			module: "package test\nimport future.keywords.if\nif = 1",
+			err:    "test.rego:3: rego_parse_error: no match found",
+		},
+		{
+			note:   "ref operands after import",
+			module: "package test\nimport future.keywords\nimport future.keywords.in\np { input.in }",
+		},
+		{
+			note:   "call operators after import",
+			module: "package test\nimport future.keywords\np { contains(\"abc\", \"b\") }",
+		},
+		{
+			note:   "options",
+			module: "package test\np if { 1 in input }",
+			opts:   ParserOptions{FutureKeywords: []string{"if", "in"}},
+		},
+		{
+			note:   "all options",
+			module: "package test\ns contains x if { some x in input }",
+			opts:   ParserOptions{AllFutureKeywords: true},
+		},
+		{
+			note:   "unknown option",
+			module: "package test",
+			opts:   ParserOptions{FutureKeywords: []string{"foo"}},
+			err:    `unknown future keyword "foo": must be one of [contains every if in]`,
+		},
+		{
+			note:   "unknown import",
+			module: "package test\nimport future.keywords.foo",
+			err:    "invalid path future.keywords.foo: future keyword must be one of [contains every if in]",
+		},
+		{
+			note:   "aliased import",
+			module: "package test\nimport future.keywords.in as foo",
+			err:    "future keyword imports cannot be aliased",
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.note, func(t *testing.T) {
+			_, err := ParseModuleWithOpts("test.rego", tc.module, tc.opts)
+			if tc.err == "" {
+				if err != nil {
+					t.Fatal(err)
+				}
+			} else if err == nil || !strings.Contains(err.Error(), tc.err) {
+				t.Fatalf("Expected error containing %q but got: %v", tc.err, err)
+			}
+		})
+	}
+}
+
+func TestFutureKeywordHintsImported(t *testing.T) {
+
+	tests := []struct {
+		note   string
+		module string
+	}{
+		{
+			note:   "reserved",
+			module: "package test\nimport future.keywords.if\nif = 1",
+		},
+		{
+			note:   "every without body",
+			module: "package test\nimport future.keywords.every\nimport future.keywords.if\nallow if every x in [1] x == 1",
+		},
+		{
+			note:   "every without domain",
+			module: "package test\nimport future.keywords.every\np { every }",
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.note, func(t *testing.T) {
+			_, err := ParseModule("test.rego", tc.module)
+			if err == nil || !strings.Contains(err.Error(), "rego_parse_error") {
+				t.Fatalf("Expected parse error but got: %v", err)
+			}
+			if strings.Contains(err.Error(), "hint:") {
+				t.Fatalf("Expected no hint for imported keyword but got: %v", err)
+			}
+		})
+	}
+}
+
+func TestRuleRefHeads(t *testing.T) {
+
+	rule := MustParseRule(`fruit.apple.color = "red" { true }`)
+	if rule.Head.Name != Var("fruit") || !rule.Head.Reference.Equal(MustParseRef(`fruit.apple.color`)) {
+		t.Fatalf("Expected head ref fruit.apple.color but got: %v", rule.Head)
+	}
+
+	if !rule.Head.Value.Equal(StringTerm("red")) || rule.Head.DocKind() != CompleteDoc {
+		t.Fatalf("Expected complete rule with value red but got: %v", rule.Head)
+	}
+
+	assertParseRule(t, "default value", `fruit.apple.ripe { true }`, &Rule{
+		Head: &Head{
+			Name:      Var("fruit"),
+			Reference: MustParseRef(`fruit.apple.ripe`),
+			Value:     BooleanTerm(true),
+		},
+		Body: NewBody(NewExpr(BooleanTerm(true))),
+	})
+
+	assertParseRule(t, "partial object", `p.q = 1 { true }`, MustParseRule(`p["q"] = 1 { true }`))
+	assertParseRule(t, "else", `p.q.r = 1 { false } else = 2 { true }`, &Rule{
+		Head: &Head{
+			Name:      Var("p"),
+			Reference: MustParseRef(`p.q.r`),
+			Value:     IntNumberTerm(1),
+		},
+		Body: NewBody(NewExpr(BooleanTerm(false))),
+		Else: &Rule{
+			Head: &Head{
+				Name:      Var("p"),
+				Reference: MustParseRef(`p.q.r`),
+				Value:     IntNumberTerm(2),
+			},
+			Body: NewBody(NewExpr(BooleanTerm(true))),
+		},
+	})
+
+	assertParseErrorContains(t, "args", `p.q.r(x) = 1 { true }`, errRefHeadArgsOrKey.Error())
+	assertParseErrorContains(t, "key", `p.q.r[x] { x = 1 }`, errRefHeadArgsOrKey.Error())
+	assertParseErrorContains(t, "partial assign", `p.q := 1 { true }`, errPartialRuleAssignOperator.Error())
+
+	mod := MustParseModule(`package a.b
+
+	fruit.apple.color = "red"
+	`)
+
+	if path := mod.Rules[0].Path(); !path.Equal(MustParseRef(`data.a.b.fruit.apple.color`)) {
+		t.Fatalf("Expected path data.a.b.fruit.apple.color but got: %v", path)
+	}
+}
+
 func TestRuleElseKeyword(t *testing.T) {
 	mod := `package test
 
@@ -1317,6 +1710,45 @@ func TestLocation(t *testing.T) {
 	}
 }
 
+func TestLocationAllNodes(t *testing.T) {
+
+	mod := MustParseModule(`package test
+import future.keywords
+import input.foo as bar
+
+p[x] = y {
+	x := input.xs[_]
+	y := count([z | z := x[_]])
+	data.q[x] with input as {"a": bar}
+	not f(x) == 1
+	every v in x { v > 0 }
+}`)
+
+	WalkTerms(mod, func(x *Term) bool {
+		if x.Location == nil {
+			t.Errorf("Expected location on term %v", x)
+		}
+		return false
+	})
+
+	WalkExprs(mod, func(x *Expr) bool {
+		if x.Location == nil {
+			t.Errorf("Expected location on expr %v", x)
+		}
+		return false
+	})
+
+	// The input var is rewritten to a ref that shares the location of the var.
+	with := mod.Rules[0].Body[2].With[0]
+	if head := with.Target.Value.(Ref)[0]; head.Location == nil || head.Location.Row != 8 || head.Location.Col != 17 {
+		t.Fatalf("Expected with target location to be 8:17 but got %v", head.Location)
+	}
+
+	if row, col := mod.Rules[0].Location.End(); row != 11 || col != 2 {
+		t.Fatalf("Expected rule to end at 11:2 but got %d:%d", row, col)
+	}
+}
+
 func TestRuleFromBody(t *testing.T) {
 	testModule := `package a.b.c
 
@@ -1336,6 +1768,7 @@ data = 2
 f(1) = 2
 f(1)
 d1 := 1234
+fruit["apple"].color = "red"
 `
 
 	assertParseModule(t, "rules from bodies", testModule, &Module{
@@ -1357,6 +1790,7 @@ d1 := 1234
 			MustParseRule(`f(1) = 2 { true }`),
 			MustParseRule(`f(1) = true { true }`),
 			MustParseRule("d1 := 1234 { true }"),
+			MustParseRule(`fruit.apple.color = "red" { true }`),
 		},
 	})
 
@@ -1397,11 +1831,6 @@ data = {"bar": 2} { true }`
 	foo = input with input as 1
 	`
 
-	badRefLen1 := `
-	package a.b.c
-
-	p["x"].y = 1`
-
 	badRefLen2 := `
 	package a.b.c
 
@@ -1431,7 +1860,6 @@ data = {"bar": 2} { true }`
 	assertParseModuleError(t, "non-equality", nonEquality)
 	assertParseModuleError(t, "non-var name", nonVarName)
 	assertParseModuleError(t, "with expr", withExpr)
-	assertParseModuleError(t, "bad ref (too long)", badRefLen1)
 	assertParseModuleError(t, "bad ref (too long)", badRefLen2)
 	assertParseModuleError(t, "negated", negated)
 	assertParseModuleError(t, "non ref term", nonRefTerm)
@@ -1538,6 +1966,138 @@ func TestNoMatchError(t *testing.T) {
 	}
 }
 
+func TestParseErrorRecovery(t *testing.T) {
+
+	mod := `package test
+
+p { true;
+	1 != 0; # <-- parse error: no match
+}
+
+q = 1
+
+r = [1 | true}
+
+s {
+	x := "foo
+}
+
+t = 2`
+
+	_, err := ParseModule("foo.rego", mod)
+
+	errs, ok := err.(Errors)
+	if !ok {
+		t.Fatalf("Expected errors but got: %v", err)
+	}
+
+	var rows []int
+	for _, e := range errs {
+		if e.Code != ParseErr {
+			t.Fatalf("Expected parse error but got: %v", e)
+		}
+		rows = append(rows, e.Location.Row)
+	}
+
+	if exp := []int{5, 9, 12}; !reflect.DeepEqual(rows, exp) {
+		t.Fatalf("Expected errors on rows %v but got: %v", exp, errs)
+	}
+
+	if errs[0].Details.(*ParserErrorDetail).Line != "}" {
+		t.Fatalf("Expected details to refer to original input but got: %v", errs[0].Details)
+	}
+
+	var many bytes.Buffer
+	many.WriteString("package test\n")
+	for i := 0; i < maxParseErrors*2; i++ {
+		many.WriteString("p = [\n")
+	}
+
+	_, err = ParseModule("foo.rego", many.String())
+	if errs, ok := err.(Errors); !ok || len(errs) != maxParseErrors {
+		t.Fatalf("Expected %d errors but got: %v", maxParseErrors, err)
+	}
+}
+
+func TestParseErrorRecoveryIndented(t *testing.T) {
+
+	mod := `
+	package test
+
+	p { true;
+		1 != 0; # <-- parse error: no match
+	}
+
+	# comment
+	q = [1 | true}
+
+	r = 1`
+
+	_, err := ParseModule("foo.rego", mod)
+
+	errs, ok := err.(Errors)
+	if !ok || len(errs) != 2 {
+		t.Fatalf("Expected two errors but got: %v", err)
+	}
+
+	exp := []*Location{
+		NewLocation(nil, "foo.rego", 6, 2),
+		NewLocation(nil, "foo.rego", 9, 15),
+	}
+
+	for i := range exp {
+		if !exp[i].Equal(errs[i].Location) {
+			t.Errorf("Expected error %d at %v but got: %v", i, exp[i], errs[i].Location)
+		}
+	}
+
+	if detail := errs[1].Details.(*ParserErrorDetail); detail.Line != "\tq = [1 | true}" || detail.Idx != 14 {
+		t.Fatalf("Unexpected error details: %+v", detail)
+	}
+}
+
+func TestParseMalformedInputs(t *testing.T) {
+
+	// None of these inputs should cause the parser to panic.
+	inputs := []string{
+		"",
+		"\x00",
+		"\xff\xfe",
+		"package",
+		"package test\n}",
+		"package test\np[",
+		"package test\np { x := `foo }",
+		"package test\np = {1: }",
+		"package test\nimport",
+		"package test\nimport data.foo as",
+		"package test\ndefault p = ",
+		"package test\nf(x",
+		"package test\np { some }",
+		"package test\np { x with }",
+		"package test\np = 1e1e1",
+		"package test\np = \"\\u12\"",
+		"package test\np { not not not }",
+		"package test\np else = 1",
+		"package test\n\"p\" = 1",
+		"package test\np[x] = y := 1",
+		"}}}}",
+		"\n\n\n#",
+	}
+
+	for _, input := range inputs {
+		func() {
+			defer func() {
+				if r := recover(); r != nil {
+					t.Fatalf("Unexpected panic for %q: %v", input, r)
+				}
+			}()
+			ParseModule("foo.rego", input)
+			ParseStatements("", input)
+			ParseBody(input)
+		}()
+	}
+}
+
 func TestParseErrorDetails(t *testing.T) {
 
 	tests := []struct {
@@ -1748,17 +2308,24 @@ func assertParsePackage(t *testing.T, msg string, input string, correct *Package
 	})
 }
 
-func assertParseOne(t *testing.T, msg string, input string, correct func(interface{})) {
+func assertParseOne(t *testing.T, msg string, input string, correct func(interface{}), opts ...ParserOptions) {
 	t.Helper()
-	p, err := ParseStatement(input)
+	var popts ParserOptions
+	if len(opts) > 0 {
+		popts = opts[0]
+	}
+	stmts, _, err := ParseStatementsWithOpts("", input, popts)
+	if err == nil && len(stmts) != 1 {
+		err = fmt.Errorf("expected exactly one statement")
+	}
 	if err != nil {
 		t.Errorf("Error on test %s: parse error on %s: %s", msg, input, err)
 		return
 	}
-	correct(p)
+	correct(stmts[0])
 }
 
-func assertParseOneExpr(t *testing.T, msg string, input string, correct *Expr) {
+func assertParseOneExpr(t *testing.T, msg string, input string, correct *Expr, opts ...ParserOptions) {
 	assertParseOne(t, msg, input, func(parsed interface{}) {
 		body := parsed.(Body)
 		if len(body) != 1 {
@@ -1769,12 +2336,12 @@ func assertParseOneExpr(t *testing.T, msg string, input string, correct *Expr) {
 		if !expr.Equal(correct) {
 			t.Errorf("Error on test %s: expressions not equal:\n%v (parsed)\n%v (correct)", msg, expr, correct)
 		}
-	})
+	}, opts...)
 }
 
-func assertParseOneExprNegated(t *testing.T, msg string, input string, correct *Expr) {
+func assertParseOneExprNegated(t *testing.T, msg string, input string, correct *Expr, opts ...ParserOptions) {
 	correct.Negated = true
-	assertParseOneExpr(t, msg, input, correct)
+	assertParseOneExpr(t, msg, input, correct, opts...)
 }
 
 func assertParseOneTerm(t *testing.T, msg string, input string, correct *Term) {
@@ -1785,7 +2352,7 @@ func assertParseOneTermNegated(t *testing.T, msg string, input string, correct *
 	assertParseOneExprNegated(t, msg, input, &Expr{Terms: correct})
 }
 
-func assertParseRule(t *testing.T, msg string, input string, correct *Rule) {
+func assertParseRule(t *testing.T, msg string, input string, correct *Rule, opts ...ParserOptions) {
 	t.Helper()
 	assertParseOne(t, msg, input, func(parsed interface{}) {
 		t.Helper()
@@ -1793,5 +2360,5 @@ func assertParseRule(t *testing.T, msg string, input string, correct *Rule) {
 		if !rule.Equal(correct) {
 			t.Errorf("Error on test %s: rules not equal: %v (parsed), %v (correct)", msg, rule, correct)
 		}
-	})
+	}, opts...)
 }
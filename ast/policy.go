@@ -31,6 +31,11 @@ var DefaultRootDocument = VarTerm("data")
 // InputRootDocument names the document containing query arguments.
 var InputRootDocument = VarTerm("input")
 
+// FutureRootRef is a reference to the root of the future keywords imports. The
+// keywords are imported with "import future.keywords" or "import
+// future.keywords.<keyword>".
+var FutureRootRef = Ref{VarTerm("future"), StringTerm("keywords")}
+
 // RootDocumentNames contains the names of top-level documents that can be
 // referred to in modules and queries.
 var RootDocumentNames = NewSet(
@@ -97,6 +102,27 @@ func IsKeyword(s string) bool {
 	return false
 }
 
+// FutureKeywords contains strings that map to language keywords that must be
+// enabled before they are recognized. Modules enable them by importing
+// future.keywords (all keywords) or future.keywords.<keyword>. Until then, the
+// strings can be used as variable and rule names.
+var FutureKeywords = [...]string{
+	"contains",
+	"every",
+	"if",
+	"in",
+}
+
+// IsFutureKeyword returns true if s is a future keyword.
+func IsFutureKeyword(s string) bool {
+	for _, x := range FutureKeywords {
+		if x == s {
+			return true
+		}
+	}
+	return false
+}
+
 type (
 	// Node represents a node in an AST. Nodes may be statements in a policy module
 	// or elements of an ad-hoc query, expression, etc.
@@ -154,6 +180,11 @@ type (
 		Body     Body      `json:"body"`
 		Else     *Rule     `json:"else,omitempty"`
 
+		// IfSyntax is true if the rule was defined with the "if" or "contains"
+		// keywords. The syntax does not change the meaning of the rule and is
+		// only used for formatting.
+		IfSyntax bool `json:"-"`
+
 		// Module is a pointer to the module containing this rule. If the rule
 		// was NOT created while parsing/constructing a module, this should be
 		// left unset. The pointer is not included in any standard operations
@@ -161,14 +192,18 @@ type (
 		Module *Module `json:"-"`
 	}
 
-	// Head represents the head of a rule.
+	// Head represents the head of a rule. Rules that define a nested document
+	// (e.g., fruit.apple.color = "red") have a Reference that contains the
+	// path of the document relative to the package. The Name of such rules is
+	// the first term of the Reference.
 	Head struct {
-		Location *Location `json:"-"`
-		Name     Var       `json:"name"`
-		Args     Args      `json:"args,omitempty"`
-		Key      *Term     `json:"key,omitempty"`
-		Value    *Term     `json:"value,omitempty"`
-		Assign   bool      `json:"assign,omitempty"`
+		Location  *Location `json:"-"`
+		Name      Var       `json:"name"`
+		Reference Ref       `json:"ref,omitempty"`
+		Args      Args      `json:"args,omitempty"`
+		Key       *Term     `json:"key,omitempty"`
+		Value     *Term     `json:"value,omitempty"`
+		Assign    bool      `json:"assign,omitempty"`
 	}
 
 	// Args represents zero or more arguments to a rule.
@@ -194,6 +229,17 @@ type (
 		Symbols  []*Term   `json:"symbols"`
 	}
 
+	// Every represents a universally quantified statement. The body must be
+	// satisfied for every element in the domain. The key and value are
+	// variables local to the statement.
+	Every struct {
+		Location *Location `json:"-"`
+		Key      *Term     `json:"key,omitempty"`
+		Value    *Term     `json:"value"`
+		Domain   *Term     `json:"domain"`
+		Body     Body      `json:"body"`
+	}
+
 	// With represents a modifier on an expression.
 	With struct {
 		Location *Location `json:"-"`
@@ -271,6 +317,54 @@ func (mod *Module) RuleSet(name Var) RuleSet {
 	return rs
 }
 
+// CommentsFor returns the comments attached to node. The comment on the row
+// that node starts on and the block of comments on the rows immediately
+// preceding node are attached to it, e.g., the comments that document a rule.
+// Comments that follow other statements on the same row are not part of the
+// block. The comments are returned in the order they appear in the module.
+func (mod *Module) CommentsFor(node Node) []*Comment {
+
+	loc := node.Loc()
+	if loc == nil {
+		return nil
+	}
+
+	rows := map[int]*Comment{}
+	for _, c := range mod.Comments {
+		if c.Location != nil && c.Location.File == loc.File {
+			rows[c.Location.Row] = c
+		}
+	}
+
+	// Rows that contain the start or end of another node contain code.
+	code := map[int]bool{}
+	WalkNodes(mod, func(x Node) bool {
+		if _, ok := x.(*Comment); ok {
+			return false
+		}
+		if l := x.Loc(); l != nil && l.File == loc.File {
+			code[l.Row] = true
+			code[l.Row+bytes.Count(l.Text, []byte("\n"))] = true
+		}
+		return false
+	})
+
+	var result []*Comment
+
+	row := loc.Row - 1
+	for rows[row] != nil && !code[row] {
+		row--
+	}
+
+	for row++; row <= loc.Row; row++ {
+		if c := rows[row]; c != nil {
+			result = append(result, c)
+		}
+	}
+
+	return result
+}
+
 // UnmarshalJSON parses bs and stores the result in mod. The rules in the module
 // will have their module pointer set to mod.
 func (mod *Module) UnmarshalJSON(bs []byte) error {
@@ -369,6 +463,9 @@ func (pkg *Package) String() string {
 // IsValidImportPath returns an error indicating if the import path is invalid.
 // If the import path is invalid, err is nil.
 func IsValidImportPath(v Value) (err error) {
+	if ref, ok := v.(Ref); ok && ref.HasPrefix(FutureRootRef) {
+		return isValidFutureKeywordsImportPath(ref)
+	}
 	switch v := v.(type) {
 	case Var:
 		if !v.Equal(DefaultRootDocument.Value) && !v.Equal(InputRootDocument.Value) {
@@ -389,6 +486,34 @@ func IsValidImportPath(v Value) (err error) {
 	return nil
 }
 
+func isValidFutureKeywordsImportPath(ref Ref) error {
+	switch len(ref) {
+	case len(FutureRootRef):
+		return nil
+	case len(FutureRootRef) + 1:
+		if kw, ok := ref[len(ref)-1].Value.(String); ok && IsFutureKeyword(string(kw)) {
+			return nil
+		}
+	}
+	return fmt.Errorf("invalid path %v: future keyword must be one of %v", ref, FutureKeywords)
+}
+
+// FutureKeywords returns the keywords enabled by the import. If the import does
+// not import future keywords, the result is nil.
+func (imp *Import) FutureKeywords() []string {
+	ref, ok := imp.Path.Value.(Ref)
+	if !ok || !ref.HasPrefix(FutureRootRef) {
+		return nil
+	}
+	if len(ref) == len(FutureRootRef) {
+		return FutureKeywords[:]
+	}
+	if kw, ok := ref[len(ref)-1].Value.(String); ok {
+		return []string{string(kw)}
+	}
+	return nil
+}
+
 // Compare returns an integer indicating whether imp is less than, equal to,
 // or greater than other.
 func (imp *Import) Compare(other *Import) int {
@@ -516,6 +641,9 @@ func (rule *Rule) Path() Ref {
 	if rule.Module == nil {
 		panic("assertion failed")
 	}
+	if len(rule.Head.Reference) > 0 {
+		return rule.Module.Package.Path.Extend(rule.Head.Reference)
+	}
 	return rule.Module.Package.Path.Append(StringTerm(string(rule.Head.Name)))
 }
 
@@ -622,6 +750,9 @@ func (head *Head) Compare(other *Head) int {
 	if cmp := Compare(head.Name, other.Name); cmp != 0 {
 		return cmp
 	}
+	if cmp := Compare(head.Reference, other.Reference); cmp != 0 {
+		return cmp
+	}
 	if cmp := Compare(head.Key, other.Key); cmp != 0 {
 		return cmp
 	}
@@ -631,6 +762,7 @@ func (head *Head) Compare(other *Head) int {
 // Copy returns a deep copy of head.
 func (head *Head) Copy() *Head {
 	cpy := *head
+	cpy.Reference = head.Reference.Copy()
 	cpy.Args = head.Args.Copy()
 	cpy.Key = head.Key.Copy()
 	cpy.Value = head.Value.Copy()
@@ -648,6 +780,8 @@ func (head *Head) String() string {
 		buf = append(buf, head.Name.String()+head.Args.String())
 	} else if head.Key != nil {
 		buf = append(buf, head.Name.String()+"["+head.Key.String()+"]")
+	} else if len(head.Reference) > 0 {
+		buf = append(buf, head.Reference.String())
 	} else {
 		buf = append(buf, head.Name.String())
 	}
@@ -940,6 +1074,10 @@ func (expr *Expr) Compare(other *Expr) int {
 		if cmp := Compare(t, other.Terms.(*SomeDecl)); cmp != 0 {
 			return cmp
 		}
+	case *Every:
+		if cmp := Compare(t, other.Terms.(*Every)); cmp != 0 {
+			return cmp
+		}
 	}
 
 	return withSliceCompare(expr.With, other.With)
@@ -953,6 +1091,8 @@ func (expr *Expr) sortOrder() int {
 		return 1
 	case []*Term:
 		return 2
+	case *Every:
+		return 3
 	}
 	return -1
 }
@@ -965,6 +1105,8 @@ func (expr *Expr) Copy() *Expr {
 	switch ts := expr.Terms.(type) {
 	case *SomeDecl:
 		cpy.Terms = ts.Copy()
+	case *Every:
+		cpy.Terms = ts.Copy()
 	case []*Term:
 		cpyTs := make([]*Term, len(ts))
 		for i := range ts {
@@ -989,6 +1131,8 @@ func (expr *Expr) Hash() int {
 	switch ts := expr.Terms.(type) {
 	case *SomeDecl:
 		s += ts.Hash()
+	case *Every:
+		s += ts.Hash()
 	case []*Term:
 		for _, t := range ts {
 			s += t.Value.Hash()
@@ -1090,9 +1234,21 @@ func (expr *Expr) SetOperator(term *Term) *Expr {
 	return expr
 }
 
-// SetLocation sets the expr's location and returns the expr itself.
+// SetLocation sets the expr's location and returns the expr itself. If the
+// expr is a call whose operator has no location (e.g., because the expr was
+// generated), the operator is given the same location.
 func (expr *Expr) SetLocation(loc *Location) *Expr {
 	expr.Location = loc
+	if terms, ok := expr.Terms.([]*Term); ok && len(terms) > 0 && terms[0].Location == nil {
+		terms[0].SetLocation(loc)
+		if ref, ok := terms[0].Value.(Ref); ok {
+			for _, t := range ref {
+				if t.Location == nil {
+					t.SetLocation(loc)
+				}
+			}
+		}
+	}
 	return expr
 }
 
@@ -1125,6 +1281,8 @@ func (expr *Expr) String() string {
 		buf = append(buf, t.String())
 	case *SomeDecl:
 		buf = append(buf, t.String())
+	case *Every:
+		buf = append(buf, t.String())
 	}
 
 	for i := range expr.With {
@@ -1158,6 +1316,13 @@ func NewBuiltinExpr(terms ...*Term) *Expr {
 }
 
 func (d *SomeDecl) String() string {
+	if call, ok := d.MembershipCall(); ok {
+		buf := make([]string, len(call)-2)
+		for i := range buf {
+			buf[i] = call[i+1].String()
+		}
+		return "some " + strings.Join(buf, ", ") + " in " + call[len(call)-1].String()
+	}
 	buf := make([]string, len(d.Symbols))
 	for i := range buf {
 		buf[i] = d.Symbols[i].String()
@@ -1165,6 +1330,23 @@ func (d *SomeDecl) String() string {
 	return "some " + strings.Join(buf, ", ")
 }
 
+// MembershipCall returns the call representing the "in" operator if d is a
+// "some ... in ..." statement, e.g., "some k, v in xs".
+func (d *SomeDecl) MembershipCall() (Call, bool) {
+	if len(d.Symbols) != 1 {
+		return nil, false
+	}
+	call, ok := d.Symbols[0].Value.(Call)
+	if !ok {
+		return nil, false
+	}
+	op := call[0].Value.String()
+	if op != Member.Name && op != MemberWithKey.Name {
+		return nil, false
+	}
+	return call, true
+}
+
 // SetLoc sets the Location on d.
 func (d *SomeDecl) SetLoc(loc *Location) {
 	d.Location = loc
@@ -1193,6 +1375,76 @@ func (d *SomeDecl) Hash() int {
 	return termSliceHash(d.Symbols)
 }
 
+func (e *Every) String() string {
+	buf := []string{"every"}
+	if e.Key != nil {
+		buf = append(buf, e.Key.String()+",")
+	}
+	buf = append(buf, e.Value.String(), "in", e.Domain.String(), "{")
+	for _, expr := range e.Body {
+		buf = append(buf, expr.String()+";")
+	}
+	buf[len(buf)-1] = strings.TrimSuffix(buf[len(buf)-1], ";")
+	return strings.Join(append(buf, "}"), " ")
+}
+
+// KeyValueVars returns the key and value vars declared by e. The key is nil
+// if e does not declare one.
+func (e *Every) KeyValueVars() VarSet {
+	vs := NewVarSet()
+	if e.Key != nil {
+		vs.Add(e.Key.Value.(Var))
+	}
+	vs.Add(e.Value.Value.(Var))
+	return vs
+}
+
+// SetLoc sets the Location on e.
+func (e *Every) SetLoc(loc *Location) {
+	e.Location = loc
+}
+
+// Loc returns the Location of e.
+func (e *Every) Loc() *Location {
+	return e.Location
+}
+
+// Copy returns a deep copy of e.
+func (e *Every) Copy() *Every {
+	cpy := *e
+	if e.Key != nil {
+		cpy.Key = e.Key.Copy()
+	}
+	cpy.Value = e.Value.Copy()
+	cpy.Domain = e.Domain.Copy()
+	cpy.Body = e.Body.Copy()
+	return &cpy
+}
+
+// Compare returns an integer indicating whether e is less than, equal to, or
+// greater than other.
+func (e *Every) Compare(other *Every) int {
+	if cmp := Compare(e.Key, other.Key); cmp != 0 {
+		return cmp
+	}
+	if cmp := Compare(e.Value, other.Value); cmp != 0 {
+		return cmp
+	}
+	if cmp := Compare(e.Domain, other.Domain); cmp != 0 {
+		return cmp
+	}
+	return e.Body.Compare(other.Body)
+}
+
+// Hash returns a hash code of e.
+func (e *Every) Hash() int {
+	s := e.Value.Hash() + e.Domain.Hash() + e.Body.Hash()
+	if e.Key != nil {
+		s += e.Key.Hash()
+	}
+	return s
+}
+
 func (w *With) String() string {
 	return "with " + w.Target.String() + " as " + w.Value.String()
 }
@@ -16,6 +16,7 @@ import (
 func TestModuleJSONRoundTrip(t *testing.T) {
 
 	mod := MustParseModule(`package a.b.c
+import future.keywords
 
 import data.x.y as z
 import data.u.i
@@ -39,8 +40,16 @@ a = true { xs = {a: b | input.y[a] = "foo"; b = input.z["bar"]} }
 b = true { xs = {{"x": a[i].a} | a[i].n = "bob"; b[x]} }
 call_values { f(x) != g(x) }
 assigned := 1
+some_decl { some x, y; input[x][y] }
+some_in { some k, v in input.xs }
+every_kv { every k, v in input.xs { k != v } }
+every_v { every v in input.xs { v > 0; not v == 3 } }
+else_rule = 1 { false } else = 2 { true }
+generated { input.x }
 `)
 
+	mod.Rules[len(mod.Rules)-1].Body[0].Generated = true
+
 	bs, err := json.Marshal(mod)
 	if err != nil {
 		t.Fatalf("Unexpected error: %v", err)
@@ -57,6 +66,10 @@ assigned := 1
 		t.Fatalf("Expected roundtripped module to be equal to original:\nExpected:\n\n%v\n\nGot:\n\n%v\n", mod, roundtrip)
 	}
 
+	if !roundtrip.Rules[len(roundtrip.Rules)-1].Body[0].Generated {
+		t.Fatal("expected generated flag to be roundtripped")
+	}
+
 	if mod.Rules[3].Path().String() != "data.a.b.c.t" {
 		t.Fatal("expected path data.a.b.c.t for 4th rule in module but got:", mod.Rules[3].Path())
 	}
@@ -356,6 +369,22 @@ func TestExprBadJSON(t *testing.T) {
 	}`
 	exp = fmt.Errorf("ast: unable to unmarshal index field with type: <nil> (expected integer)")
 	assert(js, exp)
+
+	js = `
+	{
+		"terms": {"symbols": "x"},
+		"index": 0
+	}`
+	exp = fmt.Errorf(`ast: unable to unmarshal some declaration (expected {"symbols": [...]})`)
+	assert(js, exp)
+
+	js = `
+	{
+		"terms": {"key": null, "value": {"value": "x", "type": "var"}, "domain": {"value": "xs", "type": "var"}},
+		"index": 0
+	}`
+	exp = fmt.Errorf(`ast: unable to unmarshal every statement (expected {"key": ..., "value": {...}, "domain": {...}, "body": [...]})`)
+	assert(js, exp)
 }
 
 func TestRuleHeadEquals(t *testing.T) {
@@ -471,6 +500,49 @@ wildcard = true { bar[_] = 1 }`
 	}
 }
 
+func TestModuleCommentsFor(t *testing.T) {
+
+	mod := MustParseModule(`# Package a.b contains
+# examples.
+package a.b
+
+# Not attached.
+
+# Rule p.
+p { # on p
+	# on expression
+	input.x # also on expression
+	input.y
+}
+q { true }`)
+
+	p := mod.Rules[0]
+
+	tests := []struct {
+		note     string
+		node     Node
+		expected []string
+	}{
+		{"package", mod.Package, []string{" Package a.b contains", " examples."}},
+		{"rule", p, []string{" Rule p.", " on p"}},
+		{"expression", p.Body[0], []string{" on expression", " also on expression"}},
+		{"none", p.Body[1], nil},
+		{"after rule", mod.Rules[1], nil},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.note, func(t *testing.T) {
+			var result []string
+			for _, c := range mod.CommentsFor(tc.node) {
+				result = append(result, string(c.Text))
+			}
+			if !reflect.DeepEqual(result, tc.expected) {
+				t.Fatalf("Expected %q but got %q", tc.expected, result)
+			}
+		})
+	}
+}
+
 func TestWithString(t *testing.T) {
 
 	with1 := &With{
@@ -497,6 +569,21 @@ func TestWithString(t *testing.T) {
 	}
 }
 
+func TestEveryString(t *testing.T) {
+
+	opts := ParserOptions{FutureKeywords: []string{"every"}}
+	every := MustParseBodyWithOpts(`every k, v in xs { k != "a"; v > 0 }`, opts)[0]
+	expected := `every k, v in xs { neq(k, "a"); gt(v, 0) }`
+
+	if result := every.String(); result != expected {
+		t.Fatalf("Expected %v but got %v", expected, result)
+	}
+
+	if !MustParseBodyWithOpts(expected, opts)[0].Equal(every) {
+		t.Fatalf("Expected %v to round trip", expected)
+	}
+}
+
 func TestSomeDeclString(t *testing.T) {
 
 	decl := &SomeDecl{
@@ -512,6 +599,19 @@ func TestSomeDeclString(t *testing.T) {
 	if result != expected {
 		t.Fatalf("Expected %v but got %v", expected, result)
 	}
+
+	decl = &SomeDecl{
+		Symbols: []*Term{
+			MemberWithKey.Call(VarTerm("k"), VarTerm("v"), ArrayTerm(IntNumberTerm(1))),
+		},
+	}
+
+	result = decl.String()
+	expected = "some k, v in [1]"
+
+	if result != expected {
+		t.Fatalf("Expected %v but got %v", expected, result)
+	}
 }
 
 func assertExprEqual(t *testing.T, a, b *Expr) {
@@ -0,0 +1,224 @@
+// Copyright 2020 The OPA Authors.  All rights reserved.
+// Use of this source code is governed by an Apache2
+// license that can be found in the LICENSE file.
+
+package ast
+
+import (
+	"encoding"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"math"
+	"reflect"
+	"strconv"
+
+	"github.com/open-policy-agent/opa/internal/jsonfield"
+	"github.com/open-policy-agent/opa/util"
+)
+
+var (
+	valueConverterType = reflect.TypeOf((*ValueConverter)(nil)).Elem()
+	jsonMarshalerType  = reflect.TypeOf((*json.Marshaler)(nil)).Elem()
+	textMarshalerType  = reflect.TypeOf((*encoding.TextMarshaler)(nil)).Elem()
+)
+
+// reflectToValue converts v to a Value the same way that encoding/json would
+// serialize v: struct fields are named by their json tags (so generated
+// protobuf messages use the names from their tags), omitempty and string
+// options are honored, embedded structs are flattened, and types that
+// implement json.Marshaler or encoding.TextMarshaler are serialized with
+// those methods. Only the values that implement json.Marshaler are serialized
+// to bytes.
+func reflectToValue(v reflect.Value) (Value, error) {
+
+	if !v.IsValid() {
+		return Null{}, nil
+	}
+
+	if t, ok := marshalerType(v); ok {
+		if v.Kind() == reflect.Ptr && v.IsNil() {
+			return Null{}, nil
+		}
+		if t != v.Type() {
+			v = v.Addr()
+		}
+		switch t := v.Interface().(type) {
+		case ValueConverter:
+			return t.ToValue()
+		case json.Marshaler:
+			bs, err := t.MarshalJSON()
+			if err != nil {
+				return nil, err
+			}
+			var x interface{}
+			if err := util.UnmarshalJSON(bs, &x); err != nil {
+				return nil, err
+			}
+			return InterfaceToValue(x)
+		case encoding.TextMarshaler:
+			bs, err := t.MarshalText()
+			if err != nil {
+				return nil, err
+			}
+			return String(bs), nil
+		}
+	}
+
+	switch v.Kind() {
+	case reflect.Bool:
+		return Boolean(v.Bool()), nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return int64Number(v.Int()), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return Number(strconv.FormatUint(v.Uint(), 10)), nil
+	case reflect.Float32, reflect.Float64:
+		f := v.Float()
+		if math.IsInf(f, 0) || math.IsNaN(f) {
+			return nil, fmt.Errorf("ast: illegal value: %v", f)
+		}
+		bits := 64
+		if v.Kind() == reflect.Float32 {
+			bits = 32
+		}
+		return Number(strconv.FormatFloat(f, 'g', -1, bits)), nil
+	case reflect.String:
+		if v.Type() == numberType {
+			return Number(v.String()), nil
+		}
+		return String(v.String()), nil
+	case reflect.Interface, reflect.Ptr:
+		if v.IsNil() {
+			return Null{}, nil
+		}
+		return reflectToValue(v.Elem())
+	case reflect.Slice:
+		if v.IsNil() {
+			return Null{}, nil
+		}
+		if v.Type().Elem().Kind() == reflect.Uint8 && !reflect.PtrTo(v.Type().Elem()).Implements(jsonMarshalerType) {
+			return String(base64.StdEncoding.EncodeToString(v.Bytes())), nil
+		}
+		return reflectArrayToValue(v)
+	case reflect.Array:
+		return reflectArrayToValue(v)
+	case reflect.Map:
+		if v.IsNil() {
+			return Null{}, nil
+		}
+		return reflectMapToValue(v)
+	case reflect.Struct:
+		return reflectStructToValue(v)
+	default:
+		return nil, fmt.Errorf("ast: illegal value: %v", v.Type())
+	}
+}
+
+var numberType = reflect.TypeOf(json.Number(""))
+
+// marshalerType returns the type that implements one of the interfaces that
+// values can use to provide their own representation. If only the pointer
+// type implements the interface, the pointer type is returned if v is
+// addressable.
+func marshalerType(v reflect.Value) (reflect.Type, bool) {
+	t := v.Type()
+	if implementsMarshaler(t) {
+		return t, true
+	}
+	if t.Kind() != reflect.Ptr && v.CanAddr() {
+		if pt := reflect.PtrTo(t); implementsMarshaler(pt) {
+			return pt, true
+		}
+	}
+	return nil, false
+}
+
+func implementsMarshaler(t reflect.Type) bool {
+	return t.Implements(valueConverterType) || t.Implements(jsonMarshalerType) || t.Implements(textMarshalerType)
+}
+
+func reflectArrayToValue(v reflect.Value) (Value, error) {
+	r := make(Array, v.Len())
+	for i := range r {
+		e, err := reflectToValue(v.Index(i))
+		if err != nil {
+			return nil, err
+		}
+		r[i] = NewTerm(e)
+	}
+	return r, nil
+}
+
+func reflectMapToValue(v reflect.Value) (Value, error) {
+	r := newobject(v.Len())
+	iter := v.MapRange()
+	for iter.Next() {
+		k, err := reflectKeyToString(iter.Key())
+		if err != nil {
+			return nil, err
+		}
+		e, err := reflectToValue(iter.Value())
+		if err != nil {
+			return nil, err
+		}
+		r.Insert(StringTerm(k), NewTerm(e))
+	}
+	return r, nil
+}
+
+// reflectKeyToString returns the object key for a map key. Like
+// encoding/json, string keys are used as-is, keys that implement
+// encoding.TextMarshaler are marshaled, and integer keys are formatted.
+func reflectKeyToString(k reflect.Value) (string, error) {
+	if k.Kind() == reflect.String {
+		return k.String(), nil
+	}
+	if tm, ok := k.Interface().(encoding.TextMarshaler); ok {
+		if k.Kind() == reflect.Ptr && k.IsNil() {
+			return "", nil
+		}
+		bs, err := tm.MarshalText()
+		return string(bs), err
+	}
+	switch k.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return strconv.FormatInt(k.Int(), 10), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return strconv.FormatUint(k.Uint(), 10), nil
+	}
+	return "", fmt.Errorf("ast: illegal object key: %v", k.Type())
+}
+
+func reflectStructToValue(v reflect.Value) (Value, error) {
+	fields := jsonfield.Fields(v.Type())
+	r := newobject(len(fields))
+
+	for _, f := range fields {
+		fv, ok := f.Value(v)
+		if !ok || (f.OmitEmpty && jsonfield.IsEmpty(fv)) {
+			continue
+		}
+
+		e, err := reflectToValue(fv)
+		if err != nil {
+			return nil, err
+		}
+
+		if f.AsString {
+			switch x := e.(type) {
+			case Boolean, Number:
+				e = String(x.String())
+			case String:
+				bs, err := json.Marshal(string(x))
+				if err != nil {
+					return nil, err
+				}
+				e = String(bs)
+			}
+		}
+
+		r.Insert(StringTerm(f.Name), NewTerm(e))
+	}
+
+	return r, nil
+}
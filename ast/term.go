@@ -11,10 +11,15 @@ import (
 	"io"
 	"math/big"
 	"net/url"
+	"reflect"
 	"regexp"
 	"sort"
 	"strconv"
 	"strings"
+	"sync"
+	"unicode"
+	"unicode/utf16"
+	"unicode/utf8"
 
 	"github.com/OneOfOne/xxhash"
 	"github.com/pkg/errors"
@@ -45,6 +50,21 @@ func (loc *Location) Equal(other *Location) bool {
 		loc.Col == other.Col
 }
 
+// End returns the row and column immediately following the last character of
+// the location's text. Like the start position, columns are counted in
+// characters (not bytes.) If the location has no text, the start position is
+// returned.
+func (loc *Location) End() (row int, col int) {
+	row, col = loc.Row, loc.Col
+	text := loc.Text
+	if i := bytes.LastIndexByte(text, '\n'); i >= 0 {
+		row += bytes.Count(text, []byte("\n"))
+		col = 1
+		text = text[i+1:]
+	}
+	return row, col + utf8.RuneCount(text)
+}
+
 // Errorf returns a new error value with a message formatted to include the location
 // info (e.g., line, column, filename, etc.)
 func (loc *Location) Errorf(f string, a ...interface{}) error {
@@ -120,7 +140,16 @@ type Value interface {
 	String() string               // String returns a human readable string representation of the value.
 }
 
-// InterfaceToValue converts a native Go value x to a Value.
+// ValueConverter is implemented by native Go values that provide their own
+// Value representation, e.g., compact encodings of documents held in storage.
+type ValueConverter interface {
+	ToValue() (Value, error)
+}
+
+// InterfaceToValue converts a native Go value x to a Value. Values that are
+// not produced by decoding JSON (e.g., structs, typed slices and maps, and
+// protobuf messages) are converted the same way encoding/json would serialize
+// them without serializing them to bytes first.
 func InterfaceToValue(x interface{}) (Value, error) {
 	switch x := x.(type) {
 	case nil:
@@ -175,8 +204,10 @@ func InterfaceToValue(x interface{}) (Value, error) {
 			r.Insert(NewTerm(k), NewTerm(v))
 		}
 		return r, nil
+	case ValueConverter:
+		return x.ToValue()
 	default:
-		return nil, fmt.Errorf("ast: illegal value: %T", x)
+		return reflectToValue(reflect.ValueOf(x))
 	}
 }
 
@@ -189,9 +220,14 @@ func ValueFromReader(r io.Reader) (Value, error) {
 	return InterfaceToValue(x)
 }
 
-// As converts v into a Go native type referred to by x.
+// As converts v into a Go native type referred to by x. The semantics are the
+// same as json.Unmarshal (see util.Decode) but v is not serialized.
 func As(v Value, x interface{}) error {
-	return util.NewJSONDecoder(bytes.NewBufferString(v.String())).Decode(x)
+	y, err := JSON(v)
+	if err != nil {
+		return err
+	}
+	return util.Decode(y, x)
 }
 
 // Resolver defines the interface for resolving references to native Go values.
@@ -256,7 +292,7 @@ func ValueToInterface(v Value, resolver Resolver) (interface{}, error) {
 			}
 			asStr, stringKey := ki.(string)
 			if !stringKey {
-				return fmt.Errorf("object value has non-string key (%T)", ki)
+				return fmt.Errorf("object value has non-string key (%v)", TypeName(k.Value))
 			}
 			vi, err := ValueToInterface(v.Value, resolver)
 			if err != nil {
@@ -385,24 +421,7 @@ func (term *Term) Equal(other *Term) bool {
 		return true
 	}
 
-	// TODO(tsandall): This early-exit avoids allocations for types that have
-	// Equal() functions that just use == underneath. We should revisit the
-	// other types and implement Equal() functions that do not require
-	// allocations.
-	switch v := term.Value.(type) {
-	case Null:
-		return v.Equal(other.Value)
-	case Boolean:
-		return v.Equal(other.Value)
-	case Number:
-		return v.Equal(other.Value)
-	case String:
-		return v.Equal(other.Value)
-	case Var:
-		return v.Equal(other.Value)
-	}
-
-	return term.Value.Compare(other.Value) == 0
+	return ValueEqual(term.Value, other.Value)
 }
 
 // Get returns a value referred to by name from the term.
@@ -649,6 +668,9 @@ func FloatNumberTerm(f float64) *Term {
 func (num Number) Equal(other Value) bool {
 	switch other := other.(type) {
 	case Number:
+		if num == other {
+			return true
+		}
 		return Compare(num, other) == 0
 	default:
 		return false
@@ -768,7 +790,53 @@ func (str String) IsGround() bool {
 }
 
 func (str String) String() string {
-	return strconv.Quote(string(str))
+	return quoteString(string(str))
+}
+
+// quoteString returns a double-quoted string literal representing s. Unlike
+// strconv.Quote, only escape sequences supported by JSON (and therefore by the
+// parser) are used so that the result can always be parsed again.
+func quoteString(s string) string {
+
+	var buf strings.Builder
+	buf.Grow(len(s) + 2)
+	buf.WriteByte('"')
+
+	for i := 0; i < len(s); {
+		r, size := utf8.DecodeRuneInString(s[i:])
+		i += size
+
+		switch r {
+		case '"', '\\':
+			buf.WriteByte('\\')
+			buf.WriteRune(r)
+		case '\b':
+			buf.WriteString(`\b`)
+		case '\f':
+			buf.WriteString(`\f`)
+		case '\n':
+			buf.WriteString(`\n`)
+		case '\r':
+			buf.WriteString(`\r`)
+		case '\t':
+			buf.WriteString(`\t`)
+		default:
+			if r == utf8.RuneError && size == 1 {
+				// Invalid UTF-8 is replaced the same way as by the JSON decoder.
+				buf.WriteString(`\ufffd`)
+			} else if unicode.IsPrint(r) {
+				buf.WriteRune(r)
+			} else if r > 0xffff {
+				r1, r2 := utf16.EncodeRune(r)
+				fmt.Fprintf(&buf, `\u%04x\u%04x`, r1, r2)
+			} else {
+				fmt.Fprintf(&buf, `\u%04x`, r)
+			}
+		}
+	}
+
+	buf.WriteByte('"')
+	return buf.String()
 }
 
 // Hash returns the hash code for the Value.
@@ -1048,7 +1116,7 @@ func (ref Ref) Ptr() (string, error) {
 	return strings.Join(parts, "/"), nil
 }
 
-var varRegexp = regexp.MustCompile("^[[:alpha:]_][[:alpha:][:digit:]_]*$")
+var varRegexp = regexp.MustCompile(`^[\pL_][\pL\p{Nd}_]*$`)
 
 func (ref Ref) String() string {
 	if len(ref) == 0 {
@@ -1105,7 +1173,7 @@ func (arr Array) Copy() Array {
 
 // Equal returns true if arr is equal to other.
 func (arr Array) Equal(other Value) bool {
-	return Compare(arr, other) == 0
+	return ValueEqual(arr, other)
 }
 
 // Compare compares arr to other, return <0, 0, or >0 if it is less than, equal to,
@@ -1162,9 +1230,14 @@ func (arr Array) Sorted() Array {
 	return cpy
 }
 
-// Hash returns the hash code for the Value.
+// Hash returns the hash code for the Value. The hash code depends on the
+// position of the elements.
 func (arr Array) Hash() int {
-	return termSliceHash(arr)
+	hash := len(arr)
+	for i := range arr {
+		hash = hash*31 + arr[i].Value.Hash()
+	}
+	return hash
 }
 
 // IsGround returns true if all of the Array elements are ground.
@@ -1222,8 +1295,9 @@ func newset(n int) *set {
 		keys = make([]*Term, 0, n)
 	}
 	return &set{
-		elems: make(map[int]*Term, n),
-		keys:  keys,
+		elems:     make(map[int]*Term, n),
+		keys:      keys,
+		sortGuard: new(sync.Once),
 	}
 }
 
@@ -1238,6 +1312,12 @@ func SetTerm(t ...*Term) *Term {
 type set struct {
 	elems map[int]*Term
 	keys  []*Term
+
+	// sortGuard protects sorted, which is computed on demand. Sets may be read
+	// by concurrent evaluations, however, they are never modified concurrently
+	// so the guard is replaced whenever an element is added.
+	sortGuard *sync.Once
+	sorted    []*Term
 }
 
 // Copy returns a deep copy of s.
@@ -1287,9 +1367,7 @@ func (s *set) Compare(other Value) int {
 		return 1
 	}
 	t := other.(*set)
-	sort.Sort(termSlice(s.keys))
-	sort.Sort(termSlice(t.keys))
-	return termSliceCompare(s.keys, t.keys)
+	return termSliceCompare(s.sortedKeys(), t.sortedKeys())
 }
 
 // Find returns the set or dereferences the element itself.
@@ -1436,14 +1514,25 @@ func (s *set) MarshalJSON() ([]byte, error) {
 
 // Sorted returns an Array that contains the sorted elements of s.
 func (s *set) Sorted() Array {
-	cpy := make(Array, len(s.keys))
-	for i := range cpy {
-		cpy[i] = s.keys[i]
-	}
-	sort.Sort(termSlice(cpy))
+	sorted := s.sortedKeys()
+	cpy := make(Array, len(sorted))
+	copy(cpy, sorted)
 	return cpy
 }
 
+// sortedKeys returns the elements of s in sorted order. The result must not be
+// modified. The order is cached for ground sets. Non-ground sets are not
+// cached because the compiler may rewrite their elements in place.
+func (s *set) sortedKeys() []*Term {
+	if !s.IsGround() {
+		return sortedTermSlice(s.keys)
+	}
+	s.sortGuard.Do(func() {
+		s.sorted = sortedTermSlice(s.keys)
+	})
+	return s.sorted
+}
+
 // Slice returns a slice of terms contained in the set.
 func (s *set) Slice() []*Term {
 	return s.keys
@@ -1502,10 +1591,25 @@ func (s *set) insert(x *Term) {
 
 	s.elems[hash] = x
 	s.keys = append(s.keys, x)
+	s.sortGuard = new(sync.Once)
 }
 
 func (s *set) get(x *Term) *Term {
 	hash := x.Hash()
+
+	// Fast path for scalar elements. See object.get for details.
+	switch v := x.Value.(type) {
+	case Null, Boolean, String, Var:
+		for curr, ok := s.elems[hash]; ok; {
+			if v == curr.Value {
+				return curr
+			}
+			hash++
+			curr, ok = s.elems[hash]
+		}
+		return nil
+	}
+
 	var equal func(v Value) bool
 
 	switch x := x.Value.(type) {
@@ -1593,6 +1697,11 @@ type object struct {
 	elems  map[int]*objectElem
 	keys   []*Term
 	ground bool
+
+	// sortGuard protects sorted, which is computed on demand. See set for
+	// details.
+	sortGuard *sync.Once
+	sorted    []*Term
 }
 
 func newobject(n int) *object {
@@ -1601,9 +1710,10 @@ func newobject(n int) *object {
 		keys = make([]*Term, 0, n)
 	}
 	return &object{
-		elems:  make(map[int]*objectElem, n),
-		keys:   keys,
-		ground: true,
+		elems:     make(map[int]*objectElem, n),
+		keys:      keys,
+		ground:    true,
+		sortGuard: new(sync.Once),
 	}
 }
 
@@ -1630,11 +1740,14 @@ func (obj *object) Compare(other Value) int {
 		return 1
 	}
 	a := obj
-	b := other.(*object)
-	keysA := a.Keys()
-	keysB := b.Keys()
-	sort.Sort(termSlice(keysA))
-	sort.Sort(termSlice(keysB))
+	b := other.(Object)
+	keysA := a.sortedKeys()
+	var keysB []*Term
+	if bObj, ok := b.(*object); ok {
+		keysB = bObj.sortedKeys()
+	} else {
+		keysB = sortedTermSlice(b.Keys())
+	}
 	minLen := a.Len()
 	if b.Len() < a.Len() {
 		minLen = b.Len()
@@ -1687,12 +1800,13 @@ func (obj *object) Get(k *Term) *Term {
 	return nil
 }
 
-// Hash returns the hash code for the Value.
+// Hash returns the hash code for the Value. The hash code does not depend on
+// the order of the keys, however, it does depend on which value is associated
+// with each key.
 func (obj *object) Hash() int {
 	var hash int
 	obj.Foreach(func(k, v *Term) {
-		hash += k.Value.Hash()
-		hash += v.Value.Hash()
+		hash += ObjectItemHash(k, v)
 	})
 	return hash
 }
@@ -1862,9 +1976,46 @@ func (obj object) String() string {
 	return "{" + strings.Join(buf, ", ") + "}"
 }
 
+// sortedKeys returns the keys of obj in sorted order. The result must not be
+// modified. See set.sortedKeys for details on caching.
+func (obj *object) sortedKeys() []*Term {
+	if !obj.ground {
+		return sortedTermSlice(obj.keys)
+	}
+	obj.sortGuard.Do(func() {
+		obj.sorted = sortedTermSlice(obj.keys)
+	})
+	return obj.sorted
+}
+
+// ObjectItemHash returns the hash code of a key-value pair in an object. The
+// hash code of an object is the sum of the hash codes of its pairs. The hash
+// codes of the pair are mixed so that swapping values between keys changes the
+// sum of the pair hashes. Implementations of Object must use this function to
+// hash their pairs so that equal objects have the same hash code.
+func ObjectItemHash(k, v *Term) int {
+	h := uint64(k.Value.Hash())*31 + uint64(v.Value.Hash())
+	h ^= h >> 33
+	h *= 0xff51afd7ed558ccd
+	h ^= h >> 33
+	return int(h)
+}
+
 func (obj *object) get(k *Term) *objectElem {
 	hash := k.Hash()
 
+	// Fast path for scalar keys that avoids allocating the equality function
+	// below.
+	switch x := k.Value.(type) {
+	case Null, Boolean, String, Var:
+		for curr := obj.elems[hash]; curr != nil; curr = curr.next {
+			if x == curr.key.Value {
+				return curr
+			}
+		}
+		return nil
+	}
+
 	var equal func(v Value) bool
 
 	switch x := k.Value.(type) {
@@ -1959,6 +2110,7 @@ func (obj *object) insert(k, v *Term) {
 	for curr := head; curr != nil; curr = curr.next {
 		if equal(curr.key.Value) {
 			curr.value = v
+			obj.ground = obj.ground && v.IsGround()
 			return
 		}
 	}
@@ -1969,6 +2121,7 @@ func (obj *object) insert(k, v *Term) {
 	}
 	obj.keys = append(obj.keys, k)
 	obj.ground = obj.ground && k.IsGround() && v.IsGround()
+	obj.sortGuard = new(sync.Once)
 }
 
 func filterObject(o Value, filter Value) (Value, error) {
@@ -2267,6 +2420,14 @@ func termSliceCopy(a []*Term) []*Term {
 	return cpy
 }
 
+// sortedTermSlice returns a sorted copy of a.
+func sortedTermSlice(a []*Term) []*Term {
+	cpy := make([]*Term, len(a))
+	copy(cpy, a)
+	sort.Sort(termSlice(cpy))
+	return cpy
+}
+
 func termSliceEqual(a, b []*Term) bool {
 	if len(a) == len(b) {
 		for i := range a {
@@ -2332,13 +2493,32 @@ func unmarshalExpr(expr *Expr, v map[string]interface{}) error {
 	if err := unmarshalExprIndex(expr, v); err != nil {
 		return err
 	}
+	if x, ok := v["generated"]; ok {
+		if b, ok := x.(bool); ok {
+			expr.Generated = b
+		}
+	}
 	switch ts := v["terms"].(type) {
 	case map[string]interface{}:
-		t, err := unmarshalTerm(ts)
-		if err != nil {
-			return err
+		if _, ok := ts["symbols"]; ok {
+			decl, err := unmarshalSomeDecl(ts)
+			if err != nil {
+				return err
+			}
+			expr.Terms = decl
+		} else if _, ok := ts["domain"]; ok {
+			every, err := unmarshalEvery(ts)
+			if err != nil {
+				return err
+			}
+			expr.Terms = every
+		} else {
+			t, err := unmarshalTerm(ts)
+			if err != nil {
+				return err
+			}
+			expr.Terms = t
 		}
-		expr.Terms = t
 	case []interface{}:
 		terms, err := unmarshalTermSlice(ts)
 		if err != nil {
@@ -2364,6 +2544,45 @@ func unmarshalExpr(expr *Expr, v map[string]interface{}) error {
 	return nil
 }
 
+func unmarshalSomeDecl(m map[string]interface{}) (*SomeDecl, error) {
+	if s, ok := m["symbols"].([]interface{}); ok {
+		symbols, err := unmarshalTermSlice(s)
+		if err != nil {
+			return nil, err
+		}
+		return &SomeDecl{Symbols: symbols}, nil
+	}
+	return nil, fmt.Errorf(`ast: unable to unmarshal some declaration (expected {"symbols": [...]})`)
+}
+
+func unmarshalEvery(m map[string]interface{}) (*Every, error) {
+	every := &Every{}
+	if k, ok := m["key"].(map[string]interface{}); ok {
+		key, err := unmarshalTerm(k)
+		if err != nil {
+			return nil, err
+		}
+		every.Key = key
+	}
+	v, ok1 := m["value"].(map[string]interface{})
+	d, ok2 := m["domain"].(map[string]interface{})
+	b, ok3 := m["body"].([]interface{})
+	if !ok1 || !ok2 || !ok3 {
+		return nil, fmt.Errorf(`ast: unable to unmarshal every statement (expected {"key": ..., "value": {...}, "domain": {...}, "body": [...]})`)
+	}
+	var err error
+	if every.Value, err = unmarshalTerm(v); err != nil {
+		return nil, err
+	}
+	if every.Domain, err = unmarshalTerm(d); err != nil {
+		return nil, err
+	}
+	if every.Body, err = unmarshalBody(b); err != nil {
+		return nil, err
+	}
+	return every, nil
+}
+
 func unmarshalExprIndex(expr *Expr, v map[string]interface{}) error {
 	if x, ok := v["index"]; ok {
 		if n, ok := x.(json.Number); ok {
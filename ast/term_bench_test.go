@@ -7,10 +7,12 @@ import (
 	"fmt"
 	"strings"
 	"testing"
+
+	"github.com/open-policy-agent/opa/util"
 )
 
 func BenchmarkObjectLookup(b *testing.B) {
-	sizes := []int{5, 50, 500, 5000}
+	sizes := []int{5, 50, 500, 5000, 10000}
 	for _, n := range sizes {
 		b.Run(fmt.Sprint(n), func(b *testing.B) {
 			obj := NewObject()
@@ -29,6 +31,47 @@ func BenchmarkObjectLookup(b *testing.B) {
 	}
 }
 
+func BenchmarkObjectEqual(b *testing.B) {
+	sizes := []int{5, 50, 500, 5000}
+	for _, n := range sizes {
+		b.Run(fmt.Sprint(n), func(b *testing.B) {
+			objA := NewObject()
+			objB := NewObject()
+			for i := 0; i < n; i++ {
+				objA.Insert(StringTerm(fmt.Sprint(i)), IntNumberTerm(i))
+				objB.Insert(StringTerm(fmt.Sprint(n-i-1)), IntNumberTerm(n-i-1))
+			}
+			a, c := NewTerm(objA), NewTerm(objB)
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				if !a.Equal(c) {
+					b.Fatal("expected equal")
+				}
+			}
+		})
+	}
+}
+
+func BenchmarkObjectCompare(b *testing.B) {
+	sizes := []int{5, 50, 500, 5000}
+	for _, n := range sizes {
+		b.Run(fmt.Sprint(n), func(b *testing.B) {
+			objA := NewObject()
+			objB := NewObject()
+			for i := 0; i < n; i++ {
+				objA.Insert(StringTerm(fmt.Sprint(i)), IntNumberTerm(i))
+				objB.Insert(StringTerm(fmt.Sprint(i)), IntNumberTerm(i+1))
+			}
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				if Compare(objA, objB) >= 0 {
+					b.Fatal("expected less than")
+				}
+			}
+		})
+	}
+}
+
 func BenchmarkSetIntersection(b *testing.B) {
 	sizes := []int{5, 50, 500, 5000}
 	for _, n := range sizes {
@@ -105,3 +148,49 @@ func BenchmarkTermHashing(b *testing.B) {
 		})
 	}
 }
+
+func BenchmarkInterfaceToValueStruct(b *testing.B) {
+
+	type resource struct {
+		Kind   string            `json:"kind"`
+		Labels map[string]string `json:"labels"`
+		Ports  []int             `json:"ports"`
+	}
+
+	input := struct {
+		User      string     `json:"user"`
+		Groups    []string   `json:"groups"`
+		Resources []resource `json:"resources"`
+	}{
+		User:   "alice",
+		Groups: []string{"dev", "ops"},
+	}
+
+	for i := 0; i < 100; i++ {
+		input.Resources = append(input.Resources, resource{
+			Kind:   "pod",
+			Labels: map[string]string{"app": fmt.Sprint(i)},
+			Ports:  []int{80, 443},
+		})
+	}
+
+	b.Run("reflect", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			if _, err := InterfaceToValue(input); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+
+	b.Run("roundtrip", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			var x interface{} = input
+			if err := util.RoundTrip(&x); err != nil {
+				b.Fatal(err)
+			}
+			if _, err := InterfaceToValue(x); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
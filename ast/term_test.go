@@ -84,6 +84,107 @@ func TestInterfaceToValue(t *testing.T) {
 
 }
 
+type reflectTestEmbedded struct {
+	ID     string `json:"id"`
+	Shadow int
+}
+
+type reflectTestHidden struct {
+	Hidden string
+}
+
+type reflectTestText int
+
+func (t reflectTestText) MarshalText() ([]byte, error) {
+	return []byte(fmt.Sprintf("text-%d", t)), nil
+}
+
+type reflectTestMessage struct {
+	reflectTestEmbedded
+	*reflectTestHidden
+	Name          string                   `json:"name,omitempty"`
+	Count         int64                    `json:"count,string"`
+	Ratio         float32                  `json:"ratio"`
+	Tags          []string                 `json:"tags"`
+	Labels        map[string]string        `json:"labels,omitempty"`
+	Scores        map[int]uint8            `json:"scores"`
+	Keys          map[reflectTestText]bool `json:"keys"`
+	Payload       []byte                   `json:"payload"`
+	Nested        *reflectTestMessage      `json:"nested,omitempty"`
+	Any           interface{}              `json:"any"`
+	Shadow        string                   `json:"Shadow"`
+	Raw           json.RawMessage          `json:"raw"`
+	Text          reflectTestText          `json:"text"`
+	Skipped       string                   `json:"-"`
+	XXX_sizecache int32                    `json:"-"`
+	unexported    string
+}
+
+func TestInterfaceToValueReflect(t *testing.T) {
+
+	tests := []struct {
+		note  string
+		input interface{}
+	}{
+		{"typed slice", []string{"a", "b"}},
+		{"typed map", map[string]int{"a": 1}},
+		{"nested typed values", map[string]interface{}{"a": []bool{true}, "b": []map[string]float64{{"c": 1.5}}}},
+		{"named string", reflectTestText(1)},
+		{"pointer", &[]int{1, 2}},
+		{"nil pointer", (*reflectTestMessage)(nil)},
+		{"struct", reflectTestMessage{
+			reflectTestEmbedded: reflectTestEmbedded{ID: "x", Shadow: 7},
+			reflectTestHidden:   &reflectTestHidden{Hidden: "promoted"},
+			Count:               12,
+			Ratio:               0.1,
+			Scores:              map[int]uint8{1: 2},
+			Keys:                map[reflectTestText]bool{3: true},
+			Payload:             []byte("hello"),
+			Nested:              &reflectTestMessage{Name: "child"},
+			Any:                 []int{3},
+			Shadow:              "outer",
+			Raw:                 json.RawMessage(`{"y": [1, 2.5]}`),
+			Text:                4,
+			Skipped:             "skipped",
+			unexported:          "unexported",
+		}},
+		{"struct with nil embedded pointer", reflectTestMessage{Labels: map[string]string{"a": "b"}}},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.note, func(t *testing.T) {
+
+			bs, err := json.Marshal(tc.input)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var x interface{}
+			if err := util.UnmarshalJSON(bs, &x); err != nil {
+				t.Fatal(err)
+			}
+
+			expected, err := InterfaceToValue(x)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			v, err := InterfaceToValue(tc.input)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if v.Compare(expected) != 0 {
+				t.Fatalf("Expected %v but got: %v", expected, v)
+			}
+		})
+	}
+
+	if _, err := InterfaceToValue(make(chan int)); err == nil || !strings.Contains(err.Error(), "illegal value") {
+		t.Fatalf("Expected illegal value error but got: %v", err)
+	}
+}
+
 func TestObjectInsertGetLen(t *testing.T) {
 	tests := []struct {
 		insert   [][2]string
@@ -328,6 +429,123 @@ func TestHash(t *testing.T) {
 	}
 }
 
+func TestHashCompositeDistinct(t *testing.T) {
+
+	tests := []struct {
+		a string
+		b string
+	}{
+		{`[1, 2]`, `[2, 1]`},
+		{`[[1], [2]]`, `[[2], [1]]`},
+		{`{"a": 1, "b": 2}`, `{"a": 2, "b": 1}`},
+		{`{1: 2}`, `{2: 1}`},
+	}
+
+	for _, tc := range tests {
+		a, b := MustParseTerm(tc.a), MustParseTerm(tc.b)
+		if a.Hash() == b.Hash() {
+			t.Errorf("Expected hash codes of %v and %v to differ", a, b)
+		}
+	}
+}
+
+func TestValueEqual(t *testing.T) {
+
+	tests := []struct {
+		a        string
+		b        string
+		expected bool
+	}{
+		{`[1, 2, 3]`, `[1, 2, 3]`, true},
+		{`[1, 2, 3]`, `[1, 3, 2]`, false},
+		{`[1, 2]`, `[1, 2, 3]`, false},
+		{`[1]`, `[1.0]`, true},
+		{`{"a": 1, "b": [2]}`, `{"b": [2], "a": 1}`, true},
+		{`{"a": 1, "b": [2]}`, `{"b": [2], "a": 2}`, false},
+		{`{"a": 1, "b": [2]}`, `{"b": [2], "c": 1}`, false},
+		{`{"a": 1}`, `{"a": 1, "b": 2}`, false},
+		{`{1, {2, 3}}`, `{{3, 2}, 1}`, true},
+		{`{1, {2, 3}}`, `{{3, 2}, 2}`, false},
+		{`{1, 2}`, `[1, 2]`, false},
+		{`{"a": x}`, `{"a": x}`, true},
+		{`{x, y}`, `{y, x}`, true},
+	}
+
+	for _, tc := range tests {
+		a, b := MustParseTerm(tc.a), MustParseTerm(tc.b)
+		if result := ValueEqual(a.Value, b.Value); result != tc.expected {
+			t.Errorf("Expected ValueEqual(%v, %v) to be %v", a, b, tc.expected)
+		}
+		if result := Compare(a, b) == 0; result != tc.expected {
+			t.Errorf("Expected Compare(%v, %v) == 0 to be %v", a, b, tc.expected)
+		}
+	}
+}
+
+func TestValueEqualRewrittenInPlace(t *testing.T) {
+
+	// Sets whose elements are rewritten in place no longer find those elements
+	// by hash.
+	rewrite := func(x *Term) *Term {
+		WalkTerms(x, func(t *Term) bool {
+			if t.Value.Compare(String("a")) == 0 {
+				t.Value = String("b")
+			}
+			return false
+		})
+		return x
+	}
+
+	tests := []struct {
+		a        *Term
+		b        *Term
+		expected bool
+	}{
+		{rewrite(MustParseTerm(`{"a", "c"}`)), MustParseTerm(`{"b", "c"}`), true},
+		{rewrite(MustParseTerm(`{"a", "c"}`)), MustParseTerm(`{"a", "c"}`), false},
+		{rewrite(MustParseTerm(`{"a", "c"}`)), MustParseTerm(`{"b", "d"}`), false},
+		{rewrite(MustParseTerm(`{{"a"}, "c"}`)), MustParseTerm(`{{"b"}, "c"}`), true},
+	}
+
+	for _, tc := range tests {
+		if result := ValueEqual(tc.a.Value, tc.b.Value); result != tc.expected {
+			t.Errorf("Expected ValueEqual(%v, %v) to be %v", tc.a, tc.b, tc.expected)
+		}
+		if result := ValueEqual(tc.b.Value, tc.a.Value); result != tc.expected {
+			t.Errorf("Expected ValueEqual(%v, %v) to be %v", tc.b, tc.a, tc.expected)
+		}
+	}
+}
+
+func TestCompareAfterInsert(t *testing.T) {
+
+	obj := MustParseTerm(`{"b": 1}`).Value.(Object)
+	exp := MustParseTerm(`{"a": 1, "b": 1}`).Value
+
+	if Compare(obj, exp) <= 0 {
+		t.Fatalf("Expected %v to be greater than %v", obj, exp)
+	}
+
+	obj.Insert(StringTerm("a"), IntNumberTerm(1))
+
+	if Compare(obj, exp) != 0 {
+		t.Fatalf("Expected %v to equal %v", obj, exp)
+	}
+
+	set := MustParseTerm(`{2}`).Value.(Set)
+	expSet := MustParseTerm(`{1, 2}`).Value
+
+	if Compare(set, expSet) <= 0 {
+		t.Fatalf("Expected %v to be greater than %v", set, expSet)
+	}
+
+	set.Add(IntNumberTerm(1))
+
+	if Compare(set, expSet) != 0 {
+		t.Fatalf("Expected %v to equal %v", set, expSet)
+	}
+}
+
 func TestTermIsGround(t *testing.T) {
 
 	tests := []struct {
@@ -420,6 +638,8 @@ func TestTermString(t *testing.T) {
 	assertToString(t, RefTerm(VarTerm("foo"), StringTerm("bar"), VarTerm("i"), IntNumberTerm(0), StringTerm("baz")).Value, "foo.bar[i][0].baz")
 	assertToString(t, RefTerm(VarTerm("foo"), BooleanTerm(false), NullTerm(), StringTerm("bar")).Value, "foo[false][null].bar")
 	assertToString(t, RefTerm(VarTerm("p"), StringTerm("not")).Value, `p["not"]`)
+	assertToString(t, RefTerm(VarTerm("p"), StringTerm("größe")).Value, `p.größe`)
+	assertToString(t, RefTerm(VarTerm("p"), StringTerm("x€")).Value, `p["x€"]`)
 	assertToString(t, ArrayTerm().Value, "[]")
 	assertToString(t, ObjectTerm().Value, "{}")
 	assertToString(t, SetTerm().Value, "set()")
@@ -429,6 +649,51 @@ func TestTermString(t *testing.T) {
 	assertToString(t, SetComprehensionTerm(ArrayTerm(VarTerm("x")), NewBody(&Expr{Terms: RefTerm(VarTerm("a"), VarTerm("i"))})).Value, `{[x] | a[i]}`)
 }
 
+func TestStringRoundTrip(t *testing.T) {
+
+	tests := []struct {
+		input    string
+		value    string
+		expected string
+	}{
+		{`"foo"`, "foo", `"foo"`},
+		{`"\u0041\n\t\r\b\f\/"`, "A\n\t\r\b\f/", `"A\n\t\r\b\f/"`},
+		{`"\u0007\u000b\u007f"`, "\a\v\x7f", `"\u0007\u000b\u007f"`},
+		{`"\u00e9\ud83d\ude00"`, "é😀", `"é😀"`},
+		{`"\u2028\ufeff"`, "\u2028\ufeff", `"\u2028\ufeff"`},
+		{`"\udb40\udc01"`, "\U000e0001", `"\udb40\udc01"`},
+		{"`C:\\foo\\bar`", `C:\foo\bar`, `"C:\\foo\\bar"`},
+		{"`^[a-z]+\\d\\.\\\\$`", `^[a-z]+\d\.\\$`, `"^[a-z]+\\d\\.\\\\$"`},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.input, func(t *testing.T) {
+			term, err := ParseTerm(tc.input)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if term.Value.Compare(String(tc.value)) != 0 {
+				t.Fatalf("Expected %q but got %q", tc.value, term.Value)
+			}
+			result := term.String()
+			if result != tc.expected {
+				t.Fatalf("Expected %v but got %v", tc.expected, result)
+			}
+			parsed, err := ParseTerm(result)
+			if err != nil {
+				t.Fatal(err)
+			} else if !parsed.Equal(term) {
+				t.Fatalf("Expected %v after round trip but got %v", term, parsed)
+			}
+		})
+	}
+
+	// Invalid UTF-8 cannot be represented in string literals.
+	if result := String("a\xffb").String(); result != `"a\ufffdb"` {
+		t.Fatalf("Unexpected string: %v", result)
+	}
+}
+
 func TestRefHasPrefix(t *testing.T) {
 
 	a := MustParseRef("foo.bar.baz")
@@ -569,6 +834,33 @@ func TestRefPtr(t *testing.T) {
 
 }
 
+func TestCompareDoesNotModifyOperands(t *testing.T) {
+
+	// Composite values may be shared by concurrent evaluations so comparison
+	// must not reorder their elements.
+	a := MustParseTerm(`{3, 1, 2}`).Value
+	b := MustParseTerm(`{2, 3, 1}`).Value
+
+	if Compare(a, b) != 0 {
+		t.Fatal("Expected sets to be equal")
+	}
+
+	if exp := MustParseTerm(`[3, 1, 2]`).Value; !Array(a.(Set).Slice()).Equal(exp) {
+		t.Fatalf("Expected set order to be unchanged but got: %v", a)
+	}
+
+	x := MustParseTerm(`{"c": 1, "a": 2, "b": 3}`).Value
+	y := MustParseTerm(`{"a": 2, "b": 3, "c": 1}`).Value
+
+	if Compare(x, y) != 0 {
+		t.Fatal("Expected objects to be equal")
+	}
+
+	if exp := MustParseTerm(`["c", "a", "b"]`).Value; !Array(x.(Object).Keys()).Equal(exp) {
+		t.Fatalf("Expected object key order to be unchanged but got: %v", x)
+	}
+}
+
 func TestSetEqual(t *testing.T) {
 	tests := []struct {
 		a        string
@@ -891,6 +1183,33 @@ func TestLocationCompare(t *testing.T) {
 	}
 }
 
+func TestLocationEnd(t *testing.T) {
+
+	tests := []struct {
+		note   string
+		text   string
+		row    int
+		col    int
+		expRow int
+		expCol int
+	}{
+		{"empty", "", 3, 4, 3, 4},
+		{"single line", "foo", 3, 4, 3, 7},
+		{"multi-byte", `"größe"`, 1, 1, 1, 8},
+		{"multiple lines", "p {\n\tx\n}", 2, 1, 4, 2},
+		{"trailing newline", "x\n", 2, 5, 3, 1},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.note, func(t *testing.T) {
+			row, col := NewLocation([]byte(tc.text), "", tc.row, tc.col).End()
+			if row != tc.expRow || col != tc.expCol {
+				t.Fatalf("Expected %d:%d but got %d:%d", tc.expRow, tc.expCol, row, col)
+			}
+		})
+	}
+}
+
 func assertTermEqual(t *testing.T, x *Term, y *Term) {
 	if !x.Equal(y) {
 		t.Errorf("Failure on equality: \n%s and \n%s\n", x, y)
@@ -151,6 +151,14 @@ func Transform(t Transformer, x interface{}) (interface{}, error) {
 				return nil, fmt.Errorf("illegal transform: %T != %T", y, decl)
 			}
 			return y, nil
+		case *Every:
+			every, err := Transform(t, ts)
+			if err != nil {
+				return nil, err
+			}
+			if y.Terms, ok = every.(*Every); !ok {
+				return nil, fmt.Errorf("illegal transform: %T != %T", y, every)
+			}
 		case []*Term:
 			for i := range ts {
 				if ts[i], err = transformTerm(t, ts[i]); err != nil {
@@ -172,6 +180,26 @@ func Transform(t Transformer, x interface{}) (interface{}, error) {
 			}
 		}
 		return y, nil
+	case *Every:
+		if y.Key != nil {
+			if y.Key, err = transformTerm(t, y.Key); err != nil {
+				return nil, err
+			}
+		}
+		if y.Value, err = transformTerm(t, y.Value); err != nil {
+			return nil, err
+		}
+		if y.Domain, err = transformTerm(t, y.Domain); err != nil {
+			return nil, err
+		}
+		body, err := Transform(t, y.Body)
+		if err != nil {
+			return nil, err
+		}
+		if y.Body, ok = body.(Body); !ok {
+			return nil, fmt.Errorf("illegal transform: %T != %T", y.Body, body)
+		}
+		return y, nil
 	case *With:
 		if y.Target, err = transformTerm(t, y.Target); err != nil {
 			return nil, err
@@ -275,7 +303,16 @@ func TransformVars(x interface{}, f func(Var) (Value, error)) (interface{}, erro
 	return Transform(t, x)
 }
 
-// TransformComprehensions calls the functio nf on all comprehensions under x.
+// TransformTerms calls the function f on all terms under x. If f returns a
+// different (non-nil) term, the term is replaced and the terms under the
+// replacement are not visited. Replacements without a location inherit the
+// location of the term they replace.
+func TransformTerms(x interface{}, f func(*Term) (*Term, error)) (interface{}, error) {
+	t := &termTransformer{f}
+	return t.Transform(x)
+}
+
+// TransformComprehensions calls the function f on all comprehensions under x.
 func TransformComprehensions(x interface{}, f func(interface{}) (Value, error)) (interface{}, error) {
 	t := &GenericTransformer{func(x interface{}) (interface{}, error) {
 		switch x := x.(type) {
@@ -345,6 +382,195 @@ func transformBody(t Transformer, body Body) (Body, error) {
 	return r, nil
 }
 
+// termTransformer implements the Transformer interface to replace entire
+// terms (as opposed to values) while preserving locations. Transform only
+// passes values to transformers, so termTransformer walks x itself.
+type termTransformer struct {
+	f func(*Term) (*Term, error)
+}
+
+func (t *termTransformer) Transform(x interface{}) (interface{}, error) {
+	var err error
+	switch y := x.(type) {
+	case *Term:
+		return t.term(y)
+	case *Module:
+		if err := t.terms(y.Package.Path); err != nil {
+			return nil, err
+		}
+		for i := range y.Imports {
+			if y.Imports[i].Path, err = t.term(y.Imports[i].Path); err != nil {
+				return nil, err
+			}
+		}
+		for i := range y.Rules {
+			if _, err := t.Transform(y.Rules[i]); err != nil {
+				return nil, err
+			}
+		}
+		return y, nil
+	case *Package:
+		return y, t.terms(y.Path)
+	case *Import:
+		y.Path, err = t.term(y.Path)
+		return y, err
+	case *Rule:
+		if _, err := t.Transform(y.Head); err != nil {
+			return nil, err
+		}
+		if _, err := t.Transform(y.Body); err != nil {
+			return nil, err
+		}
+		if y.Else != nil {
+			if _, err := t.Transform(y.Else); err != nil {
+				return nil, err
+			}
+		}
+		return y, nil
+	case *Head:
+		if err := t.terms(y.Args); err != nil {
+			return nil, err
+		}
+		if y.Key != nil {
+			if y.Key, err = t.term(y.Key); err != nil {
+				return nil, err
+			}
+		}
+		if y.Value != nil {
+			if y.Value, err = t.term(y.Value); err != nil {
+				return nil, err
+			}
+		}
+		return y, nil
+	case Args:
+		return y, t.terms(y)
+	case Body:
+		for i := range y {
+			if _, err := t.Transform(y[i]); err != nil {
+				return nil, err
+			}
+		}
+		return y, nil
+	case *Expr:
+		switch ts := y.Terms.(type) {
+		case *Every:
+			if _, err := t.Transform(ts); err != nil {
+				return nil, err
+			}
+		case []*Term:
+			if err := t.terms(ts); err != nil {
+				return nil, err
+			}
+		case *Term:
+			if y.Terms, err = t.term(ts); err != nil {
+				return nil, err
+			}
+		}
+		for i := range y.With {
+			if _, err := t.Transform(y.With[i]); err != nil {
+				return nil, err
+			}
+		}
+		return y, nil
+	case *Every:
+		if y.Key != nil {
+			if y.Key, err = t.term(y.Key); err != nil {
+				return nil, err
+			}
+		}
+		if y.Value, err = t.term(y.Value); err != nil {
+			return nil, err
+		}
+		if y.Domain, err = t.term(y.Domain); err != nil {
+			return nil, err
+		}
+		if _, err := t.Transform(y.Body); err != nil {
+			return nil, err
+		}
+		return y, nil
+	case *With:
+		if y.Target, err = t.term(y.Target); err != nil {
+			return nil, err
+		}
+		if y.Value, err = t.term(y.Value); err != nil {
+			return nil, err
+		}
+		return y, nil
+	case Ref:
+		return y, t.terms(y)
+	case Array:
+		return y, t.terms(y)
+	case Call:
+		return y, t.terms(y)
+	case Object:
+		return y.Map(func(k, v *Term) (*Term, *Term, error) {
+			k, err := t.term(k)
+			if err != nil {
+				return nil, nil, err
+			}
+			v, err = t.term(v)
+			if err != nil {
+				return nil, nil, err
+			}
+			return k, v, nil
+		})
+	case Set:
+		return y.Map(t.term)
+	case *ArrayComprehension:
+		if y.Term, err = t.term(y.Term); err != nil {
+			return nil, err
+		}
+		_, err = t.Transform(y.Body)
+		return y, err
+	case *SetComprehension:
+		if y.Term, err = t.term(y.Term); err != nil {
+			return nil, err
+		}
+		_, err = t.Transform(y.Body)
+		return y, err
+	case *ObjectComprehension:
+		if y.Key, err = t.term(y.Key); err != nil {
+			return nil, err
+		}
+		if y.Value, err = t.term(y.Value); err != nil {
+			return nil, err
+		}
+		_, err = t.Transform(y.Body)
+		return y, err
+	}
+	return x, nil
+}
+
+// term calls f on the term. If f returns a different (non-nil) term, the
+// replacement is returned without walking it. Otherwise, the terms under the
+// value of the term are transformed.
+func (t *termTransformer) term(term *Term) (*Term, error) {
+	r, err := t.f(term)
+	if err != nil {
+		return nil, err
+	} else if r != nil && r != term {
+		if r.Location == nil {
+			return &Term{Value: r.Value, Location: term.Location}, nil
+		}
+		return r, nil
+	}
+	v, err := t.Transform(term.Value)
+	if err != nil {
+		return nil, err
+	}
+	return &Term{Value: v.(Value), Location: term.Location}, nil
+}
+
+func (t *termTransformer) terms(ts []*Term) error {
+	var err error
+	for i := range ts {
+		if ts[i], err = t.term(ts[i]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 func transformTerm(t Transformer, term *Term) (*Term, error) {
 	v, err := transformValue(t, term.Value)
 	if err != nil {
@@ -4,7 +4,10 @@
 
 package ast
 
-import "testing"
+import (
+	"fmt"
+	"testing"
+)
 
 func TestTransform(t *testing.T) {
 	module := MustParseModule(`package ex.this
@@ -60,3 +63,77 @@ foo(x) = y { split(x, "that", y) }
 	}
 
 }
+
+func TestTransformTerms(t *testing.T) {
+	module := MustParseModule(`package test
+
+p[x] = y { x := {"a", input.a}; y := [z | z := input.a[_]] }
+q { input.a with input.b as input.a }
+`)
+
+	target := MustParseRef("input.a")
+
+	result, err := TransformTerms(module, func(x *Term) (*Term, error) {
+		if r, ok := x.Value.(Ref); ok && r.Equal(target) {
+			return VarTerm("a"), nil
+		}
+		return x, nil
+	})
+
+	if err != nil {
+		t.Fatalf("Unexpected error during transform: %v", err)
+	}
+
+	expected := MustParseModule(`package test
+
+p[x] = y { x := {"a", a}; y := [z | z := input.a[_]] }
+q { a with input.b as a }
+`)
+
+	resultMod := result.(*Module)
+
+	if !expected.Equal(resultMod) {
+		t.Fatalf("Expected module:\n%v\n\nGot:\n%v\n", expected, resultMod)
+	}
+
+	// Sets are rebuilt so that lookups on replaced elements succeed.
+	set := resultMod.Rules[0].Body[0].Operand(1).Value.(Set)
+	if !set.Contains(VarTerm("a")) || set.Contains(MustParseTerm("input.a")) {
+		t.Fatalf("Expected set to contain replaced element but got: %v", set)
+	}
+
+	// Replacements inherit the location of the replaced term.
+	WalkTerms(resultMod, func(x *Term) bool {
+		if x.Location == nil {
+			t.Fatalf("Expected location on term %v", x)
+		}
+		return false
+	})
+
+	term, err := TransformTerms(MustParseTerm("input.a"), func(x *Term) (*Term, error) {
+		return BooleanTerm(true), nil
+	})
+	if err != nil || !term.(*Term).Equal(BooleanTerm(true)) {
+		t.Fatalf("Expected term to be replaced but got: %v (err: %v)", term, err)
+	}
+
+	// Terms under replacements are not visited.
+	visited := 0
+	term, err = TransformTerms(MustParseTerm(`[input.a, f(input.a)]`), func(x *Term) (*Term, error) {
+		visited++
+		if _, ok := x.Value.(Call); ok {
+			return MustParseTerm("input.a.b"), nil
+		}
+		return x, nil
+	})
+	if err != nil || !term.(*Term).Equal(MustParseTerm(`[input.a, input.a.b]`)) || visited != 5 {
+		t.Fatalf("Expected call to be replaced without visiting replacement but got: %v (visited: %v, err: %v)", term, visited, err)
+	}
+
+	_, err = TransformTerms(module, func(x *Term) (*Term, error) {
+		return nil, fmt.Errorf("bad term")
+	})
+	if err == nil || err.Error() != "bad term" {
+		t.Fatalf("Expected error but got: %v", err)
+	}
+}
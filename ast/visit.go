@@ -87,6 +87,8 @@ func walk(v Visitor, x interface{}) {
 		switch ts := x.Terms.(type) {
 		case *SomeDecl:
 			Walk(w, ts)
+		case *Every:
+			Walk(w, ts)
 		case []*Term:
 			for _, t := range ts {
 				Walk(w, t)
@@ -97,6 +99,13 @@ func walk(v Visitor, x interface{}) {
 		for i := range x.With {
 			Walk(w, x.With[i])
 		}
+	case *Every:
+		if x.Key != nil {
+			Walk(w, x.Key)
+		}
+		Walk(w, x.Value)
+		Walk(w, x.Domain)
+		Walk(w, x.Body)
 	case *With:
 		Walk(w, x.Target)
 		Walk(w, x.Value)
@@ -9,12 +9,15 @@ import (
 	"archive/tar"
 	"bytes"
 	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
 	"fmt"
 	"io"
 	"net/url"
 	"path/filepath"
 	"reflect"
+	"sort"
 	"strings"
 
 	"github.com/open-policy-agent/opa/internal/file/archive"
@@ -33,6 +36,13 @@ const (
 	manifestExt  = ".manifest"
 	dataFile     = "data.json"
 	yamlDataFile = "data.yaml"
+	patchFile    = "patch.json"
+)
+
+// Bundle types.
+const (
+	SnapshotBundleType = "snapshot"
+	DeltaBundleType    = "delta"
 )
 
 const bundleLimitBytes = (1024 * 1024 * 1024) + 1 // limit bundle reads to 1GB to protect against gzip bombs
@@ -42,13 +52,151 @@ type Bundle struct {
 	Manifest Manifest
 	Data     map[string]interface{}
 	Modules  []ModuleFile
+	Patch    Patch
+}
+
+// Patch contains the JSON Patch operations carried by a delta bundle.
+type Patch struct {
+	Data []PatchOperation `json:"data"`
+}
+
+// PatchOperation represents a single JSON Patch operation on the data. The op
+// is one of "add", "remove", "replace", or "upsert". Upsert operations create
+// missing parent documents before adding the value.
+type PatchOperation struct {
+	Op    string      `json:"op"`
+	Path  string      `json:"path"`
+	Value interface{} `json:"value,omitempty"`
+}
+
+// Type returns the type of the bundle. Bundles that contain a patch file
+// (i.e., a non-nil list of patch operations) are delta bundles. Delta bundles
+// carry patch operations that are applied on top of the data of the active
+// (snapshot) bundle with the same name. Snapshot bundles replace the policies
+// and data under their roots.
+func (b Bundle) Type() string {
+	if b.Patch.Data != nil {
+		return DeltaBundleType
+	}
+	return SnapshotBundleType
 }
 
 // Manifest represents the manifest from a bundle. The manifest may contain
 // metadata such as the bundle revision.
 type Manifest struct {
-	Revision string    `json:"revision"`
-	Roots    *[]string `json:"roots,omitempty"`
+	Revision     string       `json:"revision"`
+	Roots        *[]string    `json:"roots,omitempty"`
+	Files        []FileDigest `json:"files,omitempty"`
+	Dependencies []Dependency `json:"dependencies,omitempty"`
+}
+
+// FileDigest contains the digest of a file in the bundle. If the manifest
+// contains file digests, readers verify that the bundle contains exactly the
+// listed data and policy files and that the contents match the digests.
+type FileDigest struct {
+	Path   string `json:"path"`
+	Digest string `json:"digest"`
+}
+
+// Dependency describes a library bundle that the bundle was built with. The
+// policies of the library are included in the bundle under the directory
+// returned by DependencyDir. The digest covers the paths and contents of the
+// policies so that readers can verify that the included policies match the
+// locked version of the library.
+type Dependency struct {
+	Name     string `json:"name"`
+	Revision string `json:"revision,omitempty"`
+	Digest   string `json:"digest"`
+}
+
+// DependencyDir returns the directory that the policies of the named
+// dependency are written to in bundles.
+func DependencyDir(name string) string {
+	return "/deps/" + name
+}
+
+// DependencyDigest returns the digest of the policies of a dependency. The
+// module paths are relative to the root of the dependency.
+func DependencyDigest(modules []ModuleFile) string {
+
+	lines := make([]string, len(modules))
+
+	for i, module := range modules {
+		lines[i] = digestPath(module.Path) + " " + digest(module.Raw) + "\n"
+	}
+
+	sort.Strings(lines)
+
+	return digest([]byte(strings.Join(lines, "")))
+}
+
+// verifyDependencies checks the policies included for the dependencies listed
+// in the manifest against the dependency digests.
+func (m *Manifest) verifyDependencies(modules []ModuleFile) error {
+
+	for _, dep := range m.Dependencies {
+
+		prefix := DependencyDir(dep.Name) + "/"
+		var included []ModuleFile
+
+		for _, module := range modules {
+			path := digestPath(module.Path)
+			if strings.HasPrefix(path, prefix) {
+				included = append(included, ModuleFile{Path: strings.TrimPrefix(path, prefix), Raw: module.Raw})
+			}
+		}
+
+		if DependencyDigest(included) != dep.Digest {
+			return fmt.Errorf("bundle dependency '%v' does not match manifest digest", dep.Name)
+		}
+	}
+
+	return nil
+}
+
+// digestAlgorithm is the prefix of the file digests written into manifests.
+const digestAlgorithm = "sha256:"
+
+func digest(bs []byte) string {
+	sum := sha256.Sum256(bs)
+	return digestAlgorithm + hex.EncodeToString(sum[:])
+}
+
+func digestPath(path string) string {
+	return "/" + strings.TrimLeft(path, "/")
+}
+
+// verifyDigests checks the digests of the files read from the bundle against
+// the digests in the manifest. If the manifest does not contain digests, the
+// check is skipped.
+func (m *Manifest) verifyDigests(digests map[string]string) error {
+
+	if len(m.Files) == 0 {
+		return nil
+	}
+
+	expected := make(map[string]string, len(m.Files))
+
+	for _, f := range m.Files {
+		expected[digestPath(f.Path)] = f.Digest
+	}
+
+	for path, d := range digests {
+		exp, ok := expected[path]
+		if !ok {
+			return fmt.Errorf("bundle file '%v' is not listed in manifest", path)
+		} else if exp != d {
+			return fmt.Errorf("bundle file '%v' does not match manifest digest", path)
+		}
+	}
+
+	for path := range expected {
+		if _, ok := digests[path]; !ok {
+			return fmt.Errorf("bundle file '%v' listed in manifest is missing", path)
+		}
+	}
+
+	return nil
 }
 
 // Init initializes the manifest. If you instantiate a manifest
@@ -85,7 +233,7 @@ func (m *Manifest) validateAndInjectDefaults(b Bundle) error {
 		found := false
 		if path, err := module.Parsed.Package.Path.Ptr(); err == nil {
 			for i := range roots {
-				if strings.HasPrefix(path, roots[i]) {
+				if RootContains(roots[i], path) {
 					found = true
 					break
 				}
@@ -100,13 +248,13 @@ func (m *Manifest) validateAndInjectDefaults(b Bundle) error {
 	return dfs(b.Data, "", func(path string, node interface{}) (bool, error) {
 		path = strings.Trim(path, "/")
 		for i := range roots {
-			if strings.HasPrefix(path, roots[i]) {
+			if RootContains(roots[i], path) {
 				return true, nil
 			}
 		}
 		if _, ok := node.(map[string]interface{}); ok {
 			for i := range roots {
-				if strings.HasPrefix(roots[i], path) {
+				if RootContains(path, roots[i]) {
 					return false, nil
 				}
 			}
@@ -163,6 +311,7 @@ func (r *Reader) Read() (Bundle, error) {
 	var bundle Bundle
 
 	bundle.Data = map[string]interface{}{}
+	digests := map[string]string{}
 
 	for {
 		f, err := r.loader.NextFile()
@@ -185,6 +334,10 @@ func (r *Reader) Read() (Bundle, error) {
 		// Normalize the paths to use `/` separators
 		path := filepath.ToSlash(f.Path())
 
+		if strings.HasSuffix(path, RegoExt) || filepath.Base(path) == dataFile || filepath.Base(path) == yamlDataFile || filepath.Base(path) == patchFile {
+			digests[digestPath(path)] = digest(buf.Bytes())
+		}
+
 		if strings.HasSuffix(path, RegoExt) {
 			r.metrics.Timer(metrics.RegoModuleParse).Start()
 			module, err := ast.ParseModule(path, buf.String())
@@ -234,6 +387,12 @@ func (r *Reader) Read() (Bundle, error) {
 				return bundle, err
 			}
 
+		} else if filepath.Base(path) == patchFile {
+
+			if err := util.NewJSONDecoder(&buf).Decode(&bundle.Patch); err != nil {
+				return bundle, errors.Wrapf(err, "bundle load failed on %v", path)
+			}
+
 		} else if strings.HasSuffix(path, manifestExt) {
 			if err := util.NewJSONDecoder(&buf).Decode(&bundle.Manifest); err != nil {
 				return bundle, errors.Wrap(err, "bundle load failed on manifest decode")
@@ -241,6 +400,23 @@ func (r *Reader) Read() (Bundle, error) {
 		}
 	}
 
+	if err := bundle.Manifest.verifyDigests(digests); err != nil {
+		return bundle, err
+	}
+
+	if err := bundle.Manifest.verifyDependencies(bundle.Modules); err != nil {
+		return bundle, err
+	}
+
+	if bundle.Type() == DeltaBundleType {
+		if len(bundle.Data) > 0 || len(bundle.Modules) > 0 {
+			return bundle, fmt.Errorf("delta bundle must only contain %v and the manifest", patchFile)
+		}
+		if err := bundle.Patch.validate(); err != nil {
+			return bundle, err
+		}
+	}
+
 	if err := bundle.Manifest.validateAndInjectDefaults(bundle); err != nil {
 		return bundle, err
 	}
@@ -268,25 +444,82 @@ func (r *Reader) Read() (Bundle, error) {
 	return bundle, nil
 }
 
-// Write serializes the Bundle and writes it to w.
-func Write(w io.Writer, bundle Bundle) error {
-	gw := gzip.NewWriter(w)
+func (p Patch) validate() error {
+	for _, op := range p.Data {
+		switch op.Op {
+		case "add", "remove", "replace", "upsert":
+		default:
+			return fmt.Errorf("delta bundle has invalid patch operation: %v", op.Op)
+		}
+		if !strings.HasPrefix(op.Path, "/") {
+			return fmt.Errorf("delta bundle has invalid patch path: %v", op.Path)
+		}
+	}
+	return nil
+}
+
+// Writer contains the writer to serialize bundles with.
+type Writer struct {
+	w              io.Writer
+	includeDigests bool
+}
+
+// NewWriter returns a new Writer which writes bundles as gzipped tarballs to
+// w.
+func NewWriter(w io.Writer) *Writer {
+	return &Writer{w: w}
+}
+
+// IncludeDigests sets whether the digests of the data and policy files should
+// be included in the manifest.
+func (w *Writer) IncludeDigests(includeDigests bool) *Writer {
+	w.includeDigests = includeDigests
+	return w
+}
+
+// Write serializes the Bundle and writes it to w. The output only depends on
+// the contents of the bundle: files are written in a fixed order and file
+// modification times are not recorded.
+func (w *Writer) Write(bundle Bundle) error {
+	gw := gzip.NewWriter(w.w)
 	tw := tar.NewWriter(gw)
 
 	var buf bytes.Buffer
 
-	if err := json.NewEncoder(&buf).Encode(bundle.Data); err != nil {
+	file := dataFile
+	var value interface{} = bundle.Data
+
+	if bundle.Type() == DeltaBundleType {
+		file = patchFile
+		value = bundle.Patch
+	}
+
+	if err := json.NewEncoder(&buf).Encode(value); err != nil {
 		return err
 	}
 
-	if err := archive.WriteFile(tw, "data.json", buf.Bytes()); err != nil {
+	files := []FileDigest{{Path: digestPath(file), Digest: digest(buf.Bytes())}}
+
+	if err := archive.WriteFile(tw, file, buf.Bytes()); err != nil {
 		return err
 	}
 
-	for _, module := range bundle.Modules {
+	modules := make([]ModuleFile, len(bundle.Modules))
+	copy(modules, bundle.Modules)
+
+	sort.Slice(modules, func(i, j int) bool {
+		return modules[i].Path < modules[j].Path
+	})
+
+	for _, module := range modules {
 		if err := archive.WriteFile(tw, module.Path, module.Raw); err != nil {
 			return err
 		}
+		files = append(files, FileDigest{Path: digestPath(module.Path), Digest: digest(module.Raw)})
+	}
+
+	if w.includeDigests {
+		bundle.Manifest.Files = files
 	}
 
 	if err := writeManifest(tw, bundle); err != nil {
@@ -300,6 +533,11 @@ func Write(w io.Writer, bundle Bundle) error {
 	return gw.Close()
 }
 
+// Write serializes the Bundle and writes it to w.
+func Write(w io.Writer, bundle Bundle) error {
+	return NewWriter(w).Write(bundle)
+}
+
 func writeManifest(tw *tar.Writer, bundle Bundle) error {
 
 	var buf bytes.Buffer
@@ -330,6 +568,9 @@ func (b Bundle) Equal(other Bundle) bool {
 	if !reflect.DeepEqual(b.Data, other.Data) {
 		return false
 	}
+	if !reflect.DeepEqual(b.Patch, other.Patch) {
+		return false
+	}
 	if len(b.Modules) != len(other.Modules) {
 		return false
 	}
@@ -407,6 +648,49 @@ func RootPathsOverlap(pathA string, pathB string) bool {
 	return true
 }
 
+// RootContains returns true if the path is equal to the root or is nested
+// under the root. Paths are compared segment by segment, e.g., the root "a/b"
+// contains "a/b/c" but not "a/bc". The empty root contains all paths.
+func RootContains(root string, path string) bool {
+	root = strings.Trim(root, "/")
+	path = strings.Trim(path, "/")
+	return root == "" || path == root || strings.HasPrefix(path, root+"/")
+}
+
+// CheckRootsOverlap returns an error if the roots of any two of the bundles
+// overlap. Callers that load several bundles without activating them (e.g.,
+// for testing) use CheckRootsOverlap to reject conflicting bundles.
+func CheckRootsOverlap(bundles map[string]*Bundle) error {
+
+	names := make([]string, 0, len(bundles))
+	for name := range bundles {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+
+	for i := range names {
+		for j := i + 1; j < len(names); j++ {
+			for _, a := range bundleRoots(bundles[names[i]]) {
+				for _, b := range bundleRoots(bundles[names[j]]) {
+					if RootPathsOverlap(a, b) {
+						return fmt.Errorf("bundles %q and %q have overlapping roots: %q and %q", names[i], names[j], a, b)
+					}
+				}
+			}
+		}
+	}
+
+	return nil
+}
+
+func bundleRoots(b *Bundle) []string {
+	if b.Manifest.Roots == nil {
+		return []string{""}
+	}
+	return *b.Manifest.Roots
+}
+
 func insertValue(b *Bundle, path string, value interface{}) error {
 
 	// Remove leading / and . characters from the directory path. If the bundle
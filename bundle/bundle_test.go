@@ -274,6 +274,242 @@ func TestRoundtrip(t *testing.T) {
 
 }
 
+func TestWriteReproducible(t *testing.T) {
+
+	modules := []ModuleFile{
+		{
+			Path:   "/a/a.rego",
+			Parsed: ast.MustParseModule(`package a`),
+			Raw:    []byte(`package a`),
+		},
+		{
+			Path:   "/b/b.rego",
+			Parsed: ast.MustParseModule(`package b`),
+			Raw:    []byte(`package b`),
+		},
+	}
+
+	write := func(modules []ModuleFile) []byte {
+		t.Helper()
+		var buf bytes.Buffer
+		b := Bundle{
+			Data:     map[string]interface{}{"x": json.Number("1"), "y": "z"},
+			Modules:  modules,
+			Manifest: Manifest{Revision: "abc"},
+		}
+		if err := NewWriter(&buf).IncludeDigests(true).Write(b); err != nil {
+			t.Fatal(err)
+		}
+		return buf.Bytes()
+	}
+
+	a := write(modules)
+	b := write([]ModuleFile{modules[1], modules[0]})
+
+	if !bytes.Equal(a, b) {
+		t.Fatal("Expected identical bundles")
+	}
+
+	result, err := NewReader(bytes.NewBuffer(a)).Read()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(result.Manifest.Files) != 3 || result.Manifest.Files[0].Path != "/data.json" || result.Manifest.Files[1].Path != "/a/a.rego" {
+		t.Fatalf("Unexpected file digests: %v", result.Manifest.Files)
+	}
+}
+
+func TestReadDigests(t *testing.T) {
+
+	manifest := func(files ...[2]string) string {
+		var m Manifest
+		for _, f := range files {
+			m.Files = append(m.Files, FileDigest{Path: f[0], Digest: digest([]byte(f[1]))})
+		}
+		bs, _ := json.Marshal(m)
+		return string(bs)
+	}
+
+	tests := []struct {
+		note  string
+		files [][2]string
+		err   string
+	}{
+		{
+			note: "ok",
+			files: [][2]string{
+				{"/a.rego", `package a`},
+				{"/data.json", `{"x": 1}`},
+				{"/.manifest", manifest([2]string{"/a.rego", `package a`}, [2]string{"data.json", `{"x": 1}`})},
+			},
+		},
+		{
+			note: "modified",
+			files: [][2]string{
+				{"/a.rego", `package a`},
+				{"/.manifest", manifest([2]string{"/a.rego", `package b`})},
+			},
+			err: "bundle file '/a.rego' does not match manifest digest",
+		},
+		{
+			note: "not listed",
+			files: [][2]string{
+				{"/a.rego", `package a`},
+				{"/b.rego", `package b`},
+				{"/.manifest", manifest([2]string{"/a.rego", `package a`})},
+			},
+			err: "bundle file '/b.rego' is not listed in manifest",
+		},
+		{
+			note: "missing",
+			files: [][2]string{
+				{"/a.rego", `package a`},
+				{"/.manifest", manifest([2]string{"/a.rego", `package a`}, [2]string{"/b.rego", `package b`})},
+			},
+			err: "bundle file '/b.rego' listed in manifest is missing",
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.note, func(t *testing.T) {
+			buf := archive.MustWriteTarGz(tc.files)
+			_, err := NewReader(buf).Read()
+			if tc.err == "" && err != nil {
+				t.Fatal("Unexpected error:", err)
+			} else if tc.err != "" && (err == nil || err.Error() != tc.err) {
+				t.Fatalf("Expected error %q but got: %v", tc.err, err)
+			}
+		})
+	}
+}
+
+func TestReadDependencies(t *testing.T) {
+
+	lib := []ModuleFile{
+		{Path: "/strings.rego", Raw: []byte(`package lib.strings`)},
+		{Path: "/sets.rego", Raw: []byte(`package lib.sets`)},
+	}
+
+	manifest := func(deps ...Dependency) string {
+		bs, _ := json.Marshal(Manifest{Dependencies: deps})
+		return string(bs)
+	}
+
+	dep := Dependency{Name: "lib", Revision: "v1", Digest: DependencyDigest(lib)}
+
+	tests := []struct {
+		note  string
+		files [][2]string
+		err   string
+	}{
+		{
+			note: "ok",
+			files: [][2]string{
+				{"/a.rego", `package a`},
+				{"/deps/lib/sets.rego", `package lib.sets`},
+				{"/deps/lib/strings.rego", `package lib.strings`},
+				{"/.manifest", manifest(dep)},
+			},
+		},
+		{
+			note: "modified",
+			files: [][2]string{
+				{"/deps/lib/sets.rego", `package lib.sets`},
+				{"/deps/lib/strings.rego", `package lib.strings.x`},
+				{"/.manifest", manifest(dep)},
+			},
+			err: "bundle dependency 'lib' does not match manifest digest",
+		},
+		{
+			note: "missing",
+			files: [][2]string{
+				{"/deps/lib/sets.rego", `package lib.sets`},
+				{"/.manifest", manifest(dep)},
+			},
+			err: "bundle dependency 'lib' does not match manifest digest",
+		},
+		{
+			note: "moved",
+			files: [][2]string{
+				{"/deps/lib/sets.rego", `package lib.sets`},
+				{"/deps/lib/x/strings.rego", `package lib.strings`},
+				{"/.manifest", manifest(dep)},
+			},
+			err: "bundle dependency 'lib' does not match manifest digest",
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.note, func(t *testing.T) {
+			buf := archive.MustWriteTarGz(tc.files)
+			b, err := NewReader(buf).Read()
+			if tc.err == "" && err != nil {
+				t.Fatal("Unexpected error:", err)
+			} else if tc.err != "" && (err == nil || err.Error() != tc.err) {
+				t.Fatalf("Expected error %q but got: %v", tc.err, err)
+			} else if tc.err == "" && (len(b.Manifest.Dependencies) != 1 || b.Manifest.Dependencies[0] != dep) {
+				t.Fatalf("Unexpected dependencies: %v", b.Manifest.Dependencies)
+			}
+		})
+	}
+}
+
+func TestReadDeltaBundle(t *testing.T) {
+
+	files := [][2]string{
+		{"/patch.json", `{"data": [{"op": "upsert", "path": "/a/b", "value": 1}, {"op": "remove", "path": "/a/c"}]}`},
+		{"/.manifest", `{"revision": "d1"}`},
+	}
+
+	b, err := NewReader(archive.MustWriteTarGz(files)).Read()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if b.Type() != DeltaBundleType || len(b.Patch.Data) != 2 || b.Patch.Data[1].Op != "remove" {
+		t.Fatalf("Unexpected delta bundle: %+v", b)
+	}
+
+	var buf bytes.Buffer
+
+	if err := Write(&buf, b); err != nil {
+		t.Fatal(err)
+	}
+
+	b2, err := NewReader(&buf).Read()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !b2.Equal(b) {
+		t.Fatal("Exp:", b, "\n\nGot:", b2)
+	}
+
+	for _, tc := range []struct {
+		files [][2]string
+		err   string
+	}{
+		{
+			files: [][2]string{{"/patch.json", `{"data": []}`}, {"/a.rego", `package a`}},
+			err:   "delta bundle must only contain patch.json and the manifest",
+		},
+		{
+			files: [][2]string{{"/patch.json", `{"data": [{"op": "move", "path": "/a"}]}`}},
+			err:   "delta bundle has invalid patch operation: move",
+		},
+		{
+			files: [][2]string{{"/patch.json", `{"data": [{"op": "add", "path": "a"}]}`}},
+			err:   "delta bundle has invalid patch path: a",
+		},
+	} {
+		_, err := NewReader(archive.MustWriteTarGz(tc.files)).Read()
+		if err == nil || err.Error() != tc.err {
+			t.Errorf("Expected error %q but got: %v", tc.err, err)
+		}
+	}
+}
+
 func TestRootPathsOverlap(t *testing.T) {
 	cases := []struct {
 		note     string
@@ -301,6 +537,68 @@ func TestRootPathsOverlap(t *testing.T) {
 	}
 }
 
+func TestRootContains(t *testing.T) {
+	cases := []struct {
+		note     string
+		root     string
+		path     string
+		expected bool
+	}{
+		{"empty root", "", "a/b", true},
+		{"equal", "a/b", "a/b", true},
+		{"nested", "a/b", "a/b/c", true},
+		{"partial segment", "a/b", "a/bc", false},
+		{"parent", "a/b", "a", false},
+		{"slashes", "/a/b/", "/a/b/c", true},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.note, func(t *testing.T) {
+			if actual := RootContains(tc.root, tc.path); actual != tc.expected {
+				t.Errorf("Expected %t, got %t", tc.expected, actual)
+			}
+		})
+	}
+}
+
+func TestCheckRootsOverlap(t *testing.T) {
+	cases := []struct {
+		note  string
+		roots map[string]*[]string
+		err   string
+	}{
+		{
+			note:  "disjoint",
+			roots: map[string]*[]string{"b1": {"a/b"}, "b2": {"a/bc", "x"}},
+		},
+		{
+			note:  "nested",
+			roots: map[string]*[]string{"b1": {"a/b"}, "b2": {"x", "a/b/c"}},
+			err:   `bundles "b1" and "b2" have overlapping roots: "a/b" and "a/b/c"`,
+		},
+		{
+			note:  "default roots",
+			roots: map[string]*[]string{"b1": nil, "b2": {"x"}},
+			err:   `bundles "b1" and "b2" have overlapping roots: "" and "x"`,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.note, func(t *testing.T) {
+			bundles := map[string]*Bundle{}
+			for name, roots := range tc.roots {
+				bundles[name] = &Bundle{Manifest: Manifest{Roots: roots}}
+			}
+			err := CheckRootsOverlap(bundles)
+			if tc.err == "" && err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			} else if tc.err != "" && (err == nil || err.Error() != tc.err) {
+				t.Fatalf("Expected error %q but got: %v", tc.err, err)
+			}
+		})
+	}
+}
+
 func TestParsedModules(t *testing.T) {
 	cases := []struct {
 		note            string
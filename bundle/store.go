@@ -187,11 +187,24 @@ func Deactivate(opts *DeactivateOpts) error {
 
 func activateBundles(opts *ActivateOpts) error {
 
+	// Delta bundles are applied on top of the active bundles so they are not
+	// erased.
+	snapshots := map[string]*Bundle{}
+	deltas := map[string]*Bundle{}
+
+	for name, b := range opts.Bundles {
+		if b.Type() == DeltaBundleType {
+			deltas[name] = b
+		} else {
+			snapshots[name] = b
+		}
+	}
+
 	// Build collections of bundle names, modules, and roots to erase
 	erase := map[string]struct{}{}
 	names := map[string]struct{}{}
 
-	for name, b := range opts.Bundles {
+	for name, b := range snapshots {
 		names[name] = struct{}{}
 
 		if roots, err := ReadBundleRootsFromStore(opts.Ctx, opts.Store, opts.Txn, name); err == nil {
@@ -210,7 +223,7 @@ func activateBundles(opts *ActivateOpts) error {
 
 	// Before changing anything make sure the roots don't collide with any
 	// other bundles that already are activated or other bundles being activated.
-	err := hasRootsOverlap(opts.Ctx, opts.Store, opts.Txn, opts.Bundles)
+	err := hasRootsOverlap(opts.Ctx, opts.Store, opts.Txn, snapshots)
 	if err != nil {
 		return err
 	}
@@ -222,7 +235,7 @@ func activateBundles(opts *ActivateOpts) error {
 		return err
 	}
 
-	for _, b := range opts.Bundles {
+	for _, b := range snapshots {
 		// Write data from each new bundle into the store. Only write under the
 		// roots contained in their manifest. This should be done *before* the
 		// policies so that path conflict checks can occur.
@@ -231,6 +244,21 @@ func activateBundles(opts *ActivateOpts) error {
 		}
 	}
 
+	for name, b := range deltas {
+		// Delta bundles keep the roots of the active bundle.
+		roots, err := ReadBundleRootsFromStore(opts.Ctx, opts.Store, opts.Txn, name)
+		if err != nil {
+			if storage.IsNotFound(err) {
+				return fmt.Errorf("delta bundle '%v' requires an active snapshot bundle", name)
+			}
+			return err
+		}
+		if err := applyPatch(opts.Ctx, opts.Store, opts.Txn, roots, b.Patch); err != nil {
+			return err
+		}
+		b.Manifest.Roots = &roots
+	}
+
 	// Write and compile the modules all at once to avoid having to re-do work.
 	remainingAndExtra := make(map[string]*ast.Module)
 	for name, mod := range remaining {
@@ -240,7 +268,7 @@ func activateBundles(opts *ActivateOpts) error {
 		remainingAndExtra[name] = mod
 	}
 
-	err = writeModules(opts.Ctx, opts.Store, opts.Txn, opts.Compiler, opts.Metrics, opts.Bundles, remainingAndExtra, opts.legacy)
+	err = writeModules(opts.Ctx, opts.Store, opts.Txn, opts.Compiler, opts.Metrics, snapshots, remainingAndExtra, opts.legacy)
 	if err != nil {
 		return err
 	}
@@ -261,6 +289,61 @@ func activateBundles(opts *ActivateOpts) error {
 	return nil
 }
 
+// applyPatch applies the patch operations of a delta bundle. The operations
+// may only modify data under the roots of the bundle.
+func applyPatch(ctx context.Context, store storage.Store, txn storage.Transaction, roots []string, patch Patch) error {
+
+	for _, op := range patch.Data {
+
+		path, ok := storage.ParsePathEscaped(op.Path)
+		if !ok || len(path) == 0 {
+			return fmt.Errorf("delta bundle has invalid patch path: %v", op.Path)
+		}
+
+		if !pathUnderRoots(path, roots) {
+			return fmt.Errorf("delta bundle patch path '%v' is outside of the bundle roots %v", op.Path, roots)
+		}
+
+		var value interface{} = op.Value
+		if err := util.RoundTrip(&value); err != nil {
+			return err
+		}
+
+		var err error
+
+		switch op.Op {
+		case "add":
+			err = store.Write(ctx, txn, storage.AddOp, path, value)
+		case "remove":
+			err = store.Write(ctx, txn, storage.RemoveOp, path, nil)
+		case "replace":
+			err = store.Write(ctx, txn, storage.ReplaceOp, path, value)
+		case "upsert":
+			if err = storage.MakeDir(ctx, store, txn, path[:len(path)-1]); err == nil {
+				err = store.Write(ctx, txn, storage.AddOp, path, value)
+			}
+		default:
+			err = fmt.Errorf("delta bundle has invalid patch operation: %v", op.Op)
+		}
+
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func pathUnderRoots(path storage.Path, roots []string) bool {
+	p := strings.Join(path, "/")
+	for _, root := range roots {
+		if RootContains(root, p) {
+			return true
+		}
+	}
+	return false
+}
+
 // erase bundles by name and roots. This will clear all policies and data at its roots and remove its
 // manifest from storage.
 func eraseBundles(ctx context.Context, store storage.Store, txn storage.Transaction, names map[string]struct{}, roots map[string]struct{}) (map[string]*ast.Module, error) {
@@ -328,7 +411,7 @@ func erasePolicies(ctx context.Context, store storage.Store, txn storage.Transac
 		}
 		deleted := false
 		for root := range roots {
-			if strings.HasPrefix(path, root) {
+			if RootContains(root, path) {
 				if err := store.DeletePolicy(ctx, txn, id); err != nil {
 					return nil, err
 				}
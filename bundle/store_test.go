@@ -378,6 +378,116 @@ func TestBundleLifecycle(t *testing.T) {
 	mockStore.AssertValid(t)
 }
 
+func TestDeltaBundleLifecycle(t *testing.T) {
+	ctx := context.Background()
+	mockStore := mock.New()
+
+	mod := "package a\np = data.a.b"
+
+	activate := func(bundles map[string]*Bundle) (*ast.Compiler, error) {
+		t.Helper()
+		compiler := ast.NewCompiler()
+		txn := storage.NewTransactionOrDie(ctx, mockStore, storage.WriteParams)
+		err := Activate(&ActivateOpts{
+			Ctx:      ctx,
+			Store:    mockStore,
+			Txn:      txn,
+			Compiler: compiler,
+			Metrics:  metrics.New(),
+			Bundles:  bundles,
+		})
+		if err != nil {
+			mockStore.Abort(ctx, txn)
+			return nil, err
+		}
+		return compiler, mockStore.Commit(ctx, txn)
+	}
+
+	delta := func(rev string, ops ...PatchOperation) map[string]*Bundle {
+		b := &Bundle{
+			Manifest: Manifest{Revision: rev},
+			Patch:    Patch{Data: ops},
+		}
+		b.Manifest.Init()
+		return map[string]*Bundle{"bundle1": b}
+	}
+
+	if _, err := activate(delta("d0", PatchOperation{Op: "add", Path: "/a/b", Value: 1})); err == nil || err.Error() != "delta bundle 'bundle1' requires an active snapshot bundle" {
+		t.Fatalf("Expected error for delta without snapshot but got: %v", err)
+	}
+
+	_, err := activate(map[string]*Bundle{
+		"bundle1": {
+			Manifest: Manifest{Revision: "s1", Roots: &[]string{"a"}},
+			Data: map[string]interface{}{
+				"a": map[string]interface{}{"b": "foo", "x": "bar"},
+			},
+			Modules: []ModuleFile{
+				{
+					Path:   "a/policy.rego",
+					Raw:    []byte(mod),
+					Parsed: ast.MustParseModule(mod),
+				},
+			},
+		},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	compiler, err := activate(delta("d1",
+		PatchOperation{Op: "replace", Path: "/a/b", Value: "baz"},
+		PatchOperation{Op: "remove", Path: "/a/x"},
+		PatchOperation{Op: "upsert", Path: "/a/c/d", Value: []interface{}{1, 2}},
+	))
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	if _, ok := compiler.Modules["bundle1/a/policy.rego"]; !ok {
+		t.Fatalf("expected module from snapshot bundle to remain compiled")
+	}
+
+	txn := storage.NewTransactionOrDie(ctx, mockStore)
+
+	actual, err := mockStore.Read(ctx, txn, storage.MustParsePath("/"))
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	expectedRaw := `
+{
+	"a": {
+		"b": "baz",
+		"c": {"d": [1, 2]}
+	},
+	"system": {
+		"bundles": {
+			"bundle1": {
+				"manifest": {
+					"revision": "d1",
+					"roots": ["a"]
+				}
+			}
+		}
+	}
+}
+`
+	expected := loadExpectedSortedResult(expectedRaw)
+	if !reflect.DeepEqual(expected, actual) {
+		t.Errorf("expected %v, got %v", expectedRaw, string(util.MustMarshalJSON(actual)))
+	}
+
+	mockStore.Abort(ctx, txn)
+
+	_, err = activate(delta("d2", PatchOperation{Op: "add", Path: "/other", Value: 1}))
+	if err == nil || !strings.Contains(err.Error(), "outside of the bundle roots") {
+		t.Fatalf("Expected error for patch outside of roots but got: %v", err)
+	}
+
+	mockStore.AssertValid(t)
+}
+
 func TestEraseData(t *testing.T) {
 	ctx := context.Background()
 	cases := []struct {
@@ -8,10 +8,23 @@ import (
 	"context"
 	"fmt"
 	"os"
+	"strings"
 
 	"github.com/spf13/cobra"
 
+	"github.com/open-policy-agent/opa/ast"
+	"github.com/open-policy-agent/opa/bundle"
+	"github.com/open-policy-agent/opa/internal/lock"
+	"github.com/open-policy-agent/opa/internal/merge"
+	"github.com/open-policy-agent/opa/internal/stdlib"
+	"github.com/open-policy-agent/opa/loader"
 	"github.com/open-policy-agent/opa/rego"
+	"github.com/open-policy-agent/opa/util"
+)
+
+const (
+	buildTargetWasm   = "wasm"
+	buildTargetBundle = "bundle"
 )
 
 var buildParams = struct {
@@ -20,7 +33,15 @@ var buildParams = struct {
 	dataPaths   repeatedStringFlag
 	ignore      []string
 	bundlePaths repeatedStringFlag
-}{}
+	target      *util.EnumFlag
+	revision    string
+	digests     bool
+	deps        string
+	updateLock  bool
+	stdlib      bool
+}{
+	target: util.NewEnumFlag(buildTargetWasm, []string{buildTargetWasm, buildTargetBundle}),
+}
 
 var buildCommand = &cobra.Command{
 	Use:   "build <query>",
@@ -30,8 +51,50 @@ var buildCommand = &cobra.Command{
 The 'build' command takes a policy query as input and compiles it into an
 executable that can be loaded into an enforcement point and evaluated with
 input values. By default, the build command produces WebAssembly (WASM)
-executables.`,
+executables.
+
+If the '--target=bundle' option is specified, the build command does not take a
+query. Instead, it writes the policies and data loaded from the '--data' and
+'--bundle' paths into a bundle (a gzipped tarball.) Bundles are reproducible:
+given identical inputs, the build command produces byte-identical bundles. If
+the '--digests' option is specified, the bundle manifest lists the SHA-256
+digests of the files in the bundle. OPA verifies the digests when it reads the
+bundle.
+
+If the '--dependencies' option is specified, the build command includes the
+policies of the library bundles listed in the dependency manifest in the bundle.
+The manifest lists the name of each library and the path of the library bundle
+(a directory or tarball) relative to the manifest:
+
+	dependencies:
+	  - name: lib
+	    path: ../lib
+
+The revisions and digests of the libraries are pinned in a lockfile next to the
+manifest (e.g., deps.yaml is locked by deps.lock.) If the lockfile does not
+exist, it is created. Otherwise, the build fails if a library does not match
+the lockfile. Use '--update-lock' to pin the current libraries. The library
+policies are written to the deps/<name> directory of the bundle and the bundle
+manifest lists the library digests. OPA verifies the library policies when it
+reads the bundle.
+
+If the '--stdlib' option is specified, the build command includes the standard
+library of Rego helpers that ships with OPA. The helpers are defined under
+data.stdlib (e.g., data.stdlib.strings, data.stdlib.sets, data.stdlib.k8s, and
+data.stdlib.rbac.) The standard library is versioned alongside OPA. If the
+target is 'bundle', the library policies are written to the deps/stdlib
+directory of the bundle and the bundle manifest lists the OPA version and the
+library digest.`,
 	PreRunE: func(Cmd *cobra.Command, args []string) error {
+		if buildParams.target.String() == buildTargetBundle {
+			if len(args) > 0 {
+				return fmt.Errorf("bundle target does not take a query argument")
+			}
+			if !Cmd.Flags().Changed("output") {
+				buildParams.outputFile = "bundle.tar.gz"
+			}
+			return nil
+		}
 		if len(args) == 0 {
 			return fmt.Errorf("specify query argument")
 		}
@@ -47,6 +110,10 @@ executables.`,
 
 func build(args []string) error {
 
+	if buildParams.target.String() == buildTargetBundle {
+		return buildBundle()
+	}
+
 	ctx := context.Background()
 
 	f := loaderFilter{
@@ -67,6 +134,16 @@ func build(args []string) error {
 		}
 	}
 
+	if buildParams.stdlib {
+		modules, err := stdlib.Modules()
+		if err != nil {
+			return err
+		}
+		for _, m := range modules {
+			regoArgs = append(regoArgs, rego.ParsedModule(m.Parsed))
+		}
+	}
+
 	if buildParams.debug {
 		regoArgs = append(regoArgs, rego.Dump(os.Stderr))
 	}
@@ -88,8 +165,158 @@ func build(args []string) error {
 	return err
 }
 
+// buildBundle writes the policies and data loaded from the data and bundle paths
+// into a bundle.
+func buildBundle() error {
+
+	f := loaderFilter{
+		Ignore: buildParams.ignore,
+	}
+
+	result := bundle.Bundle{
+		Manifest: bundle.Manifest{Revision: buildParams.revision},
+		Data:     map[string]interface{}{},
+	}
+
+	modules := map[string]*ast.Module{}
+
+	addModule := func(path string, raw []byte, parsed *ast.Module) error {
+		if _, ok := modules[path]; ok {
+			return fmt.Errorf("duplicate module path: %v", path)
+		}
+		modules[path] = parsed
+		result.Modules = append(result.Modules, bundle.ModuleFile{Path: path, Raw: raw, Parsed: parsed})
+		return nil
+	}
+
+	addData := func(data map[string]interface{}) error {
+		merged, ok := merge.InterfaceMaps(result.Data, data)
+		if !ok {
+			return fmt.Errorf("data conflict between loaded paths")
+		}
+		result.Data = merged
+		return nil
+	}
+
+	if buildParams.dataPaths.isFlagSet() {
+		loaded, err := loader.NewFileLoader().Filtered(buildParams.dataPaths.v, f.Apply)
+		if err != nil {
+			return err
+		}
+		for _, m := range loaded.Modules {
+			if err := addModule(m.Name, m.Raw, m.Parsed); err != nil {
+				return err
+			}
+		}
+		if err := addData(loaded.Documents); err != nil {
+			return err
+		}
+	}
+
+	if buildParams.bundlePaths.isFlagSet() {
+		for _, path := range buildParams.bundlePaths.v {
+			b, err := loader.NewFileLoader().AsBundle(path)
+			if err != nil {
+				return err
+			}
+			for _, m := range b.Modules {
+				if err := addModule(m.Path, m.Raw, m.Parsed); err != nil {
+					return err
+				}
+			}
+			if err := addData(b.Data); err != nil {
+				return err
+			}
+		}
+	}
+
+	if buildParams.deps != "" {
+		deps, err := resolveDependencies(buildParams.deps, buildParams.updateLock)
+		if err != nil {
+			return err
+		}
+		for _, dep := range deps {
+			for _, m := range dep.Modules {
+				if err := addModule(bundle.DependencyDir(dep.Dependency.Name)+"/"+strings.TrimLeft(m.Path, "/"), m.Raw, m.Parsed); err != nil {
+					return err
+				}
+			}
+			result.Manifest.Dependencies = append(result.Manifest.Dependencies, dep.Dependency)
+		}
+	}
+
+	if buildParams.stdlib {
+		lib, err := stdlib.Modules()
+		if err != nil {
+			return err
+		}
+		for _, m := range lib {
+			if err := addModule(bundle.DependencyDir(stdlib.Name)+m.Path, m.Raw, m.Parsed); err != nil {
+				return err
+			}
+		}
+		result.Manifest.Dependencies = append(result.Manifest.Dependencies, stdlib.Dependency(lib))
+	}
+
+	compiler := ast.NewCompiler()
+	if compiler.Compile(modules); compiler.Failed() {
+		return compiler.Errors
+	}
+
+	out, err := os.Create(buildParams.outputFile)
+	if err != nil {
+		return err
+	}
+
+	if err := bundle.NewWriter(out).IncludeDigests(buildParams.digests).Write(result); err != nil {
+		out.Close()
+		return err
+	}
+
+	return out.Close()
+}
+
+// resolveDependencies resolves the library bundles listed in the dependency
+// manifest at path and checks them against the lockfile. If the lockfile does
+// not exist or update is true, the lockfile is written.
+func resolveDependencies(path string, update bool) ([]*lock.Resolved, error) {
+
+	m, err := lock.ReadManifest(path)
+	if err != nil {
+		return nil, err
+	}
+
+	resolved, err := lock.Resolve(path, m)
+	if err != nil {
+		return nil, err
+	}
+
+	lockPath := lock.Path(path)
+
+	l, err := lock.ReadLock(lockPath)
+	if err != nil {
+		return nil, err
+	}
+
+	if l == nil || update {
+		return resolved, lock.WriteLock(lockPath, resolved)
+	}
+
+	if err := l.Verify(resolved); err != nil {
+		return nil, fmt.Errorf("%v: %v (hint: use --update-lock to pin the current dependencies)", lockPath, err)
+	}
+
+	return resolved, nil
+}
+
 func init() {
-	buildCommand.Flags().StringVarP(&buildParams.outputFile, "output", "o", "policy.wasm", "set the filename of the compiled policy")
+	buildCommand.Flags().StringVarP(&buildParams.outputFile, "output", "o", "policy.wasm", "set the filename of the compiled policy or bundle")
+	buildCommand.Flags().VarP(buildParams.target, "target", "t", "set the output type")
+	buildCommand.Flags().StringVarP(&buildParams.revision, "revision", "r", "", "set the bundle revision (bundle target only)")
+	buildCommand.Flags().BoolVarP(&buildParams.digests, "digests", "", false, "include file digests in the bundle manifest (bundle target only)")
+	buildCommand.Flags().StringVarP(&buildParams.deps, "dependencies", "", "", "set the path of the dependency manifest listing library bundles to include (bundle target only)")
+	buildCommand.Flags().BoolVarP(&buildParams.updateLock, "update-lock", "", false, "update the dependency lockfile instead of verifying it (bundle target only)")
+	buildCommand.Flags().BoolVarP(&buildParams.stdlib, "stdlib", "", false, "include the standard library of Rego helpers")
 	buildCommand.Flags().BoolVarP(&buildParams.debug, "debug", "D", false, "enable debug output")
 	buildCommand.Flags().VarP(&buildParams.dataPaths, "data", "d", "set data file(s) or directory path(s)")
 	buildCommand.Flags().VarP(&buildParams.bundlePaths, "bundle", "b", "set bundle file(s) or directory path(s)")
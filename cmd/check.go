@@ -8,10 +8,13 @@ import (
 	"fmt"
 	"io"
 	"os"
+	"sort"
+	"strings"
 
 	"github.com/spf13/cobra"
 
 	"github.com/open-policy-agent/opa/ast"
+	"github.com/open-policy-agent/opa/internal/junit"
 	pr "github.com/open-policy-agent/opa/internal/presentation"
 	"github.com/open-policy-agent/opa/loader"
 	"github.com/open-policy-agent/opa/util"
@@ -22,15 +25,17 @@ var checkParams = struct {
 	errLimit   int
 	ignore     []string
 	bundleMode bool
+	strict     bool
 }{
 	format: util.NewEnumFlag(checkFormatPretty, []string{
-		checkFormatPretty, checkFormatJSON,
+		checkFormatPretty, checkFormatJSON, checkFormatJUnit,
 	}),
 }
 
 const (
 	checkFormatPretty = "pretty"
 	checkFormatJSON   = "json"
+	checkFormatJUnit  = "junit"
 )
 
 var checkCommand = &cobra.Command{
@@ -40,7 +45,11 @@ var checkCommand = &cobra.Command{
 
 If the 'check' command succeeds in parsing and compiling the source file(s), no output
 is produced. If the parsing or compiling fails, 'check' will output the errors
-and exit with a non-zero exit code.`,
+and exit with a non-zero exit code.
+
+If the '--format=junit' option is specified, 'check' outputs a JUnit XML report
+that contains a test case for each source file. Files that contain errors are
+reported as failures. The report is produced even if there are no errors.`,
 
 	PreRunE: func(Cmd *cobra.Command, args []string) error {
 		if len(args) == 0 {
@@ -61,7 +70,7 @@ func checkModules(args []string) int {
 		for _, path := range args {
 			b, err := loader.NewFileLoader().AsBundle(path)
 			if err != nil {
-				outputErrors(err)
+				outputErrors(nil, err)
 				return 1
 			}
 			for name, mod := range b.ParsedModules(path) {
@@ -75,7 +84,7 @@ func checkModules(args []string) int {
 
 		result, err := loader.NewFileLoader().Filtered(args, f.Apply)
 		if err != nil {
-			outputErrors(err)
+			outputErrors(nil, err)
 			return 1
 		}
 
@@ -84,20 +93,34 @@ func checkModules(args []string) int {
 		}
 	}
 
-	compiler := ast.NewCompiler().SetErrorLimit(checkParams.errLimit)
+	compiler := ast.NewCompiler().
+		SetErrorLimit(checkParams.errLimit).
+		WithStrict(checkParams.strict)
 
 	compiler.Compile(modules)
 
+	files := make([]string, 0, len(modules))
+	for name := range modules {
+		files = append(files, name)
+	}
+
+	sort.Strings(files)
+
 	if !compiler.Failed() {
+		if checkParams.format.String() == checkFormatJUnit {
+			outputErrors(files, nil)
+		}
 		return 0
 	}
 
-	outputErrors(compiler.Errors)
+	outputErrors(files, compiler.Errors)
 
 	return 1
 }
 
-func outputErrors(err error) {
+// outputErrors prints the errors in the format selected by the user. The files
+// are only used by the JUnit format which reports a test case for each file.
+func outputErrors(files []string, err error) {
 	switch checkParams.format.String() {
 	case checkFormatJSON:
 		result := pr.Output{
@@ -113,15 +136,82 @@ func outputErrors(err error) {
 		if err != nil {
 			fmt.Fprintln(os.Stderr, err.Error())
 		}
+	case checkFormatJUnit:
+		if err := checkJUnit(os.Stdout, files, err); err != nil {
+			fmt.Fprintln(os.Stderr, err.Error())
+		}
 	default:
 		fmt.Fprintln(os.Stdout, err)
 	}
 }
 
+func checkJUnit(w io.Writer, files []string, err error) error {
+
+	byFile := map[string][]pr.OutputError{}
+	var other []pr.OutputError
+
+	for _, e := range pr.NewOutputErrors(err) {
+		if loc, ok := e.Location.(*ast.Location); ok && loc != nil && loc.File != "" {
+			if _, ok := byFile[loc.File]; !ok {
+				files = appendMissing(files, loc.File)
+			}
+			byFile[loc.File] = append(byFile[loc.File], e)
+		} else {
+			other = append(other, e)
+		}
+	}
+
+	report := &junit.Testsuites{Name: "opa"}
+	suite := report.Suite("check")
+
+	for _, file := range files {
+		tc := junit.NewTestcase("check", file, 0)
+		tc.File = file
+		tc.Failure = checkJUnitFailure(byFile[file])
+		suite.Add(tc)
+	}
+
+	if len(other) > 0 {
+		tc := junit.NewTestcase("check", "check", 0)
+		tc.Failure = checkJUnitFailure(other)
+		suite.Add(tc)
+	}
+
+	return junit.Write(w, report)
+}
+
+func checkJUnitFailure(errs []pr.OutputError) *junit.Result {
+
+	if len(errs) == 0 {
+		return nil
+	}
+
+	lines := make([]string, len(errs))
+	for i := range errs {
+		lines[i] = errs[i].Error()
+	}
+
+	return &junit.Result{
+		Message: errs[0].Message,
+		Type:    errs[0].Code,
+		Text:    strings.Join(lines, "\n"),
+	}
+}
+
+func appendMissing(files []string, file string) []string {
+	for _, f := range files {
+		if f == file {
+			return files
+		}
+	}
+	return append(files, file)
+}
+
 func init() {
 	setMaxErrors(checkCommand.Flags(), &checkParams.errLimit)
 	setIgnore(checkCommand.Flags(), &checkParams.ignore)
 	checkCommand.Flags().VarP(checkParams.format, "format", "f", "set output format")
 	checkCommand.Flags().BoolVarP(&checkParams.bundleMode, "bundle", "b", false, "load paths as bundle files or root directories")
+	checkCommand.Flags().BoolVarP(&checkParams.strict, "strict", "S", false, "enable compiler strict mode")
 	RootCommand.AddCommand(checkCommand)
 }
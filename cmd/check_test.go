@@ -0,0 +1,66 @@
+// Copyright 2020 The OPA Authors.  All rights reserved.
+// Use of this source code is governed by an Apache2
+// license that can be found in the LICENSE file.
+
+package cmd
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/open-policy-agent/opa/ast"
+)
+
+func TestCheckJUnit(t *testing.T) {
+
+	modules := map[string]string{
+		"a.rego": "package a\n\np { true }",
+		"b.rego": "package b\n\np { q }\nr { s }",
+	}
+
+	parsed := map[string]*ast.Module{}
+	for name, src := range modules {
+		mod, err := ast.ParseModule(name, src)
+		if err != nil {
+			t.Fatal(err)
+		}
+		parsed[name] = mod
+	}
+
+	compiler := ast.NewCompiler()
+	compiler.Compile(parsed)
+
+	if !compiler.Failed() {
+		t.Fatal("Expected compile errors")
+	}
+
+	var buf bytes.Buffer
+
+	if err := checkJUnit(&buf, []string{"a.rego", "b.rego"}, compiler.Errors); err != nil {
+		t.Fatal(err)
+	}
+
+	result := buf.String()
+
+	for _, exp := range []string{
+		`<testsuite name="check" tests="2" failures="1" errors="0" time="0.000">`,
+		`<testcase name="a.rego" classname="check" time="0.000" file="a.rego"></testcase>`,
+		`<failure message="var q is unsafe" type="rego_unsafe_var_error">b.rego:3: rego_unsafe_var_error: var q is unsafe&#xA;b.rego:4: rego_unsafe_var_error: var s is unsafe</failure>`,
+	} {
+		if !strings.Contains(result, exp) {
+			t.Errorf("Expected output to contain:\n\n%v\n\nGot:\n\n%v", exp, result)
+		}
+	}
+
+	buf.Reset()
+
+	if err := checkJUnit(&buf, nil, fmt.Errorf("load failed")); err != nil {
+		t.Fatal(err)
+	}
+
+	if exp := `<failure message="load failed">load failed</failure>`; !strings.Contains(buf.String(), exp) {
+		t.Errorf("Expected output to contain:\n\n%v\n\nGot:\n\n%v", exp, buf.String())
+	}
+}
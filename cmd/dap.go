@@ -0,0 +1,42 @@
+// Copyright 2020 The OPA Authors.  All rights reserved.
+// Use of this source code is governed by an Apache2
+// license that can be found in the LICENSE file.
+
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+
+	"github.com/open-policy-agent/opa/internal/dap"
+)
+
+var dapCommand = &cobra.Command{
+	Use:   "dap",
+	Short: "Start a Rego debug adapter",
+	Long: `Start a Rego debug adapter.
+
+The 'dap' command starts a server that implements the Debug Adapter Protocol
+over stdin and stdout. Editors can use the server to set breakpoints in policy
+files, step through the evaluation of a query, and inspect variable bindings.
+
+The launch request accepts the following arguments:
+
+	query:        the query to evaluate (required)
+	data:         policy and data files or directories to load
+	input:        path of the input document
+	stopOnEntry:  pause before the first expression is evaluated`,
+
+	Run: func(cmd *cobra.Command, args []string) {
+		if err := dap.New(os.Stdin, os.Stdout).Serve(); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+	},
+}
+
+func init() {
+	RootCommand.AddCommand(dapCommand)
+}
@@ -42,6 +42,15 @@ func init() {
 	depsCommand := &cobra.Command{
 		Use:   "deps <query>",
 		Short: "Analyze Rego query dependencies",
+		Long: `Analyze Rego query dependencies.
+
+The 'deps' command reports the base and virtual documents that the query
+depends on, the import aliases declared by the modules that define the virtual
+documents, and the fields of the input document that the query refers to. The
+types of the input fields are inferred from the built-in functions the fields
+are passed to and the constants they are compared with. The JSON output
+describes the input fields with a JSON schema-like document, e.g., to validate
+requests before they are sent to OPA.`,
 		PreRunE: func(cmd *cobra.Command, args []string) error {
 			if len(args) != 1 {
 				return errors.New("specify exactly one query argument")
@@ -118,15 +127,59 @@ func deps(args []string, params depsCommandParams) error {
 		return err
 	}
 
+	input, err := dependencies.Input(compiler, query)
+	if err != nil {
+		return err
+	}
+
 	output := presentation.DepAnalysisOutput{
 		Base:    brs,
 		Virtual: vrs,
+		Aliases: depsAliases(compiler, modules, vrs),
+		Input:   input,
 	}
 
 	switch params.format.String() {
 	case depsFormatJSON:
-		return presentation.JSON(os.Stdout, output)
+		return output.JSON(os.Stdout)
 	default:
 		return output.Pretty(os.Stdout)
 	}
 }
+
+// depsAliases returns the import aliases declared by modules that define the
+// virtual documents in vrs. The compiler discards imports once references have
+// been resolved so the aliases are read from the parsed modules.
+func depsAliases(compiler *ast.Compiler, modules map[string]*ast.Module, vrs []ast.Ref) []presentation.DepAnalysisAlias {
+
+	var result []presentation.DepAnalysisAlias
+
+	for name, mod := range compiler.Modules {
+		if !definesAny(mod, vrs) {
+			continue
+		}
+		for _, imp := range modules[name].Imports {
+			if len(imp.Alias) > 0 {
+				result = append(result, presentation.DepAnalysisAlias{
+					Module: name,
+					Alias:  imp.Alias,
+					Path:   imp.Path.Value.(ast.Ref),
+				})
+			}
+		}
+	}
+
+	return result
+}
+
+func definesAny(mod *ast.Module, refs []ast.Ref) bool {
+	for _, rule := range mod.Rules {
+		path := rule.Path()
+		for _, ref := range refs {
+			if path.HasPrefix(ref) || ref.HasPrefix(path) {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,172 @@
+// Copyright 2019 The OPA Authors.  All rights reserved.
+// Use of this source code is governed by an Apache2
+// license that can be found in the LICENSE file.
+
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+
+	"github.com/spf13/cobra"
+
+	"github.com/open-policy-agent/opa/diff"
+	"github.com/open-policy-agent/opa/internal/presentation"
+	"github.com/open-policy-agent/opa/util"
+)
+
+type diffCommandParams struct {
+	current   string
+	candidate string
+	inputPath string
+	format    *util.EnumFlag
+	fail      bool
+}
+
+const (
+	diffFormatPretty = "pretty"
+	diffFormatJSON   = "json"
+)
+
+func init() {
+
+	var params diffCommandParams
+
+	params.format = util.NewEnumFlag(diffFormatPretty, []string{
+		diffFormatPretty, diffFormatJSON,
+	})
+
+	diffCommand := &cobra.Command{
+		Use:   "diff <query>",
+		Short: "Compare decisions between two bundles",
+		Long: `Compare decisions between two bundles.
+
+The diff command evaluates the query against the current and candidate bundles
+for each of the recorded inputs and reports the decisions that changed.
+
+The inputs file contains either a JSON array of inputs or a stream of JSON
+values. Decision log events in the stream are recognized and their input is
+used:
+
+	$ opa diff --current v1.tar.gz --candidate v2.tar.gz --inputs decisions.log 'data.authz.allow'
+`,
+		PreRunE: func(cmd *cobra.Command, args []string) error {
+			if len(args) != 1 {
+				return errors.New("specify exactly one query argument")
+			}
+			if params.current == "" || params.candidate == "" || params.inputPath == "" {
+				return errors.New("specify --current, --candidate, and --inputs")
+			}
+			return nil
+		},
+		Run: func(cmd *cobra.Command, args []string) {
+			changed, err := diffDecisions(args[0], params, os.Stdout)
+			if err != nil {
+				fmt.Fprintln(os.Stderr, err)
+				os.Exit(2)
+			}
+			if params.fail && changed {
+				os.Exit(1)
+			}
+		},
+	}
+
+	diffCommand.Flags().StringVarP(&params.current, "current", "", "", "set current bundle file or directory path")
+	diffCommand.Flags().StringVarP(&params.candidate, "candidate", "", "", "set candidate bundle file or directory path")
+	diffCommand.Flags().StringVarP(&params.inputPath, "inputs", "i", "", "set recorded inputs file path")
+	diffCommand.Flags().VarP(params.format, "format", "f", "set output format")
+	diffCommand.Flags().BoolVarP(&params.fail, "fail", "", false, "exits with non-zero exit code if any decision changed")
+
+	RootCommand.AddCommand(diffCommand)
+}
+
+func diffDecisions(query string, params diffCommandParams, w io.Writer) (bool, error) {
+
+	current, err := diff.LoadSnapshot(params.current)
+	if err != nil {
+		return false, err
+	}
+
+	candidate, err := diff.LoadSnapshot(params.candidate)
+	if err != nil {
+		return false, err
+	}
+
+	bs, err := ioutil.ReadFile(params.inputPath)
+	if err != nil {
+		return false, err
+	}
+
+	inputs, err := readRecordedInputs(bs)
+	if err != nil {
+		return false, err
+	}
+
+	report, err := diff.Decisions(context.Background(), query, current, candidate, inputs)
+	if err != nil {
+		return false, err
+	}
+
+	changed := len(report.Changes) > 0
+
+	switch params.format.String() {
+	case diffFormatJSON:
+		return changed, presentation.JSON(w, report)
+	default:
+		fmt.Fprintf(w, "%d of %d decisions changed\n", len(report.Changes), report.Total)
+		for _, c := range report.Changes {
+			fmt.Fprintf(w, "\ninput #%d: %s\n", c.Index, util.MustMarshalJSON(c.Input))
+			fmt.Fprintf(w, "  current:   %v\n", prettyDecision(c.Current))
+			fmt.Fprintf(w, "  candidate: %v\n", prettyDecision(c.Candidate))
+		}
+		return changed, nil
+	}
+}
+
+func prettyDecision(d diff.Decision) string {
+	if d.Error != "" {
+		return "error: " + d.Error
+	} else if d.Result == nil {
+		return "undefined"
+	}
+	return string(util.MustMarshalJSON(*d.Result))
+}
+
+// readRecordedInputs returns the inputs contained in bs. The inputs may be
+// provided as a JSON array or as a stream of JSON values. Decision log events
+// contained in the stream are replaced by their input.
+func readRecordedInputs(bs []byte) ([]interface{}, error) {
+
+	trimmed := bytes.TrimSpace(bs)
+
+	if len(trimmed) > 0 && trimmed[0] == '[' {
+		var inputs []interface{}
+		if err := util.UnmarshalJSON(trimmed, &inputs); err != nil {
+			return nil, err
+		}
+		return inputs, nil
+	}
+
+	var inputs []interface{}
+	decoder := util.NewJSONDecoder(bytes.NewReader(trimmed))
+
+	for {
+		var x interface{}
+		if err := decoder.Decode(&x); err == io.EOF {
+			return inputs, nil
+		} else if err != nil {
+			return nil, err
+		}
+		if event, ok := x.(map[string]interface{}); ok {
+			if _, ok := event["decision_id"]; ok {
+				x = event["input"]
+			}
+		}
+		inputs = append(inputs, x)
+	}
+}
@@ -0,0 +1,127 @@
+// Copyright 2020 The OPA Authors.  All rights reserved.
+// Use of this source code is governed by an Apache2
+// license that can be found in the LICENSE file.
+
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+
+	"github.com/spf13/cobra"
+
+	"github.com/open-policy-agent/opa/diff"
+	"github.com/open-policy-agent/opa/internal/presentation"
+	"github.com/open-policy-agent/opa/util"
+)
+
+type diffInputsCommandParams struct {
+	bundlePath string
+	format     *util.EnumFlag
+}
+
+func init() {
+
+	var params diffInputsCommandParams
+
+	params.format = util.NewEnumFlag(diffFormatPretty, []string{
+		diffFormatPretty, diffFormatJSON,
+	})
+
+	diffInputsCommand := &cobra.Command{
+		Use:   "diff-inputs <query> <input file a> <input file b>",
+		Short: "Explain why the decisions for two inputs differ",
+		Long: `Explain why the decisions for two inputs differ.
+
+The diff-inputs command evaluates the query against the bundle for both inputs
+and reports the decisions and the expressions whose outcomes differ between
+the inputs. This helps answer questions like "why was request a allowed but
+request b denied?":
+
+	$ opa diff-inputs --bundle bundle/ 'data.authz.allow' a.json b.json
+
+Each expression is reported as true (it was true at least once), false (it was
+evaluated but never true), or not evaluated (e.g., because an earlier
+expression in the same rule was false). The input files may contain JSON or
+YAML.
+`,
+		PreRunE: func(cmd *cobra.Command, args []string) error {
+			if len(args) != 3 {
+				return errors.New("specify a query and two input files")
+			}
+			if params.bundlePath == "" {
+				return errors.New("specify --bundle")
+			}
+			return nil
+		},
+		Run: func(cmd *cobra.Command, args []string) {
+			if err := diffInputs(args[0], args[1], args[2], params, os.Stdout); err != nil {
+				fmt.Fprintln(os.Stderr, err)
+				os.Exit(2)
+			}
+		},
+	}
+
+	diffInputsCommand.Flags().StringVarP(&params.bundlePath, "bundle", "b", "", "set bundle file or directory path")
+	diffInputsCommand.Flags().VarP(params.format, "format", "f", "set output format")
+
+	RootCommand.AddCommand(diffInputsCommand)
+}
+
+func diffInputs(query, pathA, pathB string, params diffInputsCommandParams, w io.Writer) error {
+
+	snapshot, err := diff.LoadSnapshot(params.bundlePath)
+	if err != nil {
+		return err
+	}
+
+	a, err := readInputFile(pathA)
+	if err != nil {
+		return err
+	}
+
+	b, err := readInputFile(pathB)
+	if err != nil {
+		return err
+	}
+
+	explanation, err := diff.Explain(context.Background(), query, snapshot, a, b)
+	if err != nil {
+		return err
+	}
+
+	switch params.format.String() {
+	case diffFormatJSON:
+		return presentation.JSON(w, explanation)
+	default:
+		fmt.Fprintf(w, "a: %v\n", prettyDecision(explanation.A))
+		fmt.Fprintf(w, "b: %v\n", prettyDecision(explanation.B))
+		fmt.Fprintf(w, "\n%d expressions differ\n", len(explanation.Changes))
+		for _, c := range explanation.Changes {
+			fmt.Fprintf(w, "\n%v:%v: %v\n", c.Location.File, c.Location.Row, c.Expr)
+			fmt.Fprintf(w, "  a: %v\n", c.A)
+			fmt.Fprintf(w, "  b: %v\n", c.B)
+		}
+		return nil
+	}
+}
+
+func readInputFile(path string) (interface{}, error) {
+
+	bs, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var x interface{}
+
+	if err := util.Unmarshal(bs, &x); err != nil {
+		return nil, fmt.Errorf("%v: %v", path, err)
+	}
+
+	return x, nil
+}
@@ -0,0 +1,62 @@
+// Copyright 2020 The OPA Authors.  All rights reserved.
+// Use of this source code is governed by an Apache2
+// license that can be found in the LICENSE file.
+
+package cmd
+
+import (
+	"bytes"
+	"path/filepath"
+	"testing"
+
+	"github.com/open-policy-agent/opa/util"
+	"github.com/open-policy-agent/opa/util/test"
+)
+
+func TestDiffInputs(t *testing.T) {
+
+	files := map[string]string{
+		"bundle/authz.rego": `package authz
+
+default allow = false
+
+allow {
+	input.method == "GET"
+	input.user == "alice"
+}`,
+		"a.json": `{"method": "GET", "user": "alice"}`,
+		"b.yaml": `{method: GET, user: bob}`,
+	}
+
+	test.WithTempFS(files, func(rootDir string) {
+		params := diffInputsCommandParams{
+			bundlePath: filepath.Join(rootDir, "bundle"),
+			format:     util.NewEnumFlag(diffFormatPretty, []string{diffFormatPretty, diffFormatJSON}),
+		}
+
+		var buf bytes.Buffer
+
+		err := diffInputs("data.authz.allow", filepath.Join(rootDir, "a.json"), filepath.Join(rootDir, "b.yaml"), params, &buf)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		exp := `a: true
+b: false
+
+2 expressions differ
+
+/authz.rego:3: default allow = false
+  a: not evaluated
+  b: true
+
+/authz.rego:7: input.user == "alice"
+  a: true
+  b: false
+`
+
+		if buf.String() != exp {
+			t.Fatalf("Expected:\n\n%v\n\nGot:\n\n%v", exp, buf.String())
+		}
+	})
+}
@@ -0,0 +1,102 @@
+// Copyright 2019 The OPA Authors.  All rights reserved.
+// Use of this source code is governed by an Apache2
+// license that can be found in the LICENSE file.
+
+package cmd
+
+import (
+	"bytes"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/open-policy-agent/opa/util"
+	"github.com/open-policy-agent/opa/util/test"
+)
+
+func TestReadRecordedInputs(t *testing.T) {
+
+	tests := []struct {
+		note     string
+		input    string
+		expected string
+		wantErr  bool
+	}{
+		{"array", `[{"a": 1}, 2]`, `[{"a": 1}, 2]`, false},
+		{"stream", "{\"a\": 1}\n2\n", `[{"a": 1}, 2]`, false},
+		{"decision logs", "{\"decision_id\": \"1\", \"input\": {\"a\": 1}}\n{\"decision_id\": \"2\"}", `[{"a": 1}, null]`, false},
+		{"empty", ``, `null`, false},
+		{"bad json", `{"a":`, ``, true},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.note, func(t *testing.T) {
+			result, err := readRecordedInputs([]byte(tc.input))
+			if tc.wantErr {
+				if err == nil {
+					t.Fatal("Expected error")
+				}
+				return
+			} else if err != nil {
+				t.Fatal(err)
+			}
+			var exp []interface{}
+			if err := util.UnmarshalJSON([]byte(tc.expected), &exp); err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(result, exp) {
+				t.Fatalf("Expected %v but got %v", exp, result)
+			}
+		})
+	}
+}
+
+func TestDiffDecisions(t *testing.T) {
+
+	files := map[string]string{
+		"v1/authz.rego": `package authz
+allow { input.user = "alice" }`,
+		"v2/authz.rego": `package authz
+allow { users := ["alice", "bob"]; input.user = users[_] }`,
+		"inputs.json": `[{"user": "alice"}, {"user": "bob"}]`,
+	}
+
+	test.WithTempFS(files, func(rootDir string) {
+		params := diffCommandParams{
+			current:   filepath.Join(rootDir, "v1"),
+			candidate: filepath.Join(rootDir, "v2"),
+			inputPath: filepath.Join(rootDir, "inputs.json"),
+			format:    util.NewEnumFlag(diffFormatPretty, []string{diffFormatPretty, diffFormatJSON}),
+		}
+
+		var buf bytes.Buffer
+
+		changed, err := diffDecisions("data.authz.allow", params, &buf)
+		if err != nil {
+			t.Fatal(err)
+		} else if !changed {
+			t.Fatal("Expected changed decisions")
+		}
+
+		exp := `1 of 2 decisions changed
+
+input #1: {"user":"bob"}
+  current:   undefined
+  candidate: true
+`
+
+		if buf.String() != exp {
+			t.Fatalf("Expected:\n%v\n\nGot:\n%v", exp, buf.String())
+		}
+
+		params.candidate = params.current
+		buf.Reset()
+
+		if changed, err := diffDecisions("data.authz.allow", params, &buf); err != nil || changed {
+			t.Fatalf("Expected no changes but got %v (err: %v)", changed, err)
+		} else if !strings.HasPrefix(buf.String(), "0 of 2") {
+			t.Fatalf("Unexpected output: %v", buf.String())
+		}
+	})
+}
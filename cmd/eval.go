@@ -9,9 +9,11 @@ import (
 	"fmt"
 	"io"
 	"io/ioutil"
+	"math/rand"
 	"os"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/pkg/errors"
 	"github.com/spf13/cobra"
@@ -42,6 +44,8 @@ type evalCommandParams struct {
 	stdin             bool
 	stdinInput        bool
 	explain           *util.EnumFlag
+	traceFormat       *util.EnumFlag
+	traceOutput       string
 	metrics           bool
 	instrument        bool
 	ignore            []string
@@ -54,6 +58,8 @@ type evalCommandParams struct {
 	fail              bool
 	failDefined       bool
 	bundlePaths       repeatedStringFlag
+	time              string
+	seed              intFlag
 }
 
 func newEvalCommandParams() evalCommandParams {
@@ -66,6 +72,11 @@ func newEvalCommandParams() evalCommandParams {
 			evalSourceOutput,
 		}),
 		explain: newExplainFlag([]string{explainModeOff, explainModeFull, explainModeNotes, explainModeFails}),
+		traceFormat: util.NewEnumFlag(evalPrettyTrace, []string{
+			evalPrettyTrace,
+			evalJSONTrace,
+			evalFoldedTrace,
+		}),
 	}
 }
 
@@ -76,6 +87,10 @@ const (
 	evalPrettyOutput   = "pretty"
 	evalSourceOutput   = "source"
 
+	evalPrettyTrace = "pretty"
+	evalJSONTrace   = "json"
+	evalFoldedTrace = "folded"
+
 	// number of profile results to return by default
 	defaultProfileLimit = 10
 
@@ -158,6 +173,24 @@ Set the output format with the --format flag.
 	--format=values    : output line separated JSON arrays containing expression values
 	--format=bindings  : output line separated JSON objects containing variable bindings
 	--format=pretty    : output query results in a human-readable format
+
+Trace Formats
+-------------
+
+Set the format of the --explain trace with the --trace-format flag. By default
+the trace is included in the output. If --trace-format is set to json or
+folded, the trace is written to stderr instead, or to the file set with the
+--trace-output flag. Either flag implies --explain=full unless another
+explain mode is set.
+
+	--trace-format=pretty  : output the trace as a human-readable tree
+	--trace-format=json    : output the trace as line separated JSON events
+	--trace-format=folded  : output the trace as folded stacks for flame graph tools
+
+For example, to render a flame graph of the rules evaluated by a query:
+
+	$ opa eval --data policy.rego --trace-format folded --trace-output trace.folded 'data.authz.allow'
+	$ flamegraph.pl trace.folded > trace.svg
 `,
 
 		PreRunE: func(cmd *cobra.Command, args []string) error {
@@ -192,6 +225,11 @@ Set the output format with the --format flag.
 			if params.instrument {
 				params.metrics = true
 			}
+			if params.traceFormat.String() != evalPrettyTrace || params.traceOutput != "" {
+				if params.explain.String() == explainModeOff {
+					params.explain.Set(explainModeFull)
+				}
+			}
 			return nil
 		},
 		Run: func(cmd *cobra.Command, args []string) {
@@ -231,8 +269,12 @@ Set the output format with the --format flag.
 	evalCommand.Flags().VarP(&params.prettyLimit, "pretty-limit", "", "set limit after which pretty output gets truncated")
 	evalCommand.Flags().BoolVarP(&params.fail, "fail", "", false, "exits with non-zero exit code on undefined/empty result and errors")
 	evalCommand.Flags().BoolVarP(&params.failDefined, "fail-defined", "", false, "exits with non-zero exit code on defined/non-empty result and errors")
+	evalCommand.Flags().StringVarP(&params.time, "time", "", "", "set time returned by time.now_ns (RFC3339 format)")
+	evalCommand.Flags().VarP(&params.seed, "seed", "", "set seed for built-in functions that generate random values")
 	setIgnore(evalCommand.Flags(), &params.ignore)
 	setExplain(evalCommand.Flags(), params.explain)
+	evalCommand.Flags().VarP(params.traceFormat, "trace-format", "", "set explanation trace format")
+	evalCommand.Flags().StringVarP(&params.traceOutput, "trace-output", "", "", "write explanation trace to file")
 	RootCommand.AddCommand(evalCommand)
 }
 
@@ -308,6 +350,18 @@ func eval(args []string, params evalCommandParams, w io.Writer) (bool, error) {
 		evalArgs = append(evalArgs, rego.EvalRuleIndexing(false))
 	}
 
+	if params.time != "" {
+		t, err := time.Parse(time.RFC3339Nano, params.time)
+		if err != nil {
+			return false, fmt.Errorf("invalid time: %v", err)
+		}
+		evalArgs = append(evalArgs, rego.EvalTime(t))
+	}
+
+	if params.seed.isSet {
+		evalArgs = append(evalArgs, rego.EvalSeed(rand.New(rand.NewSource(int64(params.seed.v)))))
+	}
+
 	var m metrics.Metrics
 
 	if params.metrics {
@@ -376,6 +430,13 @@ func eval(args []string, params evalCommandParams, w io.Writer) (bool, error) {
 		result.Explanation = lineage.Fails(*tracer)
 	}
 
+	if params.traceFormat.String() != evalPrettyTrace || params.traceOutput != "" {
+		if err := writeTrace(params, result.Explanation); err != nil {
+			return false, err
+		}
+		result.Explanation = nil
+	}
+
 	if m != nil {
 		result.Metrics = m
 	}
@@ -422,6 +483,32 @@ func eval(args []string, params evalCommandParams, w io.Writer) (bool, error) {
 	}
 }
 
+// writeTrace writes the trace in the requested format to the trace output file
+// or stderr.
+func writeTrace(params evalCommandParams, trace []*topdown.Event) error {
+
+	w := io.Writer(os.Stderr)
+
+	if params.traceOutput != "" {
+		f, err := os.Create(params.traceOutput)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		w = f
+	}
+
+	switch params.traceFormat.String() {
+	case evalJSONTrace:
+		return topdown.JSONTrace(w, trace)
+	case evalFoldedTrace:
+		return topdown.FoldedTrace(w, trace)
+	default:
+		topdown.PrettyTrace(w, trace)
+		return nil
+	}
+}
+
 func getProfileSortOrder(sortOrder []string) []string {
 
 	// convert the sort order slice to a map for faster lookups
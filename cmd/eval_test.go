@@ -8,8 +8,10 @@ import (
 	"bufio"
 	"bytes"
 	"fmt"
+	"io/ioutil"
 	"path/filepath"
 	"reflect"
+	"strings"
 	"testing"
 
 	"github.com/open-policy-agent/opa/ast"
@@ -210,6 +212,40 @@ func TestEvalReturnsRegoError(t *testing.T) {
 	}
 }
 
+func TestEvalDeterministic(t *testing.T) {
+
+	params := newEvalCommandParams()
+	params.time = "2019-10-01T12:00:00Z"
+	if err := params.seed.Set("42"); err != nil {
+		t.Fatal(err)
+	}
+
+	query := `x := [time.now_ns(), rand.intn("a", 1000), uuid.rfc4122("b")]`
+
+	var outputs []string
+
+	for i := 0; i < 2; i++ {
+		buf := new(bytes.Buffer)
+		if _, err := eval([]string{query}, params, buf); err != nil {
+			t.Fatal(err)
+		}
+		outputs = append(outputs, buf.String())
+	}
+
+	if outputs[0] != outputs[1] {
+		t.Fatalf("Expected identical outputs but got:\n%v\n%v", outputs[0], outputs[1])
+	}
+
+	if !strings.Contains(outputs[0], "1569931200000000000") {
+		t.Fatalf("Expected fixed time in output but got: %v", outputs[0])
+	}
+
+	params.time = "yesterday"
+	if _, err := eval([]string{query}, params, new(bytes.Buffer)); err == nil {
+		t.Fatal("Expected error for invalid time")
+	}
+}
+
 func TestEvalWithBundleData(t *testing.T) {
 	files := map[string]string{
 		"x/x.rego":            "package x\np = 1",
@@ -443,3 +479,68 @@ func TestEvalDebugTraceJSONOutput(t *testing.T) {
 		}
 	}
 }
+
+func TestEvalTraceFormats(t *testing.T) {
+
+	files := map[string]string{
+		"policy.rego": `package x
+
+		p { q }
+		q { input.z == 1 }`,
+		"input.json": `{"z": 1}`,
+	}
+
+	test.WithTempFS(files, func(path string) {
+
+		params := newEvalCommandParams()
+		params.inputPath = filepath.Join(path, "input.json")
+		params.traceOutput = filepath.Join(path, "trace.folded")
+		if err := params.dataPaths.Set(filepath.Join(path, "policy.rego")); err != nil {
+			t.Fatal(err)
+		}
+		if err := params.traceFormat.Set(evalFoldedTrace); err != nil {
+			t.Fatal(err)
+		}
+		if err := params.explain.Set(explainModeFull); err != nil {
+			t.Fatal(err)
+		}
+
+		var buf bytes.Buffer
+
+		if _, err := eval([]string{"data.x.p"}, params, &buf); err != nil {
+			t.Fatal(err)
+		}
+
+		if strings.Contains(buf.String(), "explanation") {
+			t.Fatalf("Expected trace to be written to trace output but got: %v", buf.String())
+		}
+
+		bs, err := ioutil.ReadFile(params.traceOutput)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		exp := "data.x.p = _ 1\ndata.x.p = _;data.x.p 1\ndata.x.p = _;data.x.p;data.x.q 1\n"
+		if string(bs) != exp {
+			t.Fatalf("Expected folded trace:\n\n%v\nGot:\n\n%v", exp, string(bs))
+		}
+
+		if err := params.traceFormat.Set(evalJSONTrace); err != nil {
+			t.Fatal(err)
+		}
+
+		if _, err := eval([]string{"data.x.p"}, params, &buf); err != nil {
+			t.Fatal(err)
+		}
+
+		bs, err = ioutil.ReadFile(params.traceOutput)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		lines := strings.Split(strings.TrimSpace(string(bs)), "\n")
+		if len(lines) < 2 || !strings.HasPrefix(lines[0], `{"op":"enter","query_id":`) {
+			t.Fatalf("Expected JSON event stream but got:\n%s", bs)
+		}
+	})
+}
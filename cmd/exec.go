@@ -0,0 +1,120 @@
+// Copyright 2020 The OPA Authors.  All rights reserved.
+// Use of this source code is governed by an Apache2
+// license that can be found in the LICENSE file.
+
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/spf13/cobra"
+
+	"github.com/open-policy-agent/opa/internal/presentation"
+	"github.com/open-policy-agent/opa/runtime"
+	"github.com/open-policy-agent/opa/util"
+)
+
+type execCommandParams struct {
+	runtime runtime.Params
+	exec    runtime.ExecParams
+	fail    bool
+}
+
+func init() {
+
+	var params execCommandParams
+
+	params.runtime = runtime.NewParams()
+
+	logLevel := util.NewEnumFlag("error", []string{"debug", "info", "error"})
+	logFormat := util.NewEnumFlag("json", []string{"text", "json", "json-pretty"})
+
+	execCommand := &cobra.Command{
+		Use:   "exec <input file> [<input file> ...]",
+		Short: "Evaluate a decision once for each input and exit",
+		Long: `Evaluate a decision once for each input and exit.
+
+The exec command loads the OPA configuration (e.g., bundles and decision logs),
+waits for the configured bundles to be activated, evaluates the decision once
+for each of the input files, uploads the decision logs, and exits. This is
+useful for CI checks and batch jobs that should not run an OPA server:
+
+	$ opa exec --config-file config.yaml --decision authz/allow input.json
+
+The decision path is separated by slashes like Data API paths. If --decision is
+not specified, the default decision from the configuration is evaluated. The
+input files may contain JSON or YAML.
+
+The results are written to stdout as JSON. If --fail is specified, the command
+exits with a non-zero exit code if any decision is undefined or fails.
+`,
+		PreRunE: func(cmd *cobra.Command, args []string) error {
+			if len(args) == 0 {
+				return errors.New("specify at least one input file")
+			}
+			return nil
+		},
+		Run: func(cmd *cobra.Command, args []string) {
+			params.runtime.Logging = runtime.LoggingConfig{
+				Level:  logLevel.String(),
+				Format: logFormat.String(),
+			}
+			params.exec.Inputs = args
+
+			ok, err := execDecisions(context.Background(), params, os.Stdout)
+			if err != nil {
+				fmt.Fprintln(os.Stderr, "error:", err)
+				os.Exit(2)
+			}
+			if params.fail && !ok {
+				os.Exit(1)
+			}
+		},
+	}
+
+	execCommand.Flags().StringVarP(&params.runtime.ConfigFile, "config-file", "c", "", "set path of configuration file")
+	execCommand.Flags().StringArrayVar(&params.runtime.ConfigOverrides, "set", []string{}, "override config values on the command line (use commas to specify multiple values)")
+	execCommand.Flags().StringArrayVar(&params.runtime.ConfigOverrideFiles, "set-file", []string{}, "override config values with files on the command line (use commas to specify multiple values)")
+	execCommand.Flags().StringVarP(&params.exec.Decision, "decision", "", "", "set decision path to evaluate (e.g., authz/allow)")
+	execCommand.Flags().DurationVar(&params.exec.Timeout, "timeout", runtime.DefaultExecTimeout, "set maximum time to wait for bundles to be activated")
+	execCommand.Flags().BoolVarP(&params.fail, "fail", "", false, "exits with non-zero exit code on undefined or failed decisions")
+	execCommand.Flags().VarP(logLevel, "log-level", "l", "set log level")
+	execCommand.Flags().VarP(logFormat, "log-format", "", "set log format")
+
+	RootCommand.AddCommand(execCommand)
+}
+
+// execDecisions evaluates the decision for each input and writes the results.
+// The returned bool is false if any decision was undefined or failed. If the
+// decisions were made but the decision logs could not be uploaded, the results
+// are written and the error is returned.
+func execDecisions(ctx context.Context, params execCommandParams, w io.Writer) (bool, error) {
+
+	rt, err := runtime.NewRuntime(ctx, params.runtime)
+	if err != nil {
+		return false, err
+	}
+
+	results, execErr := rt.Exec(ctx, params.exec)
+	if results == nil {
+		return false, execErr
+	}
+
+	ok := true
+
+	for _, r := range results {
+		if r.Result == nil || r.Error != "" {
+			ok = false
+		}
+	}
+
+	if err := presentation.JSON(w, map[string]interface{}{"result": results}); err != nil {
+		return false, err
+	}
+
+	return ok, execErr
+}
@@ -0,0 +1,49 @@
+// Copyright 2020 The OPA Authors.  All rights reserved.
+// Use of this source code is governed by an Apache2
+// license that can be found in the LICENSE file.
+
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"path/filepath"
+	"testing"
+
+	"github.com/open-policy-agent/opa/runtime"
+	"github.com/open-policy-agent/opa/util"
+	"github.com/open-policy-agent/opa/util/test"
+)
+
+func TestExecDecisions(t *testing.T) {
+
+	files := map[string]string{
+		"config.yaml": `default_decision: /example/allow`,
+		"input.json":  `{"user": "alice"}`,
+	}
+
+	test.WithTempFS(files, func(root string) {
+
+		var params execCommandParams
+		params.runtime = runtime.NewParams()
+		params.runtime.ConfigFile = filepath.Join(root, "config.yaml")
+		params.exec.Inputs = []string{filepath.Join(root, "input.json")}
+
+		var buf bytes.Buffer
+
+		ok, err := execDecisions(context.Background(), params, &buf)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if ok {
+			t.Fatal("Expected undefined decision to be reported")
+		}
+
+		exp := util.MustUnmarshalJSON([]byte(`{"result": [{"path": "` + params.exec.Inputs[0] + `"}]}`))
+
+		if result := util.MustUnmarshalJSON(buf.Bytes()); util.Compare(result, exp) != 0 {
+			t.Fatalf("Expected %v but got %v", exp, result)
+		}
+	})
+}
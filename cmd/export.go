@@ -0,0 +1,159 @@
+// Copyright 2019 The OPA Authors.  All rights reserved.
+// Use of this source code is governed by an Apache2
+// license that can be found in the LICENSE file.
+
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/open-policy-agent/opa/server/types"
+	"github.com/open-policy-agent/opa/util"
+)
+
+type exportCommandParams struct {
+	addr       string
+	token      string
+	outputPath string
+	format     *util.EnumFlag
+}
+
+const (
+	exportFormatJSON = "json"
+	exportFormatTar  = "tar"
+)
+
+func init() {
+
+	var params exportCommandParams
+
+	params.format = util.NewEnumFlag(exportFormatJSON, []string{
+		exportFormatJSON, exportFormatTar,
+	})
+
+	exportCommand := &cobra.Command{
+		Use:   "export [<path>]",
+		Short: "Export data from a running OPA",
+		Long: `Export data from a running OPA.
+
+The export command downloads the base document at the path (or the entire data
+tree if the path is omitted) from the OPA server. Policies are not evaluated
+so the export only contains data that was pushed or loaded into OPA.
+
+The export is written as JSON by default. If --format=tar is specified, the
+export is written as a gzipped tarball in the bundle format:
+
+	$ opa export --format=tar --output=backup.tar.gz
+
+Exports can be restored with the import command.
+`,
+		PreRunE: func(cmd *cobra.Command, args []string) error {
+			if len(args) > 1 {
+				return errors.New("specify at most one path argument")
+			}
+			return nil
+		},
+		Run: func(cmd *cobra.Command, args []string) {
+
+			var path string
+			if len(args) > 0 {
+				path = args[0]
+			}
+
+			out := io.Writer(os.Stdout)
+
+			if params.outputPath != "" {
+				f, err := os.Create(params.outputPath)
+				if err != nil {
+					fmt.Fprintln(os.Stderr, err)
+					os.Exit(1)
+				}
+				defer f.Close()
+				out = f
+			}
+
+			if err := exportData(path, params, out); err != nil {
+				fmt.Fprintln(os.Stderr, err)
+				os.Exit(1)
+			}
+		},
+	}
+
+	setServerAddr(exportCommand.Flags(), &params.addr)
+	setServerToken(exportCommand.Flags(), &params.token)
+	exportCommand.Flags().StringVarP(&params.outputPath, "output", "o", "", "set output file path (default stdout)")
+	exportCommand.Flags().VarP(params.format, "format", "f", "set output format")
+
+	RootCommand.AddCommand(exportCommand)
+}
+
+func exportData(path string, params exportCommandParams, w io.Writer) error {
+
+	req, err := newServerRequest(http.MethodGet, params.addr, params.token, "/v1/export", path, nil)
+	if err != nil {
+		return err
+	}
+
+	if params.format.String() == exportFormatTar {
+		req.Header.Set("Accept", "application/gzip")
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return responseError(resp)
+	}
+
+	_, err = io.Copy(w, resp.Body)
+	return err
+}
+
+// newServerRequest returns a request for the API endpoint on the OPA server
+// at addr. The path is appended to the endpoint.
+func newServerRequest(method, addr, token, endpoint, path string, body io.Reader) (*http.Request, error) {
+
+	url := strings.TrimSuffix(addr, "/") + endpoint
+	if path = strings.Trim(path, "/"); path != "" {
+		url += "/" + path
+	}
+
+	req, err := http.NewRequest(method, url, body)
+	if err != nil {
+		return nil, err
+	}
+
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+
+	return req, nil
+}
+
+// responseError returns an error describing the unsuccessful response.
+func responseError(resp *http.Response) error {
+
+	bs, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+
+	var apiErr types.ErrorV1
+	if err := util.UnmarshalJSON(bs, &apiErr); err != nil || apiErr.Code == "" {
+		return fmt.Errorf("server responded with %v", resp.Status)
+	}
+
+	return fmt.Errorf("server responded with %v: %v", resp.Status, apiErr.Message)
+}
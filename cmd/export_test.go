@@ -0,0 +1,78 @@
+// Copyright 2019 The OPA Authors.  All rights reserved.
+// Use of this source code is governed by an Apache2
+// license that can be found in the LICENSE file.
+
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/open-policy-agent/opa/plugins"
+	"github.com/open-policy-agent/opa/server"
+	"github.com/open-policy-agent/opa/storage"
+	"github.com/open-policy-agent/opa/storage/inmem"
+	"github.com/open-policy-agent/opa/util"
+)
+
+func TestExportImport(t *testing.T) {
+
+	ctx := context.Background()
+	store := inmem.NewFromObject(map[string]interface{}{
+		"a": map[string]interface{}{"b": "x"},
+	})
+
+	m, err := plugins.New([]byte{}, "test", store)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := m.Start(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	s, err := server.New().WithStore(store).WithManager(m).Init(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ts := httptest.NewServer(s.Handler)
+	defer ts.Close()
+
+	for _, format := range []string{exportFormatJSON, exportFormatTar} {
+		t.Run(format, func(t *testing.T) {
+
+			exportParams := exportCommandParams{addr: ts.URL, format: util.NewEnumFlag(format, []string{format})}
+
+			var buf bytes.Buffer
+			if err := exportData("a", exportParams, &buf); err != nil {
+				t.Fatal(err)
+			}
+
+			importParams := importCommandParams{addr: ts.URL}
+			if err := importData("c/"+format, importParams, &buf); err != nil {
+				t.Fatal(err)
+			}
+
+			result, err := storage.ReadOne(ctx, store, storage.MustParsePath("/c/"+format))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			exp := map[string]interface{}{"b": "x"}
+			if !reflect.DeepEqual(result, exp) {
+				t.Fatalf("Expected %v but got %v", exp, result)
+			}
+		})
+	}
+
+	exportParams := exportCommandParams{addr: ts.URL, format: util.NewEnumFlag(exportFormatJSON, []string{exportFormatJSON})}
+	err = exportData("missing", exportParams, &bytes.Buffer{})
+	if err == nil || !strings.Contains(err.Error(), "404 Not Found: storage_not_found_error") {
+		t.Fatalf("Expected not found error but got: %v", err)
+	}
+}
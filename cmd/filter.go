@@ -0,0 +1,207 @@
+// Copyright 2020 The OPA Authors.  All rights reserved.
+// Use of this source code is governed by an Apache2
+// license that can be found in the LICENSE file.
+
+package cmd
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/spf13/cobra"
+
+	pr "github.com/open-policy-agent/opa/internal/presentation"
+	"github.com/open-policy-agent/opa/internal/runtime"
+	"github.com/open-policy-agent/opa/rego"
+	"github.com/open-policy-agent/opa/util"
+)
+
+// maxFilterLineBytes is the maximum size of an input line read by the filter
+// command.
+const maxFilterLineBytes = 64 * 1024 * 1024
+
+type filterCommandParams struct {
+	dataPaths    repeatedStringFlag
+	bundlePaths  repeatedStringFlag
+	imports      repeatedStringFlag
+	pkg          string
+	ignore       []string
+	outputFormat *util.EnumFlag
+	fail         bool
+}
+
+func init() {
+
+	params := filterCommandParams{
+		outputFormat: util.NewEnumFlag(evalJSONOutput, []string{evalJSONOutput, evalValuesOutput}),
+	}
+
+	filterCommand := &cobra.Command{
+		Use:   "filter <query>",
+		Short: "Evaluate a query for each input read from stdin",
+		Long: `Evaluate a query for each input read from stdin.
+
+The filter command reads newline-delimited JSON values from stdin, evaluates
+the query once for each value (as the input document), and writes the results
+to stdout. The policy and data are loaded once so the command can be used in
+shell pipelines and stream processors:
+
+	$ cat requests.ndjson | opa filter -b bundle/ 'data.authz.allow'
+
+The --format flag controls the output:
+
+	json    one JSON object per input line containing the result set (or
+	        the errors). Undefined results produce an empty object.
+
+	values  one JSON array per result containing the expression values.
+	        Undefined results produce no output and errors are written to
+	        stderr.
+
+Each line of output is flushed as soon as it is written. Empty input lines are
+skipped. Lines that cannot be parsed are reported as errors and the command
+continues with the next line.
+`,
+		PreRunE: func(cmd *cobra.Command, args []string) error {
+			if len(args) != 1 {
+				return errors.New("specify exactly one query argument")
+			}
+			return nil
+		},
+		Run: func(cmd *cobra.Command, args []string) {
+			ok, err := filter(args[0], params, os.Stdin, os.Stdout, os.Stderr)
+			if err != nil {
+				fmt.Fprintln(os.Stderr, "error:", err)
+				os.Exit(2)
+			}
+			if params.fail && !ok {
+				os.Exit(1)
+			}
+		},
+	}
+
+	filterCommand.Flags().VarP(&params.dataPaths, "data", "d", "set data file(s) or directory path(s)")
+	filterCommand.Flags().VarP(&params.bundlePaths, "bundle", "b", "set bundle file(s) or directory path(s)")
+	filterCommand.Flags().VarP(&params.imports, "import", "", "set query import(s)")
+	filterCommand.Flags().StringVarP(&params.pkg, "package", "", "", "set query package")
+	filterCommand.Flags().VarP(params.outputFormat, "format", "f", "set output format")
+	filterCommand.Flags().BoolVarP(&params.fail, "fail", "", false, "exits with non-zero exit code if any result is undefined or fails")
+	setIgnore(filterCommand.Flags(), &params.ignore)
+
+	RootCommand.AddCommand(filterCommand)
+}
+
+// filter evaluates the query for each line read from r. The returned bool is
+// false if any input produced an undefined result or an error. Errors are only
+// returned if the query cannot be prepared or the input or output fails.
+func filter(query string, params filterCommandParams, r io.Reader, w io.Writer, errw io.Writer) (bool, error) {
+
+	ctx := context.Background()
+
+	info, err := runtime.Term(runtime.Params{})
+	if err != nil {
+		return false, err
+	}
+
+	regoArgs := []func(*rego.Rego){rego.Query(query), rego.Runtime(info)}
+
+	if len(params.imports.v) > 0 {
+		regoArgs = append(regoArgs, rego.Imports(params.imports.v))
+	}
+
+	if params.pkg != "" {
+		regoArgs = append(regoArgs, rego.Package(params.pkg))
+	}
+
+	if len(params.dataPaths.v) > 0 {
+		f := loaderFilter{
+			Ignore: params.ignore,
+		}
+		regoArgs = append(regoArgs, rego.Load(params.dataPaths.v, f.Apply))
+	}
+
+	for _, path := range params.bundlePaths.v {
+		regoArgs = append(regoArgs, rego.LoadBundle(path))
+	}
+
+	pq, err := rego.New(regoArgs...).PrepareForEval(ctx)
+	if err != nil {
+		return false, err
+	}
+
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(nil, maxFilterLineBytes)
+
+	bw := bufio.NewWriter(w)
+	ok := true
+
+	for scanner.Scan() {
+		line := bytes.TrimSpace(scanner.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+
+		var output pr.Output
+		var input interface{}
+
+		if err := util.UnmarshalJSON(line, &input); err != nil {
+			output.Errors = pr.NewOutputErrors(fmt.Errorf("unable to parse input: %v", err))
+		} else {
+			output.Result, err = pq.Eval(ctx, rego.EvalInput(input))
+			output.Errors = pr.NewOutputErrors(err)
+		}
+
+		if len(output.Errors) > 0 || len(output.Result) == 0 {
+			ok = false
+		}
+
+		if err := writeFilterOutput(bw, errw, params.outputFormat.String(), output); err != nil {
+			return false, err
+		}
+
+		if err := bw.Flush(); err != nil {
+			return false, err
+		}
+	}
+
+	return ok, scanner.Err()
+}
+
+func writeFilterOutput(w io.Writer, errw io.Writer, format string, output pr.Output) error {
+
+	switch format {
+	case evalValuesOutput:
+		if len(output.Errors) > 0 {
+			for _, err := range output.Errors {
+				fmt.Fprintln(errw, err)
+			}
+			return nil
+		}
+		for _, result := range output.Result {
+			values := make([]interface{}, len(result.Expressions))
+			for i := range values {
+				values[i] = result.Expressions[i].Value
+			}
+			if err := writeJSONLine(w, values); err != nil {
+				return err
+			}
+		}
+		return nil
+	default:
+		return writeJSONLine(w, output)
+	}
+}
+
+func writeJSONLine(w io.Writer, x interface{}) error {
+	bs, err := json.Marshal(x)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(w, "%s\n", bs)
+	return err
+}
@@ -0,0 +1,109 @@
+// Copyright 2020 The OPA Authors.  All rights reserved.
+// Use of this source code is governed by an Apache2
+// license that can be found in the LICENSE file.
+
+package cmd
+
+import (
+	"bytes"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/open-policy-agent/opa/util"
+	"github.com/open-policy-agent/opa/util/test"
+)
+
+func TestFilter(t *testing.T) {
+
+	files := map[string]string{
+		"authz.rego": `package authz
+
+		allow { input.user = data.admins[_] }
+
+		deny[msg] { input.user = "mallory"; msg := "blocked" }`,
+		"data.json": `{"admins": ["alice"]}`,
+	}
+
+	stdin := `{"user": "alice"}
+
+{"user": "bob"}
+{"user": "mallory"}
+{"user":
+`
+
+	tests := []struct {
+		note   string
+		query  string
+		format string
+		stdout string
+		stderr string
+		ok     bool
+	}{
+		{
+			note:  "json",
+			query: "data.authz.allow",
+			stdout: `{"result":[{"expressions":[{"value":true,"text":"data.authz.allow","location":{"row":1,"col":1}}]}]}
+{}
+{}
+{"errors":[{"message":"unable to parse input: unexpected EOF"}]}
+`,
+		},
+		{
+			note:   "values",
+			query:  "data.authz.deny[x]",
+			format: "values",
+			stdout: `["blocked"]
+`,
+			stderr: "unable to parse input",
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.note, func(t *testing.T) {
+			test.WithTempFS(files, func(root string) {
+
+				params := filterCommandParams{
+					outputFormat: util.NewEnumFlag(evalJSONOutput, []string{evalJSONOutput, evalValuesOutput}),
+				}
+				params.dataPaths = newrepeatedStringFlag([]string{root})
+
+				if tc.format != "" {
+					params.outputFormat.Set(tc.format)
+				}
+
+				var stdout, stderr bytes.Buffer
+
+				ok, err := filter(tc.query, params, strings.NewReader(stdin), &stdout, &stderr)
+				if err != nil {
+					t.Fatal(err)
+				}
+
+				if ok != tc.ok {
+					t.Fatalf("Expected ok to be %v", tc.ok)
+				}
+
+				if stdout.String() != tc.stdout {
+					t.Fatalf("Expected stdout:\n\n%v\n\nGot:\n\n%v", tc.stdout, stdout.String())
+				}
+
+				if !strings.Contains(stderr.String(), tc.stderr) {
+					t.Fatalf("Expected stderr to contain %q but got: %v", tc.stderr, stderr.String())
+				}
+			})
+		})
+	}
+}
+
+func TestFilterPrepareError(t *testing.T) {
+	params := filterCommandParams{
+		outputFormat: util.NewEnumFlag(evalJSONOutput, []string{evalJSONOutput, evalValuesOutput}),
+	}
+	params.dataPaths = newrepeatedStringFlag([]string{filepath.Join("does", "not", "exist")})
+
+	var stdout, stderr bytes.Buffer
+
+	if _, err := filter("data.x", params, strings.NewReader(""), &stdout, &stderr); err == nil {
+		t.Fatal("Expected error")
+	}
+}
@@ -33,3 +33,11 @@ func newExplainFlag(modes []string) *util.EnumFlag {
 func setExplain(fs *pflag.FlagSet, explain *util.EnumFlag) {
 	fs.VarP(explain, "explain", "", "enable query explanations")
 }
+
+func setServerAddr(fs *pflag.FlagSet, addr *string) {
+	fs.StringVarP(addr, "addr", "a", "http://localhost:8181", "set address of the OPA server")
+}
+
+func setServerToken(fs *pflag.FlagSet, token *string) {
+	fs.StringVarP(token, "token", "", "", "set bearer token to authenticate with the OPA server")
+}
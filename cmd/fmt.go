@@ -17,13 +17,24 @@ import (
 
 	"github.com/open-policy-agent/opa/format"
 	fileurl "github.com/open-policy-agent/opa/internal/file/url"
+	"github.com/open-policy-agent/opa/util"
+)
+
+const (
+	fmtSyntaxPreserve = "preserve"
+	fmtSyntaxIf       = "if"
+	fmtSyntaxClassic  = "classic"
 )
 
 var fmtParams = struct {
 	overwrite bool
 	list      bool
 	diff      bool
-}{}
+	fix       bool
+	syntax    *util.EnumFlag
+}{
+	syntax: util.NewEnumFlag(fmtSyntaxPreserve, []string{fmtSyntaxPreserve, fmtSyntaxIf, fmtSyntaxClassic}),
+}
 
 var formatCommand = &cobra.Command{
 	Use:   "fmt [path [...]]",
@@ -43,7 +54,17 @@ original and formatted source.
 
 If the '-l' option is supplied, the 'fmt' command will output the names of files
 that would change if formatted. The '-l' option will suppress any other output
-to stdout from the 'fmt' command.`,
+to stdout from the 'fmt' command.
+
+If the '--syntax' option is supplied, the 'fmt' command will rewrite rules to
+use the 'if' and 'contains' keywords ('if') or not ('classic'). By default,
+rules keep the syntax they were written with. The 'fmt' command imports the
+future keywords that the formatted rules use.
+
+If the '--fix' option is supplied, the 'fmt' command will migrate the source
+files to the future keywords ('in', 'every', 'if', and 'contains'). Variables
+named after future keywords are renamed and 'future.keywords' is imported.
+Rules named after future keywords must be renamed by hand.`,
 	Run: func(cmd *cobra.Command, args []string) {
 		os.Exit(opaFmt(args))
 	},
@@ -101,7 +122,7 @@ func formatFile(filename string, info os.FileInfo, err error) error {
 		return newError("failed to open file: %v", err)
 	}
 
-	formatted, err := format.Source(filename, contents)
+	formatted, err := format.SourceWithOpts(filename, contents, fmtOpts())
 	if err != nil {
 		return newError("failed to parse Rego source file: %v", err)
 	}
@@ -153,7 +174,7 @@ func formatStdin(r io.Reader, w io.Writer) error {
 		return err
 	}
 
-	formatted, err := format.Source("stdin", contents)
+	formatted, err := format.SourceWithOpts("stdin", contents, fmtOpts())
 	if err != nil {
 		return err
 	}
@@ -206,9 +227,22 @@ func newError(msg string, a ...interface{}) fmtError {
 	}
 }
 
+func fmtOpts() format.Opts {
+	opts := format.Opts{Fix: fmtParams.fix}
+	switch fmtParams.syntax.String() {
+	case fmtSyntaxIf:
+		opts.Syntax = format.SyntaxIf
+	case fmtSyntaxClassic:
+		opts.Syntax = format.SyntaxClassic
+	}
+	return opts
+}
+
 func init() {
+	formatCommand.Flags().VarP(fmtParams.syntax, "syntax", "", "set rule syntax")
 	formatCommand.Flags().BoolVarP(&fmtParams.overwrite, "write", "w", false, "overwrite the original source file")
 	formatCommand.Flags().BoolVarP(&fmtParams.list, "list", "l", false, "list all files who would change when formatted")
 	formatCommand.Flags().BoolVarP(&fmtParams.diff, "diff", "d", false, "only display a diff of the changes")
+	formatCommand.Flags().BoolVarP(&fmtParams.fix, "fix", "", false, "rename variables named after future keywords and import them")
 	RootCommand.AddCommand(formatCommand)
 }
@@ -0,0 +1,110 @@
+// Copyright 2019 The OPA Authors.  All rights reserved.
+// Use of this source code is governed by an Apache2
+// license that can be found in the LICENSE file.
+
+package cmd
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+
+	"github.com/spf13/cobra"
+)
+
+type importCommandParams struct {
+	addr      string
+	token     string
+	inputPath string
+}
+
+func init() {
+
+	var params importCommandParams
+
+	importCommand := &cobra.Command{
+		Use:   "import [<path>]",
+		Short: "Import data into a running OPA",
+		Long: `Import data into a running OPA.
+
+The import command uploads a document to the OPA server and replaces the base
+document at the path (or the entire data tree if the path is omitted) with it.
+The import is atomic: if it fails, the data in OPA is not modified.
+
+The document is read from the input file (or stdin) and may be JSON or a
+gzipped tarball in the bundle format (e.g., produced by the export command):
+
+	$ opa import --input=backup.tar.gz
+`,
+		PreRunE: func(cmd *cobra.Command, args []string) error {
+			if len(args) > 1 {
+				return errors.New("specify at most one path argument")
+			}
+			return nil
+		},
+		Run: func(cmd *cobra.Command, args []string) {
+
+			var path string
+			if len(args) > 0 {
+				path = args[0]
+			}
+
+			in := io.Reader(os.Stdin)
+
+			if params.inputPath != "" {
+				f, err := os.Open(params.inputPath)
+				if err != nil {
+					fmt.Fprintln(os.Stderr, err)
+					os.Exit(1)
+				}
+				defer f.Close()
+				in = f
+			}
+
+			if err := importData(path, params, in); err != nil {
+				fmt.Fprintln(os.Stderr, err)
+				os.Exit(1)
+			}
+		},
+	}
+
+	setServerAddr(importCommand.Flags(), &params.addr)
+	setServerToken(importCommand.Flags(), &params.token)
+	importCommand.Flags().StringVarP(&params.inputPath, "input", "i", "", "set input file path (default stdin)")
+
+	RootCommand.AddCommand(importCommand)
+}
+
+func importData(path string, params importCommandParams, r io.Reader) error {
+
+	br := bufio.NewReader(r)
+
+	// Tarballs are recognized by the gzip magic number.
+	contentType := "application/json"
+	if magic, err := br.Peek(2); err == nil && magic[0] == 0x1f && magic[1] == 0x8b {
+		contentType = "application/gzip"
+	}
+
+	req, err := newServerRequest(http.MethodPut, params.addr, params.token, "/v1/import", path, br)
+	if err != nil {
+		return err
+	}
+
+	req.Header.Set("Content-Type", contentType)
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusNoContent {
+		return responseError(resp)
+	}
+
+	return nil
+}
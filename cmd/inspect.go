@@ -0,0 +1,115 @@
+// Copyright 2020 The OPA Authors.  All rights reserved.
+// Use of this source code is governed by an Apache2
+// license that can be found in the LICENSE file.
+
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+
+	"github.com/open-policy-agent/opa/ast"
+	pr "github.com/open-policy-agent/opa/internal/presentation"
+	"github.com/open-policy-agent/opa/loader"
+	"github.com/open-policy-agent/opa/util"
+)
+
+type inspectCommandParams struct {
+	format *util.EnumFlag
+	ignore []string
+}
+
+const (
+	inspectFormatPretty = "pretty"
+	inspectFormatJSON   = "json"
+)
+
+func init() {
+
+	var params inspectCommandParams
+
+	params.format = util.NewEnumFlag(inspectFormatPretty, []string{
+		inspectFormatPretty, inspectFormatJSON,
+	})
+
+	inspectCommand := &cobra.Command{
+		Use:   "inspect <path> [path [...]]",
+		Short: "Inspect Rego source files",
+		Long: `Inspect Rego source files.
+
+The 'inspect' command lists the packages and rules defined by Rego source files
+along with the comments attached to them. A comment is attached to a package or
+rule if it is on the same row as the package or rule or part of the block of
+comments on the rows immediately preceding it. Use '--format json' to generate
+documentation from the output.`,
+		PreRunE: func(cmd *cobra.Command, args []string) error {
+			if len(args) == 0 {
+				return errors.New("specify at least one path")
+			}
+			return nil
+		},
+		Run: func(cmd *cobra.Command, args []string) {
+			if err := inspect(os.Stdout, args, params); err != nil {
+				fmt.Fprintln(os.Stderr, err)
+				os.Exit(1)
+			}
+		},
+	}
+
+	inspectCommand.Flags().VarP(params.format, "format", "f", "set output format")
+	setIgnore(inspectCommand.Flags(), &params.ignore)
+
+	RootCommand.AddCommand(inspectCommand)
+}
+
+func inspect(w io.Writer, args []string, params inspectCommandParams) error {
+
+	f := loaderFilter{
+		Ignore: params.ignore,
+	}
+
+	result, err := loader.NewFileLoader().Filtered(args, f.Apply)
+	if err != nil {
+		return err
+	}
+
+	var output pr.InspectOutput
+
+	for _, m := range result.Modules {
+		mod := m.Parsed
+		output.Documents = append(output.Documents, inspectDocument(mod, "package", mod.Package.Path, mod.Package))
+		for _, rule := range mod.Rules {
+			output.Documents = append(output.Documents, inspectDocument(mod, "rule", rule.Path(), rule))
+		}
+	}
+
+	switch params.format.String() {
+	case inspectFormatJSON:
+		return output.JSON(w)
+	default:
+		return output.Pretty(w)
+	}
+}
+
+func inspectDocument(mod *ast.Module, kind string, path ast.Ref, node ast.Node) pr.InspectDocument {
+
+	loc := node.Loc()
+
+	doc := pr.InspectDocument{
+		Kind: kind,
+		Path: path.String(),
+		File: loc.File,
+		Row:  loc.Row,
+	}
+
+	for _, c := range mod.CommentsFor(node) {
+		doc.Comments = append(doc.Comments, strings.TrimSpace(string(c.Text)))
+	}
+
+	return doc
+}
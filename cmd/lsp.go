@@ -0,0 +1,39 @@
+// Copyright 2019 The OPA Authors.  All rights reserved.
+// Use of this source code is governed by an Apache2
+// license that can be found in the LICENSE file.
+
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+
+	"github.com/open-policy-agent/opa/internal/lsp"
+)
+
+var lspCommand = &cobra.Command{
+	Use:   "lsp",
+	Short: "Start a Rego language server",
+	Long: `Start a Rego language server.
+
+The 'lsp' command starts a server that implements the Language Server Protocol
+over stdin and stdout. Editors can use the server to report parse and compile
+errors, jump to the definitions of rules and imports, show rule documentation
+on hover, and format documents.
+
+The documentation for a rule is taken from the comments immediately preceding
+the rule.`,
+
+	Run: func(cmd *cobra.Command, args []string) {
+		if err := lsp.New(os.Stdin, os.Stdout).Serve(); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+	},
+}
+
+func init() {
+	RootCommand.AddCommand(lspCommand)
+}
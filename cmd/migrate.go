@@ -0,0 +1,178 @@
+// Copyright 2020 The OPA Authors.  All rights reserved.
+// Use of this source code is governed by an Apache2
+// license that can be found in the LICENSE file.
+
+package cmd
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"github.com/spf13/cobra"
+
+	fileurl "github.com/open-policy-agent/opa/internal/file/url"
+	"github.com/open-policy-agent/opa/internal/migrate"
+)
+
+type migrateCommandParams struct {
+	overwrite bool
+	list      bool
+	diff      bool
+}
+
+func init() {
+
+	var params migrateCommandParams
+
+	migrateCommand := &cobra.Command{
+		Use:   "migrate [path [...]]",
+		Short: "Rewrite deprecated constructs in Rego source files",
+		Long: `Rewrite deprecated constructs in Rego source files.
+
+The 'migrate' command takes Rego source files and outputs versions that use
+the modern equivalents of deprecated constructs. If no file path is provided,
+the command reads from stdin. The command:
+
+  * replaces calls to deprecated built-in functions, e.g., set_diff(a, b)
+    is rewritten to a - b.
+  * rewrites implicit iteration in assignments, e.g., x := xs[_] is rewritten
+    to some x in xs.
+  * rewrites rules to use the 'if' and 'contains' keywords.
+  * renames variables named after future keywords and imports the future
+    keywords (see 'opa fmt --fix').
+
+Comments are preserved and the output is formatted like 'opa fmt' output.
+
+If the '-w' option is supplied, the 'migrate' command will overwrite the source
+file instead of printing to stdout.
+
+If the '-d' option is supplied, the 'migrate' command will output a diff
+between the original and migrated source.
+
+If the '-l' option is supplied, the 'migrate' command will output the names of
+files that would change if migrated.`,
+		Run: func(cmd *cobra.Command, args []string) {
+			os.Exit(opaMigrate(args, params))
+		},
+	}
+
+	migrateCommand.Flags().BoolVarP(&params.overwrite, "write", "w", false, "overwrite the original source file")
+	migrateCommand.Flags().BoolVarP(&params.list, "list", "l", false, "list all files who would change when migrated")
+	migrateCommand.Flags().BoolVarP(&params.diff, "diff", "d", false, "only display a diff of the changes")
+
+	RootCommand.AddCommand(migrateCommand)
+}
+
+func opaMigrate(args []string, params migrateCommandParams) int {
+
+	if len(args) == 0 {
+		if err := migrateStdin(os.Stdin, os.Stdout); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 1
+		}
+		return 0
+	}
+
+	for _, filename := range args {
+
+		var err error
+		filename, err = fileurl.Clean(filename)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 1
+		}
+
+		err = filepath.Walk(filename, func(path string, info os.FileInfo, err error) error {
+			return migrateFile(path, info, err, params)
+		})
+
+		if err != nil {
+			switch err := err.(type) {
+			case fmtError:
+				fmt.Fprintln(os.Stderr, err.msg)
+				return err.code
+			default:
+				fmt.Fprintln(os.Stderr, err.Error())
+				return 1
+			}
+		}
+	}
+
+	return 0
+}
+
+func migrateFile(filename string, info os.FileInfo, err error, params migrateCommandParams) error {
+	if err != nil {
+		return err
+	}
+
+	if info.IsDir() || filepath.Ext(filename) != ".rego" {
+		return nil
+	}
+
+	contents, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return newError("failed to open file: %v", err)
+	}
+
+	migrated, err := migrate.Source(filename, contents)
+	if err != nil {
+		return newError("failed to migrate Rego source file: %v", err)
+	}
+
+	if bytes.Equal(migrated, contents) {
+		return nil
+	}
+
+	var out io.Writer = os.Stdout
+	if params.list {
+		fmt.Fprintln(out, filename)
+		out = ioutil.Discard
+	}
+
+	if params.diff {
+		stdout, stderr, err := doDiff(contents, migrated)
+		if err != nil && stdout.Len() == 0 {
+			fmt.Fprintln(os.Stderr, stderr.String())
+			return newError("failed to diff migration: %v", err)
+		}
+
+		fmt.Fprintln(out, stdout.String())
+		out = ioutil.Discard
+	}
+
+	if params.overwrite {
+		outfile, err := os.OpenFile(filename, os.O_WRONLY|os.O_TRUNC, info.Mode().Perm())
+		if err != nil {
+			return newError("failed to open file for writing: %v", err)
+		}
+		defer outfile.Close()
+		out = outfile
+	}
+
+	if _, err := out.Write(migrated); err != nil {
+		return newError("failed writing migrated contents: %v", err)
+	}
+
+	return nil
+}
+
+func migrateStdin(r io.Reader, w io.Writer) error {
+
+	contents, err := ioutil.ReadAll(r)
+	if err != nil {
+		return err
+	}
+
+	migrated, err := migrate.Source("stdin", contents)
+	if err != nil {
+		return err
+	}
+
+	_, err = w.Write(migrated)
+	return err
+}
@@ -0,0 +1,193 @@
+// Copyright 2020 The OPA Authors.  All rights reserved.
+// Use of this source code is governed by an Apache2
+// license that can be found in the LICENSE file.
+
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/open-policy-agent/opa/internal/presentation"
+	"github.com/open-policy-agent/opa/replay"
+	"github.com/open-policy-agent/opa/topdown"
+	"github.com/open-policy-agent/opa/topdown/lineage"
+	"github.com/open-policy-agent/opa/util"
+)
+
+type replayCommandParams struct {
+	archive    string
+	decisionID string
+	explain    *util.EnumFlag
+	format     *util.EnumFlag
+	fail       bool
+}
+
+func init() {
+
+	var params replayCommandParams
+
+	params.explain = newExplainFlag([]string{explainModeOff, explainModeFull, explainModeNotes, explainModeFails})
+	params.format = util.NewEnumFlag(diffFormatPretty, []string{
+		diffFormatPretty, diffFormatJSON,
+	})
+
+	replayCommand := &cobra.Command{
+		Use:   "replay <decision log file>",
+		Short: "Re-evaluate logged decisions against archived bundles",
+		Long: `Re-evaluate logged decisions against archived bundles.
+
+The replay command reads decision log events and evaluates each decision
+against the policy and data of the bundle revisions that were active when the
+decision was made. The decisions are evaluated at the time they were logged.
+The replayed decision is compared with the logged decision and, if --explain
+is specified, the explanation of the evaluation is reported:
+
+	$ opa replay --archive bundles/ --decision-id 4be2b4e7 --explain full decisions.log
+
+The archive directory contains one directory per bundle name. Each of these
+directories contains the bundle files (or directories) of the revisions of the
+bundle. The revision of each bundle is read from its manifest.
+
+The decision log file contains a JSON array of events or a stream of JSON
+events (e.g., the output of the console decision logger.)
+`,
+		PreRunE: func(cmd *cobra.Command, args []string) error {
+			if len(args) != 1 {
+				return errors.New("specify exactly one decision log file")
+			}
+			if params.archive == "" {
+				return errors.New("specify --archive")
+			}
+			return nil
+		},
+		Run: func(cmd *cobra.Command, args []string) {
+			changed, err := replayDecisions(args[0], params, os.Stdout)
+			if err != nil {
+				fmt.Fprintln(os.Stderr, err)
+				os.Exit(2)
+			}
+			if params.fail && changed {
+				os.Exit(1)
+			}
+		},
+	}
+
+	replayCommand.Flags().StringVarP(&params.archive, "archive", "", "", "set bundle archive directory path")
+	replayCommand.Flags().StringVarP(&params.decisionID, "decision-id", "", "", "only replay the decision with the ID")
+	replayCommand.Flags().VarP(params.format, "format", "f", "set output format")
+	replayCommand.Flags().BoolVarP(&params.fail, "fail", "", false, "exits with non-zero exit code if any decision changed")
+	setExplain(replayCommand.Flags(), params.explain)
+
+	RootCommand.AddCommand(replayCommand)
+}
+
+func replayDecisions(path string, params replayCommandParams, w io.Writer) (bool, error) {
+
+	bs, err := ioutil.ReadFile(path)
+	if err != nil {
+		return false, err
+	}
+
+	events, err := readDecisionLogEvents(bs)
+	if err != nil {
+		return false, err
+	}
+
+	ctx := context.Background()
+	archive := replay.NewArchive(params.archive)
+	trace := params.explain.String() != explainModeOff
+
+	var results []*replay.Result
+	var changed bool
+
+	for _, event := range events {
+		if params.decisionID != "" && event.DecisionID != params.decisionID {
+			continue
+		}
+
+		result, err := replay.Decision(ctx, archive, event, trace)
+		if err != nil {
+			return false, err
+		}
+
+		switch params.explain.String() {
+		case explainModeNotes:
+			result.Explanation = lineage.Notes(result.Explanation)
+		case explainModeFails:
+			result.Explanation = lineage.Fails(result.Explanation)
+		}
+
+		changed = changed || result.Changed
+		results = append(results, result)
+	}
+
+	if params.decisionID != "" && len(results) == 0 {
+		return false, fmt.Errorf("decision %v not found", params.decisionID)
+	}
+
+	switch params.format.String() {
+	case diffFormatJSON:
+		return changed, presentation.JSON(w, results)
+	default:
+		var n int
+		for _, r := range results {
+			if r.Changed {
+				n++
+			}
+			fmt.Fprintf(w, "decision %v (%v)\n", r.DecisionID, strings.Join(r.Bundles, ", "))
+			fmt.Fprintf(w, "  query:    %v\n", r.Query)
+			fmt.Fprintf(w, "  logged:   %v\n", prettyDecision(r.Logged))
+			fmt.Fprintf(w, "  replayed: %v\n", prettyDecision(r.Replayed))
+			if r.Changed {
+				fmt.Fprintln(w, "  changed")
+			}
+			if r.Masked {
+				fmt.Fprintln(w, "  note: the logged decision was masked")
+			}
+			if len(r.Explanation) > 0 {
+				fmt.Fprintln(w)
+				topdown.PrettyTrace(w, r.Explanation)
+			}
+			fmt.Fprintln(w)
+		}
+		fmt.Fprintf(w, "%d of %d decisions changed\n", n, len(results))
+		return changed, nil
+	}
+}
+
+// readDecisionLogEvents returns the decision log events contained in bs. The
+// events may be provided as a JSON array or as a stream of JSON values.
+func readDecisionLogEvents(bs []byte) ([]*replay.Event, error) {
+
+	trimmed := bytes.TrimSpace(bs)
+
+	if len(trimmed) > 0 && trimmed[0] == '[' {
+		var events []*replay.Event
+		if err := util.UnmarshalJSON(trimmed, &events); err != nil {
+			return nil, err
+		}
+		return events, nil
+	}
+
+	var events []*replay.Event
+	decoder := util.NewJSONDecoder(bytes.NewReader(trimmed))
+
+	for {
+		var event replay.Event
+		if err := decoder.Decode(&event); err == io.EOF {
+			return events, nil
+		} else if err != nil {
+			return nil, err
+		}
+		events = append(events, &event)
+	}
+}
@@ -0,0 +1,67 @@
+// Copyright 2020 The OPA Authors.  All rights reserved.
+// Use of this source code is governed by an Apache2
+// license that can be found in the LICENSE file.
+
+package cmd
+
+import (
+	"bytes"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/open-policy-agent/opa/util"
+	"github.com/open-policy-agent/opa/util/test"
+)
+
+func TestReplayDecisions(t *testing.T) {
+
+	files := map[string]string{
+		"archive/authz/v1/.manifest": `{"revision": "v1"}`,
+		"archive/authz/v1/authz.rego": `package authz
+allow { input.user = "alice" }`,
+		"decisions.log": `{"decision_id": "1", "bundles": {"authz": {"revision": "v1"}}, "path": "authz/allow", "input": {"user": "alice"}, "result": true}
+{"decision_id": "2", "bundles": {"authz": {"revision": "v1"}}, "path": "authz/allow", "input": {"user": "bob"}, "result": true}`,
+	}
+
+	test.WithTempFS(files, func(rootDir string) {
+		params := replayCommandParams{
+			archive: filepath.Join(rootDir, "archive"),
+			explain: newExplainFlag([]string{explainModeOff, explainModeFull, explainModeNotes, explainModeFails}),
+			format:  util.NewEnumFlag(diffFormatPretty, []string{diffFormatPretty, diffFormatJSON}),
+		}
+
+		var buf bytes.Buffer
+
+		changed, err := replayDecisions(filepath.Join(rootDir, "decisions.log"), params, &buf)
+		if err != nil {
+			t.Fatal(err)
+		} else if !changed {
+			t.Fatal("Expected changed decisions")
+		} else if !strings.Contains(buf.String(), "1 of 2 decisions changed") {
+			t.Fatalf("Unexpected output: %v", buf.String())
+		}
+
+		params.decisionID = "1"
+		if err := params.explain.Set(explainModeFull); err != nil {
+			t.Fatal(err)
+		}
+
+		buf.Reset()
+
+		changed, err = replayDecisions(filepath.Join(rootDir, "decisions.log"), params, &buf)
+		if err != nil {
+			t.Fatal(err)
+		} else if changed {
+			t.Fatal("Expected unchanged decision")
+		} else if !strings.Contains(buf.String(), "Enter data.authz.allow") {
+			t.Fatalf("Expected explanation but got: %v", buf.String())
+		}
+
+		params.decisionID = "3"
+
+		if _, err := replayDecisions(filepath.Join(rootDir, "decisions.log"), params, &buf); err == nil {
+			t.Fatal("Expected error for missing decision")
+		}
+	})
+}
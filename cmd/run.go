@@ -17,6 +17,7 @@ import (
 
 	"github.com/open-policy-agent/opa/runtime"
 	"github.com/open-policy-agent/opa/server"
+	"github.com/open-policy-agent/opa/server/limiter"
 	"github.com/open-policy-agent/opa/util"
 )
 
@@ -30,6 +31,7 @@ func init() {
 	var serverMode bool
 	var tlsCertFile, tlsPrivateKeyFile, tlsCACertFile string
 	var ignore []string
+	var rateLimits []string
 
 	authentication := util.NewEnumFlag("off", []string{"token", "tls", "off"})
 
@@ -79,6 +81,9 @@ compressed archive file or a directory which will be treated as a bundle.
 Without the '--bundle' flag OPA will recursively load ALL rego, JSON, and YAML
 files.
 
+If the '--stdlib' option is specified, OPA also loads the standard library of
+Rego helpers that ships with OPA. The helpers are defined under data.stdlib.
+
 When loading from directories, only files with known extensions are considered.
 The current set of file extensions that OPA will consider are:
 
@@ -112,6 +117,18 @@ File paths can be specified as URLs to resolve ambiguity in paths containing col
 				params.CertPool = pool
 			}
 
+			if len(rateLimits) > 0 {
+				params.RateLimits = map[string]limiter.Rate{}
+				for _, s := range rateLimits {
+					prefix, rate, err := limiter.ParseRate(s)
+					if err != nil {
+						fmt.Println("error:", err)
+						os.Exit(1)
+					}
+					params.RateLimits[prefix] = rate
+				}
+			}
+
 			params.Authentication = authenticationSchemes[authentication.String()]
 			params.Authorization = authorizationScheme[authorization.String()]
 			params.Certificate = cert
@@ -149,7 +166,15 @@ File paths can be specified as URLs to resolve ambiguity in paths containing col
 	runCommand.Flags().StringVarP(&params.OutputFormat, "format", "f", "pretty", "set shell output format, i.e, pretty, json")
 	runCommand.Flags().BoolVarP(&params.Watch, "watch", "w", false, "watch command line files for changes")
 	setMaxErrors(runCommand.Flags(), &params.ErrorLimit)
-	runCommand.Flags().BoolVarP(&params.PprofEnabled, "pprof", "", false, "enables pprof endpoints")
+	runCommand.Flags().BoolVarP(&params.PprofEnabled, "pprof", "", false, "enables pprof endpoints and labels query evaluation in profiles")
+	runCommand.Flags().StringArrayVar(&rateLimits, "rate-limit", []string{}, "set rate limit for server endpoints with path prefix (e.g., /v1/data=100:200 for 100 requests per second with bursts of 200)")
+	runCommand.Flags().Int64Var(&params.MaxRequestBodySize, "max-request-body-size", 0, "set maximum size (in bytes) of server request bodies (0 means unlimited)")
+	runCommand.Flags().IntVar(&params.MaxQueryExpressions, "max-query-expressions", 0, "set maximum number of expressions in ad-hoc server queries (0 means unlimited)")
+	runCommand.Flags().Float64Var(&params.MaxQueryCost, "max-query-cost", 0, "set maximum estimated cost of ad-hoc server queries (0 means unlimited)")
+	runCommand.Flags().IntVar(&params.MaxQueryResults, "max-query-results", 0, "set maximum number of results produced by ad-hoc server queries (0 means unlimited)")
+	runCommand.Flags().DurationVar(&params.DecisionCacheTTL, "decision-cache-ttl", 0, "set duration that Data API decisions are cached by the server (0 disables the cache)")
+	runCommand.Flags().IntVar(&params.DecisionCacheMaxEntries, "decision-cache-max-entries", server.DefaultDecisionCacheMaxEntries, "set maximum number of Data API decisions cached by the server")
+	runCommand.Flags().BoolVar(&params.ValidateInput, "validate-input", false, "reject Data API requests with inputs that do not conform to the input schemas inferred from the policies")
 	runCommand.Flags().StringVarP(&tlsCertFile, "tls-cert-file", "", "", "set path of TLS certificate file")
 	runCommand.Flags().StringVarP(&tlsPrivateKeyFile, "tls-private-key-file", "", "", "set path of TLS private key file")
 	runCommand.Flags().StringVarP(&tlsCACertFile, "tls-ca-cert-file", "", "", "set path of TLS CA cert file")
@@ -161,6 +186,7 @@ File paths can be specified as URLs to resolve ambiguity in paths containing col
 	runCommand.Flags().StringArrayVar(&params.ConfigOverrides, "set", []string{}, "override config values on the command line (use commas to specify multiple values)")
 	runCommand.Flags().StringArrayVar(&params.ConfigOverrideFiles, "set-file", []string{}, "override config values with files on the command line (use commas to specify multiple values)")
 	runCommand.Flags().BoolVarP(&params.BundleMode, "bundle", "b", false, "load paths as bundle files or root directories")
+	runCommand.Flags().BoolVar(&params.Stdlib, "stdlib", false, "load the standard library of Rego helpers")
 	setIgnore(runCommand.Flags(), &ignore)
 
 	usageTemplate := `Usage:
@@ -8,6 +8,9 @@ import (
 	"context"
 	"fmt"
 	"os"
+	"path/filepath"
+	goruntime "runtime"
+	"strings"
 	"time"
 
 	"github.com/open-policy-agent/opa/storage/inmem"
@@ -15,10 +18,12 @@ import (
 	"github.com/open-policy-agent/opa/bundle"
 
 	"github.com/open-policy-agent/opa/internal/runtime"
+	"github.com/open-policy-agent/opa/loader"
 	"github.com/open-policy-agent/opa/storage"
 	"github.com/open-policy-agent/opa/topdown/lineage"
 
 	"github.com/spf13/cobra"
+	"gopkg.in/fsnotify.v1"
 
 	"github.com/open-policy-agent/opa/ast"
 	"github.com/open-policy-agent/opa/cover"
@@ -30,6 +35,7 @@ import (
 const (
 	testPrettyOutput = "pretty"
 	testJSONOutput   = "json"
+	testJUnitOutput  = "junit"
 )
 
 var testParams = struct {
@@ -40,11 +46,14 @@ var testParams = struct {
 	coverage     bool
 	threshold    float64
 	timeout      time.Duration
+	parallel     int
+	slowest      int
 	ignore       []string
 	failureLine  bool
 	bundleMode   bool
+	watch        bool
 }{
-	outputFormat: util.NewEnumFlag(testPrettyOutput, []string{testPrettyOutput, testJSONOutput}),
+	outputFormat: util.NewEnumFlag(testPrettyOutput, []string{testPrettyOutput, testJSONOutput, testJUnitOutput}),
 	explain:      newExplainFlag([]string{explainModeFails, explainModeFull, explainModeNotes}),
 }
 
@@ -60,6 +69,21 @@ If the '--bundle' option is specified the paths will be treated as policy bundle
 and loaded following standard bundle conventions. The path can be a compressed archive
 file or a directory which will be treated as a bundle. Without the '--bundle' flag OPA
 will recursively load ALL *.rego, *.json, and *.yaml files for evaluating the test cases.
+
+Test cases are evaluated concurrently. The '--parallel' option controls how many
+test cases are evaluated at the same time and the '--timeout' option limits how long
+each test case may run. Results are always reported in the order the test cases
+were discovered. The '--slowest' option lists the slowest test cases after the summary.
+
+The '--format' option controls how the results are reported. In addition to the
+default human-readable format, results can be reported as JSON ('json') or as
+JUnit XML ('junit') for consumption by CI systems.
+
+If the '--watch' option is specified, the 'test' command keeps running after the
+first run and re-runs tests when files under the paths change. If only policy
+files change, only the tests that are defined in, or depend on rules defined in,
+the changed files are re-run. If data files change or files are removed, all
+tests are re-run.
 	
 Example policy (example/authz.rego):
 
@@ -116,6 +140,17 @@ func opaTest(args []string) int {
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
 
+	if testParams.watch {
+		return watchTests(ctx, args)
+	}
+
+	return runTests(ctx, args, nil)
+}
+
+// runTests loads the files and runs the tests. If changed is non-nil, only the
+// tests that depend on the changed files are run.
+func runTests(ctx context.Context, args []string, changed []string) int {
+
 	filter := loaderFilter{
 		Ignore: testParams.ignore,
 	}
@@ -176,7 +211,12 @@ func opaTest(args []string) int {
 		SetRuntime(info).
 		SetModules(modules).
 		SetBundles(bundles).
-		SetTimeout(testParams.timeout)
+		SetTimeout(testParams.timeout).
+		SetParallelism(testParams.parallel)
+
+	if changed != nil {
+		runner.SetFilter(tester.DependsOn(compiler, changed))
+	}
 
 	ch, err := runner.RunTests(ctx, txn)
 	if err != nil {
@@ -192,10 +232,15 @@ func opaTest(args []string) int {
 			reporter = tester.JSONReporter{
 				Output: os.Stdout,
 			}
+		case testJUnitOutput:
+			reporter = tester.JUnitReporter{
+				Output: os.Stdout,
+			}
 		default:
 			reporter = tester.PrettyReporter{
 				Verbose:     testParams.verbose,
 				FailureLine: testParams.failureLine,
+				Slowest:     testParams.slowest,
 				Output:      os.Stdout,
 			}
 		}
@@ -242,12 +287,109 @@ func init() {
 	testCommand.Flags().BoolVarP(&testParams.verbose, "verbose", "v", false, "set verbose reporting mode")
 	testCommand.Flags().BoolVarP(&testParams.failureLine, "show-failure-line", "l", false, "show test failure line")
 	testCommand.Flags().DurationVarP(&testParams.timeout, "timeout", "t", time.Second*5, "set test timeout")
+	testCommand.Flags().IntVarP(&testParams.parallel, "parallel", "p", goruntime.NumCPU(), "set the number of tests to evaluate concurrently")
+	testCommand.Flags().IntVarP(&testParams.slowest, "slowest", "", 0, "list the N slowest tests after the summary")
 	testCommand.Flags().VarP(testParams.outputFormat, "format", "f", "set output format")
 	testCommand.Flags().BoolVarP(&testParams.coverage, "coverage", "c", false, "report coverage (overrides debug tracing)")
 	testCommand.Flags().Float64VarP(&testParams.threshold, "threshold", "", 0, "set coverage threshold and exit with non-zero status if coverage is less than threshold %")
+	testCommand.Flags().BoolVarP(&testParams.watch, "watch", "w", false, "watch the paths and re-run affected tests when files change")
 	testCommand.Flags().BoolVarP(&testParams.bundleMode, "bundle", "b", false, "load paths as bundle files or root directories")
 	setMaxErrors(testCommand.Flags(), &testParams.errLimit)
 	setIgnore(testCommand.Flags(), &testParams.ignore)
 	setExplain(testCommand.Flags(), testParams.explain)
 	RootCommand.AddCommand(testCommand)
 }
+
+// watchTests runs the tests and then re-runs the affected tests each time files
+// under the paths change. It only returns if the watch cannot be set up.
+func watchTests(ctx context.Context, args []string) int {
+
+	runTests(ctx, args, nil)
+
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+
+	defer watcher.Close()
+
+	for {
+		if err := addWatchPaths(watcher, args); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 1
+		}
+
+		changed, all, err := readWatchEvents(ctx, watcher)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 1
+		}
+
+		fmt.Fprintln(os.Stdout)
+		fmt.Fprintf(os.Stdout, "# changed: %v\n", strings.Join(changed, ", "))
+
+		if all || testParams.bundleMode {
+			changed = nil
+		}
+
+		runTests(ctx, args, changed)
+	}
+}
+
+func addWatchPaths(watcher *fsnotify.Watcher, args []string) error {
+	for _, arg := range args {
+		_, path := loader.SplitPrefix(arg)
+		paths, err := loader.Paths(path, true)
+		if err != nil {
+			return err
+		}
+		for _, p := range paths {
+			if err := watcher.Add(p); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// readWatchEvents blocks until files change and returns the names of the
+// changed files. Events that arrive shortly after the first one are included so
+// that editors that write several files (or write files in several steps) only
+// trigger one run. The returned flag is true if all tests should be re-run
+// (i.e., if files other than policy files changed or files were removed.)
+func readWatchEvents(ctx context.Context, watcher *fsnotify.Watcher) ([]string, bool, error) {
+
+	removalMask := fsnotify.Remove | fsnotify.Rename
+	mask := fsnotify.Create | fsnotify.Write | removalMask
+
+	var changed []string
+	var all bool
+	var timeout <-chan time.Time
+	seen := map[string]struct{}{}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil, false, ctx.Err()
+		case err := <-watcher.Errors:
+			return nil, false, err
+		case evt := <-watcher.Events:
+			if evt.Op&mask == 0 {
+				continue
+			}
+			if evt.Op&removalMask != 0 || filepath.Ext(evt.Name) != ".rego" {
+				all = true
+			}
+			if _, ok := seen[evt.Name]; !ok {
+				seen[evt.Name] = struct{}{}
+				changed = append(changed, evt.Name)
+			}
+			if timeout == nil {
+				timeout = time.After(100 * time.Millisecond)
+			}
+		case <-timeout:
+			return changed, all, nil
+		}
+	}
+}
@@ -7,6 +7,7 @@ package config
 
 import (
 	"encoding/json"
+	"fmt"
 	"strings"
 
 	"github.com/open-policy-agent/opa/version"
@@ -24,9 +25,20 @@ type Config struct {
 	Bundles                      json.RawMessage            `json:"bundles"`
 	DecisionLogs                 json.RawMessage            `json:"decision_logs"`
 	Status                       json.RawMessage            `json:"status"`
+	Replication                  json.RawMessage            `json:"replication"`
 	Plugins                      map[string]json.RawMessage `json:"plugins"`
 	DefaultDecision              *string                    `json:"default_decision"`
 	DefaultAuthorizationDecision *string                    `json:"default_authorization_decision"`
+	ShadowDecisions              []ShadowDecision           `json:"shadow_decisions"`
+}
+
+// ShadowDecision configures a decision that is evaluated in shadow mode. The
+// decision is evaluated and logged as usual but callers always receive the
+// default value. If the default is not set, callers receive an undefined
+// decision.
+type ShadowDecision struct {
+	Path    string          `json:"path"`
+	Default json.RawMessage `json:"default"`
 }
 
 // ParseConfig returns a valid Config object with defaults injected. The id
@@ -41,7 +53,7 @@ func ParseConfig(raw []byte, id string) (*Config, error) {
 
 // PluginsEnabled returns true if one or more plugin features are enabled.
 func (c Config) PluginsEnabled() bool {
-	return c.Bundle != nil || c.Bundles != nil || c.DecisionLogs != nil || c.Status != nil || len(c.Plugins) > 0
+	return c.Bundle != nil || c.Bundles != nil || c.DecisionLogs != nil || c.Status != nil || c.Replication != nil || len(c.Plugins) > 0
 }
 
 // DefaultDecisionRef returns the default decision as a reference.
@@ -57,6 +69,27 @@ func (c Config) DefaultAuthorizationDecisionRef() ast.Ref {
 	return ref
 }
 
+// ShadowDecision returns the default value for the decision at path if the
+// decision is evaluated in shadow mode. The value is nil if the default is not
+// set.
+func (c Config) ShadowDecision(path ast.Ref) (*interface{}, bool) {
+	for _, d := range c.ShadowDecisions {
+		ref, _ := parsePathToRef(d.Path)
+		if !ref.Equal(path) {
+			continue
+		}
+		if d.Default == nil {
+			return nil, true
+		}
+		var x interface{}
+		if err := util.UnmarshalJSON(d.Default, &x); err != nil {
+			return nil, true
+		}
+		return &x, true
+	}
+	return nil, false
+}
+
 func (c *Config) validateAndInjectDefaults(id string) error {
 
 	if c.DefaultDecision == nil {
@@ -79,6 +112,12 @@ func (c *Config) validateAndInjectDefaults(id string) error {
 		return err
 	}
 
+	for _, d := range c.ShadowDecisions {
+		if _, err := parsePathToRef(d.Path); err != nil {
+			return fmt.Errorf("invalid shadow decision path %q: %v", d.Path, err)
+		}
+	}
+
 	if c.Labels == nil {
 		c.Labels = map[string]string{}
 	}
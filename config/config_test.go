@@ -7,6 +7,8 @@ package config
 import (
 	"encoding/json"
 	"testing"
+
+	"github.com/open-policy-agent/opa/ast"
 )
 
 func TestConfigPluginsEnabled(t *testing.T) {
@@ -48,6 +50,13 @@ func TestConfigPluginsEnabled(t *testing.T) {
 			},
 			expected: true,
 		},
+		{
+			name: "replication",
+			conf: Config{
+				Replication: []byte(`{replication: {}}`),
+			},
+			expected: true,
+		},
 		{
 			name: "plugins",
 			conf: Config{
@@ -68,3 +77,32 @@ func TestConfigPluginsEnabled(t *testing.T) {
 		})
 	}
 }
+
+func TestConfigShadowDecision(t *testing.T) {
+	conf, err := ParseConfig([]byte(`
+shadow_decisions:
+  - path: /authz/allow
+    default: true
+  - path: authz/reasons
+`), "test")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	value, ok := conf.ShadowDecision(ast.MustParseRef("data.authz.allow"))
+	if !ok || value == nil || *value != true {
+		t.Fatalf("Expected shadow decision with default true but got: %v, %v", value, ok)
+	}
+
+	if value, ok := conf.ShadowDecision(ast.MustParseRef("data.authz.reasons")); !ok || value != nil {
+		t.Fatalf("Expected shadow decision without default but got: %v, %v", value, ok)
+	}
+
+	if _, ok := conf.ShadowDecision(ast.MustParseRef("data.authz")); ok {
+		t.Fatal("Expected decision not to be evaluated in shadow mode")
+	}
+
+	if _, err := ParseConfig([]byte(`{"shadow_decisions": [{"path": "a/b c"}]}`), "test"); err == nil {
+		t.Fatal("Expected error for invalid path")
+	}
+}
@@ -0,0 +1,305 @@
+// Copyright 2020 The OPA Authors.  All rights reserved.
+// Use of this source code is governed by an Apache2
+// license that can be found in the LICENSE file.
+
+// Package cost estimates the cost of evaluating queries before they are
+// executed so that callers can refuse queries that would iterate over large
+// collections, e.g., cross products of base documents.
+package cost
+
+import (
+	"math"
+
+	"github.com/open-policy-agent/opa/ast"
+)
+
+// DefaultSize is the size assumed for collections whose size is unknown, e.g.,
+// collections in the input if no input is provided.
+const DefaultSize = 10
+
+// Estimate is the estimated cost of evaluating a query.
+type Estimate struct {
+
+	// Rows is the estimated number of results produced by the query.
+	Rows float64 `json:"rows"`
+
+	// Cost is the estimated number of expressions evaluated by the query
+	// (including expressions in the rules that the query refers to.)
+	Cost float64 `json:"cost"`
+}
+
+// Estimator estimates the cost of evaluating queries against the policies
+// defined by a compiler. The estimate is an upper bound: expressions are
+// assumed to iterate over every element of the collections they refer to and
+// comparisons are not assumed to filter results. Virtual documents are
+// evaluated once per query while functions are evaluated on every call.
+//
+// Queries must be compiled (e.g., with ast.QueryCompiler) before they are
+// estimated so that references are fully qualified.
+type Estimator struct {
+	compiler    *ast.Compiler
+	stats       Stats
+	defaultSize float64
+	rules       map[string]*Estimate
+	charged     map[string]bool
+	fixed       float64
+}
+
+// New returns a new Estimator that looks up collection sizes in stats. If stats
+// is nil, the sizes of all collections are unknown.
+func New(compiler *ast.Compiler, stats Stats) *Estimator {
+	return &Estimator{
+		compiler:    compiler,
+		stats:       stats,
+		defaultSize: DefaultSize,
+	}
+}
+
+// WithDefaultSize sets the size assumed for collections whose size is unknown.
+func (e *Estimator) WithDefaultSize(n int) *Estimator {
+	e.defaultSize = float64(n)
+	return e
+}
+
+// Query returns the estimated cost of evaluating the query.
+func (e *Estimator) Query(query ast.Body) *Estimate {
+	e.rules = map[string]*Estimate{}
+	e.charged = map[string]bool{}
+	e.fixed = 0
+
+	est := e.body(query, ast.NewVarSet())
+	est.Cost += e.fixed
+
+	return est
+}
+
+// body returns the estimated rows and cost of the body. Each expression is
+// evaluated once for every row produced by the preceding expressions and each
+// evaluation enumerates the elements of the collections it iterates over.
+func (e *Estimator) body(body ast.Body, bound ast.VarSet) *Estimate {
+
+	result := &Estimate{Rows: 1}
+	bound = bound.Copy()
+
+	for _, expr := range body {
+		factor, cost := e.expr(expr, bound)
+		result.Cost += result.Rows * (math.Max(factor, 1) + cost)
+		if !expr.Negated {
+			result.Rows *= factor
+		}
+		bound.Update(expr.Vars(ast.VarVisitorParams{SkipClosures: true}))
+	}
+
+	return result
+}
+
+// expr returns the factor by which the expression multiplies the rows and the
+// cost of evaluating the expression once (excluding the cost of virtual
+// documents, which is charged once per query.)
+func (e *Estimator) expr(expr *ast.Expr, bound ast.VarSet) (float64, float64) {
+
+	w := &walker{e: e, bound: bound.Copy(), factor: 1}
+
+	switch terms := expr.Terms.(type) {
+	case *ast.Term:
+		w.walk(terms)
+	case []*ast.Term:
+		if expr.IsCall() {
+			w.call(expr.Operator())
+			for _, operand := range expr.Operands() {
+				w.walk(operand)
+			}
+		} else {
+			for _, t := range terms {
+				w.walk(t)
+			}
+		}
+	}
+
+	return w.factor, w.cost
+}
+
+// walker accumulates the rows factor and cost of the terms in an expression.
+// Variables are bound by the first reference that iterates over them.
+type walker struct {
+	e      *Estimator
+	bound  ast.VarSet
+	factor float64
+	cost   float64
+}
+
+func (w *walker) walk(x interface{}) {
+	ast.Walk(ast.NewGenericVisitor(func(x interface{}) bool {
+		switch x := x.(type) {
+		case *ast.ArrayComprehension:
+			w.closure(x.Body)
+			return true
+		case *ast.SetComprehension:
+			w.closure(x.Body)
+			return true
+		case *ast.ObjectComprehension:
+			w.closure(x.Body)
+			return true
+		case ast.Call:
+			w.call(x[0].Value.(ast.Ref))
+			for _, arg := range x[1:] {
+				w.walk(arg)
+			}
+			return true
+		case ast.Ref:
+			w.ref(x)
+		}
+		return false
+	}), x)
+}
+
+// closure adds the cost of the comprehension body. Comprehensions produce a
+// single value so they do not multiply the rows.
+func (w *walker) closure(body ast.Body) {
+	w.cost += w.e.body(body, w.bound).Cost
+}
+
+// call adds the cost of calling the function. Built-in functions are evaluated
+// as part of the expression so only functions defined in policies are charged.
+func (w *walker) call(op ast.Ref) {
+	if rules := w.e.compiler.GetRulesExact(op); len(rules) > 0 {
+		w.cost += w.e.rulesEstimate(op, rules).Cost
+	}
+}
+
+// ref multiplies the rows factor by the sizes of the collections that the
+// reference iterates over.
+func (w *walker) ref(ref ast.Ref) {
+
+	head, ok := ref[0].Value.(ast.Var)
+	if !ok {
+		return
+	}
+
+	var node *ast.TreeNode
+	if ref[0].Equal(ast.DefaultRootDocument) {
+		node = w.e.compiler.RuleTree.Child(ast.DefaultRootDocument.Value)
+	} else if !ref[0].Equal(ast.InputRootDocument) && !w.bound.Contains(head) {
+		// The reference is the first occurrence of a local variable.
+		w.bound.Add(head)
+		return
+	}
+
+	for i := 1; i < len(ref); i++ {
+
+		if node != nil && len(node.Values) > 0 {
+			w.virtual(ref[:i], ref[i:], node)
+			return
+		}
+
+		v, ok := ref[i].Value.(ast.Var)
+		if ok && !w.bound.Contains(v) {
+			w.factor *= w.size(ref[:i], node)
+			w.bound.Add(v)
+			node = nil
+			continue
+		}
+
+		if node != nil {
+			if ok {
+				node = nil
+			} else {
+				node = node.Children[ref[i].Value]
+			}
+		}
+	}
+
+	if node != nil && len(node.Values) > 0 {
+		w.virtual(ref, nil, node)
+	}
+}
+
+// virtual charges the cost of the virtual document defined by the rules at
+// the node and multiplies the rows factor by the sizes of the collections that
+// the remaining reference iterates over.
+func (w *walker) virtual(path, rest ast.Ref, node *ast.TreeNode) {
+
+	rules := make([]*ast.Rule, len(node.Values))
+	for i := range node.Values {
+		rules[i] = node.Values[i].(*ast.Rule)
+	}
+
+	est := w.e.rulesEstimate(path, rules)
+
+	key := path.String()
+	if !w.e.charged[key] {
+		w.e.charged[key] = true
+		w.e.fixed += est.Cost
+	}
+
+	for i := range rest {
+		v, ok := rest[i].Value.(ast.Var)
+		if !ok || w.bound.Contains(v) {
+			continue
+		}
+		if i == 0 && rules[0].Head.Key != nil {
+			// The keys of partial sets and objects are produced by the rule
+			// bodies.
+			w.factor *= est.Rows
+		} else {
+			w.factor *= w.e.defaultSize
+		}
+		w.bound.Add(v)
+	}
+}
+
+// size returns the size of the collection referred to by ref. Collections in
+// base documents are looked up in the stats. Packages in the rule tree are
+// counted as elements of the data document.
+func (w *walker) size(ref ast.Ref, node *ast.TreeNode) float64 {
+	var result float64
+	n, ok := 0, false
+	if w.e.stats != nil {
+		n, ok = w.e.stats.Size(ref)
+	}
+	if ok {
+		result = float64(n)
+	} else {
+		result = w.e.defaultSize
+	}
+	if node != nil {
+		result += float64(len(node.Children))
+	}
+	return result
+}
+
+// rulesEstimate returns the estimated rows and cost of evaluating the rules
+// that define the document at path. The compiler rejects recursive rules so
+// the estimates of the rules that the rules refer to are always complete.
+func (e *Estimator) rulesEstimate(path ast.Ref, rules []*ast.Rule) *Estimate {
+
+	key := path.String()
+
+	if est, ok := e.rules[key]; ok {
+		return est
+	}
+
+	result := &Estimate{}
+
+	for _, rule := range rules {
+		for r := rule; r != nil; r = r.Else {
+			bound := ast.NewVarSet()
+			for _, arg := range r.Head.Args {
+				bound.Update(arg.Vars())
+			}
+			est := e.body(r.Body, bound)
+			result.Cost += est.Cost
+			if r == rule {
+				result.Rows += est.Rows
+			}
+		}
+	}
+
+	if rules[0].Head.Key == nil && result.Rows > 1 {
+		// Complete documents and functions produce a single value.
+		result.Rows = 1
+	}
+
+	e.rules[key] = result
+	return result
+}
@@ -0,0 +1,101 @@
+// Copyright 2020 The OPA Authors.  All rights reserved.
+// Use of this source code is governed by an Apache2
+// license that can be found in the LICENSE file.
+
+package cost
+
+import (
+	"context"
+	"testing"
+
+	"github.com/open-policy-agent/opa/ast"
+	"github.com/open-policy-agent/opa/storage"
+	"github.com/open-policy-agent/opa/storage/inmem"
+	"github.com/open-policy-agent/opa/util"
+)
+
+func TestEstimate(t *testing.T) {
+
+	ctx := context.Background()
+
+	store := inmem.NewFromObject(util.MustUnmarshalJSON([]byte(`{
+		"users": {
+			"alice": {"roles": ["admin", "dev"]},
+			"bob": {"roles": ["dev"]},
+			"carol": {"roles": []}
+		},
+		"items": [1, 2, 3, 4, 5, 6, 7, 8, 9, 10]
+	}`)).(map[string]interface{}))
+
+	compiler := ast.MustCompileModules(map[string]string{"test.rego": `package test
+
+	users[u] { data.users[u] }
+
+	total = n { n := count(data.items) }
+
+	above(x) = y { y := data.items[_]; y > x }
+	`})
+
+	tests := []struct {
+		note  string
+		query string
+		input string
+		rows  float64
+		cost  float64
+	}{
+		{"base", `data.users[u]`, ``, 3, 3},
+		{"nested", `data.users[u].roles[r]`, ``, 6, 6},
+		{"bound", `data.users[u]; data.users[u].roles[r]`, ``, 6, 9},
+		{"missing", `data.missing[x]`, ``, 0, 1},
+		{"cross product", `data.items[i]; data.items[j]; data.items[k]`, ``, 1000, 1110},
+		{"partial set", `data.test.users[u]`, ``, 3, 6},
+		{"complete", `data.test.total`, ``, 1, 4},
+		{"complete charged once", `data.test.total; data.test.total`, ``, 1, 5},
+		{"function", `data.test.above(1, y)`, ``, 1, 21},
+		{"function per row", `data.items[i]; data.test.above(i, y)`, ``, 10, 220},
+		{"comprehension", `xs := [x | data.items[x]]`, ``, 1, 11},
+		{"negation", `not data.users.alice`, ``, 1, 1},
+		{"input", `input.xs[i]`, `{"xs": [1, 2, 3]}`, 3, 3},
+		{"unknown input", `input.xs[i]`, ``, DefaultSize, DefaultSize},
+		{"local", `xs := [1, 2]; xs[i]`, ``, DefaultSize, 1 + DefaultSize},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.note, func(t *testing.T) {
+
+			var input ast.Value
+			if tc.input != "" {
+				input = ast.MustParseTerm(tc.input).Value
+			}
+
+			txn := storage.NewTransactionOrDie(ctx, store)
+			defer store.Abort(ctx, txn)
+
+			stats, err := NewStoreStats(ctx, store, txn, input)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			query, err := compiler.QueryCompiler().Compile(ast.MustParseBody(tc.query))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			est := New(compiler, stats).Query(query)
+			if est.Rows != tc.rows || est.Cost != tc.cost {
+				t.Fatalf("Expected rows=%v cost=%v but got rows=%v cost=%v for %v", tc.rows, tc.cost, est.Rows, est.Cost, query)
+			}
+		})
+	}
+}
+
+func TestEstimateDefaultSize(t *testing.T) {
+
+	compiler := ast.MustCompileModules(nil)
+	query := ast.MustParseBody(`input.xs[i]; input.ys[j]`)
+
+	est := New(compiler, nil).WithDefaultSize(100).Query(query)
+	if est.Rows != 10000 {
+		t.Fatalf("Expected rows=10000 but got: %v", est.Rows)
+	}
+}
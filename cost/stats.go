@@ -0,0 +1,132 @@
+// Copyright 2020 The OPA Authors.  All rights reserved.
+// Use of this source code is governed by an Apache2
+// license that can be found in the LICENSE file.
+
+package cost
+
+import (
+	"context"
+
+	"github.com/open-policy-agent/opa/ast"
+	"github.com/open-policy-agent/opa/storage"
+)
+
+// Stats provides the sizes of the collections (arrays, objects, and sets)
+// that queries iterate over.
+type Stats interface {
+
+	// Size returns the number of elements in the collection referred to by
+	// ref. Variables in ref match any element, in which case the size of the
+	// largest matching collection is returned. If the referenced value does
+	// not exist or is not a collection, the size is zero. If the size is
+	// unknown, false is returned.
+	Size(ref ast.Ref) (int, bool)
+}
+
+type storeStats struct {
+	ctx   context.Context
+	store storage.Store
+	txn   storage.Transaction
+	input interface{}
+}
+
+// NewStoreStats returns Stats for the base documents in the store and the
+// input. If input is nil, the sizes of collections in the input are unknown.
+func NewStoreStats(ctx context.Context, store storage.Store, txn storage.Transaction, input ast.Value) (Stats, error) {
+	s := &storeStats{
+		ctx:   ctx,
+		store: store,
+		txn:   txn,
+	}
+	if input != nil {
+		x, err := ast.JSON(input)
+		if err != nil {
+			return nil, err
+		}
+		s.input = x
+	}
+	return s, nil
+}
+
+func (s *storeStats) Size(ref ast.Ref) (int, bool) {
+
+	switch {
+	case ref[0].Equal(ast.InputRootDocument):
+		if s.input == nil {
+			return 0, false
+		}
+		return size(s.input, ref[1:])
+	case ref[0].Equal(ast.DefaultRootDocument):
+		// Read the longest ground prefix from the store and walk the rest of
+		// the reference in memory.
+		prefix := ref.ConstantPrefix()
+		path, err := storage.NewPathForRef(prefix)
+		if err != nil {
+			return 0, storage.IsNotFound(err)
+		}
+		x, err := s.store.Read(s.ctx, s.txn, path)
+		if err != nil {
+			return 0, storage.IsNotFound(err)
+		}
+		return size(x, ref[len(prefix):])
+	}
+
+	return 0, false
+}
+
+func size(x interface{}, ref ast.Ref) (int, bool) {
+
+	if len(ref) == 0 {
+		switch x := x.(type) {
+		case map[string]interface{}:
+			return len(x), true
+		case []interface{}:
+			return len(x), true
+		}
+		return 0, true
+	}
+
+	if _, ok := ref[0].Value.(ast.Var); ok {
+		var max int
+		for _, child := range children(x) {
+			n, ok := size(child, ref[1:])
+			if !ok {
+				return 0, false
+			} else if n > max {
+				max = n
+			}
+		}
+		return max, true
+	}
+
+	switch x := x.(type) {
+	case map[string]interface{}:
+		if key, ok := ref[0].Value.(ast.String); ok {
+			if child, ok := x[string(key)]; ok {
+				return size(child, ref[1:])
+			}
+		}
+	case []interface{}:
+		if num, ok := ref[0].Value.(ast.Number); ok {
+			if i, ok := num.Int(); ok && i >= 0 && i < len(x) {
+				return size(x[i], ref[1:])
+			}
+		}
+	}
+
+	return 0, true
+}
+
+func children(x interface{}) []interface{} {
+	switch x := x.(type) {
+	case map[string]interface{}:
+		result := make([]interface{}, 0, len(x))
+		for _, v := range x {
+			result = append(result, v)
+		}
+		return result
+	case []interface{}:
+		return x
+	}
+	return nil
+}
@@ -9,13 +9,16 @@ import (
 	"fmt"
 	"math"
 	"sort"
+	"sync"
 
 	"github.com/open-policy-agent/opa/ast"
 	"github.com/open-policy-agent/opa/topdown"
 )
 
-// Cover computes and reports on coverage.
+// Cover computes and reports on coverage. Cover is safe for concurrent use so
+// that it can trace multiple evaluations at the same time.
 type Cover struct {
+	mtx  sync.Mutex
 	hits map[string]map[Position]struct{}
 }
 
@@ -33,6 +36,8 @@ func (c *Cover) Enabled() bool {
 
 // Report returns a coverage Report for the given modules.
 func (c *Cover) Report(modules map[string]*ast.Module) (report Report) {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
 	report.Files = map[string]*FileReport{}
 	for file, hits := range c.hits {
 		covered := make(PositionSlice, 0, len(hits))
@@ -108,6 +113,8 @@ func (c *Cover) Trace(event *topdown.Event) {
 
 func (c *Cover) setHit(loc *ast.Location) {
 	if hasFileLocation(loc) {
+		c.mtx.Lock()
+		defer c.mtx.Unlock()
 		hits, ok := c.hits[loc.File]
 		if !ok {
 			hits = map[Position]struct{}{}
@@ -85,6 +85,9 @@ func Minimal(x interface{}) (resolved []ast.Ref, err error) {
 }
 
 // Base returns the list of base data documents that the given AST element depends on.
+// Given a query compiled with the compiler's query compiler, the result is the set of
+// base document path prefixes that the query may read. References to virtual documents
+// are followed through the rules that produce them.
 //
 // The returned refs are always constant and are truncated at any point where they become
 // dynamic. That is, a ref like data.a.b[x] will be truncated to data.a.b.
@@ -5,7 +5,9 @@
 package dependencies
 
 import (
+	"encoding/json"
 	"fmt"
+	"reflect"
 	"sort"
 	"testing"
 
@@ -440,6 +442,64 @@ func TestBaseAndVirtual(t *testing.T) {
 	}
 }
 
+func TestBaseCompiledQuery(t *testing.T) {
+	compiler := ast.NewCompiler()
+	compiler.Compile(map[string]*ast.Module{
+		"mod1": ast.MustParseModule(`package a
+
+		p[x] { q[x]; data.roles[x] = "admin" }
+		q[x] { data.users[x] }
+		r = y { y := f(data.config.limit) }
+		f(x) = y { y := x + data.config.offset }`),
+		"mod2": ast.MustParseModule(`package b.c
+
+		s = 1`),
+	})
+	if compiler.Failed() {
+		t.Fatal(compiler.Errors)
+	}
+
+	tests := []struct {
+		note     string
+		query    string
+		expected []string
+	}{
+		{"base", `data.x.y = 1`, []string{"data.x.y"}},
+		{"dynamic", `data.x[y].z = 1`, []string{"data.x"}},
+		{"nested refs", `data.x[data.y.z] = 1`, []string{"data.x", "data.y.z"}},
+		{"virtual", `data.a.p[x]`, []string{"data.roles", "data.users"}},
+		{"virtual extent", `data.a.q.bob`, []string{"data.users"}},
+		{"functions", `data.a.r = 1`, []string{"data.config.limit", "data.config.offset"}},
+		{"virtual only", `data.b.c.s = x`, nil},
+		{"input", `input.x = 1`, []string{"input.x"}},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.note, func(t *testing.T) {
+			query, err := compiler.QueryCompiler().Compile(ast.MustParseBody(tc.query))
+			if err != nil {
+				t.Fatal(err)
+			}
+			result, err := Base(compiler, query)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var expected []ast.Ref
+			for _, s := range tc.expected {
+				expected = append(expected, ast.MustParseRef(s))
+			}
+			if len(result) != len(expected) {
+				t.Fatalf("Expected %v but got: %v", expected, result)
+			}
+			for i := range result {
+				if !result[i].Equal(expected[i]) {
+					t.Fatalf("Expected %v but got: %v", expected, result)
+				}
+			}
+		})
+	}
+}
+
 func TestBase(t *testing.T) {
 	modules := map[string]*ast.Module{
 		"test": ast.MustParseModule(`
@@ -515,3 +575,169 @@ func assertRefSliceEq(t *testing.T, exp, result []ast.Ref) {
 		}
 	}
 }
+
+func TestInput(t *testing.T) {
+	compiler := ast.MustCompileModules(map[string]string{
+		"test.rego": `package authz
+
+		import input.user
+
+		allow {
+			user.role == "admin"
+			startswith(input.path, "/admin")
+		}
+
+		allow {
+			input.groups[_].name == "dev"
+			is_owner
+		}
+
+		is_owner {
+			r := input.resource
+			r.owner == user.name
+			r.size > 10
+		}
+
+		unused { input.unused }`,
+	})
+
+	schema, err := Input(compiler, ast.MustParseBody("data.authz.allow"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	bs, err := json.Marshal(schema)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	exp := `{"type":"object","properties":{` +
+		`"groups":{"type":"array","items":{"type":"object","properties":{"name":{"type":"string"}}}},` +
+		`"path":{"type":"string"},` +
+		`"resource":{"type":"object","properties":{"owner":{},"size":{"type":"number"}}},` +
+		`"user":{"type":"object","properties":{"name":{},"role":{"type":"string"}}}}}`
+
+	if string(bs) != exp {
+		t.Fatalf("Expected:\n\n%v\n\nGot:\n\n%v", exp, string(bs))
+	}
+
+	compiler = ast.MustCompileModules(map[string]string{
+		"test.rego": `package test
+
+		allow { input.user == "root" }
+		allow { input.user.name == "bob" }
+
+		port_ok { input.port == 80 }
+		port_ok { input.port == "any" }
+		port_ok { input.port == true }`,
+	})
+
+	schema, err = Input(compiler, ast.MustParseBody("data.test"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	bs, err = json.Marshal(schema)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	exp = `{"type":"object","properties":{` +
+		`"port":{"type":"boolean","alternatives":["number","string"]},` +
+		`"user":{"type":"object","alternatives":["string"],"properties":{"name":{"type":"string"}}}}}`
+
+	if string(bs) != exp {
+		t.Fatalf("Expected:\n\n%v\n\nGot:\n\n%v", exp, string(bs))
+	}
+
+	if _, err := Input(compiler, "data.authz.allow"); err == nil {
+		t.Fatal("Expected error for non-AST element")
+	}
+}
+
+func TestSchemaSetTypeOrder(t *testing.T) {
+	types := []string{TypeNumber, TypeString, TypeObject, TypeBoolean, TypeArray}
+	exp := &Schema{Type: TypeObject, Alternatives: []string{TypeArray, TypeBoolean, TypeNumber, TypeString}}
+
+	for i := range types {
+		s := &Schema{}
+		for j := range types {
+			s.setType(types[(i+j)%len(types)])
+		}
+		if !reflect.DeepEqual(s, exp) {
+			t.Fatalf("Expected %+v but got %+v", exp, s)
+		}
+	}
+}
+
+func TestSchemaValidate(t *testing.T) {
+	schema := &Schema{Type: TypeObject, Properties: map[string]*Schema{
+		"path":   {Type: TypeString},
+		"groups": {Type: TypeArray, Items: &Schema{Type: TypeObject, Properties: map[string]*Schema{"name": {Type: TypeString}}}},
+		"user":   {Type: TypeObject, Properties: map[string]*Schema{"name": {}}},
+		"owner":  {Type: TypeObject, Alternatives: []string{TypeString}, Properties: map[string]*Schema{"name": {Type: TypeString}}},
+		"port":   {Type: TypeNumber, Alternatives: []string{TypeString}},
+	}}
+
+	tests := []struct {
+		note     string
+		input    string
+		expected []string
+	}{
+		{
+			note:  "valid",
+			input: `{"path": "/", "groups": [{"name": "dev"}], "user": {"name": 1}, "other": 1}`,
+		},
+		{
+			note:  "alternatives",
+			input: `{"owner": "root", "port": "any"}`,
+		},
+		{
+			note:  "alternatives collection",
+			input: `{"owner": {"name": "bob"}, "port": 80}`,
+		},
+		{
+			note:  "alternatives invalid",
+			input: `{"owner": {"name": 1}, "port": true}`,
+			expected: []string{
+				"input.owner.name: expected string but got number",
+				"input.port: expected number or string but got boolean",
+			},
+		},
+		{
+			note:  "missing fields",
+			input: `{}`,
+		},
+		{
+			note:  "collection object",
+			input: `{"groups": {"a": {"name": "dev"}}}`,
+		},
+		{
+			note:     "root",
+			input:    `[1]`,
+			expected: []string{"input: expected object but got array"},
+		},
+		{
+			note:  "fields",
+			input: `{"path": 1, "groups": [{"name": "dev"}, {"name": null}, "x"], "user": "bob"}`,
+			expected: []string{
+				"input.groups[1].name: expected string but got null",
+				"input.groups[2]: expected object but got string",
+				"input.path: expected string but got number",
+				"input.user: expected object but got string",
+			},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.note, func(t *testing.T) {
+			var result []string
+			for _, err := range schema.Validate(ast.InputRootRef, ast.MustParseTerm(tc.input).Value) {
+				result = append(result, err.Error())
+			}
+			if !reflect.DeepEqual(result, tc.expected) {
+				t.Fatalf("Expected %v but got %v", tc.expected, result)
+			}
+		})
+	}
+}
@@ -3,5 +3,5 @@
 // license that can be found in the LICENSE file.
 
 // Package dependencies provides functions for determining the set of ast.Refs that AST
-// elements depend on.
+// elements depend on and the fields of the input document that they refer to.
 package dependencies
@@ -0,0 +1,387 @@
+// Copyright 2020 The OPA Authors.  All rights reserved.
+// Use of this source code is governed by an Apache2
+// license that can be found in the LICENSE file.
+
+package dependencies
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/open-policy-agent/opa/ast"
+	"github.com/open-policy-agent/opa/types"
+)
+
+// Schema is a JSON schema-like description of a document. Type is one of
+// "object", "array", "string", "number", "boolean", or "null". If the type
+// cannot be inferred, Type is empty. If different types are inferred for the
+// document (e.g., by rules that compare it with a string and refer to its
+// fields), Alternatives lists the other types that the document may have
+// instead of Type.
+type Schema struct {
+	Type         string             `json:"type,omitempty"`
+	Alternatives []string           `json:"alternatives,omitempty"`
+	Properties   map[string]*Schema `json:"properties,omitempty"`
+	Items        *Schema            `json:"items,omitempty"`
+}
+
+// Schema types.
+const (
+	TypeObject  = "object"
+	TypeArray   = "array"
+	TypeString  = "string"
+	TypeNumber  = "number"
+	TypeBoolean = "boolean"
+	TypeNull    = "null"
+)
+
+// Input returns a schema describing the fields of the input document that
+// the given AST element refers to, including the fields referred to by the
+// rules and functions that the element depends on. The compiler must have
+// compiled the modules that define the rules.
+//
+// Fields that are iterated over (e.g., input.xs[_]) are described as arrays
+// although they may also be objects. The types of the fields are inferred
+// from the built-in functions they are passed to and the constants they are
+// compared with.
+func Input(compiler *ast.Compiler, x interface{}) (*Schema, error) {
+
+	switch x.(type) {
+	case *ast.Module, *ast.Rule, ast.Body, *ast.Expr, *ast.Term, ast.Ref:
+	default:
+		return nil, fmt.Errorf("not an ast element: %v", x)
+	}
+
+	root := &Schema{Type: TypeObject}
+	visited := map[*ast.Rule]bool{}
+
+	var visit func(x interface{})
+
+	visit = func(x interface{}) {
+
+		inferInput(root, x)
+
+		ast.WalkRefs(x, func(ref ast.Ref) bool {
+			if !ref.HasPrefix(ast.DefaultRootRef) {
+				return false
+			}
+			for _, rule := range compiler.GetRules(ref.ConstantPrefix()) {
+				for r := rule; r != nil; r = r.Else {
+					if !visited[r] {
+						visited[r] = true
+						visit(r)
+					}
+				}
+			}
+			return false
+		})
+	}
+
+	visit(x)
+
+	return root, nil
+}
+
+// inferInput adds the input fields referred to by x to the schema. Variables
+// bound to references with equality expressions (e.g., x = input.user) are
+// treated as aliases of the referenced fields.
+func inferInput(root *Schema, x interface{}) {
+
+	aliases := map[ast.Var]ast.Ref{}
+
+	ast.WalkExprs(x, func(expr *ast.Expr) bool {
+		if !expr.IsEquality() && !expr.IsAssignment() {
+			return false
+		}
+		a, b := expr.Operand(0), expr.Operand(1)
+		if v, ok := a.Value.(ast.Var); ok {
+			if ref, ok := b.Value.(ast.Ref); ok {
+				aliases[v] = ref
+			}
+		} else if v, ok := b.Value.(ast.Var); ok {
+			if ref, ok := a.Value.(ast.Ref); ok {
+				aliases[v] = ref
+			}
+		}
+		return false
+	})
+
+	var resolveRef func(ref ast.Ref, seen map[ast.Var]bool) ast.Ref
+
+	resolveRef = func(ref ast.Ref, seen map[ast.Var]bool) ast.Ref {
+		if ref.HasPrefix(ast.InputRootRef) {
+			return ref
+		}
+		head, ok := ref[0].Value.(ast.Var)
+		if !ok || seen[head] {
+			return nil
+		}
+		alias, ok := aliases[head]
+		if !ok {
+			return nil
+		}
+		seen[head] = true
+		if resolved := resolveRef(alias, seen); resolved != nil {
+			return resolved.Concat(ref[1:])
+		}
+		return nil
+	}
+
+	resolve := func(term *ast.Term) ast.Ref {
+		switch v := term.Value.(type) {
+		case ast.Ref:
+			return resolveRef(v, map[ast.Var]bool{})
+		case ast.Var:
+			return resolveRef(ast.Ref{term}, map[ast.Var]bool{})
+		}
+		return nil
+	}
+
+	ast.WalkTerms(x, func(term *ast.Term) bool {
+		if ref := resolve(term); ref != nil {
+			root.field(ref[1:])
+		}
+		return false
+	})
+
+	ast.WalkExprs(x, func(expr *ast.Expr) bool {
+		if !expr.IsCall() {
+			return false
+		}
+		bi, ok := ast.BuiltinMap[expr.Operator().String()]
+		if !ok {
+			return false
+		}
+		operands := expr.Operands()
+		args := bi.Decl.Args()
+		for i, operand := range operands {
+			ref := resolve(operand)
+			if ref == nil {
+				continue
+			}
+			var typ string
+			if i < len(args) {
+				typ = schemaType(args[i])
+			}
+			if typ == "" && bi.Infix != "" && len(operands) == 2 {
+				// The constant that the field is compared with (e.g.,
+				// input.x == "foo") determines the type.
+				typ = valueType(operands[1-i].Value)
+			}
+			if typ != "" {
+				root.field(ref[1:]).setType(typ)
+			}
+		}
+		return false
+	})
+}
+
+// field returns the schema for the field at path, adding the field and its
+// ancestors to the schema if necessary.
+func (s *Schema) field(path ast.Ref) *Schema {
+
+	curr := s
+
+	for _, term := range path {
+		if str, ok := term.Value.(ast.String); ok {
+			curr.setType(TypeObject)
+			if curr.Properties == nil {
+				curr.Properties = map[string]*Schema{}
+			}
+			next, ok := curr.Properties[string(str)]
+			if !ok {
+				next = &Schema{}
+				curr.Properties[string(str)] = next
+			}
+			curr = next
+		} else {
+			curr.setType(TypeArray)
+			if curr.Items == nil {
+				curr.Items = &Schema{}
+			}
+			curr = curr.Items
+		}
+	}
+
+	return curr
+}
+
+// setType adds the type to the schema. Objects and arrays take precedence
+// over scalars as the Type since the properties and items of the schema
+// describe collections. Other types are recorded as alternatives. The Type is
+// chosen by precedence rather than by the order in which the types were added
+// so that the schema does not depend on the order in which rules are visited.
+func (s *Schema) setType(typ string) {
+	switch {
+	case s.Type == "":
+		s.Type = typ
+	case s.Type == typ:
+	case typePrecedes(typ, s.Type):
+		s.addAlternative(s.Type)
+		s.Type = typ
+	default:
+		s.addAlternative(typ)
+	}
+}
+
+func (s *Schema) addAlternative(typ string) {
+	for _, alt := range s.Alternatives {
+		if alt == typ {
+			return
+		}
+	}
+	s.Alternatives = append(s.Alternatives, typ)
+	sort.Strings(s.Alternatives)
+}
+
+// typePrecedes returns true if a should be the Type of a schema instead of b.
+// Objects precede arrays, collections precede scalars, and scalars are ordered
+// by name.
+func typePrecedes(a, b string) bool {
+	if a == TypeObject || b == TypeObject {
+		return a == TypeObject
+	}
+	if isCollectionType(a) != isCollectionType(b) {
+		return isCollectionType(a)
+	}
+	return a < b
+}
+
+func isCollectionType(typ string) bool {
+	return typ == TypeObject || typ == TypeArray
+}
+
+// ValidationError describes a field of a document that does not conform to a
+// schema.
+type ValidationError struct {
+	Field    string `json:"field"`
+	Expected string `json:"expected"`
+	Actual   string `json:"actual"`
+}
+
+func (e *ValidationError) Error() string {
+	return fmt.Sprintf("%v: expected %v but got %v", e.Field, e.Expected, e.Actual)
+}
+
+// Validate returns errors for the fields of the document x that do not
+// conform to the schema. The root reference names the document in the errors
+// (e.g., input.) Fields that are described by the schema but missing from the
+// document are not reported since references to missing fields are undefined.
+// Fields described as arrays may be objects or sets since the schemas returned
+// by Input describe collections that are iterated over as arrays. Fields
+// conform to the schema if they have the Type or one of the Alternatives.
+func (s *Schema) Validate(root ast.Ref, x ast.Value) []*ValidationError {
+	var errs []*ValidationError
+	s.validate(root, x, &errs)
+	return errs
+}
+
+func (s *Schema) validate(path ast.Ref, x ast.Value, errs *[]*ValidationError) {
+
+	if s == nil || s.Type == "" {
+		return
+	}
+
+	switch s.Type {
+	case TypeObject:
+		obj, ok := x.(ast.Object)
+		if !ok {
+			break
+		}
+		keys := make([]string, 0, len(s.Properties))
+		for k := range s.Properties {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			key := ast.StringTerm(k)
+			if v := obj.Get(key); v != nil {
+				s.Properties[k].validate(path.Append(key), v.Value, errs)
+			}
+		}
+		return
+	case TypeArray:
+		switch x := x.(type) {
+		case ast.Array:
+			for i := range x {
+				s.Items.validate(path.Append(ast.IntNumberTerm(i)), x[i].Value, errs)
+			}
+			return
+		case ast.Object:
+			x.Foreach(func(k, v *ast.Term) {
+				s.Items.validate(path.Append(k), v.Value, errs)
+			})
+			return
+		case ast.Set:
+			x.Foreach(func(v *ast.Term) {
+				s.Items.validate(path.Append(v), v.Value, errs)
+			})
+			return
+		}
+	default:
+		if valueType(x) == s.Type {
+			return
+		}
+	}
+
+	actual := documentType(x)
+
+	for _, alt := range s.Alternatives {
+		if actual == alt {
+			return
+		}
+	}
+
+	expected := s.Type
+	for _, alt := range s.Alternatives {
+		expected += " or " + alt
+	}
+
+	*errs = append(*errs, &ValidationError{
+		Field:    path.String(),
+		Expected: expected,
+		Actual:   actual,
+	})
+}
+
+func schemaType(t types.Type) string {
+	switch t.(type) {
+	case types.String:
+		return TypeString
+	case types.Number:
+		return TypeNumber
+	case types.Boolean:
+		return TypeBoolean
+	case types.Null:
+		return TypeNull
+	case *types.Array, *types.Set:
+		return TypeArray
+	case *types.Object:
+		return TypeObject
+	}
+	return ""
+}
+
+func valueType(v ast.Value) string {
+	switch v.(type) {
+	case ast.String:
+		return TypeString
+	case ast.Number:
+		return TypeNumber
+	case ast.Boolean:
+		return TypeBoolean
+	case ast.Null:
+		return TypeNull
+	}
+	return ""
+}
+
+func documentType(v ast.Value) string {
+	switch v.(type) {
+	case ast.Object:
+		return TypeObject
+	case ast.Array, ast.Set:
+		return TypeArray
+	}
+	return valueType(v)
+}
@@ -0,0 +1,150 @@
+// Copyright 2019 The OPA Authors.  All rights reserved.
+// Use of this source code is governed by an Apache2
+// license that can be found in the LICENSE file.
+
+// Package diff compares the decisions made by two snapshots of policy and data.
+//
+// A set of recorded inputs (e.g., taken from decision logs) is evaluated
+// against the current snapshot and a candidate snapshot. The decisions that
+// changed are reported so that policy changes can be vetted before rollout.
+package diff
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+
+	"github.com/open-policy-agent/opa/ast"
+	"github.com/open-policy-agent/opa/loader"
+	"github.com/open-policy-agent/opa/rego"
+	"github.com/open-policy-agent/opa/storage"
+	"github.com/open-policy-agent/opa/storage/inmem"
+	"github.com/open-policy-agent/opa/util"
+)
+
+// Snapshot represents the policy and data that decisions are evaluated against.
+type Snapshot struct {
+	Compiler *ast.Compiler
+	Store    storage.Store
+}
+
+// LoadSnapshot returns a snapshot containing the policy and data from the
+// bundle at path. The path may refer to a bundle file or directory.
+func LoadSnapshot(path string) (*Snapshot, error) {
+
+	b, err := loader.NewFileLoader().AsBundle(path)
+	if err != nil {
+		return nil, err
+	}
+
+	compiler := ast.NewCompiler()
+
+	if compiler.Compile(b.ParsedModules(path)); compiler.Failed() {
+		return nil, compiler.Errors
+	}
+
+	return &Snapshot{
+		Compiler: compiler,
+		Store:    inmem.NewFromObject(b.Data),
+	}, nil
+}
+
+// Decision represents the outcome of evaluating the query for one input. If
+// the query is undefined, Result is nil. If evaluation fails, Error is set.
+type Decision struct {
+	Result *interface{} `json:"result,omitempty"`
+	Error  string       `json:"error,omitempty"`
+}
+
+// Equal returns true if both decisions have the same result and error.
+func (d Decision) Equal(other Decision) bool {
+	if d.Error != other.Error || (d.Result == nil) != (other.Result == nil) {
+		return false
+	}
+	return d.Result == nil || reflect.DeepEqual(*d.Result, *other.Result)
+}
+
+// Change represents an input whose decision differs between the snapshots.
+// Index refers to the position of the input in the list of inputs.
+type Change struct {
+	Index     int         `json:"index"`
+	Input     interface{} `json:"input"`
+	Current   Decision    `json:"current"`
+	Candidate Decision    `json:"candidate"`
+}
+
+// Report contains the result of comparing the snapshots.
+type Report struct {
+	Total   int      `json:"total"`
+	Changes []Change `json:"changes,omitempty"`
+}
+
+// Decisions evaluates query against the current and candidate snapshots for
+// each of the inputs and returns a report containing the decisions that
+// changed.
+func Decisions(ctx context.Context, query string, current, candidate *Snapshot, inputs []interface{}) (*Report, error) {
+
+	currentQuery, err := prepare(ctx, query, current)
+	if err != nil {
+		return nil, fmt.Errorf("current snapshot: %v", err)
+	}
+
+	candidateQuery, err := prepare(ctx, query, candidate)
+	if err != nil {
+		return nil, fmt.Errorf("candidate snapshot: %v", err)
+	}
+
+	report := &Report{Total: len(inputs)}
+
+	for i, input := range inputs {
+
+		a := decide(ctx, currentQuery, input)
+		b := decide(ctx, candidateQuery, input)
+
+		if !a.Equal(b) {
+			report.Changes = append(report.Changes, Change{
+				Index:     i,
+				Input:     input,
+				Current:   a,
+				Candidate: b,
+			})
+		}
+	}
+
+	return report, nil
+}
+
+func prepare(ctx context.Context, query string, snapshot *Snapshot) (rego.PreparedEvalQuery, error) {
+	return rego.New(
+		rego.Query(query),
+		rego.Compiler(snapshot.Compiler),
+		rego.Store(snapshot.Store),
+	).PrepareForEval(ctx)
+}
+
+func decide(ctx context.Context, pq rego.PreparedEvalQuery, input interface{}, opts ...rego.EvalOption) Decision {
+
+	rs, err := pq.Eval(ctx, append([]rego.EvalOption{rego.EvalInput(input)}, opts...)...)
+	if err != nil {
+		return Decision{Error: err.Error()}
+	}
+
+	if len(rs) == 0 {
+		return Decision{}
+	}
+
+	// Queries that refer to a single document (e.g., data.authz.allow) yield
+	// the value of the document. Otherwise the entire result set is used.
+	var result interface{} = rs
+	if len(rs) == 1 && len(rs[0].Expressions) == 1 && len(rs[0].Bindings) == 0 {
+		result = rs[0].Expressions[0].Value
+	}
+
+	// Normalize the result so that decisions compare equally regardless of
+	// the Go types used to represent them.
+	if err := util.RoundTrip(&result); err != nil {
+		return Decision{Error: err.Error()}
+	}
+
+	return Decision{Result: &result}
+}
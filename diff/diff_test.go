@@ -0,0 +1,126 @@
+// Copyright 2019 The OPA Authors.  All rights reserved.
+// Use of this source code is governed by an Apache2
+// license that can be found in the LICENSE file.
+
+package diff
+
+import (
+	"context"
+	"encoding/json"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/open-policy-agent/opa/ast"
+	"github.com/open-policy-agent/opa/storage/inmem"
+	"github.com/open-policy-agent/opa/util"
+	"github.com/open-policy-agent/opa/util/test"
+)
+
+func TestDecisions(t *testing.T) {
+
+	current := newSnapshot(t, `package authz
+
+	default allow = false
+
+	allow { input.user = "alice" }
+	allow { data.admins[_] = input.user }`, `{"admins": ["bob"]}`)
+
+	candidate := newSnapshot(t, `package authz
+
+	allow { input.user = "alice" }
+	allow { data.admins[_] = input.user }
+	allow { 1 / input.n }`, `{"admins": ["bob", "carol"]}`)
+
+	inputs := []interface{}{
+		map[string]interface{}{"user": "alice"},
+		map[string]interface{}{"user": "bob"},
+		map[string]interface{}{"user": "carol"},
+		map[string]interface{}{"user": "dave"},
+		map[string]interface{}{"user": "eve", "n": 0},
+	}
+
+	report, err := Decisions(context.Background(), "data.authz.allow", current, candidate, inputs)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if report.Total != len(inputs) {
+		t.Fatalf("Expected total %d but got %d", len(inputs), report.Total)
+	}
+
+	var boolTrue, boolFalse interface{} = true, false
+
+	exp := []Change{
+		{Index: 2, Input: inputs[2], Current: Decision{Result: &boolFalse}, Candidate: Decision{Result: &boolTrue}},
+		{Index: 3, Input: inputs[3], Current: Decision{Result: &boolFalse}, Candidate: Decision{}},
+	}
+
+	if len(report.Changes) != 3 {
+		t.Fatalf("Expected 3 changes but got: %+v", report.Changes)
+	}
+
+	for i := range exp {
+		if !reflect.DeepEqual(report.Changes[i], exp[i]) {
+			t.Errorf("Expected change %d to be %+v but got %+v", i, exp[i], report.Changes[i])
+		}
+	}
+
+	if c := report.Changes[2]; c.Index != 4 || c.Candidate.Error == "" {
+		t.Errorf("Expected error in candidate decision but got %+v", c)
+	}
+}
+
+func TestDecisionsResultSet(t *testing.T) {
+
+	snapshot := newSnapshot(t, `package x`, `{"a": {"b": 1}}`)
+
+	report, err := Decisions(context.Background(), "x = data.a[k]", snapshot, snapshot, []interface{}{nil})
+	if err != nil {
+		t.Fatal(err)
+	} else if len(report.Changes) != 0 {
+		t.Fatalf("Expected no changes but got: %+v", report.Changes)
+	}
+
+	if _, err := Decisions(context.Background(), "x = ", snapshot, snapshot, nil); err == nil {
+		t.Fatal("Expected error for invalid query")
+	}
+}
+
+func TestLoadSnapshot(t *testing.T) {
+
+	files := map[string]string{
+		"bundle/x.rego": `package x
+p = data.y`,
+		"bundle/data.json": `{"y": 1}`,
+	}
+
+	test.WithTempFS(files, func(rootDir string) {
+		snapshot, err := LoadSnapshot(filepath.Join(rootDir, "bundle"))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		var one interface{} = json.Number("1")
+
+		report, err := Decisions(context.Background(), "data.x.p", snapshot, newSnapshot(t, `package x`, `{}`), []interface{}{nil})
+		if err != nil {
+			t.Fatal(err)
+		} else if len(report.Changes) != 1 || !reflect.DeepEqual(report.Changes[0].Current.Result, &one) {
+			t.Fatalf("Unexpected report: %+v", report)
+		}
+	})
+}
+
+func newSnapshot(t *testing.T, module string, data string) *Snapshot {
+	t.Helper()
+	compiler := ast.NewCompiler()
+	if compiler.Compile(map[string]*ast.Module{"test.rego": ast.MustParseModule(module)}); compiler.Failed() {
+		t.Fatal(compiler.Errors)
+	}
+	var x map[string]interface{}
+	if err := util.UnmarshalJSON([]byte(data), &x); err != nil {
+		t.Fatal(err)
+	}
+	return &Snapshot{Compiler: compiler, Store: inmem.NewFromObject(x)}
+}
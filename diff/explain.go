@@ -0,0 +1,186 @@
+// Copyright 2020 The OPA Authors.  All rights reserved.
+// Use of this source code is governed by an Apache2
+// license that can be found in the LICENSE file.
+
+package diff
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	"github.com/open-policy-agent/opa/ast"
+	"github.com/open-policy-agent/opa/rego"
+	"github.com/open-policy-agent/opa/topdown"
+)
+
+// Outcome describes how an expression evaluated for an input.
+type Outcome string
+
+const (
+	// OutcomeTrue indicates the expression was true at least once.
+	OutcomeTrue Outcome = "true"
+
+	// OutcomeFalse indicates the expression was evaluated but never true.
+	OutcomeFalse Outcome = "false"
+
+	// OutcomeNotEvaluated indicates the expression was not evaluated, e.g.,
+	// because an earlier expression in the same body was false.
+	OutcomeNotEvaluated Outcome = "not evaluated"
+)
+
+// ExprChange represents an expression whose outcome differs between the two
+// inputs. Expr contains the source text of the expression.
+type ExprChange struct {
+	Location *ast.Location `json:"location"`
+	Expr     string        `json:"expr"`
+	A        Outcome       `json:"a"`
+	B        Outcome       `json:"b"`
+}
+
+// Explanation contains the decisions for two inputs and the expressions whose
+// outcomes differ between them. The changes are ordered by location.
+type Explanation struct {
+	A       Decision     `json:"a"`
+	B       Decision     `json:"b"`
+	Changes []ExprChange `json:"changes,omitempty"`
+}
+
+// Explain evaluates query against the snapshot for inputs a and b and returns
+// the expressions whose outcomes differ. This helps answer questions like "why
+// was request a allowed but request b denied?": the changes identify the
+// conditions that were satisfied by one input but not the other.
+func Explain(ctx context.Context, query string, snapshot *Snapshot, a, b interface{}) (*Explanation, error) {
+
+	pq, err := prepare(ctx, query, snapshot)
+	if err != nil {
+		return nil, err
+	}
+
+	var result Explanation
+
+	// Rule indexing is disabled so that the expressions in rules that cannot
+	// match an input are evaluated (and reported as false) instead of skipped.
+	traceA := topdown.NewBufferTracer()
+	result.A = decide(ctx, pq, a, rego.EvalTracer(traceA), rego.EvalRuleIndexing(false))
+
+	traceB := topdown.NewBufferTracer()
+	result.B = decide(ctx, pq, b, rego.EvalTracer(traceB), rego.EvalRuleIndexing(false))
+
+	outcomesA := exprOutcomes(*traceA)
+	outcomesB := exprOutcomes(*traceB)
+
+	for key, x := range outcomesA {
+		if y, ok := outcomesB[key]; !ok {
+			result.Changes = append(result.Changes, x.change(x.outcome(), OutcomeNotEvaluated))
+		} else if x.outcome() != y.outcome() {
+			result.Changes = append(result.Changes, x.change(x.outcome(), y.outcome()))
+		}
+	}
+
+	for key, y := range outcomesB {
+		if _, ok := outcomesA[key]; !ok {
+			result.Changes = append(result.Changes, y.change(OutcomeNotEvaluated, y.outcome()))
+		}
+	}
+
+	sort.Slice(result.Changes, func(i, j int) bool {
+		return result.Changes[i].Location.Compare(result.Changes[j].Location) < 0
+	})
+
+	return &result, nil
+}
+
+// exprState records how the expressions on one line evaluated. The compiler
+// may rewrite an expression into several expressions (e.g., count(x) > 1
+// becomes count(x, y); y > 1) so the expressions are tracked individually and
+// expr refers to the original expression, i.e., the longest one on the line.
+type exprState struct {
+	expr  *ast.Expr
+	evals map[*ast.Expr]int
+	fails map[*ast.Expr]int
+}
+
+// outcome returns false if any of the expressions on the line was never true
+// and true otherwise. Expressions emit one Fail event for each Eval event that
+// did not produce a result.
+func (s *exprState) outcome() Outcome {
+	for expr, n := range s.evals {
+		if s.fails[expr] >= n {
+			return OutcomeFalse
+		}
+	}
+	return OutcomeTrue
+}
+
+func (s *exprState) change(a, b Outcome) ExprChange {
+	text := string(s.expr.Location.Text)
+	if s.expr.Negated {
+		text = "not " + text
+	}
+	return ExprChange{
+		Location: s.expr.Location,
+		Expr:     text,
+		A:        a,
+		B:        b,
+	}
+}
+
+func exprOutcomes(trace []*topdown.Event) map[string]*exprState {
+
+	result := map[string]*exprState{}
+
+	// Negated expressions are evaluated by evaluating their complement in a
+	// child query. The child query's events are skipped so that the outcome
+	// of the negated expression is not mixed with the outcome of its
+	// complement.
+	negations := map[uint64]bool{}
+	lastEval := map[uint64]*ast.Expr{}
+
+	for _, event := range trace {
+
+		if event.Op == topdown.EnterOp {
+			if body, ok := event.Node.(ast.Body); ok && len(body) == 1 {
+				if expr := lastEval[event.ParentID]; expr != nil && expr.Negated && expr.Location.Compare(body[0].Location) == 0 {
+					negations[event.QueryID] = true
+				}
+			}
+			continue
+		}
+
+		if event.Op != topdown.EvalOp && event.Op != topdown.FailOp {
+			continue
+		}
+
+		expr, ok := event.Node.(*ast.Expr)
+		if !ok || expr.Location == nil || negations[event.QueryID] {
+			continue
+		}
+
+		if event.Op == topdown.EvalOp {
+			lastEval[event.QueryID] = expr
+		}
+
+		key := fmt.Sprintf("%v:%v", expr.Location.File, expr.Location.Row)
+
+		state, ok := result[key]
+		if !ok {
+			state = &exprState{
+				expr:  expr,
+				evals: map[*ast.Expr]int{},
+				fails: map[*ast.Expr]int{},
+			}
+			result[key] = state
+		} else if len(expr.Location.Text) > len(state.expr.Location.Text) {
+			state.expr = expr
+		}
+
+		if event.Op == topdown.EvalOp {
+			state.evals[expr]++
+		} else {
+			state.fails[expr]++
+		}
+	}
+
+	return result
+}
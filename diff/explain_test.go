@@ -0,0 +1,87 @@
+// Copyright 2020 The OPA Authors.  All rights reserved.
+// Use of this source code is governed by an Apache2
+// license that can be found in the LICENSE file.
+
+package diff
+
+import (
+	"context"
+	"reflect"
+	"testing"
+)
+
+func TestExplain(t *testing.T) {
+
+	snapshot := newSnapshot(t, `package authz
+
+	default allow = false
+
+	allow {
+		input.method == "GET"
+		input.user == data.admins[_]
+	}
+
+	allow {
+		not input.blocked
+		count(input.roles) > 1
+	}`, `{"admins": ["alice", "bob"]}`)
+
+	a := map[string]interface{}{"method": "GET", "user": "bob", "roles": []interface{}{}}
+	b := map[string]interface{}{"method": "POST", "user": "bob", "roles": []interface{}{"x", "y"}, "blocked": true}
+
+	explanation, err := Explain(context.Background(), "data.authz.allow", snapshot, a, b)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var boolTrue, boolFalse interface{} = true, false
+
+	if !explanation.A.Equal(Decision{Result: &boolTrue}) || !explanation.B.Equal(Decision{Result: &boolFalse}) {
+		t.Fatalf("Unexpected decisions: %+v", explanation)
+	}
+
+	type change struct {
+		row  int
+		expr string
+		a, b Outcome
+	}
+
+	exp := []change{
+		{3, `default allow = false`, OutcomeNotEvaluated, OutcomeTrue},
+		{6, `input.method == "GET"`, OutcomeTrue, OutcomeFalse},
+		{7, `input.user == data.admins[_]`, OutcomeTrue, OutcomeNotEvaluated},
+		{11, `not input.blocked`, OutcomeTrue, OutcomeFalse},
+		{12, `count(input.roles) > 1`, OutcomeFalse, OutcomeNotEvaluated},
+	}
+
+	var result []change
+
+	for _, c := range explanation.Changes {
+		result = append(result, change{c.Location.Row, c.Expr, c.A, c.B})
+	}
+
+	if !reflect.DeepEqual(result, exp) {
+		t.Fatalf("Expected changes %+v but got %+v", exp, result)
+	}
+}
+
+func TestExplainNoChanges(t *testing.T) {
+
+	snapshot := newSnapshot(t, `package x
+
+	p { input.x > 1 }`, `{}`)
+
+	a := map[string]interface{}{"x": 2}
+	b := map[string]interface{}{"x": 3}
+
+	explanation, err := Explain(context.Background(), "data.x.p", snapshot, a, b)
+	if err != nil {
+		t.Fatal(err)
+	} else if len(explanation.Changes) != 0 {
+		t.Fatalf("Expected no changes but got: %+v", explanation.Changes)
+	}
+
+	if _, err := Explain(context.Background(), "x = ", snapshot, a, b); err == nil {
+		t.Fatal("Expected error for invalid query")
+	}
+}
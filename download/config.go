@@ -18,6 +18,10 @@ const (
 type PollingConfig struct {
 	MinDelaySeconds *int64 `json:"min_delay_seconds,omitempty"` // min amount of time to wait between successful poll attempts
 	MaxDelaySeconds *int64 `json:"max_delay_seconds,omitempty"` // max amount of time to wait between poll attempts
+
+	// LongPollingTimeoutSeconds enables long polling if set. The server may
+	// hold the request for up to this many seconds before replying.
+	LongPollingTimeoutSeconds *int64 `json:"long_polling_timeout_seconds,omitempty"`
 }
 
 // Config represents the configuration for the downloader.
@@ -45,6 +49,10 @@ func (c *Config) ValidateAndInjectDefaults() error {
 		return fmt.Errorf("polling configuration missing 'min_delay_seconds'")
 	}
 
+	if c.Polling.LongPollingTimeoutSeconds != nil && *c.Polling.LongPollingTimeoutSeconds < 1 {
+		return fmt.Errorf("long polling timeout must be >= 1")
+	}
+
 	// scale to seconds
 	minSeconds := int64(time.Duration(min) * time.Second)
 	c.Polling.MinDelaySeconds = &minSeconds
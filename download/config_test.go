@@ -53,6 +53,15 @@ func TestConfigValidation(t *testing.T) {
 			}`,
 			wantErr: true,
 		},
+		{
+			note: "bad long polling timeout",
+			input: `{
+				"polling": {
+					"long_polling_timeout_seconds": 0
+				}
+			}`,
+			wantErr: true,
+		},
 		{
 			note: "user supplied",
 			input: `{
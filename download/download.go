@@ -24,6 +24,14 @@ import (
 
 const (
 	minRetryDelay = time.Millisecond * 100
+
+	// longPollingGracePeriod is added to the long polling timeout to give the
+	// server time to reply before the request is cancelled.
+	longPollingGracePeriod = time.Second * 5
+
+	// longPollingContentType is the content type that servers use to indicate
+	// that they support long polling.
+	longPollingContentType = "application/vnd.openpolicyagent.bundles"
 )
 
 // Update contains the result of a download. If an error occurred, the Error
@@ -48,6 +56,8 @@ type Downloader struct {
 	f        func(context.Context, Update) // callback function invoked when download updates occur
 	logAttrs [][2]string                   // optional attributes to include in log messages
 	etag     string                        // HTTP Etag for caching purposes
+	longPoll bool                          // indicates the server supports long polling
+	token    string                        // registry token for OCI downloads
 }
 
 // New returns a new Downloader that can be started.
@@ -95,7 +105,11 @@ func (d *Downloader) loop() {
 		err := d.oneShot(ctx)
 		var delay time.Duration
 
-		if err == nil {
+		if err == nil && d.longPoll {
+			// The server held the request until an update was available (or
+			// the timeout expired) so the next request can be sent immediately.
+			delay = 0
+		} else if err == nil {
 			min := float64(*d.config.Polling.MinDelaySeconds)
 			max := float64(*d.config.Polling.MaxDelaySeconds)
 			delay = time.Duration(((max - min) * rand.Float64()) + min)
@@ -136,15 +150,31 @@ func (d *Downloader) oneShot(ctx context.Context) error {
 
 func (d *Downloader) download(ctx context.Context, m metrics.Metrics) (*bundle.Bundle, string, error) {
 
+	if d.client.Config().Type == OCIServiceType {
+		return d.downloadOCI(ctx, m)
+	}
+
 	d.logDebug("Download starting.")
 
-	resp, err := d.client.WithHeader("If-None-Match", d.etag).Do(ctx, "GET", d.path)
+	client := d.client.WithHeader("If-None-Match", d.etag)
+
+	if timeout := d.config.Polling.LongPollingTimeoutSeconds; timeout != nil {
+		client = client.WithHeader("Prefer", fmt.Sprintf("wait=%d", *timeout))
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, time.Duration(*timeout)*time.Second+longPollingGracePeriod)
+		defer cancel()
+	}
+
+	resp, err := client.Do(ctx, "GET", d.path)
 	if err != nil {
+		d.longPoll = false
 		return nil, "", errors.Wrap(err, "request failed")
 	}
 
 	defer util.Close(resp)
 
+	d.longPoll = d.config.Polling.LongPollingTimeoutSeconds != nil && resp.Header.Get("Content-Type") == longPollingContentType
+
 	switch resp.StatusCode {
 	case http.StatusOK:
 		if resp.Body != nil {
@@ -111,6 +111,47 @@ func TestFailureUnexpected(t *testing.T) {
 	}
 }
 
+func TestLongPolling(t *testing.T) {
+
+	ctx := context.Background()
+	fixture := newTestFixture(t)
+	fixture.server.longPoll = true
+	defer fixture.server.stop()
+
+	timeout := int64(10)
+	config := Config{}
+	config.Polling.LongPollingTimeoutSeconds = &timeout
+
+	d := New(config, fixture.client, "/bundles/test/bundle1")
+
+	if err := d.oneShot(ctx); err != nil {
+		t.Fatal("Unexpected:", err)
+	} else if fixture.server.prefer != "wait=10" {
+		t.Fatalf("Expected Prefer header %q but got %q", "wait=10", fixture.server.prefer)
+	} else if !d.longPoll {
+		t.Fatal("Expected long polling to be enabled")
+	}
+
+	fixture.server.longPoll = false
+
+	if err := d.oneShot(ctx); err != nil {
+		t.Fatal("Unexpected:", err)
+	} else if d.longPoll {
+		t.Fatal("Expected long polling to be disabled")
+	}
+
+	d = New(Config{}, fixture.client, "/bundles/test/bundle1")
+	fixture.server.longPoll = true
+
+	if err := d.oneShot(ctx); err != nil {
+		t.Fatal("Unexpected:", err)
+	} else if fixture.server.prefer != "" {
+		t.Fatalf("Expected no Prefer header but got %q", fixture.server.prefer)
+	} else if d.longPoll {
+		t.Fatal("Expected long polling to be disabled")
+	}
+}
+
 type testFixture struct {
 	d      *Downloader
 	client rest.Client
@@ -169,16 +210,20 @@ func newTestFixture(t *testing.T) testFixture {
 }
 
 type testServer struct {
-	t       *testing.T
-	expCode int
-	expEtag string
-	expAuth string
-	bundles map[string]bundle.Bundle
-	server  *httptest.Server
+	t        *testing.T
+	expCode  int
+	expEtag  string
+	expAuth  string
+	longPoll bool
+	prefer   string
+	bundles  map[string]bundle.Bundle
+	server   *httptest.Server
 }
 
 func (t *testServer) handle(w http.ResponseWriter, r *http.Request) {
 
+	t.prefer = r.Header.Get("Prefer")
+
 	if t.expCode != 0 {
 		w.WriteHeader(t.expCode)
 		return
@@ -206,7 +251,11 @@ func (t *testServer) handle(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
-	w.Header().Add("Content-Type", "application/gzip")
+	if t.longPoll {
+		w.Header().Add("Content-Type", longPollingContentType)
+	} else {
+		w.Header().Add("Content-Type", "application/gzip")
+	}
 
 	if t.expEtag != "" {
 		w.Header().Add("Etag", t.expEtag)
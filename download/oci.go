@@ -0,0 +1,318 @@
+// Copyright 2020 The OPA Authors.  All rights reserved.
+// Use of this source code is governed by an Apache2
+// license that can be found in the LICENSE file.
+
+package download
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/pkg/errors"
+
+	"github.com/open-policy-agent/opa/bundle"
+	"github.com/open-policy-agent/opa/metrics"
+	"github.com/open-policy-agent/opa/util"
+)
+
+const (
+	// OCIServiceType is the service type that identifies OCI registries.
+	// Bundles are downloaded from registries using the OCI distribution API
+	// instead of plain HTTP GET requests.
+	OCIServiceType = "oci"
+
+	ociManifestMediaType       = "application/vnd.oci.image.manifest.v1+json"
+	ociLayerMediaType          = "application/vnd.oci.image.layer.v1.tar+gzip"
+	ociBundleLayerMediaType    = "application/vnd.openpolicyagent.layer.v1.tar+gzip"
+	ociContentDigestHeader     = "Docker-Content-Digest"
+	ociDigestAlgorithmSHA256   = "sha256:"
+	ociDefaultReferenceTag     = "latest"
+	ociAuthenticateHeader      = "WWW-Authenticate"
+	ociAuthenticateSchemeToken = "bearer"
+
+	// ociMaxManifestSize and ociMaxTokenSize bound the size of manifests and
+	// token responses read from registries and authorization servers. The
+	// size of bundle layers is bounded by the size listed in the manifest.
+	ociMaxManifestSize = 4 << 20
+	ociMaxTokenSize    = 1 << 20
+)
+
+type ociManifest struct {
+	MediaType string          `json:"mediaType"`
+	Layers    []ociDescriptor `json:"layers"`
+}
+
+type ociDescriptor struct {
+	MediaType string `json:"mediaType"`
+	Digest    string `json:"digest"`
+	Size      int64  `json:"size"`
+}
+
+// downloadOCI downloads the bundle referenced by the downloader path (e.g.,
+// "acmecorp/policy:1.0" or "acmecorp/policy@sha256:...") from an OCI
+// registry. The digest of the image manifest is used as the ETag so the
+// bundle is only downloaded when the manifest changes.
+func (d *Downloader) downloadOCI(ctx context.Context, m metrics.Metrics) (*bundle.Bundle, string, error) {
+
+	repository, reference, err := parseOCIReference(d.path)
+	if err != nil {
+		return nil, "", err
+	}
+
+	d.logDebug("Download starting.")
+
+	body, digest, err := d.ociFetch(ctx, "/v2/"+repository+"/manifests/"+reference, ociManifestMediaType, ociMaxManifestSize)
+	if err != nil {
+		return nil, "", err
+	}
+
+	if strings.HasPrefix(reference, ociDigestAlgorithmSHA256) && digest != reference {
+		return nil, "", fmt.Errorf("manifest digest mismatch: expected %v but got %v", reference, digest)
+	}
+
+	if digest == d.etag {
+		return nil, digest, nil
+	}
+
+	var manifest ociManifest
+
+	if err := util.UnmarshalJSON(body, &manifest); err != nil {
+		return nil, "", errors.Wrap(err, "bad manifest")
+	}
+
+	var layer *ociDescriptor
+
+	for i := range manifest.Layers {
+		if mt := manifest.Layers[i].MediaType; mt == ociBundleLayerMediaType || mt == ociLayerMediaType {
+			layer = &manifest.Layers[i]
+			break
+		}
+	}
+
+	if layer == nil {
+		return nil, "", fmt.Errorf("manifest does not contain a bundle layer")
+	} else if layer.Size <= 0 {
+		return nil, "", fmt.Errorf("manifest does not specify the size of the bundle layer")
+	}
+
+	d.logDebug("Download in progress.")
+
+	blob, blobDigest, err := d.ociFetch(ctx, "/v2/"+repository+"/blobs/"+layer.Digest, "", layer.Size)
+	if err != nil {
+		return nil, "", err
+	}
+
+	if blobDigest != layer.Digest {
+		return nil, "", fmt.Errorf("layer digest mismatch: expected %v but got %v", layer.Digest, blobDigest)
+	}
+
+	m.Timer(metrics.RegoLoadBundles).Start()
+	defer m.Timer(metrics.RegoLoadBundles).Stop()
+
+	b, err := bundle.NewReader(bytes.NewReader(blob)).WithMetrics(m).Read()
+	if err != nil {
+		return nil, "", err
+	}
+
+	return &b, digest, nil
+}
+
+// ociFetch returns the content at the path on the registry and its digest. The
+// digest is computed from the content; the digest reported by the registry is
+// only used to detect corrupted downloads. Content larger than limit bytes is
+// rejected.
+func (d *Downloader) ociFetch(ctx context.Context, path string, accept string, limit int64) ([]byte, string, error) {
+
+	resp, err := d.ociRequest(ctx, path, accept)
+	if err != nil {
+		return nil, "", errors.Wrap(err, "request failed")
+	}
+
+	defer util.Close(resp)
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+	case http.StatusNotFound:
+		return nil, "", fmt.Errorf("server replied with not found")
+	case http.StatusUnauthorized:
+		return nil, "", fmt.Errorf("server replied with not authorized")
+	default:
+		return nil, "", fmt.Errorf("server replied with HTTP %v", resp.StatusCode)
+	}
+
+	body, err := ioutil.ReadAll(io.LimitReader(resp.Body, limit+1))
+	if err != nil {
+		return nil, "", err
+	}
+
+	if int64(len(body)) > limit {
+		return nil, "", fmt.Errorf("content exceeds %v bytes", limit)
+	}
+
+	digest := fmt.Sprintf("%v%x", ociDigestAlgorithmSHA256, sha256.Sum256(body))
+
+	if exp := resp.Header.Get(ociContentDigestHeader); exp != "" && exp != digest {
+		return nil, "", fmt.Errorf("content digest mismatch: expected %v but got %v", exp, digest)
+	}
+
+	return body, digest, nil
+}
+
+// ociRequest sends a GET request to the registry. If the registry replies with
+// a token authentication challenge, a token is requested from the
+// authorization server (using the credentials configured for the service) and
+// the request is retried with the token. The token is reused for subsequent
+// requests until the registry rejects it.
+func (d *Downloader) ociRequest(ctx context.Context, path string, accept string) (*http.Response, error) {
+
+	client := d.client.WithHeader("Accept", accept)
+
+	if d.token != "" {
+		client = client.WithHeader("Authorization", "Bearer "+d.token)
+	}
+
+	resp, err := client.Do(ctx, "GET", path)
+	if err != nil || resp.StatusCode != http.StatusUnauthorized {
+		return resp, err
+	}
+
+	challenge := resp.Header.Get(ociAuthenticateHeader)
+	util.Close(resp)
+
+	scheme, params := parseOCIChallenge(challenge)
+	if scheme != ociAuthenticateSchemeToken || params["realm"] == "" {
+		d.token = ""
+		return resp, nil
+	}
+
+	token, err := d.ociToken(ctx, params)
+	if err != nil {
+		return nil, err
+	}
+
+	d.token = token
+
+	return d.client.WithHeader("Accept", accept).WithHeader("Authorization", "Bearer "+token).Do(ctx, "GET", path)
+}
+
+func (d *Downloader) ociToken(ctx context.Context, params map[string]string) (string, error) {
+
+	u, err := url.Parse(params["realm"])
+	if err != nil {
+		return "", errors.Wrap(err, "bad authentication realm")
+	}
+
+	query := u.Query()
+	for _, key := range []string{"service", "scope"} {
+		if params[key] != "" {
+			query.Set(key, params[key])
+		}
+	}
+
+	u.RawQuery = query.Encode()
+
+	resp, err := d.client.Do(ctx, "GET", u.String())
+	if err != nil {
+		return "", errors.Wrap(err, "token request failed")
+	}
+
+	defer util.Close(resp)
+
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("token server replied with HTTP %v", resp.StatusCode)
+	}
+
+	var result struct {
+		Token       string `json:"token"`
+		AccessToken string `json:"access_token"`
+	}
+
+	if err := json.NewDecoder(io.LimitReader(resp.Body, ociMaxTokenSize)).Decode(&result); err != nil {
+		return "", errors.Wrap(err, "bad token response")
+	}
+
+	if result.Token != "" {
+		return result.Token, nil
+	} else if result.AccessToken != "" {
+		return result.AccessToken, nil
+	}
+
+	return "", fmt.Errorf("token server replied without token")
+}
+
+// parseOCIReference splits a reference like "acmecorp/policy:1.0" or
+// "acmecorp/policy@sha256:..." into the repository and the tag or digest. If
+// the reference does not contain a tag or digest, the "latest" tag is used.
+func parseOCIReference(ref string) (string, string, error) {
+
+	ref = strings.Trim(ref, "/")
+
+	repository, reference := ref, ociDefaultReferenceTag
+
+	if i := strings.Index(ref, "@"); i >= 0 {
+		repository, reference = ref[:i], ref[i+1:]
+		if !strings.HasPrefix(reference, ociDigestAlgorithmSHA256) {
+			return "", "", fmt.Errorf("invalid reference %q: unsupported digest algorithm", ref)
+		}
+	} else if i := strings.LastIndex(ref, ":"); i > strings.LastIndex(ref, "/") {
+		repository, reference = ref[:i], ref[i+1:]
+	}
+
+	if repository == "" || reference == "" {
+		return "", "", fmt.Errorf("invalid reference %q", ref)
+	}
+
+	return repository, reference, nil
+}
+
+// parseOCIChallenge parses the value of a WWW-Authenticate header, e.g.,
+// `Bearer realm="https://auth.example.com/token",scope="repository:x:pull"`.
+// The returned scheme is lower case.
+func parseOCIChallenge(challenge string) (string, map[string]string) {
+
+	challenge = strings.TrimSpace(challenge)
+	params := map[string]string{}
+
+	i := strings.Index(challenge, " ")
+	if i < 0 {
+		return strings.ToLower(challenge), params
+	}
+
+	scheme, rest := strings.ToLower(challenge[:i]), challenge[i+1:]
+
+	for {
+		rest = strings.TrimLeft(rest, " ,")
+		eq := strings.Index(rest, "=")
+		if eq < 0 {
+			break
+		}
+
+		key, value := strings.ToLower(strings.TrimSpace(rest[:eq])), ""
+		rest = rest[eq+1:]
+
+		if strings.HasPrefix(rest, `"`) {
+			end := strings.Index(rest[1:], `"`)
+			if end < 0 {
+				value, rest = rest[1:], ""
+			} else {
+				value, rest = rest[1:end+1], rest[end+2:]
+			}
+		} else if end := strings.Index(rest, ","); end >= 0 {
+			value, rest = rest[:end], rest[end:]
+		} else {
+			value, rest = rest, ""
+		}
+
+		params[key] = strings.TrimSpace(value)
+	}
+
+	return scheme, params
+}
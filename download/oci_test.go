@@ -0,0 +1,271 @@
+// Copyright 2020 The OPA Authors.  All rights reserved.
+// Use of this source code is governed by an Apache2
+// license that can be found in the LICENSE file.
+
+package download
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/open-policy-agent/opa/bundle"
+	"github.com/open-policy-agent/opa/plugins/rest"
+)
+
+func TestOCIDownload(t *testing.T) {
+
+	ctx := context.Background()
+	registry := newTestRegistry(t)
+	defer registry.server.Close()
+
+	client, err := rest.New([]byte(fmt.Sprintf(`{
+		"url": %q,
+		"type": "oci",
+		"credentials": {
+			"bearer": {
+				"scheme": "Basic",
+				"token": "dXNlcjpwYXNz"
+			}
+		}
+	}`, registry.server.URL)))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	d := New(Config{}, client, "acmecorp/policy:1.0")
+
+	var updates []Update
+	d.WithCallback(func(_ context.Context, u Update) {
+		updates = append(updates, u)
+	})
+
+	if err := d.oneShot(ctx); err != nil {
+		t.Fatal("Unexpected:", err)
+	} else if updates[0].Bundle == nil || updates[0].Bundle.Manifest.Revision != "quickbrownfaux" {
+		t.Fatalf("Expected bundle but got: %+v", updates[0])
+	} else if updates[0].ETag != registry.manifestDigest {
+		t.Fatalf("Expected ETag %v but got %v", registry.manifestDigest, updates[0].ETag)
+	}
+
+	if err := d.oneShot(ctx); err != nil {
+		t.Fatal("Unexpected:", err)
+	} else if updates[1].Bundle != nil {
+		t.Fatal("Expected no change")
+	}
+
+	if registry.tokens != 1 {
+		t.Fatalf("Expected token to be reused but got %d token requests", registry.tokens)
+	}
+
+	d = New(Config{}, client, "acmecorp/policy@"+registry.manifestDigest)
+
+	if err := d.oneShot(ctx); err != nil {
+		t.Fatal("Unexpected:", err)
+	}
+
+	d = New(Config{}, client, "acmecorp/policy@sha256:deadbeef")
+
+	if err := d.oneShot(ctx); err == nil || !strings.Contains(err.Error(), "not found") {
+		t.Fatal("Expected not found error but got:", err)
+	}
+
+	blob := registry.blob
+	registry.blob = []byte("corrupted")
+
+	d = New(Config{}, client, "acmecorp/policy:1.0")
+
+	if err := d.oneShot(ctx); err == nil || !strings.Contains(err.Error(), "digest mismatch") {
+		t.Fatal("Expected digest mismatch error but got:", err)
+	}
+
+	registry.blob = append(blob, 0)
+
+	d = New(Config{}, client, "acmecorp/policy:1.0")
+
+	if err := d.oneShot(ctx); err == nil || !strings.Contains(err.Error(), "content exceeds") {
+		t.Fatal("Expected size error but got:", err)
+	}
+}
+
+func TestOCIDownloadForeignRealm(t *testing.T) {
+
+	ctx := context.Background()
+	registry := newTestRegistry(t)
+	defer registry.server.Close()
+
+	var auth []string
+	realm := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		auth = r.Header["Authorization"]
+		fmt.Fprint(w, `{"token": "t1"}`)
+	}))
+	defer realm.Close()
+
+	registry.realm = realm.URL + "/token"
+
+	client, err := rest.New([]byte(fmt.Sprintf(`{
+		"url": %q,
+		"type": "oci",
+		"credentials": {
+			"bearer": {
+				"scheme": "Basic",
+				"token": "dXNlcjpwYXNz"
+			}
+		}
+	}`, registry.server.URL)))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	d := New(Config{}, client, "acmecorp/policy:1.0")
+
+	if err := d.oneShot(ctx); err != nil {
+		t.Fatal("Unexpected:", err)
+	}
+
+	if auth != nil {
+		t.Fatalf("Expected token request without credentials but got: %v", auth)
+	}
+}
+
+func TestParseOCIReference(t *testing.T) {
+
+	tests := []struct {
+		ref        string
+		repository string
+		reference  string
+		err        bool
+	}{
+		{ref: "acmecorp/policy:1.0", repository: "acmecorp/policy", reference: "1.0"},
+		{ref: "/acmecorp/policy", repository: "acmecorp/policy", reference: "latest"},
+		{ref: "acmecorp/policy@sha256:abc", repository: "acmecorp/policy", reference: "sha256:abc"},
+		{ref: "acmecorp/policy@md5:abc", err: true},
+		{ref: ":1.0", err: true},
+	}
+
+	for _, tc := range tests {
+		repository, reference, err := parseOCIReference(tc.ref)
+		if tc.err {
+			if err == nil {
+				t.Errorf("Expected error for %q", tc.ref)
+			}
+		} else if err != nil {
+			t.Errorf("Unexpected error for %q: %v", tc.ref, err)
+		} else if repository != tc.repository || reference != tc.reference {
+			t.Errorf("Expected %q and %q for %q but got %q and %q", tc.repository, tc.reference, tc.ref, repository, reference)
+		}
+	}
+}
+
+func TestParseOCIChallenge(t *testing.T) {
+
+	scheme, params := parseOCIChallenge(`Bearer realm="https://auth.example.com/token",service="registry.example.com",scope="repository:a/b:pull,push"`)
+
+	exp := map[string]string{
+		"realm":   "https://auth.example.com/token",
+		"service": "registry.example.com",
+		"scope":   "repository:a/b:pull,push",
+	}
+
+	if scheme != "bearer" || !reflect.DeepEqual(params, exp) {
+		t.Fatalf("Expected bearer and %v but got %v and %v", exp, scheme, params)
+	}
+}
+
+type testRegistry struct {
+	server         *httptest.Server
+	manifest       []byte
+	manifestDigest string
+	blob           []byte
+	blobDigest     string
+	tokens         int
+	realm          string
+}
+
+func newTestRegistry(t *testing.T) *testRegistry {
+
+	b := bundle.Bundle{
+		Manifest: bundle.Manifest{Revision: "quickbrownfaux"},
+		Data:     map[string]interface{}{"foo": "bar"},
+	}
+
+	var buf bytes.Buffer
+	if err := bundle.Write(&buf, b); err != nil {
+		t.Fatal(err)
+	}
+
+	r := &testRegistry{blob: buf.Bytes()}
+	r.blobDigest = ociTestDigest(r.blob)
+
+	manifest, err := json.Marshal(ociManifest{
+		MediaType: ociManifestMediaType,
+		Layers: []ociDescriptor{
+			{MediaType: "application/vnd.oci.image.config.v1+json", Digest: "sha256:unused"},
+			{MediaType: ociLayerMediaType, Digest: r.blobDigest, Size: int64(len(r.blob))},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	r.manifest = manifest
+	r.manifestDigest = ociTestDigest(manifest)
+	r.server = httptest.NewServer(http.HandlerFunc(r.handle))
+
+	return r
+}
+
+func (r *testRegistry) handle(w http.ResponseWriter, req *http.Request) {
+
+	if req.URL.Path == "/token" {
+		if req.Header.Get("Authorization") != "Basic dXNlcjpwYXNz" || req.URL.Query().Get("scope") != "repository:acmecorp/policy:pull" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		r.tokens++
+		fmt.Fprintf(w, `{"token": "t%d"}`, r.tokens)
+		return
+	}
+
+	if !strings.HasPrefix(req.Header.Get("Authorization"), "Bearer t") {
+		realm := r.realm
+		if realm == "" {
+			realm = r.server.URL + "/token"
+		}
+		w.Header().Set(ociAuthenticateHeader, fmt.Sprintf(`Bearer realm="%v",service="test",scope="repository:acmecorp/policy:pull"`, realm))
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	var content []byte
+
+	switch req.URL.Path {
+	case "/v2/acmecorp/policy/manifests/1.0", "/v2/acmecorp/policy/manifests/" + r.manifestDigest:
+		if req.Header.Get("Accept") != ociManifestMediaType {
+			w.WriteHeader(http.StatusNotAcceptable)
+			return
+		}
+		content = r.manifest
+		w.Header().Set(ociContentDigestHeader, r.manifestDigest)
+	case "/v2/acmecorp/policy/blobs/" + r.blobDigest:
+		content = r.blob
+	default:
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	if _, err := w.Write(content); err != nil {
+		panic(err)
+	}
+}
+
+func ociTestDigest(bs []byte) string {
+	return fmt.Sprintf("sha256:%x", sha256.Sum256(bs))
+}
@@ -13,6 +13,30 @@ import (
 	"github.com/open-policy-agent/opa/ast"
 )
 
+// Syntax controls the syntax used to write rules.
+type Syntax int
+
+const (
+	// SyntaxPreserve writes each rule with the syntax it was defined with.
+	SyntaxPreserve Syntax = iota
+
+	// SyntaxIf writes rules with the "if" and "contains" keywords, e.g.,
+	// "p contains x if { ... }".
+	SyntaxIf
+
+	// SyntaxClassic writes rules without keywords, e.g., "p[x] { ... }".
+	SyntaxClassic
+)
+
+// Opts contains options for formatting Rego source.
+type Opts struct {
+	Syntax Syntax
+
+	// Fix migrates modules to the future keywords. Variables named after
+	// future keywords are renamed and the future keywords are imported.
+	Fix bool
+}
+
 // Bytes formats Rego source code. The bytes provided do not have to be an entire
 // source file, but they must be parse-able. If the bytes are not parse-able, Bytes
 // will return an error resulting from the attempt to parse them.
@@ -28,11 +52,17 @@ func Bytes(src []byte) ([]byte, error) {
 // Rego module. If they don't, Source will return an error resulting from the attempt
 // to parse the bytes.
 func Source(filename string, src []byte) ([]byte, error) {
+	return SourceWithOpts(filename, src, Opts{})
+}
+
+// SourceWithOpts formats a Rego source file like Source using the supplied
+// options.
+func SourceWithOpts(filename string, src []byte, opts Opts) ([]byte, error) {
 	module, err := ast.ParseModule(filename, string(src))
 	if err != nil {
 		return nil, err
 	}
-	formatted, err := Ast(module)
+	formatted, err := AstWithOpts(module, opts)
 	if err != nil {
 		return nil, fmt.Errorf("%s: %v", filename, err)
 	}
@@ -54,6 +84,19 @@ func MustAst(x interface{}) []byte {
 // non-nil Location values. If an AST element with a nil Location value is
 // encountered, a default location will be set on the AST node.
 func Ast(x interface{}) (formatted []byte, err error) {
+	return AstWithOpts(x, Opts{})
+}
+
+// AstWithOpts formats a Rego AST element like Ast using the supplied options.
+func AstWithOpts(x interface{}, opts Opts) (formatted []byte, err error) {
+
+	if module, ok := x.(*ast.Module); ok && opts.Fix {
+		module = module.Copy()
+		if err := fixFutureKeywords(module); err != nil {
+			return nil, err
+		}
+		x = module
+	}
 
 	ast.WalkNodes(x, func(x ast.Node) bool {
 		if b, ok := x.(ast.Body); ok {
@@ -67,7 +110,7 @@ func Ast(x interface{}) (formatted []byte, err error) {
 		return false
 	})
 
-	w := &writer{indent: "\t"}
+	w := &writer{indent: "\t", syntax: opts.Syntax, fix: opts.Fix}
 	switch x := x.(type) {
 	case *ast.Module:
 		w.writeModule(x)
@@ -78,7 +121,7 @@ func Ast(x interface{}) (formatted []byte, err error) {
 	case *ast.Rule:
 		w.writeRule(x, false, nil)
 	case *ast.Head:
-		w.writeHead(x, false, false, nil)
+		w.writeHead(x, false, false, false, nil)
 	case ast.Body:
 		w.writeBody(x, nil)
 	case *ast.Expr:
@@ -113,6 +156,8 @@ type writer struct {
 	buf bytes.Buffer
 
 	indent    string
+	syntax    Syntax
+	fix       bool
 	level     int
 	inline    bool
 	beforeEnd *ast.Comment
@@ -151,6 +196,26 @@ func (w *writer) writeModule(module *ast.Module) {
 		return locLess(others[i], others[j])
 	})
 
+	// The formatted module must import the future keywords it uses so that
+	// it can be parsed again. The missing imports are grouped with the first
+	// import or written after the package if there are no imports.
+	if missing := w.missingFutureImports(module); len(missing) > 0 {
+		pos, loc := 0, pkg.Location
+		for i := range others {
+			if imp, ok := others[i].(*ast.Import); ok {
+				pos, loc = i+1, imp.Location
+				break
+			}
+		}
+		added := make([]interface{}, 0, len(others)+len(missing))
+		added = append(added, others[:pos]...)
+		for _, imp := range missing {
+			imp.Location = loc
+			added = append(added, imp)
+		}
+		others = append(added, others[pos:]...)
+	}
+
 	comments = w.writePackage(pkg, comments)
 	var imports []*ast.Import
 	var rules []*ast.Rule
@@ -207,19 +272,21 @@ func (w *writer) writeRule(rule *ast.Rule, isElse bool, comments []*ast.Comment)
 		w.write("default ")
 	}
 
-	// OPA transforms lone bodies like `foo = {"a": "b"}` into rules of the form
-	// `foo = {"a": "b"} { true }` in the AST. We want to preserve that notation
-	// in the formatted code instead of expanding the bodies into rules, so we
-	// pretend that the rule has no body in this case.
-	isExpandedConst := rule.Body.Equal(ast.NewBody(ast.NewExpr(ast.BooleanTerm(true)))) && rule.Else == nil
+	isExpandedConst := expandedConst(rule)
+	hasBody := ruleHasBody(rule, isElse)
+	ifSyntax, contains := w.ruleKeywords(rule, isElse)
 
-	comments = w.writeHead(rule.Head, rule.Default, isExpandedConst, comments)
+	comments = w.writeHead(rule.Head, rule.Default, isExpandedConst, contains, comments)
 
-	if (len(rule.Body) == 0 || isExpandedConst) && !isElse {
+	if !hasBody {
 		w.endLine()
 		return comments
 	}
 
+	if ifSyntax {
+		w.write(" if")
+	}
+
 	w.write(" {")
 	w.endLine()
 	w.up()
@@ -230,6 +297,8 @@ func (w *writer) writeRule(rule *ast.Rule, isElse bool, comments []*ast.Comment)
 
 	if len(rule.Head.Args) > 0 {
 		close = closingLoc('(', ')', '{', '}', rule.Location)
+	} else if rule.IfSyntax && rule.Head.DocKind() == ast.PartialSetDoc {
+		close = closingLoc(0, 0, '{', '}', rule.Location)
 	} else {
 		close = closingLoc('[', ']', '{', '}', rule.Location)
 	}
@@ -242,6 +311,7 @@ func (w *writer) writeRule(rule *ast.Rule, isElse bool, comments []*ast.Comment)
 	if rule.Else != nil {
 		w.blankLine()
 		rule.Else.Head.Name = ast.Var("else")
+		rule.Else.Head.Reference = nil
 		rule.Else.Head.Args = nil
 		comments = w.insertComments(comments, rule.Else.Head.Location)
 		comments = w.writeRule(rule.Else, true, comments)
@@ -249,8 +319,45 @@ func (w *writer) writeRule(rule *ast.Rule, isElse bool, comments []*ast.Comment)
 	return comments
 }
 
-func (w *writer) writeHead(head *ast.Head, isDefault bool, isExpandedConst bool, comments []*ast.Comment) []*ast.Comment {
-	w.write(head.Name.String())
+// OPA transforms lone bodies like `foo = {"a": "b"}` into rules of the form
+// `foo = {"a": "b"} { true }` in the AST. We want to preserve that notation
+// in the formatted code instead of expanding the bodies into rules, so we
+// pretend that the rule has no body in this case.
+func expandedConst(rule *ast.Rule) bool {
+	return rule.Body.Equal(ast.NewBody(ast.NewExpr(ast.BooleanTerm(true)))) && rule.Else == nil
+}
+
+func ruleHasBody(rule *ast.Rule, isElse bool) bool {
+	return (len(rule.Body) > 0 && !expandedConst(rule)) || isElse
+}
+
+// ruleKeywords returns true for the "if" and "contains" keywords if the rule
+// is written with them. Rules without bodies cannot be written with keywords.
+func (w *writer) ruleKeywords(rule *ast.Rule, isElse bool) (ifSyntax bool, contains bool) {
+	ifSyntax = ruleHasBody(rule, isElse) && w.useIfSyntax(rule)
+	contains = ifSyntax && rule.Head.DocKind() == ast.PartialSetDoc
+	return ifSyntax, contains
+}
+
+// useIfSyntax returns true if the rule should be written with the "if" and
+// "contains" keywords.
+func (w *writer) useIfSyntax(rule *ast.Rule) bool {
+	switch w.syntax {
+	case SyntaxIf:
+		return true
+	case SyntaxClassic:
+		return false
+	default:
+		return rule.IfSyntax
+	}
+}
+
+func (w *writer) writeHead(head *ast.Head, isDefault bool, isExpandedConst bool, contains bool, comments []*ast.Comment) []*ast.Comment {
+	if len(head.Reference) > 0 {
+		w.write(head.Reference.String())
+	} else {
+		w.write(head.Name.String())
+	}
 	if len(head.Args) > 0 {
 		w.write("(")
 		var args []interface{}
@@ -260,7 +367,10 @@ func (w *writer) writeHead(head *ast.Head, isDefault bool, isExpandedConst bool,
 		comments = w.writeIterable(args, head.Location, closingLoc(0, 0, '(', ')', head.Location), comments, w.listWriter())
 		w.write(")")
 	}
-	if head.Key != nil {
+	if contains {
+		w.write(" contains ")
+		comments = w.writeTerm(head.Key, comments)
+	} else if head.Key != nil {
 		w.write("[")
 		comments = w.writeTerm(head.Key, comments)
 		w.write("]")
@@ -298,6 +408,14 @@ func (w *writer) writeBody(body ast.Body, comments []*ast.Comment) []*ast.Commen
 
 		comments = w.writeExpr(expr, comments)
 		w.endLine()
+
+		// Statements with bodies span multiple lines so the rows of
+		// subsequent expressions are offset by the length of the body.
+		offset = 0
+		if _, ok := expr.Terms.(*ast.Every); ok {
+			row, _ := expr.Location.End()
+			offset = row - expr.Location.Row
+		}
 	}
 	return comments
 }
@@ -315,6 +433,8 @@ func (w *writer) writeExpr(expr *ast.Expr, comments []*ast.Comment) []*ast.Comme
 	switch t := expr.Terms.(type) {
 	case *ast.SomeDecl:
 		comments = w.writeSomeDecl(t, comments)
+	case *ast.Every:
+		comments = w.writeEvery(t, comments)
 	case []*ast.Term:
 		comments = w.writeFunctionCall(expr, comments)
 	case *ast.Term:
@@ -343,6 +463,17 @@ func (w *writer) writeSomeDecl(decl *ast.SomeDecl, comments []*ast.Comment) []*a
 	comments = w.insertComments(comments, decl.Location)
 	w.write("some ")
 
+	if call, ok := decl.MembershipCall(); ok {
+		for i, term := range call[1 : len(call)-1] {
+			if i > 0 {
+				w.write(", ")
+			}
+			comments = w.writeTerm(term, comments)
+		}
+		w.write(" in ")
+		return w.writeTerm(call[len(call)-1], comments)
+	}
+
 	row := decl.Location.Row
 
 	for i, term := range decl.Symbols {
@@ -366,6 +497,34 @@ func (w *writer) writeSomeDecl(decl *ast.SomeDecl, comments []*ast.Comment) []*a
 	return comments
 }
 
+func (w *writer) writeEvery(every *ast.Every, comments []*ast.Comment) []*ast.Comment {
+	comments = w.insertComments(comments, every.Location)
+	w.write("every ")
+
+	if every.Key != nil {
+		comments = w.writeTerm(every.Key, comments)
+		w.write(", ")
+	}
+
+	comments = w.writeTerm(every.Value, comments)
+	w.write(" in ")
+	comments = w.writeTerm(every.Domain, comments)
+	w.write(" {")
+	w.endLine()
+	w.up()
+
+	comments = w.writeBody(every.Body, comments)
+
+	// The closing brace is the last character of the statement.
+	row, _ := every.Location.End()
+	comments = w.insertComments(comments, &ast.Location{Row: row})
+
+	w.down()
+	w.startLine()
+	w.write("}")
+	return comments
+}
+
 func (w *writer) writeFunctionCall(expr *ast.Expr, comments []*ast.Comment) []*ast.Comment {
 
 	terms := expr.Terms.([]*ast.Term)
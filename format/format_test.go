@@ -34,6 +34,25 @@ r = y {
 	}
 }
 
+func TestFormatNilLocationEscapedString(t *testing.T) {
+	body := ast.NewBody(ast.Equality.Expr(ast.VarTerm("x"), ast.StringTerm("bell\a\ttab")))
+
+	bs, err := Ast(body)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	exp := "x = \"bell\\u0007\\ttab\"\n"
+
+	if string(bs) != exp {
+		t.Fatalf("Expected %q but got %q", exp, string(bs))
+	}
+
+	if _, err := ast.ParseBody(string(bs)); err != nil {
+		t.Fatalf("Expected formatted output to parse but got: %v", err)
+	}
+}
+
 func TestFormatNilLocationEmptyBody(t *testing.T) {
 	b := ast.NewBody()
 	x, err := Ast(b)
@@ -61,6 +80,256 @@ func TestFormatSourceError(t *testing.T) {
 	}
 }
 
+func TestFormatSyntax(t *testing.T) {
+
+	src := []byte(`package test
+
+import future.keywords.contains
+import future.keywords.if
+
+p contains x if { x := input.xs[_] }
+q[x] = y { y := input.ys[x] }
+r if { true } else = false if { input.r }
+s = 1
+`)
+
+	tests := []struct {
+		note     string
+		syntax   Syntax
+		expected string
+	}{
+		{"preserve", SyntaxPreserve, `package test
+
+import future.keywords.contains
+import future.keywords.if
+
+p contains x if {
+	x := input.xs[_]
+}
+
+q[x] = y {
+	y := input.ys[x]
+}
+
+r if {
+	true
+}
+
+else = false if {
+	input.r
+}
+
+s = 1
+`},
+		{"if", SyntaxIf, `package test
+
+import future.keywords.contains
+import future.keywords.if
+
+p contains x if {
+	x := input.xs[_]
+}
+
+q[x] = y if {
+	y := input.ys[x]
+}
+
+r if {
+	true
+}
+
+else = false if {
+	input.r
+}
+
+s = 1
+`},
+		{"classic", SyntaxClassic, `package test
+
+import future.keywords.contains
+import future.keywords.if
+
+p[x] {
+	x := input.xs[_]
+}
+
+q[x] = y {
+	y := input.ys[x]
+}
+
+r {
+	true
+}
+
+else = false {
+	input.r
+}
+
+s = 1
+`},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.note, func(t *testing.T) {
+			formatted, err := SourceWithOpts("test.rego", src, Opts{Syntax: tc.syntax})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(formatted) != tc.expected {
+				t.Fatalf("Expected:\n%s\n\nGot:\n%s", tc.expected, formatted)
+			}
+			if !ast.MustParseModule(string(formatted)).Equal(ast.MustParseModule(string(src))) {
+				t.Fatal("Expected formatted module to be equal to source module")
+			}
+		})
+	}
+}
+
+func TestFormatFutureKeywords(t *testing.T) {
+
+	tests := []struct {
+		note     string
+		src      string
+		opts     Opts
+		expected string
+		err      string
+	}{
+		{
+			note: "imports added after package",
+			src: `package test
+
+p[x] { x := input.xs[_] }
+q { input.q }`,
+			opts: Opts{Syntax: SyntaxIf},
+			expected: `package test
+
+import future.keywords.contains
+import future.keywords.if
+
+p contains x if {
+	x := input.xs[_]
+}
+
+q if {
+	input.q
+}
+`,
+		},
+		{
+			note: "imports added to first group",
+			src: `package test
+
+import future.keywords.in # in is imported
+import input.xs
+
+import data.ys
+
+p { some x in xs; x = ys[_] }
+q { true } else = false { false }`,
+			opts: Opts{Syntax: SyntaxIf},
+			expected: `package test
+
+import future.keywords.if
+import future.keywords.in # in is imported
+import input.xs
+
+import data.ys
+
+p if {
+	some x in xs
+	x = ys[_]
+}
+
+q if {
+	true
+}
+
+else = false if {
+	false
+}
+`,
+		},
+		{
+			note: "all keywords imported",
+			src: `package test
+
+import future.keywords
+
+p contains x if { x := 1 }`,
+			opts: Opts{Syntax: SyntaxIf},
+			expected: `package test
+
+import future.keywords
+
+p contains x if {
+	x := 1
+}
+`,
+		},
+		{
+			note: "fix",
+			src: `package test
+
+import data.xs as every
+
+p[in] { some in; every[in]; contains(in, "x") }
+q = every_ { every_ := [if | if := input.ifs[_]] }`,
+			opts: Opts{Fix: true},
+			expected: `package test
+
+import data.xs as every__
+import future.keywords
+
+p[in_] {
+	some in_
+	every__[in_]
+	contains(in_, "x")
+}
+
+q = every_ {
+	every_ := [if_ | if_ := input.ifs[_]]
+}
+`,
+		},
+		{
+			note: "fix rule name",
+			src: `package test
+
+contains { true }`,
+			opts: Opts{Fix: true},
+			err:  "line 3: rule contains conflicts with future keyword",
+		},
+		{
+			note: "fix import",
+			src: `package test
+
+import input.in`,
+			opts: Opts{Fix: true},
+			err:  "line 3: import input.in conflicts with future keyword",
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.note, func(t *testing.T) {
+			formatted, err := SourceWithOpts("test.rego", []byte(tc.src), tc.opts)
+			if tc.err != "" {
+				if err == nil || !strings.Contains(err.Error(), tc.err) {
+					t.Fatalf("Expected error %q but got: %v", tc.err, err)
+				}
+				return
+			} else if err != nil {
+				t.Fatal(err)
+			}
+			if string(formatted) != tc.expected {
+				t.Fatalf("Expected:\n%s\n\nGot:\n%s", tc.expected, formatted)
+			}
+			if _, err := ast.ParseModule("test.rego", string(formatted)); err != nil {
+				t.Fatalf("Failed to parse formatted bytes: %v", err)
+			}
+		})
+	}
+}
+
 func TestFormatSource(t *testing.T) {
 	regoFiles, err := filepath.Glob("testfiles/*.rego")
 	if err != nil {
@@ -0,0 +1,186 @@
+// Copyright 2020 The OPA Authors.  All rights reserved.
+// Use of this source code is governed by an Apache2
+// license that can be found in the LICENSE file.
+
+package format
+
+import (
+	"fmt"
+
+	"github.com/open-policy-agent/opa/ast"
+)
+
+// missingFutureImports returns the imports required for the future keywords
+// that the formatted module uses. Keywords that the module already imports
+// are omitted. If the module is being fixed, all future keywords are imported.
+func (w *writer) missingFutureImports(module *ast.Module) []*ast.Import {
+
+	imported := map[string]bool{}
+	all := false
+
+	for _, imp := range module.Imports {
+		kws := imp.FutureKeywords()
+		if len(kws) == len(ast.FutureKeywords) {
+			all = true
+		}
+		for _, kw := range kws {
+			imported[kw] = true
+		}
+	}
+
+	if all {
+		return nil
+	}
+
+	if w.fix {
+		return []*ast.Import{{Path: ast.NewTerm(ast.FutureRootRef.Copy())}}
+	}
+
+	used := map[string]bool{}
+
+	for _, rule := range module.Rules {
+		for r, isElse := rule, false; r != nil; r, isElse = r.Else, true {
+			ifSyntax, contains := w.ruleKeywords(r, isElse)
+			used["if"] = used["if"] || ifSyntax
+			used["contains"] = used["contains"] || contains
+		}
+	}
+
+	ast.Walk(ast.NewGenericVisitor(func(x interface{}) bool {
+		switch x := x.(type) {
+		case *ast.SomeDecl:
+			if _, ok := x.MembershipCall(); ok {
+				used["in"] = true
+			}
+		case *ast.Every:
+			used["every"] = true
+			used["in"] = true
+		case *ast.Expr:
+			if x.IsCall() && x.Operator().Equal(ast.Member.Ref()) {
+				used["in"] = true
+			}
+		case ast.Call:
+			if x[0].Value.Compare(ast.Member.Ref()) == 0 {
+				used["in"] = true
+			}
+		}
+		return false
+	}), module)
+
+	var missing []*ast.Import
+
+	for _, kw := range ast.FutureKeywords {
+		if used[kw] && !imported[kw] {
+			path := ast.FutureRootRef.Append(ast.StringTerm(kw))
+			missing = append(missing, &ast.Import{Path: ast.NewTerm(path)})
+		}
+	}
+
+	return missing
+}
+
+// fixFutureKeywords renames the variables and import aliases in the module
+// that are named after future keywords. Rules and imports without aliases
+// that are named after future keywords cannot be renamed without changing
+// the documents that other modules refer to so they are reported as errors.
+func fixFutureKeywords(module *ast.Module) error {
+
+	for _, imp := range module.Imports {
+		if imp.FutureKeywords() != nil || len(imp.Alias) > 0 {
+			continue
+		}
+		if name := imp.Name(); ast.IsFutureKeyword(string(name)) {
+			return fmt.Errorf("line %d: import %v conflicts with future keyword", imp.Location.Row, imp.Path)
+		}
+	}
+
+	var err error
+
+	ast.WalkRules(module, func(rule *ast.Rule) bool {
+		name := rule.Head.Name
+		if len(rule.Head.Reference) > 0 {
+			name = rule.Head.Reference[0].Value.(ast.Var)
+		}
+		if ast.IsFutureKeyword(string(name)) && err == nil {
+			err = fmt.Errorf("line %d: rule %v conflicts with future keyword", rule.Location.Row, name)
+		}
+		return false
+	})
+
+	if err != nil {
+		return err
+	}
+
+	// The names of functions and the targets of with statements refer to
+	// rules and built-in functions, e.g., contains(x, y), so they are
+	// not renamed.
+	refs := map[*ast.Term]bool{}
+
+	ast.Walk(ast.NewGenericVisitor(func(x interface{}) bool {
+		switch x := x.(type) {
+		case *ast.Expr:
+			if x.IsCall() {
+				refs[x.Operator()[0]] = true
+			}
+		case ast.Call:
+			if ref, ok := x[0].Value.(ast.Ref); ok {
+				refs[ref[0]] = true
+			}
+		case *ast.With:
+			if ref, ok := x.Target.Value.(ast.Ref); ok {
+				refs[ref[0]] = true
+			}
+		}
+		return false
+	}), module)
+
+	names := ast.NewVarSet()
+
+	ast.WalkVars(module, func(v ast.Var) bool {
+		names.Add(v)
+		return false
+	})
+
+	renamed := map[ast.Var]ast.Var{}
+
+	rename := func(v ast.Var) ast.Var {
+		if !ast.IsFutureKeyword(string(v)) {
+			return v
+		}
+		if r, ok := renamed[v]; ok {
+			return r
+		}
+		r := v + "_"
+		for names.Contains(r) {
+			r += "_"
+		}
+		names.Add(r)
+		renamed[v] = r
+		return r
+	}
+
+	for _, imp := range module.Imports {
+		imp.Alias = rename(imp.Alias)
+	}
+
+	renameTerms := func(term *ast.Term) bool {
+		if v, ok := term.Value.(ast.Var); ok && !refs[term] {
+			term.Value = rename(v)
+		}
+		return false
+	}
+
+	ast.WalkTerms(module, renameTerms)
+
+	// The walk does not descend into the symbols of some declarations.
+	ast.WalkExprs(module, func(expr *ast.Expr) bool {
+		if decl, ok := expr.Terms.(*ast.SomeDecl); ok {
+			for _, symbol := range decl.Symbols {
+				ast.WalkTerms(symbol, renameTerms)
+			}
+		}
+		return false
+	})
+
+	return nil
+}
@@ -10,6 +10,8 @@ import data.a.b.c              # Another comment!
 # I belong with data.a, there should be a newline before me.
 import data.a
 import data.f.g
+import future.keywords.in
+import future.keywords.every
 
 default foo = false
 foo[x] {
@@ -83,6 +85,8 @@ raw_multiline = `this
 string
   is on
 multiple lines`
+escaped_string = "\u0041\t\"\\\ud83d\ude00"
+raw_regex = `^\d+\.\d+$`
 
 fn2([x, y,
 z], {"foo": a}) = b {
@@ -191,10 +195,26 @@ vardecls {
         v5 # c3
 }
 
+membership {
+    some   k ,  v   in   input.xs
+    not v    in  {1,2}
+}
+
+quantified {
+    every  k,v   in input.xs {   # all positive
+        v > 0;  k != "x"
+    }
+    every x in [1,2] { x }
+    input.y
+}
+
 declare1 := 1
 
 declare2 := 2 { false }
 
+fruit.apple.color = "red"
+fruit.banana.color   = "yellow" { true }
+
 # more comments!
 # more comments!
 # more comments!
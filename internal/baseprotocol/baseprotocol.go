@@ -0,0 +1,66 @@
+// Copyright 2020 The OPA Authors.  All rights reserved.
+// Use of this source code is governed by an Apache2
+// license that can be found in the LICENSE file.
+
+// Package baseprotocol implements the message framing shared by the Language
+// Server Protocol and the Debug Adapter Protocol. Each message consists of a
+// header section that contains the length of the content followed by the
+// content itself.
+package baseprotocol
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net/textproto"
+	"strconv"
+)
+
+// MaxContentLength is the maximum size of a message accepted by the Reader.
+const MaxContentLength = 16 << 20
+
+// Reader reads messages from a stream.
+type Reader struct {
+	in *textproto.Reader
+}
+
+// NewReader returns a new Reader that reads messages from r.
+func NewReader(r io.Reader) *Reader {
+	return &Reader{in: textproto.NewReader(bufio.NewReader(r))}
+}
+
+// Read returns the content of the next message. If the stream ends before
+// the next message, the error is io.EOF.
+func (r *Reader) Read() ([]byte, error) {
+
+	header, err := r.in.ReadMIMEHeader()
+	if err != nil {
+		if err == io.EOF || err == io.ErrUnexpectedEOF {
+			return nil, io.EOF
+		}
+		return nil, err
+	}
+
+	n, err := strconv.Atoi(header.Get("Content-Length"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid Content-Length header: %v", err)
+	} else if n < 0 || n > MaxContentLength {
+		return nil, fmt.Errorf("invalid Content-Length header: %d is not between 0 and %d", n, MaxContentLength)
+	}
+
+	bs := make([]byte, n)
+	if _, err := io.ReadFull(r.in.R, bs); err != nil {
+		return nil, err
+	}
+
+	return bs, nil
+}
+
+// Write writes bs to w as a single message.
+func Write(w io.Writer, bs []byte) error {
+	if _, err := fmt.Fprintf(w, "Content-Length: %d\r\n\r\n", len(bs)); err != nil {
+		return err
+	}
+	_, err := w.Write(bs)
+	return err
+}
@@ -0,0 +1,64 @@
+// Copyright 2020 The OPA Authors.  All rights reserved.
+// Use of this source code is governed by an Apache2
+// license that can be found in the LICENSE file.
+
+package baseprotocol
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestReadWrite(t *testing.T) {
+
+	var buf bytes.Buffer
+
+	for _, msg := range []string{`{"a": 1}`, ``, `{"b": 2}`} {
+		if err := Write(&buf, []byte(msg)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	r := NewReader(&buf)
+
+	for _, exp := range []string{`{"a": 1}`, ``, `{"b": 2}`} {
+		bs, err := r.Read()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(bs) != exp {
+			t.Fatalf("Expected %q but got %q", exp, bs)
+		}
+	}
+
+	if _, err := r.Read(); err != io.EOF {
+		t.Fatalf("Expected EOF but got: %v", err)
+	}
+}
+
+func TestReadInvalid(t *testing.T) {
+
+	tests := []struct {
+		note  string
+		input string
+		err   string
+	}{
+		{"missing length", "Content-Type: application/json\r\n\r\n{}", "invalid Content-Length header"},
+		{"non-integer length", "Content-Length: x\r\n\r\n{}", "invalid Content-Length header"},
+		{"negative length", "Content-Length: -1\r\n\r\n{}", "invalid Content-Length header"},
+		{"oversized length", fmt.Sprintf("Content-Length: %d\r\n\r\n{}", MaxContentLength+1), "invalid Content-Length header"},
+		{"truncated content", "Content-Length: 10\r\n\r\n{}", "unexpected EOF"},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.note, func(t *testing.T) {
+			_, err := NewReader(strings.NewReader(tc.input)).Read()
+			if err == nil || !strings.Contains(err.Error(), tc.err) {
+				t.Fatalf("Expected error containing %q but got: %v", tc.err, err)
+			}
+		})
+	}
+}
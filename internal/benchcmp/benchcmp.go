@@ -0,0 +1,151 @@
+// Copyright 2019 The OPA Authors.  All rights reserved.
+// Use of this source code is governed by an Apache2
+// license that can be found in the LICENSE file.
+
+// Package benchcmp compares the output of Go benchmarks against a stored
+// baseline to detect performance regressions.
+package benchcmp
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Result contains the measurements of a single benchmark.
+type Result struct {
+	NsPerOp     float64 `json:"ns_per_op"`
+	BytesPerOp  float64 `json:"bytes_per_op,omitempty"`
+	AllocsPerOp float64 `json:"allocs_per_op,omitempty"`
+}
+
+// Baseline maps benchmark names to results.
+type Baseline map[string]Result
+
+// Regression describes a benchmark whose measurement exceeded the baseline by
+// more than the threshold.
+type Regression struct {
+	Name     string
+	Unit     string
+	Baseline float64
+	Current  float64
+}
+
+// Delta returns the relative change of the measurement.
+func (r Regression) Delta() float64 {
+	return (r.Current - r.Baseline) / r.Baseline
+}
+
+func (r Regression) String() string {
+	return fmt.Sprintf("%v: %v %.0f -> %.0f (%+.1f%%)", r.Name, r.Unit, r.Baseline, r.Current, r.Delta()*100)
+}
+
+// Parse reads the output of `go test -bench` and returns the results keyed by
+// benchmark name. The GOMAXPROCS suffix is removed from the names. If a
+// benchmark is reported more than once (e.g., with -count), the fastest run is
+// kept.
+func Parse(r io.Reader) (Baseline, error) {
+
+	result := Baseline{}
+	scanner := bufio.NewScanner(r)
+
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 4 || !strings.HasPrefix(fields[0], "Benchmark") {
+			continue
+		}
+		if _, err := strconv.Atoi(fields[1]); err != nil {
+			continue
+		}
+
+		name := trimProcs(fields[0])
+		var res Result
+
+		for i := 2; i+1 < len(fields); i += 2 {
+			v, err := strconv.ParseFloat(fields[i], 64)
+			if err != nil {
+				return nil, fmt.Errorf("%v: bad measurement %q", name, fields[i])
+			}
+			switch fields[i+1] {
+			case "ns/op":
+				res.NsPerOp = v
+			case "B/op":
+				res.BytesPerOp = v
+			case "allocs/op":
+				res.AllocsPerOp = v
+			}
+		}
+
+		if prev, ok := result[name]; !ok || res.NsPerOp < prev.NsPerOp {
+			result[name] = res
+		}
+	}
+
+	return result, scanner.Err()
+}
+
+// Compare returns the regressions in current with respect to baseline.
+// Measurements are regressions if they exceed the baseline by more than
+// threshold (e.g., 0.2 for 20%). Benchmarks missing from either side are
+// ignored. The regressions are sorted by name.
+func Compare(baseline, current Baseline, threshold float64) []Regression {
+
+	var result []Regression
+
+	for name, cur := range current {
+		base, ok := baseline[name]
+		if !ok {
+			continue
+		}
+		check := func(unit string, a, b float64) {
+			if a > 0 && (b-a)/a > threshold {
+				result = append(result, Regression{
+					Name:     name,
+					Unit:     unit,
+					Baseline: a,
+					Current:  b,
+				})
+			}
+		}
+		check("ns/op", base.NsPerOp, cur.NsPerOp)
+		check("allocs/op", base.AllocsPerOp, cur.AllocsPerOp)
+	}
+
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].Name == result[j].Name {
+			return result[i].Unit < result[j].Unit
+		}
+		return result[i].Name < result[j].Name
+	})
+
+	return result
+}
+
+// ReadBaseline decodes a baseline from r.
+func ReadBaseline(r io.Reader) (Baseline, error) {
+	var baseline Baseline
+	if err := json.NewDecoder(r).Decode(&baseline); err != nil {
+		return nil, err
+	}
+	return baseline, nil
+}
+
+// WriteBaseline encodes the baseline to w.
+func WriteBaseline(w io.Writer, baseline Baseline) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(baseline)
+}
+
+func trimProcs(name string) string {
+	if i := strings.LastIndexByte(name, '-'); i > 0 {
+		if _, err := strconv.Atoi(name[i+1:]); err == nil {
+			return name[:i]
+		}
+	}
+	return name
+}
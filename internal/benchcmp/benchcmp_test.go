@@ -0,0 +1,89 @@
+// Copyright 2019 The OPA Authors.  All rights reserved.
+// Use of this source code is governed by an Apache2
+// license that can be found in the LICENSE file.
+
+package benchcmp
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const testOutput = `goos: linux
+goarch: amd64
+pkg: github.com/open-policy-agent/opa/test/benchmarks
+BenchmarkFoo-8   	     288	   4571544 ns/op	 1136446 B/op	   30458 allocs/op
+BenchmarkFoo-8   	     300	   4000000 ns/op	 1136446 B/op	   30458 allocs/op
+BenchmarkBar     	    1520	    702297 ns/op
+BenchmarkBaz-8   	--- FAIL: BenchmarkBaz
+PASS
+ok  	github.com/open-policy-agent/opa/test/benchmarks	10.939s
+`
+
+func TestParse(t *testing.T) {
+
+	result, err := Parse(strings.NewReader(testOutput))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := Baseline{
+		"BenchmarkFoo": {NsPerOp: 4000000, BytesPerOp: 1136446, AllocsPerOp: 30458},
+		"BenchmarkBar": {NsPerOp: 702297},
+	}
+
+	if !reflect.DeepEqual(result, expected) {
+		t.Fatalf("Expected %v but got %v", expected, result)
+	}
+}
+
+func TestCompare(t *testing.T) {
+
+	baseline := Baseline{
+		"BenchmarkFoo": {NsPerOp: 100, AllocsPerOp: 10},
+		"BenchmarkBar": {NsPerOp: 100, AllocsPerOp: 10},
+		"BenchmarkBaz": {NsPerOp: 100},
+	}
+
+	current := Baseline{
+		"BenchmarkFoo": {NsPerOp: 119, AllocsPerOp: 13},
+		"BenchmarkBar": {NsPerOp: 150, AllocsPerOp: 5},
+		"BenchmarkQux": {NsPerOp: 1000},
+	}
+
+	result := Compare(baseline, current, 0.2)
+
+	expected := []Regression{
+		{Name: "BenchmarkBar", Unit: "ns/op", Baseline: 100, Current: 150},
+		{Name: "BenchmarkFoo", Unit: "allocs/op", Baseline: 10, Current: 13},
+	}
+
+	if !reflect.DeepEqual(result, expected) {
+		t.Fatalf("Expected %v but got %v", expected, result)
+	}
+
+	if s := result[0].String(); s != "BenchmarkBar: ns/op 100 -> 150 (+50.0%)" {
+		t.Fatalf("Unexpected string: %v", s)
+	}
+}
+
+func TestBaselineRoundTrip(t *testing.T) {
+
+	baseline := Baseline{"BenchmarkFoo": {NsPerOp: 100, AllocsPerOp: 10}}
+
+	var buf bytes.Buffer
+	if err := WriteBaseline(&buf, baseline); err != nil {
+		t.Fatal(err)
+	}
+
+	result, err := ReadBaseline(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !reflect.DeepEqual(result, baseline) {
+		t.Fatalf("Expected %v but got %v", baseline, result)
+	}
+}
@@ -0,0 +1,128 @@
+// Copyright 2020 The OPA Authors.  All rights reserved.
+// Use of this source code is governed by an Apache2
+// license that can be found in the LICENSE file.
+
+// Package capi implements the evaluation core exposed by the OPA shared
+// library. The functions in this package exchange JSON documents and opaque
+// handles so that the cgo wrappers never pass Go pointers to C.
+package capi
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sync"
+
+	"github.com/open-policy-agent/opa/rego"
+	"github.com/open-policy-agent/opa/storage/inmem"
+	"github.com/open-policy-agent/opa/util"
+)
+
+// ABIVersion is the version of the C ABI. It is incremented when a function
+// is removed or its signature or ownership rules change. Adding functions or
+// optional request fields does not change the version.
+const ABIVersion = 1
+
+// CompileRequest is the JSON document accepted by Compile.
+type CompileRequest struct {
+	Query   string            `json:"query"`
+	Modules map[string]string `json:"modules,omitempty"`
+	Data    interface{}       `json:"data,omitempty"`
+}
+
+// EvalResponse is the JSON document returned by Eval.
+type EvalResponse struct {
+	Result rego.ResultSet `json:"result,omitempty"`
+}
+
+var policies = struct {
+	sync.RWMutex
+	next   uint64
+	values map[uint64]rego.PreparedEvalQuery
+}{
+	values: map[uint64]rego.PreparedEvalQuery{},
+}
+
+// Compile parses and compiles the modules and query in the request and
+// returns a handle for the prepared query. Handles are never zero. The
+// handle must be released with Release.
+func Compile(request []byte) (uint64, error) {
+
+	var req CompileRequest
+
+	if err := util.UnmarshalJSON(request, &req); err != nil {
+		return 0, fmt.Errorf("invalid compile request: %v", err)
+	}
+
+	if req.Query == "" {
+		return 0, fmt.Errorf("invalid compile request: missing query")
+	}
+
+	args := []func(*rego.Rego){rego.Query(req.Query)}
+
+	for path, src := range req.Modules {
+		args = append(args, rego.Module(path, src))
+	}
+
+	if req.Data != nil {
+		data, ok := req.Data.(map[string]interface{})
+		if !ok {
+			return 0, fmt.Errorf("invalid compile request: data must be an object")
+		}
+		args = append(args, rego.Store(inmem.NewFromObject(data)))
+	}
+
+	pq, err := rego.New(args...).PrepareForEval(context.Background())
+	if err != nil {
+		return 0, err
+	}
+
+	policies.Lock()
+	defer policies.Unlock()
+	policies.next++
+	policies.values[policies.next] = pq
+
+	return policies.next, nil
+}
+
+// Eval evaluates the prepared query identified by handle with the JSON input
+// document and returns the JSON encoded EvalResponse. If input is empty, the
+// query is evaluated without an input document. Eval may be called
+// concurrently with the same handle.
+func Eval(handle uint64, input []byte) ([]byte, error) {
+
+	policies.RLock()
+	pq, ok := policies.values[handle]
+	policies.RUnlock()
+
+	if !ok {
+		return nil, fmt.Errorf("invalid policy handle: %d", handle)
+	}
+
+	var opts []rego.EvalOption
+
+	if len(input) > 0 {
+		var x interface{}
+		if err := util.UnmarshalJSON(input, &x); err != nil {
+			return nil, fmt.Errorf("invalid input: %v", err)
+		}
+		opts = append(opts, rego.EvalInput(x))
+	}
+
+	rs, err := pq.Eval(context.Background(), opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	return json.Marshal(EvalResponse{Result: rs})
+}
+
+// Release discards the prepared query identified by handle. Release returns
+// false if the handle is unknown (e.g., it was already released).
+func Release(handle uint64) bool {
+	policies.Lock()
+	defer policies.Unlock()
+	_, ok := policies.values[handle]
+	delete(policies.values, handle)
+	return ok
+}
@@ -0,0 +1,86 @@
+// Copyright 2020 The OPA Authors.  All rights reserved.
+// Use of this source code is governed by an Apache2
+// license that can be found in the LICENSE file.
+
+package capi
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestCompileEval(t *testing.T) {
+
+	handle, err := Compile([]byte(`{
+		"query": "data.authz.allow",
+		"modules": {"authz.rego": "package authz\n\nallow { data.users[_] = input.user }"},
+		"data": {"users": ["alice"]}
+	}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer Release(handle)
+
+	tests := []struct {
+		input string
+		exp   string
+	}{
+		{`{"user": "alice"}`, `{"result":[{"expressions":[{"value":true,"text":"data.authz.allow","location":{"row":1,"col":1}}]}]}`},
+		{`{"user": "bob"}`, `{}`},
+		{``, `{}`},
+	}
+
+	for _, tc := range tests {
+		result, err := Eval(handle, []byte(tc.input))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(result) != tc.exp {
+			t.Fatalf("Expected %v for input %q but got: %v", tc.exp, tc.input, string(result))
+		}
+	}
+}
+
+func TestCompileErrors(t *testing.T) {
+
+	tests := []struct {
+		note    string
+		request string
+		exp     string
+	}{
+		{"bad json", `{`, "invalid compile request"},
+		{"missing query", `{}`, "missing query"},
+		{"bad data", `{"query": "true", "data": []}`, "data must be an object"},
+		{"bad module", `{"query": "true", "modules": {"x.rego": "package"}}`, "rego_parse_error"},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.note, func(t *testing.T) {
+			_, err := Compile([]byte(tc.request))
+			if err == nil || !strings.Contains(err.Error(), tc.exp) {
+				t.Fatalf("Expected error containing %q but got: %v", tc.exp, err)
+			}
+		})
+	}
+}
+
+func TestEvalRelease(t *testing.T) {
+
+	handle, err := Compile([]byte(`{"query": "x = input.x"}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := Eval(handle, []byte(`{`)); err == nil || !strings.Contains(err.Error(), "invalid input") {
+		t.Fatalf("Expected input error but got: %v", err)
+	}
+
+	if !Release(handle) || Release(handle) {
+		t.Fatal("Expected handle to be released exactly once")
+	}
+
+	if _, err := Eval(handle, nil); err == nil || !strings.Contains(err.Error(), "invalid policy handle") {
+		t.Fatalf("Expected handle error but got: %v", err)
+	}
+}
@@ -0,0 +1,109 @@
+// Copyright 2019 The OPA Authors.  All rights reserved.
+// Use of this source code is governed by an Apache2
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path"
+
+	"github.com/open-policy-agent/opa/internal/benchcmp"
+	"github.com/spf13/cobra"
+)
+
+type params struct {
+	Baseline  string
+	Threshold float64
+	Update    bool
+}
+
+func main() {
+
+	var params params
+	executable := path.Base(os.Args[0])
+
+	command := &cobra.Command{
+		Use:   executable,
+		Short: executable + " [benchmark output path]",
+		Long: `Compare Go benchmark results against a stored baseline.
+
+Reads the output of 'go test -bench' from the file given as an argument (or
+stdin) and exits with a non-zero status if any benchmark in the baseline
+regressed by more than the threshold. With --update, the baseline is
+overwritten with the results instead.`,
+		SilenceUsage: true,
+		RunE: func(_ *cobra.Command, args []string) error {
+			if len(args) > 1 {
+				return fmt.Errorf("provide at most one benchmark output path")
+			}
+			return run(params, args)
+		},
+	}
+
+	command.Flags().StringVarP(&params.Baseline, "baseline", "b", "test/benchmarks/baseline.json", "set path of baseline file")
+	command.Flags().Float64VarP(&params.Threshold, "threshold", "t", 0.2, "set relative increase that is considered a regression")
+	command.Flags().BoolVarP(&params.Update, "update", "u", false, "overwrite baseline with benchmark results")
+
+	if err := command.Execute(); err != nil {
+		os.Exit(1)
+	}
+}
+
+func run(params params, args []string) error {
+
+	var in io.Reader = os.Stdin
+
+	if len(args) == 1 {
+		f, err := os.Open(args[0])
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		in = f
+	}
+
+	current, err := benchcmp.Parse(in)
+	if err != nil {
+		return err
+	}
+
+	if len(current) == 0 {
+		return fmt.Errorf("no benchmark results found")
+	}
+
+	if params.Update {
+		f, err := os.Create(params.Baseline)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		return benchcmp.WriteBaseline(f, current)
+	}
+
+	f, err := os.Open(params.Baseline)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	baseline, err := benchcmp.ReadBaseline(f)
+	if err != nil {
+		return err
+	}
+
+	regressions := benchcmp.Compare(baseline, current, params.Threshold)
+
+	for _, r := range regressions {
+		fmt.Fprintln(os.Stderr, r)
+	}
+
+	if len(regressions) > 0 {
+		return fmt.Errorf("%d regression(s) exceed threshold of %.0f%%", len(regressions), params.Threshold*100)
+	}
+
+	fmt.Printf("%d benchmark(s) within threshold of %.0f%%\n", len(current), params.Threshold*100)
+	return nil
+}
@@ -0,0 +1,79 @@
+// Copyright 2020 The OPA Authors.  All rights reserved.
+// Use of this source code is governed by an Apache2
+// license that can be found in the LICENSE file.
+
+// Command libopa is built with -buildmode=c-shared to produce a shared library
+// that exposes the OPA evaluation core to non-Go applications. See opa.h for
+// the C interface and memory ownership rules.
+package main
+
+/*
+#include <stdlib.h>
+*/
+import "C"
+
+import (
+	"unsafe"
+
+	"github.com/open-policy-agent/opa/internal/capi"
+	"github.com/open-policy-agent/opa/version"
+)
+
+//export opa_abi_version
+func opa_abi_version() C.int {
+	return C.int(capi.ABIVersion)
+}
+
+//export opa_version
+func opa_version() *C.char {
+	return C.CString(version.Version)
+}
+
+//export opa_compile
+func opa_compile(request *C.char, errp **C.char) C.ulonglong {
+	if request == nil {
+		setError(errp, "invalid compile request: null")
+		return 0
+	}
+	handle, err := capi.Compile([]byte(C.GoString(request)))
+	if err != nil {
+		setError(errp, err.Error())
+		return 0
+	}
+	return C.ulonglong(handle)
+}
+
+//export opa_eval
+func opa_eval(policy C.ulonglong, input *C.char, errp **C.char) *C.char {
+	var bs []byte
+	if input != nil {
+		bs = []byte(C.GoString(input))
+	}
+	result, err := capi.Eval(uint64(policy), bs)
+	if err != nil {
+		setError(errp, err.Error())
+		return nil
+	}
+	return C.CString(string(result))
+}
+
+//export opa_release
+func opa_release(policy C.ulonglong) C.int {
+	if capi.Release(uint64(policy)) {
+		return 1
+	}
+	return 0
+}
+
+//export opa_free
+func opa_free(ptr unsafe.Pointer) {
+	C.free(ptr)
+}
+
+func setError(errp **C.char, msg string) {
+	if errp != nil {
+		*errp = C.CString(msg)
+	}
+}
+
+func main() {}
@@ -0,0 +1,74 @@
+/*
+ * Copyright 2020 The OPA Authors.  All rights reserved.
+ * Use of this source code is governed by an Apache2
+ * license that can be found in the LICENSE file.
+ */
+
+/*
+ * C interface of the OPA shared library (libopa). Build the library with:
+ *
+ *   make libopa
+ *
+ * Memory ownership:
+ *
+ *   - Strings passed to the library are borrowed for the duration of the call.
+ *     The library does not retain pointers to them.
+ *   - Every non-NULL char* returned by the library (including error messages)
+ *     is owned by the caller and must be freed with opa_free.
+ *   - Policy handles returned by opa_compile must be released with
+ *     opa_release. Handles are opaque integers; zero is never a valid handle.
+ *
+ * All functions are safe to call from multiple threads. The same policy handle
+ * may be evaluated concurrently.
+ *
+ * The ABI version returned by opa_abi_version is incremented when a function is
+ * removed or its signature or ownership rules change. Callers should check it
+ * against OPA_ABI_VERSION before calling other functions.
+ */
+
+#ifndef OPA_H
+#define OPA_H
+
+#ifdef __cplusplus
+extern "C" {
+#endif
+
+#define OPA_ABI_VERSION 1
+
+typedef unsigned long long opa_policy_t;
+
+/* Returns the ABI version implemented by the library. */
+int opa_abi_version(void);
+
+/* Returns the OPA version the library was built from. */
+char *opa_version(void);
+
+/*
+ * Compiles the policy described by the JSON request:
+ *
+ *   {"query": "data.authz.allow", "modules": {"authz.rego": "..."}, "data": {...}}
+ *
+ * Returns a policy handle or zero on failure. On failure, *error is set to the
+ * error message if error is not NULL.
+ */
+opa_policy_t opa_compile(char *request, char **error);
+
+/*
+ * Evaluates the policy with the JSON input document (or no input if input is
+ * NULL) and returns the JSON result: {"result": [...]}. The result is omitted
+ * if the query is undefined. Returns NULL on failure and sets *error to the
+ * error message if error is not NULL.
+ */
+char *opa_eval(opa_policy_t policy, char *input, char **error);
+
+/* Releases the policy. Returns zero if the handle was unknown. */
+int opa_release(opa_policy_t policy);
+
+/* Frees a string returned by the library. */
+void opa_free(void *ptr);
+
+#ifdef __cplusplus
+}
+#endif
+
+#endif
@@ -0,0 +1,526 @@
+// Copyright 2020 The OPA Authors.  All rights reserved.
+// Use of this source code is governed by an Apache2
+// license that can be found in the LICENSE file.
+
+// Package dap implements a Debug Adapter Protocol server for Rego. Editors
+// use the server to set breakpoints in policy files, step through the
+// evaluation of a query, and inspect the variables bound in each query.
+package dap
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"path/filepath"
+	"sort"
+	"strconv"
+
+	"github.com/open-policy-agent/opa/ast"
+	"github.com/open-policy-agent/opa/internal/baseprotocol"
+	"github.com/open-policy-agent/opa/loader"
+	"github.com/open-policy-agent/opa/rego"
+	"github.com/open-policy-agent/opa/storage/inmem"
+	"github.com/open-policy-agent/opa/topdown"
+	"github.com/open-policy-agent/opa/topdown/debug"
+	"github.com/open-policy-agent/opa/util"
+)
+
+// Server implements a debug adapter that communicates over a pair of streams
+// (e.g., stdin and stdout). The server evaluates the query from the launch
+// request once. Execution cannot be paused while the query is running: it
+// stops at breakpoints and after steps.
+type Server struct {
+	in  *baseprotocol.Reader
+	out io.Writer
+	seq int
+
+	compiler    *ast.Compiler
+	session     *debug.Session
+	result      rego.ResultSet
+	stopOnEntry bool
+	configured  bool
+	breakpoints map[string][]debug.Breakpoint
+
+	// refs contains the values that the client can request the variables of.
+	// A reference is the index of the value plus one. The references are
+	// only valid while evaluation is stopped.
+	refs []interface{}
+}
+
+// New returns a new Server that reads messages from r and writes messages to w.
+func New(r io.Reader, w io.Writer) *Server {
+	return &Server{
+		in:          baseprotocol.NewReader(r),
+		out:         w,
+		breakpoints: map[string][]debug.Breakpoint{},
+	}
+}
+
+// Serve processes messages until the client sends the disconnect request or
+// closes the input stream.
+func (s *Server) Serve() error {
+
+	defer func() {
+		if s.session != nil {
+			s.session.Abort()
+		}
+	}()
+
+	for {
+		bs, err := s.in.Read()
+		if err == io.EOF {
+			return nil
+		} else if err != nil {
+			return err
+		}
+
+		var req request
+		if err := json.Unmarshal(bs, &req); err != nil {
+			return fmt.Errorf("invalid message: %v", err)
+		}
+
+		if req.Type != typeRequest {
+			continue
+		}
+
+		body, after, err := s.handle(req)
+
+		resp := response{
+			Type:       typeResponse,
+			RequestSeq: req.Seq,
+			Success:    err == nil,
+			Command:    req.Command,
+			Body:       body,
+		}
+
+		if err != nil {
+			resp.Message = err.Error()
+		}
+
+		if err := s.write(&resp); err != nil {
+			return err
+		}
+
+		// Events caused by the request (e.g., the stopped event after a
+		// step) are sent after the response.
+		if after != nil {
+			if err := after(); err != nil {
+				return err
+			}
+		}
+
+		if req.Command == "disconnect" {
+			return nil
+		}
+	}
+}
+
+// handle returns the body of the response to req and optionally a function
+// to call after the response has been sent.
+func (s *Server) handle(req request) (interface{}, func() error, error) {
+	switch req.Command {
+	case "initialize":
+		return capabilities{SupportsConfigurationDoneRequest: true}, func() error {
+			return s.sendEvent("initialized", nil)
+		}, nil
+	case "launch":
+		var args launchArguments
+		if err := unmarshalArgs(req.Arguments, &args); err != nil {
+			return nil, nil, err
+		}
+		if err := s.launch(args); err != nil {
+			return nil, nil, err
+		}
+		if s.configured {
+			return nil, s.start, nil
+		}
+		return nil, nil, nil
+	case "setBreakpoints":
+		var args setBreakpointsArguments
+		if err := unmarshalArgs(req.Arguments, &args); err != nil {
+			return nil, nil, err
+		}
+		return s.setBreakpoints(args), nil, nil
+	case "configurationDone":
+		s.configured = true
+		if s.session != nil {
+			return nil, s.start, nil
+		}
+		return nil, nil, nil
+	case "threads":
+		return threadsResponseBody{Threads: []thread{{ID: threadID, Name: "main"}}}, nil, nil
+	case "stackTrace":
+		return s.stackTrace(), nil, nil
+	case "scopes":
+		var args scopesArguments
+		if err := unmarshalArgs(req.Arguments, &args); err != nil {
+			return nil, nil, err
+		}
+		return s.scopes(args)
+	case "variables":
+		var args variablesArguments
+		if err := unmarshalArgs(req.Arguments, &args); err != nil {
+			return nil, nil, err
+		}
+		return s.variables(args)
+	case "continue":
+		return s.resume(func(session *debug.Session) (*debug.Stop, error) { return session.Continue() })
+	case "next":
+		return s.resume(func(session *debug.Session) (*debug.Stop, error) { return session.Next() })
+	case "stepIn":
+		return s.resume(func(session *debug.Session) (*debug.Stop, error) { return session.Step() })
+	case "stepOut":
+		return s.resume(func(session *debug.Session) (*debug.Stop, error) { return session.Out() })
+	case "disconnect":
+		if s.session != nil {
+			s.session.Abort()
+			s.session = nil
+		}
+		return nil, nil, nil
+	}
+	return nil, nil, fmt.Errorf("unsupported command: %v", req.Command)
+}
+
+func (s *Server) launch(args launchArguments) error {
+
+	if s.session != nil {
+		return fmt.Errorf("already launched")
+	}
+
+	if args.Query == "" {
+		return fmt.Errorf("missing query")
+	}
+
+	query, err := ast.ParseBody(args.Query)
+	if err != nil {
+		return err
+	}
+
+	// Files are loaded by absolute path so that the locations of expressions
+	// match the paths of the sources that clients set breakpoints in.
+	paths := make([]string, len(args.Data))
+	for i := range args.Data {
+		if paths[i], err = filepath.Abs(args.Data[i]); err != nil {
+			return err
+		}
+	}
+
+	result, err := loader.All(paths)
+	if err != nil {
+		return err
+	}
+
+	compiler := ast.NewCompiler()
+	if compiler.Compile(result.ParsedModules()); compiler.Failed() {
+		return compiler.Errors
+	}
+
+	var input interface{}
+
+	if args.Input != "" {
+		bs, err := ioutil.ReadFile(args.Input)
+		if err != nil {
+			return err
+		}
+		if err := util.Unmarshal(bs, &input); err != nil {
+			return fmt.Errorf("%v: %v", args.Input, err)
+		}
+	}
+
+	store := inmem.NewFromObject(result.Documents)
+
+	s.compiler = compiler
+	s.stopOnEntry = args.StopOnEntry
+	s.session = debug.NewSession(context.Background(), func(ctx context.Context, tracer topdown.Tracer) error {
+		var err error
+		s.result, err = rego.New(
+			rego.ParsedQuery(query),
+			rego.Compiler(compiler),
+			rego.Store(store),
+			rego.Input(input),
+			rego.Tracer(tracer),
+		).Eval(ctx)
+		return err
+	})
+
+	s.session.SetBreakpoints(s.allBreakpoints())
+
+	// Breakpoints set before launch are verified now that the policies have
+	// been loaded.
+	for path, bps := range s.breakpoints {
+		for _, bp := range bps {
+			if !s.verify(path, bp.Row) {
+				msg := fmt.Sprintf("no expression or rule on line %d", bp.Row)
+				if err := s.sendEvent("output", outputEventBody{Category: "console", Output: fmt.Sprintf("%v:%d: %v\n", path, bp.Row, msg)}); err != nil {
+					return err
+				}
+			}
+		}
+	}
+
+	return nil
+}
+
+func (s *Server) start() error {
+	if s.stopOnEntry {
+		return s.stopped("entry")(s.session.Step())
+	}
+	return s.stopped("")(s.session.Continue())
+}
+
+func (s *Server) resume(f func(*debug.Session) (*debug.Stop, error)) (interface{}, func() error, error) {
+	if s.session == nil || s.session.Current() == nil {
+		return nil, nil, fmt.Errorf("not stopped")
+	}
+	s.refs = nil
+	return nil, func() error {
+		return s.stopped("step")(f(s.session))
+	}, nil
+}
+
+// stopped returns a function that sends the events for the result of
+// resuming evaluation. If evaluation stopped, the stopped event is sent with
+// the reason (or "breakpoint" if a breakpoint was hit). Otherwise the result
+// is sent as output followed by the exited and terminated events.
+func (s *Server) stopped(reason string) func(*debug.Stop, error) error {
+	return func(stop *debug.Stop, err error) error {
+
+		if stop != nil {
+			if stop.Breakpoint != nil || reason == "" {
+				reason = "breakpoint"
+			}
+			return s.sendEvent("stopped", stoppedEventBody{Reason: reason, ThreadID: threadID, AllThreadsStopped: true})
+		}
+
+		exitCode := 0
+		output := outputEventBody{Category: "stdout"}
+
+		if err != nil {
+			exitCode = 1
+			output.Category = "stderr"
+			output.Output = err.Error() + "\n"
+		} else {
+			bs, err := json.MarshalIndent(s.result, "", "  ")
+			if err != nil {
+				return err
+			}
+			output.Output = string(bs) + "\n"
+		}
+
+		s.session = nil
+
+		if err := s.sendEvent("output", output); err != nil {
+			return err
+		}
+
+		if err := s.sendEvent("exited", exitedEventBody{ExitCode: exitCode}); err != nil {
+			return err
+		}
+
+		return s.sendEvent("terminated", nil)
+	}
+}
+
+func (s *Server) setBreakpoints(args setBreakpointsArguments) setBreakpointsResponseBody {
+
+	path := args.Source.Path
+	body := setBreakpointsResponseBody{Breakpoints: []breakpoint{}}
+	var bps []debug.Breakpoint
+
+	for _, sb := range args.Breakpoints {
+		bps = append(bps, debug.Breakpoint{File: path, Row: sb.Line})
+		bp := breakpoint{Verified: s.verify(path, sb.Line), Line: sb.Line}
+		if !bp.Verified {
+			bp.Message = fmt.Sprintf("no expression or rule on line %d", sb.Line)
+		}
+		body.Breakpoints = append(body.Breakpoints, bp)
+	}
+
+	s.breakpoints[path] = bps
+
+	if s.session != nil {
+		s.session.SetBreakpoints(s.allBreakpoints())
+	}
+
+	return body
+}
+
+func (s *Server) allBreakpoints() []debug.Breakpoint {
+	var result []debug.Breakpoint
+	for _, bps := range s.breakpoints {
+		result = append(result, bps...)
+	}
+	return result
+}
+
+// verify returns true if an expression or rule starts on the row of the file.
+// If the policies have not been loaded yet, breakpoints cannot be verified and
+// verify returns true.
+func (s *Server) verify(path string, row int) bool {
+
+	if s.compiler == nil {
+		return true
+	}
+
+	var found bool
+
+	for _, module := range s.compiler.Modules {
+		for _, rule := range module.Rules {
+			if rule.Location == nil || rule.Location.File != path {
+				continue
+			}
+			ast.WalkRules(rule, func(r *ast.Rule) bool {
+				found = found || r.Location.Row == row
+				return found
+			})
+			ast.WalkExprs(rule, func(expr *ast.Expr) bool {
+				found = found || (expr.Location != nil && expr.Location.Row == row)
+				return found
+			})
+		}
+	}
+
+	return found
+}
+
+func (s *Server) stackTrace() stackTraceResponseBody {
+
+	body := stackTraceResponseBody{StackFrames: []stackFrame{}}
+
+	if s.session == nil || s.session.Current() == nil {
+		return body
+	}
+
+	for i, event := range s.session.Current().Stack {
+		stop := debug.Stop{Event: event}
+		frame := stackFrame{
+			ID:   i + 1,
+			Name: fmt.Sprintf("%v %v", event.Op, stop.Node()),
+		}
+		if loc := stop.Location(); loc != nil {
+			frame.Line = loc.Row
+			frame.Column = loc.Col
+			if loc.File != "" {
+				frame.Source = &source{Name: filepath.Base(loc.File), Path: loc.File}
+			}
+		}
+		body.StackFrames = append(body.StackFrames, frame)
+	}
+
+	body.TotalFrames = len(body.StackFrames)
+
+	return body
+}
+
+func (s *Server) scopes(args scopesArguments) (interface{}, func() error, error) {
+
+	if s.session == nil || s.session.Current() == nil {
+		return nil, nil, fmt.Errorf("not stopped")
+	}
+
+	stack := s.session.Current().Stack
+
+	if args.FrameID < 1 || args.FrameID > len(stack) {
+		return nil, nil, fmt.Errorf("invalid frame: %d", args.FrameID)
+	}
+
+	return scopesResponseBody{Scopes: []scope{
+		{Name: "Locals", VariablesReference: s.ref(&debug.Stop{Event: stack[args.FrameID-1]})},
+	}}, nil, nil
+}
+
+func (s *Server) variables(args variablesArguments) (interface{}, func() error, error) {
+
+	if args.VariablesReference < 1 || args.VariablesReference > len(s.refs) {
+		return nil, nil, fmt.Errorf("invalid variables reference: %d", args.VariablesReference)
+	}
+
+	body := variablesResponseBody{Variables: []variable{}}
+
+	switch x := s.refs[args.VariablesReference-1].(type) {
+	case *debug.Stop:
+		if expr := x.Resolved(); expr != nil {
+			body.Variables = append(body.Variables, variable{Name: "(expression)", Value: expr.String()})
+		}
+		for _, b := range x.Bindings() {
+			body.Variables = append(body.Variables, s.variable(string(b.Name), b.Value))
+		}
+	case ast.Object:
+		keys := x.Keys()
+		sort.Slice(keys, func(i, j int) bool {
+			return keys[i].Value.Compare(keys[j].Value) < 0
+		})
+		for _, k := range keys {
+			body.Variables = append(body.Variables, s.variable(k.String(), x.Get(k).Value))
+		}
+	case ast.Array:
+		for i := range x {
+			body.Variables = append(body.Variables, s.variable(strconv.Itoa(i), x[i].Value))
+		}
+	case ast.Set:
+		for i, elem := range x.Sorted() {
+			body.Variables = append(body.Variables, s.variable(strconv.Itoa(i), elem.Value))
+		}
+	}
+
+	return body, nil, nil
+}
+
+// variable returns a variable for the value. Objects, arrays, and sets are
+// given references so that clients can expand them.
+func (s *Server) variable(name string, value ast.Value) variable {
+	v := variable{Name: name, Value: value.String()}
+	switch value := value.(type) {
+	case ast.Object:
+		if value.Len() > 0 {
+			v.VariablesReference = s.ref(value)
+		}
+	case ast.Array:
+		if len(value) > 0 {
+			v.VariablesReference = s.ref(value)
+		}
+	case ast.Set:
+		if value.Len() > 0 {
+			v.VariablesReference = s.ref(value)
+		}
+	}
+	return v
+}
+
+func (s *Server) ref(x interface{}) int {
+	s.refs = append(s.refs, x)
+	return len(s.refs)
+}
+
+func (s *Server) sendEvent(name string, body interface{}) error {
+	return s.write(&event{Type: typeEvent, Event: name, Body: body})
+}
+
+// write assigns the next sequence number to the response or event and writes
+// it to the output stream.
+func (s *Server) write(msg interface{}) error {
+
+	s.seq++
+
+	switch msg := msg.(type) {
+	case *response:
+		msg.Seq = s.seq
+	case *event:
+		msg.Seq = s.seq
+	}
+
+	bs, err := json.Marshal(msg)
+	if err != nil {
+		return err
+	}
+	return baseprotocol.Write(s.out, bs)
+}
+
+func unmarshalArgs(raw json.RawMessage, x interface{}) error {
+	if len(raw) == 0 {
+		return nil
+	}
+	return json.Unmarshal(raw, x)
+}
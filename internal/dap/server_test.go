@@ -0,0 +1,202 @@
+// Copyright 2020 The OPA Authors.  All rights reserved.
+// Use of this source code is governed by an Apache2
+// license that can be found in the LICENSE file.
+
+package dap
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/open-policy-agent/opa/internal/baseprotocol"
+	"github.com/open-policy-agent/opa/util/test"
+)
+
+const testModule = `package authz
+
+allow {
+	user := input.user
+	is_admin(user)
+}
+
+is_admin(user) {
+	data.admins[_] = user
+}
+`
+
+func TestServer(t *testing.T) {
+
+	files := map[string]string{
+		"authz.rego": testModule,
+		"data.json":  `{"admins": ["alice", "bob"]}`,
+		"input.json": `{"user": "bob"}`,
+	}
+
+	test.WithTempFS(files, func(root string) {
+
+		path := filepath.Join(root, "authz.rego")
+		launch := fmt.Sprintf(`{"query": "data.authz.allow", "data": [%q, %q], "input": %q}`,
+			path, filepath.Join(root, "data.json"), filepath.Join(root, "input.json"))
+
+		msgs := run(t,
+			`{"seq": 1, "type": "request", "command": "initialize", "arguments": {"adapterID": "opa"}}`,
+			`{"seq": 2, "type": "request", "command": "launch", "arguments": `+launch+`}`,
+			fmt.Sprintf(`{"seq": 3, "type": "request", "command": "setBreakpoints", "arguments": {"source": {"path": %q}, "breakpoints": [{"line": 9}, {"line": 7}]}}`, path),
+			`{"seq": 4, "type": "request", "command": "configurationDone"}`,
+			`{"seq": 5, "type": "request", "command": "threads"}`,
+			`{"seq": 6, "type": "request", "command": "stackTrace", "arguments": {"threadId": 1}}`,
+			`{"seq": 7, "type": "request", "command": "scopes", "arguments": {"frameId": 1}}`,
+			`{"seq": 8, "type": "request", "command": "variables", "arguments": {"variablesReference": 1}}`,
+			`{"seq": 9, "type": "request", "command": "stepOut", "arguments": {"threadId": 1}}`,
+			`{"seq": 10, "type": "request", "command": "continue", "arguments": {"threadId": 1}}`,
+			`{"seq": 11, "type": "request", "command": "unknown"}`,
+			`{"seq": 12, "type": "request", "command": "disconnect"}`,
+			`{"seq": 13, "type": "request", "command": "threads"}`,
+		)
+
+		file := fmt.Sprintf(`{"name":"authz.rego","path":%q}`, path)
+
+		expected := []string{
+			`{"seq":1,"type":"response","request_seq":1,"success":true,"command":"initialize","body":{"supportsConfigurationDoneRequest":true}}`,
+			`{"seq":2,"type":"event","event":"initialized"}`,
+			`{"seq":3,"type":"response","request_seq":2,"success":true,"command":"launch"}`,
+			`{"seq":4,"type":"response","request_seq":3,"success":true,"command":"setBreakpoints","body":{"breakpoints":[{"verified":true,"line":9},{"verified":false,"line":7,"message":"no expression or rule on line 7"}]}}`,
+			`{"seq":5,"type":"response","request_seq":4,"success":true,"command":"configurationDone"}`,
+			`{"seq":6,"type":"event","event":"stopped","body":{"reason":"breakpoint","threadId":1,"allThreadsStopped":true}}`,
+			`{"seq":7,"type":"response","request_seq":5,"success":true,"command":"threads","body":{"threads":[{"id":1,"name":"main"}]}}`,
+			`{"seq":8,"type":"response","request_seq":6,"success":true,"command":"stackTrace","body":{"stackFrames":[` +
+				`{"id":1,"name":"Eval data.admins[_] = user","source":` + file + `,"line":9,"column":2},` +
+				`{"id":2,"name":"Eval data.authz.is_admin(user)","source":` + file + `,"line":5,"column":2},` +
+				`{"id":3,"name":"Eval data.authz.allow = _","line":1,"column":1}],"totalFrames":3}}`,
+			`{"seq":9,"type":"response","request_seq":7,"success":true,"command":"scopes","body":{"scopes":[{"name":"Locals","variablesReference":1,"expensive":false}]}}`,
+			`{"seq":10,"type":"response","request_seq":8,"success":true,"command":"variables","body":{"variables":[{"name":"(expression)","value":"data.admins[_] = \"bob\"","variablesReference":0},{"name":"user","value":"\"bob\"","variablesReference":0}]}}`,
+			`{"seq":11,"type":"response","request_seq":9,"success":true,"command":"stepOut"}`,
+			`{"seq":12,"type":"event","event":"stopped","body":{"reason":"step","threadId":1,"allThreadsStopped":true}}`,
+			`{"seq":13,"type":"response","request_seq":10,"success":true,"command":"continue"}`,
+			`{"seq":14,"type":"event","event":"output","body":{"category":"stdout","output":"[\n  {\n    \"expressions\": [\n      {\n        \"value\": true,\n        \"text\": \"data.authz.allow\",\n        \"location\": {\n          \"row\": 1,\n          \"col\": 1\n        }\n      }\n    ]\n  }\n]\n"}}`,
+			`{"seq":15,"type":"event","event":"exited","body":{"exitCode":0}}`,
+			`{"seq":16,"type":"event","event":"terminated"}`,
+			`{"seq":17,"type":"response","request_seq":11,"success":false,"command":"unknown","message":"unsupported command: unknown"}`,
+			`{"seq":18,"type":"response","request_seq":12,"success":true,"command":"disconnect"}`,
+		}
+
+		if len(msgs) != len(expected) {
+			t.Fatalf("Expected %d messages but got %d:\n%v", len(expected), len(msgs), strings.Join(msgs, "\n"))
+		}
+
+		for i := range expected {
+			if msgs[i] != expected[i] {
+				t.Errorf("Expected message %d to be:\n\n%v\n\nGot:\n\n%v", i, expected[i], msgs[i])
+			}
+		}
+	})
+}
+
+func TestServerStopOnEntry(t *testing.T) {
+
+	test.WithTempFS(map[string]string{"authz.rego": testModule}, func(root string) {
+
+		msgs := run(t,
+			`{"seq": 1, "type": "request", "command": "configurationDone"}`,
+			fmt.Sprintf(`{"seq": 2, "type": "request", "command": "launch", "arguments": {"query": "data.authz.allow", "data": [%q], "stopOnEntry": true}}`, root),
+			`{"seq": 3, "type": "request", "command": "next", "arguments": {"threadId": 1}}`,
+			`{"seq": 4, "type": "request", "command": "variables", "arguments": {"variablesReference": 1}}`,
+		)
+
+		exp := []string{
+			`{"seq":1,"type":"response","request_seq":1,"success":true,"command":"configurationDone"}`,
+			`{"seq":2,"type":"response","request_seq":2,"success":true,"command":"launch"}`,
+			`{"seq":3,"type":"event","event":"stopped","body":{"reason":"entry","threadId":1,"allThreadsStopped":true}}`,
+			`{"seq":4,"type":"response","request_seq":3,"success":true,"command":"next"}`,
+			`{"seq":5,"type":"event","event":"stopped","body":{"reason":"step","threadId":1,"allThreadsStopped":true}}`,
+			`{"seq":6,"type":"response","request_seq":4,"success":false,"command":"variables","message":"invalid variables reference: 1"}`,
+		}
+
+		if len(msgs) != len(exp) {
+			t.Fatalf("Expected %d messages but got %d:\n%v", len(exp), len(msgs), strings.Join(msgs, "\n"))
+		}
+
+		for i := range exp {
+			if msgs[i] != exp[i] {
+				t.Errorf("Expected message %d to be:\n\n%v\n\nGot:\n\n%v", i, exp[i], msgs[i])
+			}
+		}
+	})
+}
+
+func TestServerLaunchErrors(t *testing.T) {
+
+	msgs := run(t,
+		`{"seq": 1, "type": "request", "command": "launch", "arguments": {}}`,
+		`{"seq": 2, "type": "request", "command": "launch", "arguments": {"query": "data.x", "data": ["does-not-exist.rego"]}}`,
+		`{"seq": 3, "type": "request", "command": "stackTrace", "arguments": {"threadId": 1}}`,
+	)
+
+	if len(msgs) != 3 {
+		t.Fatalf("Expected three messages but got: %v", msgs)
+	}
+
+	if !strings.Contains(msgs[0], `"success":false`) || !strings.Contains(msgs[0], `"message":"missing query"`) {
+		t.Fatalf("Expected missing query error but got: %v", msgs[0])
+	}
+
+	if !strings.Contains(msgs[1], `"success":false`) || !strings.Contains(msgs[1], "does-not-exist.rego") {
+		t.Fatalf("Expected load error but got: %v", msgs[1])
+	}
+
+	if exp := `"body":{"stackFrames":[],"totalFrames":0}`; !strings.Contains(msgs[2], exp) {
+		t.Fatalf("Expected empty stack trace but got: %v", msgs[2])
+	}
+}
+
+func TestServerInvalidContentLength(t *testing.T) {
+	for _, n := range []int{-1, baseprotocol.MaxContentLength + 1} {
+		in := bytes.NewBufferString(fmt.Sprintf("Content-Length: %d\r\n\r\n{}", n))
+		var out bytes.Buffer
+		err := New(in, &out).Serve()
+		if err == nil || !strings.Contains(err.Error(), "invalid Content-Length header") {
+			t.Fatalf("Expected Content-Length error for %d but got: %v", n, err)
+		}
+	}
+}
+
+// run sends the messages to a new server and returns the messages written by
+// the server.
+func run(t *testing.T, msgs ...string) []string {
+	t.Helper()
+
+	var in, out bytes.Buffer
+
+	for _, msg := range msgs {
+		if err := baseprotocol.Write(&in, []byte(msg)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := New(&in, &out).Serve(); err != nil {
+		t.Fatal(err)
+	}
+
+	var result []string
+	r := baseprotocol.NewReader(&out)
+
+	for {
+		bs, err := r.Read()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			t.Fatal(err)
+		}
+		if !json.Valid(bs) {
+			t.Fatalf("Expected valid JSON but got: %s", bs)
+		}
+		result = append(result, string(bs))
+	}
+
+	return result
+}
@@ -0,0 +1,237 @@
+// Copyright 2020 The OPA Authors.  All rights reserved.
+// Use of this source code is governed by an Apache2
+// license that can be found in the LICENSE file.
+
+// Package jsonfield resolves the object keys of struct fields the same way
+// that encoding/json does.
+package jsonfield
+
+import (
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// IsEmpty returns true if v is empty according to the omitempty option.
+func IsEmpty(v reflect.Value) bool {
+	switch v.Kind() {
+	case reflect.Array, reflect.Map, reflect.Slice, reflect.String:
+		return v.Len() == 0
+	case reflect.Bool:
+		return !v.Bool()
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return v.Int() == 0
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return v.Uint() == 0
+	case reflect.Float32, reflect.Float64:
+		return v.Float() == 0
+	case reflect.Interface, reflect.Ptr:
+		return v.IsNil()
+	}
+	return false
+}
+
+// Field describes a struct field that is represented by an object key. The
+// index is the path to the field through embedded structs.
+type Field struct {
+	Name      string
+	Index     []int
+	Tagged    bool
+	OmitEmpty bool
+	AsString  bool
+}
+
+// Value returns the field of the struct v. If the field is promoted from an
+// embedded struct pointer that is nil, false is returned.
+func (f Field) Value(v reflect.Value) (reflect.Value, bool) {
+	for _, i := range f.Index {
+		if v.Kind() == reflect.Ptr {
+			if v.IsNil() {
+				return reflect.Value{}, false
+			}
+			v = v.Elem()
+		}
+		v = v.Field(i)
+	}
+	return v, true
+}
+
+// Alloc returns the field of the struct v. Embedded struct pointers that are
+// nil are allocated. If a nil pointer cannot be set (because the embedded
+// struct type is unexported), false is returned.
+func (f Field) Alloc(v reflect.Value) (reflect.Value, bool) {
+	for _, i := range f.Index {
+		if v.Kind() == reflect.Ptr {
+			if v.IsNil() {
+				if !v.CanSet() {
+					return reflect.Value{}, false
+				}
+				v.Set(reflect.New(v.Type().Elem()))
+			}
+			v = v.Elem()
+		}
+		v = v.Field(i)
+	}
+	return v, true
+}
+
+var cache sync.Map // map[reflect.Type][]Field
+
+// Fields returns the fields of the struct type t that are represented by
+// object keys. Fields of embedded structs are promoted following the
+// encoding/json rules: the least nested field with a name wins and, at the
+// same depth, a tagged field wins over untagged fields. Otherwise the
+// conflicting fields are dropped.
+func Fields(t reflect.Type) []Field {
+	if fields, ok := cache.Load(t); ok {
+		return fields.([]Field)
+	}
+	fields, _ := cache.LoadOrStore(t, typeFields(t))
+	return fields.([]Field)
+}
+
+func typeFields(t reflect.Type) []Field {
+
+	var all []Field
+	collectFields(t, nil, map[reflect.Type]bool{}, &all)
+
+	byName := map[string][]Field{}
+	var names []string
+
+	for _, f := range all {
+		if _, ok := byName[f.Name]; !ok {
+			names = append(names, f.Name)
+		}
+		byName[f.Name] = append(byName[f.Name], f)
+	}
+
+	result := make([]Field, 0, len(names))
+
+	for _, name := range names {
+		if f, ok := dominantField(byName[name]); ok {
+			result = append(result, f)
+		}
+	}
+
+	sort.SliceStable(result, func(i, j int) bool {
+		return lessIndex(result[i].Index, result[j].Index)
+	})
+
+	return result
+}
+
+func collectFields(t reflect.Type, index []int, visited map[reflect.Type]bool, result *[]Field) {
+
+	if visited[t] {
+		return
+	}
+
+	visited[t] = true
+	defer delete(visited, t)
+
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+
+		tag := sf.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+
+		ft := sf.Type
+		if ft.Kind() == reflect.Ptr && sf.Anonymous {
+			ft = ft.Elem()
+		}
+
+		if sf.PkgPath != "" && !(sf.Anonymous && ft.Kind() == reflect.Struct) {
+			continue
+		}
+
+		name, opts := tag, ""
+		if idx := strings.Index(tag, ","); idx >= 0 {
+			name, opts = tag[:idx], tag[idx+1:]
+		}
+
+		fieldIndex := make([]int, len(index)+1)
+		copy(fieldIndex, index)
+		fieldIndex[len(index)] = i
+
+		if name == "" && sf.Anonymous && ft.Kind() == reflect.Struct {
+			collectFields(ft, fieldIndex, visited, result)
+			continue
+		}
+
+		if sf.PkgPath != "" {
+			continue
+		}
+
+		f := Field{
+			Name:   name,
+			Index:  fieldIndex,
+			Tagged: name != "",
+		}
+
+		if f.Name == "" {
+			f.Name = sf.Name
+		}
+
+		for _, opt := range strings.Split(opts, ",") {
+			switch opt {
+			case "omitempty":
+				f.OmitEmpty = true
+			case "string":
+				switch ft.Kind() {
+				case reflect.Bool, reflect.String,
+					reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+					reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr,
+					reflect.Float32, reflect.Float64:
+					f.AsString = true
+				}
+			}
+		}
+
+		*result = append(*result, f)
+	}
+}
+
+func dominantField(fields []Field) (Field, bool) {
+	depth := len(fields[0].Index)
+	for _, f := range fields[1:] {
+		if len(f.Index) < depth {
+			depth = len(f.Index)
+		}
+	}
+
+	var candidates []Field
+	for _, f := range fields {
+		if len(f.Index) == depth {
+			candidates = append(candidates, f)
+		}
+	}
+
+	if len(candidates) == 1 {
+		return candidates[0], true
+	}
+
+	var tagged []Field
+	for _, f := range candidates {
+		if f.Tagged {
+			tagged = append(tagged, f)
+		}
+	}
+
+	if len(tagged) == 1 {
+		return tagged[0], true
+	}
+
+	return Field{}, false
+}
+
+func lessIndex(a, b []int) bool {
+	for i := 0; i < len(a) && i < len(b); i++ {
+		if a[i] != b[i] {
+			return a[i] < b[i]
+		}
+	}
+	return len(a) < len(b)
+}
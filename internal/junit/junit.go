@@ -0,0 +1,129 @@
+// Copyright 2020 The OPA Authors.  All rights reserved.
+// Use of this source code is governed by an Apache2
+// license that can be found in the LICENSE file.
+
+// Package junit implements JUnit XML reports that can be consumed by CI
+// systems.
+package junit
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"time"
+)
+
+// Testsuites is the root element of a JUnit XML report.
+type Testsuites struct {
+	XMLName  xml.Name     `xml:"testsuites"`
+	Name     string       `xml:"name,attr,omitempty"`
+	Tests    int          `xml:"tests,attr"`
+	Failures int          `xml:"failures,attr"`
+	Errors   int          `xml:"errors,attr"`
+	Time     string       `xml:"time,attr"`
+	Suites   []*Testsuite `xml:"testsuite"`
+}
+
+// Testsuite contains the test cases of a single suite (e.g., a Rego package.)
+type Testsuite struct {
+	Name      string      `xml:"name,attr"`
+	Tests     int         `xml:"tests,attr"`
+	Failures  int         `xml:"failures,attr"`
+	Errors    int         `xml:"errors,attr"`
+	Time      string      `xml:"time,attr"`
+	Testcases []*Testcase `xml:"testcase"`
+
+	duration time.Duration
+}
+
+// Testcase represents the outcome of a single test case. If Failure and Error
+// are nil, the test case passed.
+type Testcase struct {
+	Name      string  `xml:"name,attr"`
+	Classname string  `xml:"classname,attr"`
+	Time      string  `xml:"time,attr"`
+	File      string  `xml:"file,attr,omitempty"`
+	Line      int     `xml:"line,attr,omitempty"`
+	Failure   *Result `xml:"failure,omitempty"`
+	Error     *Result `xml:"error,omitempty"`
+
+	duration time.Duration
+}
+
+// Result contains the details of a failed or errored test case.
+type Result struct {
+	Message string `xml:"message,attr"`
+	Type    string `xml:"type,attr,omitempty"`
+	Text    string `xml:",chardata"`
+}
+
+// NewTestcase returns a new test case that took d to run.
+func NewTestcase(classname, name string, d time.Duration) *Testcase {
+	return &Testcase{
+		Name:      name,
+		Classname: classname,
+		Time:      seconds(d),
+		duration:  d,
+	}
+}
+
+// Suite returns the suite with the given name, adding it to the report if
+// necessary.
+func (ts *Testsuites) Suite(name string) *Testsuite {
+	for _, s := range ts.Suites {
+		if s.Name == name {
+			return s
+		}
+	}
+	s := &Testsuite{Name: name, Time: seconds(0)}
+	ts.Suites = append(ts.Suites, s)
+	return s
+}
+
+// Add adds the test case to the suite and updates the counters of the suite.
+func (s *Testsuite) Add(tc *Testcase) {
+	s.Testcases = append(s.Testcases, tc)
+	s.Tests++
+	if tc.Failure != nil {
+		s.Failures++
+	}
+	if tc.Error != nil {
+		s.Errors++
+	}
+	s.duration += tc.duration
+	s.Time = seconds(s.duration)
+}
+
+// Write writes the report to w in XML format.
+func Write(w io.Writer, ts *Testsuites) error {
+
+	var total time.Duration
+	ts.Tests, ts.Failures, ts.Errors = 0, 0, 0
+
+	for _, s := range ts.Suites {
+		ts.Tests += s.Tests
+		ts.Failures += s.Failures
+		ts.Errors += s.Errors
+		total += s.duration
+	}
+
+	ts.Time = seconds(total)
+
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "  ")
+
+	if err := enc.Encode(ts); err != nil {
+		return err
+	}
+
+	_, err := fmt.Fprintln(w)
+	return err
+}
+
+func seconds(d time.Duration) string {
+	return fmt.Sprintf("%.3f", d.Seconds())
+}
@@ -0,0 +1,181 @@
+// Copyright 2020 The OPA Authors.  All rights reserved.
+// Use of this source code is governed by an Apache2
+// license that can be found in the LICENSE file.
+
+// Package lock resolves the library bundles that a bundle depends on and
+// pins them in a lockfile so that builds are reproducible.
+package lock
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/open-policy-agent/opa/bundle"
+	"github.com/open-policy-agent/opa/loader"
+	"github.com/open-policy-agent/opa/util"
+)
+
+// Manifest lists the library bundles that a bundle depends on. The paths of
+// the library bundles (directories or tarballs) are relative to the manifest.
+type Manifest struct {
+	Dependencies []Source `json:"dependencies"`
+}
+
+// Source names a library bundle and the path it is loaded from.
+type Source struct {
+	Name string `json:"name"`
+	Path string `json:"path"`
+}
+
+// Lock records the revisions and digests of the resolved library bundles.
+type Lock struct {
+	Dependencies []bundle.Dependency `json:"dependencies"`
+}
+
+// Resolved is a library bundle loaded from its source.
+type Resolved struct {
+	Dependency bundle.Dependency
+	Modules    []bundle.ModuleFile
+}
+
+// Path returns the path of the lockfile for the manifest at path. The
+// lockfile is stored next to the manifest, e.g., deps.yaml is locked by
+// deps.lock.
+func Path(path string) string {
+	return strings.TrimSuffix(path, filepath.Ext(path)) + ".lock"
+}
+
+// ReadManifest reads the manifest at path. The manifest may be JSON or YAML.
+func ReadManifest(path string) (*Manifest, error) {
+
+	bs, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var m Manifest
+	if err := util.Unmarshal(bs, &m); err != nil {
+		return nil, fmt.Errorf("%v: %v", path, err)
+	}
+
+	names := map[string]bool{}
+
+	for _, src := range m.Dependencies {
+		if src.Name == "" || strings.ContainsAny(src.Name, "/\\") {
+			return nil, fmt.Errorf("%v: invalid dependency name %q", path, src.Name)
+		} else if src.Path == "" {
+			return nil, fmt.Errorf("%v: dependency %v: missing path", path, src.Name)
+		} else if names[src.Name] {
+			return nil, fmt.Errorf("%v: duplicate dependency %v", path, src.Name)
+		}
+		names[src.Name] = true
+	}
+
+	return &m, nil
+}
+
+// Resolve loads the library bundles listed in the manifest at path. Library
+// bundles may only contain policies.
+func Resolve(path string, m *Manifest) ([]*Resolved, error) {
+
+	dir := filepath.Dir(path)
+	result := make([]*Resolved, 0, len(m.Dependencies))
+
+	for _, src := range m.Dependencies {
+
+		srcPath := src.Path
+		if !filepath.IsAbs(srcPath) {
+			srcPath = filepath.Join(dir, srcPath)
+		}
+
+		b, err := loader.NewFileLoader().AsBundle(srcPath)
+		if err != nil {
+			return nil, fmt.Errorf("dependency %v: %v", src.Name, err)
+		}
+
+		if len(b.Data) > 0 {
+			return nil, fmt.Errorf("dependency %v: library bundles must not contain data", src.Name)
+		}
+
+		result = append(result, &Resolved{
+			Dependency: bundle.Dependency{
+				Name:     src.Name,
+				Revision: b.Manifest.Revision,
+				Digest:   bundle.DependencyDigest(b.Modules),
+			},
+			Modules: b.Modules,
+		})
+	}
+
+	return result, nil
+}
+
+// ReadLock reads the lockfile at path. If the lockfile does not exist, nil is
+// returned.
+func ReadLock(path string) (*Lock, error) {
+
+	bs, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	var l Lock
+	if err := util.UnmarshalJSON(bs, &l); err != nil {
+		return nil, fmt.Errorf("%v: %v", path, err)
+	}
+
+	return &l, nil
+}
+
+// WriteLock writes the lockfile for the resolved library bundles to path.
+func WriteLock(path string, resolved []*Resolved) error {
+
+	l := Lock{Dependencies: make([]bundle.Dependency, len(resolved))}
+
+	for i := range resolved {
+		l.Dependencies[i] = resolved[i].Dependency
+	}
+
+	bs, err := json.MarshalIndent(l, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	return ioutil.WriteFile(path, append(bs, '\n'), 0644)
+}
+
+// Verify returns an error if the resolved library bundles do not match the
+// lock, e.g., because a library was modified after it was locked or the
+// manifest lists libraries that are not locked.
+func (l *Lock) Verify(resolved []*Resolved) error {
+
+	locked := make(map[string]bundle.Dependency, len(l.Dependencies))
+
+	for _, dep := range l.Dependencies {
+		locked[dep.Name] = dep
+	}
+
+	for _, r := range resolved {
+		dep, ok := locked[r.Dependency.Name]
+		if !ok {
+			return fmt.Errorf("dependency %v is not locked", r.Dependency.Name)
+		} else if dep.Digest != r.Dependency.Digest {
+			return fmt.Errorf("dependency %v does not match lock: locked revision %q (%v) but resolved revision %q (%v)", dep.Name, dep.Revision, dep.Digest, r.Dependency.Revision, r.Dependency.Digest)
+		}
+		delete(locked, dep.Name)
+	}
+
+	for _, dep := range l.Dependencies {
+		if _, ok := locked[dep.Name]; ok {
+			return fmt.Errorf("dependency %v is locked but not listed in manifest", dep.Name)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,106 @@
+// Copyright 2020 The OPA Authors.  All rights reserved.
+// Use of this source code is governed by an Apache2
+// license that can be found in the LICENSE file.
+
+package lock
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+
+	"github.com/open-policy-agent/opa/util/test"
+)
+
+func TestResolveAndVerify(t *testing.T) {
+
+	files := map[string]string{
+		"app/deps.yaml": `
+dependencies:
+  - name: lib
+    path: ../lib
+`,
+		"lib/.manifest":    `{"revision": "v1"}`,
+		"lib/strings.rego": `package lib.strings`,
+	}
+
+	test.WithTempFS(files, func(root string) {
+
+		path := filepath.Join(root, "app", "deps.yaml")
+
+		m, err := ReadManifest(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		resolved, err := Resolve(path, m)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if len(resolved) != 1 || resolved[0].Dependency.Name != "lib" || resolved[0].Dependency.Revision != "v1" || len(resolved[0].Modules) != 1 {
+			t.Fatalf("Unexpected dependencies: %+v", resolved)
+		}
+
+		lockPath := Path(path)
+		if lockPath != filepath.Join(root, "app", "deps.lock") {
+			t.Fatalf("Unexpected lockfile path: %v", lockPath)
+		}
+
+		if l, err := ReadLock(lockPath); err != nil || l != nil {
+			t.Fatalf("Expected missing lockfile but got: %v, %v", l, err)
+		}
+
+		if err := WriteLock(lockPath, resolved); err != nil {
+			t.Fatal(err)
+		}
+
+		l, err := ReadLock(lockPath)
+		if err != nil {
+			t.Fatal(err)
+		} else if err := l.Verify(resolved); err != nil {
+			t.Fatal(err)
+		}
+
+		// modify the library after it was locked
+		if err := ioutil.WriteFile(filepath.Join(root, "lib", "strings.rego"), []byte(`package lib.strings.v2`), 0644); err != nil {
+			t.Fatal(err)
+		}
+
+		modified, err := Resolve(path, m)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if err := l.Verify(modified); err == nil {
+			t.Fatal("Expected error for modified dependency")
+		}
+
+		if err := l.Verify(nil); err == nil || err.Error() != "dependency lib is locked but not listed in manifest" {
+			t.Fatalf("Expected error for unlisted dependency but got: %v", err)
+		}
+
+		if err := (&Lock{}).Verify(resolved); err == nil || err.Error() != "dependency lib is not locked" {
+			t.Fatalf("Expected error for unlocked dependency but got: %v", err)
+		}
+	})
+}
+
+func TestReadManifestErrors(t *testing.T) {
+
+	tests := map[string]string{
+		"invalid name": `{"dependencies": [{"name": "a/b", "path": "x"}]}`,
+		"missing path": `{"dependencies": [{"name": "a"}]}`,
+		"duplicate":    `{"dependencies": [{"name": "a", "path": "x"}, {"name": "a", "path": "y"}]}`,
+	}
+
+	for note, manifest := range tests {
+		t.Run(note, func(t *testing.T) {
+			test.WithTempFS(map[string]string{"deps.json": manifest}, func(root string) {
+				if _, err := ReadManifest(filepath.Join(root, "deps.json")); err == nil {
+					t.Fatal("Expected error")
+				}
+			})
+		})
+	}
+}
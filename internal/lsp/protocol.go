@@ -0,0 +1,189 @@
+// Copyright 2019 The OPA Authors.  All rights reserved.
+// Use of this source code is governed by an Apache2
+// license that can be found in the LICENSE file.
+
+package lsp
+
+import (
+	"encoding/json"
+
+	"github.com/open-policy-agent/opa/ast"
+)
+
+// This file contains the subset of the Language Server Protocol messages
+// that the server understands. See
+// https://microsoft.github.io/language-server-protocol/specification for the
+// full protocol.
+
+const jsonrpcVersion = "2.0"
+
+// JSON-RPC error codes.
+const (
+	codeParseError     = -32700
+	codeInvalidRequest = -32600
+	codeMethodNotFound = -32601
+	codeInvalidParams  = -32602
+	codeInternalError  = -32603
+)
+
+// Diagnostic severities.
+const (
+	severityError = 1
+)
+
+// Text document synchronization kinds.
+const (
+	syncFull = 1
+)
+
+type request struct {
+	JSONRPC string           `json:"jsonrpc"`
+	ID      *json.RawMessage `json:"id,omitempty"`
+	Method  string           `json:"method"`
+	Params  json.RawMessage  `json:"params,omitempty"`
+}
+
+type response struct {
+	JSONRPC string           `json:"jsonrpc"`
+	ID      *json.RawMessage `json:"id"`
+	Result  interface{}      `json:"result"`
+}
+
+type errorResponse struct {
+	JSONRPC string           `json:"jsonrpc"`
+	ID      *json.RawMessage `json:"id"`
+	Error   *responseError   `json:"error"`
+}
+
+type notification struct {
+	JSONRPC string      `json:"jsonrpc"`
+	Method  string      `json:"method"`
+	Params  interface{} `json:"params"`
+}
+
+type responseError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+func (err *responseError) Error() string {
+	return err.Message
+}
+
+type position struct {
+	Line      int `json:"line"`
+	Character int `json:"character"`
+}
+
+type textRange struct {
+	Start position `json:"start"`
+	End   position `json:"end"`
+}
+
+type location struct {
+	URI   string    `json:"uri"`
+	Range textRange `json:"range"`
+}
+
+type diagnostic struct {
+	Range    textRange `json:"range"`
+	Severity int       `json:"severity"`
+	Code     string    `json:"code,omitempty"`
+	Source   string    `json:"source"`
+	Message  string    `json:"message"`
+}
+
+type publishDiagnosticsParams struct {
+	URI         string       `json:"uri"`
+	Diagnostics []diagnostic `json:"diagnostics"`
+}
+
+type textDocumentIdentifier struct {
+	URI string `json:"uri"`
+}
+
+type textDocumentItem struct {
+	URI  string `json:"uri"`
+	Text string `json:"text"`
+}
+
+type textDocumentContentChangeEvent struct {
+	Text string `json:"text"`
+}
+
+type didOpenTextDocumentParams struct {
+	TextDocument textDocumentItem `json:"textDocument"`
+}
+
+type didChangeTextDocumentParams struct {
+	TextDocument   textDocumentIdentifier           `json:"textDocument"`
+	ContentChanges []textDocumentContentChangeEvent `json:"contentChanges"`
+}
+
+type didCloseTextDocumentParams struct {
+	TextDocument textDocumentIdentifier `json:"textDocument"`
+}
+
+type textDocumentPositionParams struct {
+	TextDocument textDocumentIdentifier `json:"textDocument"`
+	Position     position               `json:"position"`
+}
+
+type documentFormattingParams struct {
+	TextDocument textDocumentIdentifier `json:"textDocument"`
+}
+
+type textEdit struct {
+	Range   textRange `json:"range"`
+	NewText string    `json:"newText"`
+}
+
+type markupContent struct {
+	Kind  string `json:"kind"`
+	Value string `json:"value"`
+}
+
+type hover struct {
+	Contents markupContent `json:"contents"`
+	Range    *textRange    `json:"range,omitempty"`
+}
+
+type serverCapabilities struct {
+	TextDocumentSync           int  `json:"textDocumentSync"`
+	DefinitionProvider         bool `json:"definitionProvider"`
+	HoverProvider              bool `json:"hoverProvider"`
+	DocumentFormattingProvider bool `json:"documentFormattingProvider"`
+}
+
+type initializeResult struct {
+	Capabilities serverCapabilities `json:"capabilities"`
+}
+
+// rangeOf returns the protocol range for the AST location. Protocol positions
+// are zero-based whereas AST locations are one-based. Columns are counted in
+// characters which matches the protocol for text in the Basic Multilingual
+// Plane.
+func rangeOf(loc *ast.Location) textRange {
+	if loc == nil {
+		return textRange{}
+	}
+	row, col := loc.End()
+	return textRange{
+		Start: position{Line: loc.Row - 1, Character: loc.Col - 1},
+		End:   position{Line: row - 1, Character: col - 1},
+	}
+}
+
+// contains returns true if the position is inside the AST location (or
+// immediately after it so that a cursor placed at the end of a word matches.)
+func contains(loc *ast.Location, pos position) bool {
+	if loc == nil {
+		return false
+	}
+	r := rangeOf(loc)
+	return !positionLess(pos, r.Start) && !positionLess(r.End, pos)
+}
+
+func positionLess(a, b position) bool {
+	return a.Line < b.Line || (a.Line == b.Line && a.Character < b.Character)
+}
@@ -0,0 +1,456 @@
+// Copyright 2019 The OPA Authors.  All rights reserved.
+// Use of this source code is governed by an Apache2
+// license that can be found in the LICENSE file.
+
+// Package lsp implements a Language Server Protocol server for Rego. The
+// server reports parse and compile errors as diagnostics and supports
+// go-to-definition, hover, and document formatting.
+package lsp
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/open-policy-agent/opa/ast"
+	"github.com/open-policy-agent/opa/format"
+	"github.com/open-policy-agent/opa/internal/baseprotocol"
+)
+
+// Server implements a language server that communicates over a pair of
+// streams (e.g., stdin and stdout) using the base protocol defined by the
+// Language Server Protocol. Documents are identified by their URIs which are
+// also used as the filenames of the parsed modules.
+type Server struct {
+	in       *baseprotocol.Reader
+	out      io.Writer
+	docs     map[string]string
+	modules  map[string]*ast.Module
+	compiler *ast.Compiler
+}
+
+// New returns a new Server that reads messages from r and writes messages to w.
+func New(r io.Reader, w io.Writer) *Server {
+	return &Server{
+		in:      baseprotocol.NewReader(r),
+		out:     w,
+		docs:    map[string]string{},
+		modules: map[string]*ast.Module{},
+	}
+}
+
+// Serve processes messages until the client sends the exit notification or
+// closes the input stream.
+func (s *Server) Serve() error {
+	for {
+		bs, err := s.in.Read()
+		if err == io.EOF {
+			return nil
+		} else if err != nil {
+			return err
+		}
+
+		var req request
+		if err := json.Unmarshal(bs, &req); err != nil {
+			if err := s.write(errorResponse{JSONRPC: jsonrpcVersion, Error: &responseError{Code: codeParseError, Message: err.Error()}}); err != nil {
+				return err
+			}
+			continue
+		}
+
+		if req.Method == "exit" {
+			return nil
+		}
+
+		result, err := s.handle(req)
+
+		// Notifications do not receive responses.
+		if req.ID == nil {
+			continue
+		}
+
+		if err != nil {
+			rerr, ok := err.(*responseError)
+			if !ok {
+				rerr = &responseError{Code: codeInternalError, Message: err.Error()}
+			}
+			err = s.write(errorResponse{JSONRPC: jsonrpcVersion, ID: req.ID, Error: rerr})
+		} else {
+			err = s.write(response{JSONRPC: jsonrpcVersion, ID: req.ID, Result: result})
+		}
+
+		if err != nil {
+			return err
+		}
+	}
+}
+
+func (s *Server) handle(req request) (interface{}, error) {
+	switch req.Method {
+	case "initialize":
+		return initializeResult{
+			Capabilities: serverCapabilities{
+				TextDocumentSync:           syncFull,
+				DefinitionProvider:         true,
+				HoverProvider:              true,
+				DocumentFormattingProvider: true,
+			},
+		}, nil
+	case "initialized":
+		return nil, nil
+	case "shutdown":
+		return nil, nil
+	case "textDocument/didOpen":
+		var params didOpenTextDocumentParams
+		if err := unmarshalParams(req.Params, &params); err != nil {
+			return nil, err
+		}
+		s.docs[params.TextDocument.URI] = params.TextDocument.Text
+		return nil, s.update()
+	case "textDocument/didChange":
+		var params didChangeTextDocumentParams
+		if err := unmarshalParams(req.Params, &params); err != nil {
+			return nil, err
+		}
+		// The server only supports full document synchronization so the last
+		// change contains the entire document.
+		if n := len(params.ContentChanges); n > 0 {
+			s.docs[params.TextDocument.URI] = params.ContentChanges[n-1].Text
+		}
+		return nil, s.update()
+	case "textDocument/didClose":
+		var params didCloseTextDocumentParams
+		if err := unmarshalParams(req.Params, &params); err != nil {
+			return nil, err
+		}
+		delete(s.docs, params.TextDocument.URI)
+		if err := s.publishDiagnostics(params.TextDocument.URI, nil); err != nil {
+			return nil, err
+		}
+		return nil, s.update()
+	case "textDocument/definition":
+		var params textDocumentPositionParams
+		if err := unmarshalParams(req.Params, &params); err != nil {
+			return nil, err
+		}
+		return s.definition(params), nil
+	case "textDocument/hover":
+		var params textDocumentPositionParams
+		if err := unmarshalParams(req.Params, &params); err != nil {
+			return nil, err
+		}
+		return s.hover(params), nil
+	case "textDocument/formatting":
+		var params documentFormattingParams
+		if err := unmarshalParams(req.Params, &params); err != nil {
+			return nil, err
+		}
+		return s.formatting(params), nil
+	}
+
+	if req.ID == nil {
+		return nil, nil
+	}
+
+	return nil, &responseError{Code: codeMethodNotFound, Message: fmt.Sprintf("method not found: %v", req.Method)}
+}
+
+// update parses and compiles the open documents and publishes diagnostics
+// for each of them.
+func (s *Server) update() error {
+
+	diagnostics := map[string][]diagnostic{}
+	s.modules = map[string]*ast.Module{}
+	s.compiler = nil
+
+	for uri, text := range s.docs {
+		diagnostics[uri] = []diagnostic{}
+		module, err := ast.ParseModule(uri, text)
+		if err != nil {
+			diagnostics[uri] = append(diagnostics[uri], errorDiagnostics(err)...)
+			continue
+		}
+		// Empty documents do not contain a module.
+		if module != nil {
+			s.modules[uri] = module
+		}
+	}
+
+	compiler := ast.NewCompiler()
+	compiler.Compile(s.modules)
+
+	if compiler.Failed() {
+		for _, err := range compiler.Errors {
+			if err.Location == nil {
+				continue
+			}
+			uri := err.Location.File
+			if _, ok := diagnostics[uri]; ok {
+				diagnostics[uri] = append(diagnostics[uri], errorDiagnostic(err))
+			}
+		}
+	} else {
+		s.compiler = compiler
+	}
+
+	uris := make([]string, 0, len(diagnostics))
+	for uri := range diagnostics {
+		uris = append(uris, uri)
+	}
+	sort.Strings(uris)
+
+	for _, uri := range uris {
+		if err := s.publishDiagnostics(uri, diagnostics[uri]); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (s *Server) publishDiagnostics(uri string, diagnostics []diagnostic) error {
+	if diagnostics == nil {
+		diagnostics = []diagnostic{}
+	}
+	return s.write(notification{
+		JSONRPC: jsonrpcVersion,
+		Method:  "textDocument/publishDiagnostics",
+		Params: publishDiagnosticsParams{
+			URI:         uri,
+			Diagnostics: diagnostics,
+		},
+	})
+}
+
+func (s *Server) definition(params textDocumentPositionParams) []location {
+	result := []location{}
+	for _, node := range s.lookup(params.TextDocument.URI, params.Position) {
+		loc := node.Loc()
+		result = append(result, location{URI: loc.File, Range: rangeOf(loc)})
+	}
+	return result
+}
+
+func (s *Server) hover(params textDocumentPositionParams) *hover {
+
+	nodes := s.lookup(params.TextDocument.URI, params.Position)
+	if len(nodes) == 0 {
+		return nil
+	}
+
+	var buf []string
+	seen := map[string]struct{}{}
+
+	for _, node := range nodes {
+		var text string
+		switch node := node.(type) {
+		case *ast.Import:
+			text = node.String()
+		case *ast.Rule:
+			text = node.Head.String()
+		}
+		if _, ok := seen[text]; !ok {
+			seen[text] = struct{}{}
+			buf = append(buf, text)
+		}
+	}
+
+	value := "```rego\n" + strings.Join(buf, "\n") + "\n```"
+
+	if rule, ok := nodes[0].(*ast.Rule); ok {
+		if doc := s.ruleDoc(rule); doc != "" {
+			value += "\n\n" + doc
+		}
+	}
+
+	return &hover{
+		Contents: markupContent{Kind: "markdown", Value: value},
+	}
+}
+
+// ruleDoc returns the text of the comments immediately preceding the rule.
+func (s *Server) ruleDoc(rule *ast.Rule) string {
+
+	module, ok := s.modules[rule.Location.File]
+	if !ok {
+		return ""
+	}
+
+	comments := map[int]string{}
+	for _, c := range module.Comments {
+		comments[c.Location.Row] = strings.TrimPrefix(string(c.Text), " ")
+	}
+
+	var lines []string
+	for row := rule.Location.Row - 1; row > 0; row-- {
+		text, ok := comments[row]
+		if !ok {
+			break
+		}
+		lines = append([]string{text}, lines...)
+	}
+
+	return strings.Join(lines, "\n")
+}
+
+func (s *Server) formatting(params documentFormattingParams) []textEdit {
+
+	text, ok := s.docs[params.TextDocument.URI]
+	if !ok {
+		return nil
+	}
+
+	formatted, err := format.Source(params.TextDocument.URI, []byte(text))
+	if err != nil || string(formatted) == text {
+		return []textEdit{}
+	}
+
+	lines := strings.Split(text, "\n")
+	end := position{
+		Line:      len(lines) - 1,
+		Character: utf8.RuneCountInString(lines[len(lines)-1]),
+	}
+
+	return []textEdit{
+		{
+			Range:   textRange{End: end},
+			NewText: string(formatted),
+		},
+	}
+}
+
+// lookup returns the imports or rules that the variable or reference at the
+// position in the document refers to.
+func (s *Server) lookup(uri string, pos position) []ast.Node {
+
+	module, ok := s.modules[uri]
+	if !ok {
+		return nil
+	}
+
+	// Variables may refer to imports. Imports are resolved by the compiler so
+	// they must be looked up in the parsed module.
+	for _, term := range termsAt(module, pos) {
+		if v, ok := term.Value.(ast.Var); ok {
+			for _, imp := range module.Imports {
+				if imp.Name().Equal(v) {
+					return []ast.Node{imp}
+				}
+			}
+		}
+	}
+
+	// References to rules have been resolved to fully qualified references
+	// in the compiled module.
+	if s.compiler != nil {
+		for _, term := range termsAt(s.compiler.Modules[uri], pos) {
+			ref, ok := term.Value.(ast.Ref)
+			if !ok || !ref[0].Equal(ast.DefaultRootDocument) {
+				continue
+			}
+			if rules := sortedRules(s.compiler.GetRules(ref.GroundPrefix())); len(rules) > 0 {
+				return rules
+			}
+		}
+	}
+
+	// Without a compiled module, fallback to matching rule names in the same
+	// module.
+	for _, term := range termsAt(module, pos) {
+		if v, ok := term.Value.(ast.Var); ok {
+			var result []*ast.Rule
+			for _, rule := range module.Rules {
+				if rule.Head.Name.Equal(v) {
+					result = append(result, rule)
+				}
+			}
+			if len(result) > 0 {
+				return sortedRules(result)
+			}
+		}
+	}
+
+	return nil
+}
+
+// termsAt returns the variables and references in the module that contain
+// the position, ordered from innermost to outermost.
+func termsAt(module *ast.Module, pos position) []*ast.Term {
+
+	var result []*ast.Term
+
+	if module == nil {
+		return nil
+	}
+
+	ast.WalkTerms(module, func(term *ast.Term) bool {
+		if contains(term.Location, pos) {
+			switch term.Value.(type) {
+			case ast.Var, ast.Ref:
+				result = append(result, term)
+			}
+		}
+		return false
+	})
+
+	sort.SliceStable(result, func(i, j int) bool {
+		return len(result[i].Location.Text) < len(result[j].Location.Text)
+	})
+
+	return result
+}
+
+func sortedRules(rules []*ast.Rule) []ast.Node {
+	sort.Slice(rules, func(i, j int) bool {
+		a, b := rules[i].Location, rules[j].Location
+		if a.File != b.File {
+			return a.File < b.File
+		}
+		return a.Row < b.Row || (a.Row == b.Row && a.Col < b.Col)
+	})
+	result := make([]ast.Node, len(rules))
+	for i := range rules {
+		result[i] = rules[i]
+	}
+	return result
+}
+
+func errorDiagnostics(err error) []diagnostic {
+	errs, ok := err.(ast.Errors)
+	if !ok {
+		return []diagnostic{{Severity: severityError, Source: "opa", Message: err.Error()}}
+	}
+	result := make([]diagnostic, len(errs))
+	for i := range errs {
+		result[i] = errorDiagnostic(errs[i])
+	}
+	return result
+}
+
+func errorDiagnostic(err *ast.Error) diagnostic {
+	return diagnostic{
+		Range:    rangeOf(err.Location),
+		Severity: severityError,
+		Code:     err.Code,
+		Source:   "opa",
+		Message:  err.Message,
+	}
+}
+
+func unmarshalParams(bs json.RawMessage, x interface{}) error {
+	if err := json.Unmarshal(bs, x); err != nil {
+		return &responseError{Code: codeInvalidParams, Message: err.Error()}
+	}
+	return nil
+}
+
+func (s *Server) write(msg interface{}) error {
+	bs, err := json.Marshal(msg)
+	if err != nil {
+		return err
+	}
+	return baseprotocol.Write(s.out, bs)
+}
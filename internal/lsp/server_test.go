@@ -0,0 +1,150 @@
+// Copyright 2019 The OPA Authors.  All rights reserved.
+// Use of this source code is governed by an Apache2
+// license that can be found in the LICENSE file.
+
+package lsp
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/open-policy-agent/opa/internal/baseprotocol"
+)
+
+const testModule = `package test
+
+import input.servers
+
+# allow is true if a server is named foo.
+allow {
+	servers[_].name = "foo"
+}
+
+deny {
+	not allow
+}
+`
+
+func TestServer(t *testing.T) {
+
+	msgs := run(t,
+		`{"jsonrpc": "2.0", "id": 1, "method": "initialize", "params": {}}`,
+		`{"jsonrpc": "2.0", "method": "initialized", "params": {}}`,
+		fmt.Sprintf(`{"jsonrpc": "2.0", "method": "textDocument/didOpen", "params": {"textDocument": {"uri": "file:///test.rego", "text": %q}}}`, testModule),
+		`{"jsonrpc": "2.0", "id": 2, "method": "textDocument/definition", "params": {"textDocument": {"uri": "file:///test.rego"}, "position": {"line": 10, "character": 6}}}`,
+		`{"jsonrpc": "2.0", "id": 3, "method": "textDocument/definition", "params": {"textDocument": {"uri": "file:///test.rego"}, "position": {"line": 6, "character": 2}}}`,
+		`{"jsonrpc": "2.0", "id": 4, "method": "textDocument/hover", "params": {"textDocument": {"uri": "file:///test.rego"}, "position": {"line": 10, "character": 6}}}`,
+		`{"jsonrpc": "2.0", "id": 5, "method": "textDocument/formatting", "params": {"textDocument": {"uri": "file:///test.rego"}}}`,
+		`{"jsonrpc": "2.0", "method": "textDocument/didChange", "params": {"textDocument": {"uri": "file:///test.rego"}, "contentChanges": [{"text": "package test\n\np { q }\n"}]}}`,
+		`{"jsonrpc": "2.0", "id": 6, "method": "unknown/method"}`,
+		`{"jsonrpc": "2.0", "id": 7, "method": "shutdown"}`,
+		`{"jsonrpc": "2.0", "method": "exit"}`,
+	)
+
+	expected := []string{
+		`{"jsonrpc":"2.0","id":1,"result":{"capabilities":{"textDocumentSync":1,"definitionProvider":true,"hoverProvider":true,"documentFormattingProvider":true}}}`,
+		`{"jsonrpc":"2.0","method":"textDocument/publishDiagnostics","params":{"uri":"file:///test.rego","diagnostics":[]}}`,
+		`{"jsonrpc":"2.0","id":2,"result":[{"uri":"file:///test.rego","range":{"start":{"line":5,"character":0},"end":{"line":7,"character":1}}}]}`,
+		`{"jsonrpc":"2.0","id":3,"result":[{"uri":"file:///test.rego","range":{"start":{"line":2,"character":0},"end":{"line":2,"character":20}}}]}`,
+		`{"jsonrpc":"2.0","id":4,"result":{"contents":{"kind":"markdown","value":"` + "```rego\\nallow = true\\n```\\n\\nallow is true if a server is named foo." + `"}}}`,
+		`{"jsonrpc":"2.0","id":5,"result":[]}`,
+		`{"jsonrpc":"2.0","method":"textDocument/publishDiagnostics","params":{"uri":"file:///test.rego","diagnostics":[{"range":{"start":{"line":2,"character":4},"end":{"line":2,"character":5}},"severity":1,"code":"rego_unsafe_var_error","source":"opa","message":"var q is unsafe"}]}}`,
+		`{"jsonrpc":"2.0","id":6,"error":{"code":-32601,"message":"method not found: unknown/method"}}`,
+		`{"jsonrpc":"2.0","id":7,"result":null}`,
+	}
+
+	if len(msgs) != len(expected) {
+		t.Fatalf("Expected %d messages but got %d:\n%v", len(expected), len(msgs), strings.Join(msgs, "\n"))
+	}
+
+	for i := range expected {
+		if msgs[i] != expected[i] {
+			t.Errorf("Expected message %d to be:\n\n%v\n\nGot:\n\n%v", i, expected[i], msgs[i])
+		}
+	}
+}
+
+func TestServerFormatting(t *testing.T) {
+
+	msgs := run(t,
+		`{"jsonrpc": "2.0", "method": "textDocument/didOpen", "params": {"textDocument": {"uri": "file:///x.rego", "text": "package x\np{true}"}}}`,
+		`{"jsonrpc": "2.0", "id": 1, "method": "textDocument/formatting", "params": {"textDocument": {"uri": "file:///x.rego"}}}`,
+	)
+
+	exp := `{"jsonrpc":"2.0","id":1,"result":[{"range":{"start":{"line":0,"character":0},"end":{"line":1,"character":7}},"newText":"package x\n\np = true\n"}]}`
+
+	if len(msgs) != 2 || msgs[1] != exp {
+		t.Fatalf("Expected formatting edit:\n\n%v\n\nGot:\n\n%v", exp, strings.Join(msgs, "\n"))
+	}
+}
+
+func TestServerParseErrors(t *testing.T) {
+
+	msgs := run(t,
+		`{"jsonrpc": "2.0", "method": "textDocument/didOpen", "params": {"textDocument": {"uri": "file:///x.rego", "text": "package x\np {"}}}`,
+		`not json`,
+	)
+
+	if len(msgs) != 2 {
+		t.Fatalf("Expected two messages but got: %v", msgs)
+	}
+
+	if !strings.Contains(msgs[0], `"code":"rego_parse_error"`) {
+		t.Fatalf("Expected parse error diagnostic but got: %v", msgs[0])
+	}
+
+	if !strings.Contains(msgs[1], `"code":-32700`) {
+		t.Fatalf("Expected JSON-RPC parse error but got: %v", msgs[1])
+	}
+}
+
+func TestServerInvalidContentLength(t *testing.T) {
+	for _, n := range []int{-1, baseprotocol.MaxContentLength + 1} {
+		in := bytes.NewBufferString(fmt.Sprintf("Content-Length: %d\r\n\r\n{}", n))
+		var out bytes.Buffer
+		err := New(in, &out).Serve()
+		if err == nil || !strings.Contains(err.Error(), "invalid Content-Length header") {
+			t.Fatalf("Expected Content-Length error for %d but got: %v", n, err)
+		}
+	}
+}
+
+// run sends the messages to a new server and returns the messages written by
+// the server.
+func run(t *testing.T, msgs ...string) []string {
+	t.Helper()
+
+	var in, out bytes.Buffer
+
+	for _, msg := range msgs {
+		if err := baseprotocol.Write(&in, []byte(msg)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := New(&in, &out).Serve(); err != nil {
+		t.Fatal(err)
+	}
+
+	var result []string
+	r := baseprotocol.NewReader(&out)
+
+	for {
+		bs, err := r.Read()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			t.Fatal(err)
+		}
+		if !json.Valid(bs) {
+			t.Fatalf("Expected valid JSON but got: %s", bs)
+		}
+		result = append(result, string(bs))
+	}
+
+	return result
+}
@@ -0,0 +1,128 @@
+// Copyright 2020 The OPA Authors.  All rights reserved.
+// Use of this source code is governed by an Apache2
+// license that can be found in the LICENSE file.
+
+// Package migrate rewrites deprecated constructs in Rego modules to their
+// modern equivalents.
+package migrate
+
+import (
+	"fmt"
+
+	"github.com/open-policy-agent/opa/ast"
+	"github.com/open-policy-agent/opa/format"
+)
+
+// replacements maps deprecated built-in functions to the built-in functions
+// that replaced them. The replacements accept the same arguments.
+var replacements = map[string]*ast.Builtin{
+	ast.SetDiff.Name:        ast.Minus,
+	ast.NetCIDROverlap.Name: ast.NetCIDRContains,
+}
+
+// Source migrates a Rego source file and returns the formatted result. The
+// comments in the source file are preserved.
+func Source(filename string, src []byte) ([]byte, error) {
+
+	module, err := ast.ParseModule(filename, string(src))
+	if err != nil {
+		return nil, err
+	}
+
+	Module(module)
+
+	formatted, err := format.AstWithOpts(module, format.Opts{Syntax: format.SyntaxIf, Fix: true})
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", filename, err)
+	}
+
+	return formatted, nil
+}
+
+// Module rewrites the deprecated constructs in the module in place:
+//
+//   - Calls to deprecated built-in functions are replaced by calls to the
+//     built-in functions that replaced them, e.g., set_diff(a, b) is
+//     rewritten to a - b.
+//   - Implicit iteration over a collection in assignments, e.g.,
+//     x := xs[_], is rewritten to some x in xs.
+//
+// Rules are rewritten to use the future keywords when the module is
+// formatted by Source.
+func Module(module *ast.Module) {
+
+	ast.WalkExprs(module, func(expr *ast.Expr) bool {
+		if expr.IsCall() {
+			rewriteOperator(expr.Terms.([]*ast.Term))
+		}
+		rewriteIteration(expr)
+		return false
+	})
+
+	ast.WalkTerms(module, func(term *ast.Term) bool {
+		if call, ok := term.Value.(ast.Call); ok {
+			rewriteOperator(call)
+		}
+		return false
+	})
+}
+
+func rewriteOperator(terms []*ast.Term) {
+	bi, ok := replacements[terms[0].Value.String()]
+	if !ok {
+		return
+	}
+	ref := bi.Ref()
+	for i := range ref {
+		ref[i].SetLocation(terms[0].Location)
+	}
+	terms[0] = ast.NewTerm(ref).SetLocation(terms[0].Location)
+}
+
+// rewriteIteration rewrites x := xs[_] to some x in xs. The collection must
+// not contain other wildcards, otherwise the iteration is left as is.
+func rewriteIteration(expr *ast.Expr) {
+
+	if !expr.IsAssignment() || expr.Negated || len(expr.With) > 0 {
+		return
+	}
+
+	value := expr.Operand(0)
+	if _, ok := value.Value.(ast.Var); !ok {
+		return
+	}
+
+	ref, ok := expr.Operand(1).Value.(ast.Ref)
+	if !ok || len(ref) < 2 {
+		return
+	}
+
+	if v, ok := ref[len(ref)-1].Value.(ast.Var); !ok || !v.IsWildcard() {
+		return
+	}
+
+	collection := ref[:len(ref)-1]
+	wildcards := false
+
+	ast.WalkVars(collection, func(v ast.Var) bool {
+		wildcards = wildcards || v.IsWildcard()
+		return wildcards
+	})
+
+	if wildcards {
+		return
+	}
+
+	loc := expr.Location
+	op := ast.Member.Ref()
+	for i := range op {
+		op[i].SetLocation(loc)
+	}
+
+	call := ast.Call{ast.NewTerm(op).SetLocation(loc), value, ast.NewTerm(collection).SetLocation(loc)}
+
+	expr.Terms = &ast.SomeDecl{
+		Symbols:  []*ast.Term{ast.NewTerm(call).SetLocation(loc)},
+		Location: loc,
+	}
+}
@@ -0,0 +1,132 @@
+// Copyright 2020 The OPA Authors.  All rights reserved.
+// Use of this source code is governed by an Apache2
+// license that can be found in the LICENSE file.
+
+package migrate
+
+import (
+	"testing"
+
+	"github.com/open-policy-agent/opa/ast"
+)
+
+func TestSource(t *testing.T) {
+
+	tests := []struct {
+		note     string
+		src      string
+		expected string
+		err      string
+	}{
+		{
+			note: "rules",
+			src: `package test
+
+p[x] { x := input.x }
+q { input.q }
+r = 1`,
+			expected: `package test
+
+import future.keywords
+
+p contains x if {
+	x := input.x
+}
+
+q if {
+	input.q
+}
+
+r = 1
+`,
+		},
+		{
+			note: "deprecated built-ins",
+			src: `package test
+
+p { set_diff(input.a, input.b, x); net.cidr_overlap("10.0.0.0/8", input.ip) }
+q = set_diff(input.a, {1})`,
+			expected: `package test
+
+import future.keywords
+
+p if {
+	x = input.a - input.b
+	net.cidr_contains("10.0.0.0/8", input.ip)
+}
+
+q = input.a - {1}
+`,
+		},
+		{
+			note: "implicit iteration",
+			src: `package test
+
+# Names of the sites in the west.
+p[name] {
+	site := data.sites[_] # each site
+	site.region == "west"
+	name := site.servers[_].name
+	x := input.xs[_][_]
+	y = input.ys[_]
+	not z := input.zs[_]
+}`,
+			expected: `package test
+
+import future.keywords
+
+# Names of the sites in the west.
+p contains name if {
+	some site in data.sites # each site
+	site.region == "west"
+	name := site.servers[_].name
+	x := input.xs[_][_]
+	y = input.ys[_]
+	not z := input.zs[_]
+}
+`,
+		},
+		{
+			note: "future keywords",
+			src: `package test
+
+p { every := input.xs[_]; every > 0 }`,
+			expected: `package test
+
+import future.keywords
+
+p if {
+	some every_ in input.xs
+	every_ > 0
+}
+`,
+		},
+		{
+			note: "rule named after future keyword",
+			src: `package test
+
+in { true }`,
+			err: "test.rego: line 3: rule in conflicts with future keyword",
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.note, func(t *testing.T) {
+			migrated, err := Source("test.rego", []byte(tc.src))
+			if tc.err != "" {
+				if err == nil || err.Error() != tc.err {
+					t.Fatalf("Expected error %q but got: %v", tc.err, err)
+				}
+				return
+			} else if err != nil {
+				t.Fatal(err)
+			}
+			if string(migrated) != tc.expected {
+				t.Fatalf("Expected:\n%s\n\nGot:\n%s", tc.expected, migrated)
+			}
+			if _, err := ast.ParseModule("test.rego", string(migrated)); err != nil {
+				t.Fatalf("Failed to parse migrated source: %v", err)
+			}
+		})
+	}
+}
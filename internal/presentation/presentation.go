@@ -20,6 +20,7 @@ import (
 
 	"github.com/open-policy-agent/opa/ast"
 	"github.com/open-policy-agent/opa/cover"
+	"github.com/open-policy-agent/opa/dependencies"
 	"github.com/open-policy-agent/opa/format"
 	"github.com/open-policy-agent/opa/loader"
 	"github.com/open-policy-agent/opa/metrics"
@@ -34,8 +35,18 @@ var DefaultProfileSortOrder = []string{"total_time_ns", "num_eval", "num_redo",
 
 // DepAnalysisOutput contains the result of dependency analysis to be presented.
 type DepAnalysisOutput struct {
-	Base    []ast.Ref `json:"base,omitempty"`
-	Virtual []ast.Ref `json:"virtual,omitempty"`
+	Base    []ast.Ref            `json:"base,omitempty"`
+	Virtual []ast.Ref            `json:"virtual,omitempty"`
+	Aliases []DepAnalysisAlias   `json:"aliases,omitempty"`
+	Input   *dependencies.Schema `json:"input,omitempty"`
+}
+
+// DepAnalysisAlias describes an import alias declared by a module that the
+// analyzed query depends on.
+type DepAnalysisAlias struct {
+	Module string  `json:"module"`
+	Alias  ast.Var `json:"alias"`
+	Path   ast.Ref `json:"path"`
 }
 
 // JSON outputs o to w as JSON.
@@ -47,6 +58,8 @@ func (o DepAnalysisOutput) JSON(w io.Writer) error {
 // Pretty outputs o to w in a human-readable format.
 func (o DepAnalysisOutput) Pretty(w io.Writer) error {
 
+	o.sort()
+
 	var headers []string
 	var rows [][]string
 
@@ -75,28 +88,67 @@ func (o DepAnalysisOutput) Pretty(w io.Writer) error {
 		}
 	} else if len(o.Virtual) > 0 {
 		headers = []string{"Virtual Documents"}
-		rows = make([][]string, len(o.Base))
+		rows = make([][]string, len(o.Virtual))
 		for i := range rows {
 			rows[i] = []string{o.Virtual[i].String()}
 		}
 	}
 
-	if len(rows) == 0 {
-		return nil
+	if len(rows) > 0 {
+		table := tablewriter.NewWriter(w)
+		table.SetHeader(headers)
+		table.SetAutoWrapText(false)
+		for i := range rows {
+			table.Append(rows[i])
+		}
+		table.Render()
 	}
 
-	table := tablewriter.NewWriter(w)
-	table.SetHeader(headers)
-	table.SetAutoWrapText(false)
-	for i := range rows {
-		table.Append(rows[i])
+	if len(o.Aliases) > 0 {
+		table := tablewriter.NewWriter(w)
+		table.SetHeader([]string{"Module", "Alias", "Path"})
+		table.SetAutoWrapText(false)
+		for _, a := range o.Aliases {
+			table.Append([]string{a.Module, a.Alias.String(), a.Path.String()})
+		}
+		table.Render()
 	}
 
-	table.Render()
+	if o.Input != nil && (len(o.Input.Properties) > 0 || o.Input.Items != nil) {
+		table := tablewriter.NewWriter(w)
+		table.SetHeader([]string{"Input Field", "Type"})
+		table.SetAutoWrapText(false)
+		appendInputFields(table, ast.InputRootRef, o.Input)
+		table.Render()
+	}
 
 	return nil
 }
 
+// appendInputFields appends a row for each field in the schema to the table.
+// Elements of arrays are represented by the _ wildcard.
+func appendInputFields(table *tablewriter.Table, path ast.Ref, s *dependencies.Schema) {
+
+	if len(path) > 1 {
+		table.Append([]string{path.String(), s.Type})
+	}
+
+	keys := make([]string, 0, len(s.Properties))
+	for k := range s.Properties {
+		keys = append(keys, k)
+	}
+
+	sort.Strings(keys)
+
+	for _, k := range keys {
+		appendInputFields(table, path.Append(ast.StringTerm(k)), s.Properties[k])
+	}
+
+	if s.Items != nil {
+		appendInputFields(table, path.Append(ast.VarTerm("_")), s.Items)
+	}
+}
+
 func (o DepAnalysisOutput) sort() {
 	sort.Slice(o.Base, func(i, j int) bool {
 		return o.Base[i].Compare(o.Base[j]) < 0
@@ -105,6 +157,64 @@ func (o DepAnalysisOutput) sort() {
 	sort.Slice(o.Virtual, func(i, j int) bool {
 		return o.Virtual[i].Compare(o.Virtual[j]) < 0
 	})
+
+	sort.Slice(o.Aliases, func(i, j int) bool {
+		if o.Aliases[i].Module != o.Aliases[j].Module {
+			return o.Aliases[i].Module < o.Aliases[j].Module
+		}
+		return o.Aliases[i].Alias.Compare(o.Aliases[j].Alias) < 0
+	})
+}
+
+// InspectOutput contains the packages and rules defined by a set of modules
+// and the comments attached to them.
+type InspectOutput struct {
+	Documents []InspectDocument `json:"documents,omitempty"`
+}
+
+// InspectDocument describes a package or rule. The comments are the text of
+// the comments attached to the package or rule without the leading "#".
+type InspectDocument struct {
+	Kind     string   `json:"kind"`
+	Path     string   `json:"path"`
+	File     string   `json:"file"`
+	Row      int      `json:"row"`
+	Comments []string `json:"comments,omitempty"`
+}
+
+// JSON outputs o to w as JSON.
+func (o InspectOutput) JSON(w io.Writer) error {
+	o.sort()
+	return JSON(w, o)
+}
+
+// Pretty outputs o to w in a human-readable format.
+func (o InspectOutput) Pretty(w io.Writer) error {
+
+	o.sort()
+
+	if len(o.Documents) == 0 {
+		return nil
+	}
+
+	table := tablewriter.NewWriter(w)
+	table.SetHeader([]string{"Kind", "Path", "Location", "Comments"})
+	table.SetAutoWrapText(false)
+	for _, d := range o.Documents {
+		table.Append([]string{d.Kind, d.Path, fmt.Sprintf("%v:%v", d.File, d.Row), strings.Join(d.Comments, " ")})
+	}
+	table.Render()
+
+	return nil
+}
+
+func (o InspectOutput) sort() {
+	sort.SliceStable(o.Documents, func(i, j int) bool {
+		if o.Documents[i].File != o.Documents[j].File {
+			return o.Documents[i].File < o.Documents[j].File
+		}
+		return o.Documents[i].Row < o.Documents[j].Row
+	})
 }
 
 // Output contains the result of evaluation to be presented.
@@ -392,3 +392,65 @@ p = 1
 	}
 
 }
+
+func TestDepAnalysisOutputPretty(t *testing.T) {
+
+	buf := new(bytes.Buffer)
+
+	err := DepAnalysisOutput{
+		Virtual: []ast.Ref{ast.MustParseRef("data.x.p")},
+		Aliases: []DepAnalysisAlias{
+			{Module: "y.rego", Alias: "zw", Path: ast.MustParseRef("data.z.w")},
+			{Module: "x.rego", Alias: "fb", Path: ast.MustParseRef("data.foo.bar")},
+		},
+	}.Pretty(buf)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	exp := `+-------------------+
+| VIRTUAL DOCUMENTS |
++-------------------+
+| data.x.p          |
++-------------------+
++--------+-------+--------------+
+| MODULE | ALIAS |     PATH     |
++--------+-------+--------------+
+| x.rego | fb    | data.foo.bar |
+| y.rego | zw    | data.z.w     |
++--------+-------+--------------+
+`
+
+	if buf.String() != exp {
+		t.Fatalf("Expected:\n\n%v\n\nGot:\n\n%v", exp, buf.String())
+	}
+}
+
+func TestInspectOutputPretty(t *testing.T) {
+
+	buf := new(bytes.Buffer)
+
+	err := InspectOutput{
+		Documents: []InspectDocument{
+			{Kind: "rule", Path: "data.x.allow", File: "x.rego", Row: 4, Comments: []string{"Allow admins."}},
+			{Kind: "package", Path: "data.x", File: "x.rego", Row: 2, Comments: []string{"Package x", "contains examples."}},
+		},
+	}.Pretty(buf)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	exp := `+---------+--------------+----------+------------------------------+
+|  KIND   |     PATH     | LOCATION |           COMMENTS           |
++---------+--------------+----------+------------------------------+
+| package | data.x       | x.rego:2 | Package x contains examples. |
+| rule    | data.x.allow | x.rego:4 | Allow admins.                |
++---------+--------------+----------+------------------------------+
+`
+
+	if buf.String() != exp {
+		t.Fatalf("Expected:\n\n%v\n\nGot:\n\n%v", exp, buf.String())
+	}
+}
@@ -15,6 +15,7 @@ import (
 	"github.com/prometheus/client_golang/prometheus/promhttp"
 
 	"github.com/open-policy-agent/opa/metrics"
+	"github.com/open-policy-agent/opa/plugins/rest"
 )
 
 // Provider wraps a metrics.Metrics provider with a Prometheus registry that can
@@ -63,6 +64,17 @@ func (p *Provider) RegisterEndpoints(registrar func(path, method string, handler
 	registrar("/metrics", http.MethodGet, promhttp.HandlerFor(p.registry, promhttp.HandlerOpts{}))
 }
 
+// RegisterServiceStats registers a collector that reports the request counters
+// returned by f for each service that plugins communicate with.
+func (p *Provider) RegisterServiceStats(f func() map[string]rest.Stats) {
+	p.registry.MustRegister(newServiceCollector(f))
+}
+
+// RegisterCounterFunc registers a counter whose value is returned by f.
+func (p *Provider) RegisterCounterFunc(name, help string, f func() float64) error {
+	return p.registry.Register(prometheus.NewCounterFunc(prometheus.CounterOpts{Name: name, Help: help}, f))
+}
+
 // InstrumentHandler returned wrapped HTTP handler with added prometheus instrumentation
 func (p *Provider) InstrumentHandler(handler http.Handler, label string) http.Handler {
 	durationCollector := p.durationHistogram.MustCurryWith(prometheus.Labels{"handler": label})
@@ -136,6 +148,49 @@ func (p *Provider) Clear() {
 	p.inner.Clear()
 }
 
+type serviceCollector struct {
+	f        func() map[string]rest.Stats
+	requests *prometheus.Desc
+	failures *prometheus.Desc
+	retries  *prometheus.Desc
+	rejected *prometheus.Desc
+	open     *prometheus.Desc
+}
+
+func newServiceCollector(f func() map[string]rest.Stats) *serviceCollector {
+	labels := []string{"service"}
+	return &serviceCollector{
+		f:        f,
+		requests: prometheus.NewDesc("service_requests_total", "A count of requests sent to services (including retries.)", labels, nil),
+		failures: prometheus.NewDesc("service_request_failures_total", "A count of requests to services that failed.", labels, nil),
+		retries:  prometheus.NewDesc("service_request_retries_total", "A count of requests to services that were retried.", labels, nil),
+		rejected: prometheus.NewDesc("service_requests_rejected_total", "A count of requests rejected because the circuit breaker was open.", labels, nil),
+		open:     prometheus.NewDesc("service_circuit_open", "Indicates whether the circuit breaker for the service is open.", labels, nil),
+	}
+}
+
+func (c *serviceCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.requests
+	ch <- c.failures
+	ch <- c.retries
+	ch <- c.rejected
+	ch <- c.open
+}
+
+func (c *serviceCollector) Collect(ch chan<- prometheus.Metric) {
+	for name, stats := range c.f() {
+		var open float64
+		if stats.CircuitState == rest.CircuitOpen {
+			open = 1
+		}
+		ch <- prometheus.MustNewConstMetric(c.requests, prometheus.CounterValue, float64(stats.Requests), name)
+		ch <- prometheus.MustNewConstMetric(c.failures, prometheus.CounterValue, float64(stats.Failures), name)
+		ch <- prometheus.MustNewConstMetric(c.retries, prometheus.CounterValue, float64(stats.Retries), name)
+		ch <- prometheus.MustNewConstMetric(c.rejected, prometheus.CounterValue, float64(stats.Rejected), name)
+		ch <- prometheus.MustNewConstMetric(c.open, prometheus.GaugeValue, open, name)
+	}
+}
+
 type captureStatusResponseWriter struct {
 	http.ResponseWriter
 	status int
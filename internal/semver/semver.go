@@ -0,0 +1,182 @@
+// Copyright 2020 The OPA Authors.  All rights reserved.
+// Use of this source code is governed by an Apache2
+// license that can be found in the LICENSE file.
+
+// Package semver implements parsing and comparison of versions as defined by
+// Semantic Versioning 2.0.0 (https://semver.org).
+package semver
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Version represents a parsed semantic version.
+type Version struct {
+	Major      uint64
+	Minor      uint64
+	Patch      uint64
+	PreRelease []string
+	Metadata   string
+}
+
+// Parse returns the version represented by s. Versions must not be prefixed
+// with "v".
+func Parse(s string) (*Version, error) {
+
+	var v Version
+
+	rest := s
+
+	if i := strings.IndexByte(rest, '+'); i >= 0 {
+		v.Metadata = rest[i+1:]
+		rest = rest[:i]
+		if err := validateIdentifiers(v.Metadata, false); err != nil {
+			return nil, fmt.Errorf("%q: build metadata %v", s, err)
+		}
+	}
+
+	if i := strings.IndexByte(rest, '-'); i >= 0 {
+		pre := rest[i+1:]
+		rest = rest[:i]
+		if err := validateIdentifiers(pre, true); err != nil {
+			return nil, fmt.Errorf("%q: pre-release %v", s, err)
+		}
+		v.PreRelease = strings.Split(pre, ".")
+	}
+
+	parts := strings.Split(rest, ".")
+	if len(parts) != 3 {
+		return nil, fmt.Errorf("%q: version must have the form MAJOR.MINOR.PATCH", s)
+	}
+
+	nums := [3]*uint64{&v.Major, &v.Minor, &v.Patch}
+
+	for i, p := range parts {
+		if !isNumeric(p) || (len(p) > 1 && p[0] == '0') {
+			return nil, fmt.Errorf("%q: invalid version number %q", s, p)
+		}
+		n, err := strconv.ParseUint(p, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("%q: invalid version number %q", s, p)
+		}
+		*nums[i] = n
+	}
+
+	return &v, nil
+}
+
+// Compare returns -1, 0, or 1 if v is less than, equal to, or greater than
+// other. Build metadata is ignored.
+func (v *Version) Compare(other *Version) int {
+
+	if c := compareUint(v.Major, other.Major); c != 0 {
+		return c
+	}
+
+	if c := compareUint(v.Minor, other.Minor); c != 0 {
+		return c
+	}
+
+	if c := compareUint(v.Patch, other.Patch); c != 0 {
+		return c
+	}
+
+	// A version without pre-release identifiers has higher precedence than
+	// one with them.
+	switch {
+	case len(v.PreRelease) == 0 && len(other.PreRelease) == 0:
+		return 0
+	case len(v.PreRelease) == 0:
+		return 1
+	case len(other.PreRelease) == 0:
+		return -1
+	}
+
+	for i := 0; i < len(v.PreRelease) && i < len(other.PreRelease); i++ {
+		if c := compareIdentifier(v.PreRelease[i], other.PreRelease[i]); c != 0 {
+			return c
+		}
+	}
+
+	return compareUint(uint64(len(v.PreRelease)), uint64(len(other.PreRelease)))
+}
+
+func (v *Version) String() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%d.%d.%d", v.Major, v.Minor, v.Patch)
+	if len(v.PreRelease) > 0 {
+		b.WriteByte('-')
+		b.WriteString(strings.Join(v.PreRelease, "."))
+	}
+	if v.Metadata != "" {
+		b.WriteByte('+')
+		b.WriteString(v.Metadata)
+	}
+	return b.String()
+}
+
+// compareIdentifier compares pre-release identifiers. Numeric identifiers are
+// compared numerically and have lower precedence than alphanumeric ones.
+func compareIdentifier(a, b string) int {
+
+	aNum, bNum := isNumeric(a), isNumeric(b)
+
+	switch {
+	case aNum && bNum:
+		if c := compareUint(uint64(len(a)), uint64(len(b))); c != 0 {
+			return c
+		}
+		return strings.Compare(a, b)
+	case aNum:
+		return -1
+	case bNum:
+		return 1
+	}
+
+	return strings.Compare(a, b)
+}
+
+func validateIdentifiers(s string, pre bool) error {
+	for _, id := range strings.Split(s, ".") {
+		if id == "" {
+			return fmt.Errorf("contains empty identifier")
+		}
+		for _, r := range id {
+			if !isAlphanumeric(r) && r != '-' {
+				return fmt.Errorf("identifier %q contains invalid character %q", id, r)
+			}
+		}
+		if pre && isNumeric(id) && len(id) > 1 && id[0] == '0' {
+			return fmt.Errorf("identifier %q has leading zeros", id)
+		}
+	}
+	return nil
+}
+
+func isNumeric(s string) bool {
+	if s == "" {
+		return false
+	}
+	for _, r := range s {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+func isAlphanumeric(r rune) bool {
+	return (r >= '0' && r <= '9') || (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z')
+}
+
+func compareUint(a, b uint64) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+	return 0
+}
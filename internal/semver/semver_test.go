@@ -0,0 +1,123 @@
+// Copyright 2020 The OPA Authors.  All rights reserved.
+// Use of this source code is governed by an Apache2
+// license that can be found in the LICENSE file.
+
+package semver
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+
+	valid := []struct {
+		input    string
+		expected Version
+	}{
+		{"0.0.0", Version{}},
+		{"1.2.3", Version{Major: 1, Minor: 2, Patch: 3}},
+		{"1.0.0-alpha", Version{Major: 1, PreRelease: []string{"alpha"}}},
+		{"1.0.0-alpha.1", Version{Major: 1, PreRelease: []string{"alpha", "1"}}},
+		{"1.0.0-0.3.7", Version{Major: 1, PreRelease: []string{"0", "3", "7"}}},
+		{"1.0.0-x-y-z.--", Version{Major: 1, PreRelease: []string{"x-y-z", "--"}}},
+		{"1.0.0+20130313144700", Version{Major: 1, Metadata: "20130313144700"}},
+		{"1.0.0-beta+exp.sha.5114f85", Version{Major: 1, PreRelease: []string{"beta"}, Metadata: "exp.sha.5114f85"}},
+		{"1.0.0+0001", Version{Major: 1, Metadata: "0001"}},
+	}
+
+	for _, tc := range valid {
+		t.Run(tc.input, func(t *testing.T) {
+			v, err := Parse(tc.input)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(*v, tc.expected) {
+				t.Fatalf("Expected %+v but got %+v", tc.expected, *v)
+			}
+			if v.String() != tc.input {
+				t.Fatalf("Expected %v but got %v", tc.input, v.String())
+			}
+		})
+	}
+
+	invalid := []string{
+		"",
+		"1",
+		"1.2",
+		"1.2.3.4",
+		"v1.2.3",
+		"01.2.3",
+		"1.02.3",
+		"1.2.03",
+		"-1.2.3",
+		"1.2.3-",
+		"1.2.3-01",
+		"1.2.3-alpha..1",
+		"1.2.3-alpha_1",
+		"1.2.3+",
+		"1.2.3+a+b",
+		"1.2.3 ",
+		"99999999999999999999.0.0",
+	}
+
+	for _, s := range invalid {
+		t.Run(s, func(t *testing.T) {
+			if v, err := Parse(s); err == nil {
+				t.Fatalf("Expected error but got %v", v)
+			}
+		})
+	}
+}
+
+func TestCompare(t *testing.T) {
+
+	// Ordered by precedence as given in the specification.
+	ordered := []string{
+		"1.0.0-alpha",
+		"1.0.0-alpha.1",
+		"1.0.0-alpha.beta",
+		"1.0.0-beta",
+		"1.0.0-beta.2",
+		"1.0.0-beta.11",
+		"1.0.0-rc.1",
+		"1.0.0",
+		"1.0.1",
+		"1.1.0",
+		"1.10.0",
+		"2.0.0",
+	}
+
+	for i := range ordered {
+		for j := range ordered {
+			a, b := mustParse(t, ordered[i]), mustParse(t, ordered[j])
+			expected := compareInt(i, j)
+			if c := a.Compare(b); c != expected {
+				t.Errorf("Expected %v compared to %v to be %d but got %d", a, b, expected, c)
+			}
+		}
+	}
+
+	if c := mustParse(t, "1.0.0+a").Compare(mustParse(t, "1.0.0+b")); c != 0 {
+		t.Fatalf("Expected build metadata to be ignored but got %d", c)
+	}
+}
+
+func mustParse(t *testing.T, s string) *Version {
+	t.Helper()
+	v, err := Parse(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return v
+}
+
+func compareInt(a, b int) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+	return 0
+}
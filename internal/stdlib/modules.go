@@ -0,0 +1,155 @@
+// Copyright 2020 The OPA Authors.  All rights reserved.
+// Use of this source code is governed by an Apache2
+// license that can be found in the LICENSE file.
+
+package stdlib
+
+// sources contains the policies of the standard library keyed by path. The
+// packages are defined under the stdlib root (e.g., data.stdlib.strings.)
+var sources = map[string]string{
+	"/strings.rego": `# Helpers for matching and building strings.
+package stdlib.strings
+
+# any_prefix_match is true if str starts with any of the prefixes.
+any_prefix_match(str, prefixes) {
+	startswith(str, prefixes[_])
+}
+
+# any_suffix_match is true if str ends with any of the suffixes.
+any_suffix_match(str, suffixes) {
+	endswith(str, suffixes[_])
+}
+
+# is_blank is true if str is empty or only contains whitespace.
+is_blank(str) {
+	trim(str, " \t\r\n") == ""
+}
+
+# join_nonempty joins the non-empty strings in arr with delim.
+join_nonempty(delim, arr) = concat(delim, [s | s := arr[_]; s != ""])
+`,
+
+	"/sets.rego": `# Helpers for working with sets.
+package stdlib.sets
+
+# to_set returns a set containing the elements of the array, set, or object
+# values in coll.
+to_set(coll) = {x | x := coll[_]}
+
+# is_subset is true if every element of a is contained in b.
+is_subset(a, b) {
+	count(a - b) == 0
+}
+
+# intersects is true if a and b have at least one element in common.
+intersects(a, b) {
+	count(a & b) > 0
+}
+
+# disjoint is true if a and b have no elements in common.
+disjoint(a, b) {
+	count(a & b) == 0
+}
+`,
+
+	"/k8s.rego": `# Accessors for Kubernetes objects, e.g., input.request.object in admission
+# reviews.
+package stdlib.k8s
+
+# workload_kinds are the kinds of objects that define a pod template.
+workload_kinds = {"DaemonSet", "Deployment", "Job", "ReplicaSet", "ReplicationController", "StatefulSet"}
+
+# name returns the name of the object.
+name(obj) = obj.metadata.name
+
+# namespace returns the namespace of the object. If the object does not set a
+# namespace, the default namespace is returned.
+namespace(obj) = ns {
+	ns := obj.metadata.namespace
+}
+
+namespace(obj) = "default" {
+	not obj.metadata.namespace
+}
+
+# labels returns the labels of the object or an empty object.
+labels(obj) = ls {
+	ls := obj.metadata.labels
+}
+
+labels(obj) = {} {
+	not obj.metadata.labels
+}
+
+# label returns the value of the label key on the object.
+label(obj, key) = value {
+	ls := labels(obj)
+	value := ls[key]
+}
+
+# annotations returns the annotations of the object or an empty object.
+annotations(obj) = anns {
+	anns := obj.metadata.annotations
+}
+
+annotations(obj) = {} {
+	not obj.metadata.annotations
+}
+
+# pod_spec returns the pod spec of pods and the pod template spec of workloads.
+pod_spec(obj) = obj.spec {
+	obj.kind == "Pod"
+}
+
+pod_spec(obj) = obj.spec.template.spec {
+	workload_kinds[obj.kind]
+}
+
+pod_spec(obj) = obj.spec.jobTemplate.spec.template.spec {
+	obj.kind == "CronJob"
+}
+
+# containers returns the containers and init containers of the pod spec.
+containers(obj) = cs {
+	spec := pod_spec(obj)
+	cs := array.concat([c | c := spec.containers[_]], [c | c := spec.initContainers[_]])
+}
+
+# images returns the set of images used by the containers of the pod spec.
+images(obj) = {img | cs := containers(obj); img := cs[_].image}
+`,
+
+	"/rbac.rego": `# Helpers for evaluating role-based access control. Roles map role names to
+# lists of grants and bindings map subjects to lists of role names:
+#
+#	roles = {"reader": [{"action": "read", "resource": "docs/*"}]}
+#	bindings = {"alice": ["reader"]}
+#
+# Grant actions may be "*" to match all actions. Grant resources are glob
+# patterns delimited by "/".
+package stdlib.rbac
+
+# roles_for returns the set of roles bound to the subject.
+roles_for(bindings, subject) = {r | r := bindings[subject][_]}
+
+# grants_for returns the set of grants of the roles bound to the subject.
+grants_for(roles, bindings, subject) = {g | rs := roles_for(bindings, subject); g := roles[rs[_]][_]}
+
+# allowed is true if a role bound to the subject grants the action on the
+# resource.
+allowed(roles, bindings, subject, action, resource) {
+	gs := grants_for(roles, bindings, subject)
+	g := gs[_]
+	action_matches(g.action, action)
+	glob.match(g.resource, ["/"], resource)
+}
+
+action_matches(pattern, action) {
+	pattern == "*"
+}
+
+action_matches(pattern, action) {
+	pattern == action
+}
+`,
+}
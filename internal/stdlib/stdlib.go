@@ -0,0 +1,59 @@
+// Copyright 2020 The OPA Authors.  All rights reserved.
+// Use of this source code is governed by an Apache2
+// license that can be found in the LICENSE file.
+
+// Package stdlib contains the standard library of Rego helpers (strings, sets,
+// Kubernetes object accessors, and RBAC evaluation) that ships with OPA. The
+// library is versioned alongside the engine and can be included by the build
+// and run commands on demand.
+package stdlib
+
+import (
+	"sort"
+
+	"github.com/open-policy-agent/opa/ast"
+	"github.com/open-policy-agent/opa/bundle"
+	"github.com/open-policy-agent/opa/version"
+)
+
+// Name is the name of the standard library. The policies of the library are
+// defined under data.stdlib.
+const Name = "stdlib"
+
+// Modules returns the parsed policies of the standard library sorted by path.
+// The paths are relative to the root of the library.
+func Modules() ([]bundle.ModuleFile, error) {
+
+	paths := make([]string, 0, len(sources))
+	for path := range sources {
+		paths = append(paths, path)
+	}
+
+	sort.Strings(paths)
+
+	result := make([]bundle.ModuleFile, len(paths))
+
+	for i, path := range paths {
+		parsed, err := ast.ParseModule(Name+path, sources[path])
+		if err != nil {
+			return nil, err
+		}
+		result[i] = bundle.ModuleFile{
+			Path:   path,
+			Raw:    []byte(sources[path]),
+			Parsed: parsed,
+		}
+	}
+
+	return result, nil
+}
+
+// Dependency returns the dependency to list in the manifests of bundles that
+// include the standard library.
+func Dependency(modules []bundle.ModuleFile) bundle.Dependency {
+	return bundle.Dependency{
+		Name:     Name,
+		Revision: version.Version,
+		Digest:   bundle.DependencyDigest(modules),
+	}
+}
@@ -0,0 +1,88 @@
+// Copyright 2020 The OPA Authors.  All rights reserved.
+// Use of this source code is governed by an Apache2
+// license that can be found in the LICENSE file.
+
+package stdlib
+
+import (
+	"context"
+	"testing"
+
+	"github.com/open-policy-agent/opa/rego"
+	"github.com/open-policy-agent/opa/util"
+)
+
+func TestStdlib(t *testing.T) {
+
+	modules, err := Modules()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	pod := `{"kind": "Pod", "metadata": {"name": "p", "labels": {"app": "web"}}, "spec": {"containers": [{"image": "nginx"}], "initContainers": [{"image": "busybox"}]}}`
+	deployment := `{"kind": "Deployment", "metadata": {"name": "d", "namespace": "prod"}, "spec": {"template": {"spec": {"containers": [{"image": "nginx"}, {"image": "envoy"}]}}}}`
+	rbac := `roles := {"reader": [{"action": "read", "resource": "docs/*"}], "admin": [{"action": "*", "resource": "**"}]}; bindings := {"alice": ["reader"], "bob": ["admin"]}`
+
+	tests := []struct {
+		note     string
+		query    string
+		expected string
+	}{
+		{"strings prefix", `x := data.stdlib.strings.any_prefix_match("foo/bar", ["baz", "foo/"])`, `true`},
+		{"strings suffix", `x := data.stdlib.strings.any_suffix_match("foo.rego", [".rego"])`, `true`},
+		{"strings blank", `x := data.stdlib.strings.is_blank(" \t")`, `true`},
+		{"strings join", `x := data.stdlib.strings.join_nonempty(",", ["a", "", "b"])`, `"a,b"`},
+		{"sets to_set", `x := data.stdlib.sets.to_set(["a", "b", "a"]) == {"a", "b"}`, `true`},
+		{"sets subset", `x := data.stdlib.sets.is_subset({1}, {1, 2})`, `true`},
+		{"sets intersects", `x := data.stdlib.sets.intersects({1, 3}, {1, 2})`, `true`},
+		{"sets disjoint", `x := data.stdlib.sets.disjoint({3}, {1, 2})`, `true`},
+		{"k8s name", `x := data.stdlib.k8s.name(` + pod + `)`, `"p"`},
+		{"k8s default namespace", `x := data.stdlib.k8s.namespace(` + pod + `)`, `"default"`},
+		{"k8s namespace", `x := data.stdlib.k8s.namespace(` + deployment + `)`, `"prod"`},
+		{"k8s label", `x := data.stdlib.k8s.label(` + pod + `, "app")`, `"web"`},
+		{"k8s empty labels", `x := data.stdlib.k8s.labels(` + deployment + `)`, `{}`},
+		{"k8s pod images", `x := data.stdlib.k8s.images(` + pod + `) == {"busybox", "nginx"}`, `true`},
+		{"k8s workload images", `x := data.stdlib.k8s.images(` + deployment + `) == {"envoy", "nginx"}`, `true`},
+		{"rbac roles", rbac + `; x := data.stdlib.rbac.roles_for(bindings, "alice")`, `["reader"]`},
+		{"rbac allowed", rbac + `; x := data.stdlib.rbac.allowed(roles, bindings, "alice", "read", "docs/a")`, `true`},
+		{"rbac wildcard", rbac + `; x := data.stdlib.rbac.allowed(roles, bindings, "bob", "write", "docs/a/b")`, `true`},
+		{"rbac denied action", rbac + `; not data.stdlib.rbac.allowed(roles, bindings, "alice", "write", "docs/a"); x := false`, `false`},
+		{"rbac denied resource", rbac + `; not data.stdlib.rbac.allowed(roles, bindings, "alice", "read", "docs/a/b"); x := false`, `false`},
+		{"rbac unbound", rbac + `; not data.stdlib.rbac.allowed(roles, bindings, "carol", "read", "docs/a"); x := false`, `false`},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.note, func(t *testing.T) {
+
+			args := []func(*rego.Rego){rego.Query(tc.query)}
+			for _, m := range modules {
+				args = append(args, rego.ParsedModule(m.Parsed))
+			}
+
+			rs, err := rego.New(args...).Eval(context.Background())
+			if err != nil {
+				t.Fatal(err)
+			} else if len(rs) != 1 {
+				t.Fatalf("Expected exactly one result but got: %v", rs)
+			}
+
+			expected := util.MustUnmarshalJSON([]byte(tc.expected))
+			if util.Compare(rs[0].Bindings["x"], expected) != 0 {
+				t.Fatalf("Expected %v but got %v", expected, rs[0].Bindings["x"])
+			}
+		})
+	}
+}
+
+func TestDependency(t *testing.T) {
+
+	modules, err := Modules()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dep := Dependency(modules)
+	if dep.Name != Name || dep.Digest == "" {
+		t.Fatalf("Unexpected dependency: %+v", dep)
+	}
+}
@@ -0,0 +1,385 @@
+// Copyright 2019 The OPA Authors.  All rights reserved.
+// Use of this source code is governed by an Apache2
+// license that can be found in the LICENSE file.
+
+// Package websocket implements the subset of the WebSocket protocol (RFC 6455)
+// required by the server: the opening handshake and text message exchange
+// with support for control frames.
+package websocket
+
+import (
+	"bufio"
+	"crypto/rand"
+	"crypto/sha1"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+)
+
+const (
+	opContinuation = 0x0
+	opText         = 0x1
+	opBinary       = 0x2
+	opClose        = 0x8
+	opPing         = 0x9
+	opPong         = 0xA
+
+	finBit  = 0x80
+	maskBit = 0x80
+
+	maxControlPayload = 125
+
+	// DefaultMaxMessageSize is the default limit on the size of messages read
+	// from the peer.
+	DefaultMaxMessageSize = 1 << 20
+
+	acceptGUID = "258EAFA5-E914-47DA-95CA-C5AB0DC85B11"
+)
+
+// ErrClosed is returned by ReadMessage when the peer closes the connection.
+var ErrClosed = errors.New("websocket: connection closed")
+
+// Conn represents a WebSocket connection.
+type Conn struct {
+	conn      net.Conn
+	rw        *bufio.ReadWriter
+	client    bool // clients must mask frames they send; servers must not
+	maxSize   int64
+	writeMu   sync.Mutex
+	closeOnce sync.Once
+}
+
+// IsUpgrade returns true if the request is a WebSocket opening handshake.
+func IsUpgrade(r *http.Request) bool {
+	return headerContains(r.Header, "Connection", "upgrade") && headerContains(r.Header, "Upgrade", "websocket")
+}
+
+// Upgrade performs the server side of the opening handshake and returns the
+// connection. If the handshake fails, an HTTP error is written to w.
+func Upgrade(w http.ResponseWriter, r *http.Request) (*Conn, error) {
+
+	if r.Method != http.MethodGet {
+		return nil, handshakeError(w, http.StatusMethodNotAllowed, "method must be GET")
+	}
+
+	if !IsUpgrade(r) {
+		return nil, handshakeError(w, http.StatusBadRequest, "missing upgrade headers")
+	}
+
+	if r.Header.Get("Sec-WebSocket-Version") != "13" {
+		w.Header().Set("Sec-WebSocket-Version", "13")
+		return nil, handshakeError(w, http.StatusBadRequest, "unsupported version")
+	}
+
+	key := r.Header.Get("Sec-WebSocket-Key")
+	if key == "" {
+		return nil, handshakeError(w, http.StatusBadRequest, "missing key")
+	}
+
+	h, ok := w.(http.Hijacker)
+	if !ok {
+		return nil, handshakeError(w, http.StatusInternalServerError, "server does not support hijacking")
+	}
+
+	conn, rw, err := h.Hijack()
+	if err != nil {
+		return nil, err
+	}
+
+	fmt.Fprintf(rw, "HTTP/1.1 101 Switching Protocols\r\n")
+	fmt.Fprintf(rw, "Upgrade: websocket\r\n")
+	fmt.Fprintf(rw, "Connection: Upgrade\r\n")
+	fmt.Fprintf(rw, "Sec-WebSocket-Accept: %s\r\n\r\n", acceptKey(key))
+
+	if err := rw.Flush(); err != nil {
+		conn.Close()
+		return nil, err
+	}
+
+	return newConn(conn, rw, false), nil
+}
+
+// Dial opens a client connection to the WebSocket endpoint at rawurl. The URL
+// scheme must be ws or http. Dial is primarily intended for tests.
+func Dial(rawurl string) (*Conn, error) {
+
+	u, err := url.Parse(rawurl)
+	if err != nil {
+		return nil, err
+	}
+
+	switch u.Scheme {
+	case "ws", "http":
+	default:
+		return nil, fmt.Errorf("websocket: unsupported scheme %q", u.Scheme)
+	}
+
+	conn, err := net.Dial("tcp", u.Host)
+	if err != nil {
+		return nil, err
+	}
+
+	nonce := make([]byte, 16)
+	if _, err := rand.Read(nonce); err != nil {
+		conn.Close()
+		return nil, err
+	}
+
+	key := base64.StdEncoding.EncodeToString(nonce)
+	rw := bufio.NewReadWriter(bufio.NewReader(conn), bufio.NewWriter(conn))
+
+	fmt.Fprintf(rw, "GET %s HTTP/1.1\r\n", u.RequestURI())
+	fmt.Fprintf(rw, "Host: %s\r\n", u.Host)
+	fmt.Fprintf(rw, "Upgrade: websocket\r\n")
+	fmt.Fprintf(rw, "Connection: Upgrade\r\n")
+	fmt.Fprintf(rw, "Sec-WebSocket-Key: %s\r\n", key)
+	fmt.Fprintf(rw, "Sec-WebSocket-Version: 13\r\n\r\n")
+
+	if err := rw.Flush(); err != nil {
+		conn.Close()
+		return nil, err
+	}
+
+	resp, err := http.ReadResponse(rw.Reader, &http.Request{Method: http.MethodGet})
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+
+	if resp.StatusCode != http.StatusSwitchingProtocols {
+		conn.Close()
+		return nil, fmt.Errorf("websocket: handshake failed with HTTP %v", resp.StatusCode)
+	}
+
+	if resp.Header.Get("Sec-WebSocket-Accept") != acceptKey(key) {
+		conn.Close()
+		return nil, errors.New("websocket: bad accept key")
+	}
+
+	return newConn(conn, rw, true), nil
+}
+
+func newConn(conn net.Conn, rw *bufio.ReadWriter, client bool) *Conn {
+	return &Conn{
+		conn:    conn,
+		rw:      rw,
+		client:  client,
+		maxSize: DefaultMaxMessageSize,
+	}
+}
+
+// SetMaxMessageSize sets the limit on the size of messages read from the peer.
+func (c *Conn) SetMaxMessageSize(n int64) {
+	c.maxSize = n
+}
+
+// ReadMessage returns the next data message sent by the peer. Control frames
+// are handled transparently: pings are answered and a close frame results in
+// ErrClosed.
+func (c *Conn) ReadMessage() ([]byte, error) {
+
+	var msg []byte
+	var started bool
+
+	for {
+		fin, op, payload, err := c.readFrame()
+		if err != nil {
+			return nil, err
+		}
+
+		switch op {
+		case opPing:
+			if err := c.writeFrame(opPong, payload); err != nil {
+				return nil, err
+			}
+			continue
+		case opPong:
+			continue
+		case opClose:
+			c.writeFrame(opClose, payload)
+			return nil, ErrClosed
+		case opText, opBinary:
+			if started {
+				return nil, c.protocolError("unexpected data frame")
+			}
+			started = true
+		case opContinuation:
+			if !started {
+				return nil, c.protocolError("unexpected continuation frame")
+			}
+		default:
+			return nil, c.protocolError("unknown opcode")
+		}
+
+		if int64(len(msg)+len(payload)) > c.maxSize {
+			return nil, c.protocolError("message too large")
+		}
+
+		msg = append(msg, payload...)
+
+		if fin {
+			return msg, nil
+		}
+	}
+}
+
+// WriteMessage sends a text message to the peer. WriteMessage is safe to call
+// from multiple goroutines.
+func (c *Conn) WriteMessage(data []byte) error {
+	return c.writeFrame(opText, data)
+}
+
+// Close sends a close frame to the peer and closes the underlying connection.
+func (c *Conn) Close() error {
+	var err error
+	c.closeOnce.Do(func() {
+		c.writeFrame(opClose, []byte{0x03, 0xE8}) // 1000: normal closure
+		err = c.conn.Close()
+	})
+	return err
+}
+
+func (c *Conn) protocolError(msg string) error {
+	c.writeFrame(opClose, append([]byte{0x03, 0xEA}, msg...)) // 1002: protocol error
+	return errors.New("websocket: " + msg)
+}
+
+func (c *Conn) readFrame() (fin bool, op byte, payload []byte, err error) {
+
+	var hdr [2]byte
+
+	if _, err = io.ReadFull(c.rw, hdr[:]); err != nil {
+		return false, 0, nil, err
+	}
+
+	fin = hdr[0]&finBit != 0
+	op = hdr[0] & 0x0F
+	masked := hdr[1]&maskBit != 0
+	length := int64(hdr[1] & 0x7F)
+
+	if masked == c.client {
+		return false, 0, nil, c.protocolError("bad frame masking")
+	}
+
+	switch length {
+	case 126:
+		var ext [2]byte
+		if _, err = io.ReadFull(c.rw, ext[:]); err != nil {
+			return false, 0, nil, err
+		}
+		length = int64(binary.BigEndian.Uint16(ext[:]))
+	case 127:
+		var ext [8]byte
+		if _, err = io.ReadFull(c.rw, ext[:]); err != nil {
+			return false, 0, nil, err
+		}
+		length = int64(binary.BigEndian.Uint64(ext[:]))
+	}
+
+	if op >= opClose && (length > maxControlPayload || !fin) {
+		return false, 0, nil, c.protocolError("bad control frame")
+	}
+
+	if length < 0 || length > c.maxSize {
+		return false, 0, nil, c.protocolError("message too large")
+	}
+
+	var mask [4]byte
+
+	if masked {
+		if _, err = io.ReadFull(c.rw, mask[:]); err != nil {
+			return false, 0, nil, err
+		}
+	}
+
+	payload = make([]byte, length)
+
+	if _, err = io.ReadFull(c.rw, payload); err != nil {
+		return false, 0, nil, err
+	}
+
+	if masked {
+		for i := range payload {
+			payload[i] ^= mask[i%4]
+		}
+	}
+
+	return fin, op, payload, nil
+}
+
+func (c *Conn) writeFrame(op byte, payload []byte) error {
+
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+
+	buf := []byte{finBit | op}
+
+	var maskFlag byte
+	if c.client {
+		maskFlag = maskBit
+	}
+
+	switch n := len(payload); {
+	case n <= 125:
+		buf = append(buf, maskFlag|byte(n))
+	case n <= 0xFFFF:
+		buf = append(buf, maskFlag|126, 0, 0)
+		binary.BigEndian.PutUint16(buf[2:], uint16(n))
+	default:
+		buf = append(buf, maskFlag|127, 0, 0, 0, 0, 0, 0, 0, 0)
+		binary.BigEndian.PutUint64(buf[2:], uint64(n))
+	}
+
+	if c.client {
+		var mask [4]byte
+		if _, err := rand.Read(mask[:]); err != nil {
+			return err
+		}
+		buf = append(buf, mask[:]...)
+		masked := make([]byte, len(payload))
+		for i := range payload {
+			masked[i] = payload[i] ^ mask[i%4]
+		}
+		payload = masked
+	}
+
+	if _, err := c.rw.Write(buf); err != nil {
+		return err
+	}
+
+	if _, err := c.rw.Write(payload); err != nil {
+		return err
+	}
+
+	return c.rw.Flush()
+}
+
+func acceptKey(key string) string {
+	h := sha1.New()
+	h.Write([]byte(key + acceptGUID))
+	return base64.StdEncoding.EncodeToString(h.Sum(nil))
+}
+
+func headerContains(header http.Header, name, value string) bool {
+	for _, v := range header[http.CanonicalHeaderKey(name)] {
+		for _, s := range strings.Split(v, ",") {
+			if strings.EqualFold(strings.TrimSpace(s), value) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func handshakeError(w http.ResponseWriter, code int, msg string) error {
+	err := errors.New("websocket: " + msg)
+	http.Error(w, err.Error(), code)
+	return err
+}
@@ -0,0 +1,192 @@
+// Copyright 2019 The OPA Authors.  All rights reserved.
+// Use of this source code is governed by an Apache2
+// license that can be found in the LICENSE file.
+
+package websocket
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func newEchoServer(t *testing.T) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := Upgrade(w, r)
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		for {
+			msg, err := conn.ReadMessage()
+			if err != nil {
+				return
+			}
+			if err := conn.WriteMessage(msg); err != nil {
+				return
+			}
+		}
+	}))
+}
+
+func TestEcho(t *testing.T) {
+
+	ts := newEchoServer(t)
+	defer ts.Close()
+
+	conn, err := Dial(ts.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer conn.Close()
+
+	msgs := [][]byte{
+		[]byte("hello"),
+		bytes.Repeat([]byte("x"), 300),     // 16-bit length
+		bytes.Repeat([]byte("y"), 1<<16+1), // 64-bit length
+	}
+
+	for _, msg := range msgs {
+		if err := conn.WriteMessage(msg); err != nil {
+			t.Fatal(err)
+		}
+		result, err := conn.ReadMessage()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(result, msg) {
+			t.Fatalf("Expected %d bytes echoed but got %d", len(msg), len(result))
+		}
+	}
+}
+
+func TestPingAndFragments(t *testing.T) {
+
+	ts := newEchoServer(t)
+	defer ts.Close()
+
+	conn, err := Dial(ts.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer conn.Close()
+
+	// A ping interleaved with a fragmented message must be answered without
+	// disturbing reassembly.
+	frames := []struct {
+		op  byte
+		fin bool
+		s   string
+	}{
+		{opText, false, "foo"},
+		{opPing, true, "ping"},
+		{opContinuation, true, "bar"},
+	}
+
+	for _, f := range frames {
+		if err := conn.writeRawFrame(f.op, f.fin, []byte(f.s)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	_, op, payload, err := conn.readFrame()
+	if err != nil {
+		t.Fatal(err)
+	} else if op != opPong || string(payload) != "ping" {
+		t.Fatalf("Expected pong but got op %v: %q", op, payload)
+	}
+
+	msg, err := conn.ReadMessage()
+	if err != nil {
+		t.Fatal(err)
+	} else if string(msg) != "foobar" {
+		t.Fatalf("Expected reassembled message but got %q", msg)
+	}
+}
+
+func TestMaxMessageSize(t *testing.T) {
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := Upgrade(w, r)
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		conn.SetMaxMessageSize(4)
+		conn.ReadMessage()
+	}))
+	defer ts.Close()
+
+	conn, err := Dial(ts.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer conn.Close()
+
+	if err := conn.WriteMessage([]byte("too long")); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := conn.ReadMessage(); err != ErrClosed {
+		t.Fatalf("Expected close but got: %v", err)
+	}
+}
+
+func TestUpgradeErrors(t *testing.T) {
+
+	ts := newEchoServer(t)
+	defer ts.Close()
+
+	tests := []struct {
+		note    string
+		headers map[string]string
+	}{
+		{"missing upgrade", map[string]string{}},
+		{"bad version", map[string]string{"Connection": "Upgrade", "Upgrade": "websocket", "Sec-WebSocket-Version": "8", "Sec-WebSocket-Key": "x"}},
+		{"missing key", map[string]string{"Connection": "keep-alive, Upgrade", "Upgrade": "websocket", "Sec-WebSocket-Version": "13"}},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.note, func(t *testing.T) {
+			req, err := http.NewRequest("GET", ts.URL, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for k, v := range tc.headers {
+				req.Header.Set(k, v)
+			}
+			resp, err := http.DefaultClient.Do(req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			resp.Body.Close()
+			if resp.StatusCode != http.StatusBadRequest {
+				t.Fatalf("Expected bad request but got %v", resp.StatusCode)
+			}
+		})
+	}
+
+	if _, err := Dial(strings.Replace(ts.URL, "http", "https", 1)); err == nil {
+		t.Fatal("Expected unsupported scheme error")
+	}
+}
+
+func (c *Conn) writeRawFrame(op byte, fin bool, payload []byte) error {
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+	var b0 byte = op
+	if fin {
+		b0 |= finBit
+	}
+	buf := []byte{b0, maskBit | byte(len(payload)), 0, 0, 0, 0}
+	buf = append(buf, payload...)
+	if _, err := c.rw.Write(buf); err != nil {
+		return err
+	}
+	return c.rw.Flush()
+}
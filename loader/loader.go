@@ -6,8 +6,10 @@
 package loader
 
 import (
+	"bufio"
 	"bytes"
 	"fmt"
+	"io"
 	"io/ioutil"
 	"os"
 	"path/filepath"
@@ -87,8 +89,20 @@ type FileLoader interface {
 	AsBundle(path string) (*bundle.Bundle, error)
 
 	WithMetrics(m metrics.Metrics) FileLoader
+	WithProgress(fn ProgressFunc) FileLoader
 }
 
+// ProgressFunc is called while large JSON files are loaded with the number of
+// bytes read so far and the size of the file.
+type ProgressFunc func(path string, read, total int64)
+
+// Large JSON files are decoded incrementally so that memory usage does not
+// double while they are loaded. Progress is reported for these files.
+var (
+	incrementalThreshold int64 = 32 * 1024 * 1024
+	progressInterval     int64 = 16 * 1024 * 1024
+)
+
 // NewFileLoader returns a new FileLoader instance.
 func NewFileLoader() FileLoader {
 	return &fileLoader{
@@ -97,7 +111,8 @@ func NewFileLoader() FileLoader {
 }
 
 type fileLoader struct {
-	metrics metrics.Metrics
+	metrics  metrics.Metrics
+	progress ProgressFunc
 }
 
 // WithMetrics provides the metrics instance to use while loading
@@ -106,6 +121,12 @@ func (fl *fileLoader) WithMetrics(m metrics.Metrics) FileLoader {
 	return fl
 }
 
+// WithProgress provides the function to call while large files are loaded.
+func (fl *fileLoader) WithProgress(fn ProgressFunc) FileLoader {
+	fl.progress = fn
+	return fl
+}
+
 // All returns a Result object loaded (recursively) from the specified paths.
 func (fl fileLoader) All(paths []string) (*Result, error) {
 	return fl.Filtered(paths, nil)
@@ -117,6 +138,20 @@ func (fl fileLoader) All(paths []string) (*Result, error) {
 func (fl fileLoader) Filtered(paths []string, filter Filter) (*Result, error) {
 	return all(paths, filter, func(curr *Result, path string, depth int) error {
 
+		if filepath.Ext(path) == ".json" {
+			info, err := os.Stat(path)
+			if err != nil {
+				return err
+			}
+			if info.Size() >= incrementalThreshold {
+				result, err := fl.loadLargeJSON(path, info.Size())
+				if err != nil {
+					return err
+				}
+				return curr.merge(path, result)
+			}
+		}
+
 		bs, err := ioutil.ReadFile(path)
 		if err != nil {
 			return err
@@ -449,6 +484,57 @@ func loadJSON(path string, bs []byte, m metrics.Metrics) (interface{}, error) {
 	return x, nil
 }
 
+func (fl fileLoader) loadLargeJSON(path string, size int64) (interface{}, error) {
+
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+
+	defer f.Close()
+
+	r := &progressReader{
+		reader: bufio.NewReader(f),
+		path:   path,
+		total:  size,
+		next:   progressInterval,
+		fn:     fl.progress,
+	}
+
+	fl.metrics.Timer(metrics.RegoDataParse).Start()
+	x, err := util.UnmarshalJSONIncremental(r)
+	fl.metrics.Timer(metrics.RegoDataParse).Stop()
+	if err != nil {
+		return nil, errors.Wrap(err, path)
+	}
+
+	if r.fn != nil {
+		r.fn(path, r.read, size)
+	}
+
+	return x, nil
+}
+
+// progressReader calls fn each time another interval of bytes has been read.
+type progressReader struct {
+	reader io.Reader
+	path   string
+	read   int64
+	total  int64
+	next   int64
+	fn     ProgressFunc
+}
+
+func (r *progressReader) Read(bs []byte) (int, error) {
+	n, err := r.reader.Read(bs)
+	r.read += int64(n)
+	if r.fn != nil && r.read >= r.next && r.read < r.total {
+		r.fn(r.path, r.read, r.total)
+		r.next = r.read + progressInterval
+	}
+	return n, err
+}
+
 func loadYAML(path string, bs []byte, m metrics.Metrics) (interface{}, error) {
 	m.Timer(metrics.RegoDataParse).Start()
 	bs, err := yaml.YAMLToJSON(bs)
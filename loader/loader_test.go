@@ -41,6 +41,52 @@ func TestLoadJSON(t *testing.T) {
 	})
 }
 
+func TestLoadLargeJSON(t *testing.T) {
+
+	defer func(threshold, interval int64) {
+		incrementalThreshold, progressInterval = threshold, interval
+	}(incrementalThreshold, progressInterval)
+
+	incrementalThreshold, progressInterval = 16, 8
+
+	files := map[string]string{
+		"/x/foo.json": `{"a": [1, 2, 3], "b": {"c": "some long string"}}`,
+		"/x/bar.json": `{"d": 1}`,
+		"/bad.json":   `{"a": [1, 2, 3]`,
+	}
+
+	test.WithTempFS(files, func(rootDir string) {
+
+		var reports []int64
+
+		loaded, err := NewFileLoader().WithProgress(func(path string, read, total int64) {
+			if filepath.Base(path) != "foo.json" || total != int64(len(files["/x/foo.json"])) {
+				t.Fatalf("Unexpected progress report for %v (total: %v)", path, total)
+			}
+			reports = append(reports, read)
+		}).All([]string{filepath.Join(rootDir, "x")})
+
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+
+		expected := parseJSON(`{"a": [1, 2, 3], "b": {"c": "some long string"}, "d": 1}`)
+
+		if !reflect.DeepEqual(loaded.Documents, expected) {
+			t.Fatalf("Expected %v but got: %v", expected, loaded.Documents)
+		}
+
+		if len(reports) == 0 || reports[len(reports)-1] != int64(len(files["/x/foo.json"])) {
+			t.Fatalf("Expected progress to be reported until the file was read but got: %v", reports)
+		}
+
+		_, err = NewFileLoader().All([]string{filepath.Join(rootDir, "bad.json")})
+		if err == nil || !strings.Contains(err.Error(), "bad.json") {
+			t.Fatalf("Expected error for bad.json but got: %v", err)
+		}
+	})
+}
+
 func TestLoadRego(t *testing.T) {
 
 	files := map[string]string{
@@ -30,6 +30,17 @@ const (
 	RegoInputParse    = "rego_input_parse"
 	RegoLoadFiles     = "rego_load_files"
 	RegoLoadBundles   = "rego_load_bundles"
+
+	RegoEvalExprs              = "rego_eval_exprs"
+	RegoEvalStorageReads       = "rego_eval_storage_reads"
+	RegoEvalVirtualCacheHits   = "rego_eval_virtual_cache_hits"
+	RegoEvalVirtualCacheMisses = "rego_eval_virtual_cache_misses"
+
+	RegoEvalProcessAllocBytes   = "rego_eval_process_alloc_bytes"
+	RegoEvalProcessAllocObjects = "rego_eval_process_alloc_objects"
+
+	ServerDecisionCacheHits   = "server_decision_cache_hits"
+	ServerDecisionCacheMisses = "server_decision_cache_misses"
 )
 
 // Info contains attributes describing the underlying metrics provider.
@@ -268,6 +279,24 @@ type Counter interface {
 	Incr()
 }
 
+// Adder is implemented by counters that can be incremented by more than one at
+// a time. Counters returned by New implement Adder.
+type Adder interface {
+	Add(n uint64)
+}
+
+// Add increments the counter by n. If the counter does not implement Adder,
+// Incr is called n times.
+func Add(c Counter, n uint64) {
+	if a, ok := c.(Adder); ok {
+		a.Add(n)
+		return
+	}
+	for i := uint64(0); i < n; i++ {
+		c.Incr()
+	}
+}
+
 type counter struct {
 	c uint64
 }
@@ -276,6 +305,10 @@ func (c *counter) Incr() {
 	atomic.AddUint64(&c.c, 1)
 }
 
+func (c *counter) Add(n uint64) {
+	atomic.AddUint64(&c.c, n)
+}
+
 func (c *counter) Value() interface{} {
 	return atomic.LoadUint64(&c.c)
 }
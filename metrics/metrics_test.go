@@ -23,3 +23,30 @@ func TestMetricsTimer(t *testing.T) {
 		t.Fatalf("Expected metrics to be cleared, but found %v", m.All())
 	}
 }
+
+type incrCounter struct {
+	n uint64
+}
+
+func (c *incrCounter) Incr() {
+	c.n++
+}
+
+func (c *incrCounter) Value() interface{} {
+	return c.n
+}
+
+func TestMetricsAdd(t *testing.T) {
+	m := New()
+	Add(m.Counter("foo"), 3)
+	Add(m.Counter("foo"), 2)
+	if v := m.All()["counter_foo"]; v != uint64(5) {
+		t.Fatalf("Expected foo counter to be 5 but got: %v", v)
+	}
+
+	c := &incrCounter{}
+	Add(c, 3)
+	if c.n != 3 {
+		t.Fatalf("Expected counter without Add to be incremented 3 times but got: %v", c.n)
+	}
+}
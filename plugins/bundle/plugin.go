@@ -264,6 +264,8 @@ func (p *Plugin) process(ctx context.Context, name string, u download.Update) {
 		p.status[name].Metrics = metrics.New()
 	}
 
+	p.status[name].SetRequest(u.Error)
+
 	if u.Error != nil {
 		p.logError(name, "Bundle download failed: %v", u.Error)
 		p.status[name].SetError(u.Error)
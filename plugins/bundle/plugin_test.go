@@ -79,6 +79,16 @@ func TestPluginOneShot(t *testing.T) {
 		t.Fatalf("Bad data content. Exp:\n%v\n\nGot:\n\n%v", expData, data)
 	}
 
+	status := plugin.status[bundleName]
+	if status.LastRequest.IsZero() || status.LastSuccessfulRequest != status.LastRequest {
+		t.Fatalf("Expected successful request in status but got: %+v", status)
+	}
+
+	plugin.oneShot(ctx, bundleName, download.Update{Error: fmt.Errorf("unknown error")})
+
+	if status.LastSuccessfulRequest.Equal(status.LastRequest) {
+		t.Fatalf("Expected failed request in status but got: %+v", status)
+	}
 }
 
 func TestPluginOneShotCompileError(t *testing.T) {
@@ -25,6 +25,8 @@ type Status struct {
 	ActiveRevision           string          `json:"active_revision,omitempty"`
 	LastSuccessfulActivation time.Time       `json:"last_successful_activation,omitempty"`
 	LastSuccessfulDownload   time.Time       `json:"last_successful_download,omitempty"`
+	LastSuccessfulRequest    time.Time       `json:"last_successful_request,omitempty"`
+	LastRequest              time.Time       `json:"last_request,omitempty"`
 	Code                     string          `json:"code,omitempty"`
 	Message                  string          `json:"message,omitempty"`
 	Errors                   []error         `json:"errors,omitempty"`
@@ -44,6 +46,15 @@ func (s *Status) SetDownloadSuccess() {
 	s.LastSuccessfulDownload = time.Now().UTC()
 }
 
+// SetRequest updates the status object to reflect a download request. If
+// err is nil, the request is also recorded as the last successful request.
+func (s *Status) SetRequest(err error) {
+	s.LastRequest = time.Now().UTC()
+	if err == nil {
+		s.LastSuccessfulRequest = s.LastRequest
+	}
+}
+
 // SetError updates the status object to reflect a failure to download or
 // activate. If err is nil, the error status is cleared.
 func (s *Status) SetError(err error) {
@@ -21,6 +21,7 @@ import (
 	"github.com/open-policy-agent/opa/plugins"
 	"github.com/open-policy-agent/opa/plugins/bundle"
 	"github.com/open-policy-agent/opa/plugins/logs"
+	"github.com/open-policy-agent/opa/plugins/replication"
 	"github.com/open-policy-agent/opa/plugins/status"
 	"github.com/open-policy-agent/opa/rego"
 	"github.com/open-policy-agent/opa/storage/inmem"
@@ -314,6 +315,11 @@ func getPluginSet(factories map[string]plugins.Factory, manager *plugins.Manager
 		return nil, err
 	}
 
+	replicationConfig, err := replication.ParseConfig(config.Replication, manager.Services())
+	if err != nil {
+		return nil, err
+	}
+
 	// Accumulate plugins to start or reconfigure.
 	starts := []plugins.Plugin{}
 	reconfigs := []pluginreconfig{}
@@ -345,6 +351,15 @@ func getPluginSet(factories map[string]plugins.Factory, manager *plugins.Manager
 		}
 	}
 
+	if replicationConfig != nil {
+		p, created := getReplicationPlugin(manager, replicationConfig)
+		if created {
+			starts = append(starts, p)
+		} else if p != nil {
+			reconfigs = append(reconfigs, pluginreconfig{replicationConfig, p})
+		}
+	}
+
 	result := &pluginSet{starts, reconfigs}
 
 	getCustomPlugins(manager, pluginFactories, result)
@@ -387,6 +402,16 @@ func getStatusPlugin(m *plugins.Manager, config *status.Config, metrics metrics.
 	return plugin, created
 }
 
+func getReplicationPlugin(m *plugins.Manager, config *replication.Config) (plugin *replication.Plugin, created bool) {
+	plugin = replication.Lookup(m)
+	if plugin == nil {
+		plugin = replication.New(config, m)
+		m.Register(replication.Name, plugin)
+		created = true
+	}
+	return plugin, created
+}
+
 func getCustomPlugins(manager *plugins.Manager, factories []pluginfactory, result *pluginSet) {
 	for _, pf := range factories {
 		if plugin := manager.Plugin(pf.name); plugin != nil {
@@ -37,19 +37,24 @@ type Logger interface {
 
 // EventV1 represents a decision log event.
 type EventV1 struct {
-	Labels      map[string]string       `json:"labels"`
-	DecisionID  string                  `json:"decision_id"`
-	Revision    string                  `json:"revision,omitempty"` // Deprecated: Use Bundles instead
-	Bundles     map[string]BundleInfoV1 `json:"bundles,omitempty"`
-	Path        string                  `json:"path,omitempty"`
-	Query       string                  `json:"query,omitempty"`
-	Input       *interface{}            `json:"input,omitempty"`
-	Result      *interface{}            `json:"result,omitempty"`
-	Erased      []string                `json:"erased,omitempty"`
-	Error       error                   `json:"error,omitempty"`
-	RequestedBy string                  `json:"requested_by"`
-	Timestamp   time.Time               `json:"timestamp"`
-	Metrics     map[string]interface{}  `json:"metrics,omitempty"`
+	Labels        map[string]string       `json:"labels"`
+	DecisionID    string                  `json:"decision_id"`
+	Revision      string                  `json:"revision,omitempty"` // Deprecated: Use Bundles instead
+	Bundles       map[string]BundleInfoV1 `json:"bundles,omitempty"`
+	StoreRevision uint64                  `json:"store_revision,omitempty"`
+	Snapshot      string                  `json:"snapshot,omitempty"`
+	Shadow        bool                    `json:"shadow,omitempty"`
+	Override      bool                    `json:"override,omitempty"`
+	Path          string                  `json:"path,omitempty"`
+	Query         string                  `json:"query,omitempty"`
+	Input         *interface{}            `json:"input,omitempty"`
+	Result        *interface{}            `json:"result,omitempty"`
+	Erased        []string                `json:"erased,omitempty"`
+	Masked        []string                `json:"masked,omitempty"`
+	Error         error                   `json:"error,omitempty"`
+	RequestedBy   string                  `json:"requested_by"`
+	Timestamp     time.Time               `json:"timestamp"`
+	Metrics       map[string]interface{}  `json:"metrics,omitempty"`
 }
 
 // BundleInfoV1 describes a bundle associated with a decision log event.
@@ -263,6 +268,17 @@ func (p *Plugin) Stop(ctx context.Context) {
 	_ = <-done
 }
 
+// Flush uploads the buffered decision log events. If the upload fails, the
+// events remain buffered and the error is returned. Flush is a no-op if
+// decisions are not uploaded to a service.
+func (p *Plugin) Flush(ctx context.Context) error {
+	if p.config.Service == "" {
+		return nil
+	}
+	_, err := p.oneShot(ctx)
+	return err
+}
+
 // Log appends a decision log event to the buffer for uploading.
 func (p *Plugin) Log(ctx context.Context, decision *server.Info) error {
 
@@ -274,16 +290,20 @@ func (p *Plugin) Log(ctx context.Context, decision *server.Info) error {
 	}
 
 	event := EventV1{
-		Labels:      p.manager.Labels(),
-		DecisionID:  decision.DecisionID,
-		Revision:    decision.Revision,
-		Bundles:     bundles,
-		Path:        path,
-		Query:       decision.Query,
-		Input:       decision.Input,
-		Result:      decision.Results,
-		RequestedBy: decision.RemoteAddr,
-		Timestamp:   decision.Timestamp,
+		Labels:        p.manager.Labels(),
+		DecisionID:    decision.DecisionID,
+		Revision:      decision.Revision,
+		Bundles:       bundles,
+		StoreRevision: decision.StoreRevision,
+		Snapshot:      decision.Snapshot,
+		Shadow:        decision.Shadow,
+		Override:      decision.Override,
+		Path:          path,
+		Query:         decision.Query,
+		Input:         decision.Input,
+		Result:        decision.Results,
+		RequestedBy:   decision.RemoteAddr,
+		Timestamp:     decision.Timestamp,
 	}
 
 	if decision.Metrics != nil {
@@ -521,13 +541,13 @@ func (p *Plugin) maskEvent(ctx context.Context, txn storage.Transaction, event *
 		return nil
 	}
 
-	ptrs, err := resultValueToPtrs(rs[0].Expressions[0].Value)
+	rules, err := resultValueToMaskRules(rs[0].Expressions[0].Value)
 	if err != nil {
 		return err
 	}
 
-	for _, ptr := range ptrs {
-		ptr.Erase(event)
+	for _, rule := range rules {
+		rule.Mask(event)
 	}
 
 	return nil
@@ -104,6 +104,26 @@ func TestPluginSingleBundle(t *testing.T) {
 	}
 }
 
+func TestPluginStoreRevision(t *testing.T) {
+	ctx := context.Background()
+	manager, _ := plugins.New(nil, "test-instance-id", inmem.New())
+
+	backend := &testPlugin{}
+	manager.Register("test_plugin", backend)
+
+	config, err := ParseConfig([]byte(`{"plugin": "test_plugin"}`), nil, []string{"test_plugin"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	plugin := New(config, manager)
+	plugin.Log(ctx, &server.Info{StoreRevision: 7})
+
+	if len(backend.events) != 1 || backend.events[0].StoreRevision != 7 {
+		t.Fatal("Unexpected events: ", backend.events)
+	}
+}
+
 func TestPluginMultiBundle(t *testing.T) {
 
 }
@@ -431,6 +451,39 @@ func TestPluginRequeue(t *testing.T) {
 	}
 }
 
+func TestPluginFlush(t *testing.T) {
+
+	ctx := context.Background()
+
+	fixture := newTestFixture(t)
+	defer fixture.server.stop()
+
+	fixture.server.ch = make(chan []EventV1, 1)
+
+	if err := fixture.plugin.Flush(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	var result interface{} = true
+
+	fixture.plugin.Log(ctx, &server.Info{
+		DecisionID: "abc",
+		Path:       "data.foo.bar",
+		Results:    &result,
+		Timestamp:  time.Now().UTC(),
+	})
+
+	if err := fixture.plugin.Flush(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	events := <-fixture.server.ch
+
+	if len(events) != 1 || events[0].DecisionID != "abc" {
+		t.Fatalf("Expected one event but got: %v", events)
+	}
+}
+
 func TestPluginReconfigure(t *testing.T) {
 
 	ctx := context.Background()
@@ -588,6 +641,53 @@ func TestPluginMasking(t *testing.T) {
 		t.Fatalf("Expected input to be nil but got: %v", *event.Input)
 	}
 
+	// Update policy to replace values and exercise.
+	err = storage.Txn(ctx, store, storage.WriteParams, func(txn storage.Transaction) error {
+		if err := store.UpsertPolicy(ctx, txn, "test.rego", []byte(`
+			package system.log
+			mask[{"op": "upsert", "path": "/input/password", "value": x}] {
+				input.input.password
+				x := "**REDACTED**"
+			}
+			mask[{"op": "remove", "path": "/input/is_sensitive"}]
+		`)); err != nil {
+			return err
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	input = map[string]interface{}{
+		"password":     "secret",
+		"is_sensitive": true,
+	}
+
+	event = &EventV1{
+		Input: &input,
+	}
+
+	if err := plugin.maskEvent(ctx, nil, event); err != nil {
+		t.Fatal(err)
+	}
+
+	exp = map[string]interface{}{
+		"password": "**REDACTED**",
+	}
+
+	if !reflect.DeepEqual(exp, *event.Input) {
+		t.Fatalf("Expected %v but got %v:", exp, *event.Input)
+	}
+
+	if exp := []string{"/input/is_sensitive"}; !reflect.DeepEqual(exp, event.Erased) {
+		t.Fatalf("Expected %v but got %v:", exp, event.Erased)
+	}
+
+	if exp := []string{"/input/password"}; !reflect.DeepEqual(exp, event.Masked) {
+		t.Fatalf("Expected %v but got %v:", exp, event.Masked)
+	}
+
 	// Reconfigure and ensure that mask is invalidated.
 	maskDecision := "dead/beef"
 	newConfig := &Config{Service: "svc", MaskDecision: &maskDecision}
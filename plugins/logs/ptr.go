@@ -114,24 +114,128 @@ func (p ptr) lookup(node interface{}) interface{} {
 	return node
 }
 
-func resultValueToPtrs(rv interface{}) ([]ptr, error) {
+// Upsert sets the field in the event referred to by p to value. Missing
+// objects along the path are created. If the path refers into a value that is
+// not an object, the event is not modified.
+func (p ptr) Upsert(event *EventV1, value interface{}) {
+
+	if len(p) == 1 {
+		switch p[0] {
+		case "input":
+			event.Input = &value
+		case "result":
+			event.Result = &value
+		default:
+			panic("illegal value")
+		}
+	} else {
+		var root *interface{}
+
+		switch p[0] {
+		case "input":
+			if event.Input == nil {
+				var x interface{} = map[string]interface{}{}
+				event.Input = &x
+			}
+			root = event.Input
+		case "result":
+			if event.Result == nil {
+				var x interface{} = map[string]interface{}{}
+				event.Result = &x
+			}
+			root = event.Result
+		}
+
+		node, ok := (*root).(map[string]interface{})
+		if !ok {
+			return
+		}
+
+		for _, key := range p[1 : len(p)-1] {
+			child, ok := node[key]
+			if !ok {
+				child = map[string]interface{}{}
+				node[key] = child
+			}
+			if node, ok = child.(map[string]interface{}); !ok {
+				return
+			}
+		}
+
+		node[p[len(p)-1]] = value
+	}
+
+	event.Masked = append(event.Masked, p.String())
+}
+
+const (
+	maskOpRemove = "remove"
+	maskOpUpsert = "upsert"
+)
+
+// maskRule represents a single operation generated by the masking policy.
+type maskRule struct {
+	op    string
+	ptr   ptr
+	value interface{}
+}
+
+func (r maskRule) Mask(event *EventV1) {
+	switch r.op {
+	case maskOpRemove:
+		r.ptr.Erase(event)
+	case maskOpUpsert:
+		r.ptr.Upsert(event, r.value)
+	}
+}
+
+// resultValueToMaskRules converts the value generated by the masking policy
+// into mask rules. The value is a collection of JSON Pointers to erase and/or
+// objects like {"op": "upsert", "path": "/input/foo", "value": "bar"}.
+func resultValueToMaskRules(rv interface{}) ([]maskRule, error) {
 
 	bs, err := json.Marshal(rv)
 	if err != nil {
 		return nil, err
 	}
 
-	var value []string
+	var values []interface{}
 
-	if err := util.Unmarshal(bs, &value); err != nil {
+	if err := util.Unmarshal(bs, &values); err != nil {
 		return nil, err
 	}
 
-	result := make([]ptr, len(value))
+	result := make([]maskRule, len(values))
 
-	for i := range result {
-		if result[i], err = parsePtr(value[i]); err != nil {
-			return nil, err
+	for i := range values {
+		switch v := values[i].(type) {
+		case string:
+			result[i].op = maskOpRemove
+			if result[i].ptr, err = parsePtr(v); err != nil {
+				return nil, err
+			}
+		case map[string]interface{}:
+			op, ok := v["op"].(string)
+			if !ok {
+				op = maskOpRemove
+			} else if op != maskOpRemove && op != maskOpUpsert {
+				return nil, fmt.Errorf("mask op must be %q or %q", maskOpRemove, maskOpUpsert)
+			}
+			path, ok := v["path"].(string)
+			if !ok {
+				return nil, fmt.Errorf("mask path must be a string")
+			}
+			result[i].op = op
+			if result[i].ptr, err = parsePtr(path); err != nil {
+				return nil, err
+			}
+			if op == maskOpUpsert {
+				if result[i].value, ok = v["value"]; !ok {
+					return nil, fmt.Errorf("mask value must be set for %q", maskOpUpsert)
+				}
+			}
+		default:
+			return nil, fmt.Errorf("mask must be a string or an object")
 		}
 	}
 
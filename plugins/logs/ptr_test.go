@@ -235,3 +235,155 @@ func TestPtrErase(t *testing.T) {
 		})
 	}
 }
+
+func TestPtrUpsert(t *testing.T) {
+
+	tests := []struct {
+		note  string
+		ptr   string
+		value interface{}
+		event string
+		exp   string
+	}{
+		{
+			note:  "upsert input",
+			ptr:   "/input",
+			value: "x",
+			event: `{"input": {"a": 1}}`,
+			exp:   `{"input": "x", "masked": ["/input"]}`,
+		},
+		{
+			note:  "upsert: undefined result",
+			ptr:   "/result/foo",
+			value: "x",
+			event: `{}`,
+			exp:   `{"result": {"foo": "x"}, "masked": ["/result/foo"]}`,
+		},
+		{
+			note:  "upsert: existing key",
+			ptr:   "/input/foo",
+			value: "x",
+			event: `{"input": {"foo": 1, "bar": 2}}`,
+			exp:   `{"input": {"foo": "x", "bar": 2}, "masked": ["/input/foo"]}`,
+		},
+		{
+			note:  "upsert: missing objects",
+			ptr:   "/input/foo/bar/baz",
+			value: "x",
+			event: `{"input": {"foo": {}}}`,
+			exp:   `{"input": {"foo": {"bar": {"baz": "x"}}}, "masked": ["/input/foo/bar/baz"]}`,
+		},
+		{
+			note:  "upsert: non-object",
+			ptr:   "/input/foo/bar",
+			value: "x",
+			event: `{"input": {"foo": [1]}}`,
+			exp:   `{"input": {"foo": [1]}}`,
+		},
+		{
+			note:  "upsert: non-object root",
+			ptr:   "/input/foo",
+			value: "x",
+			event: `{"input": 1}`,
+			exp:   `{"input": 1}`,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.note, func(t *testing.T) {
+
+			ptr, err := parsePtr(tc.ptr)
+			if err != nil {
+				panic(err)
+			}
+
+			var exp EventV1
+			if err := util.UnmarshalJSON([]byte(tc.exp), &exp); err != nil {
+				panic(err)
+			}
+
+			var event EventV1
+			if err := util.UnmarshalJSON([]byte(tc.event), &event); err != nil {
+				panic(err)
+			}
+
+			ptr.Upsert(&event, tc.value)
+
+			if !reflect.DeepEqual(event, exp) {
+				bs1, _ := json.MarshalIndent(exp, "", "  ")
+				bs2, _ := json.MarshalIndent(event, "", "  ")
+				t.Fatalf("Expected: %s\nGot: %s", bs1, bs2)
+			}
+		})
+	}
+}
+
+func TestResultValueToMaskRules(t *testing.T) {
+
+	tests := []struct {
+		note   string
+		value  string
+		expErr error
+		exp    []maskRule
+	}{
+		{
+			note:  "pointers",
+			value: `["/input/a", "/result/b"]`,
+			exp: []maskRule{
+				{op: maskOpRemove, ptr: ptr{"input", "a"}},
+				{op: maskOpRemove, ptr: ptr{"result", "b"}},
+			},
+		},
+		{
+			note:  "objects",
+			value: `[{"op": "remove", "path": "/input/a"}, {"op": "upsert", "path": "/input/b", "value": [1]}]`,
+			exp: []maskRule{
+				{op: maskOpRemove, ptr: ptr{"input", "a"}},
+				{op: maskOpUpsert, ptr: ptr{"input", "b"}, value: []interface{}{json.Number("1")}},
+			},
+		},
+		{
+			note:   "bad op",
+			value:  `[{"op": "add", "path": "/input/a"}]`,
+			expErr: fmt.Errorf(`mask op must be "remove" or "upsert"`),
+		},
+		{
+			note:   "missing value",
+			value:  `[{"op": "upsert", "path": "/input/a"}]`,
+			expErr: fmt.Errorf(`mask value must be set for "upsert"`),
+		},
+		{
+			note:   "bad path",
+			value:  `[{"op": "remove", "path": "/labels/a"}]`,
+			expErr: fmt.Errorf("mask prefix not allowed"),
+		},
+		{
+			note:   "bad type",
+			value:  `[1]`,
+			expErr: fmt.Errorf("mask must be a string or an object"),
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.note, func(t *testing.T) {
+
+			var value interface{}
+			if err := util.UnmarshalJSON([]byte(tc.value), &value); err != nil {
+				panic(err)
+			}
+
+			result, err := resultValueToMaskRules(value)
+			if tc.expErr != nil {
+				if err == nil {
+					t.Fatalf("Expected error but got: %v", result)
+				} else if !strings.Contains(err.Error(), tc.expErr.Error()) {
+					t.Fatalf("Expected error: %v, but got error: %v", tc.expErr, err)
+				}
+			} else if err != nil {
+				t.Fatal("Unexpected error:", err)
+			} else if !reflect.DeepEqual(result, tc.exp) {
+				t.Fatalf("Expected %v but got %v", tc.exp, result)
+			}
+		})
+	}
+}
@@ -348,6 +348,17 @@ func (m *Manager) Services() []string {
 	return s
 }
 
+// ServiceStats returns the request counters for each service.
+func (m *Manager) ServiceStats() map[string]rest.Stats {
+	m.mtx.Lock()
+	defer m.mtx.Unlock()
+	stats := make(map[string]rest.Stats, len(m.services))
+	for name, client := range m.services {
+		stats[name] = client.Stats()
+	}
+	return stats
+}
+
 // parseServicesConfig returns a set of named service clients. The service
 // clients can be specified either as an array or as a map. Some systems (e.g.,
 // Helm) do not have proper support for configuration values nested under
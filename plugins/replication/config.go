@@ -0,0 +1,125 @@
+// Copyright 2019 The OPA Authors.  All rights reserved.
+// Use of this source code is governed by an Apache2
+// license that can be found in the LICENSE file.
+
+package replication
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/open-policy-agent/opa/download"
+	"github.com/open-policy-agent/opa/storage"
+	"github.com/open-policy-agent/opa/util"
+)
+
+// ParseConfig validates the config and injects default values. The config is
+// a map of feed names to feed configurations.
+func ParseConfig(config []byte, services []string) (*Config, error) {
+	if config == nil {
+		return nil, nil
+	}
+
+	var feeds map[string]*FeedConfig
+
+	if err := util.Unmarshal(config, &feeds); err != nil {
+		return nil, err
+	}
+
+	c := Config{Feeds: map[string]*FeedConfig{}}
+	for name, feed := range feeds {
+		if feed != nil {
+			c.Feeds[name] = feed
+		}
+	}
+
+	if err := c.validateAndInjectDefaults(services); err != nil {
+		return nil, err
+	}
+
+	return &c, nil
+}
+
+// Config represents the configuration of the plugin.
+type Config struct {
+	Feeds map[string]*FeedConfig
+}
+
+// FeedConfig represents the configuration of a single replicated document.
+// The document served at Resource on Service is written into the store under
+// Path each time it changes. Long polling is enabled by the
+// polling.long_polling_timeout_seconds setting of the embedded download
+// config.
+type FeedConfig struct {
+	download.Config
+
+	Service  string `json:"service"`
+	Resource string `json:"resource"`
+	Path     string `json:"path"`
+
+	path storage.Path
+}
+
+// StoragePath returns the parsed data path the feed writes into.
+func (c *FeedConfig) StoragePath() storage.Path {
+	return c.path
+}
+
+func (c *Config) validateAndInjectDefaults(services []string) error {
+
+	for name, feed := range c.Feeds {
+		if err := feed.validateAndInjectDefaults(name, services); err != nil {
+			return fmt.Errorf("invalid configuration for replication feed %q: %s", name, err.Error())
+		}
+	}
+
+	// Feeds must not write into overlapping portions of the data tree
+	// otherwise the last writer would silently clobber the others.
+	for a, feedA := range c.Feeds {
+		for b, feedB := range c.Feeds {
+			if a < b && (feedA.path.HasPrefix(feedB.path) || feedB.path.HasPrefix(feedA.path)) {
+				return fmt.Errorf("replication feeds %q and %q have overlapping paths", a, b)
+			}
+		}
+	}
+
+	return nil
+}
+
+func (c *FeedConfig) validateAndInjectDefaults(name string, services []string) error {
+
+	if c.Resource == "" {
+		return fmt.Errorf("missing resource")
+	}
+
+	if c.Path == "" {
+		c.Path = name
+	}
+
+	path, ok := storage.ParsePathEscaped("/" + strings.Trim(c.Path, "/"))
+	if !ok || len(path) == 0 {
+		return fmt.Errorf("invalid path %q", c.Path)
+	}
+
+	c.path = path
+
+	if c.Service == "" {
+		if len(services) == 0 {
+			return fmt.Errorf("missing service")
+		}
+		c.Service = services[0]
+	} else {
+		var found bool
+		for _, svc := range services {
+			if svc == c.Service {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return fmt.Errorf("service name %q not found", c.Service)
+		}
+	}
+
+	return c.Config.ValidateAndInjectDefaults()
+}
@@ -0,0 +1,383 @@
+// Copyright 2019 The OPA Authors.  All rights reserved.
+// Use of this source code is governed by an Apache2
+// license that can be found in the LICENSE file.
+
+// Package replication implements replication of external JSON documents into
+// the store.
+//
+// Each configured feed points at a resource on a remote service. The plugin
+// polls the resource (optionally using long-polling) and whenever the document
+// changes, it is written into the configured data path inside of a single
+// write transaction. This lets frequently changing context (e.g., user/group
+// mappings) stay fresh without having to rebuild and redistribute bundles.
+package replication
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"net/http"
+	"reflect"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+
+	"github.com/open-policy-agent/opa/plugins"
+	"github.com/open-policy-agent/opa/plugins/rest"
+	"github.com/open-policy-agent/opa/storage"
+	"github.com/open-policy-agent/opa/util"
+)
+
+// Name identifies the plugin on manager.
+const Name = "replication"
+
+const (
+	minRetryDelay = time.Millisecond * 100
+	errCode       = "replication_error"
+)
+
+// Feed defines the interface for sources of replicated documents. The HTTP
+// feed is used by default. Other transports (e.g., message queues) can be
+// supplied by embedders with the Feeds option.
+type Feed interface {
+
+	// Poll returns the latest version of the document. If the document has
+	// not changed since the last call, Poll returns false. Poll may block
+	// (e.g., for long-polling) but must return when ctx is cancelled.
+	Poll(ctx context.Context) (value interface{}, changed bool, err error)
+}
+
+// Status represents the status of a replication feed.
+type Status struct {
+	Name               string    `json:"name"`
+	LastSuccessfulSync time.Time `json:"last_successful_sync,omitempty"`
+	LastUpdate         time.Time `json:"last_update,omitempty"`
+	Code               string    `json:"code,omitempty"`
+	Message            string    `json:"message,omitempty"`
+}
+
+// SetError updates the status object to reflect a failure to fetch or write
+// the document. If err is nil, the error status is cleared.
+func (s *Status) SetError(err error) {
+	if err == nil {
+		s.Code = ""
+		s.Message = ""
+		return
+	}
+	s.Code = errCode
+	s.Message = err.Error()
+}
+
+// Plugin implements document replication.
+type Plugin struct {
+	manager *plugins.Manager
+	config  Config
+	feeds   map[string]Feed    // user supplied feeds keyed by feed name
+	workers map[string]*worker // running feed workers keyed by feed name
+	status  map[string]*Status
+	mtx     sync.Mutex
+}
+
+// Feeds returns an option that overrides the transport used for the named
+// feeds. Feeds that are not included in the map use HTTP.
+func Feeds(feeds map[string]Feed) func(*Plugin) {
+	return func(p *Plugin) {
+		p.feeds = feeds
+	}
+}
+
+// New returns a new Plugin with the given config.
+func New(config *Config, manager *plugins.Manager, opts ...func(*Plugin)) *Plugin {
+	p := &Plugin{
+		manager: manager,
+		config:  *config,
+		workers: map[string]*worker{},
+		status:  map[string]*Status{},
+	}
+	for _, f := range opts {
+		f(p)
+	}
+	return p
+}
+
+// Lookup returns the replication plugin registered with the manager.
+func Lookup(manager *plugins.Manager) *Plugin {
+	if p := manager.Plugin(Name); p != nil {
+		return p.(*Plugin)
+	}
+	return nil
+}
+
+// Start starts a worker for each configured feed.
+func (p *Plugin) Start(ctx context.Context) error {
+	p.mtx.Lock()
+	defer p.mtx.Unlock()
+	for name := range p.config.Feeds {
+		p.startWorker(name)
+	}
+	return nil
+}
+
+// Stop stops all of the feed workers.
+func (p *Plugin) Stop(ctx context.Context) {
+	p.mtx.Lock()
+	workers := p.workers
+	p.workers = map[string]*worker{}
+	p.mtx.Unlock()
+	for _, w := range workers {
+		w.stop()
+	}
+}
+
+// Reconfigure notifies the plugin that it's configuration has changed. Feeds
+// that were added or changed are (re)started and feeds that were removed are
+// stopped. Replicated documents are left in the store.
+func (p *Plugin) Reconfigure(ctx context.Context, config interface{}) {
+	newConfig := config.(*Config)
+
+	// Workers must be stopped without holding the lock because they acquire
+	// it to update status after each poll.
+	p.mtx.Lock()
+	stopped := map[string]*worker{}
+	for name, w := range p.workers {
+		if feed, ok := newConfig.Feeds[name]; !ok || !reflect.DeepEqual(feed, p.config.Feeds[name]) {
+			stopped[name] = w
+			delete(p.workers, name)
+		}
+	}
+	p.mtx.Unlock()
+
+	for _, w := range stopped {
+		w.stop()
+	}
+
+	p.mtx.Lock()
+	defer p.mtx.Unlock()
+
+	for name := range stopped {
+		if _, ok := newConfig.Feeds[name]; !ok {
+			delete(p.status, name)
+		}
+	}
+
+	p.config = *newConfig
+
+	for name := range p.config.Feeds {
+		if _, ok := p.workers[name]; !ok {
+			p.startWorker(name)
+		}
+	}
+}
+
+// Status returns a copy of the current status of each feed.
+func (p *Plugin) Status() map[string]*Status {
+	p.mtx.Lock()
+	defer p.mtx.Unlock()
+	result := make(map[string]*Status, len(p.status))
+	for name, s := range p.status {
+		cpy := *s
+		result[name] = &cpy
+	}
+	return result
+}
+
+// startWorker must be called with p.mtx held.
+func (p *Plugin) startWorker(name string) {
+	config := p.config.Feeds[name]
+
+	feed, ok := p.feeds[name]
+	if !ok {
+		feed = newHTTPFeed(p.manager.Client(config.Service), config)
+	}
+
+	if _, ok := p.status[name]; !ok {
+		p.status[name] = &Status{Name: name}
+	}
+
+	w := &worker{
+		name:   name,
+		config: config,
+		feed:   feed,
+		plugin: p,
+		done:   make(chan struct{}),
+	}
+	w.ctx, w.cancel = context.WithCancel(context.Background())
+	p.workers[name] = w
+	go w.loop()
+}
+
+// oneShot fetches the document from the feed and writes it into the store if
+// it has changed.
+func (p *Plugin) oneShot(ctx context.Context, name string, config *FeedConfig, feed Feed) error {
+
+	value, changed, err := feed.Poll(ctx)
+	if err == nil && changed {
+		err = p.write(ctx, config.StoragePath(), value)
+	}
+
+	p.mtx.Lock()
+	defer p.mtx.Unlock()
+
+	status, ok := p.status[name]
+	if !ok {
+		return err
+	}
+
+	status.SetError(err)
+
+	if err != nil {
+		p.logError(name, "Replication failed: %v.", err)
+		return err
+	}
+
+	now := time.Now().UTC()
+	status.LastSuccessfulSync = now
+
+	if changed {
+		status.LastUpdate = now
+		p.logInfo(name, "Replicated document written to %v.", config.StoragePath())
+	}
+
+	return nil
+}
+
+func (p *Plugin) write(ctx context.Context, path storage.Path, value interface{}) error {
+	return storage.Txn(ctx, p.manager.Store, storage.WriteParams, func(txn storage.Transaction) error {
+		if _, err := p.manager.Store.Read(ctx, txn, path); err != nil {
+			if !storage.IsNotFound(err) {
+				return err
+			}
+			if err := storage.MakeDir(ctx, p.manager.Store, txn, path[:len(path)-1]); err != nil {
+				return err
+			}
+			return p.manager.Store.Write(ctx, txn, storage.AddOp, path, value)
+		}
+		return p.manager.Store.Write(ctx, txn, storage.ReplaceOp, path, value)
+	})
+}
+
+func (p *Plugin) logError(name string, fmt string, a ...interface{}) {
+	logrus.WithFields(p.logrusFields(name)).Errorf(fmt, a...)
+}
+
+func (p *Plugin) logInfo(name string, fmt string, a ...interface{}) {
+	logrus.WithFields(p.logrusFields(name)).Infof(fmt, a...)
+}
+
+func (p *Plugin) logDebug(name string, fmt string, a ...interface{}) {
+	logrus.WithFields(p.logrusFields(name)).Debugf(fmt, a...)
+}
+
+func (p *Plugin) logrusFields(name string) logrus.Fields {
+	return logrus.Fields{
+		"plugin": Name,
+		"name":   name,
+	}
+}
+
+type worker struct {
+	name   string
+	config *FeedConfig
+	feed   Feed
+	plugin *Plugin
+	ctx    context.Context
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+func (w *worker) stop() {
+	w.cancel()
+	<-w.done
+}
+
+func (w *worker) loop() {
+
+	defer close(w.done)
+
+	var retry int
+
+	for {
+		err := w.plugin.oneShot(w.ctx, w.name, w.config, w.feed)
+
+		var delay time.Duration
+
+		if err != nil {
+			retry++
+			delay = util.DefaultBackoff(float64(minRetryDelay), float64(*w.config.Polling.MaxDelaySeconds), retry)
+		} else {
+			retry = 0
+			// Long-polling requests block on the server until the document
+			// changes so there is no need to wait between them.
+			if w.config.Polling.LongPollingTimeoutSeconds == nil {
+				min := float64(*w.config.Polling.MinDelaySeconds)
+				max := float64(*w.config.Polling.MaxDelaySeconds)
+				delay = time.Duration(((max - min) * rand.Float64()) + min)
+			}
+		}
+
+		w.plugin.logDebug(w.name, "Waiting %v before next poll.", delay)
+
+		timer := time.NewTimer(delay)
+
+		select {
+		case <-timer.C:
+		case <-w.ctx.Done():
+			timer.Stop()
+			return
+		}
+	}
+}
+
+// httpFeed fetches documents from a remote HTTP endpoint. ETags are used to
+// avoid transferring and rewriting unchanged documents. If long-polling is
+// enabled, the request asks the server to block until the document changes.
+type httpFeed struct {
+	client   rest.Client
+	resource string
+	timeout  *int64
+	etag     string
+}
+
+func newHTTPFeed(client rest.Client, config *FeedConfig) *httpFeed {
+	return &httpFeed{
+		client:   client,
+		resource: config.Resource,
+		timeout:  config.Polling.LongPollingTimeoutSeconds,
+	}
+}
+
+func (f *httpFeed) Poll(ctx context.Context) (interface{}, bool, error) {
+
+	client := f.client.WithHeader("If-None-Match", f.etag)
+
+	if f.timeout != nil {
+		client = client.WithHeader("Prefer", fmt.Sprintf("wait=%d", *f.timeout))
+	}
+
+	resp, err := client.Do(ctx, "GET", f.resource)
+	if err != nil {
+		return nil, false, errors.Wrap(err, "request failed")
+	}
+
+	defer util.Close(resp)
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+		var value interface{}
+		if err := util.NewJSONDecoder(resp.Body).Decode(&value); err != nil {
+			return nil, false, errors.Wrap(err, "invalid document")
+		}
+		f.etag = resp.Header.Get("ETag")
+		return value, true, nil
+	case http.StatusNotModified:
+		return nil, false, nil
+	case http.StatusNotFound:
+		return nil, false, fmt.Errorf("server replied with not found")
+	case http.StatusUnauthorized:
+		return nil, false, fmt.Errorf("server replied with not authorized")
+	default:
+		return nil, false, fmt.Errorf("server replied with HTTP %v", resp.StatusCode)
+	}
+}
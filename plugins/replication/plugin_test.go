@@ -0,0 +1,344 @@
+// Copyright 2019 The OPA Authors.  All rights reserved.
+// Use of this source code is governed by an Apache2
+// license that can be found in the LICENSE file.
+
+package replication
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/open-policy-agent/opa/plugins"
+	"github.com/open-policy-agent/opa/storage"
+	"github.com/open-policy-agent/opa/storage/inmem"
+	"github.com/open-policy-agent/opa/util"
+)
+
+func TestParseConfig(t *testing.T) {
+
+	tests := []struct {
+		note    string
+		config  string
+		wantErr bool
+	}{
+		{
+			note:   "defaults",
+			config: `{"users": {"resource": "/feeds/users"}}`,
+		},
+		{
+			note:    "missing resource",
+			config:  `{"users": {}}`,
+			wantErr: true,
+		},
+		{
+			note:    "unknown service",
+			config:  `{"users": {"resource": "/feeds/users", "service": "missing"}}`,
+			wantErr: true,
+		},
+		{
+			note:    "overlapping paths",
+			config:  `{"a": {"resource": "/a", "path": "x/y"}, "b": {"resource": "/b", "path": "x"}}`,
+			wantErr: true,
+		},
+		{
+			note:    "bad long polling timeout",
+			config:  `{"users": {"resource": "/feeds/users", "polling": {"long_polling_timeout_seconds": 0}}}`,
+			wantErr: true,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.note, func(t *testing.T) {
+			_, err := ParseConfig([]byte(tc.config), []string{"acmecorp"})
+			if tc.wantErr && err == nil {
+				t.Fatal("Expected error")
+			} else if !tc.wantErr && err != nil {
+				t.Fatal(err)
+			}
+		})
+	}
+
+	config, err := ParseConfig([]byte(`{"users": {"resource": "/feeds/users"}}`), []string{"acmecorp"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	feed := config.Feeds["users"]
+
+	if feed.Service != "acmecorp" || !feed.StoragePath().Equal(storage.MustParsePath("/users")) {
+		t.Fatalf("Unexpected defaults: %+v", feed)
+	}
+
+	if feed.Polling.LongPollingTimeoutSeconds != nil {
+		t.Fatalf("Expected long polling to be disabled by default")
+	}
+
+	config, err = ParseConfig([]byte(`{"users": {"resource": "/feeds/users", "polling": {"long_polling_timeout_seconds": 5}}}`), []string{"acmecorp"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if timeout := config.Feeds["users"].Polling.LongPollingTimeoutSeconds; timeout == nil || *timeout != 5 {
+		t.Fatalf("Expected long polling timeout of 5 seconds but got: %v", timeout)
+	}
+}
+
+func TestPluginOneShot(t *testing.T) {
+
+	ctx := context.Background()
+	fixture := newTestFixture(t)
+	defer fixture.server.Close()
+
+	fixture.setDocument(`{"alice": ["admin"]}`, "v1")
+
+	config := fixture.parseConfig(`{"users": {"resource": "/feeds/users", "path": "context/users"}}`)
+	plugin := New(config, fixture.manager)
+	plugin.status["users"] = &Status{Name: "users"}
+	feed := newHTTPFeed(fixture.manager.Client("test"), config.Feeds["users"])
+
+	if err := plugin.oneShot(ctx, "users", config.Feeds["users"], feed); err != nil {
+		t.Fatal(err)
+	}
+
+	fixture.assertData(t, "/context/users", `{"alice": ["admin"]}`)
+
+	// The second request should carry the ETag and not rewrite the document.
+	if err := plugin.oneShot(ctx, "users", config.Feeds["users"], feed); err != nil {
+		t.Fatal(err)
+	}
+
+	if fixture.notModified != 1 {
+		t.Fatalf("Expected one not modified response but got %v", fixture.notModified)
+	}
+
+	fixture.setDocument(`{"alice": ["admin"], "bob": ["dev"]}`, "v2")
+
+	if err := plugin.oneShot(ctx, "users", config.Feeds["users"], feed); err != nil {
+		t.Fatal(err)
+	}
+
+	fixture.assertData(t, "/context/users", `{"alice": ["admin"], "bob": ["dev"]}`)
+
+	status := plugin.Status()["users"]
+	if status.Code != "" || status.LastUpdate.IsZero() || status.LastSuccessfulSync.IsZero() {
+		t.Fatalf("Unexpected status: %+v", status)
+	}
+}
+
+func TestPluginOneShotError(t *testing.T) {
+
+	ctx := context.Background()
+	fixture := newTestFixture(t)
+	defer fixture.server.Close()
+
+	fixture.setDocument(`{"alice": ["admin"]}`, "v1")
+
+	config := fixture.parseConfig(`{"users": {"resource": "/feeds/users"}}`)
+	plugin := New(config, fixture.manager)
+	plugin.status["users"] = &Status{Name: "users"}
+	feed := newHTTPFeed(fixture.manager.Client("test"), config.Feeds["users"])
+
+	if err := plugin.oneShot(ctx, "users", config.Feeds["users"], feed); err != nil {
+		t.Fatal(err)
+	}
+
+	fixture.setDocument(`{"alice": [`, "v2")
+
+	if err := plugin.oneShot(ctx, "users", config.Feeds["users"], feed); err == nil {
+		t.Fatal("Expected error")
+	}
+
+	// The last good document must remain in the store.
+	fixture.assertData(t, "/users", `{"alice": ["admin"]}`)
+
+	if status := plugin.Status()["users"]; status.Code != errCode {
+		t.Fatalf("Expected error status but got: %+v", status)
+	}
+}
+
+func TestPluginLongPolling(t *testing.T) {
+
+	fixture := newTestFixture(t)
+	defer fixture.server.Close()
+
+	fixture.setDocument(`[1,2,3]`, "v1")
+
+	config := fixture.parseConfig(`{"nums": {"resource": "/feeds/nums", "polling": {"long_polling_timeout_seconds": 10}}}`)
+	feed := newHTTPFeed(fixture.manager.Client("test"), config.Feeds["nums"])
+
+	if _, _, err := feed.Poll(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	if fixture.prefer != "wait=10" {
+		t.Fatalf("Expected long-polling header but got %q", fixture.prefer)
+	}
+}
+
+func TestPluginStartStop(t *testing.T) {
+
+	ctx := context.Background()
+	fixture := newTestFixture(t)
+	defer fixture.server.Close()
+
+	fixture.setDocument(`{"x": 1}`, "v1")
+
+	config := fixture.parseConfig(`{"doc": {"resource": "/feeds/doc", "polling": {"min_delay_seconds": 10, "max_delay_seconds": 20}}}`)
+	plugin := New(config, fixture.manager)
+
+	if err := plugin.Start(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	deadline := time.Now().Add(5 * time.Second)
+
+	for {
+		if _, err := storage.ReadOne(ctx, fixture.manager.Store, storage.MustParsePath("/doc")); err == nil {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("Timed out waiting for replicated document")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	plugin.Stop(ctx)
+
+	fixture.assertData(t, "/doc", `{"x": 1}`)
+}
+
+func TestPluginCustomFeed(t *testing.T) {
+
+	ctx := context.Background()
+	fixture := newTestFixture(t)
+	defer fixture.server.Close()
+
+	config := fixture.parseConfig(`{"doc": {"resource": "/unused"}}`)
+	plugin := New(config, fixture.manager, Feeds(map[string]Feed{
+		"doc": staticFeed{value: "hello"},
+	}))
+
+	if err := plugin.Start(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	defer plugin.Stop(ctx)
+
+	deadline := time.Now().Add(5 * time.Second)
+
+	for {
+		if v, err := storage.ReadOne(ctx, fixture.manager.Store, storage.MustParsePath("/doc")); err == nil && v == "hello" {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("Timed out waiting for replicated document")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+type staticFeed struct {
+	value interface{}
+}
+
+func (f staticFeed) Poll(context.Context) (interface{}, bool, error) {
+	return f.value, true, nil
+}
+
+type testFixture struct {
+	server      *httptest.Server
+	manager     *plugins.Manager
+	mtx         sync.Mutex
+	document    string
+	etag        string
+	prefer      string
+	notModified int
+}
+
+func newTestFixture(t *testing.T) *testFixture {
+
+	f := &testFixture{}
+
+	f.server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		f.mtx.Lock()
+		defer f.mtx.Unlock()
+		f.prefer = r.Header.Get("Prefer")
+		if r.Header.Get("If-None-Match") == f.etag {
+			f.notModified++
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		w.Header().Set("ETag", f.etag)
+		fmt.Fprint(w, f.document)
+	}))
+
+	services := fmt.Sprintf(`{"services": {"test": {"url": %q}}}`, f.server.URL)
+
+	manager, err := plugins.New([]byte(services), "test-instance-id", inmem.New())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	f.manager = manager
+
+	return f
+}
+
+func (f *testFixture) setDocument(doc, etag string) {
+	f.mtx.Lock()
+	defer f.mtx.Unlock()
+	f.document = doc
+	f.etag = etag
+}
+
+func (f *testFixture) parseConfig(s string) *Config {
+	config, err := ParseConfig([]byte(s), f.manager.Services())
+	if err != nil {
+		panic(err)
+	}
+	return config
+}
+
+func (f *testFixture) assertData(t *testing.T, path string, expected string) {
+	t.Helper()
+	result, err := storage.ReadOne(context.Background(), f.manager.Store, storage.MustParsePath(path))
+	if err != nil {
+		t.Fatal(err)
+	}
+	exp := util.MustUnmarshalJSON([]byte(expected))
+	if !reflect.DeepEqual(result, exp) {
+		t.Fatalf("Expected %v but got %v", exp, result)
+	}
+}
+
+func TestPluginReconfigure(t *testing.T) {
+
+	ctx := context.Background()
+	fixture := newTestFixture(t)
+	defer fixture.server.Close()
+
+	fixture.setDocument(`{"x": 1}`, "v1")
+
+	plugin := New(fixture.parseConfig(`{"a": {"resource": "/feeds/a"}}`), fixture.manager)
+
+	if err := plugin.Start(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	plugin.Reconfigure(ctx, fixture.parseConfig(`{"b": {"resource": "/feeds/b"}}`))
+
+	status := plugin.Status()
+	if _, ok := status["a"]; ok {
+		t.Fatal("Expected status for removed feed to be deleted")
+	} else if _, ok := status["b"]; !ok {
+		t.Fatal("Expected status for added feed")
+	}
+
+	plugin.Stop(ctx)
+}
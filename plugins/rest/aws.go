@@ -5,6 +5,7 @@
 package rest
 
 import (
+	"bytes"
 	"crypto/hmac"
 	"crypto/sha256"
 	"encoding/json"
@@ -12,6 +13,7 @@ import (
 	"fmt"
 	"io/ioutil"
 	"net/http"
+	"net/url"
 	"os"
 	"sort"
 	"strings"
@@ -194,8 +196,9 @@ func sortKeys(strMap map[string]string) []string {
 	return keys
 }
 
-// signV4 modifies an http.Request to include an AWS V4 signature based on a credential provider
-func signV4(req *http.Request, credService awsCredentialService, theTime time.Time) error {
+// signV4 modifies an http.Request to include an AWS V4 signature for the
+// service (e.g., "s3") based on a credential provider
+func signV4(req *http.Request, service string, credService awsCredentialService, theTime time.Time) error {
 	var body []byte
 	if req.Body == nil {
 		body = []byte("")
@@ -205,6 +208,8 @@ func signV4(req *http.Request, credService awsCredentialService, theTime time.Ti
 		if err != nil {
 			return errors.New("error getting request body: " + err.Error())
 		}
+		// the body has been consumed; restore it so that the request can be sent
+		req.Body = ioutil.NopCloser(bytes.NewReader(body))
 	}
 	creds, err := credService.credentials()
 	if err != nil {
@@ -234,10 +239,10 @@ func signV4(req *http.Request, credService awsCredentialService, theTime time.Ti
 	// ref. https://docs.aws.amazon.com/AmazonS3/latest/API/sigv4-auth-using-authorization-header.html
 
 	// the "canonical request" is the normalized version of the AWS service access
-	// that we're attempting to perform; in this case, a GET from an S3 bucket
-	canonicalReq := req.Method + "\n"            // HTTP method
-	canonicalReq += req.URL.EscapedPath() + "\n" // URI-escaped path
-	canonicalReq += "\n"                         // query string; not implemented
+	// that we're attempting to perform, e.g., a GET from an S3 bucket
+	canonicalReq := req.Method + "\n"                            // HTTP method
+	canonicalReq += req.URL.EscapedPath() + "\n"                 // URI-escaped path
+	canonicalReq += canonicalQueryString(req.URL.Query()) + "\n" // sorted query string
 
 	// include the values for the signed headers
 	orderedKeys := sortKeys(awsHeaders)
@@ -253,17 +258,18 @@ func signV4(req *http.Request, credService awsCredentialService, theTime time.Ti
 
 	// the "string to sign" is a time-bounded, scoped request token which
 	// is linked to the "canonical request" by inclusion of its SHA-256 hash
-	strToSign := "AWS4-HMAC-SHA256\n"                                    // V4 signing with SHA-256 HMAC
-	strToSign += iso8601Now + "\n"                                       // ISO 8601 time
-	strToSign += dateNow + "/" + creds.RegionName + "/s3/aws4_request\n" // scoping for signature
-	strToSign += fmt.Sprintf("%x", sha256.Sum256([]byte(canonicalReq)))  // SHA-256 of canonical request
+	scope := dateNow + "/" + creds.RegionName + "/" + service + "/aws4_request"
+	strToSign := "AWS4-HMAC-SHA256\n"                                   // V4 signing with SHA-256 HMAC
+	strToSign += iso8601Now + "\n"                                      // ISO 8601 time
+	strToSign += scope + "\n"                                           // scoping for signature
+	strToSign += fmt.Sprintf("%x", sha256.Sum256([]byte(canonicalReq))) // SHA-256 of canonical request
 
 	// the "signing key" is generated by repeated HMAC-SHA256 based on the same
 	// scoping that's included in the "string to sign"; but including the secret key
 	// to allow AWS to validate it
 	signingKey := sha256MAC([]byte(dateNow), []byte("AWS4"+creds.SecretKey))
 	signingKey = sha256MAC([]byte(creds.RegionName), signingKey)
-	signingKey = sha256MAC([]byte("s3"), signingKey)
+	signingKey = sha256MAC([]byte(service), signingKey)
 	signingKey = sha256MAC([]byte("aws4_request"), signingKey)
 
 	// the "signature" is finally the "string to sign" signed by the "signing key"
@@ -271,8 +277,7 @@ func signV4(req *http.Request, credService awsCredentialService, theTime time.Ti
 
 	// required format of Authorization header; n.b. the access key corresponding to
 	// the secret key is included here
-	authHdr := "AWS4-HMAC-SHA256 Credential=" + creds.AccessKey + "/" + dateNow
-	authHdr += "/" + creds.RegionName + "/s3/aws4_request,"
+	authHdr := "AWS4-HMAC-SHA256 Credential=" + creds.AccessKey + "/" + scope + ","
 	authHdr += "SignedHeaders=" + headerList + ","
 	authHdr += "Signature=" + fmt.Sprintf("%x", signature)
 
@@ -286,3 +291,32 @@ func signV4(req *http.Request, credService awsCredentialService, theTime time.Ti
 
 	return nil
 }
+
+// canonicalQueryString returns the query parameters sorted by key (and value)
+// with keys and values URI-encoded as required by V4 signing
+func canonicalQueryString(query url.Values) string {
+	keys := make([]string, 0, len(query))
+	encoded := make(map[string][]string, len(query))
+	for k, vs := range query {
+		ek := awsURIEncode(k)
+		keys = append(keys, ek)
+		for _, v := range vs {
+			encoded[ek] = append(encoded[ek], awsURIEncode(v))
+		}
+	}
+	sort.Strings(keys)
+	var pairs []string
+	for _, k := range keys {
+		sort.Strings(encoded[k])
+		for _, v := range encoded[k] {
+			pairs = append(pairs, k+"="+v)
+		}
+	}
+	return strings.Join(pairs, "&")
+}
+
+// awsURIEncode encodes all characters except the unreserved characters defined
+// by RFC 3986
+func awsURIEncode(s string) string {
+	return strings.Replace(url.QueryEscape(s), "+", "%20", -1)
+}
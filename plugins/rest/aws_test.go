@@ -5,9 +5,13 @@
 package rest
 
 import (
+	"crypto/sha256"
 	"encoding/json"
+	"fmt"
+	"io/ioutil"
 	"net/http"
 	"net/http/httptest"
+	"net/url"
 	"os"
 	"strings"
 	"testing"
@@ -202,7 +206,7 @@ func TestV4Signing(t *testing.T) {
 		RegionName:      "us-east-1",
 		credServicePath: ts.server.URL + "/latest/meta-data/iam/security-credentials/"}
 	req, _ := http.NewRequest("GET", "https://mybucket.s3.amazonaws.com/bundle.tar.gz", strings.NewReader(""))
-	err := signV4(req, cs, time.Unix(1556129697, 0))
+	err := signV4(req, "s3", cs, time.Unix(1556129697, 0))
 
 	assertErr("error getting AWS credentials: metadata service HTTP request failed: 404 Not Found", err, t)
 
@@ -218,7 +222,7 @@ func TestV4Signing(t *testing.T) {
 		Token:           "MYAWSSECURITYTOKENGOESHERE",
 		Expiration:      time.Now().UTC().Add(time.Minute * 2)}
 	req, _ = http.NewRequest("GET", "https://mybucket.s3.amazonaws.com/bundle.tar.gz", strings.NewReader(""))
-	err = signV4(req, cs, time.Unix(1556129697, 0))
+	err = signV4(req, "s3", cs, time.Unix(1556129697, 0))
 
 	if err != nil {
 		t.Error("unexpected error during signing")
@@ -268,3 +272,33 @@ func (t *credTestServer) start() {
 func (t *credTestServer) stop() {
 	t.server.Close()
 }
+
+func TestV4SigningServiceAndBody(t *testing.T) {
+	req, _ := http.NewRequest("POST", "https://example.execute-api.us-east-1.amazonaws.com/logs?b=2&a=1", strings.NewReader("payload"))
+	err := signV4(req, "execute-api", &testCredentialService{}, time.Unix(1556129697, 0))
+	if err != nil {
+		t.Fatal("unexpected error during signing:", err)
+	}
+
+	auth := req.Header.Get("Authorization")
+	if !strings.HasPrefix(auth, "AWS4-HMAC-SHA256 Credential=MYAWSACCESSKEYGOESHERE/20190424/us-east-1/execute-api/aws4_request,") {
+		t.Fatal("unexpected authorization header:", auth)
+	}
+
+	// the body must still be readable after signing
+	body, err := ioutil.ReadAll(req.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertEq("payload", string(body), t)
+	assertEq(fmt.Sprintf("%x", sha256.Sum256([]byte("payload"))), req.Header.Get("X-Amz-Content-Sha256"), t)
+}
+
+func TestCanonicalQueryString(t *testing.T) {
+	query := url.Values{
+		"b":   []string{"2"},
+		"a-b": []string{"~x"},
+		"a":   []string{"z y", "c/d"},
+	}
+	assertEq("a=c%2Fd&a=z%20y&a-b=~x&b=2", canonicalQueryString(query), t)
+}
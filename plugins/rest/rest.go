@@ -10,10 +10,13 @@ import (
 	"context"
 	"encoding/json"
 	"errors"
+	"fmt"
 	"io"
 	"net/http"
+	"net/url"
 	"reflect"
 	"strings"
+	"time"
 
 	"github.com/open-policy-agent/opa/internal/version"
 
@@ -31,14 +34,19 @@ type HTTPAuthPlugin interface {
 
 // Config represents configuration for a REST client.
 type Config struct {
-	Name           string            `json:"name"`
-	URL            string            `json:"url"`
-	Headers        map[string]string `json:"headers"`
-	AllowInsureTLS bool              `json:"allow_insecure_tls,omitempty"`
+	Name           string               `json:"name"`
+	URL            string               `json:"url"`
+	Type           string               `json:"type,omitempty"`
+	Headers        map[string]string    `json:"headers"`
+	AllowInsureTLS bool                 `json:"allow_insecure_tls,omitempty"`
+	TimeoutSeconds *int64               `json:"timeout_seconds,omitempty"`
+	Retry          RetryConfig          `json:"retry,omitempty"`
+	CircuitBreaker CircuitBreakerConfig `json:"circuit_breaker,omitempty"`
 	Credentials    struct {
-		Bearer    *bearerAuthPlugin     `json:"bearer,omitempty"`
-		ClientTLS *clientTLSAuthPlugin  `json:"client_tls,omitempty"`
-		S3Signing *awsSigningAuthPlugin `json:"s3_signing,omitempty"`
+		Bearer    *bearerAuthPlugin                  `json:"bearer,omitempty"`
+		ClientTLS *clientTLSAuthPlugin               `json:"client_tls,omitempty"`
+		S3Signing *awsSigningAuthPlugin              `json:"s3_signing,omitempty"`
+		OAuth2    *oauth2ClientCredentialsAuthPlugin `json:"oauth2,omitempty"`
 	} `json:"credentials"`
 }
 
@@ -84,6 +92,7 @@ type Client struct {
 	json    *interface{}
 	config  Config
 	headers map[string]string
+	state   *clientState
 }
 
 // Name returns an option that overrides the service name on the client.
@@ -105,12 +114,17 @@ func New(config []byte, opts ...func(*Client)) (Client, error) {
 
 	client := Client{
 		config: parsedConfig,
+		state:  newClientState(),
 	}
 
 	for _, f := range opts {
 		f(&client)
 	}
 
+	if err := client.config.validateAndInjectDefaults(); err != nil {
+		return Client{}, fmt.Errorf("invalid configuration for service %q: %v", client.config.Name, err)
+	}
+
 	return client, nil
 }
 
@@ -119,6 +133,18 @@ func (c Client) Service() string {
 	return c.config.Name
 }
 
+// Config returns the configuration of the service this Client is configured
+// for.
+func (c Client) Config() *Config {
+	return &c.config
+}
+
+// Stats returns the request counters and the circuit breaker state for the
+// service. The counters are shared by all copies of the Client.
+func (c Client) Stats() Stats {
+	return c.state.snapshot()
+}
+
 // WithHeader returns a shallow copy of the client with a header to include the
 // requests.
 func (c Client) WithHeader(k, v string) Client {
@@ -148,31 +174,119 @@ func (c Client) WithBytes(body []byte) Client {
 	return c
 }
 
-// Do executes a request using the client.
+// Do executes a request using the client. The path is relative to the URL of
+// the service unless it is an absolute URL. If an Authorization header is set
+// directly on the client, it takes precedence over the configured credentials.
+// The configured credentials and headers are only included in requests to
+// absolute URLs with the same scheme and host as the service URL.
+//
+// Failed attempts (e.g., connection errors or 5xx responses) are retried with
+// an exponential backoff according to the retry configuration of the service.
+// If the context has no deadline, the request (including reading the response
+// body) is bounded by the timeout of the service.
 func (c Client) Do(ctx context.Context, method, path string) (*http.Response, error) {
 
-	httpClient, err := c.config.authHTTPClient()
+	url := path
+	trusted := true
+
+	if !strings.HasPrefix(path, "http://") && !strings.HasPrefix(path, "https://") {
+		url = c.config.URL + "/" + strings.Trim(path, "/")
+	} else {
+		trusted = sameOrigin(c.config.URL, url)
+	}
+
+	var httpClient *http.Client
+	var err error
+
+	if trusted {
+		httpClient, err = c.config.authHTTPClient()
+	} else {
+		httpClient, err = (&defaultAuthPlugin{}).NewClient(c.config)
+	}
+
 	if err != nil {
 		return nil, err
 	}
 
-	path = strings.Trim(path, "/")
-
-	var body io.Reader
+	var body []byte
 
 	if c.bytes != nil {
-		buf := bytes.NewBuffer(*c.bytes)
-		body = buf
+		body = *c.bytes
 	} else if c.json != nil {
 		var buf bytes.Buffer
 		if err := json.NewEncoder(&buf).Encode(*c.json); err != nil {
 			return nil, err
 		}
-		body = &buf
+		body = buf.Bytes()
 	}
 
-	url := c.config.URL + "/" + path
-	req, err := http.NewRequest(method, url, body)
+	if err := c.state.allow(&c.config, time.Now()); err != nil {
+		return nil, err
+	}
+
+	cancel := context.CancelFunc(func() {})
+
+	if _, ok := ctx.Deadline(); !ok && c.config.TimeoutSeconds != nil && *c.config.TimeoutSeconds > 0 {
+		ctx, cancel = context.WithTimeout(ctx, time.Duration(*c.config.TimeoutSeconds)*time.Second)
+	}
+
+	maxAttempts := c.config.Retry.MaxAttempts
+	var resp *http.Response
+	var failed bool
+
+	for attempt := 0; ; attempt++ {
+
+		if attempt > 0 {
+			var maxDelay float64
+			if c.config.Retry.MaxDelaySeconds != nil {
+				maxDelay = float64(time.Duration(*c.config.Retry.MaxDelaySeconds) * time.Second)
+			}
+			delay := util.DefaultBackoff(float64(minRetryDelay), maxDelay, attempt)
+			logrus.WithFields(logrus.Fields{
+				"method":  method,
+				"url":     url,
+				"attempt": attempt + 1,
+			}).Debugf("Retrying request in %v.", delay)
+			if err = sleep(ctx, delay); err != nil {
+				resp = nil
+				break
+			}
+		}
+
+		resp, err = c.do(ctx, httpClient, method, url, body, trusted)
+		failed = isFailure(resp, err)
+		c.state.attempt(attempt > 0, failed)
+
+		if !failed || attempt+1 >= maxAttempts || ctx.Err() != nil {
+			break
+		}
+
+		if resp != nil {
+			util.Close(resp)
+		}
+	}
+
+	c.state.done(&c.config, failed, time.Now())
+
+	if resp == nil {
+		cancel()
+		return nil, err
+	}
+
+	resp.Body = &cancelBody{ReadCloser: resp.Body, cancel: cancel}
+
+	return resp, err
+}
+
+func (c Client) do(ctx context.Context, httpClient *http.Client, method, url string, body []byte, trusted bool) (*http.Response, error) {
+
+	var r io.Reader
+
+	if body != nil {
+		r = bytes.NewReader(body)
+	}
+
+	req, err := http.NewRequest(method, url, r)
 	if err != nil {
 		return nil, err
 	}
@@ -182,8 +296,10 @@ func (c Client) Do(ctx context.Context, method, path string) (*http.Response, er
 	}
 
 	// Copy custom headers from config.
-	for key, value := range c.config.Headers {
-		headers[key] = value
+	if trusted {
+		for key, value := range c.config.Headers {
+			headers[key] = value
+		}
 	}
 
 	// Overwrite with headers set directly on client.
@@ -197,9 +313,11 @@ func (c Client) Do(ctx context.Context, method, path string) (*http.Response, er
 
 	req = req.WithContext(ctx)
 
-	err = c.config.authPrepare(req)
-	if err != nil {
-		return nil, err
+	if _, ok := c.headers["Authorization"]; !ok && trusted {
+		err = c.config.authPrepare(req)
+		if err != nil {
+			return nil, err
+		}
 	}
 
 	logrus.WithFields(logrus.Fields{
@@ -222,3 +340,16 @@ func (c Client) Do(ctx context.Context, method, path string) (*http.Response, er
 
 	return resp, err
 }
+
+// sameOrigin returns true if the URLs have the same scheme and host.
+func sameOrigin(a, b string) bool {
+	u, err := url.Parse(a)
+	if err != nil {
+		return false
+	}
+	v, err := url.Parse(b)
+	if err != nil {
+		return false
+	}
+	return strings.EqualFold(u.Scheme, v.Scheme) && strings.EqualFold(u.Host, v.Host)
+}
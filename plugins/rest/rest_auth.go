@@ -5,9 +5,11 @@
 package rest
 
 import (
+	"context"
 	"crypto/rsa"
 	"crypto/tls"
 	"crypto/x509"
+	"encoding/json"
 	"encoding/pem"
 	"errors"
 	"fmt"
@@ -15,11 +17,23 @@ import (
 	"net"
 	"net/http"
 	"net/url"
+	"strings"
+	"sync"
 	"time"
 
 	"github.com/sirupsen/logrus"
 )
 
+const (
+	// awsDefaultSigningService is the AWS service that requests are signed
+	// for if no service is configured
+	awsDefaultSigningService = "s3"
+
+	// oauth2TokenExpiryMargin is subtracted from the token expiry so that
+	// tokens are refreshed before they expire
+	oauth2TokenExpiryMargin = 10 * time.Second
+)
+
 // defaultTLSConfig defines standard TLS configurations based on the Config
 func defaultTLSConfig(c Config) (*tls.Config, error) {
 	t := &tls.Config{}
@@ -83,8 +97,9 @@ func (ap *defaultAuthPlugin) Prepare(req *http.Request) error {
 
 // bearerAuthPlugin represents authentication via a bearer token in the HTTP Authorization header
 type bearerAuthPlugin struct {
-	Scheme string `json:"scheme,omitempty"`
-	Token  string `json:"token"`
+	Scheme    string `json:"scheme,omitempty"`
+	Token     string `json:"token"`
+	TokenPath string `json:"token_path,omitempty"`
 }
 
 func (ap *bearerAuthPlugin) NewClient(c Config) (*http.Client, error) {
@@ -92,6 +107,9 @@ func (ap *bearerAuthPlugin) NewClient(c Config) (*http.Client, error) {
 	if err != nil {
 		return nil, err
 	}
+	if ap.Token != "" && ap.TokenPath != "" {
+		return nil, errors.New("invalid config: specify a value for either the \"token\" or \"token_path\" field")
+	}
 	if ap.Scheme == "" {
 		ap.Scheme = "Bearer"
 	}
@@ -99,10 +117,125 @@ func (ap *bearerAuthPlugin) NewClient(c Config) (*http.Client, error) {
 }
 
 func (ap *bearerAuthPlugin) Prepare(req *http.Request) error {
-	req.Header.Add("Authorization", fmt.Sprintf("%v %v", ap.Scheme, ap.Token))
+	token := ap.Token
+
+	// the token file is read on every request so that rotated tokens are
+	// picked up without a restart
+	if ap.TokenPath != "" {
+		bs, err := ioutil.ReadFile(ap.TokenPath)
+		if err != nil {
+			return err
+		}
+		token = strings.TrimSpace(string(bs))
+	}
+
+	req.Header.Add("Authorization", fmt.Sprintf("%v %v", ap.Scheme, token))
 	return nil
 }
 
+// oauth2ClientCredentialsAuthPlugin represents authentication via a bearer
+// token obtained with the OAuth2 client credentials grant
+type oauth2ClientCredentialsAuthPlugin struct {
+	TokenURL     string   `json:"token_url"`
+	ClientID     string   `json:"client_id"`
+	ClientSecret string   `json:"client_secret"`
+	Scopes       []string `json:"scopes,omitempty"`
+
+	client    *http.Client
+	mtx       sync.Mutex
+	token     string
+	expiresAt time.Time
+}
+
+func (ap *oauth2ClientCredentialsAuthPlugin) NewClient(c Config) (*http.Client, error) {
+	t, err := defaultTLSConfig(c)
+	if err != nil {
+		return nil, err
+	}
+	if ap.TokenURL == "" {
+		return nil, errors.New("token_url is needed when OAuth2 client credentials are enabled")
+	}
+	if ap.ClientID == "" || ap.ClientSecret == "" {
+		return nil, errors.New("client_id and client_secret are needed when OAuth2 client credentials are enabled")
+	}
+	ap.mtx.Lock()
+	defer ap.mtx.Unlock()
+	if ap.client == nil {
+		ap.client = defaultRoundTripperClient(t)
+	}
+	return ap.client, nil
+}
+
+func (ap *oauth2ClientCredentialsAuthPlugin) Prepare(req *http.Request) error {
+	token, err := ap.requestToken(req.Context())
+	if err != nil {
+		return err
+	}
+	req.Header.Add("Authorization", "Bearer "+token)
+	return nil
+}
+
+// requestToken returns the cached access token or requests a new token if the
+// cached token has expired (or will expire shortly.)
+func (ap *oauth2ClientCredentialsAuthPlugin) requestToken(ctx context.Context) (string, error) {
+	ap.mtx.Lock()
+	defer ap.mtx.Unlock()
+
+	if ap.token != "" && time.Now().Add(oauth2TokenExpiryMargin).Before(ap.expiresAt) {
+		return ap.token, nil
+	}
+
+	body := url.Values{"grant_type": []string{"client_credentials"}}
+	if len(ap.Scopes) > 0 {
+		body.Set("scope", strings.Join(ap.Scopes, " "))
+	}
+
+	req, err := http.NewRequest("POST", ap.TokenURL, strings.NewReader(body.Encode()))
+	if err != nil {
+		return "", err
+	}
+
+	req = req.WithContext(ctx)
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.SetBasicAuth(url.QueryEscape(ap.ClientID), url.QueryEscape(ap.ClientSecret))
+
+	logrus.Debug("Requesting OAuth2 token with client credentials.")
+
+	resp, err := ap.client.Do(req)
+	if err != nil {
+		return "", err
+	}
+
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return "", errors.New("OAuth2 token request failed: " + resp.Status)
+	}
+
+	var payload struct {
+		AccessToken string `json:"access_token"`
+		TokenType   string `json:"token_type"`
+		ExpiresIn   int64  `json:"expires_in"`
+	}
+
+	if err := json.NewDecoder(resp.Body).Decode(&payload); err != nil {
+		return "", errors.New("failed to parse OAuth2 token response: " + err.Error())
+	}
+
+	if payload.AccessToken == "" {
+		return "", errors.New("OAuth2 token response did not include an access token")
+	}
+
+	if payload.TokenType != "" && !strings.EqualFold(payload.TokenType, "bearer") {
+		return "", errors.New("OAuth2 token response has unsupported token type: " + payload.TokenType)
+	}
+
+	ap.token = payload.AccessToken
+	ap.expiresAt = time.Now().Add(time.Duration(payload.ExpiresIn) * time.Second)
+
+	return ap.token, nil
+}
+
 // clientTLSAuthPlugin represents authentication via client certificate on a TLS connection
 type clientTLSAuthPlugin struct {
 	Cert                 string `json:"cert"`
@@ -186,6 +319,7 @@ func (ap *clientTLSAuthPlugin) Prepare(req *http.Request) error {
 type awsSigningAuthPlugin struct {
 	AWSEnvironmentCredentials *awsEnvironmentCredentialService `json:"environment_credentials,omitempty"`
 	AWSMetadataCredentials    *awsMetadataCredentialService    `json:"metadata_credentials,omitempty"`
+	AWSService                string                           `json:"service,omitempty"`
 }
 
 func (ap *awsSigningAuthPlugin) awsCredentialService() awsCredentialService {
@@ -209,11 +343,14 @@ func (ap *awsSigningAuthPlugin) NewClient(c Config) (*http.Client, error) {
 			return nil, errors.New("at least aws_region must be specified for AWS metadata credential service")
 		}
 	}
+	if ap.AWSService == "" {
+		ap.AWSService = awsDefaultSigningService
+	}
 	return defaultRoundTripperClient(t), nil
 }
 
 func (ap *awsSigningAuthPlugin) Prepare(req *http.Request) error {
 	logrus.Debug("Signing request with AWS credentials.")
-	err := signV4(req, ap.awsCredentialService(), time.Now())
+	err := signV4(req, ap.AWSService, ap.awsCredentialService(), time.Now())
 	return err
 }
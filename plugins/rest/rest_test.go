@@ -20,6 +20,7 @@ import (
 	"net/http"
 	"net/http/httptest"
 	"path/filepath"
+	"reflect"
 	"strings"
 	"testing"
 	"time"
@@ -261,6 +262,180 @@ func TestBearerTokenCustomScheme(t *testing.T) {
 	testBearerToken(t, "Acmecorp-Token", "secret")
 }
 
+func TestBearerTokenPath(t *testing.T) {
+	ts := testServer{
+		t:               t,
+		expBearerScheme: "Bearer",
+		expBearerToken:  "secret",
+	}
+	ts.start()
+	defer ts.stop()
+
+	files := map[string]string{
+		"token": "secret\n",
+	}
+
+	test.WithTempFS(files, func(path string) {
+		config := fmt.Sprintf(`{
+			"name": "foo",
+			"url": %q,
+			"credentials": {
+				"bearer": {
+					"token_path": %q
+				}
+			}
+		}`, ts.server.URL, filepath.Join(path, "token"))
+		client, err := New([]byte(config))
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		ctx := context.Background()
+		if _, err := client.Do(ctx, "GET", "test"); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+	})
+}
+
+func TestOAuth2ClientCredentials(t *testing.T) {
+	var tokens int
+	expiresIn := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/token" {
+			if id, secret, ok := r.BasicAuth(); !ok || id != "opa" || secret != "s3cr3t" {
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+			if r.FormValue("grant_type") != "client_credentials" || r.FormValue("scope") != "read write" {
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+			tokens++
+			fmt.Fprintf(w, `{"access_token": "t%d", "token_type": "bearer", "expires_in": %d}`, tokens, expiresIn)
+			return
+		}
+		if r.Header.Get("Authorization") != fmt.Sprintf("Bearer t%d", tokens) {
+			w.WriteHeader(http.StatusUnauthorized)
+		}
+	}))
+	defer server.Close()
+
+	config := fmt.Sprintf(`{
+		"name": "foo",
+		"url": %q,
+		"credentials": {
+			"oauth2": {
+				"token_url": "%v/token",
+				"client_id": "opa",
+				"client_secret": "s3cr3t",
+				"scopes": ["read", "write"]
+			}
+		}
+	}`, server.URL, server.URL)
+	client, err := New([]byte(config))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	ctx := context.Background()
+
+	// expired tokens are refreshed on the next request, valid tokens are reused
+	for i, exp := range []int{1, 2, 2} {
+		if i == 1 {
+			expiresIn = 3600
+		}
+		resp, err := client.Do(ctx, "GET", "test")
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		} else if resp.StatusCode != http.StatusOK {
+			t.Fatalf("Expected 200 but got: %v", resp.StatusCode)
+		} else if tokens != exp {
+			t.Fatalf("Expected %d token requests after request %d but got %d", exp, i, tokens)
+		}
+	}
+}
+
+func TestDoAbsoluteURLWithAuthorizationHeader(t *testing.T) {
+	var auth []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/token" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		auth = r.Header["Authorization"]
+	}))
+	defer server.Close()
+	config := `{
+		"name": "foo",
+		"url": "http://example.com",
+		"credentials": {
+			"bearer": {
+				"token": "secret"
+			}
+		}
+	}`
+	client, err := New([]byte(config))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	ctx := context.Background()
+	resp, err := client.WithHeader("Authorization", "Bearer override").Do(ctx, "GET", server.URL+"/token")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	} else if resp.StatusCode != http.StatusOK {
+		t.Fatalf("Expected 200 but got: %v", resp.StatusCode)
+	}
+	if !reflect.DeepEqual(auth, []string{"Bearer override"}) {
+		t.Fatalf("Expected only the Authorization header set on the client but got: %v", auth)
+	}
+}
+
+func TestDoAbsoluteURLCredentials(t *testing.T) {
+	var auth, custom []string
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		auth = r.Header["Authorization"]
+		custom = r.Header["X-Custom"]
+	})
+	service := httptest.NewServer(handler)
+	defer service.Close()
+	other := httptest.NewServer(handler)
+	defer other.Close()
+
+	config := fmt.Sprintf(`{
+		"name": "foo",
+		"url": %q,
+		"headers": {"x-custom": "value"},
+		"credentials": {
+			"bearer": {
+				"token": "secret"
+			}
+		}
+	}`, service.URL)
+	client, err := New([]byte(config))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	tests := []struct {
+		url       string
+		expAuth   []string
+		expCustom []string
+	}{
+		{service.URL + "/test", []string{"Bearer secret"}, []string{"value"}},
+		{other.URL + "/test", nil, nil},
+	}
+
+	for _, tc := range tests {
+		resp, err := client.Do(context.Background(), "GET", tc.url)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		resp.Body.Close()
+		if !reflect.DeepEqual(auth, tc.expAuth) || !reflect.DeepEqual(custom, tc.expCustom) {
+			t.Fatalf("Expected headers %v and %v for %v but got %v and %v", tc.expAuth, tc.expCustom, tc.url, auth, custom)
+		}
+	}
+}
+
 func TestClientCert(t *testing.T) {
 	ts := testServer{
 		t:                t,
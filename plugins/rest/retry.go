@@ -0,0 +1,237 @@
+// Copyright 2020 The OPA Authors.  All rights reserved.
+// Use of this source code is governed by an Apache2
+// license that can be found in the LICENSE file.
+
+package rest
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+)
+
+const (
+	defaultTimeoutSeconds             = int64(60)
+	defaultRetryMaxAttempts           = 1
+	defaultRetryMaxDelaySeconds       = int64(10)
+	defaultCircuitBreakerResetSeconds = int64(30)
+
+	minRetryDelay = time.Millisecond * 100
+)
+
+// Circuit breaker states reported in Stats.
+const (
+	CircuitClosed   = "closed"
+	CircuitOpen     = "open"
+	CircuitHalfOpen = "half-open"
+)
+
+// RetryConfig represents the retry configuration for requests to a service.
+type RetryConfig struct {
+	MaxAttempts     int    `json:"max_attempts,omitempty"`      // max number of attempts per request (including the first attempt)
+	MaxDelaySeconds *int64 `json:"max_delay_seconds,omitempty"` // max amount of time to wait between attempts
+}
+
+// CircuitBreakerConfig represents the circuit breaker configuration for
+// requests to a service. The circuit breaker is disabled if the failure
+// threshold is zero.
+type CircuitBreakerConfig struct {
+	FailureThreshold    int    `json:"failure_threshold,omitempty"`     // number of consecutive failed requests that open the circuit
+	ResetTimeoutSeconds *int64 `json:"reset_timeout_seconds,omitempty"` // amount of time to wait before probing the service again
+}
+
+// Stats contains counters for the requests sent to a service.
+type Stats struct {
+	Requests     uint64 `json:"requests"`      // number of attempts sent to the service
+	Failures     uint64 `json:"failures"`      // number of attempts that failed
+	Retries      uint64 `json:"retries"`       // number of attempts that were retries
+	Rejected     uint64 `json:"rejected"`      // number of requests rejected by the open circuit breaker
+	CircuitState string `json:"circuit_state"` // current state of the circuit breaker
+}
+
+// CircuitOpenError is returned when a request is rejected because the circuit
+// breaker for the service is open.
+type CircuitOpenError struct {
+	Service string
+}
+
+func (e *CircuitOpenError) Error() string {
+	return fmt.Sprintf("circuit breaker for service %q is open", e.Service)
+}
+
+func (c *Config) validateAndInjectDefaults() error {
+
+	if c.TimeoutSeconds == nil {
+		timeout := defaultTimeoutSeconds
+		c.TimeoutSeconds = &timeout
+	} else if *c.TimeoutSeconds < 0 {
+		return fmt.Errorf("timeout_seconds must be >= 0")
+	}
+
+	if c.Retry.MaxAttempts == 0 {
+		c.Retry.MaxAttempts = defaultRetryMaxAttempts
+	} else if c.Retry.MaxAttempts < 0 {
+		return fmt.Errorf("retry.max_attempts must be >= 1")
+	}
+
+	if c.Retry.MaxDelaySeconds == nil {
+		delay := defaultRetryMaxDelaySeconds
+		c.Retry.MaxDelaySeconds = &delay
+	} else if *c.Retry.MaxDelaySeconds < 0 {
+		return fmt.Errorf("retry.max_delay_seconds must be >= 0")
+	}
+
+	if c.CircuitBreaker.FailureThreshold < 0 {
+		return fmt.Errorf("circuit_breaker.failure_threshold must be >= 0")
+	}
+
+	if c.CircuitBreaker.ResetTimeoutSeconds == nil {
+		reset := defaultCircuitBreakerResetSeconds
+		c.CircuitBreaker.ResetTimeoutSeconds = &reset
+	} else if *c.CircuitBreaker.ResetTimeoutSeconds < 0 {
+		return fmt.Errorf("circuit_breaker.reset_timeout_seconds must be >= 0")
+	}
+
+	return nil
+}
+
+// clientState is shared by all copies of a Client. It tracks the request
+// counters and the circuit breaker for the service.
+type clientState struct {
+	mtx      sync.Mutex
+	stats    Stats
+	state    string
+	failures int
+	openedAt time.Time
+	probing  bool
+}
+
+func newClientState() *clientState {
+	return &clientState{state: CircuitClosed}
+}
+
+// allow returns an error if the request must be rejected because the circuit
+// breaker is open. Once the reset timeout has elapsed, a single request is let
+// through to probe the service.
+func (s *clientState) allow(c *Config, now time.Time) error {
+	if s == nil || c.CircuitBreaker.FailureThreshold == 0 {
+		return nil
+	}
+
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	if s.state == CircuitOpen && now.Sub(s.openedAt) >= time.Duration(*c.CircuitBreaker.ResetTimeoutSeconds)*time.Second {
+		s.state = CircuitHalfOpen
+	}
+
+	if s.state == CircuitOpen || (s.state == CircuitHalfOpen && s.probing) {
+		s.stats.Rejected++
+		return &CircuitOpenError{Service: c.Name}
+	}
+
+	if s.state == CircuitHalfOpen {
+		s.probing = true
+	}
+
+	return nil
+}
+
+// attempt records the outcome of a single attempt.
+func (s *clientState) attempt(retry bool, failed bool) {
+	if s == nil {
+		return
+	}
+
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	s.stats.Requests++
+
+	if retry {
+		s.stats.Retries++
+	}
+
+	if failed {
+		s.stats.Failures++
+	}
+}
+
+// done records the outcome of a request (after all attempts) and updates the
+// circuit breaker.
+func (s *clientState) done(c *Config, failed bool, now time.Time) {
+	if s == nil || c.CircuitBreaker.FailureThreshold == 0 {
+		return
+	}
+
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	s.probing = false
+
+	if !failed {
+		s.state = CircuitClosed
+		s.failures = 0
+		return
+	}
+
+	s.failures++
+
+	if s.state == CircuitHalfOpen || s.failures >= c.CircuitBreaker.FailureThreshold {
+		s.state = CircuitOpen
+		s.openedAt = now
+	}
+}
+
+func (s *clientState) snapshot() Stats {
+	if s == nil {
+		return Stats{CircuitState: CircuitClosed}
+	}
+
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	stats := s.stats
+	stats.CircuitState = s.state
+
+	return stats
+}
+
+// isFailure returns true if the attempt failed in a way that may succeed if the
+// request is retried, i.e., the request could not be sent or the service
+// replied with a server error or asked the client to back off.
+func isFailure(resp *http.Response, err error) bool {
+	if err != nil {
+		return true
+	}
+	return resp.StatusCode == http.StatusTooManyRequests ||
+		(resp.StatusCode >= 500 && resp.StatusCode != http.StatusNotImplemented)
+}
+
+// sleep waits for d or until the context is done.
+func sleep(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// cancelBody cancels the request context when the response body is closed.
+type cancelBody struct {
+	io.ReadCloser
+	cancel context.CancelFunc
+}
+
+func (b *cancelBody) Close() error {
+	err := b.ReadCloser.Close()
+	b.cancel()
+	return err
+}
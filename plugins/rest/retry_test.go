@@ -0,0 +1,172 @@
+// Copyright 2020 The OPA Authors.  All rights reserved.
+// Use of this source code is governed by an Apache2
+// license that can be found in the LICENSE file.
+
+package rest
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestRetry(t *testing.T) {
+	var calls int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		bs, _ := ioutil.ReadAll(r.Body)
+		if string(bs) != "payload" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		if atomic.AddInt32(&calls, 1) < 3 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	}))
+	defer server.Close()
+
+	client, err := New([]byte(fmt.Sprintf(`{
+		"name": "foo",
+		"url": %q,
+		"retry": {"max_attempts": 3, "max_delay_seconds": 0}
+	}`, server.URL)))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	resp, err := client.WithBytes([]byte("payload")).Do(context.Background(), "POST", "test")
+	if err != nil {
+		t.Fatal(err)
+	} else if resp.StatusCode != http.StatusOK {
+		t.Fatalf("Expected 200 but got %v", resp.StatusCode)
+	}
+
+	exp := Stats{Requests: 3, Failures: 2, Retries: 2, CircuitState: CircuitClosed}
+	if stats := client.Stats(); stats != exp {
+		t.Fatalf("Expected %+v but got %+v", exp, stats)
+	}
+
+	// client errors are not retried
+	atomic.StoreInt32(&calls, 0)
+
+	resp, err = client.Do(context.Background(), "POST", "test")
+	if err != nil {
+		t.Fatal(err)
+	} else if resp.StatusCode != http.StatusBadRequest {
+		t.Fatalf("Expected 400 but got %v", resp.StatusCode)
+	} else if stats := client.Stats(); stats.Requests != 4 {
+		t.Fatalf("Expected 4 requests but got %+v", stats)
+	}
+}
+
+func TestCircuitBreaker(t *testing.T) {
+	var fail int32 = 1
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.LoadInt32(&fail) == 1 {
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+	}))
+	defer server.Close()
+
+	client, err := New([]byte(fmt.Sprintf(`{
+		"name": "foo",
+		"url": %q,
+		"circuit_breaker": {"failure_threshold": 2, "reset_timeout_seconds": 60}
+	}`, server.URL)))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx := context.Background()
+
+	for i := 0; i < 2; i++ {
+		if _, err := client.Do(ctx, "GET", "test"); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if _, err := client.Do(ctx, "GET", "test"); err == nil {
+		t.Fatal("Expected circuit breaker error")
+	} else if _, ok := err.(*CircuitOpenError); !ok {
+		t.Fatalf("Expected circuit breaker error but got: %v", err)
+	}
+
+	exp := Stats{Requests: 2, Failures: 2, Rejected: 1, CircuitState: CircuitOpen}
+	if stats := client.Stats(); stats != exp {
+		t.Fatalf("Expected %+v but got %+v", exp, stats)
+	}
+
+	// simulate the reset timeout elapsing, the probe fails and re-opens the circuit
+	client.state.openedAt = time.Now().Add(-time.Minute)
+
+	if _, err := client.Do(ctx, "GET", "test"); err != nil {
+		t.Fatal(err)
+	} else if stats := client.Stats(); stats.CircuitState != CircuitOpen || stats.Requests != 3 {
+		t.Fatalf("Expected circuit to re-open after failed probe but got %+v", stats)
+	}
+
+	// the next probe succeeds and closes the circuit
+	client.state.openedAt = time.Now().Add(-time.Minute)
+	atomic.StoreInt32(&fail, 0)
+
+	if _, err := client.Do(ctx, "GET", "test"); err != nil {
+		t.Fatal(err)
+	} else if stats := client.Stats(); stats.CircuitState != CircuitClosed {
+		t.Fatalf("Expected circuit to close after successful probe but got %+v", stats)
+	}
+}
+
+func TestTimeout(t *testing.T) {
+	done := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-done:
+		case <-r.Context().Done():
+		}
+	}))
+	defer server.Close()
+	defer close(done)
+
+	client, err := New([]byte(fmt.Sprintf(`{
+		"name": "foo",
+		"url": %q,
+		"timeout_seconds": 1
+	}`, server.URL)))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := client.Do(context.Background(), "GET", "test"); err == nil {
+		t.Fatal("Expected timeout error")
+	} else if stats := client.Stats(); stats.Failures != 1 {
+		t.Fatalf("Expected failure to be recorded but got %+v", stats)
+	}
+}
+
+func TestRetryConfigValidation(t *testing.T) {
+	tests := []string{
+		`{"name": "foo", "url": "http://localhost", "timeout_seconds": -1}`,
+		`{"name": "foo", "url": "http://localhost", "retry": {"max_attempts": -1}}`,
+		`{"name": "foo", "url": "http://localhost", "circuit_breaker": {"failure_threshold": -1}}`,
+	}
+
+	for _, config := range tests {
+		if _, err := New([]byte(config)); err == nil {
+			t.Errorf("Expected error for %v", config)
+		}
+	}
+
+	client, err := New([]byte(`{"name": "foo", "url": "http://localhost"}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	config := client.Config()
+	if *config.TimeoutSeconds != defaultTimeoutSeconds || config.Retry.MaxAttempts != defaultRetryMaxAttempts || config.CircuitBreaker.FailureThreshold != 0 {
+		t.Fatalf("Unexpected defaults: %+v", config)
+	}
+}
@@ -143,6 +143,46 @@ p = ["hello", "world"] { true }`,
 	// err: <nil>
 }
 
+func ExampleResultSet_Decode() {
+
+	ctx := context.Background()
+
+	// Create query that produces a single document.
+	rego := rego.New(
+		rego.Query("data.example.decision"),
+		rego.Module("example.rego",
+			`package example
+
+decision = {"allowed": true, "reasons": ["owner"], "ttl_seconds": 300}`,
+		))
+
+	// Run evaluation.
+	rs, err := rego.Eval(ctx)
+	if err != nil {
+		// Handle error.
+	}
+
+	// Decode the result into a struct.
+	var decision struct {
+		Allowed bool     `json:"allowed"`
+		Reasons []string `json:"reasons"`
+		TTL     int      `json:"ttl_seconds"`
+	}
+
+	defined, err := rs.Decode(&decision)
+
+	// Inspect result.
+	fmt.Println("defined:", defined)
+	fmt.Printf("decision: %+v\n", decision)
+	fmt.Println("err:", err)
+
+	// Output:
+	//
+	// defined: true
+	// decision: {Allowed:true Reasons:[owner] TTL:300}
+	// err: <nil>
+}
+
 func ExampleRego_Eval_multipleDocuments() {
 
 	ctx := context.Background()
@@ -633,6 +673,42 @@ func ExampleRego_PrepareForEval() {
 	// updated result: true
 }
 
+func ExamplePreparedEvalQuery_Eval_callback() {
+	ctx := context.Background()
+
+	// Create a query that produces many results.
+	r := rego.New(
+		rego.Query("numbers = [1, 2, 3, 4, 5]; x = numbers[_]; x > 1"),
+	)
+
+	pq, err := r.PrepareForEval(ctx)
+
+	if err != nil {
+		// Handle error.
+	}
+
+	// Process each result as it is produced and stop after the second one.
+	n := 0
+
+	_, err = pq.Eval(ctx, rego.EvalCallback(func(result rego.Result) error {
+		fmt.Println("x:", result.Bindings["x"])
+		n++
+		if n == 2 {
+			return rego.ErrStopEval
+		}
+		return nil
+	}))
+
+	if err != nil {
+		// Handle error.
+	}
+
+	// Output:
+	//
+	// x: 2
+	// x: 3
+}
+
 func ExampleRego_PrepareForPartial() {
 
 	ctx := context.Background()
@@ -10,11 +10,19 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"hash/fnv"
 	"io"
+	"runtime"
+	"runtime/pprof"
+	"sort"
+	"strconv"
 	"strings"
+	"sync"
+	"time"
 
 	"github.com/open-policy-agent/opa/loader"
 	"github.com/open-policy-agent/opa/types"
+	"github.com/open-policy-agent/opa/util"
 
 	"github.com/open-policy-agent/opa/bundle"
 
@@ -27,11 +35,17 @@ import (
 	"github.com/open-policy-agent/opa/storage"
 	"github.com/open-policy-agent/opa/storage/inmem"
 	"github.com/open-policy-agent/opa/topdown"
-	"github.com/open-policy-agent/opa/util"
+	"github.com/open-policy-agent/opa/topdown/builtins"
 )
 
 const defaultPartialNamespace = "partial"
 
+// Names of the pprof labels set on evaluation when profile labels are enabled.
+const (
+	profileLabelQueryHash  = "opa_query_hash"
+	profileLabelPolicyPath = "opa_policy_path"
+)
+
 // CompileResult represents the result of compiling a Rego query, zero or more
 // Rego modules, and arbitrary contextual data into an executable.
 type CompileResult struct {
@@ -78,6 +92,8 @@ type EvalContext struct {
 	txn              storage.Transaction
 	instrument       bool
 	instrumentation  *topdown.Instrumentation
+	profileLabels    bool
+	processAllocs    bool
 	partialNamespace string
 	tracers          []topdown.Tracer
 	compiledQuery    compiledQuery
@@ -85,6 +101,11 @@ type EvalContext struct {
 	disableInlining  []ast.Ref
 	parsedUnknowns   []*ast.Term
 	indexing         bool
+	time             time.Time
+	seed             io.Reader
+	interQueryCache  *builtins.InterQueryCache
+	resultLimit      int
+	resultCallback   func(Result) error
 }
 
 // EvalOption defines a function to set an option on an EvalConfig
@@ -98,6 +119,24 @@ func EvalInput(input interface{}) EvalOption {
 	}
 }
 
+// EvalTime sets the time returned by the time.now_ns built-in function. If
+// the time is not set, the current time is used.
+func EvalTime(t time.Time) EvalOption {
+	return func(e *EvalContext) {
+		e.time = t
+	}
+}
+
+// EvalSeed sets the source of randomness used by built-in functions that
+// generate random values (e.g., rand.intn and uuid.rfc4122). If the seed is
+// not set, crypto/rand is used. Reads from r are serialized so the same
+// reader may be passed to concurrent evaluations.
+func EvalSeed(r io.Reader) EvalOption {
+	return func(e *EvalContext) {
+		e.seed = newLockedReader(r)
+	}
+}
+
 // EvalParsedInput configures the input for a Prepared Query's evaluation
 func EvalParsedInput(input ast.Value) EvalOption {
 	return func(e *EvalContext) {
@@ -113,6 +152,32 @@ func EvalMetrics(metric metrics.Metrics) EvalOption {
 	}
 }
 
+// EvalResultLimit sets the maximum number of results a Prepared Query's
+// evaluation may produce. Evaluation fails with a topdown.ResultLimitErr error
+// if the limit is exceeded. Zero means unlimited.
+func EvalResultLimit(n int) EvalOption {
+	return func(e *EvalContext) {
+		e.resultLimit = n
+	}
+}
+
+// ErrStopEval can be returned by the callback set with EvalCallback to stop
+// evaluation without an error.
+var ErrStopEval = errors.New("stop evaluation")
+
+// EvalCallback configures a function that is called with each result of a
+// Prepared Query's evaluation as soon as the result is produced. Results
+// passed to the callback are not included in the ResultSet returned by Eval,
+// so large result sets do not have to be held in memory. If the callback
+// returns ErrStopEval, evaluation stops and Eval returns without an error.
+// Other errors returned by the callback stop evaluation and are returned by
+// Eval.
+func EvalCallback(f func(Result) error) EvalOption {
+	return func(e *EvalContext) {
+		e.resultCallback = f
+	}
+}
+
 // EvalTransaction configures the Transaction for a Prepared Query's evaluation
 func EvalTransaction(txn storage.Transaction) EvalOption {
 	return func(e *EvalContext) {
@@ -127,6 +192,22 @@ func EvalInstrument(instrument bool) EvalOption {
 	}
 }
 
+// EvalProfileLabels enables or disables pprof labels for a Prepared Query's
+// evaluation. See ProfileLabels for details.
+func EvalProfileLabels(yes bool) EvalOption {
+	return func(e *EvalContext) {
+		e.profileLabels = yes
+	}
+}
+
+// EvalProcessAllocMetrics enables or disables process-wide allocation metrics
+// for a Prepared Query's evaluation. See ProcessAllocMetrics for details.
+func EvalProcessAllocMetrics(yes bool) EvalOption {
+	return func(e *EvalContext) {
+		e.processAllocs = yes
+	}
+}
+
 // EvalTracer configures a tracer for a Prepared Query's evaluation
 func EvalTracer(tracer topdown.Tracer) EvalOption {
 	return func(e *EvalContext) {
@@ -206,12 +287,18 @@ func (pq preparedQuery) newEvalContext(ctx context.Context, options []EvalOption
 		txn:              nil,
 		instrument:       false,
 		instrumentation:  nil,
+		profileLabels:    pq.r.profileLabels,
+		processAllocs:    pq.r.processAllocs,
 		partialNamespace: pq.r.partialNamespace,
 		tracers:          nil,
 		unknowns:         pq.r.unknowns,
 		parsedUnknowns:   pq.r.parsedUnknowns,
 		compiledQuery:    compiledQuery{},
 		indexing:         true,
+		time:             pq.r.time,
+		seed:             pq.r.seed,
+		interQueryCache:  pq.r.interQueryCache,
+		resultLimit:      pq.r.resultLimit,
 	}
 
 	for _, o := range options {
@@ -268,7 +355,8 @@ func (pq preparedQuery) newEvalContext(ctx context.Context, options []EvalOption
 }
 
 // PreparedEvalQuery holds the prepared Rego state that has been pre-processed
-// for subsequent evaluations.
+// for subsequent evaluations. A PreparedEvalQuery is safe for concurrent use by
+// multiple goroutines.
 type PreparedEvalQuery struct {
 	preparedQuery
 }
@@ -287,11 +375,59 @@ func (pq PreparedEvalQuery) Eval(ctx context.Context, options ...EvalOption) (Re
 
 	ectx.compiledQuery = pq.r.compiledQueries[evalQueryType]
 
-	return pq.r.eval(ctx, ectx)
+	var rs ResultSet
+	err = pq.r.profile(ctx, ectx, func(ctx context.Context) error {
+		var err error
+		rs, err = pq.r.eval(ctx, ectx)
+		return err
+	})
+
+	return rs, err
+}
+
+// EvalBatch evaluates the prepared query against each of the inputs and returns
+// the result sets in the order of the inputs. The result set of an input is nil
+// if the query is undefined for it. Nil inputs are treated as missing inputs.
+// The inputs are evaluated in a single transaction and caches that do not
+// depend on the input (e.g., base documents read from the store and built-in
+// function results) are shared across the inputs so evaluating a batch is
+// cheaper than calling Eval once per input. As a consequence, time.now_ns
+// returns the same time for all inputs. Input options are ignored and result
+// callbacks are not supported. If evaluation fails for any input, an error is
+// returned.
+func (pq PreparedEvalQuery) EvalBatch(ctx context.Context, inputs []interface{}, options ...EvalOption) ([]ResultSet, error) {
+	ectx, finish, err := pq.newEvalContext(ctx, options)
+	if err != nil {
+		return nil, err
+	}
+	defer finish(ctx)
+
+	ectx.compiledQuery = pq.r.compiledQueries[evalQueryType]
+
+	parsed := make([]ast.Value, len(inputs))
+	for i := range inputs {
+		if inputs[i] == nil {
+			continue
+		}
+		parsed[i], err = pq.r.parseRawInput(&inputs[i], ectx.metrics)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	var results []ResultSet
+	err = pq.r.profile(ctx, ectx, func(ctx context.Context) error {
+		var err error
+		results, err = pq.r.evalBatch(ctx, ectx, parsed)
+		return err
+	})
+
+	return results, err
 }
 
 // PreparedPartialQuery holds the prepared Rego state that has been pre-processed
-// for partial evaluations.
+// for partial evaluations. A PreparedPartialQuery is safe for concurrent use by
+// multiple goroutines.
 type PreparedPartialQuery struct {
 	preparedQuery
 }
@@ -308,7 +444,14 @@ func (pq PreparedPartialQuery) Partial(ctx context.Context, options ...EvalOptio
 
 	ectx.compiledQuery = pq.r.compiledQueries[partialQueryType]
 
-	return pq.r.partial(ctx, ectx)
+	var pqs *PartialQueries
+	err = pq.r.profile(ctx, ectx, func(ctx context.Context) error {
+		var err error
+		pqs, err = pq.r.partial(ctx, ectx)
+		return err
+	})
+
+	return pqs, err
 }
 
 // Result defines the output of Rego evaluation.
@@ -336,6 +479,14 @@ type ExpressionValue struct {
 	Location *Location   `json:"location"`
 }
 
+// Decode stores the value of the expression in the value pointed to by x. The
+// semantics are the same as json.Unmarshal (e.g., struct fields are matched by
+// their json tags) but the value is not serialized. See util.Decode for
+// details.
+func (ev *ExpressionValue) Decode(x interface{}) error {
+	return util.Decode(ev.Value, x)
+}
+
 func newExpressionValue(expr *ast.Expr, value interface{}) *ExpressionValue {
 	result := &ExpressionValue{
 		Value: value,
@@ -358,10 +509,99 @@ func (ev *ExpressionValue) String() string {
 // result set represents an undefined query.
 type ResultSet []Result
 
+// Decode stores the value of the first expression of the first result in the
+// value pointed to by x (see ExpressionValue.Decode). If the result set is
+// empty (i.e., the query is undefined), x is not modified and false is
+// returned.
+func (rs ResultSet) Decode(x interface{}) (bool, error) {
+	if len(rs) == 0 || len(rs[0].Expressions) == 0 {
+		return false, nil
+	}
+	return true, rs[0].Expressions[0].Decode(x)
+}
+
+// Bindings returns the variable bindings of each solution in the result set.
+// Wildcard and generated variables are omitted.
+func (rs ResultSet) Bindings() []Vars {
+	result := make([]Vars, len(rs))
+	for i := range rs {
+		result[i] = rs[i].Bindings.WithoutWildcards()
+	}
+	return result
+}
+
+// SortByBindings sorts the result set so that solutions are returned in a
+// deterministic order regardless of the order in which they were produced
+// during evaluation. Solutions are compared by the values bound to vars, in
+// the order given. If vars is empty, all variables are compared in
+// lexicographic order of their names.
+func (rs ResultSet) SortByBindings(vars ...string) {
+
+	if len(vars) == 0 {
+		names := map[string]struct{}{}
+		for i := range rs {
+			for k := range rs[i].Bindings {
+				names[k] = struct{}{}
+			}
+		}
+		for k := range names {
+			vars = append(vars, k)
+		}
+		sort.Strings(vars)
+	}
+
+	keys := make([][]ast.Value, len(rs))
+	for i := range rs {
+		keys[i] = make([]ast.Value, len(vars))
+		for j, name := range vars {
+			if x, ok := rs[i].Bindings[name]; ok {
+				if v, err := ast.InterfaceToValue(x); err == nil {
+					keys[i][j] = v
+				}
+			}
+		}
+	}
+
+	sort.Stable(resultSetSorter{rs: rs, keys: keys})
+}
+
+type resultSetSorter struct {
+	rs   ResultSet
+	keys [][]ast.Value
+}
+
+func (s resultSetSorter) Len() int {
+	return len(s.rs)
+}
+
+func (s resultSetSorter) Less(i, j int) bool {
+	for k := range s.keys[i] {
+		if cmp := ast.Compare(s.keys[i][k], s.keys[j][k]); cmp != 0 {
+			return cmp < 0
+		}
+	}
+	return false
+}
+
+func (s resultSetSorter) Swap(i, j int) {
+	s.rs[i], s.rs[j] = s.rs[j], s.rs[i]
+	s.keys[i], s.keys[j] = s.keys[j], s.keys[i]
+}
+
 // Vars represents a collection of variable bindings. The keys are the variable
 // names and the values are the binding values.
 type Vars map[string]interface{}
 
+// Decode stores the value bound to the variable in the value pointed to by x
+// (see ExpressionValue.Decode).
+func (v Vars) Decode(name string, x interface{}) error {
+	value, ok := v[name]
+	if !ok {
+		return fmt.Errorf("rego: variable %v is not bound", name)
+	}
+	return util.Decode(value, x)
+}
+
 // WithoutWildcards returns a copy of v with wildcard variables removed.
 func (v Vars) WithoutWildcards() Vars {
 	n := Vars{}
@@ -412,7 +652,10 @@ type loadPaths struct {
 	filter loader.Filter
 }
 
-// Rego constructs a query and can be evaluated to obtain results.
+// Rego constructs a query and can be evaluated to obtain results. A Rego
+// object must not be used by multiple goroutines at the same time. To evaluate
+// the same query concurrently, prepare it with PrepareForEval or
+// PrepareForPartial and share the prepared query instead.
 type Rego struct {
 	query            string
 	parsedQuery      ast.Body
@@ -421,6 +664,7 @@ type Rego struct {
 	parsedPackage    *ast.Package
 	imports          []string
 	parsedImports    []*ast.Import
+	selection        *selection
 	rawInput         *interface{}
 	parsedInput      ast.Value
 	unknowns         []string
@@ -439,13 +683,20 @@ type Rego struct {
 	trace            bool
 	instrumentation  *topdown.Instrumentation
 	instrument       bool
+	profileLabels    bool
+	processAllocs    bool
 	capture          map[*ast.Expr]ast.Var // map exprs to generated capture vars
 	termVarID        int
 	dump             io.Writer
 	runtime          *ast.Term
+	time             time.Time
+	seed             io.Reader
+	interQueryCache  *builtins.InterQueryCache
+	resultLimit      int
 	builtinDecls     map[string]*ast.Builtin
 	builtinFuncs     map[string]*topdown.Builtin
 	unsafeBuiltins   map[string]struct{}
+	strict           bool
 	loadPaths        loadPaths
 	bundlePaths      []string
 	bundles          map[string]*bundle.Bundle
@@ -702,6 +953,27 @@ func ParsedModule(module *ast.Module) func(*Rego) {
 	}
 }
 
+// Selection returns an argument that adds a Rego module and sets the query to
+// the expressions found between the start and end positions of the module
+// source. The positions are inclusive and exclusive respectively and use the
+// same row/column numbering as parser locations. The query is evaluated in the
+// context of the module's package and imports. If the selection covers one or
+// more complete rules, the query refers to the documents generated by those
+// rules. If the selection is inside of a rule body, the expressions preceding
+// the selection in that body are evaluated as well so that the local variables
+// referenced by the selection are bound.
+func Selection(filename, module string, start, end Location) func(r *Rego) {
+	return func(r *Rego) {
+		Module(filename, module)(r)
+		r.selection = &selection{
+			filename: filename,
+			module:   module,
+			start:    start,
+			end:      end,
+		}
+	}
+}
+
 // Load returns an argument that adds a filesystem path to load data
 // and Rego modules from. Any file with a *.rego, *.yaml, or *.json
 // extension will be loaded. The path can be either a directory or file,
@@ -777,6 +1049,32 @@ func Instrument(yes bool) func(r *Rego) {
 	}
 }
 
+// ProfileLabels returns an argument that enables pprof labels on evaluation.
+// While the query is evaluated, the labels opa_query_hash (a hash of the
+// compiled query) and opa_policy_path (the first document under data that
+// the query refers to) are set so that CPU and goroutine profiles can
+// attribute samples to the query. Labels are disabled by default because they
+// add a small cost to each evaluation.
+func ProfileLabels(yes bool) func(r *Rego) {
+	return func(r *Rego) {
+		r.profileLabels = yes
+	}
+}
+
+// ProcessAllocMetrics returns an argument that records the bytes and objects
+// allocated by the process while the query is evaluated in the
+// rego_eval_process_alloc_bytes and rego_eval_process_alloc_objects counters.
+// The counters are computed from the process-wide memory statistics of the Go
+// runtime, so they include allocations made concurrently by other goroutines
+// and only approximate the allocations of the query if nothing else runs at
+// the same time. Reading the statistics stops the world, so the metrics are
+// disabled by default.
+func ProcessAllocMetrics(yes bool) func(r *Rego) {
+	return func(r *Rego) {
+		r.processAllocs = yes
+	}
+}
+
 // Trace returns an argument that enables tracing on r.
 func Trace(yes bool) func(r *Rego) {
 	return func(r *Rego) {
@@ -801,6 +1099,69 @@ func Runtime(term *ast.Term) func(r *Rego) {
 	}
 }
 
+// Time returns an argument that sets the time returned by the time.now_ns
+// built-in function. Together with Seed, this makes evaluation reproducible.
+func Time(t time.Time) func(r *Rego) {
+	return func(r *Rego) {
+		r.time = t
+	}
+}
+
+// Seed returns an argument that sets the source of randomness used by
+// built-in functions that generate random values (e.g., rand.intn and
+// uuid.rfc4122). The reader is shared by all evaluations of the query (and of
+// queries prepared from it.) Reads from r are serialized so r does not have to
+// be safe for concurrent use, however, concurrent evaluations consume the
+// reader in an unspecified order so results are only reproducible if
+// evaluations do not overlap. Use EvalSeed to supply a reader per evaluation.
+func Seed(r io.Reader) func(r *Rego) {
+	return func(rego *Rego) {
+		rego.seed = newLockedReader(r)
+	}
+}
+
+// lockedReader serializes reads from the underlying reader.
+type lockedReader struct {
+	mtx sync.Mutex
+	r   io.Reader
+}
+
+func newLockedReader(r io.Reader) io.Reader {
+	if r == nil {
+		return nil
+	}
+	if _, ok := r.(*lockedReader); ok {
+		return r
+	}
+	return &lockedReader{r: r}
+}
+
+func (l *lockedReader) Read(p []byte) (int, error) {
+	l.mtx.Lock()
+	defer l.mtx.Unlock()
+	return l.r.Read(p)
+}
+
+// InterQueryBuiltinCache returns an argument that sets the cache that built-in
+// functions use to share state (e.g., compiled regular expressions) across
+// queries. Callers that embed multiple engines can supply a separate cache to
+// each one to keep them isolated. If the cache is not set, a process-wide
+// default cache is used.
+func InterQueryBuiltinCache(c *builtins.InterQueryCache) func(r *Rego) {
+	return func(r *Rego) {
+		r.interQueryCache = c
+	}
+}
+
+// ResultLimit returns an argument that sets the maximum number of results
+// that evaluation may produce. Evaluation fails with a topdown.ResultLimitErr
+// error if the limit is exceeded. Zero means unlimited.
+func ResultLimit(n int) func(r *Rego) {
+	return func(r *Rego) {
+		r.resultLimit = n
+	}
+}
+
 // PrintTrace is a helper function to write a human-readable version of the
 // trace to the writer w.
 func PrintTrace(w io.Writer, r *Rego) {
@@ -820,6 +1181,14 @@ func UnsafeBuiltins(unsafeBuiltins map[string]struct{}) func(r *Rego) {
 	}
 }
 
+// Strict enables strict mode checks when compiling modules. This option is
+// ignored if the caller supplies the compiler.
+func Strict(yes bool) func(r *Rego) {
+	return func(r *Rego) {
+		r.strict = yes
+	}
+}
+
 // New returns a new Rego object.
 func New(options ...func(r *Rego)) *Rego {
 
@@ -838,7 +1207,8 @@ func New(options ...func(r *Rego)) *Rego {
 	if r.compiler == nil {
 		r.compiler = ast.NewCompiler().
 			WithUnsafeBuiltins(r.unsafeBuiltins).
-			WithBuiltins(r.builtinDecls)
+			WithBuiltins(r.builtinDecls).
+			WithStrict(r.strict)
 	}
 
 	if r.store == nil {
@@ -1373,13 +1743,9 @@ func (r *Rego) parseRawInput(rawInput *interface{}, m metrics.Metrics) (ast.Valu
 	defer m.Timer(metrics.RegoInputParse).Stop()
 	var input ast.Value
 	if rawInput != nil {
-		rawPtr := util.Reference(rawInput)
-		// roundtrip through json: this turns slices (e.g. []string, []bool) into
-		// []interface{}, the only array type ast.InterfaceToValue can work with
-		if err := util.RoundTrip(rawPtr); err != nil {
-			return nil, err
-		}
-		val, err := ast.InterfaceToValue(*rawPtr)
+		// Structs, typed slices and maps, etc. are converted directly
+		// without a round trip through JSON.
+		val, err := ast.InterfaceToValue(*rawInput)
 		if err != nil {
 			return nil, err
 		}
@@ -1394,11 +1760,22 @@ func (r *Rego) parseQuery(m metrics.Metrics) (ast.Body, error) {
 
 	var query ast.Body
 
-	if r.parsedQuery != nil {
+	if r.selection != nil {
+		var err error
+		query, err = r.parseSelection()
+		if err != nil {
+			return nil, err
+		}
+	} else if r.parsedQuery != nil {
 		query = r.parsedQuery
 	} else {
-		var err error
-		query, err = ast.ParseBody(r.query)
+		// The query can use the future keywords imported by the query's
+		// imports.
+		imports, err := r.getImports()
+		if err != nil {
+			return nil, err
+		}
+		query, err = ast.ParseBodyWithOpts(r.query, ast.ParserOptionsFromImports(imports))
 		if err != nil {
 			return nil, err
 		}
@@ -1407,6 +1784,25 @@ func (r *Rego) parseQuery(m metrics.Metrics) (ast.Body, error) {
 	return query, nil
 }
 
+func (r *Rego) getImports() ([]*ast.Import, error) {
+
+	imports := r.parsedImports
+
+	if len(r.imports) > 0 {
+		s := make([]string, len(r.imports))
+		for i := range r.imports {
+			s[i] = fmt.Sprintf("import %v", r.imports[i])
+		}
+		parsed, err := ast.ParseImports(strings.Join(s, "\n"))
+		if err != nil {
+			return nil, err
+		}
+		imports = append(imports, parsed...)
+	}
+
+	return imports, nil
+}
+
 func (r *Rego) compileModules(ctx context.Context, txn storage.Transaction, m metrics.Metrics) error {
 
 	// Only compile again if there are new modules.
@@ -1470,18 +1866,9 @@ func (r *Rego) compileQuery(query ast.Body, m metrics.Metrics, extras []extraSta
 		pkg = r.parsedPackage
 	}
 
-	imports := r.parsedImports
-
-	if len(r.imports) > 0 {
-		s := make([]string, len(r.imports))
-		for i := range r.imports {
-			s[i] = fmt.Sprintf("import %v", r.imports[i])
-		}
-		parsed, err := ast.ParseImports(strings.Join(s, "\n"))
-		if err != nil {
-			return nil, nil, err
-		}
-		imports = append(imports, parsed...)
+	imports, err := r.getImports()
+	if err != nil {
+		return nil, nil, err
 	}
 
 	qctx := ast.NewQueryContext().
@@ -1502,8 +1889,135 @@ func (r *Rego) compileQuery(query ast.Body, m metrics.Metrics, extras []extraSta
 
 }
 
+// profile calls f to evaluate the query in ectx. If profile labels are
+// enabled, f is called with pprof labels that identify the query. If process
+// allocation metrics are enabled, the memory allocated by the process while f
+// runs is recorded in the metrics.
+func (r *Rego) profile(ctx context.Context, ectx *EvalContext, f func(context.Context) error) error {
+
+	if ectx.processAllocs {
+		var before runtime.MemStats
+		runtime.ReadMemStats(&before)
+		defer func() {
+			var after runtime.MemStats
+			runtime.ReadMemStats(&after)
+			metrics.Add(ectx.metrics.Counter(metrics.RegoEvalProcessAllocBytes), after.TotalAlloc-before.TotalAlloc)
+			metrics.Add(ectx.metrics.Counter(metrics.RegoEvalProcessAllocObjects), after.Mallocs-before.Mallocs)
+		}()
+	}
+
+	if !ectx.profileLabels {
+		return f(ctx)
+	}
+
+	query := ectx.compiledQuery.query
+	h := fnv.New64a()
+	h.Write([]byte(query.String()))
+
+	labels := pprof.Labels(
+		profileLabelQueryHash, strconv.FormatUint(h.Sum64(), 16),
+		profileLabelPolicyPath, policyPath(query),
+	)
+
+	var err error
+	pprof.Do(ctx, labels, func(ctx context.Context) {
+		err = f(ctx)
+	})
+
+	return err
+}
+
+// policyPath returns the path of the first document under data that the query
+// refers to or an empty string if the query does not refer to data.
+func policyPath(query ast.Body) string {
+	var path ast.Ref
+	ast.WalkRefs(query, func(ref ast.Ref) bool {
+		if path == nil && ref.HasPrefix(ast.DefaultRootRef) {
+			path = ref.ConstantPrefix()
+		}
+		return path != nil
+	})
+	return path.String()
+}
+
 func (r *Rego) eval(ctx context.Context, ectx *EvalContext) (ResultSet, error) {
 
+	q := r.evalQuery(ectx)
+
+	if ectx.parsedInput != nil {
+		q = q.WithInput(ast.NewTerm(ectx.parsedInput))
+	}
+
+	// Cancel query if context is cancelled or deadline is reached.
+	exit := make(chan struct{})
+	defer close(exit)
+	q = withCancel(ctx, q, exit)
+
+	var rs ResultSet
+	err := q.Iter(ctx, func(qr topdown.QueryResult) error {
+		result, err := r.evalResult(ectx, qr)
+		if err != nil {
+			return err
+		}
+		if ectx.resultCallback != nil {
+			return ectx.resultCallback(result)
+		}
+		rs = append(rs, result)
+		return nil
+	})
+
+	if err == ErrStopEval {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	if len(rs) == 0 {
+		return nil, nil
+	}
+
+	return rs, nil
+}
+
+func (r *Rego) evalBatch(ctx context.Context, ectx *EvalContext, inputs []ast.Value) ([]ResultSet, error) {
+
+	if ectx.resultCallback != nil {
+		return nil, fmt.Errorf("result callbacks are not supported in batch evaluation")
+	}
+
+	q := r.evalQuery(ectx)
+
+	terms := make([]*ast.Term, len(inputs))
+	for i := range inputs {
+		if inputs[i] != nil {
+			terms[i] = ast.NewTerm(inputs[i])
+		}
+	}
+
+	// Cancel query if context is cancelled or deadline is reached.
+	exit := make(chan struct{})
+	defer close(exit)
+	q = withCancel(ctx, q, exit)
+
+	results := make([]ResultSet, len(inputs))
+	err := q.IterBatch(ctx, terms, func(i int, qr topdown.QueryResult) error {
+		result, err := r.evalResult(ectx, qr)
+		if err != nil {
+			return err
+		}
+		results[i] = append(results[i], result)
+		return nil
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	return results, nil
+}
+
+func (r *Rego) evalQuery(ectx *EvalContext) *topdown.Query {
+
 	q := topdown.NewQuery(ectx.compiledQuery.query).
 		WithQueryCompiler(ectx.compiledQuery.compiler).
 		WithCompiler(r.compiler).
@@ -1515,67 +2029,70 @@ func (r *Rego) eval(ctx context.Context, ectx *EvalContext) (ResultSet, error) {
 		WithRuntime(r.runtime).
 		WithIndexing(ectx.indexing)
 
+	if !ectx.time.IsZero() {
+		q = q.WithTime(ectx.time)
+	}
+
+	if ectx.seed != nil {
+		q = q.WithSeed(ectx.seed)
+	}
+
+	if ectx.interQueryCache != nil {
+		q = q.WithInterQueryBuiltinCache(ectx.interQueryCache)
+	}
+
+	if ectx.resultLimit > 0 {
+		q = q.WithResultLimit(ectx.resultLimit)
+	}
+
 	for i := range ectx.tracers {
 		q = q.WithTracer(ectx.tracers[i])
 	}
 
-	if ectx.parsedInput != nil {
-		q = q.WithInput(ast.NewTerm(ectx.parsedInput))
-	}
+	return q
+}
 
-	// Cancel query if context is cancelled or deadline is reached.
+// withCancel cancels the query when the context is done. The caller must close
+// exit once evaluation is complete.
+func withCancel(ctx context.Context, q *topdown.Query, exit chan struct{}) *topdown.Query {
 	c := topdown.NewCancel()
-	q = q.WithCancel(c)
-	exit := make(chan struct{})
-	defer close(exit)
 	go waitForDone(ctx, exit, func() {
 		c.Cancel()
 	})
+	return q.WithCancel(c)
+}
 
+func (r *Rego) evalResult(ectx *EvalContext, qr topdown.QueryResult) (Result, error) {
 	rewritten := ectx.compiledQuery.compiler.RewrittenVars()
-	var rs ResultSet
-	err := q.Iter(ctx, func(qr topdown.QueryResult) error {
-		result := newResult()
-		for k := range qr {
+	result := newResult()
+	for k := range qr {
+		v, err := ast.JSON(qr[k].Value)
+		if err != nil {
+			return result, err
+		}
+		if rw, ok := rewritten[k]; ok {
+			k = rw
+		}
+		if isTermVar(k) || k.IsGenerated() || k.IsWildcard() {
+			continue
+		}
+		result.Bindings[string(k)] = v
+	}
+	for _, expr := range ectx.compiledQuery.query {
+		if expr.Generated {
+			continue
+		}
+		if k, ok := r.capture[expr]; ok {
 			v, err := ast.JSON(qr[k].Value)
 			if err != nil {
-				return err
-			}
-			if rw, ok := rewritten[k]; ok {
-				k = rw
-			}
-			if isTermVar(k) || k.IsGenerated() || k.IsWildcard() {
-				continue
-			}
-			result.Bindings[string(k)] = v
-		}
-		for _, expr := range ectx.compiledQuery.query {
-			if expr.Generated {
-				continue
-			}
-			if k, ok := r.capture[expr]; ok {
-				v, err := ast.JSON(qr[k].Value)
-				if err != nil {
-					return err
-				}
-				result.Expressions = append(result.Expressions, newExpressionValue(expr, v))
-			} else {
-				result.Expressions = append(result.Expressions, newExpressionValue(expr, true))
+				return result, err
 			}
+			result.Expressions = append(result.Expressions, newExpressionValue(expr, v))
+		} else {
+			result.Expressions = append(result.Expressions, newExpressionValue(expr, true))
 		}
-		rs = append(rs, result)
-		return nil
-	})
-
-	if err != nil {
-		return nil, err
-	}
-
-	if len(rs) == 0 {
-		return nil, nil
 	}
-
-	return rs, nil
+	return result, nil
 }
 
 func (r *Rego) partialResult(ctx context.Context, pCfg *PrepareConfig) (PartialResult, error) {
@@ -1603,6 +2120,10 @@ func (r *Rego) partialResult(ctx context.Context, pCfg *PrepareConfig) (PartialR
 		compiledQuery:    r.compiledQueries[partialResultQueryType],
 		instrumentation:  r.instrumentation,
 		indexing:         true,
+		time:             r.time,
+		seed:             r.seed,
+		interQueryCache:  r.interQueryCache,
+		resultLimit:      r.resultLimit,
 	}
 
 	disableInlining := r.disableInlining
@@ -1695,6 +2216,22 @@ func (r *Rego) partial(ctx context.Context, ectx *EvalContext) (*PartialQueries,
 		WithRuntime(r.runtime).
 		WithIndexing(ectx.indexing)
 
+	if !ectx.time.IsZero() {
+		q = q.WithTime(ectx.time)
+	}
+
+	if ectx.seed != nil {
+		q = q.WithSeed(ectx.seed)
+	}
+
+	if ectx.interQueryCache != nil {
+		q = q.WithInterQueryBuiltinCache(ectx.interQueryCache)
+	}
+
+	if ectx.resultLimit > 0 {
+		q = q.WithResultLimit(ectx.resultLimit)
+	}
+
 	for i := range ectx.tracers {
 		q = q.WithTracer(r.tracers[i])
 	}
@@ -1816,7 +2353,7 @@ func (r *Rego) generateTermVar() *ast.Term {
 }
 
 func (r Rego) hasQuery() bool {
-	return len(r.query) != 0 || len(r.parsedQuery) != 0
+	return len(r.query) != 0 || len(r.parsedQuery) != 0 || r.selection != nil
 }
 
 type transactionCloser func(ctx context.Context, err error) error
@@ -1888,6 +2425,125 @@ func waitForDone(ctx context.Context, exit chan struct{}, f func()) {
 	}
 }
 
+type selection struct {
+	filename string
+	module   string
+	start    Location
+	end      Location
+}
+
+// parseSelection returns the query for the selected span of the module and
+// sets the package and imports on the query's context.
+func (r *Rego) parseSelection() (ast.Body, error) {
+
+	sel := r.selection
+
+	module, err := ast.ParseModule(sel.filename, sel.module)
+	if err != nil {
+		return nil, err
+	}
+
+	if !positionLess(sel.start, sel.end) {
+		return nil, fmt.Errorf("invalid selection: start %d:%d is not before end %d:%d", sel.start.Row, sel.start.Col, sel.end.Row, sel.end.Col)
+	}
+
+	r.parsedPackage = module.Package
+	r.parsedImports = module.Imports
+
+	// If the selection covers complete rules, query the documents they
+	// generate. Functions are skipped because they cannot be referred to
+	// without arguments.
+	var query ast.Body
+	seen := map[ast.Var]struct{}{}
+
+	for _, rule := range module.Rules {
+		if !spanContains(sel.start, sel.end, positionOf(rule.Location), endPositionOf(rule.Location)) || len(rule.Head.Args) > 0 {
+			continue
+		}
+		if _, ok := seen[rule.Head.Name]; ok {
+			continue
+		}
+		seen[rule.Head.Name] = struct{}{}
+		ref := module.Package.Path.Copy().Append(ast.StringTerm(string(rule.Head.Name)))
+		query.Append(ast.NewExpr(ast.NewTerm(ref).SetLocation(rule.Location)).SetLocation(rule.Location))
+	}
+
+	if len(query) > 0 {
+		return query, nil
+	}
+
+	text, err := selectionText(sel)
+	if err != nil {
+		return nil, err
+	}
+
+	selected, err := ast.ParseBody(text)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, rule := range module.Rules {
+		if !spanContains(positionOf(rule.Location), endPositionOf(rule.Location), sel.start, sel.end) {
+			continue
+		}
+		for _, expr := range rule.Body {
+			if positionLess(sel.start, endPositionOf(expr.Location)) {
+				break
+			}
+			query.Append(expr.Copy())
+		}
+		break
+	}
+
+	for _, expr := range selected {
+		query.Append(expr)
+	}
+
+	return query, nil
+}
+
+// spanContains returns true if the span [innerStart, innerEnd) is inside of
+// the span [start, end).
+func spanContains(start, end, innerStart, innerEnd Location) bool {
+	return !positionLess(innerStart, start) && !positionLess(end, innerEnd)
+}
+
+func positionOf(loc *ast.Location) Location {
+	return Location{Row: loc.Row, Col: loc.Col}
+}
+
+func endPositionOf(loc *ast.Location) Location {
+	row, col := loc.End()
+	return Location{Row: row, Col: col}
+}
+
+func positionLess(a, b Location) bool {
+	return a.Row < b.Row || (a.Row == b.Row && a.Col < b.Col)
+}
+
+// selectionText returns the module source between the start and end positions
+// of the selection.
+func selectionText(sel *selection) (string, error) {
+	var buf strings.Builder
+	row, col := 1, 1
+	for _, c := range sel.module {
+		pos := Location{Row: row, Col: col}
+		if !positionLess(pos, sel.start) && positionLess(pos, sel.end) {
+			buf.WriteRune(c)
+		}
+		if c == '\n' {
+			row++
+			col = 1
+		} else {
+			col++
+		}
+	}
+	if buf.Len() == 0 {
+		return "", fmt.Errorf("invalid selection: span %d:%d-%d:%d is outside of module", sel.start.Row, sel.start.Col, sel.end.Row, sel.end.Col)
+	}
+	return buf.String(), nil
+}
+
 type rawModule struct {
 	filename string
 	module   string
@@ -5,11 +5,15 @@
 package rego
 
 import (
+	"bytes"
 	"context"
 	"encoding/json"
+	"fmt"
 	"log"
 	"reflect"
+	"runtime/pprof"
 	"strings"
+	"sync"
 	"testing"
 	"time"
 
@@ -149,6 +153,11 @@ func TestRegoEvalExpressionValue(t *testing.T) {
 	}
 }
 
+// Inner is embedded in the inputs of TestRegoInputs.
+type Inner struct {
+	Bar string `json:"bar"`
+}
+
 func TestRegoInputs(t *testing.T) {
 	tests := map[string]struct {
 		input    interface{}
@@ -173,6 +182,12 @@ func TestRegoInputs(t *testing.T) {
 		"slice":              {[]string{"a", "b"}, `[[["a", "b"]]]`},
 		"nil":                {nil, `[[null]]`},
 		"slice of interface": {[]interface{}{"a", 2, true}, `[[["a", 2, true]]]`},
+		"map of slices":      {map[string][]int{"a": {1, 2}}, `[[{"a": [1, 2]}]]`},
+		"embedded struct": {struct {
+			Inner
+			Foo string `json:"foo,omitempty"`
+		}{Inner: Inner{Bar: "baz"}}, `[[{"bar": "baz"}]]`},
+		"json marshaler": {time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC), `[["2020-01-02T03:04:05Z"]]`},
 	}
 
 	for desc, tc := range tests {
@@ -186,6 +201,49 @@ func TestRegoInputs(t *testing.T) {
 	}
 }
 
+func TestResultSetDecode(t *testing.T) {
+
+	ctx := context.Background()
+
+	rs, err := New(
+		Query("x = data.example.users[_]; x.admin"),
+		Module("example.rego", `package example
+
+		users = [{"name": "alice", "admin": true, "age": 30}, {"name": "bob", "admin": false}]`),
+	).Eval(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	type user struct {
+		Name  string `json:"name"`
+		Admin bool   `json:"admin"`
+		Age   int    `json:"age"`
+	}
+
+	var u user
+	if err := rs[0].Bindings.Decode("x", &u); err != nil {
+		t.Fatal(err)
+	}
+
+	if exp := (user{Name: "alice", Admin: true, Age: 30}); u != exp {
+		t.Fatalf("Expected %+v but got %+v", exp, u)
+	}
+
+	if err := rs[0].Bindings.Decode("y", &u); err == nil {
+		t.Fatal("Expected error for unbound variable")
+	}
+
+	rs, err = New(Query("data.example.missing")).Eval(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if defined, err := rs.Decode(&u); defined || err != nil {
+		t.Fatalf("Expected undefined result but got: %v (err: %v)", defined, err)
+	}
+}
+
 func TestRegoRewrittenVarsCapture(t *testing.T) {
 
 	ctx := context.Background()
@@ -205,6 +263,176 @@ func TestRegoRewrittenVarsCapture(t *testing.T) {
 
 }
 
+func TestRegoFutureKeywords(t *testing.T) {
+
+	ctx := context.Background()
+
+	if _, err := New(Query("x := 1; x in [1]")).Eval(ctx); err == nil {
+		t.Fatal("Expected error")
+	}
+
+	rs, err := New(
+		Query("x := 1; x in [1]"),
+		Imports([]string{"future.keywords.in"}),
+	).Eval(ctx)
+	if err != nil || len(rs) != 1 {
+		t.Fatalf("Unexpected result: %v (err: %v)", rs, err)
+	}
+}
+
+func TestResultSetBindings(t *testing.T) {
+
+	ctx := context.Background()
+
+	r := New(
+		Query(`x = input.xs[_]; y = input.ys[_]`),
+		Input(map[string]interface{}{
+			"xs": []string{"b", "c", "a"},
+			"ys": []int{2, 1},
+		}),
+	)
+
+	rs, err := r.Eval(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rs.SortByBindings("y", "x")
+
+	result, err := json.Marshal(rs.Bindings())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := `[{"x":"a","y":1},{"x":"b","y":1},{"x":"c","y":1},{"x":"a","y":2},{"x":"b","y":2},{"x":"c","y":2}]`
+
+	if string(result) != expected {
+		t.Fatalf("Expected %v but got %v", expected, string(result))
+	}
+
+	rs.SortByBindings()
+
+	result, err = json.Marshal(rs.Bindings())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected = `[{"x":"a","y":1},{"x":"a","y":2},{"x":"b","y":1},{"x":"b","y":2},{"x":"c","y":1},{"x":"c","y":2}]`
+
+	if string(result) != expected {
+		t.Fatalf("Expected %v but got %v", expected, string(result))
+	}
+}
+
+func TestRegoMultiExpressionQuery(t *testing.T) {
+
+	ctx := context.Background()
+
+	rs, err := New(Query(`x := 1, y := [x, 2], y[_] > x`)).Eval(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	result, err := json.Marshal(rs.Bindings())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if exp := `[{"x":1,"y":[1,2]}]`; string(result) != exp {
+		t.Fatalf("Expected %v but got %v", exp, string(result))
+	}
+}
+
+func TestRegoSelection(t *testing.T) {
+
+	module := `package test
+
+import input.servers
+
+p[x] {
+	y := servers[_]
+	x := y.name
+	startswith(x, "a")
+}
+
+q = 1
+`
+
+	input := map[string]interface{}{
+		"servers": []interface{}{
+			map[string]interface{}{"name": "a1"},
+			map[string]interface{}{"name": "b1"},
+		},
+	}
+
+	tests := []struct {
+		note       string
+		start, end Location
+		bindings   string
+		exprs      string
+	}{
+		{
+			note:     "inside rule body",
+			start:    Location{Row: 7, Col: 2},
+			end:      Location{Row: 7, Col: 13},
+			bindings: `[{"x":"a1","y":{"name":"a1"}},{"x":"b1","y":{"name":"b1"}}]`,
+		},
+		{
+			note:     "single rule",
+			start:    Location{Row: 11, Col: 1},
+			end:      Location{Row: 11, Col: 6},
+			bindings: `[{}]`,
+			exprs:    `[1]`,
+		},
+		{
+			note:     "multiple rules",
+			start:    Location{Row: 5, Col: 1},
+			end:      Location{Row: 12, Col: 1},
+			bindings: `[{}]`,
+			exprs:    `[["a1"],1]`,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.note, func(t *testing.T) {
+			rs, err := New(
+				Selection("test.rego", module, tc.start, tc.end),
+				Input(input),
+			).Eval(context.Background())
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			rs.SortByBindings()
+
+			bs, err := json.Marshal(rs.Bindings())
+			if err != nil {
+				t.Fatal(err)
+			} else if string(bs) != tc.bindings {
+				t.Fatalf("Expected bindings %v but got %v", tc.bindings, string(bs))
+			}
+
+			if tc.exprs != "" {
+				var values []interface{}
+				for _, ev := range rs[0].Expressions {
+					values = append(values, ev.Value)
+				}
+				bs, err := json.Marshal(values)
+				if err != nil {
+					t.Fatal(err)
+				} else if string(bs) != tc.exprs {
+					t.Fatalf("Expected expression values %v but got %v", tc.exprs, string(bs))
+				}
+			}
+		})
+	}
+
+	_, err := New(Selection("test.rego", module, Location{Row: 7, Col: 2}, Location{Row: 7, Col: 2})).Eval(context.Background())
+	if err == nil || !strings.Contains(err.Error(), "invalid selection") {
+		t.Fatalf("Expected invalid selection error but got: %v", err)
+	}
+}
+
 func TestRegoCancellation(t *testing.T) {
 
 	ast.RegisterBuiltin(&ast.Builtin{
@@ -315,6 +543,96 @@ func TestPreparedRegoMetricsEvalOnly(t *testing.T) {
 	})
 }
 
+func TestRegoResultLimit(t *testing.T) {
+	ctx := context.Background()
+	r := New(Query("a = [1, 2, 3]; a[_] = x; a[_] = y"), ResultLimit(5))
+
+	_, err := r.Eval(ctx)
+	if !topdown.IsResultLimit(err) {
+		t.Fatalf("Expected result limit error but got: %v", err)
+	}
+
+	pq, err := r.PrepareForEval(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rs, err := pq.Eval(ctx, EvalResultLimit(9))
+	if err != nil {
+		t.Fatal(err)
+	} else if len(rs) != 9 {
+		t.Fatalf("Expected 9 results but got %d", len(rs))
+	}
+}
+
+func TestPreparedRegoEvalCallback(t *testing.T) {
+	ctx := context.Background()
+	pq, err := New(Query("a = [1, 2, 3]; a[_] = x")).PrepareForEval(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var xs []interface{}
+
+	rs, err := pq.Eval(ctx, EvalCallback(func(result Result) error {
+		xs = append(xs, result.Bindings["x"])
+		if len(xs) == 2 {
+			return ErrStopEval
+		}
+		return nil
+	}))
+
+	if err != nil {
+		t.Fatal(err)
+	} else if len(rs) != 0 {
+		t.Fatalf("Expected results to be passed to callback but got: %v", rs)
+	} else if !reflect.DeepEqual(xs, []interface{}{json.Number("1"), json.Number("2")}) {
+		t.Fatalf("Expected evaluation to stop after two results but got: %v", xs)
+	}
+
+	expErr := fmt.Errorf("callback failed")
+
+	_, err = pq.Eval(ctx, EvalCallback(func(Result) error {
+		return expErr
+	}))
+
+	if err != expErr {
+		t.Fatalf("Expected callback error but got: %v", err)
+	}
+}
+
+func TestRegoMetricsCounters(t *testing.T) {
+	m := metrics.New()
+	store := inmem.NewFromObject(map[string]interface{}{"xs": []interface{}{"a", "b"}})
+	r := New(
+		Query("data.x.p = y; data.x.p = z"),
+		Module("foo.rego", `package x
+
+		p = n { n = count(data.xs) }`),
+		Store(store),
+		Metrics(m),
+	)
+	_, err := r.Eval(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	all := m.All()
+
+	expected := map[string]uint64{
+		"counter_rego_eval_exprs":                5,
+		"counter_rego_eval_storage_reads":        1,
+		"counter_rego_eval_virtual_cache_hits":   1,
+		"counter_rego_eval_virtual_cache_misses": 1,
+	}
+
+	for k, v := range expected {
+		if all[k] != v {
+			t.Errorf("Expected %v to be %v but got %v", k, v, all[k])
+		}
+	}
+}
+
 func validateRegoMetrics(t *testing.T, m metrics.Metrics, expectedFields []string) {
 	t.Helper()
 
@@ -414,6 +732,98 @@ func TestRegoInstrumentExtraPartialCompilerStage(t *testing.T) {
 	}
 }
 
+func TestRegoProcessAllocMetrics(t *testing.T) {
+	module := `package x
+
+	p = {x | x := sprintf("%d", [data.x.n[_]])}
+
+	n = [1, 2, 3, 4, 5]`
+
+	names := []string{"counter_rego_eval_process_alloc_bytes", "counter_rego_eval_process_alloc_objects"}
+
+	m := metrics.New()
+	r := New(Query("data.x.p"), Module("foo.rego", module), Metrics(m), Instrument(true))
+
+	if _, err := r.Eval(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	all := m.All()
+
+	for _, name := range names {
+		if _, ok := all[name]; ok {
+			t.Errorf("Expected %v not to be recorded by instrumentation\n\nActual:\n %+v", name, all)
+		}
+	}
+
+	m = metrics.New()
+	r = New(Query("data.x.p"), Module("foo.rego", module), Metrics(m), ProcessAllocMetrics(true))
+
+	if _, err := r.Eval(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	all = m.All()
+
+	for _, name := range names {
+		if v, ok := all[name]; !ok || v.(uint64) == 0 {
+			t.Errorf("Expected non-zero %v in metrics\n\nActual:\n %+v", name, all)
+		}
+	}
+}
+
+func TestRegoProfileLabels(t *testing.T) {
+
+	var labels []string
+
+	label := Function1(&Function{
+		Name: "test.label",
+		Decl: types.NewFunction(types.Args(types.S), types.S),
+	}, func(bctx BuiltinContext, key *ast.Term) (*ast.Term, error) {
+		value, ok := pprof.Label(bctx.Context, string(key.Value.(ast.String)))
+		if ok {
+			labels = append(labels, value)
+		}
+		return ast.StringTerm(value), nil
+	})
+
+	module := `package x
+
+	p {
+		test.label("opa_policy_path")
+		test.label("opa_query_hash")
+	}`
+
+	ctx := context.Background()
+
+	for _, yes := range []bool{false, true} {
+		labels = nil
+		pq, err := New(Query("data.x.p"), Module("foo.rego", module), label, ProfileLabels(yes)).PrepareForEval(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if _, err := pq.Eval(ctx); err != nil {
+			t.Fatal(err)
+		}
+
+		if !yes {
+			if len(labels) != 0 {
+				t.Fatalf("Expected no labels but got: %v", labels)
+			}
+			continue
+		}
+
+		if len(labels) != 2 || labels[0] != "data.x.p" || labels[1] == "" {
+			t.Fatalf("Expected policy path and query hash labels but got: %v", labels)
+		}
+	}
+
+	if _, ok := pprof.Label(ctx, "opa_policy_path"); ok {
+		t.Fatal("Expected labels not to leak into caller's context")
+	}
+}
+
 func TestRegoInstrumentExtraPartialResultCompilerStage(t *testing.T) {
 	m := metrics.New()
 	r := New(Query("input.x"), Module("foo.rego", "package x"), Metrics(m), Instrument(true))
@@ -464,6 +874,77 @@ func TestPreparedRegoTracerNoPropagate(t *testing.T) {
 	}
 }
 
+func TestRegoTimeAndSeed(t *testing.T) {
+
+	ctx := context.Background()
+	now := time.Date(2019, 11, 1, 0, 0, 0, 0, time.UTC)
+
+	eval := func(opts ...EvalOption) interface{} {
+		pq, err := New(
+			Query(`x := [time.now_ns(), rand.intn("a", 1000000), uuid.rfc4122("b")]`),
+			Time(now),
+		).PrepareForEval(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		rs, err := pq.Eval(ctx, opts...)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return rs[0].Bindings["x"]
+	}
+
+	a := eval(EvalSeed(strings.NewReader(strings.Repeat("a", 64))))
+	b := eval(EvalSeed(strings.NewReader(strings.Repeat("a", 64))))
+
+	if !reflect.DeepEqual(a, b) {
+		t.Fatalf("Expected identical results but got %v and %v", a, b)
+	}
+
+	if ns := a.([]interface{})[0].(json.Number); ns.String() != "1572566400000000000" {
+		t.Fatalf("Expected fixed time but got %v", ns)
+	}
+
+	later := now.Add(time.Hour)
+	c := eval(EvalTime(later), EvalSeed(strings.NewReader(strings.Repeat("b", 64))))
+
+	if reflect.DeepEqual(a, c) {
+		t.Fatalf("Expected different results but got %v", c)
+	}
+}
+
+func TestRegoSeedConcurrentEval(t *testing.T) {
+
+	ctx := context.Background()
+
+	// bytes.Reader is not safe for concurrent use. Run with -race to detect
+	// unsynchronized reads.
+	seed := bytes.NewReader(bytes.Repeat([]byte("a"), 4096))
+
+	pq, err := New(
+		Query(`x := rand.intn("a", 100)`),
+		Seed(seed),
+	).PrepareForEval(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for n := 0; n < 10; n++ {
+				if _, err := pq.Eval(ctx); err != nil {
+					t.Error(err)
+					return
+				}
+			}
+		}()
+	}
+	wg.Wait()
+}
+
 func TestRegoDisableIndexing(t *testing.T) {
 	tracer := topdown.NewBufferTracer()
 	mod := `
@@ -595,6 +1076,62 @@ func TestPrepareAndEvalNewInput(t *testing.T) {
 	}, "[[1]]")
 }
 
+func TestPrepareAndEvalBatch(t *testing.T) {
+	module := `
+	package test
+	x { data.roles[input.user][_] == input.action }
+	`
+
+	store := inmem.NewFromObject(map[string]interface{}{
+		"roles": map[string]interface{}{
+			"alice": []interface{}{"read", "write"},
+			"bob":   []interface{}{"read"},
+		},
+	})
+
+	pq, err := New(
+		Query("data.test.x"),
+		Module("", module),
+		Store(store),
+	).PrepareForEval(context.Background())
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err.Error())
+	}
+
+	inputs := []interface{}{
+		map[string]interface{}{"user": "alice", "action": "write"},
+		map[string]interface{}{"user": "bob", "action": "write"},
+		nil,
+		map[string]interface{}{"user": "bob", "action": "read"},
+	}
+
+	m := metrics.New()
+
+	results, err := pq.EvalBatch(context.Background(), inputs, EvalMetrics(m))
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err.Error())
+	}
+
+	if len(results) != len(inputs) {
+		t.Fatalf("Expected %d result sets but got: %v", len(inputs), results)
+	}
+
+	for i, expected := range []string{`[[true]]`, `[]`, `[]`, `[[true]]`} {
+		assertResultSet(t, results[i], expected)
+	}
+
+	// The roles read from the store are shared across the inputs so the roles
+	// of bob are only read once.
+	if reads := m.Counter(metrics.RegoEvalStorageReads).Value(); reads != uint64(2) {
+		t.Fatalf("Expected two storage reads but got: %v", reads)
+	}
+
+	_, err = pq.EvalBatch(context.Background(), inputs, EvalCallback(func(Result) error { return nil }))
+	if err == nil {
+		t.Fatal("Expected error for result callback")
+	}
+}
+
 func TestPrepareAndEvalNewMetrics(t *testing.T) {
 	module := `
 	package test
@@ -766,6 +1303,88 @@ func TestPrepareAndEvalIdempotent(t *testing.T) {
 	}
 }
 
+func TestPrepareAndEvalConcurrent(t *testing.T) {
+	module := `
+	package test
+	s = {3, 1, 2}
+	x = {"y": input.y, "ok": ok}
+	ok { s == {1, 2, 3}; {"b": 1, "a": 2} == {"a": 2, "b": 1} }
+	`
+
+	ctx := context.Background()
+	store := inmem.NewFromObject(map[string]interface{}{"z": 0})
+
+	pq, err := New(
+		Query("data.test.x"),
+		Module("", module),
+		Store(store),
+	).PrepareForEval(ctx)
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err.Error())
+	}
+
+	// Evaluate the prepared query from multiple goroutines while the store is
+	// being written to. Run with -race to detect unsafe sharing.
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			for n := 0; n < 50; n++ {
+				if i == 0 {
+					if err := storage.WriteOne(ctx, store, storage.ReplaceOp, storage.MustParsePath("/z"), n); err != nil {
+						t.Error(err)
+					}
+					continue
+				}
+				rs, err := pq.Eval(ctx, EvalInput(map[string]int{"y": i}))
+				if err != nil {
+					t.Error(err)
+					return
+				}
+				exp := map[string]interface{}{"y": json.Number(fmt.Sprint(i)), "ok": true}
+				if len(rs) != 1 || !reflect.DeepEqual(rs[0].Expressions[0].Value, exp) {
+					t.Errorf("Unexpected result: %v", rs)
+					return
+				}
+			}
+		}(i)
+	}
+	wg.Wait()
+}
+
+func BenchmarkPreparedEvalParallel(b *testing.B) {
+	module := `
+	package test
+	allow { input.roles[_] = data.roles[_] }
+	`
+
+	ctx := context.Background()
+	store := inmem.NewFromObject(map[string]interface{}{"roles": []interface{}{"admin", "dev"}})
+
+	pq, err := New(
+		Query("data.test.allow"),
+		Module("", module),
+		Store(store),
+	).PrepareForEval(ctx)
+	if err != nil {
+		b.Fatal(err)
+	}
+
+	input := map[string]interface{}{"roles": []interface{}{"guest", "dev"}}
+
+	b.ResetTimer()
+
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			rs, err := pq.Eval(ctx, EvalInput(input))
+			if err != nil || len(rs) != 1 {
+				b.Fatalf("Unexpected result: %v (err: %v)", rs, err)
+			}
+		}
+	})
+}
+
 func TestPrepareAndEvalOriginal(t *testing.T) {
 	module := `
 	package test
@@ -1308,3 +1927,105 @@ func TestRegoLoadBundleWithProvidedStore(t *testing.T) {
 		}
 	})
 }
+
+func TestRegoStrict(t *testing.T) {
+
+	ctx := context.Background()
+	module := `package test
+
+	x = "a"
+	xs = {"a": 1, "b": 2}
+
+	p[y] { y := xs[x] }`
+
+	rs, err := New(Query("data.test.p"), Module("test.rego", module)).Eval(ctx)
+	if err != nil {
+		t.Fatal(err)
+	} else if len(rs) != 1 || !reflect.DeepEqual(rs[0].Expressions[0].Value, []interface{}{json.Number("1")}) {
+		t.Fatalf("Unexpected result: %v", rs)
+	}
+
+	_, err = New(Query("data.test.p"), Module("test.rego", module), Strict(true)).Eval(ctx)
+	if err == nil || !strings.Contains(err.Error(), "var x refers to data.test.x") {
+		t.Fatalf("Expected strict mode error but got: %v", err)
+	}
+}
+
+func TestViolations(t *testing.T) {
+
+	ctx := context.Background()
+	module := `package test
+
+# Images must come from the internal registry.
+# See the image policy for details.
+deny[msg] {
+	img := input.images[_]
+	not startswith(img, "internal/")
+	msg := sprintf("untrusted image %v", [img])
+}
+
+deny["missing owner"] { not input.owner }
+
+violation[{"msg": "too many replicas", "replicas": n}] {
+	n := input.replicas
+	n > 3
+}
+
+warn["ignored"]`
+
+	input := map[string]interface{}{
+		"images":   []interface{}{"nginx", "internal/app"},
+		"replicas": 5,
+	}
+
+	result, err := Violations(ctx, ast.MustParseRef("data.test"), nil, Module("test.rego", module), Input(input))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []Violation{
+		{
+			Rule:        "data.test.deny",
+			Message:     "untrusted image nginx",
+			Value:       "untrusted image nginx",
+			Description: "Images must come from the internal registry.\nSee the image policy for details.",
+			Location:    &ast.Location{File: "test.rego", Row: 5, Col: 1},
+		},
+		{
+			Rule:     "data.test.deny",
+			Message:  "missing owner",
+			Value:    "missing owner",
+			Location: &ast.Location{File: "test.rego", Row: 11, Col: 1},
+		},
+		{
+			Rule:    "data.test.violation",
+			Message: "too many replicas",
+			Value: map[string]interface{}{
+				"msg":      "too many replicas",
+				"replicas": json.Number("5"),
+			},
+			Location: &ast.Location{File: "test.rego", Row: 13, Col: 1},
+		},
+	}
+
+	if len(result) != len(expected) {
+		t.Fatalf("Expected %d violations but got: %+v", len(expected), result)
+	}
+
+	for i := range expected {
+		if result[i].Location == nil || result[i].Location.Row != expected[i].Location.Row || result[i].Location.File != expected[i].Location.File {
+			t.Fatalf("Expected location %v for violation %d but got: %v", expected[i].Location, i, result[i].Location)
+		}
+		result[i].Location, expected[i].Location = nil, nil
+		if !reflect.DeepEqual(result[i], expected[i]) {
+			t.Fatalf("Expected violation %d to be %+v but got: %+v", i, expected[i], result[i])
+		}
+	}
+
+	result, err = Violations(ctx, ast.MustParseRef("data.test"), []string{"warn"}, Module("test.rego", module))
+	if err != nil {
+		t.Fatal(err)
+	} else if len(result) != 1 || result[0].Message != "ignored" || result[0].Rule != "data.test.warn" {
+		t.Fatalf("Unexpected violations: %+v", result)
+	}
+}
@@ -0,0 +1,211 @@
+// Copyright 2020 The OPA Authors.  All rights reserved.
+// Use of this source code is governed by an Apache2
+// license that can be found in the LICENSE file.
+
+package rego
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/open-policy-agent/opa/ast"
+	"github.com/open-policy-agent/opa/topdown"
+	"github.com/open-policy-agent/opa/util"
+)
+
+// DefaultViolationRules contains the names of the rules collected by
+// Violations if no names are given.
+var DefaultViolationRules = []string{"deny", "violation"}
+
+// Violation represents an element of a partial set rule that reports a policy
+// violation, e.g., deny[msg] or violation[{"msg": msg, ...}].
+type Violation struct {
+
+	// Rule is the path of the document that contains the violation, e.g.,
+	// data.kubernetes.admission.deny.
+	Rule string `json:"rule"`
+
+	// Message is the element itself if it is a string or the "msg" key of the
+	// element if it is an object.
+	Message string `json:"msg,omitempty"`
+
+	// Value is the element of the partial set.
+	Value interface{} `json:"value"`
+
+	// Description contains the comments immediately preceding the rule
+	// definition that produced the violation.
+	Description string `json:"description,omitempty"`
+
+	// Location is the location of the rule definition that produced the
+	// violation.
+	Location *ast.Location `json:"location,omitempty"`
+}
+
+// Violations evaluates the partial set rules with the given names in the
+// package identified by pkg (e.g., data.kubernetes.admission) and returns
+// their elements as violations. If no names are given, DefaultViolationRules
+// is used. The options are the same as the options accepted by New; the query
+// is set by Violations.
+func Violations(ctx context.Context, pkg ast.Ref, names []string, options ...func(r *Rego)) ([]Violation, error) {
+
+	if len(names) == 0 {
+		names = DefaultViolationRules
+	}
+
+	tracer := &violationTracer{
+		rules: util.NewHashMap(func(a, b util.T) bool {
+			return a.(ast.Value).Compare(b.(ast.Value)) == 0
+		}, func(x util.T) int {
+			return x.(ast.Value).Hash()
+		}),
+	}
+
+	query := make(ast.Body, len(names))
+
+	for i, name := range names {
+		ref := pkg.Append(ast.StringTerm(name))
+		tracer.refs = append(tracer.refs, ref)
+		elem := ast.VarTerm(fmt.Sprintf("x%d", i))
+		query[i] = ast.Equality.Expr(
+			ast.VarTerm(fmt.Sprintf("violations%d", i)),
+			ast.ArrayComprehensionTerm(elem, ast.NewBody(ast.NewExpr(ast.NewTerm(ref.Append(elem))))),
+		)
+		query[i].Index = i
+	}
+
+	options = append(options, ParsedQuery(query), Tracer(tracer))
+
+	rs, err := New(options...).Eval(ctx)
+	if err != nil {
+		return nil, err
+	} else if len(rs) != 1 {
+		return nil, fmt.Errorf("violations: unexpected number of results: %d", len(rs))
+	}
+
+	var result []Violation
+
+	for i, ref := range tracer.refs {
+		elems, _ := rs[0].Bindings[fmt.Sprintf("violations%d", i)].([]interface{})
+		for _, elem := range elems {
+			v, err := newViolation(ref, elem, tracer)
+			if err != nil {
+				return nil, err
+			}
+			result = append(result, v)
+		}
+	}
+
+	return result, nil
+}
+
+func newViolation(ref ast.Ref, elem interface{}, tracer *violationTracer) (Violation, error) {
+
+	v := Violation{
+		Rule:  ref.String(),
+		Value: elem,
+	}
+
+	switch x := elem.(type) {
+	case string:
+		v.Message = x
+	case map[string]interface{}:
+		v.Message, _ = x["msg"].(string)
+	}
+
+	value, err := ast.InterfaceToValue(elem)
+	if err != nil {
+		return v, err
+	}
+
+	if rule, ok := tracer.rules.Get(value); ok {
+		rule := rule.(*ast.Rule)
+		v.Location = rule.Location
+		v.Description = ruleDescription(rule)
+	}
+
+	return v, nil
+}
+
+// ruleDescription returns the text of the comments immediately preceding the
+// rule definition.
+func ruleDescription(rule *ast.Rule) string {
+
+	if rule.Location == nil || rule.Module == nil {
+		return ""
+	}
+
+	byRow := map[int]string{}
+
+	for _, c := range rule.Module.Comments {
+		if c.Location != nil && c.Location.File == rule.Location.File {
+			byRow[c.Location.Row] = strings.TrimSpace(string(c.Text))
+		}
+	}
+
+	var lines []string
+
+	for row := rule.Location.Row - 1; ; row-- {
+		text, ok := byRow[row]
+		if !ok {
+			break
+		}
+		lines = append([]string{text}, lines...)
+	}
+
+	return strings.Join(lines, "\n")
+}
+
+// violationTracer records the rule definitions that produced the elements of
+// the violation documents.
+type violationTracer struct {
+	refs  []ast.Ref
+	rules *util.HashMap
+}
+
+func (t *violationTracer) Enabled() bool {
+	return true
+}
+
+func (t *violationTracer) Trace(evt *topdown.Event) {
+
+	if evt.Op != topdown.ExitOp {
+		return
+	}
+
+	rule, ok := evt.Node.(*ast.Rule)
+	if !ok || rule.Module == nil || rule.Head.Key == nil {
+		return
+	}
+
+	path := rule.Path()
+	found := false
+
+	for _, ref := range t.refs {
+		if ref.Equal(path) {
+			found = true
+			break
+		}
+	}
+
+	if !found {
+		return
+	}
+
+	key, err := ast.TransformVars(rule.Head.Key.Value, func(v ast.Var) (ast.Value, error) {
+		if x := evt.Locals.Get(v); x != nil {
+			return x, nil
+		}
+		return v, nil
+	})
+
+	if err != nil {
+		return
+	}
+
+	if k, ok := key.(ast.Value); ok && ast.IsConstant(k) {
+		if _, ok := t.rules.Get(k); !ok {
+			t.rules.Put(k, rule)
+		}
+	}
+}
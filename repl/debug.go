@@ -0,0 +1,233 @@
+// Copyright 2020 The OPA Authors.  All rights reserved.
+// Use of this source code is governed by an Apache2
+// license that can be found in the LICENSE file.
+
+package repl
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/open-policy-agent/opa/ast"
+	pr "github.com/open-policy-agent/opa/internal/presentation"
+	"github.com/open-policy-agent/opa/rego"
+	"github.com/open-policy-agent/opa/topdown"
+	"github.com/open-policy-agent/opa/topdown/debug"
+)
+
+// debugger contains the commands that control the active debug session. The
+// commands are only recognized while a session is active so that they do not
+// shadow rules with the same names otherwise.
+var debugger = [...]commandDesc{
+	{"step", []string{}, "debugger: step to the next event"},
+	{"next", []string{}, "debugger: step over rule and function calls"},
+	{"out", []string{}, "debugger: step out of the current rule or function"},
+	{"continue", []string{}, "debugger: continue to the next breakpoint"},
+	{"locals", []string{}, "debugger: show the current expression and bindings"},
+	{"abort", []string{}, "debugger: stop the debug session"},
+}
+
+func newDebuggerCommand(line string) *command {
+	p := strings.Fields(strings.TrimSpace(strings.ToLower(line)))
+	if len(p) != 1 {
+		return nil
+	}
+	for _, c := range debugger {
+		if c.name == p[0] {
+			return &command{op: c.name}
+		}
+	}
+	return nil
+}
+
+// commandText returns the text following the command name in line. Unlike
+// the command's args, the case of the text is preserved.
+func commandText(line string, op string) string {
+	return strings.TrimSpace(strings.TrimSpace(line)[len(op):])
+}
+
+func (r *REPL) cmdDebug(ctx context.Context, text string) error {
+
+	if text == "" {
+		return fmt.Errorf("usage: debug <stmt>")
+	}
+
+	body, err := ast.ParseBodyWithOpts(text, r.parserOptions())
+	if err != nil {
+		return err
+	}
+
+	compiler, err := r.loadCompiler(ctx)
+	if err != nil {
+		return err
+	}
+
+	input, err := r.loadInput(ctx, compiler)
+	if err != nil {
+		return err
+	}
+
+	r.abortDebug()
+
+	module := r.getCurrentOrDefaultModule()
+
+	session := debug.NewSession(ctx, func(ctx context.Context, tracer topdown.Tracer) error {
+
+		// The session outlives the REPL's per-line transaction.
+		txn, err := r.store.NewTransaction(ctx)
+		if err != nil {
+			return err
+		}
+
+		defer r.store.Abort(ctx, txn)
+
+		r.debugResult, err = rego.New(
+			rego.Compiler(compiler),
+			rego.Store(r.store),
+			rego.Transaction(txn),
+			rego.ParsedImports(module.Imports),
+			rego.ParsedPackage(module.Package),
+			rego.ParsedQuery(body),
+			rego.ParsedInput(input),
+			rego.Runtime(r.runtime),
+			rego.Tracer(tracer),
+		).Eval(ctx)
+
+		return err
+	})
+
+	session.SetBreakpoints(r.breakpoints)
+	r.debugSession = session
+
+	if len(r.breakpoints) > 0 {
+		return r.debugStop(session.Continue())
+	}
+
+	return r.debugStop(session.Step())
+}
+
+func (r *REPL) cmdBreak(text string) error {
+
+	if text == "" {
+		if len(r.breakpoints) == 0 {
+			fmt.Fprintln(r.output, "no breakpoints set")
+		}
+		for i, bp := range r.breakpoints {
+			fmt.Fprintf(r.output, "%d: %v\n", i+1, bp)
+		}
+		return nil
+	}
+
+	bp, err := debug.ParseBreakpoint(text)
+	if err != nil {
+		return err
+	}
+
+	r.breakpoints = append(r.breakpoints, bp)
+
+	if r.debugSession != nil {
+		r.debugSession.SetBreakpoints(r.breakpoints)
+	}
+
+	fmt.Fprintf(r.output, "breakpoint %d: %v\n", len(r.breakpoints), bp)
+	return nil
+}
+
+func (r *REPL) cmdClear(args []string) error {
+
+	if len(args) == 0 {
+		r.breakpoints = nil
+	} else {
+		n, err := strconv.Atoi(args[0])
+		if err != nil || n < 1 || n > len(r.breakpoints) {
+			return fmt.Errorf("unknown breakpoint '%v'", args[0])
+		}
+		r.breakpoints = append(r.breakpoints[:n-1:n-1], r.breakpoints[n:]...)
+	}
+
+	if r.debugSession != nil {
+		r.debugSession.SetBreakpoints(r.breakpoints)
+	}
+
+	return nil
+}
+
+func (r *REPL) cmdDebugger(op string) error {
+
+	session := r.debugSession
+
+	switch op {
+	case "step":
+		return r.debugStop(session.Step())
+	case "next":
+		return r.debugStop(session.Next())
+	case "out":
+		return r.debugStop(session.Out())
+	case "continue":
+		return r.debugStop(session.Continue())
+	case "locals":
+		return r.printLocals(session.Current())
+	case "abort":
+		r.abortDebug()
+	}
+
+	return nil
+}
+
+// debugStop prints the event that the session stopped on. If evaluation
+// finished, the result is printed and the session is discarded.
+func (r *REPL) debugStop(stop *debug.Stop, err error) error {
+
+	if stop != nil {
+		if stop.Breakpoint != nil {
+			fmt.Fprintf(r.output, "breakpoint %v\n", stop.Breakpoint)
+		}
+		fmt.Fprintln(r.output, stop)
+		return nil
+	}
+
+	output := pr.Output{
+		Errors: pr.NewOutputErrors(err),
+		Result: r.debugResult,
+	}
+
+	r.debugSession = nil
+	r.debugResult = nil
+
+	output = output.WithLimit(r.prettyLimit)
+
+	switch r.outputFormat {
+	case "json":
+		return pr.JSON(r.output, output)
+	default:
+		return pr.Pretty(r.output, output)
+	}
+}
+
+func (r *REPL) printLocals(stop *debug.Stop) error {
+
+	if stop == nil {
+		return fmt.Errorf("evaluation has not stopped")
+	}
+
+	if expr := stop.Resolved(); expr != nil {
+		fmt.Fprintln(r.output, "resolving:", expr)
+	}
+
+	for _, b := range stop.Bindings() {
+		fmt.Fprintf(r.output, "%v = %v\n", b.Name, b.Value)
+	}
+
+	return nil
+}
+
+func (r *REPL) abortDebug() {
+	if r.debugSession != nil {
+		r.debugSession.Abort()
+		r.debugSession = nil
+		r.debugResult = nil
+		fmt.Fprintln(r.output, "debug session aborted")
+	}
+}
@@ -0,0 +1,88 @@
+// Copyright 2020 The OPA Authors.  All rights reserved.
+// Use of this source code is governed by an Apache2
+// license that can be found in the LICENSE file.
+
+package repl
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	"github.com/open-policy-agent/opa/storage/inmem"
+)
+
+func TestDebug(t *testing.T) {
+	ctx := context.Background()
+	store := inmem.New()
+	var buffer bytes.Buffer
+	repl := newRepl(store, &buffer)
+
+	repl.OneShot(ctx, `users = ["alice", "bob"]`)
+	repl.OneShot(ctx, `isAdmin[x] { x = users[_]; x != "bob" }`)
+	buffer.Reset()
+
+	steps := []struct {
+		line string
+		exp  string
+	}{
+		{"break data.repl.isAdmin", "breakpoint 1: data.repl.isAdmin\n"},
+		{"break", "1: data.repl.isAdmin\n"},
+		{"debug isAdmin[y]", "breakpoint data.repl.isAdmin\n:1: Enter data.repl.isAdmin\n"},
+		{"step", ":1: Eval x = data.repl.users[_]\n"},
+		{"next", ":1: Eval neq(x, \"bob\")\n"},
+		{"locals", "resolving: neq(\"alice\", \"bob\")\nx = \"alice\"\n"},
+		{"clear", ""},
+		{"continue", "+---------+------------+\n|    y    | isAdmin[y] |\n+---------+------------+\n| \"alice\" | \"alice\"    |\n+---------+------------+\n"},
+	}
+
+	for _, step := range steps {
+		buffer.Reset()
+		if err := repl.OneShot(ctx, step.line); err != nil {
+			t.Fatalf("%v: unexpected error: %v", step.line, err)
+		}
+		if buffer.String() != step.exp {
+			t.Fatalf("%v: expected output:\n%q\n\nGot:\n%q", step.line, step.exp, buffer.String())
+		}
+	}
+
+	if repl.debugSession != nil {
+		t.Fatal("Expected debug session to be finished")
+	}
+
+	// Debugger commands are only recognized during debug sessions.
+	if err := repl.OneShot(ctx, "step"); err == nil {
+		t.Fatal("Expected step to be evaluated as a statement")
+	}
+}
+
+func TestDebugAbort(t *testing.T) {
+	ctx := context.Background()
+	store := inmem.New()
+	var buffer bytes.Buffer
+	repl := newRepl(store, &buffer)
+
+	if err := repl.OneShot(ctx, "debug x = 1"); err != nil {
+		t.Fatal(err)
+	}
+
+	if repl.getPrompt() != "(debug) > " {
+		t.Fatalf("Expected debug prompt but got: %q", repl.getPrompt())
+	}
+
+	buffer.Reset()
+
+	if err := repl.OneShot(ctx, "abort"); err != nil {
+		t.Fatal(err)
+	} else if buffer.String() != "debug session aborted\n" || repl.debugSession != nil {
+		t.Fatalf("Expected session to be aborted but got: %q", buffer.String())
+	}
+
+	if err := repl.OneShot(ctx, "break input.x"); err == nil {
+		t.Fatal("Expected error for invalid breakpoint")
+	}
+
+	if err := repl.OneShot(ctx, "clear 1"); err == nil {
+		t.Fatal("Expected error for unknown breakpoint")
+	}
+}
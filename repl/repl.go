@@ -26,6 +26,7 @@ import (
 	"github.com/open-policy-agent/opa/rego"
 	"github.com/open-policy-agent/opa/storage"
 	"github.com/open-policy-agent/opa/topdown"
+	"github.com/open-policy-agent/opa/topdown/debug"
 	"github.com/open-policy-agent/opa/topdown/lineage"
 )
 
@@ -57,6 +58,9 @@ type REPL struct {
 	undefinedDisabled bool
 	errLimit          int
 	prettyLimit       int
+	breakpoints       []debug.Breakpoint
+	debugSession      *debug.Session
+	debugResult       rego.ResultSet
 }
 
 type explainMode string
@@ -105,6 +109,12 @@ func (r *REPL) getCurrentOrDefaultModule() *ast.Module {
 	return r.modules[r.currentModuleID]
 }
 
+// parserOptions returns the options for parsing statements. Statements can
+// use the future keywords imported by the current module.
+func (r *REPL) parserOptions() ast.ParserOptions {
+	return ast.ParserOptionsFromImports(r.getCurrentOrDefaultModule().Imports)
+}
+
 func (r *REPL) initModule(ctx context.Context) error {
 	if r.currentModuleID != "" {
 		return nil
@@ -212,8 +222,19 @@ func (r *REPL) OneShot(ctx context.Context, line string) error {
 	}
 
 	if len(r.buffer) == 0 {
+		if r.debugSession != nil {
+			if cmd := newDebuggerCommand(line); cmd != nil {
+				return r.cmdDebugger(cmd.op)
+			}
+		}
 		if cmd := newCommand(line); cmd != nil {
 			switch cmd.op {
+			case "debug":
+				return r.cmdDebug(ctx, commandText(line, cmd.op))
+			case "break":
+				return r.cmdBreak(commandText(line, cmd.op))
+			case "clear":
+				return r.cmdClear(cmd.args)
 			case "dump":
 				return r.cmdDump(ctx, cmd.args)
 			case "json":
@@ -623,15 +644,25 @@ func (r *REPL) compileBody(ctx context.Context, compiler *ast.Compiler, body ast
 func (r *REPL) compileRule(ctx context.Context, rule *ast.Rule) error {
 
 	var unset bool
+	var orig *ast.Module
 
 	if rule.Head.Assign {
 		var err error
+		orig = r.modules[r.currentModuleID]
 		unset, err = r.unsetRule(ctx, rule.Head.Name)
 		if err != nil {
 			return err
 		}
 	}
 
+	// If the rule replaced existing rules and cannot be defined, the
+	// existing rules are restored.
+	restore := func() {
+		if unset {
+			r.modules[r.currentModuleID] = orig
+		}
+	}
+
 	r.timerStart(metrics.RegoModuleCompile)
 	defer r.timerStop(metrics.RegoModuleCompile)
 
@@ -649,6 +680,8 @@ func (r *REPL) compileRule(ctx context.Context, rule *ast.Rule) error {
 
 	policies, err := r.loadModules(ctx, r.txn)
 	if err != nil {
+		mod.Rules = prev
+		restore()
 		return err
 	}
 
@@ -664,9 +697,16 @@ func (r *REPL) compileRule(ctx context.Context, rule *ast.Rule) error {
 
 	if compiler.Compile(policies); compiler.Failed() {
 		mod.Rules = prev
+		restore()
 		return compiler.Errors
 	}
 
+	if err := r.checkPathConflict(ctx, rule); err != nil {
+		mod.Rules = prev
+		restore()
+		return err
+	}
+
 	switch r.outputFormat {
 	case "json":
 	default:
@@ -682,6 +722,32 @@ func (r *REPL) compileRule(ctx context.Context, rule *ast.Rule) error {
 	return nil
 }
 
+// checkPathConflict returns an error if a base document exists at the path of
+// the rule, below it, or above it (if the base document is not an object). The
+// rule and the base document would conflict so the rule cannot be defined.
+func (r *REPL) checkPathConflict(ctx context.Context, rule *ast.Rule) error {
+
+	ref := rule.Path()
+	path := make([]string, 0, len(ref)-1)
+
+	for _, term := range ref[1:] {
+		s, ok := term.Value.(ast.String)
+		if !ok {
+			return nil
+		}
+		path = append(path, string(s))
+	}
+
+	exists, err := storage.NonEmpty(ctx, r.store, r.txn)(path)
+	if err != nil {
+		return err
+	} else if exists {
+		return ast.Errors{ast.NewError(ast.CompileErr, rule.Loc(), "conflicting rule for data path %v found", strings.Join(path, "/"))}
+	}
+
+	return nil
+}
+
 func (r *REPL) evalBufferOne(ctx context.Context) error {
 
 	line := strings.Join(r.buffer, "\n")
@@ -695,7 +761,7 @@ func (r *REPL) evalBufferOne(ctx context.Context) error {
 	// multiple lines with comments interspersed. In these cases
 	// the parser will return multiple statements.
 	r.timerStart(metrics.RegoQueryParse)
-	stmts, _, err := ast.ParseStatements("", line)
+	stmts, _, err := ast.ParseStatementsWithOpts("", line, r.parserOptions())
 	r.timerStop(metrics.RegoQueryParse)
 
 	if err != nil {
@@ -726,7 +792,7 @@ func (r *REPL) evalBufferMulti(ctx context.Context) error {
 	}
 
 	r.timerStart(metrics.RegoQueryParse)
-	stmts, _, err := ast.ParseStatements("", line)
+	stmts, _, err := ast.ParseStatementsWithOpts("", line, r.parserOptions())
 	r.timerStop(metrics.RegoQueryParse)
 
 	if err != nil {
@@ -1035,6 +1101,9 @@ func (r *REPL) getPrompt() string {
 	if len(r.buffer) > 0 {
 		return r.bufferPrompt
 	}
+	if r.debugSession != nil {
+		return "(debug) " + r.initPrompt
+	}
 	return r.initPrompt
 }
 
@@ -1142,6 +1211,9 @@ var builtin = [...]commandDesc{
 	{"types", []string{}, "toggle type information"},
 	{"unknown", []string{"[ref-1 [ref-2 [...]]]"}, "toggle partial evaluation mode"},
 	{"dump", []string{"[path]"}, "dump raw data in storage"},
+	{"debug", []string{"<stmt>"}, "evaluate the statement in the debugger"},
+	{"break", []string{"[<file:row>|<ref>]"}, "set (or list) debugger breakpoints"},
+	{"clear", []string{"[n]"}, "clear debugger breakpoint n (or all breakpoints)"},
 	{"help", []string{"[topic]"}, "print this message"},
 	{"exit", []string{}, "exit out of shell (or ctrl+d)"},
 	{"ctrl+l", []string{}, "clear the screen"},
@@ -1250,6 +1322,7 @@ func printHelpCommands(output io.Writer) {
 
 	all := extra[:]
 	all = append(all, builtin[:]...)
+	all = append(all, debugger[:]...)
 
 	// Compute max length of all command and topic names.
 	names := []string{}
@@ -1355,6 +1355,81 @@ func TestEvalRuleCompileError(t *testing.T) {
 	}
 }
 
+func TestEvalRuleBaseDocumentConflict(t *testing.T) {
+	ctx := context.Background()
+	store := inmem.NewFromObject(map[string]interface{}{
+		"x": map[string]interface{}{"y": map[string]interface{}{"z": 1}},
+	})
+	var buffer bytes.Buffer
+	repl := newRepl(store, &buffer)
+
+	if err := repl.OneShot(ctx, "package x"); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, rule := range []string{`y = 1`, `y := 1`} {
+		err := repl.OneShot(ctx, rule)
+		if err == nil || !strings.Contains(err.Error(), "conflicting rule for data path x/y found") {
+			t.Fatalf("Expected conflict error for %q but got: %v", rule, err)
+		}
+	}
+
+	buffer.Reset()
+
+	if err := repl.OneShot(ctx, `q = 1`); err != nil {
+		t.Fatal(err)
+	}
+
+	buffer.Reset()
+
+	if err := repl.OneShot(ctx, `data.x`); err != nil {
+		t.Fatal(err)
+	}
+
+	assertREPLText(t, buffer, `{
+  "q": 1,
+  "y": {
+    "z": 1
+  }
+}
+`)
+}
+
+func TestEvalRuleAssignErrorRestoresRule(t *testing.T) {
+	ctx := context.Background()
+	store := inmem.New()
+	var buffer bytes.Buffer
+	repl := newRepl(store, &buffer)
+
+	if err := repl.OneShot(ctx, "package x"); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := repl.OneShot(ctx, `y := 1`); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := repl.OneShot(ctx, `y := z`); err == nil || !strings.Contains(err.Error(), "var z is unsafe") {
+		t.Fatalf("Expected unsafe var error but got: %v", err)
+	}
+
+	if err := storage.WriteOne(ctx, store, storage.AddOp, storage.MustParsePath("/x"), map[string]interface{}{"y": 2}); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := repl.OneShot(ctx, `y := 3`); err == nil || !strings.Contains(err.Error(), "conflicting rule for data path x/y found") {
+		t.Fatalf("Expected conflict error but got: %v", err)
+	}
+
+	buffer.Reset()
+
+	if err := repl.OneShot(ctx, `show`); err != nil {
+		t.Fatal(err)
+	}
+
+	assertREPLText(t, buffer, "package x\n\ny := 1\n")
+}
+
 func TestEvalBodyCompileError(t *testing.T) {
 	ctx := context.Background()
 	store := newTestStore()
@@ -1777,6 +1852,24 @@ func TestEvalImport(t *testing.T) {
 	}
 }
 
+func TestEvalImportFutureKeywords(t *testing.T) {
+	ctx := context.Background()
+	store := newTestStore()
+	var buffer bytes.Buffer
+	repl := newRepl(store, &buffer)
+	if err := repl.OneShot(ctx, "1 in [1]"); err == nil {
+		t.Fatal("Expected parse error")
+	}
+	repl.OneShot(ctx, "import future.keywords.in")
+	buffer.Reset()
+	if err := repl.OneShot(ctx, "1 in [1]"); err != nil {
+		t.Fatal(err)
+	}
+	if buffer.String() != "true\n" {
+		t.Fatalf("Expected expression to evaluate successfully but got: %v", buffer.String())
+	}
+}
+
 func TestEvalPackage(t *testing.T) {
 	ctx := context.Background()
 	store := newTestStore()
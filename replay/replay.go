@@ -0,0 +1,351 @@
+// Copyright 2020 The OPA Authors.  All rights reserved.
+// Use of this source code is governed by an Apache2
+// license that can be found in the LICENSE file.
+
+// Package replay re-evaluates logged decisions against the policy and data
+// that were active when the decisions were made.
+//
+// The bundles are read from an archive directory that contains one
+// subdirectory per bundle name. Each subdirectory contains the bundle files
+// (or directories) of the revisions of that bundle, e.g.:
+//
+//	archive/authz/2020-06-01.tar.gz
+//	archive/authz/2020-06-02.tar.gz
+//	archive/users/6f1ed002ab5595859014ebf0951522d9.tar.gz
+//
+// The revision of each archived bundle is read from its manifest so the files
+// can be named freely.
+package replay
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/open-policy-agent/opa/ast"
+	"github.com/open-policy-agent/opa/bundle"
+	"github.com/open-policy-agent/opa/diff"
+	"github.com/open-policy-agent/opa/internal/merge"
+	"github.com/open-policy-agent/opa/loader"
+	"github.com/open-policy-agent/opa/rego"
+	"github.com/open-policy-agent/opa/storage/inmem"
+	"github.com/open-policy-agent/opa/topdown"
+	"github.com/open-policy-agent/opa/util"
+)
+
+// Event represents a decision log event. Only the fields required to replay
+// the decision are decoded.
+type Event struct {
+	DecisionID string                `json:"decision_id"`
+	Bundles    map[string]BundleInfo `json:"bundles,omitempty"`
+	Path       string                `json:"path,omitempty"`
+	Query      string                `json:"query,omitempty"`
+	Input      *interface{}          `json:"input,omitempty"`
+	Result     *interface{}          `json:"result,omitempty"`
+	Error      interface{}           `json:"error,omitempty"`
+	Erased     []string              `json:"erased,omitempty"`
+	Masked     []string              `json:"masked,omitempty"`
+	Timestamp  time.Time             `json:"timestamp"`
+}
+
+// BundleInfo describes the revision of a bundle that was active when the
+// decision was made.
+type BundleInfo struct {
+	Revision string `json:"revision"`
+}
+
+// Result represents the outcome of replaying a decision. If the logged input
+// or result was erased or masked by the decision log masking policy, Masked is
+// true and the replayed decision may differ from the logged decision.
+type Result struct {
+	DecisionID  string           `json:"decision_id"`
+	Query       string           `json:"query"`
+	Bundles     []string         `json:"bundles"`
+	Logged      diff.Decision    `json:"logged"`
+	Replayed    diff.Decision    `json:"replayed"`
+	Changed     bool             `json:"changed"`
+	Masked      bool             `json:"masked,omitempty"`
+	Explanation []*topdown.Event `json:"explanation,omitempty"`
+}
+
+// Archive provides the policy and data for bundle revisions. Bundles and the
+// snapshots built from them are loaded once and reused.
+type Archive struct {
+	dir       string
+	bundles   map[string]map[string]*bundle.Bundle // bundle name -> revision -> bundle
+	snapshots map[string]*diff.Snapshot            // keyed by bundle revisions
+}
+
+// NewArchive returns an archive that reads bundles from the directory.
+func NewArchive(dir string) *Archive {
+	return &Archive{
+		dir:       dir,
+		bundles:   map[string]map[string]*bundle.Bundle{},
+		snapshots: map[string]*diff.Snapshot{},
+	}
+}
+
+// Snapshot returns the policy and data of the bundle revisions (keyed by
+// bundle name). The data of the bundles is combined the same way as it would
+// be when the bundles are activated together.
+func (a *Archive) Snapshot(revisions map[string]string) (*diff.Snapshot, error) {
+
+	if len(revisions) == 0 {
+		return nil, fmt.Errorf("no bundle revisions")
+	}
+
+	names := make([]string, 0, len(revisions))
+	for name := range revisions {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+
+	key := snapshotKey(names, revisions)
+
+	if s, ok := a.snapshots[key]; ok {
+		return s, nil
+	}
+
+	bundles := make(map[string]*bundle.Bundle, len(names))
+
+	for _, name := range names {
+		b, err := a.bundle(name, revisions[name])
+		if err != nil {
+			return nil, err
+		}
+		bundles[name] = b
+	}
+
+	if err := bundle.CheckRootsOverlap(bundles); err != nil {
+		return nil, err
+	}
+
+	data := map[string]interface{}{}
+	modules := map[string]*ast.Module{}
+
+	for _, name := range names {
+		var ok bool
+		if data, ok = merge.InterfaceMaps(data, bundles[name].Data); !ok {
+			return nil, fmt.Errorf("bundle %q has data that conflicts with other bundles", name)
+		}
+
+		for id, module := range bundles[name].ParsedModules(name) {
+			modules[id] = module
+		}
+	}
+
+	compiler := ast.NewCompiler()
+
+	if compiler.Compile(modules); compiler.Failed() {
+		return nil, compiler.Errors
+	}
+
+	s := &diff.Snapshot{
+		Compiler: compiler,
+		Store:    inmem.NewFromObject(data),
+	}
+
+	a.snapshots[key] = s
+
+	return s, nil
+}
+
+// bundle returns the archived bundle with the name and revision. The first
+// time a bundle name is requested, all of the revisions of the bundle are
+// loaded.
+func (a *Archive) bundle(name, revision string) (*bundle.Bundle, error) {
+
+	revisions, ok := a.bundles[name]
+
+	if !ok {
+		dir := filepath.Join(a.dir, name)
+
+		infos, err := ioutil.ReadDir(dir)
+		if err != nil {
+			if os.IsNotExist(err) {
+				return nil, fmt.Errorf("bundle %q not found in archive", name)
+			}
+			return nil, err
+		}
+
+		revisions = map[string]*bundle.Bundle{}
+
+		for _, info := range infos {
+			path := filepath.Join(dir, info.Name())
+
+			b, err := loader.NewFileLoader().AsBundle(path)
+			if err != nil {
+				return nil, fmt.Errorf("archived bundle %v: %v", path, err)
+			}
+
+			revisions[b.Manifest.Revision] = b
+		}
+
+		a.bundles[name] = revisions
+	}
+
+	b, ok := revisions[revision]
+	if !ok {
+		return nil, fmt.Errorf("bundle %q revision %q not found in archive", name, revision)
+	}
+
+	return b, nil
+}
+
+// Decision replays the decision logged in the event. The decision is
+// evaluated at the time that it was logged so that policies that depend on the
+// current time make the same decision. If trace is true, the full explanation
+// of the evaluation is included in the result.
+func Decision(ctx context.Context, archive *Archive, event *Event, trace bool) (*Result, error) {
+
+	if len(event.Bundles) == 0 {
+		return nil, fmt.Errorf("decision %v: event does not contain bundle revisions", event.DecisionID)
+	}
+
+	revisions := make(map[string]string, len(event.Bundles))
+	for name, info := range event.Bundles {
+		revisions[name] = info.Revision
+	}
+
+	snapshot, err := archive.Snapshot(revisions)
+	if err != nil {
+		return nil, fmt.Errorf("decision %v: %v", event.DecisionID, err)
+	}
+
+	query := event.Query
+	if query == "" {
+		query = pathToQuery(event.Path)
+	}
+
+	result := &Result{
+		DecisionID: event.DecisionID,
+		Query:      query,
+		Bundles:    bundleRevisions(revisions),
+		Logged:     loggedDecision(event),
+		Masked:     len(event.Erased) > 0 || len(event.Masked) > 0,
+	}
+
+	opts := []func(*rego.Rego){
+		rego.Query(query),
+		rego.Compiler(snapshot.Compiler),
+		rego.Store(snapshot.Store),
+		rego.Time(event.Timestamp),
+	}
+
+	if event.Input != nil {
+		opts = append(opts, rego.Input(*event.Input))
+	}
+
+	var buf *topdown.BufferTracer
+
+	if trace {
+		buf = topdown.NewBufferTracer()
+		opts = append(opts, rego.Tracer(buf))
+	}
+
+	rs, err := rego.New(opts...).Eval(ctx)
+
+	if err != nil {
+		result.Replayed = diff.Decision{Error: err.Error()}
+	} else {
+		result.Replayed = replayedDecision(rs, event.Query != "")
+	}
+
+	if buf != nil {
+		result.Explanation = *buf
+	}
+
+	result.Changed = changed(result.Logged, result.Replayed)
+
+	return result, nil
+}
+
+// pathToQuery returns the query for a Data API decision. The path is logged
+// with slashes separating the segments (e.g., "authz/allow".)
+func pathToQuery(path string) string {
+	ref := ast.DefaultRootRef.Copy()
+	for _, s := range strings.Split(strings.Trim(path, "/"), "/") {
+		if s != "" {
+			ref = append(ref, ast.StringTerm(s))
+		}
+	}
+	return ref.String()
+}
+
+func loggedDecision(event *Event) diff.Decision {
+	if event.Error != nil {
+		if obj, ok := event.Error.(map[string]interface{}); ok {
+			if msg, ok := obj["message"].(string); ok && msg != "" {
+				return diff.Decision{Error: msg}
+			}
+		}
+		return diff.Decision{Error: string(util.MustMarshalJSON(event.Error))}
+	}
+	return diff.Decision{Result: event.Result}
+}
+
+// replayedDecision returns the decision in the same form as it is logged:
+// Data API decisions are logged as the value of the document and Query API
+// decisions are logged as the list of variable bindings.
+func replayedDecision(rs rego.ResultSet, query bool) diff.Decision {
+
+	var result interface{}
+
+	if query {
+		bindings := make([]interface{}, len(rs))
+		for i := range rs {
+			bindings[i] = rs[i].Bindings.WithoutWildcards()
+		}
+		result = bindings
+	} else if len(rs) == 0 {
+		return diff.Decision{}
+	} else {
+		result = rs[0].Expressions[0].Value
+	}
+
+	if err := util.RoundTrip(&result); err != nil {
+		return diff.Decision{Error: err.Error()}
+	}
+
+	return diff.Decision{Result: &result}
+}
+
+// changed returns true if the decisions differ. Error messages are not
+// compared because they may contain details (e.g., locations) that changed
+// between versions.
+func changed(logged, replayed diff.Decision) bool {
+	if logged.Error != "" || replayed.Error != "" {
+		return (logged.Error == "") != (replayed.Error == "")
+	}
+	if logged.Result == nil || replayed.Result == nil {
+		return logged.Result != replayed.Result
+	}
+	return !reflect.DeepEqual(*logged.Result, *replayed.Result)
+}
+
+func bundleRevisions(revisions map[string]string) []string {
+	result := make([]string, 0, len(revisions))
+	for name, revision := range revisions {
+		result = append(result, name+"@"+revision)
+	}
+	sort.Strings(result)
+	return result
+}
+
+func snapshotKey(names []string, revisions map[string]string) string {
+	var sb strings.Builder
+	for _, name := range names {
+		sb.WriteString(name)
+		sb.WriteByte(0)
+		sb.WriteString(revisions[name])
+		sb.WriteByte(0)
+	}
+	return sb.String()
+}
@@ -0,0 +1,171 @@
+// Copyright 2020 The OPA Authors.  All rights reserved.
+// Use of this source code is governed by an Apache2
+// license that can be found in the LICENSE file.
+
+package replay
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/open-policy-agent/opa/util"
+	"github.com/open-policy-agent/opa/util/test"
+)
+
+func TestDecision(t *testing.T) {
+
+	files := map[string]string{
+		"authz/a/.manifest": `{"revision": "v1", "roots": ["authz"]}`,
+		"authz/a/authz.rego": `package authz
+
+		default allow = false
+
+		allow { data.users.admins[_] = input.user }
+
+		default expired = false
+
+		expired { time.now_ns() > time.parse_rfc3339_ns("2020-01-01T00:00:00Z") }`,
+		"authz/b/.manifest": `{"revision": "v2", "roots": ["authz"]}`,
+		"authz/b/authz.rego": `package authz
+
+		allow { input.user = "carol" }`,
+		"users/u1/.manifest": `{"revision": "u1", "roots": ["users"]}`,
+		"users/u1/data.json": `{"users": {"admins": ["alice"]}}`,
+		"users/u2/.manifest": `{"revision": "u2", "roots": ["users"]}`,
+		"users/u2/data.json": `{"users": {"admins": ["alice", "bob"]}}`,
+	}
+
+	tests := []struct {
+		note     string
+		event    string
+		replayed string
+		changed  bool
+		masked   bool
+		err      string
+	}{
+		{
+			note:     "unchanged",
+			event:    `{"decision_id": "1", "bundles": {"authz": {"revision": "v1"}, "users": {"revision": "u1"}}, "path": "authz/allow", "input": {"user": "bob"}, "result": false}`,
+			replayed: `false`,
+		},
+		{
+			note:     "data revision",
+			event:    `{"decision_id": "2", "bundles": {"authz": {"revision": "v1"}, "users": {"revision": "u2"}}, "path": "authz/allow", "input": {"user": "bob"}, "result": true}`,
+			replayed: `true`,
+		},
+		{
+			note:     "changed",
+			event:    `{"decision_id": "3", "bundles": {"authz": {"revision": "v1"}, "users": {"revision": "u1"}}, "path": "authz/allow", "input": {"user": "bob"}, "result": true}`,
+			replayed: `false`,
+			changed:  true,
+		},
+		{
+			note:     "undefined",
+			event:    `{"decision_id": "4", "bundles": {"authz": {"revision": "v2"}, "users": {"revision": "u1"}}, "path": "authz/allow", "input": {"user": "bob"}}`,
+			replayed: ``,
+		},
+		{
+			note:     "logged time",
+			event:    `{"decision_id": "5", "bundles": {"authz": {"revision": "v1"}, "users": {"revision": "u1"}}, "path": "authz/expired", "timestamp": "2019-06-01T00:00:00Z", "result": false}`,
+			replayed: `false`,
+		},
+		{
+			note:     "query",
+			event:    `{"decision_id": "6", "bundles": {"authz": {"revision": "v1"}, "users": {"revision": "u1"}}, "query": "data.authz.allow = x", "input": {"user": "alice"}, "result": [{"x": true}]}`,
+			replayed: `[{"x": true}]`,
+		},
+		{
+			note:     "masked",
+			event:    `{"decision_id": "7", "bundles": {"authz": {"revision": "v1"}, "users": {"revision": "u1"}}, "path": "authz/allow", "erased": ["/input/user"], "result": true}`,
+			replayed: `false`,
+			changed:  true,
+			masked:   true,
+		},
+		{
+			note:  "missing revision",
+			event: `{"decision_id": "8", "bundles": {"authz": {"revision": "v3"}}, "path": "authz/allow"}`,
+			err:   `bundle "authz" revision "v3" not found in archive`,
+		},
+		{
+			note:  "missing bundle",
+			event: `{"decision_id": "9", "bundles": {"other": {"revision": "v1"}}, "path": "authz/allow"}`,
+			err:   `bundle "other" not found in archive`,
+		},
+		{
+			note:  "no bundles",
+			event: `{"decision_id": "10", "path": "authz/allow"}`,
+			err:   `event does not contain bundle revisions`,
+		},
+	}
+
+	test.WithTempFS(files, func(root string) {
+
+		archive := NewArchive(root)
+
+		for _, tc := range tests {
+			t.Run(tc.note, func(t *testing.T) {
+
+				var event Event
+				if err := util.UnmarshalJSON([]byte(tc.event), &event); err != nil {
+					t.Fatal(err)
+				}
+
+				result, err := Decision(context.Background(), archive, &event, false)
+
+				if tc.err != "" {
+					if err == nil || !strings.Contains(err.Error(), tc.err) {
+						t.Fatalf("Expected error containing %q but got: %v", tc.err, err)
+					}
+					return
+				} else if err != nil {
+					t.Fatal(err)
+				}
+
+				if tc.replayed == "" {
+					if result.Replayed.Result != nil {
+						t.Fatalf("Expected undefined decision but got: %v", *result.Replayed.Result)
+					}
+				} else if result.Replayed.Result == nil || util.Compare(*result.Replayed.Result, util.MustUnmarshalJSON([]byte(tc.replayed))) != 0 {
+					t.Fatalf("Expected replayed decision %v but got: %+v", tc.replayed, result.Replayed)
+				}
+
+				if result.Changed != tc.changed || result.Masked != tc.masked {
+					t.Fatalf("Expected changed=%v and masked=%v but got: %+v", tc.changed, tc.masked, result)
+				}
+			})
+		}
+	})
+}
+
+func TestDecisionTrace(t *testing.T) {
+
+	files := map[string]string{
+		"authz/v1/.manifest": `{"revision": "v1"}`,
+		"authz/v1/authz.rego": `package authz
+
+		allow { input.user = "alice" }`,
+	}
+
+	test.WithTempFS(files, func(root string) {
+
+		event := &Event{
+			DecisionID: "1",
+			Bundles:    map[string]BundleInfo{"authz": {Revision: "v1"}},
+			Path:       "authz/allow",
+		}
+
+		result, err := Decision(context.Background(), NewArchive(root), event, true)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if len(result.Explanation) == 0 {
+			t.Fatal("Expected explanation")
+		}
+
+		if exp := []string{"authz@v1"}; len(result.Bundles) != 1 || result.Bundles[0] != exp[0] {
+			t.Fatalf("Expected bundles %v but got %v", exp, result.Bundles)
+		}
+	})
+}
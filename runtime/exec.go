@@ -0,0 +1,268 @@
+// Copyright 2020 The OPA Authors.  All rights reserved.
+// Use of this source code is governed by an Apache2
+// license that can be found in the LICENSE file.
+
+package runtime
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/open-policy-agent/opa/ast"
+	"github.com/open-policy-agent/opa/bundle"
+	"github.com/open-policy-agent/opa/metrics"
+	bundlePlugin "github.com/open-policy-agent/opa/plugins/bundle"
+	"github.com/open-policy-agent/opa/plugins/logs"
+	"github.com/open-policy-agent/opa/rego"
+	"github.com/open-policy-agent/opa/server"
+	"github.com/open-policy-agent/opa/storage"
+	"github.com/open-policy-agent/opa/util"
+)
+
+// DefaultExecTimeout is the default amount of time that Exec waits for
+// bundles to be activated.
+const DefaultExecTimeout = 30 * time.Second
+
+// ExecParams stores the configuration for a one-shot evaluation.
+type ExecParams struct {
+
+	// Decision is the path of the decision to evaluate (e.g., "authz/allow".)
+	// If the decision is empty, the default decision from the configuration
+	// is evaluated.
+	Decision string
+
+	// Inputs contains the filenames of the JSON or YAML input documents. The
+	// decision is evaluated once for each input.
+	Inputs []string
+
+	// Timeout is the amount of time to wait for the configured bundles to be
+	// activated before evaluating the decision.
+	Timeout time.Duration
+}
+
+// ExecResult represents the decision made for one input. If the decision is
+// undefined, Result is nil.
+type ExecResult struct {
+	Path       string       `json:"path"`
+	DecisionID string       `json:"decision_id,omitempty"`
+	Result     *interface{} `json:"result,omitempty"`
+	Error      string       `json:"error,omitempty"`
+}
+
+// Exec starts the plugins, waits for the configured bundles to be activated,
+// evaluates the decision for each of the inputs, and stops the plugins. The
+// decisions are logged by the decision log plugin (if configured) and the
+// logs are uploaded before Exec returns.
+func (rt *Runtime) Exec(ctx context.Context, params ExecParams) ([]*ExecResult, error) {
+	setupLogging(rt.Params.Logging)
+
+	ref, err := rt.execRef(params.Decision)
+	if err != nil {
+		return nil, err
+	}
+
+	inputs := make([]interface{}, len(params.Inputs))
+
+	for i, path := range params.Inputs {
+		bs, err := ioutil.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		if err := util.Unmarshal(bs, &inputs[i]); err != nil {
+			return nil, fmt.Errorf("%v: %v", path, err)
+		}
+	}
+
+	if err := rt.Manager.Start(ctx); err != nil {
+		return nil, err
+	}
+
+	defer rt.Manager.Stop(ctx)
+
+	timeout := params.Timeout
+	if timeout <= 0 {
+		timeout = DefaultExecTimeout
+	}
+
+	if err := rt.waitForBundles(ctx, timeout); err != nil {
+		return nil, err
+	}
+
+	results := make([]*ExecResult, len(inputs))
+
+	for i := range inputs {
+		results[i], err = rt.execDecision(ctx, ref, params.Inputs[i], &inputs[i])
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if p := logs.Lookup(rt.Manager); p != nil {
+		if err := p.Flush(ctx); err != nil {
+			return results, fmt.Errorf("decision logs: %v", err)
+		}
+	}
+
+	return results, nil
+}
+
+// execRef returns the reference to the decision. Decision paths are
+// separated by slashes like Data API paths.
+func (rt *Runtime) execRef(decision string) (ast.Ref, error) {
+
+	if decision == "" {
+		return rt.Manager.Config.DefaultDecisionRef(), nil
+	}
+
+	ref := ast.DefaultRootRef.Copy()
+
+	for _, s := range strings.Split(strings.Trim(decision, "/"), "/") {
+		if s == "" {
+			return nil, fmt.Errorf("invalid decision path: %v", decision)
+		}
+		ref = append(ref, ast.StringTerm(s))
+	}
+
+	return ref, nil
+}
+
+// waitForBundles blocks until all of the configured bundles have been
+// activated. If discovery is enabled, the bundles are not known until the
+// discovered configuration has been applied.
+func (rt *Runtime) waitForBundles(ctx context.Context, timeout time.Duration) error {
+
+	deadline := time.Now().Add(timeout)
+
+	for {
+		missing, err := rt.missingBundles(ctx)
+		if err != nil {
+			return err
+		} else if len(missing) == 0 {
+			return nil
+		}
+
+		if time.Now().After(deadline) {
+			return fmt.Errorf("bundles not activated after %v: %v", timeout, strings.Join(missing, ", "))
+		}
+
+		logrus.WithField("bundles", missing).Debug("Waiting for bundles to be activated.")
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(100 * time.Millisecond):
+		}
+	}
+}
+
+// missingBundles returns the sorted names of the configured bundles that
+// have not been activated.
+func (rt *Runtime) missingBundles(ctx context.Context) ([]string, error) {
+
+	bp := bundlePlugin.Lookup(rt.Manager)
+	if bp == nil {
+		if rt.Manager.Config.Discovery != nil {
+			return []string{"discovery"}, nil
+		}
+		return nil, nil
+	}
+
+	txn, err := rt.Store.NewTransaction(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rt.Store.Abort(ctx, txn)
+
+	var missing []string
+
+	for name := range bp.Config().Bundles {
+		if _, err := bundle.ReadBundleRevisionFromStore(ctx, rt.Store, txn, name); err != nil {
+			if !storage.IsNotFound(err) {
+				return nil, err
+			}
+			missing = append(missing, name)
+		}
+	}
+
+	sort.Strings(missing)
+
+	return missing, nil
+}
+
+// execDecision evaluates the decision for the input and logs the decision.
+func (rt *Runtime) execDecision(ctx context.Context, ref ast.Ref, path string, input *interface{}) (*ExecResult, error) {
+
+	txn, err := rt.Store.NewTransaction(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rt.Store.Abort(ctx, txn)
+
+	m := metrics.New()
+
+	info := &server.Info{
+		Txn:        txn,
+		Bundles:    map[string]server.BundleInfo{},
+		DecisionID: rt.decisionIDFactory(),
+		Path:       ref.String(),
+		Input:      input,
+		Timestamp:  time.Now().UTC(),
+		Metrics:    m,
+	}
+
+	names, err := bundle.ReadBundleNamesFromStore(ctx, rt.Store, txn)
+	if err != nil && !storage.IsNotFound(err) {
+		return nil, err
+	}
+
+	for _, name := range names {
+		rev, err := bundle.ReadBundleRevisionFromStore(ctx, rt.Store, txn, name)
+		if err != nil {
+			return nil, err
+		}
+		info.Bundles[name] = server.BundleInfo{Revision: rev}
+	}
+
+	if rev, _, err := storage.Revision(ctx, rt.Store, txn); err == nil {
+		info.StoreRevision = rev
+	}
+
+	rs, err := rego.New(
+		rego.ParsedQuery(ast.NewBody(ast.NewExpr(ast.NewTerm(ref)))),
+		rego.Compiler(rt.Manager.GetCompiler()),
+		rego.Store(rt.Store),
+		rego.Transaction(txn),
+		rego.Runtime(rt.info),
+		rego.Metrics(m),
+		rego.Time(info.Timestamp),
+		rego.Input(*input),
+	).Eval(ctx)
+
+	result := &ExecResult{
+		Path:       path,
+		DecisionID: info.DecisionID,
+	}
+
+	if err != nil {
+		info.Error = err
+		result.Error = err.Error()
+	} else if len(rs) > 0 {
+		value := rs[0].Expressions[0].Value
+		info.Results = &value
+		result.Result = &value
+	}
+
+	if err := rt.decisionLogger(ctx, info); err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
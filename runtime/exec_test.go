@@ -0,0 +1,150 @@
+// Copyright 2020 The OPA Authors.  All rights reserved.
+// Use of this source code is governed by an Apache2
+// license that can be found in the LICENSE file.
+
+package runtime
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/open-policy-agent/opa/bundle"
+	"github.com/open-policy-agent/opa/plugins/logs"
+	"github.com/open-policy-agent/opa/util/test"
+)
+
+func TestExec(t *testing.T) {
+
+	var buf bytes.Buffer
+
+	err := bundle.Write(&buf, bundle.Bundle{
+		Manifest: bundle.Manifest{Revision: "v1", Roots: &[]string{"authz"}},
+		Data:     map[string]interface{}{},
+		Modules: []bundle.ModuleFile{
+			{
+				Path: "/authz.rego",
+				Raw: []byte(`package authz
+
+				allow { input.user = "alice" }`),
+			},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var events []logs.EventV1
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/bundles/authz":
+			w.Write(buf.Bytes())
+		case "/logs":
+			gr, err := gzip.NewReader(r.Body)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var chunk []logs.EventV1
+			if err := json.NewDecoder(gr).Decode(&chunk); err != nil {
+				t.Fatal(err)
+			}
+			events = append(events, chunk...)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+
+	defer ts.Close()
+
+	config := fmt.Sprintf(`{
+		"services": {"example": {"url": %q}},
+		"bundles": {"authz": {"service": "example", "resource": "/bundles/authz"}},
+		"decision_logs": {"service": "example"}
+	}`, ts.URL)
+
+	fs := map[string]string{
+		"config.json": config,
+		"alice.json":  `{"user": "alice"}`,
+		"bob.yaml":    `user: bob`,
+	}
+
+	test.WithTempFS(fs, func(rootDir string) {
+
+		ctx := context.Background()
+
+		params := NewParams()
+		params.ConfigFile = filepath.Join(rootDir, "config.json")
+
+		rt, err := NewRuntime(ctx, params)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		results, err := rt.Exec(ctx, ExecParams{
+			Decision: "authz/allow",
+			Inputs:   []string{filepath.Join(rootDir, "alice.json"), filepath.Join(rootDir, "bob.yaml")},
+			Timeout:  5 * time.Second,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if len(results) != 2 || results[0].Result == nil || *results[0].Result != true || results[1].Result != nil {
+			t.Fatalf("Expected alice to be allowed and bob to be undefined but got: %v", results)
+		}
+
+		if len(events) != 2 {
+			t.Fatalf("Expected two decision log events but got: %v", events)
+		}
+
+		for i := range events {
+			if events[i].DecisionID == "" || events[i].DecisionID != results[i].DecisionID {
+				t.Fatalf("Expected decision ID %q but got: %v", results[i].DecisionID, events[i])
+			}
+			if events[i].Path != "authz/allow" || events[i].Bundles["authz"].Revision != "v1" {
+				t.Fatalf("Unexpected decision log event: %+v", events[i])
+			}
+		}
+	})
+}
+
+func TestExecBundleTimeout(t *testing.T) {
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+	}))
+
+	defer ts.Close()
+
+	config := fmt.Sprintf(`{
+		"services": {"example": {"url": %q}},
+		"bundles": {"authz": {"service": "example", "resource": "/bundles/authz"}}
+	}`, ts.URL)
+
+	test.WithTempFS(map[string]string{"config.json": config}, func(rootDir string) {
+
+		ctx := context.Background()
+
+		params := NewParams()
+		params.ConfigFile = filepath.Join(rootDir, "config.json")
+
+		rt, err := NewRuntime(ctx, params)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		_, err = rt.Exec(ctx, ExecParams{Decision: "authz/allow", Timeout: 200 * time.Millisecond})
+		if err == nil || !strings.Contains(err.Error(), "bundles not activated after 200ms: authz") {
+			t.Fatalf("Expected timeout error but got: %v", err)
+		}
+	})
+}
@@ -27,6 +27,7 @@ import (
 	"github.com/open-policy-agent/opa/ast"
 	"github.com/open-policy-agent/opa/internal/prometheus"
 	"github.com/open-policy-agent/opa/internal/runtime"
+	"github.com/open-policy-agent/opa/internal/stdlib"
 	storedversion "github.com/open-policy-agent/opa/internal/version"
 	"github.com/open-policy-agent/opa/loader"
 	"github.com/open-policy-agent/opa/metrics"
@@ -35,6 +36,7 @@ import (
 	"github.com/open-policy-agent/opa/plugins/logs"
 	"github.com/open-policy-agent/opa/repl"
 	"github.com/open-policy-agent/opa/server"
+	"github.com/open-policy-agent/opa/server/limiter"
 	"github.com/open-policy-agent/opa/storage"
 	"github.com/open-policy-agent/opa/storage/inmem"
 	"github.com/open-policy-agent/opa/version"
@@ -102,6 +104,10 @@ type Params struct {
 	// loading all data & policy files.
 	BundleMode bool
 
+	// Stdlib flag controls whether the standard library of Rego helpers that
+	// ships with OPA is loaded in addition to the Paths.
+	Stdlib bool
+
 	// Watch flag controls whether OPA will watch the Paths files for changes.
 	// If this flag is true, OPA will watch the Paths files for changes and
 	// reload the storage layer each time they change. This is useful for
@@ -115,6 +121,39 @@ type Params struct {
 	// PprofEnabled flag controls whether pprof endpoints are enabled
 	PprofEnabled bool
 
+	// RateLimits are the per-endpoint rate limits (keyed by path prefix)
+	// enforced by the server.
+	RateLimits map[string]limiter.Rate
+
+	// MaxRequestBodySize is the maximum size (in bytes) of request bodies
+	// accepted by the server. Zero means unlimited.
+	MaxRequestBodySize int64
+
+	// MaxQueryExpressions is the maximum number of expressions in ad-hoc
+	// queries accepted by the server. Zero means unlimited.
+	MaxQueryExpressions int
+
+	// MaxQueryResults is the maximum number of results that ad-hoc queries
+	// executed by the server may produce. Zero means unlimited.
+	MaxQueryResults int
+
+	// MaxQueryCost is the maximum estimated cost of ad-hoc queries accepted by
+	// the server. Zero means unlimited.
+	MaxQueryCost float64
+
+	// DecisionCacheTTL is the amount of time that Data API decisions are
+	// cached by the server. Zero disables the decision cache.
+	DecisionCacheTTL time.Duration
+
+	// DecisionCacheMaxEntries is the maximum number of decisions cached by
+	// the server.
+	DecisionCacheMaxEntries int
+
+	// ValidateInput enables validation of Data API inputs against the input
+	// schemas inferred from the policies. Requests with inputs that do not
+	// conform are rejected by the server.
+	ValidateInput bool
+
 	// DecisionIDFactory generates decision IDs to include in API responses
 	// sent by the server (in response to Data API queries.)
 	DecisionIDFactory func() string
@@ -186,7 +225,7 @@ func NewRuntime(ctx context.Context, params Params) (*Runtime, error) {
 		}
 	}
 
-	loaded, err := loadPaths(params.Paths, params.Filter, params.BundleMode)
+	loaded, err := loadPaths(params.Paths, params.Filter, params.BundleMode, params.Stdlib)
 	if err != nil {
 		return nil, errors.Wrap(err, "load error")
 	}
@@ -236,6 +275,7 @@ func NewRuntime(ctx context.Context, params Params) (*Runtime, error) {
 	}
 
 	metrics := prometheus.New(metrics.New(), errorLogger)
+	metrics.RegisterServiceStats(manager.ServiceStats)
 
 	disco, err := discovery.New(manager, discovery.Factories(registeredPlugins), discovery.Metrics(metrics))
 	if err != nil {
@@ -288,6 +328,13 @@ func (rt *Runtime) Serve(ctx context.Context) error {
 		WithManager(rt.Manager).
 		WithCompilerErrorLimit(rt.Params.ErrorLimit).
 		WithPprofEnabled(rt.Params.PprofEnabled).
+		WithRateLimits(rt.Params.RateLimits).
+		WithMaxRequestBodySize(rt.Params.MaxRequestBodySize).
+		WithMaxQueryExpressions(rt.Params.MaxQueryExpressions).
+		WithMaxQueryCost(rt.Params.MaxQueryCost).
+		WithMaxQueryResults(rt.Params.MaxQueryResults).
+		WithDecisionCache(rt.Params.DecisionCacheTTL, rt.Params.DecisionCacheMaxEntries).
+		WithInputValidation(rt.Params.ValidateInput).
 		WithAddresses(*rt.Params.Addrs).
 		WithInsecureAddress(rt.Params.InsecureAddr).
 		WithCertificate(rt.Params.Certificate).
@@ -305,6 +352,10 @@ func (rt *Runtime) Serve(ctx context.Context) error {
 		return err
 	}
 
+	if rt.Params.DecisionCacheTTL > 0 {
+		rt.registerDecisionCacheMetrics()
+	}
+
 	if rt.Params.Watch {
 		if err := rt.startWatcher(ctx, rt.Params.Paths, onReloadLogger); err != nil {
 			logrus.WithField("err", err).Error("Unable to open watch.")
@@ -399,6 +450,29 @@ func (rt *Runtime) decisionLogger(ctx context.Context, event *server.Info) error
 	return plugin.Log(ctx, event)
 }
 
+func (rt *Runtime) registerDecisionCacheMetrics() {
+	counters := []struct {
+		name string
+		help string
+		f    func() float64
+	}{
+		{"decision_cache_hits_total", "A count of decisions served from the decision cache.", func() float64 {
+			hits, _ := rt.server.DecisionCacheStats()
+			return float64(hits)
+		}},
+		{"decision_cache_misses_total", "A count of cacheable decisions that were evaluated.", func() float64 {
+			_, misses := rt.server.DecisionCacheStats()
+			return float64(misses)
+		}},
+	}
+
+	for _, c := range counters {
+		if err := rt.metrics.RegisterCounterFunc(c.name, c.help, c.f); err != nil {
+			logrus.WithField("err", err).Warn("Unable to register decision cache metrics.")
+		}
+	}
+}
+
 func (rt *Runtime) startWatcher(ctx context.Context, paths []string, onReload func(time.Duration, error)) error {
 	watcher, err := getWatcher(paths)
 	if err != nil {
@@ -429,7 +503,7 @@ func (rt *Runtime) readWatcher(ctx context.Context, watcher *fsnotify.Watcher, p
 
 func (rt *Runtime) processWatcherUpdate(ctx context.Context, paths []string, removed string) error {
 
-	loaded, err := loadPaths(paths, rt.Params.Filter, rt.Params.BundleMode)
+	loaded, err := loadPaths(paths, rt.Params.Filter, rt.Params.BundleMode, rt.Params.Stdlib)
 	if err != nil {
 		return err
 	}
@@ -514,7 +588,7 @@ type loadResult struct {
 	Bundles map[string]*bundle.Bundle
 }
 
-func loadPaths(paths []string, filter loader.Filter, asBundle bool) (*loadResult, error) {
+func loadPaths(paths []string, filter loader.Filter, asBundle bool, withStdlib bool) (*loadResult, error) {
 	result := &loadResult{}
 	var err error
 
@@ -527,7 +601,7 @@ func loadPaths(paths []string, filter loader.Filter, asBundle bool) (*loadResult
 			}
 		}
 	} else {
-		loaded, err := loader.NewFileLoader().Filtered(paths, filter)
+		loaded, err := loader.NewFileLoader().WithProgress(onLoadProgress).Filtered(paths, filter)
 		if err != nil {
 			return nil, err
 		}
@@ -535,6 +609,27 @@ func loadPaths(paths []string, filter loader.Filter, asBundle bool) (*loadResult
 		result.Documents = loaded.Documents
 	}
 
+	if withStdlib {
+		modules, err := stdlib.Modules()
+		if err != nil {
+			return nil, err
+		}
+		if result.Modules == nil {
+			result.Modules = make(map[string]*loader.RegoFile, len(modules))
+		}
+		for _, m := range modules {
+			id := stdlib.Name + m.Path
+			if _, ok := result.Modules[id]; ok {
+				return nil, fmt.Errorf("%v: conflicts with the standard library", id)
+			}
+			result.Modules[id] = &loader.RegoFile{
+				Name:   id,
+				Raw:    m.Raw,
+				Parsed: m.Parsed,
+			}
+		}
+	}
+
 	return result, nil
 }
 
@@ -612,6 +707,13 @@ func getWatchPaths(rootPaths []string) ([]string, error) {
 	return paths, nil
 }
 
+func onLoadProgress(path string, read, total int64) {
+	logrus.WithFields(logrus.Fields{
+		"path":    path,
+		"percent": read * 100 / total,
+	}).Info("Loading data file.")
+}
+
 func onReloadLogger(d time.Duration, err error) {
 	logrus.WithFields(logrus.Fields{
 		"duration": d,
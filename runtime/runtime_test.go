@@ -268,6 +268,63 @@ func testRuntimeProcessWatchEvents(t *testing.T, asBundle bool) {
 	})
 }
 
+func TestRuntimeStdlib(t *testing.T) {
+	testRuntimeStdlib(t, false)
+}
+
+func TestRuntimeStdlibWithBundle(t *testing.T) {
+	testRuntimeStdlib(t, true)
+}
+
+func testRuntimeStdlib(t *testing.T, asBundle bool) {
+	ctx := context.Background()
+
+	fs := map[string]string{
+		"/x.rego": `package test
+
+		import data.stdlib.strings
+
+		p { strings.any_prefix_match("foo/bar", ["foo/"]) }
+		`,
+	}
+
+	test.WithTempFS(fs, func(rootDir string) {
+		params := NewParams()
+		params.Paths = []string{rootDir}
+		params.BundleMode = asBundle
+		params.Stdlib = true
+
+		rt, err := NewRuntime(ctx, params)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		// The standard library must be kept when the paths are reloaded.
+		if err := rt.processWatcherUpdate(ctx, params.Paths, ""); err != nil {
+			t.Fatal(err)
+		}
+
+		txn := storage.NewTransactionOrDie(ctx, rt.Store)
+		defer rt.Store.Abort(ctx, txn)
+
+		ids, err := rt.Store.ListPolicies(ctx, txn)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		found := false
+		for _, id := range ids {
+			if id == "stdlib/strings.rego" {
+				found = true
+			}
+		}
+
+		if !found {
+			t.Fatalf("Expected standard library policies but got: %v", ids)
+		}
+	})
+}
+
 func TestRuntimeProcessWatchEventPolicyError(t *testing.T) {
 	testRuntimeProcessWatchEventPolicyError(t, false)
 }
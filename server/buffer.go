@@ -25,19 +25,23 @@ type Buffer interface {
 
 // Info contains information describing a policy decision.
 type Info struct {
-	Txn        storage.Transaction
-	Revision   string // Deprecated: Use `Bundles` instead
-	Bundles    map[string]BundleInfo
-	DecisionID string
-	RemoteAddr string
-	Query      string
-	Path       string
-	Timestamp  time.Time
-	Input      *interface{}
-	Results    *interface{}
-	Error      error
-	Metrics    metrics.Metrics
-	Trace      []*topdown.Event
+	Txn           storage.Transaction
+	Revision      string // Deprecated: Use `Bundles` instead
+	Bundles       map[string]BundleInfo
+	DecisionID    string
+	StoreRevision uint64 // Zero if the store does not track revisions.
+	Snapshot      string // Empty unless the decision was read from a named snapshot.
+	Shadow        bool   // True if the decision was evaluated in shadow mode.
+	Override      bool   // True if the decision was forced by an override.
+	RemoteAddr    string
+	Query         string
+	Path          string
+	Timestamp     time.Time
+	Input         *interface{}
+	Results       *interface{}
+	Error         error
+	Metrics       metrics.Metrics
+	Trace         []*topdown.Event
 }
 
 // BundleInfo contains information describing a bundle
@@ -0,0 +1,193 @@
+// Copyright 2020 The OPA Authors.  All rights reserved.
+// Use of this source code is governed by an Apache2
+// license that can be found in the LICENSE file.
+
+package server
+
+import (
+	"container/list"
+	"crypto/sha256"
+	"encoding/json"
+	"sync"
+	"time"
+
+	"github.com/open-policy-agent/opa/util"
+)
+
+// DefaultDecisionCacheMaxEntries is the default number of decisions kept in
+// the decision cache.
+const DefaultDecisionCacheMaxEntries = 10000
+
+// decisionCacheKey identifies a decision. The generation is incremented when
+// the store is written to so that decisions are never served from policy or
+// data that has been replaced.
+type decisionCacheKey struct {
+	generation uint64
+	path       string
+	input      [sha256.Size]byte
+	hasInput   bool
+}
+
+// decisionCacheEntry holds the JSON encoding of the result so that callers
+// (e.g., decision log masking) can modify the results returned by the cache.
+type decisionCacheEntry struct {
+	key     decisionCacheKey
+	result  []byte
+	expires time.Time
+}
+
+// decisionCache caches the results of Data API queries for a TTL. When the
+// cache is full, the least recently used decision is evicted. A nil
+// decisionCache does not store any decisions.
+type decisionCache struct {
+	mtx        sync.Mutex
+	ttl        time.Duration
+	maxEntries int
+	generation uint64
+	entries    map[decisionCacheKey]*list.Element
+	lru        *list.List
+	hits       uint64
+	misses     uint64
+}
+
+func newDecisionCache(ttl time.Duration, maxEntries int) *decisionCache {
+	if ttl <= 0 {
+		return nil
+	}
+	if maxEntries <= 0 {
+		maxEntries = DefaultDecisionCacheMaxEntries
+	}
+	return &decisionCache{
+		ttl:        ttl,
+		maxEntries: maxEntries,
+		entries:    map[decisionCacheKey]*list.Element{},
+		lru:        list.New(),
+	}
+}
+
+// Key returns the key for the decision at path for input. Key must be called
+// before the storage transaction used to evaluate the decision is opened.
+func (c *decisionCache) Key(path string, input *interface{}) (decisionCacheKey, bool) {
+	if c == nil {
+		return decisionCacheKey{}, false
+	}
+
+	key := decisionCacheKey{path: path}
+
+	if input != nil {
+		bs, err := json.Marshal(*input)
+		if err != nil {
+			return key, false
+		}
+		key.input = sha256.Sum256(bs)
+		key.hasInput = true
+	}
+
+	c.mtx.Lock()
+	key.generation = c.generation
+	c.mtx.Unlock()
+
+	return key, true
+}
+
+// Get returns the cached result for the key. The result is nil if the
+// decision was undefined.
+func (c *decisionCache) Get(key decisionCacheKey, now time.Time) (*interface{}, bool) {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+
+	elem, ok := c.entries[key]
+	if !ok {
+		c.misses++
+		return nil, false
+	}
+
+	entry := elem.Value.(*decisionCacheEntry)
+
+	if now.After(entry.expires) {
+		c.lru.Remove(elem)
+		delete(c.entries, key)
+		c.misses++
+		return nil, false
+	}
+
+	if entry.result == nil {
+		c.lru.MoveToFront(elem)
+		c.hits++
+		return nil, true
+	}
+
+	var result interface{}
+
+	if err := util.UnmarshalJSON(entry.result, &result); err != nil {
+		c.misses++
+		return nil, false
+	}
+
+	c.lru.MoveToFront(elem)
+	c.hits++
+
+	return &result, true
+}
+
+// Put inserts the result under the key unless the store has been written to
+// since the key was created.
+func (c *decisionCache) Put(key decisionCacheKey, result *interface{}, now time.Time) {
+	var bs []byte
+
+	if result != nil {
+		var err error
+		if bs, err = json.Marshal(*result); err != nil {
+			return
+		}
+	}
+
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+
+	if key.generation != c.generation {
+		return
+	}
+
+	entry := &decisionCacheEntry{key: key, result: bs, expires: now.Add(c.ttl)}
+
+	if elem, ok := c.entries[key]; ok {
+		elem.Value = entry
+		c.lru.MoveToFront(elem)
+		return
+	}
+
+	c.entries[key] = c.lru.PushFront(entry)
+
+	for c.lru.Len() > c.maxEntries {
+		oldest := c.lru.Back()
+		c.lru.Remove(oldest)
+		delete(c.entries, oldest.Value.(*decisionCacheEntry).key)
+	}
+}
+
+// Invalidate removes all decisions from the cache.
+func (c *decisionCache) Invalidate() {
+	if c == nil {
+		return
+	}
+
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+
+	c.generation++
+	c.entries = map[decisionCacheKey]*list.Element{}
+	c.lru.Init()
+}
+
+// Stats returns the number of cache hits and misses.
+func (c *decisionCache) Stats() (uint64, uint64) {
+	if c == nil {
+		return 0, 0
+	}
+
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+
+	return c.hits, c.misses
+}
@@ -0,0 +1,356 @@
+// Copyright 2020 The OPA Authors.  All rights reserved.
+// Use of this source code is governed by an Apache2
+// license that can be found in the LICENSE file.
+
+package server
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io/ioutil"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"sync/atomic"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/open-policy-agent/opa/ast"
+	"github.com/open-policy-agent/opa/bundle"
+	"github.com/open-policy-agent/opa/metrics"
+	"github.com/open-policy-agent/opa/plugins"
+	"github.com/open-policy-agent/opa/rego"
+	"github.com/open-policy-agent/opa/server/types"
+	"github.com/open-policy-agent/opa/server/writer"
+	"github.com/open-policy-agent/opa/storage"
+	"github.com/open-policy-agent/opa/util"
+)
+
+const (
+	// canaryMaxEvals is the maximum number of decisions evaluated against the
+	// staged bundle at the same time. Sampled decisions are dropped while the
+	// limit is reached so that the staged bundle cannot exhaust the server.
+	canaryMaxEvals = 16
+
+	// canaryEvalTimeout is the deadline for evaluating a decision against the
+	// staged bundle.
+	canaryEvalTimeout = 10 * time.Second
+)
+
+// canary is a bundle staged next to the active bundles. The staged bundle is
+// activated on a copy of the store so decisions can be evaluated against both
+// and compared before the staged bundle is promoted.
+type canary struct {
+	bundle   string
+	revision string
+	percent  float64
+	created  time.Time
+	raw      []byte
+	store    storage.Store
+	compiler *ast.Compiler
+	evals    chan struct{}
+
+	// Updated atomically.
+	compared   uint64
+	mismatched uint64
+	errors     uint64
+	dropped    uint64
+}
+
+func (c *canary) v1() *types.CanaryV1 {
+	return &types.CanaryV1{
+		Bundle:     c.bundle,
+		Revision:   c.revision,
+		Percent:    c.percent,
+		Created:    c.created,
+		Compared:   atomic.LoadUint64(&c.compared),
+		Mismatched: atomic.LoadUint64(&c.mismatched),
+		Errors:     atomic.LoadUint64(&c.errors),
+		Dropped:    atomic.LoadUint64(&c.dropped),
+	}
+}
+
+func (c *canary) logrusFields() logrus.Fields {
+	return logrus.Fields{
+		"bundle":   c.bundle,
+		"revision": c.revision,
+	}
+}
+
+// v1CanaryGet writes the status of the staged bundle.
+func (s *Server) v1CanaryGet(w http.ResponseWriter, r *http.Request) {
+	pretty := getBoolParam(r.URL, types.ParamPrettyV1, true)
+
+	c, ok := s.getCanary(w)
+	if !ok {
+		return
+	}
+
+	writer.JSON(w, http.StatusOK, types.CanaryResponseV1{Result: c.v1()}, pretty)
+}
+
+// v1CanaryPut stages the bundle in the request body. The bundle is activated
+// on a snapshot of the store. If a bundle is already staged, it is replaced.
+func (s *Server) v1CanaryPut(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	pretty := getBoolParam(r.URL, types.ParamPrettyV1, true)
+
+	name := r.URL.Query().Get(types.ParamBundleV1)
+	if name == "" {
+		writer.ErrorString(w, http.StatusBadRequest, types.CodeInvalidParameter, fmt.Errorf("missing %v parameter", types.ParamBundleV1))
+		return
+	}
+
+	percent := 100.0
+	if param := r.URL.Query().Get(types.ParamPercentV1); param != "" {
+		var err error
+		percent, err = strconv.ParseFloat(param, 64)
+		if err != nil || percent < 0 || percent > 100 {
+			writer.ErrorString(w, http.StatusBadRequest, types.CodeInvalidParameter, fmt.Errorf("invalid %v parameter %q: must be between 0 and 100", types.ParamPercentV1, param))
+			return
+		}
+	}
+
+	raw, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		writer.ErrorAuto(w, err)
+		return
+	}
+
+	b, err := bundle.NewReader(bytes.NewReader(raw)).Read()
+	if err != nil {
+		writer.ErrorString(w, http.StatusBadRequest, types.CodeInvalidParameter, err)
+		return
+	}
+
+	sn, ok := s.store.(storage.Snapshotter)
+	if !ok {
+		writer.ErrorString(w, http.StatusNotImplemented, types.CodeInvalidOperation, fmt.Errorf("store does not support snapshots"))
+		return
+	}
+
+	txn, err := s.store.NewTransaction(ctx)
+	if err != nil {
+		writer.ErrorAuto(w, err)
+		return
+	}
+
+	staged, err := sn.Snapshot(ctx, txn)
+	s.store.Abort(ctx, txn)
+	if err != nil {
+		writer.ErrorAuto(w, err)
+		return
+	}
+
+	compiler, err := activateBundle(ctx, staged, name, &b, nil)
+	if err != nil {
+		writeActivateError(w, err)
+		return
+	}
+
+	c := &canary{
+		bundle:   name,
+		revision: b.Manifest.Revision,
+		percent:  percent,
+		created:  time.Now().UTC(),
+		raw:      raw,
+		store:    staged,
+		compiler: compiler,
+		evals:    make(chan struct{}, canaryMaxEvals),
+	}
+
+	s.canaryMtx.Lock()
+	s.canary = c
+	s.canaryMtx.Unlock()
+
+	logrus.WithFields(c.logrusFields()).WithField("percent", percent).Info("Canary bundle staged.")
+
+	writer.JSON(w, http.StatusOK, types.CanaryResponseV1{Result: c.v1()}, pretty)
+}
+
+// v1CanaryPromotePost activates the staged bundle on the store. Decisions are
+// no longer evaluated against the staged bundle.
+func (s *Server) v1CanaryPromotePost(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	pretty := getBoolParam(r.URL, types.ParamPrettyV1, true)
+
+	c, ok := s.getCanary(w)
+	if !ok {
+		return
+	}
+
+	// The staged bundle was activated on a copy of the store so it is read
+	// again to avoid sharing data between the stores.
+	b, err := bundle.NewReader(bytes.NewReader(c.raw)).Read()
+	if err != nil {
+		writer.ErrorAuto(w, err)
+		return
+	}
+
+	if _, err := activateBundle(ctx, s.store, c.bundle, &b, storage.NewContext()); err != nil {
+		writeActivateError(w, err)
+		return
+	}
+
+	s.canaryMtx.Lock()
+	if s.canary == c {
+		s.canary = nil
+	}
+	s.canaryMtx.Unlock()
+
+	logrus.WithFields(c.logrusFields()).Info("Canary bundle promoted.")
+
+	writer.JSON(w, http.StatusOK, types.CanaryResponseV1{Result: c.v1()}, pretty)
+}
+
+// v1CanaryDelete aborts the canary evaluation. The staged bundle is discarded.
+func (s *Server) v1CanaryDelete(w http.ResponseWriter, r *http.Request) {
+	c, ok := s.getCanary(w)
+	if !ok {
+		return
+	}
+
+	s.canaryMtx.Lock()
+	if s.canary == c {
+		s.canary = nil
+	}
+	s.canaryMtx.Unlock()
+
+	logrus.WithFields(c.logrusFields()).Info("Canary bundle aborted.")
+
+	writer.Bytes(w, http.StatusNoContent, nil)
+}
+
+func (s *Server) getCanary(w http.ResponseWriter) (*canary, bool) {
+	s.canaryMtx.RLock()
+	c := s.canary
+	s.canaryMtx.RUnlock()
+
+	if c == nil {
+		writer.ErrorString(w, http.StatusNotFound, types.CodeResourceNotFound, fmt.Errorf("no bundle staged"))
+		return nil, false
+	}
+
+	return c, true
+}
+
+// mirrorCanary evaluates the decision against the staged bundle if the decision
+// is sampled and compares the result with the result of the active bundles.
+// The staged decision is evaluated in the background so callers are not
+// delayed. If canaryMaxEvals decisions are already being evaluated, the
+// decision is dropped. Mismatches and errors are logged.
+func (s *Server) mirrorCanary(decisionID string, path ast.Ref, input ast.Value, active *interface{}) {
+	s.canaryMtx.RLock()
+	c := s.canary
+	s.canaryMtx.RUnlock()
+
+	if c == nil || rand.Float64()*100 >= c.percent {
+		return
+	}
+
+	select {
+	case c.evals <- struct{}{}:
+	default:
+		atomic.AddUint64(&c.dropped, 1)
+		return
+	}
+
+	s.canaryEvals.Add(1)
+
+	go func() {
+		defer s.canaryEvals.Done()
+		defer func() { <-c.evals }()
+
+		ctx, cancel := context.WithTimeout(context.Background(), canaryEvalTimeout)
+		defer cancel()
+
+		fields := c.logrusFields()
+		fields["decision_id"] = decisionID
+		fields["path"] = path.String()
+
+		rs, err := rego.New(
+			rego.Compiler(c.compiler),
+			rego.Store(c.store),
+			rego.ParsedInput(input),
+			rego.Query(path.String()),
+			rego.Runtime(s.runtime),
+			rego.UnsafeBuiltins(unsafeBuiltinsMap),
+		).Eval(ctx)
+
+		atomic.AddUint64(&c.compared, 1)
+
+		if err != nil {
+			atomic.AddUint64(&c.errors, 1)
+			logrus.WithFields(fields).WithField("err", err).Warn("Canary decision failed.")
+			return
+		}
+
+		var staged *interface{}
+		if len(rs) > 0 {
+			staged = &rs[0].Expressions[0].Value
+		}
+
+		if resultsEqual(active, staged) {
+			logrus.WithFields(fields).Debug("Canary decision matched.")
+			return
+		}
+
+		atomic.AddUint64(&c.mismatched, 1)
+		fields["active_result"] = resultString(active)
+		fields["staged_result"] = resultString(staged)
+		logrus.WithFields(fields).Info("Canary decision mismatched.")
+	}()
+}
+
+// activateBundle activates the bundle on the store and returns the compiler
+// for the resulting policies. If stctx is not nil, the compiler is set on the
+// storage context so that the manager replaces its compiler when the
+// transaction is committed.
+func activateBundle(ctx context.Context, store storage.Store, name string, b *bundle.Bundle, stctx *storage.Context) (*ast.Compiler, error) {
+	params := storage.WriteParams
+	params.Context = stctx
+
+	var compiler *ast.Compiler
+
+	err := storage.Txn(ctx, store, params, func(txn storage.Transaction) error {
+		compiler = ast.NewCompiler().WithPathConflictsCheck(storage.NonEmpty(ctx, store, txn))
+		err := bundle.Activate(&bundle.ActivateOpts{
+			Ctx:      ctx,
+			Store:    store,
+			Txn:      txn,
+			Compiler: compiler,
+			Metrics:  metrics.New(),
+			Bundles:  map[string]*bundle.Bundle{name: b},
+		})
+		if err == nil && stctx != nil {
+			plugins.SetCompilerOnContext(stctx, compiler)
+		}
+		return err
+	})
+
+	return compiler, err
+}
+
+func writeActivateError(w http.ResponseWriter, err error) {
+	if errs, ok := err.(ast.Errors); ok {
+		writer.Error(w, http.StatusBadRequest, types.NewErrorV1(types.CodeInvalidParameter, types.MsgCompileModuleError).WithASTErrors(errs))
+		return
+	}
+	writer.ErrorString(w, http.StatusBadRequest, types.CodeInvalidParameter, err)
+}
+
+func resultsEqual(a, b *interface{}) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return util.Compare(*a, *b) == 0
+}
+
+func resultString(x *interface{}) string {
+	if x == nil {
+		return "undefined"
+	}
+	return string(util.MustMarshalJSON(*x))
+}
@@ -0,0 +1,109 @@
+// Copyright 2019 The OPA Authors.  All rights reserved.
+// Use of this source code is governed by an Apache2
+// license that can be found in the LICENSE file.
+
+package server
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/gorilla/mux"
+
+	"github.com/open-policy-agent/opa/bundle"
+	"github.com/open-policy-agent/opa/server/types"
+	"github.com/open-policy-agent/opa/server/writer"
+	"github.com/open-policy-agent/opa/storage"
+	"github.com/open-policy-agent/opa/util"
+)
+
+// contentTypeGzip identifies exports and imports encoded as gzipped tarballs.
+// The tarballs use the bundle format (without policies.)
+const contentTypeGzip = "application/gzip"
+
+// v1ExportGet writes the base document at the path without evaluating any
+// policies. The document is written as JSON unless the client accepts
+// tarballs. If the request names a snapshot, the document is read from the
+// snapshot so that long-running exports do not block writes to the store.
+func (s *Server) v1ExportGet(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	vars := mux.Vars(r)
+	pretty := getBoolParam(r.URL, types.ParamPrettyV1, true)
+
+	path, ok := storage.ParsePathEscaped("/" + strings.Trim(vars["path"], "/"))
+	if !ok {
+		writer.Error(w, http.StatusBadRequest, types.NewErrorV1(types.CodeInvalidParameter, "bad path: %v", vars["path"]))
+		return
+	}
+
+	snap, ok := s.getSnapshotParam(w, r)
+	if !ok {
+		return
+	}
+
+	store, _ := s.getStoreAndCompiler(snap)
+
+	txn, err := store.NewTransaction(ctx)
+	if err != nil {
+		writer.ErrorAuto(w, err)
+		return
+	}
+
+	defer store.Abort(ctx, txn)
+
+	if _, _, err := s.setRevisionHeader(ctx, w, store, txn); err != nil {
+		writer.ErrorAuto(w, err)
+		return
+	}
+
+	value, err := store.Read(ctx, txn, path)
+	if err != nil {
+		writer.ErrorAuto(w, err)
+		return
+	}
+
+	if !strings.Contains(r.Header.Get("Accept"), contentTypeGzip) {
+		writer.JSON(w, http.StatusOK, value, pretty)
+		return
+	}
+
+	obj, ok := value.(map[string]interface{})
+	if !ok {
+		writer.ErrorString(w, http.StatusBadRequest, types.CodeInvalidParameter, fmt.Errorf("tarball exports require an object document: %v", path))
+		return
+	}
+
+	w.Header().Set("Content-Type", contentTypeGzip)
+	w.WriteHeader(http.StatusOK)
+
+	// The status has been sent so errors cannot be reported to the client.
+	// Clients detect truncated exports when reading the tarball.
+	bundle.Write(w, bundle.Bundle{Data: obj})
+}
+
+// v1ImportPut replaces the base document at the path with the imported
+// document. The import is atomic: either the entire document is written or
+// the store is not modified.
+func (s *Server) v1ImportPut(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+
+	var value interface{}
+
+	if strings.HasPrefix(r.Header.Get("Content-Type"), contentTypeGzip) {
+		b, err := bundle.NewReader(r.Body).Read()
+		if err != nil {
+			writer.ErrorString(w, http.StatusBadRequest, types.CodeInvalidParameter, err)
+			return
+		} else if len(b.Modules) > 0 {
+			writer.ErrorString(w, http.StatusBadRequest, types.CodeInvalidParameter, fmt.Errorf("imports must not contain policies"))
+			return
+		}
+		value = b.Data
+	} else if err := util.NewJSONDecoder(r.Body).Decode(&value); err != nil {
+		writer.ErrorString(w, http.StatusBadRequest, types.CodeInvalidParameter, err)
+		return
+	}
+
+	s.putDocument(w, r, vars["path"], value)
+}
@@ -0,0 +1,243 @@
+// Copyright 2019 The OPA Authors.  All rights reserved.
+// Use of this source code is governed by an Apache2
+// license that can be found in the LICENSE file.
+
+// Package limiter provides handlers that limit the resources consumed by
+// incoming requests.
+package limiter
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"math"
+	"net"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/open-policy-agent/opa/server/types"
+	"github.com/open-policy-agent/opa/server/writer"
+)
+
+// Rate describes the number of requests per second allowed for an endpoint.
+// Burst is the number of requests that can be served at once. If Burst is
+// zero, it defaults to the limit (rounded up.)
+type Rate struct {
+	Limit float64
+	Burst int
+}
+
+// ParseRate parses a rate limit for an endpoint. The value has the form
+// <path-prefix>=<requests-per-second>[:<burst>], e.g., /v1/data=100:200.
+func ParseRate(s string) (string, Rate, error) {
+
+	parts := strings.SplitN(s, "=", 2)
+	if len(parts) != 2 || !strings.HasPrefix(parts[0], "/") {
+		return "", Rate{}, fmt.Errorf("rate limit must have form <path-prefix>=<requests-per-second>[:<burst>]")
+	}
+
+	prefix := parts[0]
+	parts = strings.SplitN(parts[1], ":", 2)
+
+	limit, err := strconv.ParseFloat(parts[0], 64)
+	if err != nil || limit <= 0 {
+		return "", Rate{}, fmt.Errorf("rate limit for %v must be a positive number", prefix)
+	}
+
+	var burst int
+	if len(parts) == 2 {
+		burst, err = strconv.Atoi(parts[1])
+		if err != nil || burst <= 0 {
+			return "", Rate{}, fmt.Errorf("burst for %v must be a positive integer", prefix)
+		}
+	}
+
+	return prefix, Rate{Limit: limit, Burst: burst}, nil
+}
+
+// RateLimited rejects requests that exceed the rate limit of the endpoint.
+// Endpoints are identified by path prefix. If multiple prefixes match a
+// request, the longest prefix is used.
+type RateLimited struct {
+	inner   http.Handler
+	buckets []*bucket
+	now     func() time.Time
+}
+
+// NewRateLimited returns a new RateLimited object.
+func NewRateLimited(inner http.Handler, rates map[string]Rate) *RateLimited {
+
+	buckets := make([]*bucket, 0, len(rates))
+
+	for prefix, rate := range rates {
+		burst := float64(rate.Burst)
+		if burst == 0 {
+			burst = math.Ceil(rate.Limit)
+		}
+		buckets = append(buckets, &bucket{
+			prefix: prefix,
+			limit:  rate.Limit,
+			burst:  burst,
+			tokens: burst,
+		})
+	}
+
+	sort.Slice(buckets, func(i, j int) bool {
+		return len(buckets[i].prefix) > len(buckets[j].prefix)
+	})
+
+	return &RateLimited{
+		inner:   inner,
+		buckets: buckets,
+		now:     time.Now,
+	}
+}
+
+func (h *RateLimited) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+
+	for _, b := range h.buckets {
+		if strings.HasPrefix(r.URL.Path, b.prefix) {
+			if !b.take(h.now()) {
+				w.Header().Set("Retry-After", "1")
+				writer.ErrorString(w, http.StatusTooManyRequests, types.CodeTooManyRequests, fmt.Errorf("rate limit exceeded for %v", b.prefix))
+				return
+			}
+			break
+		}
+	}
+
+	h.inner.ServeHTTP(w, r)
+}
+
+// bucket implements a token bucket that is refilled at the rate limit.
+type bucket struct {
+	prefix string
+	limit  float64
+	burst  float64
+	mtx    sync.Mutex
+	tokens float64
+	last   time.Time
+}
+
+func (b *bucket) take(now time.Time) bool {
+	b.mtx.Lock()
+	defer b.mtx.Unlock()
+
+	if !b.last.IsZero() {
+		b.tokens = math.Min(b.burst, b.tokens+now.Sub(b.last).Seconds()*b.limit)
+	}
+
+	b.last = now
+
+	if b.tokens < 1 {
+		return false
+	}
+
+	b.tokens--
+	return true
+}
+
+// SizeLimited rejects requests with bodies larger than the maximum size.
+type SizeLimited struct {
+	inner http.Handler
+	max   int64
+}
+
+// NewSizeLimited returns a new SizeLimited object.
+func NewSizeLimited(inner http.Handler, max int64) *SizeLimited {
+	return &SizeLimited{
+		inner: inner,
+		max:   max,
+	}
+}
+
+func (h *SizeLimited) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+
+	if r.ContentLength > h.max {
+		h.reject(w)
+		return
+	}
+
+	// Requests that do not declare their size (e.g., chunked requests) fail
+	// when the handler reads past the maximum size. The handler reports the
+	// read error, so its response is replaced with the same response that is
+	// sent for requests that declare a larger size.
+	body := &sizeLimitedBody{ReadCloser: http.MaxBytesReader(w, r.Body, h.max), max: h.max}
+	r.Body = body
+	h.inner.ServeHTTP(&sizeLimitedWriter{ResponseWriter: w, body: body, limiter: h}, r)
+}
+
+func (h *SizeLimited) reject(w http.ResponseWriter) {
+	writer.ErrorString(w, http.StatusRequestEntityTooLarge, types.CodeRequestTooLarge, fmt.Errorf("request body exceeds maximum size of %d bytes", h.max))
+}
+
+// sizeLimitedBody records whether the request body exceeded the maximum size.
+type sizeLimitedBody struct {
+	io.ReadCloser
+	max      int64
+	n        int64
+	exceeded bool
+}
+
+func (b *sizeLimitedBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	b.n += int64(n)
+	if err != nil && err != io.EOF && b.n >= b.max {
+		b.exceeded = true
+	}
+	return n, err
+}
+
+// sizeLimitedWriter replaces the handler's response with a 413 response if
+// the request body exceeded the maximum size before the response was started.
+type sizeLimitedWriter struct {
+	http.ResponseWriter
+	body        *sizeLimitedBody
+	limiter     *SizeLimited
+	wroteHeader bool
+	rejected    bool
+}
+
+func (w *sizeLimitedWriter) WriteHeader(code int) {
+	if w.wroteHeader {
+		return
+	}
+	w.wroteHeader = true
+	if w.body.exceeded {
+		w.rejected = true
+		w.limiter.reject(w.ResponseWriter)
+		return
+	}
+	w.ResponseWriter.WriteHeader(code)
+}
+
+func (w *sizeLimitedWriter) Write(bs []byte) (int, error) {
+	if !w.wroteHeader {
+		w.WriteHeader(http.StatusOK)
+	}
+	if w.rejected {
+		return len(bs), nil
+	}
+	return w.ResponseWriter.Write(bs)
+}
+
+func (w *sizeLimitedWriter) Flush() {
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// Hijack allows handlers that take over the connection (e.g., watches) to
+// bypass the limiter.
+func (w *sizeLimitedWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	h, ok := w.ResponseWriter.(http.Hijacker)
+	if !ok {
+		return nil, nil, fmt.Errorf("response writer does not support hijacking")
+	}
+	w.wroteHeader = true
+	return h.Hijack()
+}
@@ -0,0 +1,162 @@
+// Copyright 2019 The OPA Authors.  All rights reserved.
+// Use of this source code is governed by an Apache2
+// license that can be found in the LICENSE file.
+
+package limiter
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+type mockHandler struct {
+	calls int
+	body  string
+	err   error
+}
+
+func (h *mockHandler) ServeHTTP(_ http.ResponseWriter, r *http.Request) {
+	h.calls++
+	bs, err := ioutil.ReadAll(r.Body)
+	h.body, h.err = string(bs), err
+}
+
+func TestParseRate(t *testing.T) {
+
+	tests := []struct {
+		input  string
+		prefix string
+		rate   Rate
+		err    string
+	}{
+		{input: "/v1/data=100", prefix: "/v1/data", rate: Rate{Limit: 100}},
+		{input: "/v1/data=0.5:10", prefix: "/v1/data", rate: Rate{Limit: 0.5, Burst: 10}},
+		{input: "/=1", prefix: "/", rate: Rate{Limit: 1}},
+		{input: "v1/data=100", err: "rate limit must have form"},
+		{input: "/v1/data", err: "rate limit must have form"},
+		{input: "/v1/data=x", err: "rate limit for /v1/data must be a positive number"},
+		{input: "/v1/data=-1", err: "rate limit for /v1/data must be a positive number"},
+		{input: "/v1/data=1:0", err: "burst for /v1/data must be a positive integer"},
+	}
+
+	for _, tc := range tests {
+		prefix, rate, err := ParseRate(tc.input)
+		if tc.err != "" {
+			if err == nil || !strings.Contains(err.Error(), tc.err) {
+				t.Errorf("%v: expected error %q but got: %v", tc.input, tc.err, err)
+			}
+		} else if err != nil {
+			t.Errorf("%v: unexpected error: %v", tc.input, err)
+		} else if prefix != tc.prefix || rate != tc.rate {
+			t.Errorf("%v: expected %v %v but got %v %v", tc.input, tc.prefix, tc.rate, prefix, rate)
+		}
+	}
+}
+
+func TestRateLimited(t *testing.T) {
+
+	inner := &mockHandler{}
+	h := NewRateLimited(inner, map[string]Rate{
+		"/v1":      {Limit: 1},
+		"/v1/data": {Limit: 2, Burst: 3},
+	})
+
+	now := time.Unix(0, 0)
+	h.now = func() time.Time { return now }
+
+	expect := func(path string, code int) {
+		t.Helper()
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+		if rec.Code != code {
+			t.Fatalf("Expected %v for %v but got %v: %v", code, path, rec.Code, rec.Body)
+		}
+	}
+
+	// The longest prefix determines the limit.
+	expect("/v1/data/x", 200)
+	expect("/v1/data/y", 200)
+	expect("/v1/data", 200)
+	expect("/v1/data", 429)
+	expect("/v1/policies", 200)
+	expect("/v1/policies", 429)
+
+	// Requests not matching any prefix are not limited.
+	expect("/health", 200)
+	expect("/health", 200)
+
+	// Tokens are refilled over time.
+	now = now.Add(500 * time.Millisecond)
+	expect("/v1/data", 200)
+	expect("/v1/data", 429)
+	expect("/v1/policies", 429)
+
+	now = now.Add(500 * time.Millisecond)
+	expect("/v1/policies", 200)
+
+	if inner.calls != 8 {
+		t.Fatalf("Expected 8 calls to inner handler but got %v", inner.calls)
+	}
+}
+
+func TestSizeLimited(t *testing.T) {
+
+	inner := &mockHandler{}
+	h := NewSizeLimited(inner, 5)
+
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/v1/data", strings.NewReader("12345")))
+
+	if rec.Code != 200 || inner.body != "12345" || inner.err != nil {
+		t.Fatalf("Expected request to succeed but got: %v (body: %q, err: %v)", rec.Code, inner.body, inner.err)
+	}
+
+	rec = httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/v1/data", strings.NewReader("123456")))
+
+	if rec.Code != http.StatusRequestEntityTooLarge || !strings.Contains(rec.Body.String(), "request_too_large") {
+		t.Fatalf("Expected request to be rejected but got: %v %v", rec.Code, rec.Body)
+	}
+
+	// Requests without a content length are limited while reading.
+	req := httptest.NewRequest(http.MethodPost, "/v1/data", strings.NewReader("123456"))
+	req.ContentLength = -1
+
+	rec = httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+
+	if inner.err == nil || inner.calls != 2 {
+		t.Fatalf("Expected read error but got: %q (calls: %v)", inner.body, inner.calls)
+	}
+
+	// Handlers that report the read error are replaced with the same
+	// response as requests that declare a larger size.
+	h = NewSizeLimited(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if _, err := ioutil.ReadAll(r.Body); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}), 5)
+
+	for _, body := range []string{"12345", "123456"} {
+		req = httptest.NewRequest(http.MethodPost, "/v1/data", strings.NewReader(body))
+		req.ContentLength = -1
+
+		rec = httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+
+		exp := http.StatusOK
+		if len(body) > 5 {
+			exp = http.StatusRequestEntityTooLarge
+		}
+
+		if rec.Code != exp || (exp != http.StatusOK && !strings.Contains(rec.Body.String(), "request_too_large")) {
+			t.Fatalf("Expected %v for %q but got: %v %v", exp, body, rec.Code, rec.Body)
+		}
+	}
+}
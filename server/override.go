@@ -0,0 +1,208 @@
+// Copyright 2020 The OPA Authors.  All rights reserved.
+// Use of this source code is governed by an Apache2
+// license that can be found in the LICENSE file.
+
+package server
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/sirupsen/logrus"
+
+	"github.com/open-policy-agent/opa/ast"
+	"github.com/open-policy-agent/opa/metrics"
+	"github.com/open-policy-agent/opa/server/types"
+	"github.com/open-policy-agent/opa/server/writer"
+	"github.com/open-policy-agent/opa/util"
+)
+
+// override forces the decision at a path to a fixed value until it expires,
+// e.g., to allow or deny all requests while a bad policy is rolled back.
+type override struct {
+	path    string
+	value   *interface{}
+	reason  string
+	created time.Time
+	expires time.Time
+}
+
+func (o *override) v1() *types.OverrideV1 {
+	return &types.OverrideV1{
+		Path:    o.path,
+		Value:   o.value,
+		Reason:  o.reason,
+		Created: o.created,
+		Expires: o.expires,
+	}
+}
+
+// audit logs a change to the override. Overrides change the decisions served
+// to every caller so changes are always logged.
+func (o *override) audit(msg string, remoteAddr string) {
+	fields := logrus.Fields{
+		"path":    o.path,
+		"value":   string(util.MustMarshalJSON(o.value)),
+		"reason":  o.reason,
+		"expires": o.expires.Format(time.RFC3339Nano),
+	}
+	if remoteAddr != "" {
+		fields["requested_by"] = remoteAddr
+	}
+	logrus.WithFields(fields).Warn(msg)
+}
+
+// v1OverridesList writes the active overrides sorted by path.
+func (s *Server) v1OverridesList(w http.ResponseWriter, r *http.Request) {
+	pretty := getBoolParam(r.URL, types.ParamPrettyV1, true)
+	now := time.Now()
+
+	s.overridesMtx.Lock()
+	result := make([]*types.OverrideV1, 0, len(s.overrides))
+	for key, o := range s.overrides {
+		if s.expireOverride(key, o, now) {
+			continue
+		}
+		result = append(result, o.v1())
+	}
+	s.overridesMtx.Unlock()
+
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].Path < result[j].Path
+	})
+
+	writer.JSON(w, http.StatusOK, types.OverrideListResponseV1{Result: result}, pretty)
+}
+
+// v1OverridesGet writes the active override for the path.
+func (s *Server) v1OverridesGet(w http.ResponseWriter, r *http.Request) {
+	pretty := getBoolParam(r.URL, types.ParamPrettyV1, true)
+	path := stringPathToDataRef(mux.Vars(r)["path"])
+
+	o, ok := s.getOverride(path, time.Now())
+	if !ok {
+		writer.ErrorString(w, http.StatusNotFound, types.CodeResourceNotFound, fmt.Errorf("override not found: %v", path))
+		return
+	}
+
+	writer.JSON(w, http.StatusOK, types.OverrideResponseV1{Result: o.v1()}, pretty)
+}
+
+// v1OverridesPut creates an override for the path. If an override for the
+// path already exists, it is replaced.
+func (s *Server) v1OverridesPut(w http.ResponseWriter, r *http.Request) {
+	pretty := getBoolParam(r.URL, types.ParamPrettyV1, true)
+	path := stringPathToDataRef(mux.Vars(r)["path"])
+
+	var request types.OverrideRequestV1
+	if err := util.NewJSONDecoder(r.Body).Decode(&request); err != nil {
+		writer.ErrorString(w, http.StatusBadRequest, types.CodeInvalidParameter, err)
+		return
+	}
+
+	if request.Value == nil {
+		writer.ErrorString(w, http.StatusBadRequest, types.CodeInvalidParameter, fmt.Errorf("missing override value"))
+		return
+	}
+
+	ttl, err := time.ParseDuration(request.TTL)
+	if err != nil || ttl <= 0 {
+		writer.ErrorString(w, http.StatusBadRequest, types.CodeInvalidParameter, fmt.Errorf("invalid override ttl %q: must be a positive duration", request.TTL))
+		return
+	}
+
+	now := time.Now().UTC()
+
+	o := &override{
+		path:    strings.Trim(mux.Vars(r)["path"], "/"),
+		value:   request.Value,
+		reason:  request.Reason,
+		created: now,
+		expires: now.Add(ttl),
+	}
+
+	s.overridesMtx.Lock()
+	s.overrides[path.String()] = o
+	s.overridesMtx.Unlock()
+
+	o.audit("Decision override created.", r.RemoteAddr)
+
+	writer.JSON(w, http.StatusOK, types.OverrideResponseV1{Result: o.v1()}, pretty)
+}
+
+// v1OverridesDelete deletes the override for the path. Decisions for the path
+// are evaluated again.
+func (s *Server) v1OverridesDelete(w http.ResponseWriter, r *http.Request) {
+	path := stringPathToDataRef(mux.Vars(r)["path"])
+	key := path.String()
+	now := time.Now()
+
+	s.overridesMtx.Lock()
+	o, ok := s.overrides[key]
+	if ok && !s.expireOverride(key, o, now) {
+		delete(s.overrides, key)
+	} else {
+		ok = false
+	}
+	s.overridesMtx.Unlock()
+
+	if !ok {
+		writer.ErrorString(w, http.StatusNotFound, types.CodeResourceNotFound, fmt.Errorf("override not found: %v", path))
+		return
+	}
+
+	o.audit("Decision override deleted.", r.RemoteAddr)
+
+	writer.Bytes(w, http.StatusNoContent, nil)
+}
+
+// getOverride returns the active override for the path.
+func (s *Server) getOverride(path ast.Ref, now time.Time) (*override, bool) {
+	key := path.String()
+
+	s.overridesMtx.Lock()
+	defer s.overridesMtx.Unlock()
+
+	o, ok := s.overrides[key]
+	if !ok || s.expireOverride(key, o, now) {
+		return nil, false
+	}
+
+	return o, true
+}
+
+// expireOverride removes the override if it has expired. The caller must hold
+// the overrides lock.
+func (s *Server) expireOverride(key string, o *override, now time.Time) bool {
+	if now.Before(o.expires) {
+		return false
+	}
+	delete(s.overrides, key)
+	o.audit("Decision override expired.", "")
+	return true
+}
+
+// writeOverride writes the value of the active override for the path as the
+// decision. The decision is logged as usual. If the path is not overridden,
+// false is returned.
+func (s *Server) writeOverride(ctx context.Context, w http.ResponseWriter, r *http.Request, logger decisionLogger, decisionID string, path ast.Ref, input *interface{}, m metrics.Metrics, pretty bool) bool {
+	o, ok := s.getOverride(path, time.Now())
+	if !ok {
+		return false
+	}
+
+	m.Timer(metrics.ServerHandler).Stop()
+
+	if err := logger.withOverride().Log(ctx, nil, decisionID, r.RemoteAddr, path.String(), "", input, o.value, nil, m); err != nil {
+		writer.ErrorAuto(w, err)
+		return true
+	}
+
+	writer.JSON(w, http.StatusOK, types.DataResponseV1{DecisionID: decisionID, Result: o.value}, pretty)
+	return true
+}
@@ -20,6 +20,7 @@ import (
 	"net/http/pprof"
 	"net/url"
 	"os"
+	"sort"
 	"strconv"
 	"strings"
 	"sync"
@@ -30,16 +31,20 @@ import (
 
 	"github.com/open-policy-agent/opa/ast"
 	"github.com/open-policy-agent/opa/bundle"
+	"github.com/open-policy-agent/opa/cost"
+	"github.com/open-policy-agent/opa/internal/websocket"
 	"github.com/open-policy-agent/opa/metrics"
 	"github.com/open-policy-agent/opa/plugins"
 	bundlePlugin "github.com/open-policy-agent/opa/plugins/bundle"
 	"github.com/open-policy-agent/opa/rego"
 	"github.com/open-policy-agent/opa/server/authorizer"
 	"github.com/open-policy-agent/opa/server/identifier"
+	"github.com/open-policy-agent/opa/server/limiter"
 	"github.com/open-policy-agent/opa/server/types"
 	"github.com/open-policy-agent/opa/server/writer"
 	"github.com/open-policy-agent/opa/storage"
 	"github.com/open-policy-agent/opa/topdown"
+	"github.com/open-policy-agent/opa/topdown/builtins"
 	"github.com/open-policy-agent/opa/topdown/lineage"
 	"github.com/open-policy-agent/opa/util"
 	"github.com/open-policy-agent/opa/version"
@@ -69,14 +74,22 @@ const (
 
 // Set of handlers for use in the "handler" dimension of the duration metric.
 const (
-	PromHandlerV0Data     = "v0/data"
-	PromHandlerV1Data     = "v1/data"
-	PromHandlerV1Query    = "v1/query"
-	PromHandlerV1Policies = "v1/policies"
-	PromHandlerV1Compile  = "v1/compile"
-	PromHandlerIndex      = "index"
-	PromHandlerCatch      = "catchall"
-	PromHandlerHealth     = "health"
+	PromHandlerV0Data       = "v0/data"
+	PromHandlerV1Data       = "v1/data"
+	PromHandlerV1Query      = "v1/query"
+	PromHandlerV1Policies   = "v1/policies"
+	PromHandlerV1Compile    = "v1/compile"
+	PromHandlerV1Subscribe  = "v1/subscribe"
+	PromHandlerV1Export     = "v1/export"
+	PromHandlerV1Import     = "v1/import"
+	PromHandlerV1Violations = "v1/violations"
+	PromHandlerV1Storage    = "v1/storage"
+	PromHandlerV1Snapshots  = "v1/snapshots"
+	PromHandlerV1Overrides  = "v1/overrides"
+	PromHandlerV1Canary     = "v1/canary"
+	PromHandlerIndex        = "index"
+	PromHandlerCatch        = "catchall"
+	PromHandlerHealth       = "health"
 )
 
 // map of unsafe builtins
@@ -106,10 +119,26 @@ type Server struct {
 	errLimit          int
 	pprofEnabled      bool
 	runtime           *ast.Term
+	interQueryCache   *builtins.InterQueryCache
 	httpListeners     []httpListener
 	bundleStatuses    map[string]*bundlePlugin.Status
 	bundleStatusMtx   sync.RWMutex
 	metrics           Metrics
+	rateLimits        map[string]limiter.Rate
+	maxBodySize       int64
+	maxQueryExprs     int
+	maxQueryResults   int
+	maxQueryCost      float64
+	etagPrefix        string
+	decisions         *decisionCache
+	inputSchemas      *inputSchemas
+	snapshots         map[string]*snapshot
+	snapshotsMtx      sync.RWMutex
+	overrides         map[string]*override
+	overridesMtx      sync.Mutex
+	canary            *canary
+	canaryMtx         sync.RWMutex
+	canaryEvals       sync.WaitGroup
 }
 
 // Metrics defines the interface that the server requires for recording HTTP
@@ -151,6 +180,16 @@ func (s *Server) Init(ctx context.Context) (*Server, error) {
 		s.Handler = identifier.NewTLSBased(s.Handler)
 	}
 
+	// Add limit handlers last so that requests exceeding the limits are
+	// rejected before any other processing.
+	if s.maxBodySize > 0 {
+		s.Handler = limiter.NewSizeLimited(s.Handler, s.maxBodySize)
+	}
+
+	if len(s.rateLimits) > 0 {
+		s.Handler = limiter.NewRateLimited(s.Handler, s.rateLimits)
+	}
+
 	txn, err := s.store.NewTransaction(ctx, storage.WriteParams)
 	if err != nil {
 		return nil, err
@@ -174,6 +213,10 @@ func (s *Server) Init(ctx context.Context) (*Server, error) {
 	}
 
 	s.partials = map[string]rego.PartialResult{}
+	s.snapshots = map[string]*snapshot{}
+	s.overrides = map[string]*override{}
+	s.interQueryCache = builtins.NewInterQueryCache(0)
+	s.etagPrefix = strconv.FormatInt(time.Now().UnixNano(), 36)
 
 	bp := bundlePlugin.Lookup(s.manager)
 	if bp != nil {
@@ -286,7 +329,9 @@ func (s *Server) WithCompilerErrorLimit(limit int) *Server {
 	return s
 }
 
-// WithPprofEnabled sets whether pprof endpoints are enabled
+// WithPprofEnabled sets whether pprof endpoints are enabled. If enabled,
+// query evaluation is also labelled so that profiles can be attributed to
+// queries (see rego.ProfileLabels).
 func (s *Server) WithPprofEnabled(pprofEnabled bool) *Server {
 	s.pprofEnabled = pprofEnabled
 	return s
@@ -320,6 +365,69 @@ func (s *Server) WithRuntime(term *ast.Term) *Server {
 	return s
 }
 
+// WithRateLimits sets the rate limits for the server endpoints. The limits are
+// keyed by path prefix, e.g., /v1/data.
+func (s *Server) WithRateLimits(limits map[string]limiter.Rate) *Server {
+	s.rateLimits = limits
+	return s
+}
+
+// WithMaxRequestBodySize sets the maximum size (in bytes) of request bodies
+// accepted by the server. If the size is zero, request bodies are not limited.
+func (s *Server) WithMaxRequestBodySize(size int64) *Server {
+	s.maxBodySize = size
+	return s
+}
+
+// WithMaxQueryExpressions sets the maximum number of expressions (including
+// expressions nested inside of comprehensions) in ad-hoc queries accepted by
+// the server. If the maximum is zero, queries are not limited.
+func (s *Server) WithMaxQueryExpressions(n int) *Server {
+	s.maxQueryExprs = n
+	return s
+}
+
+// WithMaxQueryCost sets the maximum estimated cost (see the cost package) of
+// ad-hoc queries accepted by the server. Queries are estimated against the
+// store and the input before they are executed. If the maximum is zero,
+// queries are not limited.
+func (s *Server) WithMaxQueryCost(cost float64) *Server {
+	s.maxQueryCost = cost
+	return s
+}
+
+// WithMaxQueryResults sets the maximum number of results that ad-hoc queries
+// may produce. Queries that produce more results fail. If the maximum is zero,
+// results are not limited.
+func (s *Server) WithMaxQueryResults(n int) *Server {
+	s.maxQueryResults = n
+	return s
+}
+
+// WithDecisionCache enables caching of Data API decisions. Decisions for the
+// same path and input are served from the cache for the ttl unless the store
+// is written to. At most maxEntries decisions are cached. If the ttl is zero,
+// decisions are not cached.
+func (s *Server) WithDecisionCache(ttl time.Duration, maxEntries int) *Server {
+	s.decisions = newDecisionCache(ttl, maxEntries)
+	return s
+}
+
+// WithInputValidation enables validation of Data API inputs. The input for a
+// path is validated against the schema inferred from the rules that the path
+// refers to (see dependencies.Input.) Requests with inputs that do not
+// conform to the schema are rejected before evaluation.
+func (s *Server) WithInputValidation(enabled bool) *Server {
+	s.inputSchemas = newInputSchemas(enabled)
+	return s
+}
+
+// DecisionCacheStats returns the number of Data API decisions served from the
+// decision cache (hits) and evaluated (misses.)
+func (s *Server) DecisionCacheStats() (hits uint64, misses uint64) {
+	return s.decisions.Stats()
+}
+
 // WithRouter sets the mux.Router to attach OPA's HTTP API routes onto. If a
 // router is not supplied, the server will create it's own.
 func (s *Server) WithRouter(router *mux.Router) *Server {
@@ -544,24 +652,46 @@ func (s *Server) initRouter() {
 		router.HandleFunc("/debug/pprof/symbol", pprof.Symbol)
 		router.HandleFunc("/debug/pprof/trace", pprof.Trace)
 	}
-	s.registerHandler(router, 0, "/data/{path:.+}", http.MethodPost, s.instrumentHandler(s.v0DataPost, PromHandlerV0Data))
-	s.registerHandler(router, 0, "/data", http.MethodPost, s.instrumentHandler(s.v0DataPost, PromHandlerV0Data))
-	s.registerHandler(router, 1, "/data/{path:.+}", http.MethodDelete, s.instrumentHandler(s.v1DataDelete, PromHandlerV1Data))
-	s.registerHandler(router, 1, "/data/{path:.+}", http.MethodPut, s.instrumentHandler(s.v1DataPut, PromHandlerV1Data))
-	s.registerHandler(router, 1, "/data", http.MethodPut, s.instrumentHandler(s.v1DataPut, PromHandlerV1Data))
-	s.registerHandler(router, 1, "/data/{path:.+}", http.MethodGet, s.instrumentHandler(s.v1DataGet, PromHandlerV1Data))
-	s.registerHandler(router, 1, "/data", http.MethodGet, s.instrumentHandler(s.v1DataGet, PromHandlerV1Data))
-	s.registerHandler(router, 1, "/data/{path:.+}", http.MethodPatch, s.instrumentHandler(s.v1DataPatch, PromHandlerV1Data))
-	s.registerHandler(router, 1, "/data", http.MethodPatch, s.instrumentHandler(s.v1DataPatch, PromHandlerV1Data))
-	s.registerHandler(router, 1, "/data/{path:.+}", http.MethodPost, s.instrumentHandler(s.v1DataPost, PromHandlerV1Data))
-	s.registerHandler(router, 1, "/data", http.MethodPost, s.instrumentHandler(s.v1DataPost, PromHandlerV1Data))
+	s.registerHandler(router, 0, "/data/{path:.+}", http.MethodPost, s.instrumentHandler(compressed(s.v0DataPost), PromHandlerV0Data))
+	s.registerHandler(router, 0, "/data", http.MethodPost, s.instrumentHandler(compressed(s.v0DataPost), PromHandlerV0Data))
+	s.registerHandler(router, 1, "/data/{path:.+}", http.MethodDelete, s.instrumentHandler(compressed(s.v1DataDelete), PromHandlerV1Data))
+	s.registerHandler(router, 1, "/data/{path:.+}", http.MethodPut, s.instrumentHandler(compressed(s.v1DataPut), PromHandlerV1Data))
+	s.registerHandler(router, 1, "/data", http.MethodPut, s.instrumentHandler(compressed(s.v1DataPut), PromHandlerV1Data))
+	s.registerHandler(router, 1, "/data/{path:.+}", http.MethodGet, s.instrumentHandler(compressed(s.v1DataGet), PromHandlerV1Data))
+	s.registerHandler(router, 1, "/data", http.MethodGet, s.instrumentHandler(compressed(s.v1DataGet), PromHandlerV1Data))
+	s.registerHandler(router, 1, "/data/{path:.+}", http.MethodPatch, s.instrumentHandler(compressed(s.v1DataPatch), PromHandlerV1Data))
+	s.registerHandler(router, 1, "/data", http.MethodPatch, s.instrumentHandler(compressed(s.v1DataPatch), PromHandlerV1Data))
+	s.registerHandler(router, 1, "/data/{path:.+}", http.MethodPost, s.instrumentHandler(compressed(s.v1DataPost), PromHandlerV1Data))
+	s.registerHandler(router, 1, "/data", http.MethodPost, s.instrumentHandler(compressed(s.v1DataPost), PromHandlerV1Data))
+	s.registerHandler(router, 1, "/export/{path:.+}", http.MethodGet, s.instrumentHandler(s.v1ExportGet, PromHandlerV1Export))
+	s.registerHandler(router, 1, "/export", http.MethodGet, s.instrumentHandler(s.v1ExportGet, PromHandlerV1Export))
+	s.registerHandler(router, 1, "/import/{path:.+}", http.MethodPut, s.instrumentHandler(s.v1ImportPut, PromHandlerV1Import))
+	s.registerHandler(router, 1, "/import", http.MethodPut, s.instrumentHandler(s.v1ImportPut, PromHandlerV1Import))
 	s.registerHandler(router, 1, "/policies", http.MethodGet, s.instrumentHandler(s.v1PoliciesList, PromHandlerV1Policies))
 	s.registerHandler(router, 1, "/policies/{path:.+}", http.MethodDelete, s.instrumentHandler(s.v1PoliciesDelete, PromHandlerV1Policies))
 	s.registerHandler(router, 1, "/policies/{path:.+}", http.MethodGet, s.instrumentHandler(s.v1PoliciesGet, PromHandlerV1Policies))
 	s.registerHandler(router, 1, "/policies/{path:.+}", http.MethodPut, s.instrumentHandler(s.v1PoliciesPut, PromHandlerV1Policies))
 	s.registerHandler(router, 1, "/query", http.MethodGet, s.instrumentHandler(s.v1QueryGet, PromHandlerV1Query))
 	s.registerHandler(router, 1, "/query", http.MethodPost, s.instrumentHandler(s.v1QueryPost, PromHandlerV1Query))
+	s.registerHandler(router, 1, "/query/validate", http.MethodPost, s.instrumentHandler(s.v1QueryValidatePost, PromHandlerV1Query))
 	s.registerHandler(router, 1, "/compile", http.MethodPost, s.instrumentHandler(s.v1CompilePost, PromHandlerV1Compile))
+	s.registerHandler(router, 1, "/violations/{path:.+}", http.MethodPost, s.instrumentHandler(compressed(s.v1ViolationsPost), PromHandlerV1Violations))
+	s.registerHandler(router, 1, "/storage/stats/{path:.+}", http.MethodGet, s.instrumentHandler(s.v1StorageStatsGet, PromHandlerV1Storage))
+	s.registerHandler(router, 1, "/storage/stats", http.MethodGet, s.instrumentHandler(s.v1StorageStatsGet, PromHandlerV1Storage))
+	s.registerHandler(router, 1, "/storage/compact", http.MethodPost, s.instrumentHandler(s.v1StorageCompactPost, PromHandlerV1Storage))
+	s.registerHandler(router, 1, "/snapshots", http.MethodGet, s.instrumentHandler(s.v1SnapshotsList, PromHandlerV1Snapshots))
+	s.registerHandler(router, 1, "/snapshots/{name}", http.MethodGet, s.instrumentHandler(s.v1SnapshotsGet, PromHandlerV1Snapshots))
+	s.registerHandler(router, 1, "/snapshots/{name}", http.MethodPut, s.instrumentHandler(s.v1SnapshotsPut, PromHandlerV1Snapshots))
+	s.registerHandler(router, 1, "/snapshots/{name}", http.MethodDelete, s.instrumentHandler(s.v1SnapshotsDelete, PromHandlerV1Snapshots))
+	s.registerHandler(router, 1, "/overrides", http.MethodGet, s.instrumentHandler(s.v1OverridesList, PromHandlerV1Overrides))
+	s.registerHandler(router, 1, "/overrides/{path:.+}", http.MethodGet, s.instrumentHandler(s.v1OverridesGet, PromHandlerV1Overrides))
+	s.registerHandler(router, 1, "/overrides/{path:.+}", http.MethodPut, s.instrumentHandler(s.v1OverridesPut, PromHandlerV1Overrides))
+	s.registerHandler(router, 1, "/overrides/{path:.+}", http.MethodDelete, s.instrumentHandler(s.v1OverridesDelete, PromHandlerV1Overrides))
+	s.registerHandler(router, 1, "/canary", http.MethodGet, s.instrumentHandler(s.v1CanaryGet, PromHandlerV1Canary))
+	s.registerHandler(router, 1, "/canary", http.MethodPut, s.instrumentHandler(s.v1CanaryPut, PromHandlerV1Canary))
+	s.registerHandler(router, 1, "/canary", http.MethodDelete, s.instrumentHandler(s.v1CanaryDelete, PromHandlerV1Canary))
+	s.registerHandler(router, 1, "/canary/promote", http.MethodPost, s.instrumentHandler(s.v1CanaryPromotePost, PromHandlerV1Canary))
+	s.registerHandler(router, 1, "/subscribe", http.MethodGet, s.instrumentHandler(s.v1SubscribeGet, PromHandlerV1Subscribe))
 	router.Handle("/", s.instrumentHandler(http.HandlerFunc(s.unversionedPost), PromHandlerIndex)).Methods(http.MethodPost)
 	router.Handle("/", s.instrumentHandler(http.HandlerFunc(s.indexGet), PromHandlerIndex)).Methods(http.MethodGet)
 	// These are catch all handlers that respond 405 for resources that exist but the method is not allowed
@@ -597,7 +727,7 @@ func (s *Server) instrumentHandler(handler func(http.ResponseWriter, *http.Reque
 	return http.HandlerFunc(handler)
 }
 
-func (s *Server) execQuery(ctx context.Context, r *http.Request, txn storage.Transaction, decisionID string, parsedQuery ast.Body, input ast.Value, m metrics.Metrics, explainMode types.ExplainModeV1, includeMetrics, includeInstrumentation, pretty bool) (results types.QueryResponseV1, err error) {
+func (s *Server) execQuery(ctx context.Context, r *http.Request, txn storage.Transaction, decisionID string, parsedQuery ast.Body, input ast.Value, m metrics.Metrics, explainMode types.ExplainModeV1, includeMetrics, includeInstrumentation, includeExpressions, pretty bool) (results types.QueryResponseV1, err error) {
 
 	logger := s.getDecisionLogger()
 
@@ -617,6 +747,10 @@ func (s *Server) execQuery(ctx context.Context, r *http.Request, txn storage.Tra
 
 	compiler := s.getCompiler()
 
+	if err := s.checkQueryCost(ctx, txn, compiler, parsedQuery, input); err != nil {
+		return results, err
+	}
+
 	rego := rego.New(
 		rego.Store(s.store),
 		rego.Transaction(txn),
@@ -625,9 +759,12 @@ func (s *Server) execQuery(ctx context.Context, r *http.Request, txn storage.Tra
 		rego.ParsedInput(input),
 		rego.Metrics(m),
 		rego.Instrument(includeInstrumentation),
+		rego.ProfileLabels(s.pprofEnabled),
 		rego.Tracer(buf),
 		rego.Runtime(s.runtime),
+		rego.InterQueryBuiltinCache(s.interQueryCache),
 		rego.UnsafeBuiltins(unsafeBuiltinsMap),
+		rego.ResultLimit(s.maxQueryResults),
 	)
 
 	output, err := rego.Eval(ctx)
@@ -637,7 +774,18 @@ func (s *Server) execQuery(ctx context.Context, r *http.Request, txn storage.Tra
 	}
 
 	for _, result := range output {
-		results.Result = append(results.Result, result.Bindings.WithoutWildcards())
+		if !includeExpressions {
+			results.Result = append(results.Result, result.Bindings.WithoutWildcards())
+			continue
+		}
+		values := make([]interface{}, len(result.Expressions))
+		for i := range result.Expressions {
+			values[i] = result.Expressions[i].Value
+		}
+		results.Result = append(results.Result, map[string]interface{}{
+			"bindings":    map[string]interface{}(result.Bindings.WithoutWildcards()),
+			"expressions": values,
+		})
 	}
 
 	if includeMetrics || includeInstrumentation {
@@ -688,7 +836,7 @@ func (s *Server) indexGet(w http.ResponseWriter, r *http.Request) {
 		input = t.Value
 	}
 
-	parsedQuery, _ := validateQuery(qStr)
+	parsedQuery, _ := s.validateQuery(qStr)
 
 	txn, err := s.store.NewTransaction(ctx)
 	if err != nil {
@@ -698,7 +846,7 @@ func (s *Server) indexGet(w http.ResponseWriter, r *http.Request) {
 
 	defer s.store.Abort(ctx, txn)
 
-	results, err := s.execQuery(ctx, r, txn, decisionID, parsedQuery, input, nil, explainMode, false, false, true)
+	results, err := s.execQuery(ctx, r, txn, decisionID, parsedQuery, input, nil, explainMode, false, false, false, true)
 	if err != nil {
 		renderQueryResult(w, nil, err, t0)
 		return
@@ -716,6 +864,7 @@ func (s *Server) reload(ctx context.Context, txn storage.Transaction, event stor
 	// reset some cached info
 	s.partials = map[string]rego.PartialResult{}
 	s.revisions = map[string]string{}
+	s.decisions.Invalidate()
 
 	// read all bundle revisions from storage (if any exist)
 	names, err := bundle.ReadBundleNamesFromStore(ctx, s.store, txn)
@@ -738,6 +887,73 @@ func (s *Server) reload(ctx context.Context, txn storage.Transaction, event stor
 	}
 }
 
+// etag returns the entity tag for documents read from the store revision. The
+// prefix distinguishes server instances since revisions restart at zero.
+func (s *Server) etag(rev uint64) string {
+	return fmt.Sprintf(`W/"%v-%v"`, s.etagPrefix, rev)
+}
+
+// setRevisionHeader sets the store revision header on the response if the
+// store tracks revisions. The revision is returned so that callers can derive
+// other headers from it.
+func (s *Server) setRevisionHeader(ctx context.Context, w http.ResponseWriter, store storage.Store, txn storage.Transaction) (uint64, bool, error) {
+	rev, ok, err := storage.Revision(ctx, store, txn)
+	if err != nil || !ok {
+		return 0, false, err
+	}
+	w.Header().Set(types.HeaderStoreRevisionV1, strconv.FormatUint(rev, 10))
+	return rev, true, nil
+}
+
+// checkIfMatch returns a precondition failed error if the request includes an
+// If-Match header that does not match the entity tag of the store revision.
+// The wildcard tag matches if the document at path exists.
+func (s *Server) checkIfMatch(ctx context.Context, txn storage.Transaction, r *http.Request, path storage.Path) error {
+
+	header := r.Header.Get("If-Match")
+	if header == "" {
+		return nil
+	}
+
+	if strings.TrimSpace(header) == "*" {
+		if _, err := s.store.Read(ctx, txn, path); err != nil {
+			if storage.IsNotFound(err) {
+				return &storage.Error{Code: storage.PreconditionFailedErr, Message: fmt.Sprintf("document does not exist: %v", path)}
+			}
+			return err
+		}
+		return nil
+	}
+
+	rev, ok, err := storage.Revision(ctx, s.store, txn)
+	if err != nil {
+		return err
+	} else if !ok {
+		return &storage.Error{Code: storage.PreconditionFailedErr, Message: "store does not track revisions"}
+	} else if !etagMatch(header, s.etag(rev)) {
+		return &storage.Error{Code: storage.PreconditionFailedErr, Message: "store has been modified"}
+	}
+
+	return nil
+}
+
+// etagMatch returns true if the If-None-Match or If-Match header value matches
+// the etag. Since the server only generates weak tags, the weak indicators are
+// ignored.
+func etagMatch(header string, etag string) bool {
+	if header == "" {
+		return false
+	}
+	etag = strings.TrimPrefix(etag, "W/")
+	for _, v := range strings.Split(header, ",") {
+		v = strings.TrimSpace(v)
+		if v == "*" || strings.TrimPrefix(v, "W/") == etag {
+			return true
+		}
+	}
+	return false
+}
+
 func (s *Server) migrateWatcher(txn storage.Transaction) {
 	var err error
 	s.watcher, err = s.watcher.Migrate(s.manager.GetCompiler(), txn)
@@ -783,6 +999,12 @@ func (s *Server) v0QueryPath(w http.ResponseWriter, r *http.Request, path ast.Re
 		goInput = &x
 	}
 
+	compiler := s.getCompiler()
+
+	if !s.validateInput(w, compiler, path, input) {
+		return
+	}
+
 	// Prepare for query.
 	txn, err := s.store.NewTransaction(ctx)
 	if err != nil {
@@ -792,14 +1014,21 @@ func (s *Server) v0QueryPath(w http.ResponseWriter, r *http.Request, path ast.Re
 
 	defer s.store.Abort(ctx, txn)
 
+	if _, _, err := s.setRevisionHeader(ctx, w, s.store, txn); err != nil {
+		writer.ErrorAuto(w, err)
+		return
+	}
+
 	rego := rego.New(
-		rego.Compiler(s.getCompiler()),
+		rego.Compiler(compiler),
 		rego.Store(s.store),
 		rego.Transaction(txn),
 		rego.ParsedInput(input),
 		rego.Query(path.String()),
 		rego.Metrics(m),
+		rego.ProfileLabels(s.pprofEnabled),
 		rego.Runtime(s.runtime),
+		rego.InterQueryBuiltinCache(s.interQueryCache),
 		rego.UnsafeBuiltins(unsafeBuiltinsMap),
 	)
 
@@ -832,7 +1061,14 @@ func (s *Server) v0QueryPath(w http.ResponseWriter, r *http.Request, path ast.Re
 		return
 	}
 
-	writer.JSON(w, 200, rs[0].Expressions[0].Value, false)
+	// There is no standard for yaml mime-type so we just look for anything
+	// related (same as the input.)
+	if strings.Contains(r.Header.Get("Accept"), "yaml") {
+		writer.YAML(w, 200, rs[0].Expressions[0].Value)
+		return
+	}
+
+	writer.JSON(w, 200, rs[0].Expressions[0].Value, getBoolParam(r.URL, types.ParamPrettyV1, true))
 }
 
 func (s *Server) updateBundleStatus(status map[string]*bundlePlugin.Status) {
@@ -844,7 +1080,7 @@ func (s *Server) updateBundleStatus(status map[string]*bundlePlugin.Status) {
 func (s *Server) canEval(ctx context.Context) bool {
 	// Create very simple query that binds a single variable.
 	eval := rego.New(rego.Compiler(s.getCompiler()),
-		rego.Store(s.store), rego.Query("x = 1"))
+		rego.Store(s.store), rego.Query("x = 1"), rego.InterQueryBuiltinCache(s.interQueryCache))
 	// Run evaluation.
 	rs, err := eval.Eval(ctx)
 	if err != nil {
@@ -913,6 +1149,11 @@ func (s *Server) v1CompilePost(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if err := s.checkQueryLimits(request.Query); err != nil {
+		writer.ErrorAuto(w, err)
+		return
+	}
+
 	m.Timer(metrics.RegoQueryParse).Stop()
 
 	txn, err := s.store.NewTransaction(ctx)
@@ -937,8 +1178,10 @@ func (s *Server) v1CompilePost(w http.ResponseWriter, r *http.Request) {
 		rego.ParsedUnknowns(request.Unknowns),
 		rego.Tracer(buf),
 		rego.Instrument(includeInstrumentation),
+		rego.ProfileLabels(s.pprofEnabled),
 		rego.Metrics(m),
 		rego.Runtime(s.runtime),
+		rego.InterQueryBuiltinCache(s.interQueryCache),
 		rego.UnsafeBuiltins(unsafeBuiltinsMap),
 	)
 
@@ -983,7 +1226,8 @@ func (s *Server) v1DataGet(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
 	vars := mux.Vars(r)
 	path := stringPathToDataRef(vars["path"])
-	logger := s.getDecisionLogger()
+	shadow, isShadow := s.getShadowDecision(path)
+	logger := s.getDecisionLogger().withShadow(isShadow)
 
 	watch := getWatch(r.URL.Query()[types.ParamWatchV1])
 	if watch {
@@ -997,6 +1241,14 @@ func (s *Server) v1DataGet(w http.ResponseWriter, r *http.Request) {
 	includeInstrumentation := getBoolParam(r.URL, types.ParamInstrumentV1, true)
 	provenance := getBoolParam(r.URL, types.ParamProvenanceV1, true)
 
+	snap, ok := s.getSnapshotParam(w, r)
+	if !ok {
+		return
+	}
+
+	store, compiler := s.getStoreAndCompiler(snap)
+	logger = logger.withSnapshot(snap)
+
 	m.Timer(metrics.RegoQueryParse).Start()
 
 	inputs := r.URL.Query()[types.ParamInputV1]
@@ -1024,14 +1276,48 @@ func (s *Server) v1DataGet(w http.ResponseWriter, r *http.Request) {
 		goInput = &x
 	}
 
+	// Overrides apply to the live store only.
+	if snap == nil && s.writeOverride(ctx, w, r, logger, decisionID, path, goInput, m, pretty) {
+		return
+	}
+
+	if !isShadow && !s.validateInput(w, compiler, path, input) {
+		return
+	}
+
+	// Decisions with explanations or instrumentation and decisions read from
+	// snapshots are always evaluated.
+	cacheKey, cacheable := decisionCacheKey{}, explainMode == types.ExplainOffV1 && !includeInstrumentation && snap == nil
+	if cacheable {
+		cacheKey, cacheable = s.decisions.Key(path.String(), goInput)
+	}
+
 	// Prepare for query.
-	txn, err := s.store.NewTransaction(ctx)
+	txn, err := store.NewTransaction(ctx)
 	if err != nil {
 		writer.ErrorAuto(w, err)
 		return
 	}
 
-	defer s.store.Abort(ctx, txn)
+	defer store.Abort(ctx, txn)
+
+	rev, ok, err := s.setRevisionHeader(ctx, w, store, txn)
+	if err != nil {
+		writer.ErrorAuto(w, err)
+		return
+	}
+
+	// Responses that include explanations or metrics differ between requests
+	// so they are not tagged. Other responses only change when the store is
+	// written to.
+	if ok && explainMode == types.ExplainOffV1 && !includeMetrics && !includeInstrumentation {
+		etag := s.etag(rev)
+		w.Header().Set("ETag", etag)
+		if etagMatch(r.Header.Get("If-None-Match"), etag) {
+			writer.Bytes(w, http.StatusNotModified, nil)
+			return
+		}
+	}
 
 	var buf *topdown.BufferTracer
 
@@ -1039,26 +1325,42 @@ func (s *Server) v1DataGet(w http.ResponseWriter, r *http.Request) {
 		buf = topdown.NewBufferTracer()
 	}
 
-	rego := rego.New(
-		rego.Compiler(s.getCompiler()),
-		rego.Store(s.store),
-		rego.Transaction(txn),
-		rego.ParsedInput(input),
-		rego.Query(path.String()),
-		rego.Metrics(m),
-		rego.Tracer(buf),
-		rego.Instrument(includeInstrumentation),
-		rego.Runtime(s.runtime),
-		rego.UnsafeBuiltins(unsafeBuiltinsMap),
-	)
+	value, cached := s.getCachedDecision(cacheKey, cacheable, m)
 
-	rs, err := rego.Eval(ctx)
+	if !cached {
+		rego := rego.New(
+			rego.Compiler(compiler),
+			rego.Store(store),
+			rego.Transaction(txn),
+			rego.ParsedInput(input),
+			rego.Query(path.String()),
+			rego.Metrics(m),
+			rego.Tracer(buf),
+			rego.Instrument(includeInstrumentation),
+			rego.ProfileLabels(s.pprofEnabled),
+			rego.Runtime(s.runtime),
+			rego.InterQueryBuiltinCache(s.interQueryCache),
+			rego.UnsafeBuiltins(unsafeBuiltinsMap),
+		)
 
-	// Handle results.
-	if err != nil {
-		_ = logger.Log(ctx, txn, decisionID, r.RemoteAddr, path.String(), "", goInput, nil, err, m)
-		writer.ErrorAuto(w, err)
-		return
+		rs, err := rego.Eval(ctx)
+
+		// Handle results.
+		if err != nil {
+			_ = logger.Log(ctx, txn, decisionID, r.RemoteAddr, path.String(), "", goInput, nil, err, m)
+			if isShadow {
+				writer.JSON(w, 200, types.DataResponseV1{DecisionID: decisionID, Result: shadow}, pretty)
+				return
+			}
+			writer.ErrorAuto(w, err)
+			return
+		}
+
+		value = s.putCachedDecision(cacheKey, cacheable, rs)
+	}
+
+	if snap == nil {
+		s.mirrorCanary(decisionID, path, input, value)
 	}
 
 	result := types.DataResponseV1{
@@ -1075,7 +1377,7 @@ func (s *Server) v1DataGet(w http.ResponseWriter, r *http.Request) {
 		result.Provenance = s.getProvenance()
 	}
 
-	if len(rs) == 0 {
+	if value == nil {
 		if explainMode == types.ExplainFullV1 {
 			result.Explanation, err = types.NewTraceV1(*buf, pretty)
 			if err != nil {
@@ -1087,11 +1389,14 @@ func (s *Server) v1DataGet(w http.ResponseWriter, r *http.Request) {
 			writer.ErrorAuto(w, err)
 			return
 		}
+		if isShadow {
+			result.Result = shadow
+		}
 		writer.JSON(w, 200, result, pretty)
 		return
 	}
 
-	result.Result = &rs[0].Expressions[0].Value
+	result.Result = value
 
 	if explainMode != types.ExplainOffV1 {
 		result.Explanation = s.getExplainResponse(explainMode, *buf, pretty)
@@ -1102,6 +1407,9 @@ func (s *Server) v1DataGet(w http.ResponseWriter, r *http.Request) {
 		writer.ErrorAuto(w, err)
 		return
 	}
+	if isShadow {
+		result.Result = shadow
+	}
 	writer.JSON(w, 200, result, pretty)
 }
 
@@ -1122,13 +1430,32 @@ func (s *Server) v1DataPatch(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	root, ok := storage.ParsePathEscaped("/" + strings.Trim(vars["path"], "/"))
+	if !ok {
+		writer.Error(w, http.StatusBadRequest, types.NewErrorV1(types.CodeInvalidParameter, "bad path: %v", vars["path"]))
+		return
+	}
+
 	txn, err := s.store.NewTransaction(ctx, storage.WriteParams)
 	if err != nil {
 		writer.ErrorAuto(w, err)
 		return
 	}
 
+	if err := s.checkIfMatch(ctx, txn, r, root); err != nil {
+		s.abortAuto(ctx, txn, w, err)
+		return
+	}
+
 	for _, patch := range patches {
+		if patch.test {
+			if err := storage.CheckValue(ctx, s.store, txn, patch.path, patch.value); err != nil {
+				s.abortAuto(ctx, txn, w, err)
+				return
+			}
+			continue
+		}
+
 		if err := s.checkPathScope(ctx, txn, patch.path); err != nil {
 			s.abortAuto(ctx, txn, w, err)
 			return
@@ -1162,7 +1489,8 @@ func (s *Server) v1DataPost(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
 	vars := mux.Vars(r)
 	path := stringPathToDataRef(vars["path"])
-	logger := s.getDecisionLogger()
+	shadow, isShadow := s.getShadowDecision(path)
+	logger := s.getDecisionLogger().withShadow(isShadow)
 
 	watch := getWatch(r.URL.Query()[types.ParamWatchV1])
 	if watch {
@@ -1177,6 +1505,17 @@ func (s *Server) v1DataPost(w http.ResponseWriter, r *http.Request) {
 	partial := getBoolParam(r.URL, types.ParamPartialV1, true)
 	provenance := getBoolParam(r.URL, types.ParamProvenanceV1, true)
 
+	snap, ok := s.getSnapshotParam(w, r)
+	if !ok {
+		return
+	}
+
+	store, compiler := s.getStoreAndCompiler(snap)
+	logger = logger.withSnapshot(snap)
+
+	// Partial results are cached for the live store only.
+	partial = partial && snap == nil
+
 	m.Timer(metrics.RegoQueryParse).Start()
 
 	input, err := readInputPostV1(r)
@@ -1197,17 +1536,33 @@ func (s *Server) v1DataPost(w http.ResponseWriter, r *http.Request) {
 
 	m.Timer(metrics.RegoQueryParse).Stop()
 
-	txn, err := s.store.NewTransaction(ctx)
+	// Overrides apply to the live store only.
+	if snap == nil && s.writeOverride(ctx, w, r, logger, decisionID, path, goInput, m, pretty) {
+		return
+	}
+
+	if !isShadow && !s.validateInput(w, compiler, path, input) {
+		return
+	}
+
+	// Decisions with explanations or instrumentation and decisions read from
+	// snapshots are always evaluated.
+	cacheKey, cacheable := decisionCacheKey{}, explainMode == types.ExplainOffV1 && !includeInstrumentation && snap == nil
+	if cacheable {
+		cacheKey, cacheable = s.decisions.Key(path.String(), goInput)
+	}
+
+	txn, err := store.NewTransaction(ctx)
 	if err != nil {
 		writer.ErrorAuto(w, err)
 		return
 	}
 
-	defer s.store.Abort(ctx, txn)
+	defer store.Abort(ctx, txn)
 
-	opts := []func(*rego.Rego){
-		rego.Compiler(s.getCompiler()),
-		rego.Store(s.store),
+	if _, _, err := s.setRevisionHeader(ctx, w, store, txn); err != nil {
+		writer.ErrorAuto(w, err)
+		return
 	}
 
 	var buf *topdown.BufferTracer
@@ -1216,25 +1571,49 @@ func (s *Server) v1DataPost(w http.ResponseWriter, r *http.Request) {
 		buf = topdown.NewBufferTracer()
 	}
 
-	rego, err := s.makeRego(ctx, partial, txn, input, path.String(), m, includeInstrumentation, buf, opts)
+	value, cached := s.getCachedDecision(cacheKey, cacheable, m)
 
-	if err != nil {
-		_ = logger.Log(ctx, txn, decisionID, r.RemoteAddr, path.String(), "", goInput, nil, err, m)
-		writer.ErrorAuto(w, err)
-		return
-	}
+	if !cached {
+		opts := []func(*rego.Rego){
+			rego.Compiler(compiler),
+			rego.Store(store),
+		}
 
-	rs, err := rego.Eval(ctx)
+		rego, err := s.makeRego(ctx, partial, txn, input, path.String(), m, includeInstrumentation, buf, opts)
 
-	m.Timer(metrics.ServerHandler).Stop()
+		if err != nil {
+			_ = logger.Log(ctx, txn, decisionID, r.RemoteAddr, path.String(), "", goInput, nil, err, m)
+			if isShadow {
+				writer.JSON(w, 200, types.DataResponseV1{DecisionID: decisionID, Result: shadow}, pretty)
+				return
+			}
+			writer.ErrorAuto(w, err)
+			return
+		}
 
-	// Handle results.
-	if err != nil {
-		_ = logger.Log(ctx, txn, decisionID, r.RemoteAddr, path.String(), "", goInput, nil, err, m)
-		writer.ErrorAuto(w, err)
-		return
+		rs, err := rego.Eval(ctx)
+
+		// Handle results.
+		if err != nil {
+			m.Timer(metrics.ServerHandler).Stop()
+			_ = logger.Log(ctx, txn, decisionID, r.RemoteAddr, path.String(), "", goInput, nil, err, m)
+			if isShadow {
+				writer.JSON(w, 200, types.DataResponseV1{DecisionID: decisionID, Result: shadow}, pretty)
+				return
+			}
+			writer.ErrorAuto(w, err)
+			return
+		}
+
+		value = s.putCachedDecision(cacheKey, cacheable, rs)
+	}
+
+	if snap == nil {
+		s.mirrorCanary(decisionID, path, input, value)
 	}
 
+	m.Timer(metrics.ServerHandler).Stop()
+
 	result := types.DataResponseV1{
 		DecisionID: decisionID,
 	}
@@ -1247,7 +1626,7 @@ func (s *Server) v1DataPost(w http.ResponseWriter, r *http.Request) {
 		result.Provenance = s.getProvenance()
 	}
 
-	if len(rs) == 0 {
+	if value == nil {
 		if explainMode == types.ExplainFullV1 {
 			result.Explanation, err = types.NewTraceV1(*buf, pretty)
 			if err != nil {
@@ -1259,11 +1638,14 @@ func (s *Server) v1DataPost(w http.ResponseWriter, r *http.Request) {
 			writer.ErrorAuto(w, err)
 			return
 		}
+		if isShadow {
+			result.Result = shadow
+		}
 		writer.JSON(w, 200, result, pretty)
 		return
 	}
 
-	result.Result = &rs[0].Expressions[0].Value
+	result.Result = value
 
 	if explainMode != types.ExplainOffV1 {
 		result.Explanation = s.getExplainResponse(explainMode, *buf, pretty)
@@ -1274,12 +1656,105 @@ func (s *Server) v1DataPost(w http.ResponseWriter, r *http.Request) {
 		writer.ErrorAuto(w, err)
 		return
 	}
+	if isShadow {
+		result.Result = shadow
+	}
 	writer.JSON(w, 200, result, pretty)
 }
 
-func (s *Server) v1DataPut(w http.ResponseWriter, r *http.Request) {
+func (s *Server) v1ViolationsPost(w http.ResponseWriter, r *http.Request) {
+	m := metrics.New()
+	m.Timer(metrics.ServerHandler).Start()
+
+	decisionID := s.generateDecisionID()
+
 	ctx := r.Context()
 	vars := mux.Vars(r)
+	path := stringPathToDataRef(vars["path"])
+	logger := s.getDecisionLogger()
+
+	pretty := getBoolParam(r.URL, types.ParamPrettyV1, true)
+	includeMetrics := getBoolParam(r.URL, types.ParamMetricsV1, true)
+	names := r.URL.Query()[types.ParamRuleV1]
+
+	m.Timer(metrics.RegoQueryParse).Start()
+
+	input, err := readInputPostV1(r)
+	if err != nil {
+		writer.ErrorString(w, http.StatusBadRequest, types.CodeInvalidParameter, err)
+		return
+	}
+
+	var goInput *interface{}
+	if input != nil {
+		x, err := ast.JSON(input)
+		if err != nil {
+			writer.ErrorString(w, http.StatusInternalServerError, types.CodeInvalidParameter, errors.Wrapf(err, "could not marshal input"))
+			return
+		}
+		goInput = &x
+	}
+
+	m.Timer(metrics.RegoQueryParse).Stop()
+
+	txn, err := s.store.NewTransaction(ctx)
+	if err != nil {
+		writer.ErrorAuto(w, err)
+		return
+	}
+
+	defer s.store.Abort(ctx, txn)
+
+	if _, _, err := s.setRevisionHeader(ctx, w, s.store, txn); err != nil {
+		writer.ErrorAuto(w, err)
+		return
+	}
+
+	violations, err := rego.Violations(ctx, path, names,
+		rego.Compiler(s.getCompiler()),
+		rego.Store(s.store),
+		rego.Transaction(txn),
+		rego.ParsedInput(input),
+		rego.Metrics(m),
+		rego.ProfileLabels(s.pprofEnabled),
+		rego.Runtime(s.runtime),
+		rego.InterQueryBuiltinCache(s.interQueryCache),
+		rego.UnsafeBuiltins(unsafeBuiltinsMap),
+	)
+
+	m.Timer(metrics.ServerHandler).Stop()
+
+	if err != nil {
+		_ = logger.Log(ctx, txn, decisionID, r.RemoteAddr, path.String(), "", goInput, nil, err, m)
+		writer.ErrorAuto(w, err)
+		return
+	}
+
+	result := types.ViolationsResponseV1{
+		DecisionID: decisionID,
+		Result:     make([]types.ViolationV1, len(violations)),
+	}
+
+	for i, v := range violations {
+		result.Result[i] = types.ViolationV1(v)
+	}
+
+	if includeMetrics {
+		result.Metrics = m.All()
+	}
+
+	var logged interface{} = result.Result
+	err = logger.Log(ctx, txn, decisionID, r.RemoteAddr, path.String(), "", goInput, &logged, nil, m)
+	if err != nil {
+		writer.ErrorAuto(w, err)
+		return
+	}
+
+	writer.JSON(w, 200, result, pretty)
+}
+
+func (s *Server) v1DataPut(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
 
 	var value interface{}
 	if err := util.NewJSONDecoder(r.Body).Decode(&value); err != nil {
@@ -1287,9 +1762,17 @@ func (s *Server) v1DataPut(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	path, ok := storage.ParsePathEscaped("/" + strings.Trim(vars["path"], "/"))
+	s.putDocument(w, r, vars["path"], value)
+}
+
+// putDocument creates or overwrites the document at the (escaped) path with
+// value inside of a single write transaction.
+func (s *Server) putDocument(w http.ResponseWriter, r *http.Request, rawPath string, value interface{}) {
+	ctx := r.Context()
+
+	path, ok := storage.ParsePathEscaped("/" + strings.Trim(rawPath, "/"))
 	if !ok {
-		writer.Error(w, http.StatusBadRequest, types.NewErrorV1(types.CodeInvalidParameter, "bad path: %v", vars["path"]))
+		writer.Error(w, http.StatusBadRequest, types.NewErrorV1(types.CodeInvalidParameter, "bad path: %v", rawPath))
 		return
 	}
 
@@ -1304,6 +1787,11 @@ func (s *Server) v1DataPut(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if err := s.checkIfMatch(ctx, txn, r, path); err != nil {
+		s.abortAuto(ctx, txn, w, err)
+		return
+	}
+
 	_, err = s.store.Read(ctx, txn, path)
 
 	if err != nil {
@@ -1360,6 +1848,11 @@ func (s *Server) v1DataDelete(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if err := s.checkIfMatch(ctx, txn, r, path); err != nil {
+		s.abortAuto(ctx, txn, w, err)
+		return
+	}
+
 	_, err = s.store.Read(ctx, txn, path)
 	if err != nil {
 		s.abortAuto(ctx, txn, w, err)
@@ -1627,7 +2120,7 @@ func (s *Server) v1QueryGet(w http.ResponseWriter, r *http.Request) {
 	}
 	qStr := qStrs[len(qStrs)-1]
 
-	parsedQuery, err := validateQuery(qStr)
+	parsedQuery, err := s.validateQuery(qStr)
 	if err != nil {
 		switch err := err.(type) {
 		case ast.Errors:
@@ -1649,6 +2142,13 @@ func (s *Server) v1QueryGet(w http.ResponseWriter, r *http.Request) {
 	explainMode := getExplain(r.URL.Query()["explain"], types.ExplainOffV1)
 	includeMetrics := getBoolParam(r.URL, types.ParamMetricsV1, true)
 	includeInstrumentation := getBoolParam(r.URL, types.ParamInstrumentV1, true)
+	includeExpressions := getBoolParam(r.URL, types.ParamExpressionsV1, true)
+
+	page, err := getPage(r.URL)
+	if err != nil {
+		writer.ErrorAuto(w, err)
+		return
+	}
 
 	txn, err := s.store.NewTransaction(ctx)
 	if err != nil {
@@ -1658,7 +2158,7 @@ func (s *Server) v1QueryGet(w http.ResponseWriter, r *http.Request) {
 
 	defer s.store.Abort(ctx, txn)
 
-	results, err := s.execQuery(ctx, r, txn, decisionID, parsedQuery, nil, m, explainMode, includeMetrics, includeInstrumentation, pretty)
+	results, err := s.execQuery(ctx, r, txn, decisionID, parsedQuery, nil, m, explainMode, includeMetrics, includeInstrumentation, includeExpressions, pretty)
 	if err != nil {
 		switch err := err.(type) {
 		case ast.Errors:
@@ -1669,6 +2169,10 @@ func (s *Server) v1QueryGet(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if page != nil {
+		page.apply(&results)
+	}
+
 	writer.JSON(w, 200, results, pretty)
 }
 
@@ -1686,7 +2190,7 @@ func (s *Server) v1QueryPost(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 	qStr := request.Query
-	parsedQuery, err := validateQuery(qStr)
+	parsedQuery, err := s.validateQuery(qStr)
 	if err != nil {
 		switch err := err.(type) {
 		case ast.Errors:
@@ -1708,6 +2212,13 @@ func (s *Server) v1QueryPost(w http.ResponseWriter, r *http.Request) {
 	explainMode := getExplain(r.URL.Query()["explain"], types.ExplainOffV1)
 	includeMetrics := getBoolParam(r.URL, types.ParamMetricsV1, true)
 	includeInstrumentation := getBoolParam(r.URL, types.ParamInstrumentV1, true)
+	includeExpressions := getBoolParam(r.URL, types.ParamExpressionsV1, true)
+
+	page, err := getPage(r.URL)
+	if err != nil {
+		writer.ErrorAuto(w, err)
+		return
+	}
 
 	txn, err := s.store.NewTransaction(ctx)
 	if err != nil {
@@ -1717,7 +2228,7 @@ func (s *Server) v1QueryPost(w http.ResponseWriter, r *http.Request) {
 
 	defer s.store.Abort(ctx, txn)
 
-	results, err := s.execQuery(ctx, r, txn, decisionID, parsedQuery, nil, m, explainMode, includeMetrics, includeInstrumentation, pretty)
+	results, err := s.execQuery(ctx, r, txn, decisionID, parsedQuery, nil, m, explainMode, includeMetrics, includeInstrumentation, includeExpressions, pretty)
 	if err != nil {
 		switch err := err.(type) {
 		case ast.Errors:
@@ -1728,9 +2239,88 @@ func (s *Server) v1QueryPost(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if page != nil {
+		page.apply(&results)
+	}
+
 	writer.JSON(w, 200, results, pretty)
 }
 
+func (s *Server) v1QueryValidatePost(w http.ResponseWriter, r *http.Request) {
+
+	var request types.QueryRequestV1
+	if err := util.NewJSONDecoder(r.Body).Decode(&request); err != nil {
+		writer.Error(w, http.StatusBadRequest, types.NewErrorV1(types.CodeInvalidParameter, "error(s) occurred while decoding request: %v", err.Error()))
+		return
+	}
+
+	parsedQuery, err := s.validateQuery(request.Query)
+	if err != nil {
+		switch err := err.(type) {
+		case ast.Errors:
+			writer.Error(w, http.StatusBadRequest, types.NewErrorV1(types.CodeInvalidParameter, types.MsgParseQueryError).WithASTErrors(err))
+		default:
+			writer.ErrorAuto(w, err)
+		}
+		return
+	}
+
+	ctx := r.Context()
+	compiler := s.getCompiler()
+	qc := compiler.QueryCompiler().WithUnsafeBuiltins(unsafeBuiltinsMap)
+
+	compiledQuery, err := qc.Compile(parsedQuery)
+	if err != nil {
+		switch err := err.(type) {
+		case ast.Errors:
+			writer.Error(w, http.StatusBadRequest, types.NewErrorV1(types.CodeInvalidParameter, types.MsgCompileQueryError).WithASTErrors(err))
+		default:
+			writer.ErrorAuto(w, err)
+		}
+		return
+	}
+
+	rewritten := qc.RewrittenVars()
+	env := qc.TypeEnv()
+	vis := ast.NewVarVisitor().WithParams(ast.VarVisitorParams{
+		SkipRefHead:  true,
+		SkipClosures: true,
+	})
+	ast.Walk(vis, compiledQuery)
+
+	txn, err := s.store.NewTransaction(ctx)
+	if err != nil {
+		writer.ErrorAuto(w, err)
+		return
+	}
+
+	defer s.store.Abort(ctx, txn)
+
+	est, err := s.estimateQueryCost(ctx, txn, compiler, compiledQuery, nil)
+	if err != nil {
+		writer.ErrorAuto(w, err)
+		return
+	}
+
+	result := types.QueryValidationResponseV1{
+		Vars: map[string]interface{}{},
+		Cost: &types.QueryCostV1{Rows: est.Rows, Cost: est.Cost},
+	}
+
+	for v := range vis.Vars() {
+		name := v
+		if orig, ok := rewritten[v]; ok {
+			name = orig
+		}
+		if name.IsWildcard() || name.IsGenerated() {
+			continue
+		}
+		result.Vars[string(name)] = env.Get(ast.VarTerm(string(v)))
+	}
+
+	writer.JSON(w, 200, result, getBoolParam(r.URL, types.ParamPrettyV1, true))
+}
+
 func (s *Server) watchQuery(query string, w http.ResponseWriter, r *http.Request, data bool) {
 	pretty := getBoolParam(r.URL, types.ParamPrettyV1, true)
 	explainMode := getExplain(r.URL.Query()["explain"], types.ExplainOffV1)
@@ -1808,6 +2398,97 @@ func (s *Server) watchQuery(query string, w http.ResponseWriter, r *http.Request
 	}
 }
 
+func (s *Server) v1SubscribeGet(w http.ResponseWriter, r *http.Request) {
+
+	conn, err := websocket.Upgrade(w, r)
+	if err != nil {
+		return
+	}
+
+	defer conn.Close()
+
+	handles := map[string]*watch.Handle{}
+
+	defer func() {
+		for _, h := range handles {
+			h.Stop()
+		}
+	}()
+
+	send := func(resp types.SubscribeResponseV1) {
+		bs, err := json.Marshal(resp)
+		if err != nil {
+			return
+		}
+		conn.WriteMessage(bs)
+	}
+
+	for {
+		bs, err := conn.ReadMessage()
+		if err != nil {
+			return
+		}
+
+		var request types.SubscribeRequestV1
+
+		if err := util.UnmarshalJSON(bs, &request); err != nil {
+			send(types.SubscribeResponseV1{Error: types.NewErrorV1(types.CodeInvalidParameter, "error(s) occurred while decoding request: %v", err.Error())})
+			continue
+		}
+
+		if h, ok := handles[request.ID]; ok {
+			h.Stop()
+			delete(handles, request.ID)
+		}
+
+		if request.Unsubscribe {
+			continue
+		}
+
+		if _, err := s.validateQuery(request.Query); err != nil {
+			resp := types.SubscribeResponseV1{ID: request.ID}
+			if astErr, ok := err.(ast.Errors); ok {
+				resp.Error = types.NewErrorV1(types.CodeInvalidParameter, types.MsgParseQueryError).WithASTErrors(astErr)
+			} else {
+				resp.Error = types.NewErrorV1(types.CodeInvalidParameter, err.Error())
+			}
+			send(resp)
+			continue
+		}
+
+		h := s.watcher.NewQuery(request.Query).WithRuntime(s.runtime)
+
+		if err := h.Start(); err != nil {
+			resp := types.SubscribeResponseV1{ID: request.ID}
+			if astErr, ok := err.(ast.Errors); ok {
+				resp.Error = types.NewErrorV1(types.CodeInvalidParameter, types.MsgCompileQueryError).WithASTErrors(astErr)
+			} else {
+				resp.Error = types.NewErrorV1(types.CodeInvalidParameter, err.Error())
+			}
+			send(resp)
+			continue
+		}
+
+		handles[request.ID] = h
+
+		// The watch delivers the initial result followed by a new result each
+		// time the data the query depends on changes.
+		go func(id string, h *watch.Handle) {
+			for e := range h.C {
+				resp := types.SubscribeResponseV1{ID: id}
+				if e.Error != nil {
+					resp.Error = types.NewErrorV1(types.CodeEvaluation, e.Error.Error())
+				} else {
+					for _, result := range e.Value {
+						resp.Result = append(resp.Result, result.Bindings.WithoutWildcards())
+					}
+				}
+				send(resp)
+			}
+		}(request.ID, h)
+	}
+}
+
 func (s *Server) checkPolicyIDScope(ctx context.Context, txn storage.Transaction, id string) error {
 
 	bs, err := s.store.GetPolicy(ctx, txn, id)
@@ -1861,7 +2542,7 @@ func (s *Server) checkPathScope(ctx context.Context, txn storage.Transaction, pa
 
 	for name, roots := range bundleRoots {
 		for _, root := range roots {
-			if strings.HasPrefix(spath, root) || strings.HasPrefix(root, spath) {
+			if bundle.RootPathsOverlap(spath, root) {
 				return types.BadRequestErr(fmt.Sprintf("path %v is owned by bundle %q", spath, name))
 			}
 		}
@@ -1870,6 +2551,40 @@ func (s *Server) checkPathScope(ctx context.Context, txn storage.Transaction, pa
 	return nil
 }
 
+// getCachedDecision returns the cached result of the decision. The result is
+// nil if the decision was undefined.
+func (s *Server) getCachedDecision(key decisionCacheKey, cacheable bool, m metrics.Metrics) (*interface{}, bool) {
+	if !cacheable {
+		return nil, false
+	}
+	value, ok := s.decisions.Get(key, time.Now())
+	if ok {
+		m.Counter(metrics.ServerDecisionCacheHits).Incr()
+	} else {
+		m.Counter(metrics.ServerDecisionCacheMisses).Incr()
+	}
+	return value, ok
+}
+
+// putCachedDecision caches the result of the decision (if cacheable) and
+// returns the result. The result is nil if the decision was undefined.
+func (s *Server) putCachedDecision(key decisionCacheKey, cacheable bool, rs rego.ResultSet) *interface{} {
+	var value *interface{}
+	if len(rs) > 0 {
+		value = &rs[0].Expressions[0].Value
+	}
+	if cacheable {
+		s.decisions.Put(key, value, time.Now())
+	}
+	return value
+}
+
+// getShadowDecision returns the default value returned to callers for the
+// decision at path if the decision is evaluated in shadow mode.
+func (s *Server) getShadowDecision(path ast.Ref) (*interface{}, bool) {
+	return s.manager.Config.ShadowDecision(path)
+}
+
 func (s *Server) getDecisionLogger() (logger decisionLogger) {
 	// For backwards compatibility use `revision` as needed.
 	if s.hasLegacyBundle() {
@@ -1879,6 +2594,7 @@ func (s *Server) getDecisionLogger() (logger decisionLogger) {
 	}
 	logger.logger = s.logger
 	logger.buffer = s.buffer
+	logger.store = s.store
 	return logger
 }
 
@@ -1952,7 +2668,7 @@ func (s *Server) makeRego(ctx context.Context, partial bool, txn storage.Transac
 		defer s.mtx.Unlock()
 		pr, ok := s.partials[path]
 		if !ok {
-			opts = append(opts, rego.Transaction(txn), rego.Query(path), rego.Metrics(m), rego.Instrument(instrument), rego.Runtime(s.runtime))
+			opts = append(opts, rego.Transaction(txn), rego.Query(path), rego.Metrics(m), rego.Instrument(instrument), rego.Runtime(s.runtime), rego.InterQueryBuiltinCache(s.interQueryCache))
 			r := rego.New(opts...)
 			var err error
 			pr, err = r.PartialResult(ctx)
@@ -1966,12 +2682,13 @@ func (s *Server) makeRego(ctx context.Context, partial bool, txn storage.Transac
 			rego.Transaction(txn),
 			rego.Metrics(m),
 			rego.Instrument(instrument),
+			rego.ProfileLabels(s.pprofEnabled),
 			rego.Tracer(tracer),
 		}
 		return pr.Rego(opts...), nil
 	}
 
-	opts = append(opts, rego.Transaction(txn), rego.Query(path), rego.ParsedInput(input), rego.Metrics(m), rego.Tracer(tracer), rego.Instrument(instrument), rego.Runtime(s.runtime), rego.UnsafeBuiltins(unsafeBuiltinsMap))
+	opts = append(opts, rego.Transaction(txn), rego.Query(path), rego.ParsedInput(input), rego.Metrics(m), rego.Tracer(tracer), rego.Instrument(instrument), rego.ProfileLabels(s.pprofEnabled), rego.Runtime(s.runtime), rego.InterQueryBuiltinCache(s.interQueryCache), rego.UnsafeBuiltins(unsafeBuiltinsMap))
 	return rego.New(opts...), nil
 }
 
@@ -1993,6 +2710,8 @@ func (s *Server) prepareV1PatchSlice(root string, ops []types.PatchV1) (result [
 			impl.op = storage.RemoveOp
 		case "replace":
 			impl.op = storage.ReplaceOp
+		case "test":
+			impl.test = true
 		default:
 			return nil, types.BadPatchOperationErr(op.Op)
 		}
@@ -2122,16 +2841,75 @@ func stringPathToRef(s string) (r ast.Ref) {
 	return r
 }
 
-func validateQuery(query string) (ast.Body, error) {
+func (s *Server) validateQuery(query string) (ast.Body, error) {
 
 	var body ast.Body
 	body, err := ast.ParseBody(query)
 	if err != nil {
 		return nil, err
 	}
+	if err := s.checkQueryLimits(body); err != nil {
+		return nil, err
+	}
 	return body, nil
 }
 
+func (s *Server) checkQueryLimits(query ast.Body) error {
+
+	if s.maxQueryExprs <= 0 {
+		return nil
+	}
+
+	var n int
+	ast.WalkExprs(query, func(*ast.Expr) bool {
+		n++
+		return false
+	})
+
+	if n > s.maxQueryExprs {
+		return types.BadRequestErr(fmt.Sprintf("query exceeds maximum of %d expressions", s.maxQueryExprs))
+	}
+
+	return nil
+}
+
+// checkQueryCost returns an error if the estimated cost of the query exceeds
+// the maximum.
+func (s *Server) checkQueryCost(ctx context.Context, txn storage.Transaction, compiler *ast.Compiler, query ast.Body, input ast.Value) error {
+
+	if s.maxQueryCost <= 0 {
+		return nil
+	}
+
+	compiled, err := compiler.QueryCompiler().WithUnsafeBuiltins(unsafeBuiltinsMap).Compile(query)
+	if err != nil {
+		return err
+	}
+
+	est, err := s.estimateQueryCost(ctx, txn, compiler, compiled, input)
+	if err != nil {
+		return err
+	}
+
+	if est.Cost > s.maxQueryCost {
+		return types.BadRequestErr(fmt.Sprintf("query exceeds maximum cost of %v (estimated cost %v)", s.maxQueryCost, est.Cost))
+	}
+
+	return nil
+}
+
+// estimateQueryCost returns the estimated cost of evaluating the compiled query
+// against the store and the input.
+func (s *Server) estimateQueryCost(ctx context.Context, txn storage.Transaction, compiler *ast.Compiler, query ast.Body, input ast.Value) (*cost.Estimate, error) {
+
+	stats, err := cost.NewStoreStats(ctx, s.store, txn, input)
+	if err != nil {
+		return nil, err
+	}
+
+	return cost.New(compiler, stats).Query(query), nil
+}
+
 func getBoolParam(url *url.URL, name string, ifEmpty bool) bool {
 
 	p, ok := url.Query()[name]
@@ -2154,6 +2932,74 @@ func getBoolParam(url *url.URL, name string, ifEmpty bool) bool {
 	return false
 }
 
+// page represents the results requested with the offset and limit
+// parameters. A limit of zero means all results after the offset.
+type page struct {
+	offset int
+	limit  int
+}
+
+// getPage returns the page requested by the offset and limit parameters or
+// nil if neither parameter is set.
+func getPage(url *url.URL) (*page, error) {
+
+	var p page
+	var found bool
+
+	for _, param := range []struct {
+		name string
+		dst  *int
+		min  int
+	}{
+		{types.ParamOffsetV1, &p.offset, 0},
+		{types.ParamLimitV1, &p.limit, 1},
+	} {
+		values, ok := url.Query()[param.name]
+		if !ok {
+			continue
+		}
+		found = true
+		n, err := strconv.Atoi(values[len(values)-1])
+		if err != nil || n < param.min {
+			return nil, types.BadRequestErr(fmt.Sprintf("invalid %v parameter: must be an integer greater than or equal to %d", param.name, param.min))
+		}
+		*param.dst = n
+	}
+
+	if !found {
+		return nil, nil
+	}
+
+	return &p, nil
+}
+
+// apply sorts the results so that pages are stable across requests and
+// replaces the results with the page. The results are ordered by the values
+// bound to the variables in order of the variable names (and the expression
+// values if they are included). If more results follow the page, the offset of
+// the next page is set on the response.
+func (p *page) apply(results *types.QueryResponseV1) {
+
+	rs := results.Result
+
+	sort.SliceStable(rs, func(i, j int) bool {
+		return util.Compare(map[string]interface{}(rs[i]), map[string]interface{}(rs[j])) < 0
+	})
+
+	start, end := p.offset, len(rs)
+
+	if start > end {
+		start = end
+	}
+
+	if p.limit > 0 && p.limit < end-start {
+		end = start + p.limit
+		results.NextOffset = &end
+	}
+
+	results.Result = rs[start:end]
+}
+
 func getWatch(p []string) (watch bool) {
 	return len(p) > 0
 }
@@ -2374,6 +3220,36 @@ type decisionLogger struct {
 	revision  string // Deprecated: Use `revisions` instead.
 	logger    func(context.Context, *Info) error
 	buffer    Buffer
+	store     storage.Store
+	snapshot  string
+	shadow    bool
+	override  bool
+}
+
+// withSnapshot returns a copy of the logger for decisions read from the
+// snapshot. If snap is nil, the logger is returned unchanged.
+func (l decisionLogger) withSnapshot(snap *snapshot) decisionLogger {
+	if snap != nil {
+		l.store = snap.store
+		l.snapshot = snap.name
+	}
+	return l
+}
+
+// withShadow returns a copy of the logger for decisions evaluated in shadow
+// mode.
+func (l decisionLogger) withShadow(shadow bool) decisionLogger {
+	l.shadow = shadow
+	return l
+}
+
+// withOverride returns a copy of the logger for decisions forced by an
+// override. Overridden decisions are not evaluated so they are never logged as
+// shadow decisions.
+func (l decisionLogger) withOverride() decisionLogger {
+	l.override = true
+	l.shadow = false
+	return l
 }
 
 func (l decisionLogger) Log(ctx context.Context, txn storage.Transaction, decisionID, remoteAddr, path string, query string, input *interface{}, results *interface{}, err error, m metrics.Metrics) error {
@@ -2396,6 +3272,23 @@ func (l decisionLogger) Log(ctx context.Context, txn storage.Transaction, decisi
 		Results:    results,
 		Error:      err,
 		Metrics:    m,
+		Shadow:     l.shadow,
+		Override:   l.override,
+	}
+
+	if txn != nil && l.store != nil {
+		rev, _, err := storage.Revision(ctx, l.store, txn)
+		if err != nil {
+			return errors.Wrap(err, "decision_logs")
+		}
+		info.StoreRevision = rev
+	}
+
+	// Transactions on snapshots cannot be used with the live store (e.g., by
+	// decision log masking policies) so they are not included.
+	if l.snapshot != "" {
+		info.Txn = nil
+		info.Snapshot = l.snapshot
 	}
 
 	if l.logger != nil {
@@ -2415,6 +3308,7 @@ type patchImpl struct {
 	path  storage.Path
 	op    storage.PatchOp
 	value interface{}
+	test  bool // if true, the patch checks the value instead of writing it
 }
 
 func parseURL(s string, useHTTPSByDefault bool) (*url.URL, error) {
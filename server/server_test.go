@@ -7,6 +7,7 @@ package server
 import (
 	"bufio"
 	"bytes"
+	"compress/gzip"
 	"context"
 	"crypto/tls"
 	"crypto/x509"
@@ -20,6 +21,7 @@ import (
 	"net/url"
 	"reflect"
 	"sort"
+	"strconv"
 	"strings"
 	"testing"
 	"time"
@@ -28,10 +30,13 @@ import (
 
 	"github.com/open-policy-agent/opa/ast"
 	"github.com/open-policy-agent/opa/bundle"
+	"github.com/open-policy-agent/opa/config"
+	"github.com/open-policy-agent/opa/internal/websocket"
 	"github.com/open-policy-agent/opa/metrics"
 	"github.com/open-policy-agent/opa/plugins"
 	pluginBundle "github.com/open-policy-agent/opa/plugins/bundle"
 	"github.com/open-policy-agent/opa/server/identifier"
+	"github.com/open-policy-agent/opa/server/limiter"
 	"github.com/open-policy-agent/opa/server/types"
 	"github.com/open-policy-agent/opa/storage"
 	"github.com/open-policy-agent/opa/storage/inmem"
@@ -294,6 +299,352 @@ func TestDataV0(t *testing.T) {
 	}
 }
 
+func TestDataGetETag(t *testing.T) {
+
+	f := newFixture(t)
+
+	if err := f.v1(http.MethodPut, "/data/x", `{"a": 1}`, 204, ""); err != nil {
+		t.Fatal(err)
+	}
+
+	get := func(path, etag string, code int) string {
+		t.Helper()
+		req := newReqV1(http.MethodGet, path, "")
+		if etag != "" {
+			req.Header.Set("If-None-Match", etag)
+		}
+		f.reset()
+		f.server.Handler.ServeHTTP(f.recorder, req)
+		if f.recorder.Code != code {
+			t.Fatalf("Expected %v from GET %v but got: %v", code, path, f.recorder)
+		}
+		return f.recorder.Header().Get("ETag")
+	}
+
+	etag := get("/data/x", "", 200)
+	if etag == "" {
+		t.Fatal("Expected ETag to be set")
+	}
+
+	if get("/data/x/a", `"foo", `+etag, 304) != etag {
+		t.Fatal("Expected ETag to be unchanged")
+	} else if f.recorder.Body.Len() != 0 {
+		t.Fatalf("Expected empty body but got: %v", f.recorder.Body)
+	}
+
+	if get("/data/x?metrics", etag, 200) != "" {
+		t.Fatal("Expected ETag to be omitted when metrics are requested")
+	}
+
+	if err := f.v1(http.MethodPut, "/data/x", `{"a": 2}`, 204, ""); err != nil {
+		t.Fatal(err)
+	}
+
+	if get("/data/x", etag, 200) == etag {
+		t.Fatal("Expected ETag to change after write")
+	}
+}
+
+func TestDataStoreRevision(t *testing.T) {
+
+	f := newFixture(t)
+
+	var logged []uint64
+
+	f.server = f.server.WithDecisionLoggerWithErr(func(_ context.Context, info *Info) error {
+		logged = append(logged, info.StoreRevision)
+		return nil
+	})
+
+	revision := func(req *http.Request) uint64 {
+		t.Helper()
+		f.reset()
+		f.server.Handler.ServeHTTP(f.recorder, req)
+		if f.recorder.Code != 200 {
+			t.Fatalf("Expected 200 but got: %v", f.recorder)
+		}
+		rev, err := strconv.ParseUint(f.recorder.Header().Get(types.HeaderStoreRevisionV1), 10, 64)
+		if err != nil {
+			t.Fatalf("Expected revision header but got: %v", f.recorder.Header())
+		}
+		return rev
+	}
+
+	before := revision(newReqV1(http.MethodGet, "/data/x", ""))
+
+	if err := f.v1(http.MethodPut, "/data/x", `1`, 204, ""); err != nil {
+		t.Fatal(err)
+	}
+
+	after := revision(newReqV1(http.MethodPost, "/data/x", ""))
+	if after != before+1 {
+		t.Fatalf("Expected revision %v but got %v", before+1, after)
+	}
+
+	if rev := revision(newReqV0(http.MethodPost, "/data/x", "")); rev != after {
+		t.Fatalf("Expected revision %v but got %v", after, rev)
+	}
+
+	exp := []uint64{before, after, after}
+	if !reflect.DeepEqual(logged, exp) {
+		t.Fatalf("Expected logged revisions %v but got %v", exp, logged)
+	}
+}
+
+func TestDataConditionalWrites(t *testing.T) {
+
+	f := newFixture(t)
+
+	if err := f.v1(http.MethodPut, "/data/x", `{"a": 1}`, 204, ""); err != nil {
+		t.Fatal(err)
+	}
+
+	write := func(method, path, body, ifMatch string, code int) {
+		t.Helper()
+		req := newReqV1(method, path, body)
+		if ifMatch != "" {
+			req.Header.Set("If-Match", ifMatch)
+		}
+		if err := f.executeRequest(req, code, ""); err != nil {
+			t.Fatal(err)
+		} else if code == 412 && !strings.Contains(f.recorder.Body.String(), types.CodePreconditionFailed) {
+			t.Fatalf("Expected precondition failed error but got: %v", f.recorder)
+		}
+	}
+
+	f.reset()
+	f.server.Handler.ServeHTTP(f.recorder, newReqV1(http.MethodGet, "/data/x", ""))
+	etag := f.recorder.Header().Get("ETag")
+
+	write(http.MethodPut, "/data/x/a", `2`, etag, 204)
+
+	// The tag is stale after the first write.
+	write(http.MethodPut, "/data/x/a", `3`, etag, 412)
+	write(http.MethodPatch, "/data/x", `[{"op": "add", "path": "b", "value": 3}]`, etag, 412)
+	write(http.MethodDelete, "/data/x/a", "", etag, 412)
+
+	write(http.MethodPut, "/data/y", `1`, "*", 412)
+	write(http.MethodDelete, "/data/x/a", "", "*", 204)
+
+	// Test operations reject the whole patch if the value does not match.
+	write(http.MethodPatch, "/data/x", `[{"op": "test", "path": "a", "value": 2}, {"op": "add", "path": "a", "value": 3}]`, "", 412)
+	write(http.MethodPatch, "/data", `[{"op": "test", "path": "x", "value": {}}, {"op": "add", "path": "x/a", "value": 4}]`, "", 204)
+
+	if err := f.v1(http.MethodGet, "/data/x", "", 200, `{"result": {"a": 4}}`); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestExportImport(t *testing.T) {
+
+	f := newFixture(t)
+
+	if err := f.v1(http.MethodPut, "/data/x", `{"a": [1, 2], "b": {"c": true}}`, 204, ""); err != nil {
+		t.Fatal(err)
+	}
+
+	// Policies are not evaluated by exports.
+	if err := f.v1(http.MethodPut, "/policies/test", "package x\n\np = 1", 200, ""); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := f.v1(http.MethodGet, "/export/x", "", 200, `{"a": [1, 2], "b": {"c": true}}`); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := f.v1(http.MethodGet, "/export/x/missing", "", 404, ""); err != nil {
+		t.Fatal(err)
+	}
+
+	req := newReqV1(http.MethodGet, "/export/x", "")
+	req.Header.Set("Accept", "application/gzip")
+	if err := f.executeRequest(req, 200, ""); err != nil {
+		t.Fatal(err)
+	}
+
+	tarball := f.recorder.Body.String()
+
+	req = newReqV1(http.MethodGet, "/export/x/a", "")
+	req.Header.Set("Accept", "application/gzip")
+	if err := f.executeRequest(req, 400, ""); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := f.v1(http.MethodPut, "/import/y", `{"d": 1}`, 204, ""); err != nil {
+		t.Fatal(err)
+	}
+
+	req = newReqV1(http.MethodPut, "/import/y", tarball)
+	req.Header.Set("Content-Type", "application/gzip")
+	if err := f.executeRequest(req, 204, ""); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := f.v1(http.MethodGet, "/data/y", "", 200, `{"result": {"a": [1, 2], "b": {"c": true}}}`); err != nil {
+		t.Fatal(err)
+	}
+
+	// Failed imports do not modify the store.
+	req = newReqV1(http.MethodPut, "/import/y", "not a tarball")
+	req.Header.Set("Content-Type", "application/gzip")
+	if err := f.executeRequest(req, 400, ""); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := f.v1(http.MethodPut, "/import/x/p", `1`, 400, ""); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := f.v1(http.MethodGet, "/export", "", 200, `{"x": {"a": [1, 2], "b": {"c": true}}, "y": {"a": [1, 2], "b": {"c": true}}}`); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestStorageStatsCompact(t *testing.T) {
+
+	f := newFixture(t)
+
+	if err := f.v1(http.MethodPut, "/data/x", `{"a": [1, 2], "b": {"c": true}}`, 204, ""); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := f.v1(http.MethodDelete, "/data/x/b", "", 204, ""); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := f.v1(http.MethodGet, "/storage/stats/x", "", 200, `{
+		"patches": 2,
+		"result": {"bytes": 11, "values": 4, "children": {"a": {"bytes": 5, "values": 3}}}
+	}`); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := f.v1(http.MethodGet, "/storage/stats/x?depth=0", "", 200, `{"patches": 2, "result": {"bytes": 11, "values": 4}}`); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := f.v1(http.MethodGet, "/storage/stats/x?depth=-1", "", 400, ""); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := f.v1(http.MethodGet, "/storage/stats/missing", "", 404, ""); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := f.v1(http.MethodPost, "/storage/compact", "", 200, `{"patches": 2}`); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := f.v1(http.MethodGet, "/storage/stats/x?depth=0", "", 200, `{"patches": 0, "result": {"bytes": 11, "values": 4}}`); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := f.v1(http.MethodGet, "/data/x", "", 200, `{"result": {"a": [1, 2]}}`); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestDataCompression(t *testing.T) {
+
+	f := newFixture(t)
+
+	var buf bytes.Buffer
+	gw := gzip.NewWriter(&buf)
+	if _, err := gw.Write([]byte(`{"a": [1, 2, 3]}`)); err != nil {
+		t.Fatal(err)
+	} else if err := gw.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	req := newReqV1(http.MethodPut, "/data/x", buf.String())
+	req.Header.Set("Content-Encoding", "gzip")
+	if err := f.executeRequest(req, 204, ""); err != nil {
+		t.Fatal(err)
+	}
+
+	req = newReqV1(http.MethodPut, "/data/x", "not gzip")
+	req.Header.Set("Content-Encoding", "gzip")
+	if err := f.executeRequest(req, 400, ""); err != nil {
+		t.Fatal(err)
+	}
+
+	req = newReqV1(http.MethodGet, "/data/x", "")
+	req.Header.Set("Accept-Encoding", "deflate, gzip;q=1.0")
+	f.reset()
+	f.server.Handler.ServeHTTP(f.recorder, req)
+
+	if f.recorder.Code != 200 || f.recorder.Header().Get("Content-Encoding") != "gzip" {
+		t.Fatalf("Expected gzip encoded response but got: %v", f.recorder)
+	}
+
+	gr, err := gzip.NewReader(f.recorder.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var result types.DataResponseV1
+	if err := util.NewJSONDecoder(gr).Decode(&result); err != nil {
+		t.Fatal(err)
+	}
+
+	var exp interface{} = map[string]interface{}{"a": []interface{}{json.Number("1"), json.Number("2"), json.Number("3")}}
+	if result.Result == nil || !reflect.DeepEqual(*result.Result, exp) {
+		t.Fatalf("Expected %v but got: %v", exp, result.Result)
+	}
+
+	// Responses without bodies are not encoded.
+	req = newReqV1(http.MethodPut, "/data/y", "1")
+	req.Header.Set("Accept-Encoding", "gzip")
+	f.reset()
+	f.server.Handler.ServeHTTP(f.recorder, req)
+
+	if f.recorder.Code != 204 || f.recorder.Header().Get("Content-Encoding") != "" {
+		t.Fatalf("Expected unencoded response but got: %v", f.recorder)
+	}
+}
+
+func TestDataV0ContentNegotiation(t *testing.T) {
+	testMod := `package test
+
+	p = {"servers": [x | x = input.servers[_].name]}
+	`
+
+	f := newFixture(t)
+
+	if err := f.v1(http.MethodPut, "/policies/test", testMod, 200, ""); err != nil {
+		t.Fatalf("Unexpected error while creating policy: %v", err)
+	}
+
+	req := newReqV0(http.MethodPost, "/data/test/p", "servers:\n- name: a\n- name: b\n")
+	req.Header.Set("Content-Type", "application/x-yaml")
+	req.Header.Set("Accept", "application/x-yaml")
+	f.reset()
+	f.server.Handler.ServeHTTP(f.recorder, req)
+
+	if f.recorder.Code != 200 {
+		t.Fatalf("Expected HTTP 200 but got: %v", f.recorder)
+	}
+
+	if ct := f.recorder.Header().Get("Content-Type"); ct != "application/x-yaml" {
+		t.Fatalf("Expected YAML content type but got: %v", ct)
+	}
+
+	if exp, result := "servers:\n- a\n- b\n", f.recorder.Body.String(); result != exp {
+		t.Fatalf("Expected %q but got: %q", exp, result)
+	}
+
+	if err := f.v0(http.MethodPost, "/data/test/p", `{"servers": [{"name": "a"}]}`, 200, `{"servers": ["a"]}`); err != nil {
+		t.Fatal(err)
+	}
+
+	f.reset()
+	f.server.Handler.ServeHTTP(f.recorder, newReqV0(http.MethodPost, "/data/test/p?pretty", `{"servers": [{"name": "a"}]}`))
+
+	if exp, result := "{\n  \"servers\": [\n    \"a\"\n  ]\n}\n", f.recorder.Body.String(); result != exp {
+		t.Fatalf("Expected %q but got: %q", exp, result)
+	}
+}
+
 // Tests that the responses for (theoretically) valid resources but with forbidden methods return the proper status code
 func Test405StatusCodev1(t *testing.T) {
 	tests := []struct {
@@ -1146,6 +1497,19 @@ func TestBundleScope(t *testing.T) {
 			body:   "1",
 			code:   http.StatusNoContent,
 		},
+		{
+			method: "PUT",
+			path:   "/data/a/b/cd",
+			body:   "1",
+			code:   http.StatusNoContent,
+		},
+		{
+			method: "PUT",
+			path:   "/policies/test2",
+			body:   `package x.yz`,
+			code:   http.StatusOK,
+			resp:   `{}`,
+		},
 	}
 
 	if err := f.v1TestRequests(cases); err != nil {
@@ -1891,6 +2255,9 @@ func TestDataMetrics(t *testing.T) {
 		"timer_rego_query_compile_ns",
 		"timer_rego_query_eval_ns",
 		"timer_server_handler_ns",
+		"counter_rego_eval_exprs",
+		"counter_rego_eval_storage_reads",
+		"counter_rego_eval_virtual_cache_hits",
 	}
 
 	for _, key := range expected {
@@ -2304,52 +2671,266 @@ func TestQueryPostBasic(t *testing.T) {
 	}
 }
 
-func TestQueryWatchBasic(t *testing.T) {
-	// Test basic watch results.
-	exp := strings.Join([]string{
-		"HTTP/1.1 200 OK\nContent-Type: application/json\nTransfer-Encoding: chunked\n\n10",
-		`{"result":null}
-`,
-		`7c`,
-		`{"result":[{"expressions":[{"value":true,"text":"a=data.x","location":{"row":1,"col":1}}],"bindings":{"a":{"a":1,"b":2}}}]}
-`,
-		`74`,
-		`{"result":[{"expressions":[{"value":true,"text":"a=data.x","location":{"row":1,"col":1}}],"bindings":{"a":"foo"}}]}
-`,
-		`70`,
-		`{"result":[{"expressions":[{"value":true,"text":"a=data.x","location":{"row":1,"col":1}}],"bindings":{"a":7}}]}
-`,
-		``,
-	}, "\r\n")
+func TestServerLimits(t *testing.T) {
 
-	requests := []*http.Request{
-		newReqV1(http.MethodGet, `/query?q=a=data.x&watch`, ""),
-		newReqV1(http.MethodPost, `/query?&watch`, `{"query": "a=data.x"}`),
-	}
+	f := newFixture(t, func(s *Server) {
+		s.WithMaxQueryExpressions(2).
+			WithMaxRequestBodySize(64).
+			WithRateLimits(map[string]limiter.Rate{"/v1/compile": {Limit: 1}})
+	})
 
-	for _, get := range requests {
-		f := newFixture(t)
-		recorder := newMockConn()
-		go f.server.Handler.ServeHTTP(recorder, get)
-		<-recorder.hijacked
-		<-recorder.write
+	if err := f.v1(http.MethodGet, "/query?q=x%3D1%3By%3D2", "", 200, `{"result": [{"x": 1, "y": 2}]}`); err != nil {
+		t.Fatal(err)
+	}
 
-		tests := []trw{
-			{tr{http.MethodPut, "/data/x", `{"a":1,"b":2}`, 204, ""}, recorder.write},
-			{tr{http.MethodPut, "/data/x", `"foo"`, 204, ""}, recorder.write},
-			{tr{http.MethodPut, "/data/x", `7`, 204, ""}, recorder.write},
-		}
+	tooComplex := `{"code": "invalid_parameter", "message": "query exceeds maximum of 2 expressions"}`
 
-		for _, test := range tests {
-			tr := test.tr
-			if err := f.v1(tr.method, tr.path, tr.body, tr.code, tr.resp); err != nil {
-				t.Fatal(err)
-			}
-			if test.wait != nil {
-				<-test.wait
-			}
-		}
-		recorder.Close()
+	if err := f.v1(http.MethodGet, "/query?q=x%3D1%3By%3D%5Bz%7Cz%3D1%5D", "", 400, tooComplex); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := f.v1(http.MethodPost, "/query", `{"query": "x=1;y=2;z=3"}`, 400, tooComplex); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := f.v1(http.MethodPost, "/compile", `{"query": "x=1;y=2;z=3"}`, 400, tooComplex); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := f.v1(http.MethodPost, "/compile", `{"query": "x=1"}`, 429, `{"code": "too_many_requests", "message": "rate limit exceeded for /v1/compile"}`); err != nil {
+		t.Fatal(err)
+	}
+
+	tooLarge := `{"code": "request_too_large", "message": "request body exceeds maximum size of 64 bytes"}`
+
+	if err := f.v1(http.MethodPut, "/data/x", `{"value": "`+strings.Repeat("x", 64)+`"}`, 413, tooLarge); err != nil {
+		t.Fatal(err)
+	}
+
+	// Requests that do not declare their size are rejected the same way.
+	req := newReqV1(http.MethodPut, "/data/x", `{"value": "`+strings.Repeat("x", 64)+`"}`)
+	req.ContentLength = -1
+
+	if err := f.executeRequest(req, 413, tooLarge); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestServerResultLimit(t *testing.T) {
+
+	f := newFixture(t, func(s *Server) {
+		s.WithMaxQueryResults(2)
+	})
+
+	if err := f.v1(http.MethodGet, "/query?q=a%3D%5B1%2C2%5D%3Ba%5B_%5D%3Dx", "", 200, `{"result": [{"a": [1, 2], "x": 1}, {"a": [1, 2], "x": 2}]}`); err != nil {
+		t.Fatal(err)
+	}
+
+	tooMany := `{
+		"code": "invalid_parameter",
+		"message": "error(s) occurred while evaluating query",
+		"errors": [{"code": "eval_result_limit_error", "message": "query produced more than 2 results"}]
+	}`
+
+	if err := f.v1(http.MethodPost, "/query", `{"query": "a=[1,2]; a[_]=x; a[_]=y"}`, 400, tooMany); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestServerQueryCost(t *testing.T) {
+
+	f := newFixture(t, func(s *Server) {
+		s.WithMaxQueryCost(1000)
+	})
+
+	if err := f.v1(http.MethodPut, "/data/xs", `[1, 2, 3, 4, 5, 6, 7, 8, 9, 10]`, 204, ""); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := f.v1(http.MethodGet, "/query?q=data.xs[i]%3Bdata.xs[j]%3Bi%3D0%3Bj%3D0", "", 200, `{"result": [{"i": 0, "j": 0}]}`); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := f.v1(http.MethodPost, "/query/validate", `{"query": "data.xs[i]; data.xs[j]; data.xs[k]"}`, 200, `{"vars": {"i": {"type": "any", "of": []}, "j": {"type": "any", "of": []}, "k": {"type": "any", "of": []}}, "cost": {"rows": 1000, "cost": 1110}}`); err != nil {
+		t.Fatal(err)
+	}
+
+	tooExpensive := `{
+		"code": "invalid_parameter",
+		"message": "query exceeds maximum cost of 1000 (estimated cost 1110)"
+	}`
+
+	if err := f.v1(http.MethodPost, "/query", `{"query": "data.xs[i]; data.xs[j]; data.xs[k]"}`, 400, tooExpensive); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestQueryExpressions(t *testing.T) {
+	f := newFixture(t)
+
+	if err := f.v1(http.MethodPut, "/data/x", `{"a": {"b": 1}, "c": {"d": 2}}`, 204, ""); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []tr{
+		{http.MethodGet, "/query?q=data.x[k]&expressions", "", 200, `{"result": [{"bindings": {"k": "a"}, "expressions": [{"b": 1}]}, {"bindings": {"k": "c"}, "expressions": [{"d": 2}]}]}`},
+		{http.MethodPost, "/query?expressions", `{"query": "data.x[k].d = v; true"}`, 200, `{"result": [{"bindings": {"k": "c", "v": 2}, "expressions": [true, true]}]}`},
+		{http.MethodGet, "/query?q=data.x[k].b", "", 200, `{"result": [{"k": "a"}]}`},
+	}
+
+	for _, tr := range tests {
+		req := newReqV1(tr.method, tr.path, tr.body)
+		if err := f.executeRequest(req, tr.code, tr.resp); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestQueryPagination(t *testing.T) {
+	f := newFixture(t)
+
+	if err := f.v1(http.MethodPut, "/data/x", `{"d": 4, "b": 2, "e": 5, "a": 1, "c": 3}`, 204, ""); err != nil {
+		t.Fatal(err)
+	}
+
+	badOffset := `{"code": "invalid_parameter", "message": "invalid offset parameter: must be an integer greater than or equal to 0"}`
+	badLimit := `{"code": "invalid_parameter", "message": "invalid limit parameter: must be an integer greater than or equal to 1"}`
+
+	tests := []tr{
+		{http.MethodGet, "/query?q=data.x[k]=v&limit=2", "", 200, `{"result": [{"k": "a", "v": 1}, {"k": "b", "v": 2}], "next_offset": 2}`},
+		{http.MethodGet, "/query?q=data.x[k]=v&limit=2&offset=2", "", 200, `{"result": [{"k": "c", "v": 3}, {"k": "d", "v": 4}], "next_offset": 4}`},
+		{http.MethodGet, "/query?q=data.x[k]=v&limit=2&offset=4", "", 200, `{"result": [{"k": "e", "v": 5}]}`},
+		{http.MethodGet, "/query?q=data.x[k]=v&offset=3", "", 200, `{"result": [{"k": "d", "v": 4}, {"k": "e", "v": 5}]}`},
+		{http.MethodGet, "/query?q=data.x[k]=v&offset=10", "", 200, `{}`},
+		{http.MethodGet, "/query?q=data.x[k]=v&limit=9223372036854775807&offset=1", "", 200, `{"result": [{"k": "b", "v": 2}, {"k": "c", "v": 3}, {"k": "d", "v": 4}, {"k": "e", "v": 5}]}`},
+		{http.MethodPost, "/query?limit=1&offset=1&expressions", `{"query": "v = data.x[k]; v > 1"}`, 200, `{"result": [{"bindings": {"k": "c", "v": 3}, "expressions": [true, true]}], "next_offset": 2}`},
+		{http.MethodGet, "/query?q=data.x[k]=v&offset=-1", "", 400, badOffset},
+		{http.MethodPost, "/query?limit=0", `{"query": "data.x[k]=v"}`, 400, badLimit},
+		{http.MethodGet, "/query?q=data.x[k]=v&limit=x", "", 400, badLimit},
+	}
+
+	for _, tr := range tests {
+		req := newReqV1(tr.method, tr.path, tr.body)
+		if err := f.executeRequest(req, tr.code, tr.resp); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestViolationsPost(t *testing.T) {
+	f := newFixture(t)
+
+	module := `package test
+
+# Containers must not run as root.
+deny[msg] {
+	input.user == "root"
+	msg := "running as root"
+}
+
+violation[{"msg": "too many replicas", "replicas": n}] {
+	n := input.replicas
+	n > 3
+}
+`
+
+	if err := f.v1(http.MethodPut, "/policies/test", module, 200, ""); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []tr{
+		{http.MethodPost, "/violations/test", `{"input": {"user": "root", "replicas": 5}}`, 200, `{"result": [
+			{"rule": "data.test.deny", "msg": "running as root", "value": "running as root", "description": "Containers must not run as root.", "location": {"file": "test", "row": 4, "col": 1}},
+			{"rule": "data.test.violation", "msg": "too many replicas", "value": {"msg": "too many replicas", "replicas": 5}, "location": {"file": "test", "row": 9, "col": 1}}
+		]}`},
+		{http.MethodPost, "/violations/test?rule=violation", `{"input": {"user": "root", "replicas": 5}}`, 200, `{"result": [
+			{"rule": "data.test.violation", "msg": "too many replicas", "value": {"msg": "too many replicas", "replicas": 5}, "location": {"file": "test", "row": 9, "col": 1}}
+		]}`},
+		{http.MethodPost, "/violations/test", `{"input": {"user": "alice", "replicas": 1}}`, 200, `{"result": []}`},
+		{http.MethodPost, "/violations/test", `{"input": `, 400, ""},
+	}
+
+	for _, tr := range tests {
+		req := newReqV1(tr.method, tr.path, tr.body)
+		if err := f.executeRequest(req, tr.code, tr.resp); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestQueryValidatePost(t *testing.T) {
+	f := newFixture(t)
+
+	module := `package test
+
+	p = 1
+	`
+
+	if err := f.v1(http.MethodPut, "/policies/test", module, 200, ""); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []tr{
+		{http.MethodPost, "/query/validate", `{"query": "x := data.test.p, y = concat(\",\", [\"a\"]), z = [q | q = 1], input[_] = w"}`, 200, `{"vars": {"x": {"type": "number"}, "y": {"type": "string"}, "z": {"type": "array", "dynamic": {"type": "number"}}, "w": {"type": "any", "of": []}}, "cost": {"rows": 10, "cost": 16}}`},
+		{http.MethodPost, "/query/validate", `{"query": "x := data.test.p; x = \"foo\""}`, 400, ""},
+		{http.MethodPost, "/query/validate", `{"query": "x :="}`, 400, ""},
+		{http.MethodPost, "/query/validate", `{"query": "http.send({}, x)"}`, 400, ""},
+	}
+
+	for _, tr := range tests {
+		req := newReqV1(tr.method, tr.path, tr.body)
+		if err := f.executeRequest(req, tr.code, tr.resp); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestQueryWatchBasic(t *testing.T) {
+	// Test basic watch results.
+	exp := strings.Join([]string{
+		"HTTP/1.1 200 OK\nContent-Type: application/json\nTransfer-Encoding: chunked\n\n10",
+		`{"result":null}
+`,
+		`7c`,
+		`{"result":[{"expressions":[{"value":true,"text":"a=data.x","location":{"row":1,"col":1}}],"bindings":{"a":{"a":1,"b":2}}}]}
+`,
+		`74`,
+		`{"result":[{"expressions":[{"value":true,"text":"a=data.x","location":{"row":1,"col":1}}],"bindings":{"a":"foo"}}]}
+`,
+		`70`,
+		`{"result":[{"expressions":[{"value":true,"text":"a=data.x","location":{"row":1,"col":1}}],"bindings":{"a":7}}]}
+`,
+		``,
+	}, "\r\n")
+
+	requests := []*http.Request{
+		newReqV1(http.MethodGet, `/query?q=a=data.x&watch`, ""),
+		newReqV1(http.MethodPost, `/query?&watch`, `{"query": "a=data.x"}`),
+	}
+
+	for _, get := range requests {
+		f := newFixture(t)
+		recorder := newMockConn()
+		go f.server.Handler.ServeHTTP(recorder, get)
+		<-recorder.hijacked
+		<-recorder.write
+
+		tests := []trw{
+			{tr{http.MethodPut, "/data/x", `{"a":1,"b":2}`, 204, ""}, recorder.write},
+			{tr{http.MethodPut, "/data/x", `"foo"`, 204, ""}, recorder.write},
+			{tr{http.MethodPut, "/data/x", `7`, 204, ""}, recorder.write},
+		}
+
+		for _, test := range tests {
+			tr := test.tr
+			if err := f.v1(tr.method, tr.path, tr.body, tr.code, tr.resp); err != nil {
+				t.Fatal(err)
+			}
+			if test.wait != nil {
+				<-test.wait
+			}
+		}
+		recorder.Close()
 
 		if result := recorder.buf.String(); result != exp {
 			t.Fatalf("Expected stream to equal %s, got %s", exp, result)
@@ -2809,6 +3390,77 @@ func TestDecisionLogErrorMessage(t *testing.T) {
 	}
 }
 
+func TestSubscribe(t *testing.T) {
+	f := newFixture(t)
+
+	if err := f.v1(http.MethodPut, "/data/x", `{"a":1}`, 204, ""); err != nil {
+		t.Fatal(err)
+	}
+
+	ts := httptest.NewServer(f.server.Handler)
+	defer ts.Close()
+
+	conn, err := websocket.Dial(ts.URL + "/v1/subscribe")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer conn.Close()
+
+	send := func(msg string) {
+		if err := conn.WriteMessage([]byte(msg)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	expect := func(exp string) {
+		t.Helper()
+		bs, err := conn.ReadMessage()
+		if err != nil {
+			t.Fatal(err)
+		}
+		var result, expected interface{}
+		if err := util.UnmarshalJSON(bs, &result); err != nil {
+			t.Fatal(err)
+		}
+		if err := util.UnmarshalJSON([]byte(exp), &expected); err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(result, expected) {
+			t.Fatalf("Expected %v but got %v", expected, result)
+		}
+	}
+
+	send(`{"id": "q1", "query": "a = data.x.a"}`)
+	expect(`{"id": "q1", "result": [{"a": 1}]}`)
+
+	if err := f.v1(http.MethodPut, "/data/x/a", `2`, 204, ""); err != nil {
+		t.Fatal(err)
+	}
+
+	expect(`{"id": "q1", "result": [{"a": 2}]}`)
+
+	send(`{"id": "q2", "query": "a = "}`)
+
+	bs, err := conn.ReadMessage()
+	if err != nil {
+		t.Fatal(err)
+	} else if !strings.Contains(string(bs), types.CodeInvalidParameter) {
+		t.Fatalf("Expected parse error but got: %s", bs)
+	}
+
+	send(`{"id": "q1", "unsubscribe": true}`)
+	send(`{"id": "q3", "query": "b = data.x.a"}`)
+	expect(`{"id": "q3", "result": [{"b": 2}]}`)
+}
+
+func TestSubscribeNotUpgraded(t *testing.T) {
+	f := newFixture(t)
+	if err := f.v1(http.MethodGet, "/subscribe", "", 400, ""); err != nil {
+		t.Fatal(err)
+	}
+}
+
 func TestWatchParams(t *testing.T) {
 	f := newFixture(t)
 	r1 := newMockConn()
@@ -3335,6 +3987,397 @@ q[x] { p[x]; not r[x] }
 r[x] { z[x] = 4 }`
 )
 
+func TestDecisionCache(t *testing.T) {
+	f := newFixture(t, func(s *Server) {
+		s.WithDecisionCache(time.Minute, 10)
+	})
+
+	if err := f.v1(http.MethodPut, "/policies/test", "package test\n\np = x { x := input.x }", 200, ""); err != nil {
+		t.Fatal(err)
+	}
+
+	expStats := func(hits, misses uint64) {
+		t.Helper()
+		if h, m := f.server.DecisionCacheStats(); h != hits || m != misses {
+			t.Fatalf("Expected %d hits and %d misses but got %d and %d", hits, misses, h, m)
+		}
+	}
+
+	steps := []struct {
+		method string
+		path   string
+		body   string
+		resp   string
+		hits   uint64
+		misses uint64
+	}{
+		{http.MethodPost, "/data/test/p", `{"input": {"x": 1}}`, `{"result": 1}`, 0, 1},
+		{http.MethodPost, "/data/test/p", `{"input": {"x": 1}}`, `{"result": 1}`, 1, 1},
+		{http.MethodGet, "/data/test/p?input=" + url.QueryEscape(`{"x": 1}`), "", `{"result": 1}`, 2, 1},
+		{http.MethodPost, "/data/test/p", `{"input": {"x": 2}}`, `{"result": 2}`, 2, 2},
+		{http.MethodPost, "/data/test/q", `{}`, `{}`, 2, 3},
+		{http.MethodPost, "/data/test/q", `{}`, `{}`, 3, 3},
+		{http.MethodPost, "/data/test/p?explain=full", `{"input": {"x": 1}}`, "", 3, 3},
+		{http.MethodPut, "/data/foo", `1`, "", 3, 3},
+		{http.MethodPost, "/data/test/p", `{"input": {"x": 1}}`, `{"result": 1}`, 3, 4},
+	}
+
+	for _, step := range steps {
+		code := 200
+		if step.method == http.MethodPut {
+			code = 204
+		}
+		if err := f.v1(step.method, step.path, step.body, code, step.resp); err != nil {
+			t.Fatal(err)
+		}
+		expStats(step.hits, step.misses)
+	}
+}
+
+func TestDecisionCacheEviction(t *testing.T) {
+	c := newDecisionCache(time.Minute, 2)
+	now := time.Now()
+
+	keys := make([]decisionCacheKey, 3)
+	for i := range keys {
+		var input interface{} = json.Number(fmt.Sprint(i))
+		keys[i], _ = c.Key("data.test.p", &input)
+		c.Put(keys[i], &input, now)
+	}
+
+	if _, ok := c.Get(keys[0], now); ok {
+		t.Fatal("Expected least recently used decision to be evicted")
+	}
+
+	if result, ok := c.Get(keys[2], now); !ok || util.Compare(*result, json.Number("2")) != 0 {
+		t.Fatalf("Expected cached decision but got: %v", result)
+	}
+
+	if _, ok := c.Get(keys[2], now.Add(time.Minute+time.Second)); ok {
+		t.Fatal("Expected expired decision to be a miss")
+	}
+
+	// decisions evaluated before the store was written to are not cached
+	key, _ := c.Key("data.test.q", nil)
+	c.Invalidate()
+	c.Put(key, nil, now)
+
+	if _, ok := c.Get(key, now); ok {
+		t.Fatal("Expected stale decision not to be cached")
+	}
+}
+
+func TestInputValidation(t *testing.T) {
+	f := newFixture(t, func(s *Server) {
+		s.WithInputValidation(true)
+	})
+
+	invalid := `{
+		"code": "invalid_parameter",
+		"message": "input does not conform to schema",
+		"errors": [
+			{"field": "input.user.name", "expected": "string", "actual": "number"},
+			{"field": "input.xs[1]", "expected": "number", "actual": "string"}
+		]
+	}`
+
+	err := f.v1TestRequests([]tr{
+		{http.MethodPut, "/policies/test", "package test\n\np { startswith(input.user.name, \"a\"); input.xs[_] > 1 }\n\nq = 1", 200, ""},
+		{http.MethodPost, "/data/test/p", `{"input": {"user": {"name": "alice"}, "xs": [1, 2]}}`, 200, `{"result": true}`},
+		{http.MethodPost, "/data/test/p", `{"input": {"user": {"name": 1}, "xs": [1, "2"]}}`, 400, invalid},
+		{http.MethodGet, "/data/test/p?input=" + url.QueryEscape(`{"user": {"name": 1}, "xs": [1, "2"]}`), "", 400, invalid},
+		{http.MethodPost, "/data/test", `{"input": {"user": "alice"}}`, 400, ""},
+		{http.MethodPost, "/data/test/p", `{"input": {}}`, 200, `{}`},
+		{http.MethodPost, "/data/test/q", `{"input": {"user": "alice"}}`, 200, `{"result": 1}`},
+		{http.MethodPut, "/policies/test", "package test\n\np { input.user == \"alice\" }", 200, ""},
+		{http.MethodPost, "/data/test/p", `{"input": {"user": "alice"}}`, 200, `{"result": true}`},
+		{http.MethodPut, "/policies/test", "package test\n\nallow { input.user == \"root\" }\n\nallow { input.user.name == \"bob\" }\n\nport_ok { input.port == 80 }\n\nport_ok { input.port == \"any\" }", 200, ""},
+		{http.MethodPost, "/data/test/allow", `{"input": {"user": "root"}}`, 200, `{"result": true}`},
+		{http.MethodPost, "/data/test/allow", `{"input": {"user": {"name": "bob"}}}`, 200, `{"result": true}`},
+		{http.MethodPost, "/data/test/allow", `{"input": {"user": 1}}`, 400, `{
+			"code": "invalid_parameter",
+			"message": "input does not conform to schema",
+			"errors": [{"field": "input.user", "expected": "object or string", "actual": "number"}]
+		}`},
+		{http.MethodPost, "/data/test/port_ok", `{"input": {"port": 80}}`, 200, `{"result": true}`},
+		{http.MethodPost, "/data/test/port_ok", `{"input": {"port": "any"}}`, 200, `{"result": true}`},
+		{http.MethodPost, "/data/test/port_ok", `{"input": {"port": true}}`, 400, `{
+			"code": "invalid_parameter",
+			"message": "input does not conform to schema",
+			"errors": [{"field": "input.port", "expected": "number or string", "actual": "boolean"}]
+		}`},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := f.v0(http.MethodPost, "/data/test/allow", `{"user": 1}`, 400, ""); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestShadowDecisions(t *testing.T) {
+	var infos []*Info
+	f := newFixture(t, func(s *Server) {
+		s.manager.Config.ShadowDecisions = []config.ShadowDecision{
+			{Path: "test/p", Default: json.RawMessage(`true`)},
+			{Path: "/test/q"},
+		}
+		s.WithDecisionLogger(func(_ context.Context, info *Info) {
+			infos = append(infos, info)
+		})
+		s.WithInputValidation(true)
+	})
+
+	err := f.v1TestRequests([]tr{
+		{http.MethodPut, "/policies/test", "package test\n\np = x { x := input.x == 1 }\n\nq = {\"a\": x | x := input.xs[_]}\n\nr = input.x", 200, ""},
+		{http.MethodPost, "/data/test/p", `{"input": {"x": 2}}`, 200, `{"result": true}`},
+		{http.MethodGet, "/data/test/p", "", 200, `{"result": true}`},
+		{http.MethodPost, "/data/test/p", `{"input": {"x": "a"}}`, 200, `{"result": true}`},
+		{http.MethodPost, "/data/test/q", `{"input": {"xs": [1, 2]}}`, 200, `{}`},
+		{http.MethodPost, "/data/test/r", `{"input": {"x": 2}}`, 200, `{"result": 2}`},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(infos) != 5 {
+		t.Fatalf("Expected 5 decisions to be logged but got %d", len(infos))
+	}
+
+	if infos[0].Results == nil || util.Compare(*infos[0].Results, false) != 0 || !infos[0].Shadow {
+		t.Fatalf("Expected shadow decision to be logged with result but got: %+v", infos[0])
+	}
+
+	if infos[3].Error == nil || !infos[3].Shadow {
+		t.Fatalf("Expected shadow decision to be logged with error but got: %+v", infos[3])
+	}
+
+	if infos[4].Shadow {
+		t.Fatalf("Expected decision not to be evaluated in shadow mode: %+v", infos[4])
+	}
+}
+
+func TestOverrides(t *testing.T) {
+	var infos []*Info
+	f := newFixture(t, func(s *Server) {
+		s.WithDecisionLogger(func(_ context.Context, info *Info) {
+			infos = append(infos, info)
+		})
+	})
+
+	err := f.v1TestRequests([]tr{
+		{http.MethodPut, "/policies/test", "package test\n\nallow { input.user == \"admin\" }", 200, ""},
+		{http.MethodPost, "/data/test/allow", `{"input": {"user": "bob"}}`, 200, `{}`},
+		{http.MethodPut, "/overrides/test/allow", `{"value": true}`, 400, ""},
+		{http.MethodPut, "/overrides/test/allow", `{"value": true, "ttl": "-1m"}`, 400, ""},
+		{http.MethodPut, "/overrides/test/allow", `{"ttl": "1m"}`, 400, ""},
+		{http.MethodPut, "/overrides/test/allow", `{"value": true, "ttl": "10m", "reason": "incident"}`, 200, ""},
+		{http.MethodPost, "/data/test/allow", `{"input": {"user": "bob"}}`, 200, `{"result": true}`},
+		{http.MethodGet, "/data/test/allow", "", 200, `{"result": true}`},
+		{http.MethodGet, "/overrides/test/allow", "", 200, ""},
+		{http.MethodGet, "/overrides/test/missing", "", 404, ""},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	last := infos[len(infos)-1]
+	if !last.Override || last.Results == nil || *last.Results != true || last.Path != "data.test.allow" {
+		t.Fatalf("Expected overridden decision to be logged but got: %+v", last)
+	}
+
+	if err := f.v1(http.MethodGet, "/overrides", "", 200, ""); err != nil {
+		t.Fatal(err)
+	}
+
+	var list types.OverrideListResponseV1
+	if err := util.NewJSONDecoder(f.recorder.Body).Decode(&list); err != nil {
+		t.Fatal(err)
+	} else if len(list.Result) != 1 || list.Result[0].Path != "test/allow" || list.Result[0].Reason != "incident" || list.Result[0].Expires.Sub(list.Result[0].Created) != 10*time.Minute {
+		t.Fatalf("Unexpected overrides: %v", util.MustMarshalJSON(list))
+	}
+
+	// expired overrides are removed
+	f.server.overridesMtx.Lock()
+	f.server.overrides["data.test.allow"].expires = time.Now()
+	f.server.overridesMtx.Unlock()
+
+	err = f.v1TestRequests([]tr{
+		{http.MethodPost, "/data/test/allow", `{"input": {"user": "bob"}}`, 200, `{}`},
+		{http.MethodGet, "/overrides", "", 200, `{"result": []}`},
+		{http.MethodPut, "/overrides/test/allow", `{"value": false, "ttl": "1h"}`, 200, ""},
+		{http.MethodPost, "/data/test/allow", `{"input": {"user": "admin"}}`, 200, `{"result": false}`},
+		{http.MethodDelete, "/overrides/test/allow", "", 204, ""},
+		{http.MethodDelete, "/overrides/test/allow", "", 404, ""},
+		{http.MethodPost, "/data/test/allow", `{"input": {"user": "admin"}}`, 200, `{"result": true}`},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestCanary(t *testing.T) {
+	f := newFixture(t)
+
+	var buf bytes.Buffer
+	err := bundle.Write(&buf, bundle.Bundle{
+		Manifest: bundle.Manifest{Revision: "r2", Roots: &[]string{"test"}},
+		Data:     map[string]interface{}{},
+		Modules: []bundle.ModuleFile{
+			{Path: "/test.rego", Raw: []byte("package test\n\nallow { input.user == \"admin\" }\n\nallow { input.user == \"bob\" }")},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	staged := buf.String()
+
+	err = f.v1TestRequests([]tr{
+		{http.MethodPut, "/policies/test", "package test\n\nallow { input.user == \"admin\" }", 200, ""},
+		{http.MethodGet, "/canary", "", 404, ""},
+		{http.MethodPut, "/canary", staged, 400, ""},
+		{http.MethodPut, "/canary?bundle=b1&percent=101", staged, 400, ""},
+		{http.MethodPut, "/canary?bundle=b1", "not a bundle", 400, ""},
+		{http.MethodPut, "/canary?bundle=b1", staged, 200, ""},
+		{http.MethodPost, "/data/test/allow", `{"input": {"user": "bob"}}`, 200, `{}`},
+		{http.MethodPost, "/data/test/allow", `{"input": {"user": "admin"}}`, 200, `{"result": true}`},
+		{http.MethodGet, "/data/test/allow?input=" + url.QueryEscape(`{"user": "alice"}`), "", 200, `{}`},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	f.server.canaryEvals.Wait()
+
+	if err := f.v1(http.MethodGet, "/canary", "", 200, ""); err != nil {
+		t.Fatal(err)
+	}
+
+	var resp types.CanaryResponseV1
+	if err := util.NewJSONDecoder(f.recorder.Body).Decode(&resp); err != nil {
+		t.Fatal(err)
+	} else if c := resp.Result; c.Bundle != "b1" || c.Revision != "r2" || c.Percent != 100 || c.Compared != 3 || c.Mismatched != 1 || c.Errors != 0 {
+		t.Fatalf("Unexpected canary: %v", util.MustMarshalJSON(c))
+	}
+
+	// Sampled decisions are dropped while the maximum number of evaluations
+	// are in progress.
+	f.server.canaryMtx.RLock()
+	c := f.server.canary
+	f.server.canaryMtx.RUnlock()
+
+	for i := 0; i < canaryMaxEvals; i++ {
+		c.evals <- struct{}{}
+	}
+
+	if err := f.v1(http.MethodPost, "/data/test/allow", `{"input": {"user": "bob"}}`, 200, `{}`); err != nil {
+		t.Fatal(err)
+	}
+
+	f.server.canaryEvals.Wait()
+
+	if v1 := c.v1(); v1.Compared != 3 || v1.Dropped != 1 {
+		t.Fatalf("Unexpected canary: %v", util.MustMarshalJSON(v1))
+	}
+
+	for i := 0; i < canaryMaxEvals; i++ {
+		<-c.evals
+	}
+
+	err = f.v1TestRequests([]tr{
+		{http.MethodPost, "/canary/promote", "", 200, ""},
+		{http.MethodGet, "/canary", "", 404, ""},
+		{http.MethodPost, "/data/test/allow", `{"input": {"user": "bob"}}`, 200, `{"result": true}`},
+		{http.MethodPost, "/canary/promote", "", 404, ""},
+		{http.MethodPut, "/canary?bundle=b1&percent=0", staged, 200, ""},
+		{http.MethodPost, "/data/test/allow", `{"input": {"user": "bob"}}`, 200, `{"result": true}`},
+		{http.MethodDelete, "/canary", "", 204, ""},
+		{http.MethodDelete, "/canary", "", 404, ""},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestSnapshots(t *testing.T) {
+	var infos []*Info
+	f := newFixture(t, func(s *Server) {
+		s.WithDecisionLogger(func(_ context.Context, info *Info) {
+			infos = append(infos, info)
+		})
+	})
+
+	err := f.v1TestRequests([]tr{
+		{http.MethodPut, "/policies/test", "package test\n\np = data.x", 200, ""},
+		{http.MethodPut, "/data/x", `1`, 204, ""},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := f.v1(http.MethodPut, "/snapshots/s1", "", 200, ""); err != nil {
+		t.Fatal(err)
+	}
+
+	var created types.SnapshotResponseV1
+	if err := util.NewJSONDecoder(f.recorder.Body).Decode(&created); err != nil {
+		t.Fatal(err)
+	} else if created.Result.Name != "s1" || created.Result.Revision == 0 || created.Result.Created.IsZero() {
+		t.Fatalf("Unexpected snapshot: %v", util.MustMarshalJSON(created))
+	}
+
+	rev := created.Result.Revision
+
+	err = f.v1TestRequests([]tr{
+		{http.MethodPut, "/data/x", `2`, 204, ""},
+		{http.MethodPut, "/policies/test", "package test\n\np = y { y := data.x + 10 }", 200, ""},
+		{http.MethodGet, "/data/test/p", "", 200, `{"result": 12}`},
+		{http.MethodGet, "/data/test/p?snapshot=s1", "", 200, `{"result": 1}`},
+		{http.MethodPost, "/data/test/p?snapshot=s1", "", 200, `{"result": 1}`},
+		{http.MethodPost, "/data/test/p?snapshot=s1&partial", "", 200, `{"result": 1}`},
+		{http.MethodPost, "/data/test/p?partial", "", 200, `{"result": 12}`},
+		{http.MethodGet, "/export/x?snapshot=s1", "", 200, `1`},
+		{http.MethodGet, "/data/test/p?snapshot=missing", "", 404, ""},
+		{http.MethodGet, "/export/x?snapshot=missing", "", 404, ""},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := f.v1(http.MethodGet, "/data/test/p?snapshot=s1", "", 200, ""); err != nil {
+		t.Fatal(err)
+	} else if header := f.recorder.Header().Get(types.HeaderStoreRevisionV1); header != fmt.Sprint(rev) {
+		t.Fatalf("Expected snapshot revision %v but got %q", rev, header)
+	}
+
+	if last := infos[len(infos)-1]; last.Snapshot != "s1" || last.StoreRevision != rev || last.Txn != nil {
+		t.Fatalf("Expected decision to be logged with snapshot but got: %+v", last)
+	}
+
+	if err := f.v1(http.MethodGet, "/snapshots", "", 200, ""); err != nil {
+		t.Fatal(err)
+	}
+
+	var list types.SnapshotListResponseV1
+	if err := util.NewJSONDecoder(f.recorder.Body).Decode(&list); err != nil {
+		t.Fatal(err)
+	} else if len(list.Result) != 1 || *list.Result[0] != *created.Result {
+		t.Fatalf("Unexpected snapshots: %v", util.MustMarshalJSON(list))
+	}
+
+	err = f.v1TestRequests([]tr{
+		{http.MethodDelete, "/snapshots/s1", "", 204, ""},
+		{http.MethodGet, "/snapshots/s1", "", 404, ""},
+		{http.MethodDelete, "/snapshots/s1", "", 404, ""},
+		{http.MethodGet, "/snapshots", "", 200, `{"result": []}`},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
 type fixture struct {
 	server   *Server
 	recorder *httptest.ResponseRecorder
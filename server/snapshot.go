@@ -0,0 +1,162 @@
+// Copyright 2020 The OPA Authors.  All rights reserved.
+// Use of this source code is governed by an Apache2
+// license that can be found in the LICENSE file.
+
+package server
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"time"
+
+	"github.com/gorilla/mux"
+
+	"github.com/open-policy-agent/opa/ast"
+	"github.com/open-policy-agent/opa/server/types"
+	"github.com/open-policy-agent/opa/server/writer"
+	"github.com/open-policy-agent/opa/storage"
+)
+
+// snapshot is a named, read-only copy of the store. The compiler is the one
+// that was active when the snapshot was created so that decisions evaluated
+// against the snapshot use the policies from that point in time.
+type snapshot struct {
+	name     string
+	store    storage.Store
+	compiler *ast.Compiler
+	revision uint64
+	created  time.Time
+}
+
+func (s *snapshot) v1() *types.SnapshotV1 {
+	return &types.SnapshotV1{
+		Name:     s.name,
+		Revision: s.revision,
+		Created:  s.created,
+	}
+}
+
+// v1SnapshotsList writes the snapshots sorted by name.
+func (s *Server) v1SnapshotsList(w http.ResponseWriter, r *http.Request) {
+	pretty := getBoolParam(r.URL, types.ParamPrettyV1, true)
+
+	s.snapshotsMtx.RLock()
+	result := make([]*types.SnapshotV1, 0, len(s.snapshots))
+	for _, snap := range s.snapshots {
+		result = append(result, snap.v1())
+	}
+	s.snapshotsMtx.RUnlock()
+
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].Name < result[j].Name
+	})
+
+	writer.JSON(w, http.StatusOK, types.SnapshotListResponseV1{Result: result}, pretty)
+}
+
+// v1SnapshotsGet writes the snapshot with the name.
+func (s *Server) v1SnapshotsGet(w http.ResponseWriter, r *http.Request) {
+	pretty := getBoolParam(r.URL, types.ParamPrettyV1, true)
+
+	snap, ok := s.getSnapshot(w, mux.Vars(r)["name"])
+	if !ok {
+		return
+	}
+
+	writer.JSON(w, http.StatusOK, types.SnapshotResponseV1{Result: snap.v1()}, pretty)
+}
+
+// v1SnapshotsPut creates a snapshot of the store with the name. If a snapshot
+// with the name already exists, it is replaced.
+func (s *Server) v1SnapshotsPut(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	name := mux.Vars(r)["name"]
+	pretty := getBoolParam(r.URL, types.ParamPrettyV1, true)
+
+	sn, ok := s.store.(storage.Snapshotter)
+	if !ok {
+		writer.ErrorString(w, http.StatusNotImplemented, types.CodeInvalidOperation, fmt.Errorf("store does not support snapshots"))
+		return
+	}
+
+	txn, err := s.store.NewTransaction(ctx)
+	if err != nil {
+		writer.ErrorAuto(w, err)
+		return
+	}
+
+	// The compiler is replaced when policies are written so, while the
+	// transaction is open, it matches the policies in the store.
+	snap := &snapshot{
+		name:     name,
+		compiler: s.getCompiler(),
+		created:  time.Now().UTC(),
+	}
+
+	snap.revision, _, err = storage.Revision(ctx, s.store, txn)
+	if err == nil {
+		snap.store, err = sn.Snapshot(ctx, txn)
+	}
+
+	s.store.Abort(ctx, txn)
+
+	if err != nil {
+		writer.ErrorAuto(w, err)
+		return
+	}
+
+	s.snapshotsMtx.Lock()
+	s.snapshots[name] = snap
+	s.snapshotsMtx.Unlock()
+
+	writer.JSON(w, http.StatusOK, types.SnapshotResponseV1{Result: snap.v1()}, pretty)
+}
+
+// v1SnapshotsDelete deletes the snapshot with the name. Requests that are
+// reading from the snapshot are not affected.
+func (s *Server) v1SnapshotsDelete(w http.ResponseWriter, r *http.Request) {
+	name := mux.Vars(r)["name"]
+
+	if _, ok := s.getSnapshot(w, name); !ok {
+		return
+	}
+
+	s.snapshotsMtx.Lock()
+	delete(s.snapshots, name)
+	s.snapshotsMtx.Unlock()
+
+	writer.Bytes(w, http.StatusNoContent, nil)
+}
+
+// getSnapshotParam returns the snapshot named by the snapshot parameter of the
+// request or nil if the request does not name a snapshot. If the snapshot does
+// not exist, an error is written to the client and false is returned.
+func (s *Server) getSnapshotParam(w http.ResponseWriter, r *http.Request) (*snapshot, bool) {
+	names := r.URL.Query()[types.ParamSnapshotV1]
+	if len(names) == 0 {
+		return nil, true
+	}
+	return s.getSnapshot(w, names[len(names)-1])
+}
+
+func (s *Server) getSnapshot(w http.ResponseWriter, name string) (*snapshot, bool) {
+	s.snapshotsMtx.RLock()
+	snap, ok := s.snapshots[name]
+	s.snapshotsMtx.RUnlock()
+
+	if !ok {
+		writer.ErrorString(w, http.StatusNotFound, types.CodeResourceNotFound, fmt.Errorf("snapshot not found: %v", name))
+	}
+
+	return snap, ok
+}
+
+// getStoreAndCompiler returns the store and compiler that requests read from.
+// If snap is nil, the live store and compiler are returned.
+func (s *Server) getStoreAndCompiler(snap *snapshot) (storage.Store, *ast.Compiler) {
+	if snap != nil {
+		return snap.store, snap.compiler
+	}
+	return s.store, s.getCompiler()
+}
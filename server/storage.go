@@ -0,0 +1,135 @@
+// Copyright 2020 The OPA Authors.  All rights reserved.
+// Use of this source code is governed by an Apache2
+// license that can be found in the LICENSE file.
+
+package server
+
+import (
+	"fmt"
+	"net/http"
+	"runtime/debug"
+	"strconv"
+	"strings"
+
+	"github.com/gorilla/mux"
+
+	"github.com/open-policy-agent/opa/server/types"
+	"github.com/open-policy-agent/opa/server/writer"
+	"github.com/open-policy-agent/opa/storage"
+)
+
+// defaultStatsDepth is the number of levels of child documents included in
+// storage statistics if the client does not specify a depth.
+const defaultStatsDepth = 1
+
+// v1StorageStatsGet writes the usage statistics of the base document at the
+// path.
+func (s *Server) v1StorageStatsGet(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	vars := mux.Vars(r)
+	pretty := getBoolParam(r.URL, types.ParamPrettyV1, true)
+
+	c, ok := s.store.(storage.Compactor)
+	if !ok {
+		writer.ErrorString(w, http.StatusNotImplemented, types.CodeInvalidOperation, fmt.Errorf("store does not support storage statistics"))
+		return
+	}
+
+	path, ok := storage.ParsePathEscaped("/" + strings.Trim(vars["path"], "/"))
+	if !ok {
+		writer.Error(w, http.StatusBadRequest, types.NewErrorV1(types.CodeInvalidParameter, "bad path: %v", vars["path"]))
+		return
+	}
+
+	depth := defaultStatsDepth
+
+	if p := r.URL.Query().Get(types.ParamDepthV1); p != "" {
+		var err error
+		depth, err = strconv.Atoi(p)
+		if err != nil || depth < 0 {
+			writer.Error(w, http.StatusBadRequest, types.NewErrorV1(types.CodeInvalidParameter, "bad depth: %v", p))
+			return
+		}
+	}
+
+	txn, err := s.store.NewTransaction(ctx)
+	if err != nil {
+		writer.ErrorAuto(w, err)
+		return
+	}
+
+	defer s.store.Abort(ctx, txn)
+
+	if _, _, err := s.setRevisionHeader(ctx, w, s.store, txn); err != nil {
+		writer.ErrorAuto(w, err)
+		return
+	}
+
+	stats, err := c.Stats(ctx, txn, path, depth)
+	if err != nil {
+		writer.ErrorAuto(w, err)
+		return
+	}
+
+	patches, err := c.Patches(ctx, txn)
+	if err != nil {
+		writer.ErrorAuto(w, err)
+		return
+	}
+
+	writer.JSON(w, http.StatusOK, types.StorageStatsResponseV1{
+		Patches: patches,
+		Result:  storageStatsV1(stats),
+	}, pretty)
+}
+
+// v1StorageCompactPost compacts the store and returns the memory that is no
+// longer used to the operating system.
+func (s *Server) v1StorageCompactPost(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	pretty := getBoolParam(r.URL, types.ParamPrettyV1, true)
+
+	c, ok := s.store.(storage.Compactor)
+	if !ok {
+		writer.ErrorString(w, http.StatusNotImplemented, types.CodeInvalidOperation, fmt.Errorf("store does not support compaction"))
+		return
+	}
+
+	txn, err := s.store.NewTransaction(ctx, storage.WriteParams)
+	if err != nil {
+		writer.ErrorAuto(w, err)
+		return
+	}
+
+	// The data is not modified so the transaction is aborted rather than
+	// committed. This avoids incrementing the revision and running triggers.
+	patches, err := c.Patches(ctx, txn)
+	if err == nil {
+		err = c.Compact(ctx, txn)
+	}
+
+	s.store.Abort(ctx, txn)
+
+	if err != nil {
+		writer.ErrorAuto(w, err)
+		return
+	}
+
+	debug.FreeOSMemory()
+
+	writer.JSON(w, http.StatusOK, types.StorageCompactResponseV1{Patches: patches}, pretty)
+}
+
+func storageStatsV1(stats *storage.Stats) *types.StorageStatsV1 {
+	result := &types.StorageStatsV1{
+		Bytes:  stats.Bytes,
+		Values: stats.Values,
+	}
+	if stats.Children != nil {
+		result.Children = make(map[string]*types.StorageStatsV1, len(stats.Children))
+		for k, v := range stats.Children {
+			result.Children[k] = storageStatsV1(v)
+		}
+	}
+	return result
+}
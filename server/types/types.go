@@ -10,6 +10,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"strings"
+	"time"
 
 	"github.com/open-policy-agent/opa/ast"
 	"github.com/open-policy-agent/opa/topdown"
@@ -18,14 +19,17 @@ import (
 
 // Error codes returned by OPA's REST API.
 const (
-	CodeInternal          = "internal_error"
-	CodeEvaluation        = "evaluation_error"
-	CodeUnauthorized      = "unauthorized"
-	CodeInvalidParameter  = "invalid_parameter"
-	CodeInvalidOperation  = "invalid_operation"
-	CodeResourceNotFound  = "resource_not_found"
-	CodeResourceConflict  = "resource_conflict"
-	CodeUndefinedDocument = "undefined_document"
+	CodeInternal           = "internal_error"
+	CodeEvaluation         = "evaluation_error"
+	CodeUnauthorized       = "unauthorized"
+	CodeInvalidParameter   = "invalid_parameter"
+	CodeInvalidOperation   = "invalid_operation"
+	CodeResourceNotFound   = "resource_not_found"
+	CodeResourceConflict   = "resource_conflict"
+	CodeUndefinedDocument  = "undefined_document"
+	CodeTooManyRequests    = "too_many_requests"
+	CodeRequestTooLarge    = "request_too_large"
+	CodePreconditionFailed = "precondition_failed"
 )
 
 // ErrorV1 models an error response sent to the client.
@@ -81,6 +85,7 @@ const (
 	MsgUnauthorizedError          = "request rejected by administrative policy"
 	MsgUndefinedError             = "document missing or undefined"
 	MsgPluginConfigError          = "error(s) occurred while configuring plugin(s)"
+	MsgInputValidationError       = "input does not conform to schema"
 )
 
 // PatchV1 models a single patch operation against a document.
@@ -151,14 +156,144 @@ type DataResponseV1 struct {
 	Result      *interface{}  `json:"result,omitempty"`
 }
 
+// ViolationsResponseV1 models the response message for Violations API
+// operations.
+type ViolationsResponseV1 struct {
+	DecisionID string        `json:"decision_id,omitempty"`
+	Metrics    MetricsV1     `json:"metrics,omitempty"`
+	Result     []ViolationV1 `json:"result"`
+}
+
+// ViolationV1 models an element of a partial set rule that reports a policy
+// violation, e.g., deny[msg].
+type ViolationV1 struct {
+	Rule        string        `json:"rule"`
+	Message     string        `json:"msg,omitempty"`
+	Value       interface{}   `json:"value"`
+	Description string        `json:"description,omitempty"`
+	Location    *ast.Location `json:"location,omitempty"`
+}
+
+// StorageStatsResponseV1 models the response message for Storage API stats
+// operations. Patches is the number of data patches committed since the store
+// was created or last compacted.
+type StorageStatsResponseV1 struct {
+	Patches uint64          `json:"patches"`
+	Result  *StorageStatsV1 `json:"result"`
+}
+
+// StorageStatsV1 models the usage statistics of a document in the store.
+type StorageStatsV1 struct {
+	Bytes    int64                      `json:"bytes"`
+	Values   int64                      `json:"values"`
+	Children map[string]*StorageStatsV1 `json:"children,omitempty"`
+}
+
+// StorageCompactResponseV1 models the response message for Storage API compact
+// operations. Patches is the number of data patches that had been committed
+// since the store was created or last compacted.
+type StorageCompactResponseV1 struct {
+	Patches uint64 `json:"patches"`
+}
+
+// SnapshotV1 models a named snapshot of the store.
+type SnapshotV1 struct {
+	Name     string    `json:"name"`
+	Revision uint64    `json:"revision"`
+	Created  time.Time `json:"created"`
+}
+
+// SnapshotResponseV1 models the response message for Snapshot API create and
+// get operations.
+type SnapshotResponseV1 struct {
+	Result *SnapshotV1 `json:"result"`
+}
+
+// SnapshotListResponseV1 models the response message for Snapshot API list
+// operations.
+type SnapshotListResponseV1 struct {
+	Result []*SnapshotV1 `json:"result"`
+}
+
+// OverrideV1 models a decision override. While the override is active, Data
+// API requests for the path receive the value instead of the decision.
+type OverrideV1 struct {
+	Path    string       `json:"path"`
+	Value   *interface{} `json:"value"`
+	Reason  string       `json:"reason,omitempty"`
+	Created time.Time    `json:"created"`
+	Expires time.Time    `json:"expires"`
+}
+
+// OverrideRequestV1 models the request message for Override API create
+// operations. The TTL is a duration string, e.g., 10m.
+type OverrideRequestV1 struct {
+	Value  *interface{} `json:"value"`
+	TTL    string       `json:"ttl"`
+	Reason string       `json:"reason"`
+}
+
+// OverrideResponseV1 models the response message for Override API create and
+// get operations.
+type OverrideResponseV1 struct {
+	Result *OverrideV1 `json:"result"`
+}
+
+// OverrideListResponseV1 models the response message for Override API list
+// operations.
+type OverrideListResponseV1 struct {
+	Result []*OverrideV1 `json:"result"`
+}
+
+// CanaryV1 models a bundle staged for canary evaluation. Compared is the
+// number of decisions evaluated against the staged bundle. Mismatched and
+// Errors count the decisions that differed or failed. Dropped counts the
+// sampled decisions that were not evaluated because too many evaluations were
+// in progress.
+type CanaryV1 struct {
+	Bundle     string    `json:"bundle"`
+	Revision   string    `json:"revision,omitempty"`
+	Percent    float64   `json:"percent"`
+	Created    time.Time `json:"created"`
+	Compared   uint64    `json:"compared"`
+	Mismatched uint64    `json:"mismatched"`
+	Errors     uint64    `json:"errors"`
+	Dropped    uint64    `json:"dropped"`
+}
+
+// CanaryResponseV1 models the response message for Canary API operations.
+type CanaryResponseV1 struct {
+	Result *CanaryV1 `json:"result"`
+}
+
 // MetricsV1 models a collection of performance metrics.
 type MetricsV1 map[string]interface{}
 
-// QueryResponseV1 models the response message for Query API operations.
+// QueryResponseV1 models the response message for Query API operations. If
+// the results are paginated and more results follow, NextOffset is the offset
+// of the next page.
 type QueryResponseV1 struct {
 	Explanation TraceV1               `json:"explanation,omitempty"`
 	Metrics     MetricsV1             `json:"metrics,omitempty"`
 	Result      AdhocQueryResultSetV1 `json:"result,omitempty"`
+	NextOffset  *int                  `json:"next_offset,omitempty"`
+}
+
+// QueryValidationResponseV1 models the response message for Query validation
+// API operations. Vars maps the names of the variables that would be bound in
+// the query results to the types inferred for them. Cost is the estimated cost
+// of evaluating the query.
+type QueryValidationResponseV1 struct {
+	Vars map[string]interface{} `json:"vars"`
+	Cost *QueryCostV1           `json:"cost,omitempty"`
+}
+
+// QueryCostV1 models the estimated cost of evaluating a query. Rows is the
+// estimated number of results and Cost is the estimated number of expressions
+// evaluated.
+type QueryCostV1 struct {
+	Rows float64 `json:"rows"`
+	Cost float64 `json:"cost"`
 }
 
 // WatchResponseV1 models a message in the response stream for a watch.
@@ -172,6 +307,24 @@ type WatchResponseV1 struct {
 	Error *ErrorV1 `json:"error,omitempty"`
 }
 
+// SubscribeRequestV1 models a message sent by clients over the subscription
+// endpoint. Each message either registers the query under the given ID or, if
+// Unsubscribe is set, removes the subscription with that ID.
+type SubscribeRequestV1 struct {
+	ID          string `json:"id"`
+	Query       string `json:"query,omitempty"`
+	Unsubscribe bool   `json:"unsubscribe,omitempty"`
+}
+
+// SubscribeResponseV1 models a message pushed to clients over the subscription
+// endpoint. The first message for a subscription contains the initial result
+// and subsequent messages are sent whenever the result may have changed.
+type SubscribeResponseV1 struct {
+	ID     string                `json:"id"`
+	Result AdhocQueryResultSetV1 `json:"result,omitempty"`
+	Error  *ErrorV1              `json:"error,omitempty"`
+}
+
 // AdhocQueryResultSetV1 models the result of a Query API query.
 type AdhocQueryResultSetV1 []map[string]interface{}
 
@@ -411,6 +564,11 @@ const (
 	// diagnosing performance issues.
 	ParamInstrumentV1 = "instrument"
 
+	// ParamExpressionsV1 defines the name of the HTTP URL parameter that
+	// indicates the client wants to receive the values of the query
+	// expressions alongside the variable bindings in each result.
+	ParamExpressionsV1 = "expressions"
+
 	// ParamPartialV1 defines the name of the HTTP URL parameter that indicates
 	// the client wants the partial evaluation optimization to be used during
 	// query evaluation.
@@ -424,12 +582,45 @@ const (
 	// the client wants to set a watch on the current query or data reference.
 	ParamWatchV1 = "watch"
 
+	// ParamRuleV1 defines the name of the HTTP URL parameter that specifies
+	// the names of the rules that report violations.
+	ParamRuleV1 = "rule"
+
+	// ParamDepthV1 defines the name of the HTTP URL parameter that specifies
+	// how many levels of child documents are included in storage statistics.
+	ParamDepthV1 = "depth"
+
+	// ParamSnapshotV1 defines the name of the HTTP URL parameter that
+	// specifies the named snapshot of the store that the request reads from.
+	ParamSnapshotV1 = "snapshot"
+
+	// ParamBundleV1 defines the name of the HTTP URL parameter that specifies
+	// the name of the bundle that a staged bundle replaces.
+	ParamBundleV1 = "bundle"
+
+	// ParamPercentV1 defines the name of the HTTP URL parameter that
+	// specifies the percentage of decisions evaluated against a staged
+	// bundle.
+	ParamPercentV1 = "percent"
+
+	// ParamLimitV1 defines the name of the HTTP URL parameter that specifies
+	// the maximum number of results to return.
+	ParamLimitV1 = "limit"
+
+	// ParamOffsetV1 defines the name of the HTTP URL parameter that specifies
+	// the number of results to skip before the first result returned.
+	ParamOffsetV1 = "offset"
+
 	// ParamBundleActivationV1 defines the name of the HTTP URL parameter that
 	// indicates the client wants to include bundle activation in the results
 	// of the health API.
 	ParamBundleActivationV1 = "bundle"
 )
 
+// HeaderStoreRevisionV1 defines the name of the HTTP response header that
+// contains the revision of the store that the response was computed from.
+const HeaderStoreRevisionV1 = "OPA-Store-Revision"
+
 // BadRequestErr represents an error condition raised if the caller passes
 // invalid parameters.
 type BadRequestErr string
@@ -0,0 +1,103 @@
+// Copyright 2020 The OPA Authors.  All rights reserved.
+// Use of this source code is governed by an Apache2
+// license that can be found in the LICENSE file.
+
+package server
+
+import (
+	"net/http"
+	"sync"
+
+	"github.com/open-policy-agent/opa/ast"
+	"github.com/open-policy-agent/opa/dependencies"
+	"github.com/open-policy-agent/opa/server/types"
+	"github.com/open-policy-agent/opa/server/writer"
+)
+
+// inputSchemas caches the input schemas inferred for Data API paths. The
+// schemas are inferred from the rules defined by the compiler so the cache is
+// reset when the compiler changes (e.g., when policies are updated.) A nil
+// inputSchemas does not validate inputs.
+type inputSchemas struct {
+	mtx      sync.Mutex
+	compiler *ast.Compiler
+	schemas  map[string]*dependencies.Schema
+}
+
+func newInputSchemas(enabled bool) *inputSchemas {
+	if !enabled {
+		return nil
+	}
+	return &inputSchemas{}
+}
+
+// Get returns the input schema for the path. Schemas for compilers other than
+// the most recently used one (e.g., compilers of snapshots) are not cached.
+func (c *inputSchemas) Get(compiler *ast.Compiler, path ast.Ref) (*dependencies.Schema, error) {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+
+	if c.compiler != compiler {
+		c.compiler = compiler
+		c.schemas = map[string]*dependencies.Schema{}
+	}
+
+	key := path.String()
+
+	if schema, ok := c.schemas[key]; ok {
+		return schema, nil
+	}
+
+	schema, err := dependencies.Input(compiler, path)
+	if err != nil {
+		return nil, err
+	}
+
+	c.schemas[key] = schema
+	return schema, nil
+}
+
+// Validate returns an error if the input does not conform to the schema for
+// the path. Inputs for paths that do not refer to the input are not
+// validated. If the input is nil, it is not validated since references to the
+// input are undefined.
+func (c *inputSchemas) Validate(compiler *ast.Compiler, path ast.Ref, input ast.Value) (*types.ErrorV1, error) {
+	if c == nil || input == nil {
+		return nil, nil
+	}
+
+	schema, err := c.Get(compiler, path)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(schema.Properties) == 0 {
+		return nil, nil
+	}
+
+	errs := schema.Validate(ast.InputRootRef, input)
+	if len(errs) == 0 {
+		return nil, nil
+	}
+
+	result := types.NewErrorV1(types.CodeInvalidParameter, types.MsgInputValidationError)
+	for _, err := range errs {
+		result.WithError(err)
+	}
+
+	return result, nil
+}
+
+// validateInput writes an error response and returns false if the input does
+// not conform to the schema for the path.
+func (s *Server) validateInput(w http.ResponseWriter, compiler *ast.Compiler, path ast.Ref, input ast.Value) bool {
+	verr, err := s.inputSchemas.Validate(compiler, path, input)
+	if err != nil {
+		writer.ErrorAuto(w, err)
+		return false
+	} else if verr != nil {
+		writer.Error(w, http.StatusBadRequest, verr)
+		return false
+	}
+	return true
+}
@@ -9,6 +9,8 @@ import (
 	"encoding/json"
 	"net/http"
 
+	"github.com/ghodss/yaml"
+
 	"github.com/open-policy-agent/opa/server/types"
 	"github.com/open-policy-agent/opa/storage"
 	"github.com/open-policy-agent/opa/topdown"
@@ -35,6 +37,16 @@ func ErrorAuto(w http.ResponseWriter, err error) {
 		return
 	}
 
+	if storage.IsPreconditionFailed(err) {
+		ErrorString(w, http.StatusPreconditionFailed, types.CodePreconditionFailed, err)
+		return
+	}
+
+	if topdown.IsResultLimit(err) {
+		Error(w, http.StatusBadRequest, types.NewErrorV1(types.CodeInvalidParameter, types.MsgEvaluationError).WithError(err))
+		return
+	}
+
 	if topdown.IsError(err) {
 		Error(w, http.StatusInternalServerError, types.NewErrorV1(types.CodeInternal, types.MsgEvaluationError).WithError(err))
 		return
@@ -93,6 +105,21 @@ func JSON(w http.ResponseWriter, code int, v interface{}, pretty bool) {
 	}
 }
 
+// YAML writes a response with the specified status code and object. The object
+// will be YAML serialized.
+func YAML(w http.ResponseWriter, code int, v interface{}) {
+
+	bs, err := yaml.Marshal(v)
+	if err != nil {
+		ErrorAuto(w, err)
+		return
+	}
+
+	headers := w.Header()
+	headers.Add("Content-Type", "application/x-yaml")
+	Bytes(w, code, bs)
+}
+
 // Bytes writes a response with the specified status code and bytes.
 func Bytes(w http.ResponseWriter, code int, bs []byte) {
 	w.WriteHeader(code)
@@ -0,0 +1,347 @@
+// Copyright 2019 The OPA Authors.  All rights reserved.
+// Use of this source code is governed by an Apache2
+// license that can be found in the LICENSE file.
+
+// Package columnar provides a compact representation for large arrays of
+// objects that share the same keys, e.g., lists of role bindings.
+package columnar
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/open-policy-agent/opa/ast"
+)
+
+// Table stores an array of objects column by column. Scalar values are
+// shared between rows so columns with few distinct values (e.g., roles or
+// kinds) are stored once.
+//
+// Tables can be written to stores in place of arrays. Tables are serialized
+// as arrays of objects and the evaluator iterates over their rows without
+// building a map for each object. Tables are immutable: documents inside of
+// tables cannot be written to.
+type Table struct {
+	names   []string      // sorted column names
+	keys    []*ast.Term   // column names as terms
+	columns [][]*ast.Term // columns[j][i] is the value of keys[j] in row i (or nil)
+	full    []bool        // full[i] is true if row i contains all of the keys
+	length  int
+
+	once  sync.Once
+	array ast.Array
+}
+
+// NewTable returns a new Table containing the rows. If any row is not an
+// object, an error is returned.
+func NewTable(rows []interface{}) (*Table, error) {
+
+	index := map[string]int{}
+
+	for i := range rows {
+		obj, ok := rows[i].(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf("row %d is not an object", i)
+		}
+		for k := range obj {
+			index[k] = 0
+		}
+	}
+
+	t := &Table{
+		names:   make([]string, 0, len(index)),
+		columns: make([][]*ast.Term, len(index)),
+		full:    make([]bool, len(rows)),
+		length:  len(rows),
+	}
+
+	for k := range index {
+		t.names = append(t.names, k)
+	}
+
+	sort.Strings(t.names)
+
+	t.keys = make([]*ast.Term, len(t.names))
+
+	for j, k := range t.names {
+		index[k] = j
+		t.keys[j] = ast.StringTerm(k)
+		t.columns[j] = make([]*ast.Term, len(rows))
+	}
+
+	scalars := map[interface{}]*ast.Term{}
+
+	for i := range rows {
+		obj := rows[i].(map[string]interface{})
+		t.full[i] = len(obj) == len(t.names)
+		for k, v := range obj {
+			term, err := intern(scalars, v)
+			if err != nil {
+				return nil, err
+			}
+			t.columns[index[k]][i] = term
+		}
+	}
+
+	return t, nil
+}
+
+// Compact replaces arrays inside of x that contain at least minLen objects
+// with Tables. Objects and arrays inside of x are modified in place. The
+// compacted value is returned.
+func Compact(x interface{}, minLen int) (interface{}, error) {
+	switch x := x.(type) {
+	case map[string]interface{}:
+		for k := range x {
+			v, err := Compact(x[k], minLen)
+			if err != nil {
+				return nil, err
+			}
+			x[k] = v
+		}
+	case []interface{}:
+		if len(x) >= minLen && allObjects(x) {
+			return NewTable(x)
+		}
+		for i := range x {
+			v, err := Compact(x[i], minLen)
+			if err != nil {
+				return nil, err
+			}
+			x[i] = v
+		}
+	}
+	return x, nil
+}
+
+// Len returns the number of rows in the table.
+func (t *Table) Len() int {
+	return t.length
+}
+
+// Row returns row i of the table as a native Go value.
+func (t *Table) Row(i int) map[string]interface{} {
+	obj := make(map[string]interface{}, len(t.names))
+	for j := range t.names {
+		if v := t.columns[j][i]; v != nil {
+			x, err := ast.JSON(v.Value)
+			if err != nil {
+				panic(err) // cells are converted from JSON values
+			}
+			obj[t.names[j]] = x
+		}
+	}
+	return obj
+}
+
+// ToValue returns an array whose elements are objects backed by the rows of
+// the table. The array is built once and shared between callers.
+func (t *Table) ToValue() (ast.Value, error) {
+	t.once.Do(func() {
+		t.array = make(ast.Array, t.length)
+		for i := range t.array {
+			t.array[i] = ast.NewTerm(row{table: t, i: i})
+		}
+	})
+	return t.array, nil
+}
+
+// MarshalJSON returns the JSON encoding of the table as an array of objects.
+func (t *Table) MarshalJSON() ([]byte, error) {
+	var buf bytes.Buffer
+	buf.WriteByte('[')
+	for i := 0; i < t.length; i++ {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		bs, err := json.Marshal(t.Row(i))
+		if err != nil {
+			return nil, err
+		}
+		buf.Write(bs)
+	}
+	buf.WriteByte(']')
+	return buf.Bytes(), nil
+}
+
+func intern(scalars map[interface{}]*ast.Term, x interface{}) (*ast.Term, error) {
+	switch x.(type) {
+	case nil, bool, json.Number, string:
+		if term, ok := scalars[x]; ok {
+			return term, nil
+		}
+	}
+	v, err := ast.InterfaceToValue(x)
+	if err != nil {
+		return nil, err
+	}
+	term := ast.NewTerm(v)
+	switch x.(type) {
+	case nil, bool, json.Number, string:
+		scalars[x] = term
+	}
+	return term, nil
+}
+
+func allObjects(x []interface{}) bool {
+	for i := range x {
+		if _, ok := x[i].(map[string]interface{}); !ok {
+			return false
+		}
+	}
+	return true
+}
+
+// row implements ast.Object for a row of a table. Lookups are resolved
+// against the columns of the table. Operations that construct new objects
+// return regular objects.
+type row struct {
+	table *Table
+	i     int
+}
+
+func (r row) Compare(other ast.Value) int {
+	return r.materialize().Compare(other)
+}
+
+func (r row) Find(path ast.Ref) (ast.Value, error) {
+	if len(path) == 0 {
+		return r, nil
+	}
+	value := r.Get(path[0])
+	if value == nil {
+		return nil, fmt.Errorf("find: not found")
+	}
+	return value.Value.Find(path[1:])
+}
+
+func (r row) Hash() int {
+	var hash int
+	r.Foreach(func(k, v *ast.Term) {
+		hash += ast.ObjectItemHash(k, v)
+	})
+	return hash
+}
+
+func (r row) IsGround() bool {
+	return true
+}
+
+func (r row) String() string {
+	var buf []string
+	r.Foreach(func(k, v *ast.Term) {
+		buf = append(buf, fmt.Sprintf("%s: %s", k, v))
+	})
+	return "{" + strings.Join(buf, ", ") + "}"
+}
+
+func (r row) Len() int {
+	if r.table.full[r.i] {
+		return len(r.table.keys)
+	}
+	var n int
+	for j := range r.table.columns {
+		if r.table.columns[j][r.i] != nil {
+			n++
+		}
+	}
+	return n
+}
+
+func (r row) Get(k *ast.Term) *ast.Term {
+	s, ok := k.Value.(ast.String)
+	if !ok {
+		return nil
+	}
+	names := r.table.names
+	j := sort.SearchStrings(names, string(s))
+	if j == len(names) || names[j] != string(s) {
+		return nil
+	}
+	return r.table.columns[j][r.i]
+}
+
+func (r row) Copy() ast.Object {
+	return r.materialize().Copy()
+}
+
+// Insert panics because rows are immutable. Callers must copy the row first.
+func (r row) Insert(*ast.Term, *ast.Term) {
+	panic("columnar: illegal insert into table row")
+}
+
+func (r row) Iter(f func(*ast.Term, *ast.Term) error) error {
+	for j := range r.table.keys {
+		if v := r.table.columns[j][r.i]; v != nil {
+			if err := f(r.table.keys[j], v); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func (r row) Until(f func(*ast.Term, *ast.Term) bool) bool {
+	for j := range r.table.keys {
+		if v := r.table.columns[j][r.i]; v != nil {
+			if f(r.table.keys[j], v) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func (r row) Foreach(f func(*ast.Term, *ast.Term)) {
+	r.Until(func(k, v *ast.Term) bool {
+		f(k, v)
+		return false
+	})
+}
+
+func (r row) Map(f func(*ast.Term, *ast.Term) (*ast.Term, *ast.Term, error)) (ast.Object, error) {
+	return r.materialize().Map(f)
+}
+
+func (r row) Diff(other ast.Object) ast.Object {
+	return r.materialize().Diff(other)
+}
+
+func (r row) Intersect(other ast.Object) [][3]*ast.Term {
+	return r.materialize().Intersect(other)
+}
+
+func (r row) Merge(other ast.Object) (ast.Object, bool) {
+	return r.materialize().Merge(other)
+}
+
+func (r row) Filter(filter ast.Object) (ast.Object, error) {
+	return r.materialize().Filter(filter)
+}
+
+func (r row) Keys() []*ast.Term {
+	if r.table.full[r.i] {
+		return r.table.keys
+	}
+	keys := make([]*ast.Term, 0, len(r.table.keys))
+	r.Foreach(func(k, _ *ast.Term) {
+		keys = append(keys, k)
+	})
+	return keys
+}
+
+func (r row) MarshalJSON() ([]byte, error) {
+	return json.Marshal(r.materialize())
+}
+
+// materialize returns a regular object with the same key-value pairs as the
+// row. The values are shared with the table.
+func (r row) materialize() ast.Object {
+	obj := ast.NewObject()
+	r.Foreach(obj.Insert)
+	return obj
+}
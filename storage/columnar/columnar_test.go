@@ -0,0 +1,192 @@
+// Copyright 2019 The OPA Authors.  All rights reserved.
+// Use of this source code is governed by an Apache2
+// license that can be found in the LICENSE file.
+
+package columnar_test
+
+import (
+	"context"
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	"github.com/open-policy-agent/opa/ast"
+	"github.com/open-policy-agent/opa/rego"
+	"github.com/open-policy-agent/opa/storage"
+	"github.com/open-policy-agent/opa/storage/columnar"
+	"github.com/open-policy-agent/opa/storage/inmem"
+	"github.com/open-policy-agent/opa/util"
+)
+
+const testBindings = `[
+	{"role": "admin", "user": "alice"},
+	{"role": "viewer", "user": "bob"},
+	{"role": "viewer", "user": "carol", "expires": 10}
+]`
+
+func TestTableRoundTrip(t *testing.T) {
+
+	var rows []interface{}
+	if err := util.UnmarshalJSON([]byte(testBindings), &rows); err != nil {
+		t.Fatal(err)
+	}
+
+	table, err := columnar.NewTable(rows)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if table.Len() != 3 {
+		t.Fatalf("Expected 3 rows but got %v", table.Len())
+	}
+
+	bs, err := json.Marshal(table)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var result interface{}
+	if err := util.UnmarshalJSON(bs, &result); err != nil {
+		t.Fatal(err)
+	}
+
+	if !reflect.DeepEqual(result, rows) {
+		t.Fatalf("Expected %v but got %v", rows, result)
+	}
+
+	v, err := ast.InterfaceToValue(table)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if ast.Compare(v, ast.MustParseTerm(testBindings).Value) != 0 {
+		t.Fatalf("Expected %v to equal %v", v, testBindings)
+	}
+}
+
+func TestNewTableErrors(t *testing.T) {
+	_, err := columnar.NewTable([]interface{}{map[string]interface{}{}, "x"})
+	if err == nil || err.Error() != "row 1 is not an object" {
+		t.Fatalf("Expected row error but got: %v", err)
+	}
+}
+
+func TestCompact(t *testing.T) {
+
+	data := util.MustUnmarshalJSON([]byte(`{"bindings": ` + testBindings + `, "small": [{"a": 1}], "mixed": [1, {"a": 1}, {"b": 2}]}`))
+
+	result, err := columnar.Compact(data, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	obj := result.(map[string]interface{})
+
+	if _, ok := obj["bindings"].(*columnar.Table); !ok {
+		t.Fatalf("Expected bindings to be compacted but got %T", obj["bindings"])
+	}
+
+	if _, ok := obj["small"].([]interface{}); !ok {
+		t.Fatalf("Expected small to be left alone but got %T", obj["small"])
+	}
+
+	if _, ok := obj["mixed"].([]interface{}); !ok {
+		t.Fatalf("Expected mixed to be left alone but got %T", obj["mixed"])
+	}
+}
+
+func TestEvalTable(t *testing.T) {
+
+	data := util.MustUnmarshalJSON([]byte(`{"bindings": ` + testBindings + `}`))
+
+	data, err := columnar.Compact(data, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	store := inmem.NewFromObject(data.(map[string]interface{}))
+
+	tests := []struct {
+		note     string
+		query    string
+		expected string
+	}{
+		{"iterate", `x = {u | data.bindings[_] = b; b.role = "viewer"; u = b.user}`, `["bob", "carol"]`},
+		{"missing key", `x = [i | data.bindings[i].expires]`, `[2]`},
+		{"object ops", `x = json.filter(data.bindings[0], ["user"])`, `{"user": "alice"}`},
+		{"count", `x = count(data.bindings[2])`, `3`},
+		{"equality", `x = data.bindings[1]; x == {"role": "viewer", "user": "bob"}`, `{"role": "viewer", "user": "bob"}`},
+		{"whole", `x = data.bindings`, testBindings},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.note, func(t *testing.T) {
+			rs, err := rego.New(rego.Query(tc.query), rego.Store(store)).Eval(context.Background())
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(rs) != 1 {
+				t.Fatalf("Expected one result but got %v", rs)
+			}
+			exp := util.MustUnmarshalJSON([]byte(tc.expected))
+			if !reflect.DeepEqual(rs[0].Bindings["x"], exp) {
+				t.Fatalf("Expected %v but got %v", exp, rs[0].Bindings["x"])
+			}
+		})
+	}
+}
+
+func TestTableRowHash(t *testing.T) {
+
+	var rows []interface{}
+	if err := util.UnmarshalJSON([]byte(testBindings), &rows); err != nil {
+		t.Fatal(err)
+	}
+
+	table, err := columnar.NewTable(rows)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	value, err := table.ToValue()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	row := value.(ast.Array)[1]
+	obj := ast.MustParseTerm(`{"role": "viewer", "user": "bob"}`)
+
+	if row.Hash() != obj.Hash() {
+		t.Fatalf("Expected row hash %v to equal object hash %v", row.Hash(), obj.Hash())
+	}
+
+	set := ast.NewSet(row)
+	if !set.Contains(obj) {
+		t.Fatalf("Expected set %v to contain %v", set, obj)
+	}
+}
+
+func TestStoreReadTable(t *testing.T) {
+
+	data := util.MustUnmarshalJSON([]byte(`{"bindings": ` + testBindings + `}`))
+
+	data, err := columnar.Compact(data, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	store := inmem.NewFromObject(data.(map[string]interface{}))
+	ctx := context.Background()
+
+	result, err := storage.ReadOne(ctx, store, storage.MustParsePath("/bindings/1/user"))
+	if err != nil {
+		t.Fatal(err)
+	} else if result != "bob" {
+		t.Fatalf("Expected bob but got %v", result)
+	}
+
+	_, err = storage.ReadOne(ctx, store, storage.MustParsePath("/bindings/3"))
+	if !storage.IsNotFound(err) {
+		t.Fatalf("Expected not found error but got %v", err)
+	}
+}
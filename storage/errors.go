@@ -24,6 +24,10 @@ const (
 	// was rejected.
 	InvalidPatchErr = "storage_invalid_patch_error"
 
+	// PreconditionFailedErr indicates a conditional write was rejected because
+	// the store or document did not match the expected state.
+	PreconditionFailedErr = "storage_precondition_failed_error"
+
 	// InvalidTransactionErr indicates an invalid operation was performed
 	// inside of the transaction.
 	InvalidTransactionErr = "storage_invalid_txn_error"
@@ -85,6 +89,15 @@ func IsInvalidPatch(err error) bool {
 	return false
 }
 
+// IsPreconditionFailed returns true if this error is a PreconditionFailedErr.
+func IsPreconditionFailed(err error) bool {
+	switch err := err.(type) {
+	case *Error:
+		return err.Code == PreconditionFailedErr
+	}
+	return false
+}
+
 // IsInvalidTransaction returns true if this error is a InvalidTransactionErr.
 func IsInvalidTransaction(err error) bool {
 	switch err := err.(type) {
@@ -110,6 +123,13 @@ func writeConflictError(path Path) *Error {
 	}
 }
 
+func preconditionFailedError(f string, a ...interface{}) *Error {
+	return &Error{
+		Code:    PreconditionFailedErr,
+		Message: fmt.Sprintf(f, a...),
+	}
+}
+
 func triggersNotSupportedError() *Error {
 	return &Error{
 		Code: TriggersNotSupportedErr,
@@ -0,0 +1,149 @@
+// Copyright 2020 The OPA Authors.  All rights reserved.
+// Use of this source code is governed by an Apache2
+// license that can be found in the LICENSE file.
+
+package inmem
+
+import (
+	"context"
+	"encoding/json"
+	"strconv"
+
+	"github.com/open-policy-agent/opa/storage"
+	"github.com/open-policy-agent/opa/util"
+)
+
+// Stats returns usage statistics for the document at path. The size of the
+// document is computed from the JSON serialization of the document.
+func (db *store) Stats(_ context.Context, txn storage.Transaction, path storage.Path, depth int) (*storage.Stats, error) {
+	underlying, err := db.underlying(txn)
+	if err != nil {
+		return nil, err
+	}
+	value, err := underlying.Read(path)
+	if err != nil {
+		return nil, err
+	}
+	return stats(value, depth), nil
+}
+
+// Patches returns the number of data patches committed since the store was
+// created or last compacted.
+func (db *store) Patches(_ context.Context, txn storage.Transaction) (uint64, error) {
+	if _, err := db.underlying(txn); err != nil {
+		return 0, err
+	}
+	return db.patches, nil
+}
+
+// Compact replaces the committed data with a copy. Go maps do not shrink when
+// keys are deleted so after many patches the data may hold on to much more
+// memory than it needs. Readers are blocked only while the copy is swapped in.
+func (db *store) Compact(_ context.Context, txn storage.Transaction) error {
+	underlying, err := db.underlying(txn)
+	if err != nil {
+		return err
+	}
+	if !underlying.write {
+		return &storage.Error{
+			Code:    storage.InvalidTransactionErr,
+			Message: "data must be compacted with a write transaction",
+		}
+	}
+
+	// The writer lock is held by the transaction so the committed data cannot
+	// change while it is copied.
+	data := compact(db.data).(map[string]interface{})
+
+	db.rmu.Lock()
+	db.data = data
+	db.patches = 0
+	db.indices = newIndices()
+	db.rmu.Unlock()
+
+	return nil
+}
+
+// Snapshot returns a new store with a copy of the committed data and policies.
+// The copy is made while the transaction holds the lock so it is consistent
+// with the revision of the store.
+func (db *store) Snapshot(_ context.Context, txn storage.Transaction) (storage.Store, error) {
+	if _, err := db.underlying(txn); err != nil {
+		return nil, err
+	}
+
+	policies := make(map[string][]byte, len(db.policies))
+	for id, bs := range db.policies {
+		policies[id] = bs
+	}
+
+	return &store{
+		revision: db.revision,
+		data:     compact(db.data).(map[string]interface{}),
+		policies: policies,
+		triggers: map[*handle]storage.TriggerConfig{},
+		indices:  newIndices(),
+	}, nil
+}
+
+func compact(value interface{}) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		cpy := make(map[string]interface{}, len(v))
+		for k := range v {
+			cpy[k] = compact(v[k])
+		}
+		return cpy
+	case []interface{}:
+		cpy := make([]interface{}, len(v))
+		for i := range v {
+			cpy[i] = compact(v[i])
+		}
+		return cpy
+	default:
+		return v
+	}
+}
+
+func stats(value interface{}, depth int) *storage.Stats {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		result := &storage.Stats{Bytes: 2, Values: 1}
+		if depth > 0 {
+			result.Children = make(map[string]*storage.Stats, len(v))
+		}
+		for k := range v {
+			child := stats(v[k], depth-1)
+			result.Bytes += int64(len(util.MustMarshalJSON(k))) + 1 + child.Bytes
+			result.Values += child.Values
+			if depth > 0 {
+				result.Children[k] = child
+			}
+		}
+		if len(v) > 1 {
+			result.Bytes += int64(len(v) - 1)
+		}
+		return result
+	case []interface{}:
+		result := &storage.Stats{Bytes: 2, Values: 1}
+		if depth > 0 {
+			result.Children = make(map[string]*storage.Stats, len(v))
+		}
+		for i := range v {
+			child := stats(v[i], depth-1)
+			result.Bytes += child.Bytes
+			result.Values += child.Values
+			if depth > 0 {
+				result.Children[strconv.Itoa(i)] = child
+			}
+		}
+		if len(v) > 1 {
+			result.Bytes += int64(len(v) - 1)
+		}
+		return result
+	case json.Number:
+		return &storage.Stats{Bytes: int64(len(v)), Values: 1}
+	default:
+		return &storage.Stats{Bytes: int64(len(util.MustMarshalJSON(v))), Values: 1}
+	}
+}
@@ -69,6 +69,8 @@ type store struct {
 	rmu      sync.RWMutex                      // reader-writer lock
 	wmu      sync.Mutex                        // writer lock
 	xid      uint64                            // last generated transaction id
+	revision uint64                            // number of committed write transactions
+	patches  uint64                            // number of data patches committed since last compaction
 	data     map[string]interface{}            // raw data
 	policies map[string][]byte                 // raw policies
 	triggers map[*handle]storage.TriggerConfig // registered triggers
@@ -103,6 +105,8 @@ func (db *store) Commit(ctx context.Context, txn storage.Transaction) error {
 	if underlying.write {
 		db.rmu.Lock()
 		event := underlying.Commit()
+		db.revision++
+		db.patches += uint64(len(event.Data))
 		db.indices = newIndices()
 		db.runOnCommitTriggers(ctx, txn, event)
 		// Mark the transaction stale after executing triggers so they can
@@ -129,6 +133,16 @@ func (db *store) Abort(ctx context.Context, txn storage.Transaction) {
 	}
 }
 
+// Revision returns the revision of the snapshot identified by txn. Reads of the
+// revision are guarded by the transaction: read transactions hold the reader
+// lock and write transactions hold the writer lock.
+func (db *store) Revision(_ context.Context, txn storage.Transaction) (uint64, error) {
+	if _, err := db.underlying(txn); err != nil {
+		return 0, err
+	}
+	return db.revision, nil
+}
+
 func (db *store) ListPolicies(_ context.Context, txn storage.Transaction) ([]string, error) {
 	underlying, err := db.underlying(txn)
 	if err != nil {
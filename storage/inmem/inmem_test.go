@@ -669,3 +669,199 @@ func loadSmallTestData() map[string]interface{} {
 	}
 	return data
 }
+
+func TestInMemoryRevision(t *testing.T) {
+
+	ctx := context.Background()
+	store := New()
+
+	readRevision := func() uint64 {
+		t.Helper()
+		txn := storage.NewTransactionOrDie(ctx, store)
+		defer store.Abort(ctx, txn)
+		rev, ok, err := storage.Revision(ctx, store, txn)
+		if err != nil || !ok {
+			t.Fatalf("Expected revision but got: %v (ok: %v, err: %v)", rev, ok, err)
+		}
+		return rev
+	}
+
+	if rev := readRevision(); rev != 0 {
+		t.Fatalf("Expected revision 0 but got %v", rev)
+	}
+
+	var triggerRev uint64
+
+	txn := storage.NewTransactionOrDie(ctx, store, storage.WriteParams)
+
+	_, err := store.Register(ctx, txn, storage.TriggerConfig{
+		OnCommit: func(ctx context.Context, txn storage.Transaction, _ storage.TriggerEvent) {
+			triggerRev, _, _ = storage.Revision(ctx, store, txn)
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := store.Write(ctx, txn, storage.AddOp, storage.MustParsePath("/a"), "x"); err != nil {
+		t.Fatal(err)
+	}
+
+	if rev, _, err := storage.Revision(ctx, store, txn); err != nil || rev != 0 {
+		t.Fatalf("Expected uncommitted revision 0 but got %v (err: %v)", rev, err)
+	}
+
+	if err := store.Commit(ctx, txn); err != nil {
+		t.Fatal(err)
+	}
+
+	if triggerRev != 1 {
+		t.Fatalf("Expected trigger to observe revision 1 but got %v", triggerRev)
+	}
+
+	// Aborted writes and read commits do not change the revision.
+	txn = storage.NewTransactionOrDie(ctx, store, storage.WriteParams)
+	if err := store.Write(ctx, txn, storage.AddOp, storage.MustParsePath("/b"), "y"); err != nil {
+		t.Fatal(err)
+	}
+	store.Abort(ctx, txn)
+
+	if _, _, err := storage.Revision(ctx, store, txn); !storage.IsInvalidTransaction(err) {
+		t.Fatalf("Expected invalid transaction error but got: %v", err)
+	}
+
+	txn = storage.NewTransactionOrDie(ctx, store)
+	if err := store.Commit(ctx, txn); err != nil {
+		t.Fatal(err)
+	}
+
+	if rev := readRevision(); rev != 1 {
+		t.Fatalf("Expected revision 1 but got %v", rev)
+	}
+}
+
+func TestInMemoryStatsAndCompact(t *testing.T) {
+
+	ctx := context.Background()
+	store := NewFromObject(loadExpectedSortedResult(`{"a": {"b": [1, "x"], "c": true}, "d": null}`).(map[string]interface{}))
+	c := store.(storage.Compactor)
+
+	txn := storage.NewTransactionOrDie(ctx, store)
+
+	result, err := c.Stats(ctx, txn, storage.Path{}, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	exp := &storage.Stats{
+		Bytes:  len64(`{"a":{"b":[1,"x"],"c":true},"d":null}`),
+		Values: 7,
+		Children: map[string]*storage.Stats{
+			"a": {Bytes: len64(`{"b":[1,"x"],"c":true}`), Values: 5},
+			"d": {Bytes: len64(`null`), Values: 1},
+		},
+	}
+
+	if !reflect.DeepEqual(result, exp) {
+		t.Fatalf("Expected %v but got %v", util.MustMarshalJSON(exp), util.MustMarshalJSON(result))
+	}
+
+	if _, err := c.Stats(ctx, txn, storage.MustParsePath("/a/missing"), 0); !storage.IsNotFound(err) {
+		t.Fatalf("Expected not found error but got: %v", err)
+	}
+
+	if err := c.Compact(ctx, txn); err == nil {
+		t.Fatal("Expected error compacting with read transaction")
+	}
+
+	store.Abort(ctx, txn)
+
+	for i := 0; i < 3; i++ {
+		if err := storage.WriteOne(ctx, store, storage.AddOp, storage.MustParsePath("/x"), i); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	txn = storage.NewTransactionOrDie(ctx, store, storage.WriteParams)
+
+	// The initial write from NewFromObject counts as a patch.
+	if n, err := c.Patches(ctx, txn); err != nil || n != 4 {
+		t.Fatalf("Expected 4 patches but got %v (err: %v)", n, err)
+	}
+
+	if err := c.Compact(ctx, txn); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := store.Commit(ctx, txn); err != nil {
+		t.Fatal(err)
+	}
+
+	txn = storage.NewTransactionOrDie(ctx, store)
+	defer store.Abort(ctx, txn)
+
+	if n, err := c.Patches(ctx, txn); err != nil || n != 0 {
+		t.Fatalf("Expected 0 patches but got %v (err: %v)", n, err)
+	}
+
+	value, err := store.Read(ctx, txn, storage.Path{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !reflect.DeepEqual(value, loadExpectedSortedResult(`{"a": {"b": [1, "x"], "c": true}, "d": null, "x": 2}`)) {
+		t.Fatalf("Unexpected data after compaction: %v", value)
+	}
+}
+
+func TestInMemorySnapshot(t *testing.T) {
+
+	ctx := context.Background()
+	store := NewFromObject(loadExpectedSortedResult(`{"a": {"b": [1, "x"]}}`).(map[string]interface{}))
+
+	if err := storage.Txn(ctx, store, storage.WriteParams, func(txn storage.Transaction) error {
+		return store.UpsertPolicy(ctx, txn, "test.rego", []byte("package test"))
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	txn := storage.NewTransactionOrDie(ctx, store)
+	snapshot, err := store.(storage.Snapshotter).Snapshot(ctx, txn)
+	store.Abort(ctx, txn)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := storage.WriteOne(ctx, store, storage.AddOp, storage.MustParsePath("/a/b/-"), "y"); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := storage.Txn(ctx, store, storage.WriteParams, func(txn storage.Transaction) error {
+		return store.DeletePolicy(ctx, txn, "test.rego")
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	txn = storage.NewTransactionOrDie(ctx, snapshot)
+	defer snapshot.Abort(ctx, txn)
+
+	value, err := snapshot.Read(ctx, txn, storage.Path{})
+	if err != nil {
+		t.Fatal(err)
+	} else if !reflect.DeepEqual(value, loadExpectedSortedResult(`{"a": {"b": [1, "x"]}}`)) {
+		t.Fatalf("Expected snapshot data to be unchanged but got: %v", value)
+	}
+
+	if bs, err := snapshot.GetPolicy(ctx, txn, "test.rego"); err != nil || string(bs) != "package test" {
+		t.Fatalf("Expected snapshot policy but got %q (err: %v)", bs, err)
+	}
+
+	if rev, ok, err := storage.Revision(ctx, snapshot, txn); err != nil || !ok || rev != 2 {
+		t.Fatalf("Expected snapshot revision 2 but got %v (err: %v)", rev, err)
+	}
+}
+
+func len64(s string) int64 {
+	return int64(len(s))
+}
@@ -10,6 +10,8 @@ import (
 	"strconv"
 
 	"github.com/open-policy-agent/opa/storage"
+	"github.com/open-policy-agent/opa/storage/columnar"
+	"github.com/open-policy-agent/opa/util"
 )
 
 // transaction implements the low-level read/write operations on the in-memory
@@ -204,7 +206,7 @@ func (txn *transaction) Read(path storage.Path) (interface{}, error) {
 		return data, nil
 	}
 
-	cpy := deepCopy(data)
+	cpy := util.DeepCopy(data)
 
 	for _, update := range merge {
 		cpy = update.Relative(path).Apply(cpy)
@@ -388,25 +390,6 @@ func (u *update) Relative(path storage.Path) *update {
 	return &cpy
 }
 
-func deepCopy(val interface{}) interface{} {
-	switch val := val.(type) {
-	case []interface{}:
-		cpy := make([]interface{}, len(val))
-		for i := range cpy {
-			cpy[i] = deepCopy(val[i])
-		}
-		return cpy
-	case map[string]interface{}:
-		cpy := make(map[string]interface{}, len(val))
-		for k := range val {
-			cpy[k] = deepCopy(val[k])
-		}
-		return cpy
-	default:
-		return val
-	}
-}
-
 func ptr(data interface{}, path storage.Path) (interface{}, error) {
 
 	node := data
@@ -424,6 +407,12 @@ func ptr(data interface{}, path storage.Path) (interface{}, error) {
 				return nil, err
 			}
 			node = curr[pos]
+		case *columnar.Table:
+			pos, err := validateIndex(curr.Len(), key, path)
+			if err != nil {
+				return nil, err
+			}
+			node = curr.Row(pos)
 		default:
 			return nil, notFoundError(path)
 		}
@@ -433,11 +422,15 @@ func ptr(data interface{}, path storage.Path) (interface{}, error) {
 }
 
 func validateArrayIndex(arr []interface{}, s string, path storage.Path) (int, error) {
+	return validateIndex(len(arr), s, path)
+}
+
+func validateIndex(n int, s string, path storage.Path) (int, error) {
 	idx, err := strconv.Atoi(s)
 	if err != nil {
 		return 0, notFoundErrorHint(path, arrayIndexTypeMsg)
 	}
-	if idx < 0 || idx >= len(arr) {
+	if idx < 0 || idx >= n {
 		return 0, notFoundErrorHint(path, outOfRangeMsg)
 	}
 	return idx, nil
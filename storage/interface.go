@@ -16,7 +16,10 @@ type Transaction interface {
 	ID() uint64
 }
 
-// Store defines the interface for the storage layer's backend.
+// Store defines the interface for the storage layer's backend. Stores must be
+// safe for concurrent use by multiple goroutines. Each transaction provides a
+// consistent snapshot of the data; values read inside a transaction must not
+// be modified by the caller.
 type Store interface {
 	Trigger
 	Policy
@@ -190,6 +193,59 @@ func (TriggersNotSupported) Register(context.Context, Transaction, TriggerConfig
 	return nil, triggersNotSupportedError()
 }
 
+// Revisioned defines the interface that stores implement to track the revision
+// of the data. The revision is incremented each time a write transaction is
+// committed.
+type Revisioned interface {
+	// Revision returns the revision of the snapshot identified by txn. For
+	// write transactions, the revision is incremented when the transaction is
+	// committed (i.e., before triggers are invoked.)
+	Revision(ctx context.Context, txn Transaction) (uint64, error)
+}
+
+// Compactor defines the interface that stores implement to report how much
+// memory the data uses and to release memory that is no longer needed (e.g.,
+// after many patches have been applied.)
+type Compactor interface {
+	// Stats returns usage statistics for the document at path in the snapshot
+	// identified by txn. Statistics for the children of the document are
+	// included up to the given depth.
+	Stats(ctx context.Context, txn Transaction, path Path, depth int) (*Stats, error)
+
+	// Patches returns the number of data patches committed since the store
+	// was created or last compacted.
+	Patches(ctx context.Context, txn Transaction) (uint64, error)
+
+	// Compact replaces the data with a compact copy. Compact must be called
+	// with a write transaction. The data is not modified so the compaction
+	// takes effect immediately and is not undone if the transaction is
+	// aborted.
+	Compact(ctx context.Context, txn Transaction) error
+}
+
+// Snapshotter defines the interface that stores implement to create
+// point-in-time copies of the data and policies.
+type Snapshotter interface {
+	// Snapshot returns a new store that contains a copy of the committed data
+	// and policies visible to txn. The copy reports the same revision as the
+	// store. Writes to either store are not visible in the other.
+	Snapshot(ctx context.Context, txn Transaction) (Store, error)
+}
+
+// Stats contains usage statistics for a document.
+type Stats struct {
+	// Bytes is the size of the JSON serialization of the document.
+	Bytes int64 `json:"bytes"`
+
+	// Values is the number of values in the document, including the document
+	// itself.
+	Values int64 `json:"values"`
+
+	// Children contains the statistics of the elements of the document keyed
+	// by object key or array index.
+	Children map[string]*Stats `json:"children,omitempty"`
+}
+
 // TriggerHandle defines the interface that can be used to unregister triggers that have
 // been registered on a Store.
 type TriggerHandle interface {
@@ -0,0 +1,467 @@
+// Copyright 2019 The OPA Authors.  All rights reserved.
+// Use of this source code is governed by an Apache2
+// license that can be found in the LICENSE file.
+
+// Package overlay implements a storage layer that applies writes on top of
+// another store without modifying it.
+//
+// The overlay is intended for what-if analysis: callers apply hypothetical
+// patches to the overlay, evaluate queries against the combined view of the
+// base data and the patches, and then discard the overlay. Only the patched
+// documents are held in memory; everything else is read from the base store.
+package overlay
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"sync"
+	"sync/atomic"
+
+	"github.com/open-policy-agent/opa/storage"
+	"github.com/open-policy-agent/opa/util"
+)
+
+// Store implements the storage.Store interface on top of a base store. Writes
+// are never applied to the base store. The overlay does not support triggers,
+// indexing, or policy writes.
+type Store struct {
+	storage.TriggersNotSupported
+	storage.IndexingNotSupported
+
+	base    storage.Store
+	updates []*update    // committed updates; never mutated after commit
+	rmu     sync.RWMutex // guards updates
+	wmu     sync.Mutex   // serializes write transactions
+	xid     uint64
+}
+
+// New returns an empty overlay on top of base.
+func New(base storage.Store) *Store {
+	return &Store{base: base}
+}
+
+// Reset discards all of the writes committed to the overlay.
+func (s *Store) Reset() {
+	s.rmu.Lock()
+	defer s.rmu.Unlock()
+	s.updates = nil
+}
+
+// NewTransaction returns a new transaction on the overlay. A read transaction
+// is opened on the base store for the lifetime of the overlay transaction.
+func (s *Store) NewTransaction(ctx context.Context, params ...storage.TransactionParams) (storage.Transaction, error) {
+
+	var write bool
+	var context *storage.Context
+
+	if len(params) > 0 {
+		write = params[0].Write
+		context = params[0].Context
+	}
+
+	base, err := s.base.NewTransaction(ctx, storage.TransactionParams{Context: context})
+	if err != nil {
+		return nil, err
+	}
+
+	if write {
+		s.wmu.Lock()
+	}
+
+	s.rmu.RLock()
+	updates := s.updates
+	s.rmu.RUnlock()
+
+	return &transaction{
+		xid:     atomic.AddUint64(&s.xid, 1),
+		write:   write,
+		base:    base,
+		store:   s,
+		updates: updates,
+	}, nil
+}
+
+// Read returns the document at path, including the effect of any writes made
+// to the overlay.
+func (s *Store) Read(ctx context.Context, txn storage.Transaction, path storage.Path) (interface{}, error) {
+	underlying, err := s.underlying(txn)
+	if err != nil {
+		return nil, err
+	}
+	return underlying.Read(ctx, path)
+}
+
+// Write applies the patch to the overlay transaction.
+func (s *Store) Write(ctx context.Context, txn storage.Transaction, op storage.PatchOp, path storage.Path, value interface{}) error {
+	underlying, err := s.underlying(txn)
+	if err != nil {
+		return err
+	}
+	return underlying.Write(ctx, op, path, value)
+}
+
+// Commit makes the writes in the transaction visible to subsequent overlay
+// transactions. The base store is not modified.
+func (s *Store) Commit(ctx context.Context, txn storage.Transaction) error {
+	underlying, err := s.underlying(txn)
+	if err != nil {
+		return err
+	}
+	if underlying.write {
+		s.rmu.Lock()
+		s.updates = underlying.updates
+		s.rmu.Unlock()
+	}
+	s.release(ctx, underlying)
+	return nil
+}
+
+// Abort discards the transaction.
+func (s *Store) Abort(ctx context.Context, txn storage.Transaction) {
+	underlying, err := s.underlying(txn)
+	if err != nil {
+		panic(err)
+	}
+	s.release(ctx, underlying)
+}
+
+// ListPolicies returns the policies stored in the base store.
+func (s *Store) ListPolicies(ctx context.Context, txn storage.Transaction) ([]string, error) {
+	underlying, err := s.underlying(txn)
+	if err != nil {
+		return nil, err
+	}
+	return s.base.ListPolicies(ctx, underlying.base)
+}
+
+// GetPolicy returns the policy from the base store.
+func (s *Store) GetPolicy(ctx context.Context, txn storage.Transaction, id string) ([]byte, error) {
+	underlying, err := s.underlying(txn)
+	if err != nil {
+		return nil, err
+	}
+	return s.base.GetPolicy(ctx, underlying.base, id)
+}
+
+// UpsertPolicy always returns an error because the overlay does not support
+// policy writes.
+func (s *Store) UpsertPolicy(context.Context, storage.Transaction, string, []byte) error {
+	return policyWritesNotSupportedError()
+}
+
+// DeletePolicy always returns an error because the overlay does not support
+// policy writes.
+func (s *Store) DeletePolicy(context.Context, storage.Transaction, string) error {
+	return policyWritesNotSupportedError()
+}
+
+func (s *Store) release(ctx context.Context, txn *transaction) {
+	txn.stale = true
+	s.base.Abort(ctx, txn.base)
+	if txn.write {
+		s.wmu.Unlock()
+	}
+}
+
+func (s *Store) underlying(txn storage.Transaction) (*transaction, error) {
+	underlying, ok := txn.(*transaction)
+	if !ok {
+		return nil, &storage.Error{
+			Code:    storage.InvalidTransactionErr,
+			Message: fmt.Sprintf("unexpected transaction type %T", txn),
+		}
+	}
+	if underlying.store != s {
+		return nil, &storage.Error{
+			Code:    storage.InvalidTransactionErr,
+			Message: "unknown transaction",
+		}
+	}
+	if underlying.stale {
+		return nil, &storage.Error{
+			Code:    storage.InvalidTransactionErr,
+			Message: "stale transaction",
+		}
+	}
+	return underlying, nil
+}
+
+// transaction holds the set of updates visible to the transaction. As in the
+// in-memory store, updates never overlap: each write either masks existing
+// updates, modifies the update that contains it, or is added to the set.
+// Updates are replaced rather than mutated so that committed updates can be
+// shared by concurrent transactions.
+type transaction struct {
+	xid     uint64
+	write   bool
+	stale   bool
+	base    storage.Transaction
+	store   *Store
+	updates []*update
+}
+
+func (txn *transaction) ID() uint64 {
+	return txn.xid
+}
+
+func (txn *transaction) Read(ctx context.Context, path storage.Path) (interface{}, error) {
+
+	var merge []*update
+
+	for _, u := range txn.updates {
+
+		if path.HasPrefix(u.path) {
+			if u.remove {
+				return nil, notFoundError(path)
+			}
+			return ptr(u.value, path[len(u.path):])
+		}
+
+		if u.path.HasPrefix(path) {
+			merge = append(merge, u)
+		}
+	}
+
+	data, err := txn.store.base.Read(ctx, txn.base, path)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(merge) == 0 {
+		return data, nil
+	}
+
+	cpy := util.DeepCopy(data)
+
+	for _, u := range merge {
+		// If the base data changed after the write, the update may no longer
+		// apply. In that case it is ignored.
+		if result, err := u.relative(path).apply(cpy); err == nil {
+			cpy = result
+		}
+	}
+
+	return cpy, nil
+}
+
+func (txn *transaction) Write(ctx context.Context, op storage.PatchOp, path storage.Path, value interface{}) error {
+
+	if !txn.write {
+		return &storage.Error{
+			Code:    storage.InvalidTransactionErr,
+			Message: "data write during read transaction",
+		}
+	}
+
+	if len(path) == 0 {
+		if op == storage.RemoveOp {
+			return invalidPatchError("root cannot be removed")
+		}
+		if _, ok := value.(map[string]interface{}); !ok {
+			return invalidPatchError("root must be object")
+		}
+		txn.updates = []*update{{path: storage.Path{}, value: value}}
+		return nil
+	}
+
+	parent, err := txn.Read(ctx, path[:len(path)-1])
+	if err != nil {
+		return err
+	}
+
+	u, err := newUpdate(parent, op, path, value)
+	if err != nil {
+		return err
+	}
+
+	var result []*update
+
+	for i, existing := range txn.updates {
+
+		if existing.path.HasPrefix(u.path) {
+			continue
+		}
+
+		if u.path.HasPrefix(existing.path) {
+			modified, err := u.relative(existing.path).apply(util.DeepCopy(existing.value))
+			if err != nil {
+				return err
+			}
+			result = append(result, &update{path: existing.path, value: modified})
+			result = append(result, txn.updates[i+1:]...)
+			txn.updates = result
+			return nil
+		}
+
+		result = append(result, existing)
+	}
+
+	txn.updates = append(result, u)
+	return nil
+}
+
+// update replaces (or removes) the value at path.
+type update struct {
+	path   storage.Path
+	remove bool
+	value  interface{}
+}
+
+// newUpdate returns the update that results from applying the patch to the
+// parent of path. Array patches are turned into replacements of the entire
+// array.
+func newUpdate(parent interface{}, op storage.PatchOp, path storage.Path, value interface{}) (*update, error) {
+
+	key := path[len(path)-1]
+
+	switch parent := parent.(type) {
+	case map[string]interface{}:
+		if op != storage.AddOp {
+			if _, ok := parent[key]; !ok {
+				return nil, notFoundError(path)
+			}
+		}
+		return &update{path: path, remove: op == storage.RemoveOp, value: value}, nil
+
+	case []interface{}:
+		arrayPath := path[:len(path)-1]
+
+		if key == "-" {
+			if op != storage.AddOp {
+				return nil, invalidPatchError("%v: invalid patch path", path)
+			}
+			cpy := make([]interface{}, len(parent), len(parent)+1)
+			copy(cpy, parent)
+			return &update{path: arrayPath, value: append(cpy, value)}, nil
+		}
+
+		pos, err := validateArrayIndex(parent, key, path)
+		if err != nil {
+			return nil, err
+		}
+
+		var cpy []interface{}
+
+		switch op {
+		case storage.AddOp:
+			cpy = make([]interface{}, 0, len(parent)+1)
+			cpy = append(cpy, parent[:pos]...)
+			cpy = append(cpy, value)
+			cpy = append(cpy, parent[pos:]...)
+		case storage.RemoveOp:
+			cpy = make([]interface{}, 0, len(parent)-1)
+			cpy = append(cpy, parent[:pos]...)
+			cpy = append(cpy, parent[pos+1:]...)
+		default:
+			cpy = make([]interface{}, len(parent))
+			copy(cpy, parent)
+			cpy[pos] = value
+		}
+
+		return &update{path: arrayPath, value: cpy}, nil
+	}
+
+	return nil, notFoundError(path)
+}
+
+func (u *update) relative(path storage.Path) *update {
+	cpy := *u
+	cpy.path = cpy.path[len(path):]
+	return &cpy
+}
+
+// apply applies the update to data. The data is modified in place.
+func (u *update) apply(data interface{}) (interface{}, error) {
+
+	if len(u.path) == 0 {
+		return u.value, nil
+	}
+
+	parent, err := ptr(data, u.path[:len(u.path)-1])
+	if err != nil {
+		return nil, err
+	}
+
+	key := u.path[len(u.path)-1]
+
+	switch parent := parent.(type) {
+	case map[string]interface{}:
+		if u.remove {
+			delete(parent, key)
+		} else {
+			parent[key] = u.value
+		}
+		return data, nil
+	case []interface{}:
+		pos, err := validateArrayIndex(parent, key, u.path)
+		if err != nil {
+			return nil, err
+		}
+		parent[pos] = u.value
+		return data, nil
+	}
+
+	return nil, notFoundError(u.path)
+}
+
+func ptr(data interface{}, path storage.Path) (interface{}, error) {
+
+	node := data
+
+	for i := range path {
+		switch curr := node.(type) {
+		case map[string]interface{}:
+			var ok bool
+			if node, ok = curr[path[i]]; !ok {
+				return nil, notFoundError(path)
+			}
+		case []interface{}:
+			pos, err := validateArrayIndex(curr, path[i], path)
+			if err != nil {
+				return nil, err
+			}
+			node = curr[pos]
+		default:
+			return nil, notFoundError(path)
+		}
+	}
+
+	return node, nil
+}
+
+func validateArrayIndex(arr []interface{}, s string, path storage.Path) (int, error) {
+	idx, err := strconv.Atoi(s)
+	if err != nil {
+		return 0, notFoundErrorf("%v: array index must be integer", path)
+	}
+	if idx < 0 || idx >= len(arr) {
+		return 0, notFoundErrorf("%v: array index out of range", path)
+	}
+	return idx, nil
+}
+
+func invalidPatchError(f string, a ...interface{}) *storage.Error {
+	return &storage.Error{
+		Code:    storage.InvalidPatchErr,
+		Message: fmt.Sprintf(f, a...),
+	}
+}
+
+func notFoundError(path storage.Path) *storage.Error {
+	return notFoundErrorf("%v: document does not exist", path)
+}
+
+func notFoundErrorf(f string, a ...interface{}) *storage.Error {
+	return &storage.Error{
+		Code:    storage.NotFoundErr,
+		Message: fmt.Sprintf(f, a...),
+	}
+}
+
+func policyWritesNotSupportedError() *storage.Error {
+	return &storage.Error{
+		Code:    storage.WritesNotSupportedErr,
+		Message: "overlay does not support policy writes",
+	}
+}
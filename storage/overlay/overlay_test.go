@@ -0,0 +1,206 @@
+// Copyright 2019 The OPA Authors.  All rights reserved.
+// Use of this source code is governed by an Apache2
+// license that can be found in the LICENSE file.
+
+package overlay
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/open-policy-agent/opa/rego"
+	"github.com/open-policy-agent/opa/storage"
+	"github.com/open-policy-agent/opa/storage/inmem"
+	"github.com/open-policy-agent/opa/util"
+)
+
+const baseData = `{
+	"a": [1, 2, 3],
+	"b": {"c": {"d": "x", "e": "y"}},
+	"f": "z"
+}`
+
+func TestOverlayWrite(t *testing.T) {
+
+	tests := []struct {
+		note     string
+		writes   [][3]string // op, path, value
+		path     string
+		expected string
+		err      string
+	}{
+		{"no writes", nil, "/", baseData, ""},
+		{"add new", [][3]string{{"add", "/g", `1`}}, "/g", `1`, ""},
+		{"add merged into parent", [][3]string{{"add", "/b/c/z", `true`}}, "/b", `{"c": {"d": "x", "e": "y", "z": true}}`, ""},
+		{"replace", [][3]string{{"replace", "/f", `"zz"`}}, "/f", `"zz"`, ""},
+		{"remove", [][3]string{{"remove", "/b/c/d"}}, "/b/c", `{"e": "y"}`, ""},
+		{"remove then read", [][3]string{{"remove", "/b"}}, "/b/c", ``, storage.NotFoundErr},
+		{"remove then add", [][3]string{{"remove", "/b"}, {"add", "/b", `{}`}}, "/b", `{}`, ""},
+		{"nested write", [][3]string{{"add", "/g", `{"h": 1}`}, {"add", "/g/i", `2`}}, "/g", `{"h": 1, "i": 2}`, ""},
+		{"masking write", [][3]string{{"add", "/b/c/z", `1`}, {"replace", "/b", `"w"`}}, "/b", `"w"`, ""},
+		{"array append", [][3]string{{"add", "/a/-", `4`}}, "/a", `[1, 2, 3, 4]`, ""},
+		{"array insert", [][3]string{{"add", "/a/0", `0`}}, "/a", `[0, 1, 2, 3]`, ""},
+		{"array remove", [][3]string{{"remove", "/a/1"}}, "/a", `[1, 3]`, ""},
+		{"array replace", [][3]string{{"replace", "/a/2", `9`}}, "/a/2", `9`, ""},
+		{"root", [][3]string{{"add", "/", `{"x": 1}`}}, "/", `{"x": 1}`, ""},
+		{"missing parent", [][3]string{{"add", "/x/y", `1`}}, "", ``, storage.NotFoundErr},
+		{"replace missing", [][3]string{{"replace", "/x", `1`}}, "", ``, storage.NotFoundErr},
+		{"bad array index", [][3]string{{"add", "/a/x", `1`}}, "", ``, storage.NotFoundErr},
+		{"root not object", [][3]string{{"add", "/", `[]`}}, "", ``, storage.InvalidPatchErr},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.note, func(t *testing.T) {
+
+			ctx := context.Background()
+			base := inmem.NewFromObject(loadData(baseData))
+			store := New(base)
+
+			err := storage.Txn(ctx, store, storage.WriteParams, func(txn storage.Transaction) error {
+				for _, w := range tc.writes {
+					op := map[string]storage.PatchOp{"add": storage.AddOp, "remove": storage.RemoveOp, "replace": storage.ReplaceOp}[w[0]]
+					var value interface{}
+					if w[2] != "" {
+						value = util.MustUnmarshalJSON([]byte(w[2]))
+					}
+					if err := store.Write(ctx, txn, op, storage.MustParsePath(w[1]), value); err != nil {
+						return err
+					}
+				}
+				return nil
+			})
+
+			if tc.path != "" && err == nil {
+				_, err = storage.ReadOne(ctx, store, storage.MustParsePath(tc.path))
+			}
+
+			if tc.err != "" {
+				if serr, ok := err.(*storage.Error); !ok || serr.Code != tc.err {
+					t.Fatalf("Expected %v but got: %v", tc.err, err)
+				}
+				return
+			} else if err != nil {
+				t.Fatal(err)
+			}
+
+			result, err := storage.ReadOne(ctx, store, storage.MustParsePath(tc.path))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if exp := util.MustUnmarshalJSON([]byte(tc.expected)); !reflect.DeepEqual(result, exp) {
+				t.Fatalf("Expected %v but got %v", exp, result)
+			}
+
+			// The base store must never be modified.
+			root, err := storage.ReadOne(ctx, base, storage.Path{})
+			if err != nil {
+				t.Fatal(err)
+			} else if exp := loadData(baseData); !reflect.DeepEqual(root, exp) {
+				t.Fatalf("Expected base to be unmodified but got %v", root)
+			}
+		})
+	}
+}
+
+func TestOverlayIsolation(t *testing.T) {
+
+	ctx := context.Background()
+	store := New(inmem.NewFromObject(loadData(baseData)))
+
+	read := storage.NewTransactionOrDie(ctx, store)
+	write := storage.NewTransactionOrDie(ctx, store, storage.WriteParams)
+
+	if err := store.Write(ctx, write, storage.ReplaceOp, storage.MustParsePath("/f"), "changed"); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := store.Commit(ctx, write); err != nil {
+		t.Fatal(err)
+	}
+
+	if v, err := store.Read(ctx, read, storage.MustParsePath("/f")); err != nil || v != "z" {
+		t.Fatalf("Expected open transaction to see old value but got %v (err: %v)", v, err)
+	}
+
+	store.Abort(ctx, read)
+
+	if v, err := storage.ReadOne(ctx, store, storage.MustParsePath("/f")); err != nil || v != "changed" {
+		t.Fatalf("Expected committed value but got %v (err: %v)", v, err)
+	}
+
+	store.Reset()
+
+	if v, err := storage.ReadOne(ctx, store, storage.MustParsePath("/f")); err != nil || v != "z" {
+		t.Fatalf("Expected base value after reset but got %v (err: %v)", v, err)
+	}
+
+	if err := store.Commit(ctx, write); err == nil {
+		t.Fatal("Expected stale transaction error")
+	}
+}
+
+func TestOverlayPolicies(t *testing.T) {
+
+	ctx := context.Background()
+	base := inmem.New()
+
+	if err := storage.Txn(ctx, base, storage.WriteParams, func(txn storage.Transaction) error {
+		return base.UpsertPolicy(ctx, txn, "test.rego", []byte("package test"))
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	store := New(base)
+	txn := storage.NewTransactionOrDie(ctx, store, storage.WriteParams)
+	defer store.Abort(ctx, txn)
+
+	if ids, err := store.ListPolicies(ctx, txn); err != nil || len(ids) != 1 {
+		t.Fatalf("Expected base policy but got %v (err: %v)", ids, err)
+	}
+
+	if err, ok := store.UpsertPolicy(ctx, txn, "x.rego", nil).(*storage.Error); !ok || err.Code != storage.WritesNotSupportedErr {
+		t.Fatalf("Expected writes not supported error but got: %v", err)
+	}
+}
+
+func TestOverlayEval(t *testing.T) {
+
+	ctx := context.Background()
+	base := inmem.NewFromObject(loadData(`{"users": {"alice": {"admin": true}, "bob": {"admin": false}}}`))
+	store := New(base)
+
+	eval := func(s storage.Store) interface{} {
+		rs, err := rego.New(
+			rego.Query(`x = sort({u | data.users[u].admin})`),
+			rego.Store(s),
+		).Eval(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return rs[0].Bindings["x"]
+	}
+
+	if err := storage.Txn(ctx, store, storage.WriteParams, func(txn storage.Transaction) error {
+		return store.Write(ctx, txn, storage.ReplaceOp, storage.MustParsePath("/users/bob/admin"), true)
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	if result := eval(store); !reflect.DeepEqual(result, []interface{}{"alice", "bob"}) {
+		t.Fatalf("Unexpected overlay result: %v", result)
+	}
+
+	if result := eval(base); !reflect.DeepEqual(result, []interface{}{"alice"}) {
+		t.Fatalf("Unexpected base result: %v", result)
+	}
+}
+
+func loadData(s string) map[string]interface{} {
+	var data map[string]interface{}
+	if err := util.UnmarshalJSON([]byte(s), &data); err != nil {
+		panic(err)
+	}
+	return data
+}
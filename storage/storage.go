@@ -6,6 +6,8 @@ package storage
 
 import (
 	"context"
+
+	"github.com/open-policy-agent/opa/ast"
 )
 
 // NewTransactionOrDie is a helper function to create a new transaction. If the
@@ -100,6 +102,74 @@ func Txn(ctx context.Context, store Store, params TransactionParams, f func(Tran
 	return store.Commit(ctx, txn)
 }
 
+// Revision returns the revision of the snapshot identified by txn. If the store
+// does not track revisions, ok is false.
+func Revision(ctx context.Context, store Store, txn Transaction) (rev uint64, ok bool, err error) {
+	r, ok := store.(Revisioned)
+	if !ok {
+		return 0, false, nil
+	}
+	rev, err = r.Revision(ctx, txn)
+	if err != nil {
+		return 0, false, err
+	}
+	return rev, true, nil
+}
+
+// CheckRevision returns a PreconditionFailedErr if the revision of the snapshot
+// identified by txn is not rev. Callers use CheckRevision inside of write
+// transactions to reject writes based on stale reads.
+func CheckRevision(ctx context.Context, store Store, txn Transaction, rev uint64) error {
+	actual, ok, err := Revision(ctx, store, txn)
+	if err != nil {
+		return err
+	} else if !ok {
+		return preconditionFailedError("store does not track revisions")
+	} else if actual != rev {
+		return preconditionFailedError("expected revision %d but store is at revision %d", rev, actual)
+	}
+	return nil
+}
+
+// CheckValue returns a PreconditionFailedErr if the document at path does not
+// exist or is not equal to expected.
+func CheckValue(ctx context.Context, store Store, txn Transaction, path Path, expected interface{}) error {
+
+	actual, err := store.Read(ctx, txn, path)
+	if err != nil {
+		if IsNotFound(err) {
+			return preconditionFailedError("document does not exist: %v", path)
+		}
+		return err
+	}
+
+	a, err := ast.InterfaceToValue(actual)
+	if err != nil {
+		return err
+	}
+
+	b, err := ast.InterfaceToValue(expected)
+	if err != nil {
+		return err
+	}
+
+	if a.Compare(b) != 0 {
+		return preconditionFailedError("document does not match expected value: %v", path)
+	}
+
+	return nil
+}
+
+// CompareAndSwap replaces the document at path with value if the document is
+// equal to expected. Otherwise, a PreconditionFailedErr is returned and the
+// store is not modified.
+func CompareAndSwap(ctx context.Context, store Store, txn Transaction, path Path, expected, value interface{}) error {
+	if err := CheckValue(ctx, store, txn, path, expected); err != nil {
+		return err
+	}
+	return store.Write(ctx, txn, ReplaceOp, path, value)
+}
+
 // NonEmpty returns a function that tests if a path is non-empty. A
 // path is non-empty if a Read on the path returns a value or a Read
 // on any of the path prefixes returns a non-object value.
@@ -66,3 +66,59 @@ func TestNonEmpty(t *testing.T) {
 	}
 
 }
+
+func TestCompareAndSwap(t *testing.T) {
+
+	ctx := context.Background()
+	store := inmem.NewFromReader(bytes.NewBufferString(`{"a": {"b": [1, 2]}}`))
+	path := storage.MustParsePath("/a/b")
+
+	tests := []struct {
+		note     string
+		path     storage.Path
+		expected interface{}
+		err      bool
+	}{
+		{note: "mismatch", path: path, expected: []interface{}{1}, err: true},
+		{note: "missing", path: storage.MustParsePath("/a/c"), expected: nil, err: true},
+		{note: "match", path: path, expected: []interface{}{1, 2}},
+		{note: "stale", path: path, expected: []interface{}{1, 2}, err: true},
+	}
+
+	for _, tc := range tests {
+		err := storage.Txn(ctx, store, storage.WriteParams, func(txn storage.Transaction) error {
+			return storage.CompareAndSwap(ctx, store, txn, tc.path, tc.expected, "x")
+		})
+		if tc.err && !storage.IsPreconditionFailed(err) {
+			t.Fatalf("%v: expected precondition failed error but got: %v", tc.note, err)
+		} else if !tc.err && err != nil {
+			t.Fatalf("%v: unexpected error: %v", tc.note, err)
+		}
+	}
+
+	result, err := storage.ReadOne(ctx, store, path)
+	if err != nil || result != "x" {
+		t.Fatalf("Expected x but got: %v (err: %v)", result, err)
+	}
+}
+
+func TestCheckRevision(t *testing.T) {
+
+	ctx := context.Background()
+	store := inmem.New()
+
+	if err := storage.WriteOne(ctx, store, storage.AddOp, storage.MustParsePath("/a"), 1); err != nil {
+		t.Fatal(err)
+	}
+
+	txn := storage.NewTransactionOrDie(ctx, store)
+	defer store.Abort(ctx, txn)
+
+	if err := storage.CheckRevision(ctx, store, txn, 1); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if err := storage.CheckRevision(ctx, store, txn, 0); !storage.IsPreconditionFailed(err) {
+		t.Fatalf("Expected precondition failed error but got: %v", err)
+	}
+}
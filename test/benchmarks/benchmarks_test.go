@@ -0,0 +1,111 @@
+// Copyright 2019 The OPA Authors.  All rights reserved.
+// Use of this source code is governed by an Apache2
+// license that can be found in the LICENSE file.
+
+package benchmarks
+
+import (
+	"context"
+	"testing"
+
+	"github.com/open-policy-agent/opa/rego"
+	"github.com/open-policy-agent/opa/storage"
+	"github.com/open-policy-agent/opa/storage/inmem"
+)
+
+func BenchmarkRefEnumeration10(b *testing.B) {
+	runBenchmark(b, RefEnumerationPolicy, "data.bench.leaves", GenerateTree(10))
+}
+
+func BenchmarkRefEnumeration20(b *testing.B) {
+	runBenchmark(b, RefEnumerationPolicy, "data.bench.leaves", GenerateTree(20))
+}
+
+func BenchmarkUnificationPatterns100(b *testing.B) {
+	runBenchmark(b, UnificationPolicy, "data.bench.matches", GenerateItems(100))
+}
+
+func BenchmarkUnificationJoin100(b *testing.B) {
+	runBenchmark(b, UnificationPolicy, "data.bench.joined", GenerateItems(100))
+}
+
+func BenchmarkLargeSet1000(b *testing.B) {
+	runBenchmark(b, LargeSetPolicy, "data.bench.size", GenerateNumbers(1000))
+}
+
+func BenchmarkLargeSet10000(b *testing.B) {
+	runBenchmark(b, LargeSetPolicy, "data.bench.size", GenerateNumbers(10000))
+}
+
+func BenchmarkBuiltins1000(b *testing.B) {
+	runBenchmark(b, BuiltinPolicy, "data.bench.names", GenerateUsers(1000))
+}
+
+func TestPolicies(t *testing.T) {
+
+	tests := []struct {
+		note   string
+		policy string
+		query  string
+		data   map[string]interface{}
+		size   int
+	}{
+		{"ref enumeration", RefEnumerationPolicy, "data.bench.leaves", GenerateTree(3), 27},
+		{"unification patterns", UnificationPolicy, "data.bench.matches", GenerateItems(20), 10},
+		{"unification join", UnificationPolicy, "data.bench.joined", GenerateItems(20), 10},
+		{"large set", LargeSetPolicy, "data.bench.both", GenerateNumbers(100), 5},
+		{"builtins", BuiltinPolicy, "data.bench.names", GenerateUsers(20), 20},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.note, func(t *testing.T) {
+			pq := prepare(t, tc.policy, tc.query, tc.data)
+			rs, err := pq.Eval(context.Background())
+			if err != nil {
+				t.Fatal(err)
+			} else if len(rs) != 1 {
+				t.Fatalf("Expected one result but got: %v", rs)
+			}
+			if n := len(rs[0].Expressions[0].Value.([]interface{})); n != tc.size {
+				t.Fatalf("Expected %d elements but got %d", tc.size, n)
+			}
+		})
+	}
+}
+
+func runBenchmark(b *testing.B, policy, query string, data map[string]interface{}) {
+
+	ctx := context.Background()
+	pq := prepare(b, policy, query, data)
+
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		rs, err := pq.Eval(ctx)
+		if err != nil {
+			b.Fatal(err)
+		} else if len(rs) != 1 {
+			b.Fatalf("Unexpected result: %v", rs)
+		}
+	}
+}
+
+func prepare(tb testing.TB, policy, query string, data map[string]interface{}) rego.PreparedEvalQuery {
+
+	ctx := context.Background()
+	store := inmem.NewFromObject(data)
+	txn := storage.NewTransactionOrDie(ctx, store)
+
+	pq, err := rego.New(
+		rego.Query(query),
+		rego.Module("bench.rego", policy),
+		rego.Store(store),
+		rego.Transaction(txn),
+	).PrepareForEval(ctx)
+
+	if err != nil {
+		tb.Fatal(err)
+	}
+
+	return pq
+}
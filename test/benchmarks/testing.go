@@ -0,0 +1,118 @@
+// Copyright 2019 The OPA Authors.  All rights reserved.
+// Use of this source code is governed by an Apache2
+// license that can be found in the LICENSE file.
+
+// Package benchmarks contains benchmarks that exercise the evaluator on
+// workloads that have historically been sensitive to performance
+// regressions. The results are compared against a stored baseline by the
+// perf-regression target in the Makefile.
+package benchmarks
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// RefEnumerationPolicy enumerates all paths into a deeply nested document.
+const RefEnumerationPolicy = `package bench
+
+leaves[[i, j, k]] { data.tree[i][j][k] }`
+
+// UnificationPolicy unifies composite patterns against every element of a
+// collection.
+const UnificationPolicy = `package bench
+
+matches[[x, y]] {
+	[{"id": x, "attrs": {"kind": "a", "ref": r}}, y] = [data.items[_], r]
+}
+
+joined[[x, y]] {
+	data.items[i] = {"id": x, "attrs": a}
+	data.items[j] = {"id": y, "attrs": {"kind": a.kind, "ref": a.ref}}
+	i < j
+}`
+
+// LargeSetPolicy generates partial sets with many elements and queries
+// membership and intersections over them.
+const LargeSetPolicy = `package bench
+
+evens[x] { data.numbers[_] = x; x % 2 == 0 }
+
+squares[y] { data.numbers[_] = x; y = x * x }
+
+both = evens & squares
+
+size = count(both)`
+
+// BuiltinPolicy calls string, regex and aggregate built-in functions in the
+// same body.
+const BuiltinPolicy = `package bench
+
+names[n] {
+	data.users[_] = u
+	n = lower(concat("-", [u.first, u.last]))
+	startswith(n, "user")
+	re_match("^user-[0-9]+-.*$", n)
+	count(split(n, "-")) > 2
+	sprintf("%s/%d", [n, u.age]) = _
+}`
+
+// GenerateTree returns a document with n^3 leaves for RefEnumerationPolicy.
+func GenerateTree(n int) map[string]interface{} {
+	tree := make(map[string]interface{}, n)
+	for i := 0; i < n; i++ {
+		level1 := make(map[string]interface{}, n)
+		for j := 0; j < n; j++ {
+			level2 := make([]interface{}, n)
+			for k := range level2 {
+				level2[k] = true
+			}
+			level1[fmt.Sprintf("key%d", j)] = level2
+		}
+		tree[fmt.Sprintf("key%d", i)] = level1
+	}
+	return map[string]interface{}{"tree": tree}
+}
+
+// GenerateItems returns a document with n items for UnificationPolicy.
+// Every tenth item shares the same reference.
+func GenerateItems(n int) map[string]interface{} {
+	items := make([]interface{}, n)
+	for i := range items {
+		kind := "b"
+		if i%2 == 0 {
+			kind = "a"
+		}
+		items[i] = map[string]interface{}{
+			"id": fmt.Sprintf("item%d", i),
+			"attrs": map[string]interface{}{
+				"kind": kind,
+				"ref":  json.Number(fmt.Sprint(i % 10)),
+			},
+		}
+	}
+	return map[string]interface{}{"items": items}
+}
+
+// GenerateNumbers returns a document with the numbers 0..n-1 for
+// LargeSetPolicy.
+func GenerateNumbers(n int) map[string]interface{} {
+	numbers := make([]interface{}, n)
+	for i := range numbers {
+		numbers[i] = json.Number(fmt.Sprint(i))
+	}
+	return map[string]interface{}{"numbers": numbers}
+}
+
+// GenerateUsers returns a document with n users for BuiltinPolicy.
+func GenerateUsers(n int) map[string]interface{} {
+	users := make([]interface{}, n)
+	for i := range users {
+		users[i] = map[string]interface{}{
+			"first": "USER",
+			"last":  fmt.Sprintf("%d-%x", i, i),
+			"age":   json.Number(fmt.Sprint(20 + i%50)),
+		}
+	}
+	return map[string]interface{}{"users": users}
+}
@@ -9,12 +9,14 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"sort"
 	"strings"
 
 	"github.com/open-policy-agent/opa/topdown"
 
 	"github.com/open-policy-agent/opa/ast"
 	"github.com/open-policy-agent/opa/cover"
+	"github.com/open-policy-agent/opa/internal/junit"
 )
 
 // Reporter defines the interface for reporting test results.
@@ -29,6 +31,10 @@ type PrettyReporter struct {
 	Output      io.Writer
 	Verbose     bool
 	FailureLine bool
+
+	// Slowest is the number of slowest tests to list after the summary. If
+	// zero, no tests are listed.
+	Slowest int
 }
 
 // Report prints the test report to the reporter's output.
@@ -106,9 +112,35 @@ func (r PrettyReporter) Report(ch chan *Result) error {
 		fmt.Fprintln(r.Output, "ERROR:", fmt.Sprintf("%d/%d", errs, total))
 	}
 
+	if r.Slowest > 0 && len(results) > 0 {
+		r.slowest(results)
+	}
+
 	return nil
 }
 
+func (r PrettyReporter) slowest(results []*Result) {
+
+	sorted := make([]*Result, len(results))
+	copy(sorted, results)
+
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[i].Duration > sorted[j].Duration
+	})
+
+	if len(sorted) > r.Slowest {
+		sorted = sorted[:r.Slowest]
+	}
+
+	r.hl()
+	fmt.Fprintln(r.Output, "SLOWEST")
+	r.hl()
+
+	for _, tr := range sorted {
+		fmt.Fprintf(r.Output, "%v.%v: %v\n", tr.Package, tr.Name, tr.Duration)
+	}
+}
+
 func (r PrettyReporter) hl() {
 	fmt.Fprintln(r.Output, strings.Repeat("-", 80))
 }
@@ -133,6 +165,37 @@ func (r JSONReporter) Report(ch chan *Result) error {
 	return nil
 }
 
+// JUnitReporter reports test results in the JUnit XML format. Each package is
+// reported as a test suite.
+type JUnitReporter struct {
+	Output io.Writer
+}
+
+// Report prints the test report to the reporter's output.
+func (r JUnitReporter) Report(ch chan *Result) error {
+
+	report := &junit.Testsuites{Name: "opa"}
+
+	for tr := range ch {
+		tc := junit.NewTestcase(tr.Package, tr.Name, tr.Duration)
+		if tr.Location != nil {
+			tc.File = tr.Location.File
+			tc.Line = tr.Location.Row
+		}
+		if tr.Error != nil {
+			tc.Error = &junit.Result{Message: tr.Error.Error(), Text: tr.Error.Error()}
+		} else if tr.Fail {
+			tc.Failure = &junit.Result{Message: "test failed"}
+			if tr.FailedAt != nil && tr.FailedAt.Location != nil {
+				tc.Failure.Text = fmt.Sprintf("%v:%d: %v", tr.FailedAt.Location.File, tr.FailedAt.Location.Row, tr.FailedAt)
+			}
+		}
+		report.Suite(tr.Package).Add(tc)
+	}
+
+	return junit.Write(r.Output, report)
+}
+
 // JSONCoverageReporter reports coverage as a JSON structure.
 type JSONCoverageReporter struct {
 	Cover     *cover.Cover
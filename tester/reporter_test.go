@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"reflect"
 	"testing"
+	"time"
 
 	"github.com/open-policy-agent/opa/ast"
 	"github.com/open-policy-agent/opa/tester"
@@ -129,6 +130,100 @@ ERROR: 1/3
 	}
 }
 
+func TestPrettyReporterSlowest(t *testing.T) {
+	var buf bytes.Buffer
+
+	ts := []*tester.Result{
+		{
+			Package:  "data.foo.bar",
+			Name:     "test_baz",
+			Duration: 2 * time.Millisecond,
+		},
+		{
+			Package:  "data.foo.bar",
+			Name:     "test_qux",
+			Duration: 30 * time.Millisecond,
+		},
+		{
+			Package:  "data.foo.bar",
+			Name:     "test_corge",
+			Duration: 10 * time.Millisecond,
+		},
+	}
+
+	r := tester.PrettyReporter{
+		Output:  &buf,
+		Slowest: 2,
+	}
+	ch := resultsChan(ts)
+	if err := r.Report(ch); err != nil {
+		t.Fatal(err)
+	}
+
+	exp := `PASS: 3/3
+--------------------------------------------------------------------------------
+SLOWEST
+--------------------------------------------------------------------------------
+data.foo.bar.test_qux: 30ms
+data.foo.bar.test_corge: 10ms
+`
+
+	if exp != buf.String() {
+		t.Fatalf("Expected:\n\n%v\n\nGot:\n\n%v", exp, buf.String())
+	}
+}
+
+func TestJUnitReporter(t *testing.T) {
+	var buf bytes.Buffer
+
+	ts := []*tester.Result{
+		{
+			Location: &ast.Location{File: "policy.rego", Row: 10},
+			Package:  "data.foo.bar",
+			Name:     "test_baz",
+			Duration: 1500 * time.Millisecond,
+		},
+		{
+			Package: "data.foo.bar",
+			Name:    "test_qux",
+			Error:   fmt.Errorf("some err"),
+		},
+		{
+			Package: "data.foo.baz",
+			Name:    "test_corge",
+			Fail:    true,
+		},
+	}
+
+	r := tester.JUnitReporter{
+		Output: &buf,
+	}
+	ch := resultsChan(ts)
+	if err := r.Report(ch); err != nil {
+		t.Fatal(err)
+	}
+
+	exp := `<?xml version="1.0" encoding="UTF-8"?>
+<testsuites name="opa" tests="3" failures="1" errors="1" time="1.500">
+  <testsuite name="data.foo.bar" tests="2" failures="0" errors="1" time="1.500">
+    <testcase name="test_baz" classname="data.foo.bar" time="1.500" file="policy.rego" line="10"></testcase>
+    <testcase name="test_qux" classname="data.foo.bar" time="0.000">
+      <error message="some err">some err</error>
+    </testcase>
+  </testsuite>
+  <testsuite name="data.foo.baz" tests="1" failures="1" errors="0" time="0.000">
+    <testcase name="test_corge" classname="data.foo.baz" time="0.000">
+      <failure message="test failed"></failure>
+    </testcase>
+  </testsuite>
+</testsuites>
+`
+
+	if exp != buf.String() {
+		t.Fatalf("Expected:\n\n%v\n\nGot:\n\n%v", exp, buf.String())
+	}
+}
+
 func TestJSONReporter(t *testing.T) {
 	var buf bytes.Buffer
 	ts := []*tester.Result{
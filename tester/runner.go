@@ -8,6 +8,7 @@ package tester
 import (
 	"context"
 	"fmt"
+	"path/filepath"
 	"sort"
 	"strings"
 	"time"
@@ -27,6 +28,12 @@ import (
 // TestPrefix declares the prefix for all rules.
 const TestPrefix = "test_"
 
+// CasesPrefix declares the prefix for documents that contain the cases of
+// table-driven tests. A test function that takes one argument, e.g.,
+// test_add(tc), is run once for each case in the document with the same name
+// and this prefix instead of TestPrefix, e.g., cases_add.
+const CasesPrefix = "cases_"
+
 // Run executes all test cases found under files in path.
 func Run(ctx context.Context, paths ...string) ([]*Result, error) {
 	return RunWithFilter(ctx, nil, paths...)
@@ -100,6 +107,8 @@ type Runner struct {
 	runtime     *ast.Term
 	failureLine bool
 	timeout     time.Duration
+	parallel    int
+	filter      func(*ast.Rule) bool
 	modules     map[string]*ast.Module
 	bundles     map[string]*bundle.Bundle
 }
@@ -107,7 +116,8 @@ type Runner struct {
 // NewRunner returns a new runner.
 func NewRunner() *Runner {
 	return &Runner{
-		timeout: 5 * time.Second,
+		timeout:  5 * time.Second,
+		parallel: 1,
 	}
 }
 
@@ -160,6 +170,24 @@ func (r *Runner) SetTimeout(timout time.Duration) *Runner {
 	return r
 }
 
+// SetParallelism sets the number of tests that the runner evaluates
+// concurrently. Results are still reported in discovery order.
+func (r *Runner) SetParallelism(n int) *Runner {
+	if n < 1 {
+		n = 1
+	}
+	r.parallel = n
+	return r
+}
+
+// SetFilter sets a function that selects the tests to run. The function is
+// called with each discovered test rule after the modules have been compiled.
+// If no filter is set, all tests are run.
+func (r *Runner) SetFilter(f func(*ast.Rule) bool) *Runner {
+	r.filter = f
+	return r
+}
+
 // SetModules will add modules to the Runner which will be compiled then used
 // for discovering and evaluating tests.
 func (r *Runner) SetModules(modules map[string]*ast.Module) *Runner {
@@ -258,25 +286,54 @@ func (r *Runner) RunTests(ctx context.Context, txn storage.Transaction) (ch chan
 
 	sort.Strings(filenames)
 
+	var jobs []*testJob
+
+	for _, name := range filenames {
+		module := r.compiler.Modules[name]
+		for _, rule := range module.Rules {
+			if !strings.HasPrefix(string(rule.Head.Name), TestPrefix) {
+				continue
+			}
+			if r.filter == nil || r.filter(rule) {
+				jobs = append(jobs, &testJob{module: module, rule: rule, done: make(chan struct{})})
+			}
+		}
+	}
+
 	ch = make(chan *Result)
+	quit := make(chan struct{})
+	queue := make(chan *testJob)
+
+	go func() {
+		defer close(queue)
+		for _, job := range jobs {
+			select {
+			case queue <- job:
+			case <-quit:
+				return
+			}
+		}
+	}()
+
+	for i := 0; i < r.parallel; i++ {
+		go func() {
+			for job := range queue {
+				job.results, job.stop = r.runJob(ctx, txn, job)
+				close(job.done)
+			}
+		}()
+	}
 
 	go func() {
 		defer close(ch)
-		for _, name := range filenames {
-			module := r.compiler.Modules[name]
-			for _, rule := range module.Rules {
-				if !strings.HasPrefix(string(rule.Head.Name), TestPrefix) {
-					continue
-				}
-				tr, stop := func() (*Result, bool) {
-					runCtx, cancel := context.WithTimeout(ctx, r.timeout)
-					defer cancel()
-					return r.runTest(runCtx, txn, module, rule)
-				}()
+		defer close(quit)
+		for _, job := range jobs {
+			<-job.done
+			for _, tr := range job.results {
 				ch <- tr
-				if stop {
-					return
-				}
+			}
+			if job.stop {
+				return
 			}
 		}
 	}()
@@ -284,6 +341,82 @@ func (r *Runner) RunTests(ctx context.Context, txn storage.Transaction) (ch chan
 	return ch, nil
 }
 
+// DependsOn returns a filter for SetFilter that selects the tests that are
+// defined in one of the given files or that depend (directly or transitively)
+// on a rule defined in one of the given files. The dependencies are taken from
+// the rule graph of the compiler, so the filter must only be used once the
+// compiler has compiled the modules.
+func DependsOn(compiler *ast.Compiler, files []string) func(*ast.Rule) bool {
+
+	changed := make(map[string]struct{}, len(files))
+	for _, f := range files {
+		changed[filepath.Clean(f)] = struct{}{}
+	}
+
+	inFiles := func(rule *ast.Rule) bool {
+		if rule.Location == nil {
+			return false
+		}
+		_, ok := changed[filepath.Clean(rule.Location.File)]
+		return ok
+	}
+
+	return func(rule *ast.Rule) bool {
+
+		if inFiles(rule) {
+			return true
+		}
+
+		if compiler.Graph == nil {
+			return false
+		}
+
+		visited := map[*ast.Rule]struct{}{rule: {}}
+		queue := []*ast.Rule{rule}
+
+		for len(queue) > 0 {
+			next := queue[0]
+			queue = queue[1:]
+			for dep := range compiler.Graph.Dependencies(next) {
+				dep := dep.(*ast.Rule)
+				if _, ok := visited[dep]; ok {
+					continue
+				}
+				if inFiles(dep) {
+					return true
+				}
+				visited[dep] = struct{}{}
+				queue = append(queue, dep)
+			}
+		}
+
+		return false
+	}
+}
+
+// testJob represents a single test rule queued for evaluation. The results are
+// available once done has been closed.
+type testJob struct {
+	module  *ast.Module
+	rule    *ast.Rule
+	results []*Result
+	stop    bool
+	done    chan struct{}
+}
+
+func (r *Runner) runJob(ctx context.Context, txn storage.Transaction, job *testJob) ([]*Result, bool) {
+
+	if len(job.rule.Head.Args) == 1 {
+		return r.runTableTest(ctx, txn, job.module, job.rule)
+	}
+
+	runCtx, cancel := context.WithTimeout(ctx, r.timeout)
+	defer cancel()
+
+	tr, stop := r.runTest(runCtx, txn, job.module, job.rule)
+	return []*Result{tr}, stop
+}
+
 // rewriteDuplicateTestNames will rewrite duplicate test names to have a numbered suffix.
 // This uses a global "count" of each to ensure compiling more than once as new modules
 // are added can't introduce duplicates again.
@@ -305,7 +438,87 @@ func rewriteDuplicateTestNames(compiler *ast.Compiler) *ast.Error {
 	return nil
 }
 
+// runTableTest runs the test function once for each case in the corresponding
+// cases document and sends a result for every case. Cases are named by their
+// keys (if the document is an object), indices (if the document is an array),
+// or values (if the document is a set). If the cases document is undefined or
+// not a collection, an error result is sent instead.
+func (r *Runner) runTableTest(ctx context.Context, txn storage.Transaction, mod *ast.Module, rule *ast.Rule) ([]*Result, bool) {
+
+	name := string(rule.Head.Name)
+	if i := strings.Index(name, "#"); i != -1 {
+		name = name[:i]
+	}
+
+	casesRef := mod.Package.Path.Append(ast.StringTerm(CasesPrefix + strings.TrimPrefix(name, TestPrefix)))
+
+	keys, values, err := func() ([]*ast.Term, []*ast.Term, error) {
+		runCtx, cancel := context.WithTimeout(ctx, r.timeout)
+		defer cancel()
+		key, value := ast.VarTerm("key"), ast.VarTerm("value")
+		rs, err := rego.New(
+			rego.Store(r.store),
+			rego.Transaction(txn),
+			rego.Compiler(r.compiler),
+			rego.ParsedQuery(ast.NewBody(ast.Equality.Expr(ast.NewTerm(casesRef.Append(key)), value))),
+			rego.Runtime(r.runtime),
+		).Eval(runCtx)
+		if err != nil {
+			return nil, nil, err
+		} else if len(rs) == 0 {
+			return nil, nil, fmt.Errorf("%v: no cases defined in %v", name, casesRef)
+		}
+		var keys, values []*ast.Term
+		for i := range rs {
+			k, err := ast.InterfaceToValue(rs[i].Bindings["key"])
+			if err != nil {
+				return nil, nil, err
+			}
+			v, err := ast.InterfaceToValue(rs[i].Bindings["value"])
+			if err != nil {
+				return nil, nil, err
+			}
+			keys = append(keys, ast.NewTerm(k))
+			values = append(values, ast.NewTerm(v))
+		}
+		return keys, values, nil
+	}()
+
+	if err != nil {
+		tr := newResult(rule.Loc(), mod.Package.Path.String(), string(rule.Head.Name), 0, nil)
+		tr.Error = err
+		return []*Result{tr}, topdown.IsCancel(err) && ctx.Err() != nil
+	}
+
+	var results []*Result
+
+	for i := range keys {
+		tr, stop := func() (*Result, bool) {
+			runCtx, cancel := context.WithTimeout(ctx, r.timeout)
+			defer cancel()
+			return r.runTestCase(runCtx, txn, mod, rule, keys[i], values[i])
+		}()
+		results = append(results, tr)
+		if stop {
+			return results, true
+		}
+	}
+
+	return results, false
+}
+
+func (r *Runner) runTestCase(ctx context.Context, txn storage.Transaction, mod *ast.Module, rule *ast.Rule, key, value *ast.Term) (*Result, bool) {
+	query := ast.NewBody(ast.NewExpr([]*ast.Term{ast.NewTerm(rule.Path()), value, ast.VarTerm("result")}))
+	name := fmt.Sprintf("%v[%v]", rule.Head.Name, key)
+	return r.runQuery(ctx, txn, mod, rule, name, query)
+}
+
 func (r *Runner) runTest(ctx context.Context, txn storage.Transaction, mod *ast.Module, rule *ast.Rule) (*Result, bool) {
+	query := ast.NewBody(ast.NewExpr(ast.NewTerm(rule.Path())))
+	return r.runQuery(ctx, txn, mod, rule, string(rule.Head.Name), query)
+}
+
+func (r *Runner) runQuery(ctx context.Context, txn storage.Transaction, mod *ast.Module, rule *ast.Rule, name string, query ast.Body) (*Result, bool) {
 
 	var bufferTracer *topdown.BufferTracer
 	var bufFailureLineTracer *topdown.BufferTracer
@@ -325,7 +538,7 @@ func (r *Runner) runTest(ctx context.Context, txn storage.Transaction, mod *ast.
 		rego.Store(r.store),
 		rego.Transaction(txn),
 		rego.Compiler(r.compiler),
-		rego.Query(rule.Path().String()),
+		rego.ParsedQuery(query),
 		rego.Tracer(tracer),
 		rego.Runtime(r.runtime),
 	)
@@ -340,7 +553,7 @@ func (r *Runner) runTest(ctx context.Context, txn storage.Transaction, mod *ast.
 		trace = *bufferTracer
 	}
 
-	tr := newResult(rule.Loc(), mod.Package.Path.String(), string(rule.Head.Name), dt, trace)
+	tr := newResult(rule.Loc(), mod.Package.Path.String(), name, dt, trace)
 	var stop bool
 
 	if err != nil {
@@ -355,6 +568,8 @@ func (r *Runner) runTest(ctx context.Context, txn storage.Transaction, mod *ast.
 		}
 	} else if b, ok := rs[0].Expressions[0].Value.(bool); !ok || !b {
 		tr.Fail = true
+	} else if result, ok := rs[0].Bindings["result"]; ok && result != true {
+		tr.Fail = true
 	}
 
 	return tr, stop
@@ -397,5 +612,9 @@ func LoadBundles(args []string, filter loader.Filter) (map[string]*bundle.Bundle
 		bundles[bundleDir] = b
 	}
 
+	if err := bundle.CheckRootsOverlap(bundles); err != nil {
+		return nil, err
+	}
+
 	return bundles, nil
 }
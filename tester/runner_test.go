@@ -152,10 +152,77 @@ func TestRun(t *testing.T) {
 	})
 }
 
+func TestRunTableTests(t *testing.T) {
+
+	ctx := context.Background()
+
+	files := map[string]string{
+		"/a_test.rego": `package foo
+
+			cases_add = {
+				"small": {"a": 1, "b": 2, "want": 3},
+				"wrong": {"a": 1, "b": 1, "want": 3},
+			}
+
+			test_add(tc) { tc.a + tc.b == tc.want }
+
+			cases_positive = [1, -1]
+
+			test_positive(x) { x > 0 }
+
+			cases_names = {"alice"}
+
+			test_names(x) = x
+
+			cases_bad = 7
+
+			test_bad(x) { true }
+
+			test_missing(x) { true }
+			`,
+	}
+
+	tests := map[string]struct {
+		wantErr  bool
+		wantFail bool
+	}{
+		`test_add["small"]`:   {false, false},
+		`test_add["wrong"]`:   {false, true},
+		`test_positive[0]`:    {false, false},
+		`test_positive[1]`:    {false, true},
+		`test_names["alice"]`: {false, true},
+		`test_bad`:            {true, false},
+		`test_missing`:        {true, false},
+	}
+
+	test.WithTempFS(files, func(d string) {
+
+		rs, err := tester.Run(ctx, d)
+		if err != nil {
+			t.Fatal(err)
+		}
+		seen := map[string]struct{}{}
+		for i := range rs {
+			seen[rs[i].Name] = struct{}{}
+			exp, ok := tests[rs[i].Name]
+			if !ok {
+				t.Errorf("Unexpected result for %v", rs[i].Name)
+			} else if exp.wantErr != (rs[i].Error != nil) || exp.wantFail != rs[i].Fail {
+				t.Errorf("Expected %v for %v but got: %v", exp, rs[i].Name, rs[i])
+			}
+		}
+		for k := range tests {
+			if _, ok := seen[k]; !ok {
+				t.Errorf("Expected result for %v", k)
+			}
+		}
+	})
+}
+
 func TestRunnerCancel(t *testing.T) {
 
 	registerSleepBuiltin()
-	
+
 	ctx, cancel := context.WithCancel(context.Background())
 	cancel()
 
@@ -219,6 +286,116 @@ func TestRunner_Timeout(t *testing.T) {
 	})
 }
 
+func TestRunner_Parallel(t *testing.T) {
+
+	registerSleepBuiltin()
+
+	ctx := context.Background()
+
+	files := map[string]string{
+		"/a_test.rego": `package foo
+
+		test_1 { test.sleep("50ms") }
+		test_2 { test.sleep("50ms") }
+		test_3 { test.sleep("50ms") }
+		test_4 { false }`,
+		"/b_test.rego": `package bar
+
+		test_5 { test.sleep("50ms") }
+		test_6 { true }`,
+	}
+
+	test.WithTempFS(files, func(d string) {
+		modules, store, err := tester.Load([]string{d}, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		t0 := time.Now()
+		ch, err := tester.NewRunner().SetParallelism(4).SetStore(store).Run(ctx, modules)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var names []string
+		for r := range ch {
+			if r.Error != nil {
+				t.Fatalf("Unexpected error for %v: %v", r.Name, r.Error)
+			}
+			if r.Fail != (r.Name == "test_4") {
+				t.Errorf("Unexpected result for %v: %v", r.Name, r)
+			}
+			names = append(names, r.Name)
+		}
+		if dt := time.Since(t0); dt >= 200*time.Millisecond {
+			t.Errorf("Expected tests to run concurrently but took %v", dt)
+		}
+		exp := []string{"test_1", "test_2", "test_3", "test_4", "test_5", "test_6"}
+		if len(names) != len(exp) {
+			t.Fatalf("Expected %v but got %v", exp, names)
+		}
+		for i := range exp {
+			if names[i] != exp[i] {
+				t.Fatalf("Expected %v but got %v", exp, names)
+			}
+		}
+	})
+}
+
+func TestRunner_DependsOn(t *testing.T) {
+
+	ctx := context.Background()
+
+	modules := map[string]*ast.Module{
+		"a.rego":      ast.MustParseModule("package a\n\np { data.b.q }"),
+		"b.rego":      ast.MustParseModule("package b\n\nq { true }"),
+		"c.rego":      ast.MustParseModule("package c\n\nr { true }"),
+		"a_test.rego": ast.MustParseModule("package a\n\ntest_p { p }"),
+		"c_test.rego": ast.MustParseModule("package c\n\ntest_r { r }\ntest_s { true }"),
+	}
+
+	for name, mod := range modules {
+		for _, rule := range mod.Rules {
+			rule.Location.File = name
+		}
+	}
+
+	tests := []struct {
+		note    string
+		changed []string
+		exp     []string
+	}{
+		{"none", []string{}, nil},
+		{"transitive", []string{"b.rego"}, []string{"test_p"}},
+		{"direct", []string{"c.rego"}, []string{"test_r"}},
+		{"test file", []string{"c_test.rego"}, []string{"test_r", "test_s"}},
+		{"multiple", []string{"a.rego", "c.rego"}, []string{"test_p", "test_r"}},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.note, func(t *testing.T) {
+			compiler := ast.NewCompiler()
+			ch, err := tester.NewRunner().
+				SetCompiler(compiler).
+				SetFilter(tester.DependsOn(compiler, tc.changed)).
+				Run(ctx, modules)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var names []string
+			for r := range ch {
+				names = append(names, r.Name)
+			}
+			if len(names) != len(tc.exp) {
+				t.Fatalf("Expected %v but got %v", tc.exp, names)
+			}
+			for i := range tc.exp {
+				if names[i] != tc.exp[i] {
+					t.Fatalf("Expected %v but got %v", tc.exp, names)
+				}
+			}
+		})
+	}
+}
+
 func registerSleepBuiltin() {
 	ast.RegisterBuiltin(&ast.Builtin{
 		Name: "test.sleep",
@@ -233,4 +410,4 @@ func registerSleepBuiltin() {
 		time.Sleep(d)
 		return ast.Null{}, nil
 	})
-}
\ No newline at end of file
+}
@@ -6,6 +6,7 @@ package topdown
 
 import (
 	"math/big"
+	"unicode/utf8"
 
 	"github.com/open-policy-agent/opa/ast"
 	"github.com/open-policy-agent/opa/topdown/builtins"
@@ -20,7 +21,7 @@ func builtinCount(a ast.Value) (ast.Value, error) {
 	case ast.Set:
 		return ast.IntNumberTerm(a.Len()).Value, nil
 	case ast.String:
-		return ast.IntNumberTerm(len(a)).Value, nil
+		return ast.IntNumberTerm(utf8.RuneCountInString(string(a))).Value, nil
 	}
 	return nil, builtins.NewOperandTypeErr(1, a, "array", "object", "set")
 }
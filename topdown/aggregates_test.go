@@ -16,6 +16,7 @@ func TestTopDownAggregates(t *testing.T) {
 		{"count keys", []string{`p[x] { count(b, x) }`}, "[2]"},
 		{"count keys virtual", []string{`p[x] { count([k | q[k] = _], x) }`, `q[k] = v { b[k] = v }`}, "[2]"},
 		{"count set", []string{`p = x { count(q, x) }`, `q[x] { x = a[_] }`}, "4"},
+		{"count string", []string{`p = x { count("héllo, 世界", x) }`}, "9"},
 		{"sum", []string{`p[x] { sum([1, 2, 3, 4], x) }`}, "[10]"},
 		{"sum set", []string{`p = x { sum({1, 2, 3, 4}, x) }`}, "10"},
 		{"sum virtual", []string{`p[x] { sum([y | q[y]], x) }`, `q[x] { a[_] = x }`}, "[10]"},
@@ -34,11 +34,12 @@ type bindings struct {
 	id     uint64
 	values bindingsArrayHashmap
 	instr  *Instrumentation
+	plugs  *plugCache
 }
 
-func newBindings(id uint64, instr *Instrumentation) *bindings {
+func newBindings(id uint64, instr *Instrumentation, plugs *plugCache) *bindings {
 	values := newBindingsArrayHashmap()
-	return &bindings{id, values, instr}
+	return &bindings{id, values, instr, plugs}
 }
 
 func (u *bindings) Iter(caller *bindings, iter func(*ast.Term, *ast.Term) error) error {
@@ -71,7 +72,7 @@ func (u *bindings) Plug(a *ast.Term) *ast.Term {
 func (u *bindings) PlugNamespaced(a *ast.Term, caller *bindings) *ast.Term {
 	if u != nil {
 		u.instr.startTimer(evalOpPlug)
-		t := u.plugNamespaced(a, caller)
+		t := u.plugCached(a, caller)
 		u.instr.stopTimer(evalOpPlug)
 		return t
 	}
@@ -79,6 +80,27 @@ func (u *bindings) PlugNamespaced(a *ast.Term, caller *bindings) *ast.Term {
 	return u.plugNamespaced(a, caller)
 }
 
+// plugCached returns the plugged value of a. Composite values are looked up in
+// the plug cache first because plugging them requires a copy.
+func (u *bindings) plugCached(a *ast.Term, caller *bindings) *ast.Term {
+	if u.plugs == nil {
+		return u.plugNamespaced(a, caller)
+	}
+	switch a.Value.(type) {
+	case ast.Array, ast.Object, ast.Set, ast.Ref:
+	default:
+		return u.plugNamespaced(a, caller)
+	}
+	if t := u.plugs.Get(a, u, caller); t != nil {
+		u.instr.counterIncr(evalOpPlugCacheHit)
+		return t
+	}
+	u.instr.counterIncr(evalOpPlugCacheMiss)
+	t := u.plugNamespaced(a, caller)
+	u.plugs.Put(a, u, caller, t)
+	return t
+}
+
 func (u *bindings) plugNamespaced(a *ast.Term, caller *bindings) *ast.Term {
 	switch v := a.Value.(type) {
 	case ast.Var:
@@ -123,6 +145,7 @@ func (u *bindings) plugNamespaced(a *ast.Term, caller *bindings) *ast.Term {
 }
 
 func (u *bindings) bind(a *ast.Term, b *ast.Term, other *bindings) *undo {
+	u.plugs.Invalidate()
 	u.values.Put(a, value{
 		u: other,
 		v: b,
@@ -147,6 +170,7 @@ func (u *bindings) apply(a *ast.Term) (*ast.Term, *bindings) {
 }
 
 func (u *bindings) delete(v *ast.Term) {
+	u.plugs.Invalidate()
 	u.values.Delete(v)
 }
 
@@ -7,8 +7,10 @@ package topdown
 import (
 	"context"
 	"fmt"
+	"io"
 
 	"github.com/open-policy-agent/opa/ast"
+	"github.com/open-policy-agent/opa/storage"
 	"github.com/open-policy-agent/opa/topdown/builtins"
 )
 
@@ -28,14 +30,19 @@ type (
 	// BuiltinContext contains context from the evaluator that may be used by
 	// built-in functions.
 	BuiltinContext struct {
-		Context  context.Context // request context that was passed when query started
-		Cancel   Cancel          // atomic value that signals evaluation to halt
-		Runtime  *ast.Term       // runtime information on the OPA instance
-		Cache    builtins.Cache  // built-in function state cache
-		Location *ast.Location   // location of built-in call
-		Tracers  []Tracer        // tracer objects for trace() built-in function
-		QueryID  uint64          // identifies query being evaluated
-		ParentID uint64          // identifies parent of query being evaluated
+		Context         context.Context           // request context that was passed when query started
+		Cancel          Cancel                    // atomic value that signals evaluation to halt
+		Runtime         *ast.Term                 // runtime information on the OPA instance
+		Store           storage.Store             // store that the query is evaluated against
+		Txn             storage.Transaction       // transaction that the query is evaluated with
+		Time            *ast.Term                 // time to return from time.now_ns (nil means current time)
+		Seed            io.Reader                 // source of randomness for random built-in functions
+		Cache           builtins.Cache            // built-in function state cache
+		InterQueryCache *builtins.InterQueryCache // built-in function state shared across queries
+		Location        *ast.Location             // location of built-in call
+		Tracers         []Tracer                  // tracer objects for trace() built-in function
+		QueryID         uint64                    // identifies query being evaluated
+		ParentID        uint64                    // identifies parent of query being evaluated
 	}
 
 	// BuiltinFunc defines an interface for implementing built-in functions.
@@ -46,6 +53,17 @@ type (
 	BuiltinFunc func(bctx BuiltinContext, operands []*ast.Term, iter func(*ast.Term) error) error
 )
 
+// defaultInterQueryCache is used by built-in functions when the query was not
+// configured with an inter-query cache.
+var defaultInterQueryCache = builtins.NewInterQueryCache(0)
+
+func interQueryCache(bctx BuiltinContext) *builtins.InterQueryCache {
+	if bctx.InterQueryCache != nil {
+		return bctx.InterQueryCache
+	}
+	return defaultInterQueryCache
+}
+
 // RegisterBuiltinFunc adds a new built-in function to the evaluation engine.
 func RegisterBuiltinFunc(name string, f BuiltinFunc) {
 	builtinFunctions[name] = f
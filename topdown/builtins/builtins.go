@@ -6,9 +6,11 @@
 package builtins
 
 import (
+	"container/list"
 	"fmt"
 	"math/big"
 	"strings"
+	"sync"
 
 	"github.com/open-policy-agent/opa/ast"
 )
@@ -28,6 +30,89 @@ func (c Cache) Get(k interface{}) (interface{}, bool) {
 	return v, ok
 }
 
+// DefaultInterQueryCacheMaxEntries is the default number of entries kept in an
+// InterQueryCache.
+const DefaultInterQueryCacheMaxEntries = 10000
+
+// InterQueryCache defines a cache that is shared by queries evaluated by the
+// same engine (e.g., compiled regular expressions and parsed JWT keys.) Unlike
+// Cache, values in the InterQueryCache outlive the query that inserted them so
+// they must not depend on query state. When the cache is full, the least
+// recently used entry is evicted. The keys must be comparable and should not be
+// of type string. The InterQueryCache is safe for concurrent use and a nil
+// InterQueryCache does not store any values.
+type InterQueryCache struct {
+	mtx        sync.Mutex
+	maxEntries int
+	entries    map[interface{}]*list.Element
+	lru        *list.List
+}
+
+type interQueryCacheEntry struct {
+	key   interface{}
+	value interface{}
+}
+
+// NewInterQueryCache returns a new InterQueryCache that holds at most
+// maxEntries values. If maxEntries is zero or negative,
+// DefaultInterQueryCacheMaxEntries is used.
+func NewInterQueryCache(maxEntries int) *InterQueryCache {
+	if maxEntries <= 0 {
+		maxEntries = DefaultInterQueryCacheMaxEntries
+	}
+	return &InterQueryCache{
+		maxEntries: maxEntries,
+		entries:    map[interface{}]*list.Element{},
+		lru:        list.New(),
+	}
+}
+
+// Put inserts v into the cache under k, evicting the least recently used
+// entry if the cache is full.
+func (c *InterQueryCache) Put(k, v interface{}) {
+	if c == nil {
+		return
+	}
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+	if elem, ok := c.entries[k]; ok {
+		elem.Value.(*interQueryCacheEntry).value = v
+		c.lru.MoveToFront(elem)
+		return
+	}
+	c.entries[k] = c.lru.PushFront(&interQueryCacheEntry{key: k, value: v})
+	for c.lru.Len() > c.maxEntries {
+		oldest := c.lru.Back()
+		c.lru.Remove(oldest)
+		delete(c.entries, oldest.Value.(*interQueryCacheEntry).key)
+	}
+}
+
+// Get returns the cached value for k.
+func (c *InterQueryCache) Get(k interface{}) (interface{}, bool) {
+	if c == nil {
+		return nil, false
+	}
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+	elem, ok := c.entries[k]
+	if !ok {
+		return nil, false
+	}
+	c.lru.MoveToFront(elem)
+	return elem.Value.(*interQueryCacheEntry).value, true
+}
+
+// Len returns the number of values in the cache.
+func (c *InterQueryCache) Len() int {
+	if c == nil {
+		return 0
+	}
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+	return c.lru.Len()
+}
+
 // ErrOperand represents an invalid operand has been passed to a built-in
 // function. Built-ins should return ErrOperand to indicate a type error has
 // occurred.
@@ -0,0 +1,51 @@
+// Copyright 2019 The OPA Authors.  All rights reserved.
+// Use of this source code is governed by an Apache2
+// license that can be found in the LICENSE file.
+
+package builtins
+
+import "testing"
+
+func TestInterQueryCacheEviction(t *testing.T) {
+
+	c := NewInterQueryCache(2)
+
+	c.Put(1, "a")
+	c.Put(2, "b")
+
+	// Touch 1 so that 2 becomes the least recently used entry.
+	if v, ok := c.Get(1); !ok || v != "a" {
+		t.Fatalf("Expected a but got %v (found: %v)", v, ok)
+	}
+
+	c.Put(3, "c")
+
+	if c.Len() != 2 {
+		t.Fatalf("Expected 2 entries but got %d", c.Len())
+	}
+
+	if _, ok := c.Get(2); ok {
+		t.Fatal("Expected 2 to be evicted")
+	}
+
+	c.Put(1, "x")
+
+	if v, ok := c.Get(1); !ok || v != "x" {
+		t.Fatalf("Expected x but got %v (found: %v)", v, ok)
+	}
+
+	if v, ok := c.Get(3); !ok || v != "c" {
+		t.Fatalf("Expected c but got %v (found: %v)", v, ok)
+	}
+}
+
+func TestInterQueryCacheNil(t *testing.T) {
+
+	var c *InterQueryCache
+
+	c.Put(1, "a")
+
+	if _, ok := c.Get(1); ok || c.Len() != 0 {
+		t.Fatal("Expected nil cache to be empty")
+	}
+}
@@ -5,6 +5,8 @@
 package topdown
 
 import (
+	"container/list"
+
 	"github.com/open-policy-agent/opa/ast"
 	"github.com/open-policy-agent/opa/util"
 )
@@ -132,6 +134,82 @@ func (e *baseCacheElem) set(value ast.Value) {
 	e.children = map[ast.Value]*baseCacheElem{}
 }
 
+// defaultPlugCacheSize is the number of plugged terms kept by the plug cache.
+const defaultPlugCacheSize = 128
+
+// plugCache implements an LRU cache of plugged composite terms. Entries are
+// keyed by the term and the bindings used to plug it. Since plugged values
+// depend on the contents of every bindings list in the evaluation, the cache
+// is shared by all bindings lists of a query and it is invalidated whenever a
+// variable is bound or unbound (e.g., on backtracking.)
+type plugCache struct {
+	version uint64
+	size    int
+	lru     *list.List
+	entries map[plugCacheKey]*list.Element
+}
+
+type plugCacheKey struct {
+	term   *ast.Term
+	u      *bindings
+	caller *bindings
+}
+
+type plugCacheElem struct {
+	key     plugCacheKey
+	version uint64
+	value   *ast.Term
+}
+
+func newPlugCache(size int) *plugCache {
+	return &plugCache{
+		size:    size,
+		lru:     list.New(),
+		entries: make(map[plugCacheKey]*list.Element, size),
+	}
+}
+
+// Invalidate discards all entries currently in the cache.
+func (c *plugCache) Invalidate() {
+	if c != nil {
+		c.version++
+	}
+}
+
+func (c *plugCache) Get(term *ast.Term, u, caller *bindings) *ast.Term {
+	elem, ok := c.entries[plugCacheKey{term, u, caller}]
+	if !ok {
+		return nil
+	}
+	entry := elem.Value.(*plugCacheElem)
+	if entry.version != c.version {
+		return nil
+	}
+	c.lru.MoveToFront(elem)
+	return entry.value
+}
+
+func (c *plugCache) Put(term *ast.Term, u, caller *bindings, value *ast.Term) {
+	key := plugCacheKey{term, u, caller}
+	if elem, ok := c.entries[key]; ok {
+		entry := elem.Value.(*plugCacheElem)
+		entry.version = c.version
+		entry.value = value
+		c.lru.MoveToFront(elem)
+		return
+	}
+	if c.lru.Len() >= c.size {
+		oldest := c.lru.Back()
+		delete(c.entries, oldest.Value.(*plugCacheElem).key)
+		c.lru.Remove(oldest)
+	}
+	c.entries[key] = c.lru.PushFront(&plugCacheElem{
+		key:     key,
+		version: c.version,
+		value:   value,
+	})
+}
+
 type refStack struct {
 	sl []refStackElem
 }
@@ -164,3 +242,37 @@ func (s *refStack) Prefixed(ref ast.Ref) bool {
 	}
 	return false
 }
+
+type functionMocksStack struct {
+	sl []functionMocksElem
+}
+
+type functionMocksElem [][2]*ast.Term
+
+func newFunctionMocksStack() *functionMocksStack {
+	return &functionMocksStack{}
+}
+
+func (s *functionMocksStack) Push(mocks [][2]*ast.Term) {
+	s.sl = append(s.sl, functionMocksElem(mocks))
+}
+
+func (s *functionMocksStack) Pop() {
+	s.sl = s.sl[:len(s.sl)-1]
+}
+
+// Get returns the replacement for the function referred to by ref. The most
+// recently pushed replacement takes precedence. A nil replacement disables the
+// replacements pushed before it.
+func (s *functionMocksStack) Get(ref ast.Ref) (*ast.Term, bool) {
+	if s != nil {
+		for i := len(s.sl) - 1; i >= 0; i-- {
+			for j := range s.sl[i] {
+				if s.sl[i][j][0].Value.Compare(ref) == 0 {
+					return s.sl[i][j][1], s.sl[i][j][1] != nil
+				}
+			}
+		}
+	}
+	return nil, false
+}
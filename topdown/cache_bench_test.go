@@ -46,3 +46,18 @@ func BenchmarkVirtualCache(b *testing.B) {
 	}
 
 }
+
+func BenchmarkPlugCache(b *testing.B) {
+
+	cache := newPlugCache(defaultPlugCacheSize)
+	u := newBindings(0, nil, cache)
+	x := ast.VarTerm("x")
+	u.bind(x, ast.MustParseTerm(`{"a": [1, 2, 3], "b": {"c": "d"}}`), u)
+	term := ast.ArrayTerm(x, ast.ArrayTerm(x, x), ast.SetTerm(ast.StringTerm("e")))
+
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		u.Plug(term)
+	}
+}
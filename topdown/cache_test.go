@@ -39,3 +39,47 @@ func TestBaseCacheGetExactMatch(t *testing.T) {
 		t.Fatalf("Expected bar but got %v", result)
 	}
 }
+
+func TestPlugCacheInvalidate(t *testing.T) {
+	cache := newPlugCache(defaultPlugCacheSize)
+	b := newBindings(0, nil, cache)
+	x := ast.VarTerm("x")
+	term := ast.ArrayTerm(x)
+
+	undo := b.bind(x, ast.IntNumberTerm(1), b)
+	result := b.Plug(term)
+	if !result.Equal(ast.ArrayTerm(ast.IntNumberTerm(1))) {
+		t.Fatalf("Expected [1] but got %v", result)
+	}
+
+	if b.Plug(term) != result {
+		t.Fatal("Expected cached result")
+	}
+
+	undo.Undo()
+	b.bind(x, ast.IntNumberTerm(2), b)
+	result = b.Plug(term)
+	if !result.Equal(ast.ArrayTerm(ast.IntNumberTerm(2))) {
+		t.Fatalf("Expected [2] but got %v", result)
+	}
+}
+
+func TestPlugCacheEvict(t *testing.T) {
+	cache := newPlugCache(2)
+	b := newBindings(0, nil, cache)
+	terms := []*ast.Term{ast.ArrayTerm(), ast.ArrayTerm(), ast.ArrayTerm()}
+
+	for _, term := range terms {
+		cache.Put(term, b, nil, term)
+	}
+
+	if cache.Get(terms[0], b, nil) != nil {
+		t.Fatal("Expected least recently used entry to be evicted")
+	}
+
+	for _, term := range terms[1:] {
+		if cache.Get(term, b, nil) != term {
+			t.Fatalf("Expected %v to be cached", term)
+		}
+	}
+}
@@ -5,23 +5,33 @@
 package topdown
 
 import (
+	"crypto/hmac"
+	"crypto/md5"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/sha512"
 	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
 	"encoding/json"
 	"fmt"
+	"hash"
 	"io/ioutil"
+	"strings"
 
 	"github.com/open-policy-agent/opa/ast"
+	"github.com/open-policy-agent/opa/topdown/builtins"
 	"github.com/open-policy-agent/opa/util"
 )
 
 func builtinCryptoX509ParseCertificates(a ast.Value) (ast.Value, error) {
 
-	str, err := builtinBase64Decode(a)
+	der, err := x509Operand(a)
 	if err != nil {
 		return nil, err
 	}
 
-	certs, err := x509.ParseCertificates([]byte(str.(ast.String)))
+	certs, err := x509.ParseCertificates(der)
 	if err != nil {
 		return nil, err
 	}
@@ -40,8 +50,141 @@ func builtinCryptoX509ParseCertificates(a ast.Value) (ast.Value, error) {
 	return ast.InterfaceToValue(x)
 }
 
+func builtinCryptoX509ParseCertificateRequest(a ast.Value) (ast.Value, error) {
+
+	der, err := x509Operand(a)
+	if err != nil {
+		return nil, err
+	}
+
+	csr, err := x509.ParseCertificateRequest(der)
+	if err != nil {
+		return nil, err
+	}
+
+	bs, err := json.Marshal(csr)
+	if err != nil {
+		return nil, err
+	}
+
+	var x interface{}
+
+	if err := util.UnmarshalJSON(bs, &x); err != nil {
+		return nil, err
+	}
+
+	return ast.InterfaceToValue(x)
+}
+
+// x509Operand returns the DER encoded contents of a. The operand may either
+// contain PEM blocks or base64 encoded DER. The contents of multiple PEM blocks
+// are concatenated.
+func x509Operand(a ast.Value) ([]byte, error) {
+
+	str, err := builtins.StringOperand(a, 1)
+	if err != nil {
+		return nil, err
+	}
+
+	if strings.HasPrefix(strings.TrimSpace(string(str)), "-----BEGIN") {
+		var der []byte
+		for _, block := range decodePEMBlocks([]byte(str)) {
+			der = append(der, block.Bytes...)
+		}
+		if len(der) == 0 {
+			return nil, fmt.Errorf("no PEM blocks found")
+		}
+		return der, nil
+	}
+
+	return base64.StdEncoding.DecodeString(string(str))
+}
+
+func builtinCryptoMd5(a ast.Value) (ast.Value, error) {
+	return hashHelper(a, md5.New)
+}
+
+func builtinCryptoSha1(a ast.Value) (ast.Value, error) {
+	return hashHelper(a, sha1.New)
+}
+
+func builtinCryptoSha256(a ast.Value) (ast.Value, error) {
+	return hashHelper(a, sha256.New)
+}
+
+func builtinCryptoHmacMd5(a, b ast.Value) (ast.Value, error) {
+	return hmacHelper(a, b, md5.New)
+}
+
+func builtinCryptoHmacSha1(a, b ast.Value) (ast.Value, error) {
+	return hmacHelper(a, b, sha1.New)
+}
+
+func builtinCryptoHmacSha256(a, b ast.Value) (ast.Value, error) {
+	return hmacHelper(a, b, sha256.New)
+}
+
+func builtinCryptoHmacSha512(a, b ast.Value) (ast.Value, error) {
+	return hmacHelper(a, b, sha512.New)
+}
+
+// builtinCryptoHmacEqual compares two MACs in constant time so that policies
+// verifying signatures do not leak timing information.
+func builtinCryptoHmacEqual(a, b ast.Value) (ast.Value, error) {
+
+	mac1, err := builtins.StringOperand(a, 1)
+	if err != nil {
+		return nil, err
+	}
+
+	mac2, err := builtins.StringOperand(b, 2)
+	if err != nil {
+		return nil, err
+	}
+
+	return ast.Boolean(hmac.Equal([]byte(mac1), []byte(mac2))), nil
+}
+
+func hashHelper(a ast.Value, h func() hash.Hash) (ast.Value, error) {
+
+	s, err := builtins.StringOperand(a, 1)
+	if err != nil {
+		return nil, err
+	}
+
+	digest := h()
+	digest.Write([]byte(s))
+	return ast.String(hex.EncodeToString(digest.Sum(nil))), nil
+}
+
+func hmacHelper(a, b ast.Value, h func() hash.Hash) (ast.Value, error) {
+
+	message, err := builtins.StringOperand(a, 1)
+	if err != nil {
+		return nil, err
+	}
+
+	key, err := builtins.StringOperand(b, 2)
+	if err != nil {
+		return nil, err
+	}
+
+	mac := hmac.New(h, []byte(key))
+	mac.Write([]byte(message))
+	return ast.String(hex.EncodeToString(mac.Sum(nil))), nil
+}
+
 func init() {
 	RegisterFunctionalBuiltin1(ast.CryptoX509ParseCertificates.Name, builtinCryptoX509ParseCertificates)
+	RegisterFunctionalBuiltin1(ast.CryptoX509ParseCertificateRequest.Name, builtinCryptoX509ParseCertificateRequest)
+	RegisterFunctionalBuiltin1(ast.CryptoMd5.Name, builtinCryptoMd5)
+	RegisterFunctionalBuiltin1(ast.CryptoSha1.Name, builtinCryptoSha1)
+	RegisterFunctionalBuiltin1(ast.CryptoSha256.Name, builtinCryptoSha256)
+	RegisterFunctionalBuiltin2(ast.CryptoHmacMd5.Name, builtinCryptoHmacMd5)
+	RegisterFunctionalBuiltin2(ast.CryptoHmacSha1.Name, builtinCryptoHmacSha1)
+	RegisterFunctionalBuiltin2(ast.CryptoHmacSha256.Name, builtinCryptoHmacSha256)
+	RegisterFunctionalBuiltin2(ast.CryptoHmacSha512.Name, builtinCryptoHmacSha512)
+	RegisterFunctionalBuiltin2(ast.CryptoHmacEqual.Name, builtinCryptoHmacEqual)
 }
 
 // createRootCAs creates a new Cert Pool from scratch or adds to a copy of System Certs
@@ -5,6 +5,13 @@
 package topdown
 
 import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
+	"encoding/pem"
 	"fmt"
 	"testing"
 )
@@ -58,3 +65,98 @@ func TestCryptoX509ParseCertificates(t *testing.T) {
 	}
 
 }
+
+func TestCryptoX509ParseCertificateRequest(t *testing.T) {
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	der, err := x509.CreateCertificateRequest(rand.Reader, &x509.CertificateRequest{
+		Subject:  pkix.Name{CommonName: "example.com", Organization: []string{"Example"}},
+		DNSNames: []string{"example.com", "www.example.com"},
+	}, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		note     string
+		csr      string
+		rule     string
+		expected interface{}
+	}{
+		{
+			note:     "subject",
+			csr:      base64.StdEncoding.EncodeToString(der),
+			rule:     `p = x { r := crypto.x509.parse_certificate_request(csr); x := r.Subject.CommonName }`,
+			expected: `"example.com"`,
+		},
+		{
+			note:     "sans",
+			csr:      base64.StdEncoding.EncodeToString(der),
+			rule:     `p[x] { r := crypto.x509.parse_certificate_request(csr); x := r.DNSNames[_] }`,
+			expected: `["example.com", "www.example.com"]`,
+		},
+		{
+			note:     "pem",
+			csr:      string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE REQUEST", Bytes: der})),
+			rule:     `p = x { r := crypto.x509.parse_certificate_request(csr); x := r.Subject.Organization }`,
+			expected: `["Example"]`,
+		},
+		{
+			note:     "pem decode",
+			csr:      "leading text\n" + string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE REQUEST", Headers: map[string]string{"Comment": "test"}, Bytes: der})),
+			rule:     `p = x { [b] := pem.decode(csr); r := crypto.x509.parse_certificate_request(b.bytes); x := [b.type, b.headers, r.DNSNames] }`,
+			expected: `["CERTIFICATE REQUEST", {"Comment": "test"}, ["example.com", "www.example.com"]]`,
+		},
+		{
+			note:     "pem decode no blocks",
+			csr:      `not pem`,
+			rule:     `p = x { x := pem.decode(csr) }`,
+			expected: fmt.Errorf("no PEM blocks found"),
+		},
+		{
+			note:     "bad",
+			csr:      `YmFkc3RyaW5n`,
+			rule:     `p = x { x := crypto.x509.parse_certificate_request(csr) }`,
+			expected: fmt.Errorf("asn1: structure error"),
+		},
+	}
+
+	data := loadSmallTestData()
+
+	for _, tc := range tests {
+		rules := []string{
+			fmt.Sprintf("csr = %q { true }", tc.csr),
+			tc.rule,
+		}
+		runTopDownTestCase(t, data, tc.note, rules, tc.expected)
+	}
+}
+
+func TestCryptoHashes(t *testing.T) {
+
+	tests := []struct {
+		note     string
+		rules    []string
+		expected interface{}
+	}{
+		{"md5", []string{`p = x { x := crypto.md5("abc") }`}, `"900150983cd24fb0d6963f7d28e17f72"`},
+		{"sha1", []string{`p = x { x := crypto.sha1("abc") }`}, `"a9993e364706816aba3e25717850c26c9cd0d89d"`},
+		{"sha256", []string{`p = x { x := crypto.sha256("abc") }`}, `"ba7816bf8f01cfea414140de5dae2223b00361a396177a9cb410ff61f20015ad"`},
+		{"hmac md5", []string{`p = x { x := crypto.hmac.md5("The quick brown fox jumps over the lazy dog", "key") }`}, `"80070713463e7749b90c2dc24911e275"`},
+		{"hmac sha1", []string{`p = x { x := crypto.hmac.sha1("The quick brown fox jumps over the lazy dog", "key") }`}, `"de7c9b85b8b78aa6bc8a7a36f70a90701c9db4d9"`},
+		{"hmac sha256", []string{`p = x { x := crypto.hmac.sha256("The quick brown fox jumps over the lazy dog", "key") }`}, `"f7bc83f430538424b13298e6aa6fb143ef4d59a14946175997479dbc2d1a3cd8"`},
+		{"hmac sha512", []string{`p = x { x := crypto.hmac.sha512("The quick brown fox jumps over the lazy dog", "key") }`}, `"b42af09057bac1e2d41708e48a902e09b5ff7f12ab428a4fe86653c73dd248fb82f948a549f7b791a5b41915ee4d1ec3935357e4e2317250d0372afa2ebeeb3a"`},
+		{"hmac equal", []string{`p = x { x := crypto.hmac.equal(crypto.hmac.sha256("msg", "key"), crypto.hmac.sha256("msg", "key")) }`}, `true`},
+		{"hmac not equal", []string{`p = x { x := crypto.hmac.equal(crypto.hmac.sha256("msg", "key"), crypto.hmac.sha256("msg", "other")) }`}, `false`},
+	}
+
+	data := loadSmallTestData()
+
+	for _, tc := range tests {
+		runTopDownTestCase(t, data, tc.note, tc.rules, tc.expected)
+	}
+}
@@ -0,0 +1,451 @@
+// Copyright 2020 The OPA Authors.  All rights reserved.
+// Use of this source code is governed by an Apache2
+// license that can be found in the LICENSE file.
+
+// Package debug implements a step debugger for policy evaluation.
+//
+// A Session runs evaluation in a separate goroutine and receives the trace
+// events emitted by the evaluator. Evaluation pauses when a breakpoint is hit
+// or a step completes so that the caller can inspect the current node and
+// bindings before resuming.
+package debug
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/open-policy-agent/opa/ast"
+	"github.com/open-policy-agent/opa/topdown"
+)
+
+// Breakpoint identifies where evaluation should pause. Location breakpoints
+// match expressions and rules on the row of a file. The file matches if the
+// location's file ends with File. Rule breakpoints match when evaluation
+// enters a rule that defines the document referred to by Rule.
+type Breakpoint struct {
+	File string
+	Row  int
+	Rule ast.Ref
+}
+
+// ParseBreakpoint returns the breakpoint described by s. The breakpoint is
+// either a location (e.g., authz.rego:12) or a rule reference (e.g.,
+// data.authz.allow).
+func ParseBreakpoint(s string) (Breakpoint, error) {
+
+	s = strings.TrimSpace(s)
+
+	if i := strings.LastIndex(s, ":"); i > 0 {
+		row, err := strconv.Atoi(s[i+1:])
+		if err != nil || row <= 0 {
+			return Breakpoint{}, fmt.Errorf("invalid breakpoint %q: row must be a positive integer", s)
+		}
+		return Breakpoint{File: s[:i], Row: row}, nil
+	}
+
+	ref, err := ast.ParseRef(s)
+	if err != nil {
+		return Breakpoint{}, fmt.Errorf("invalid breakpoint %q: %v", s, err)
+	}
+
+	if !ref.HasPrefix(ast.DefaultRootRef) {
+		return Breakpoint{}, fmt.Errorf("invalid breakpoint %q: rule references must start with %v", s, ast.DefaultRootRef)
+	}
+
+	return Breakpoint{Rule: ref}, nil
+}
+
+func (b Breakpoint) String() string {
+	if b.Rule != nil {
+		return b.Rule.String()
+	}
+	return fmt.Sprintf("%v:%v", b.File, b.Row)
+}
+
+func (b Breakpoint) matches(event *topdown.Event) bool {
+
+	if b.Rule != nil {
+		rule, ok := event.Node.(*ast.Rule)
+		return ok && event.Op == topdown.EnterOp && rule.Path().Equal(b.Rule)
+	}
+
+	switch event.Op {
+	case topdown.EvalOp, topdown.EnterOp:
+	default:
+		return false
+	}
+
+	var loc *ast.Location
+
+	switch node := event.Node.(type) {
+	case *ast.Expr:
+		loc = node.Location
+	case *ast.Rule:
+		loc = node.Location
+	}
+
+	return loc != nil && loc.Row == b.Row && strings.HasSuffix(loc.File, b.File)
+}
+
+// Stop describes the event that evaluation paused on. If the event matched
+// a breakpoint, Breakpoint is set. Depth is the nesting level of the query
+// that emitted the event (starting at 1). Stack contains the latest event of
+// each enclosing query, starting with Event itself, e.g., the expression that
+// called the rule that Event belongs to is the second element.
+type Stop struct {
+	Event      *topdown.Event
+	Depth      int
+	Breakpoint *Breakpoint
+	Stack      []*topdown.Event
+}
+
+// Binding represents a variable bound in the query that emitted an event.
+type Binding struct {
+	Name  ast.Var
+	Value ast.Value
+}
+
+// Bindings returns the variables bound in the query that emitted the event.
+// Variables rewritten by the compiler are reported with their original names.
+// Generated variables and wildcards are omitted. The bindings are sorted by name.
+func (s *Stop) Bindings() []Binding {
+
+	var result []Binding
+
+	if s.Event.Locals == nil {
+		return result
+	}
+
+	s.Event.Locals.Iter(func(k, v ast.Value) bool {
+		name, ok := k.(ast.Var)
+		if !ok {
+			return false
+		}
+		if meta, ok := s.Event.LocalMetadata[name]; ok {
+			name = meta.Name
+		}
+		if name.IsGenerated() || name.IsWildcard() {
+			return false
+		}
+		result = append(result, Binding{Name: name, Value: v})
+		return false
+	})
+
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].Name.Compare(result[j].Name) < 0
+	})
+
+	return result
+}
+
+// Resolved returns the expression that emitted the event with the bound
+// variables replaced by their values, e.g., input.user == "bob" for the
+// expression input.user == x when x is bound to "bob". If the event was not
+// emitted by an expression, Resolved returns nil.
+func (s *Stop) Resolved() *ast.Expr {
+
+	expr, ok := s.Event.Node.(*ast.Expr)
+	if !ok {
+		return nil
+	}
+
+	cpy := expr.Copy()
+
+	ast.WalkTerms(cpy, func(term *ast.Term) bool {
+		if v, ok := term.Value.(ast.Var); ok && s.Event.Locals != nil {
+			if x := s.Event.Locals.Get(v); x != nil {
+				term.Value = x
+			} else if meta, ok := s.Event.LocalMetadata[v]; ok {
+				term.Value = meta.Name
+			}
+		}
+		return false
+	})
+
+	return cpy
+}
+
+// Location returns the location of the node that emitted the event, or nil if
+// the node has no location.
+func (s *Stop) Location() *ast.Location {
+	switch n := s.Event.Node.(type) {
+	case *ast.Rule:
+		return n.Location
+	case *ast.Expr:
+		return n.Location
+	case ast.Body:
+		if len(n) > 0 {
+			return n.Loc()
+		}
+	}
+	return nil
+}
+
+// Node returns the node that emitted the event for display. Rules are
+// represented by their paths and variables rewritten by the compiler are
+// replaced by their original names.
+func (s *Stop) Node() interface{} {
+	switch n := s.Event.Node.(type) {
+	case *ast.Rule:
+		return n.Path()
+	case *ast.Expr:
+		return rename(n.Copy(), s.Event.LocalMetadata)
+	case ast.Body:
+		return rename(n.Copy(), s.Event.LocalMetadata)
+	}
+	return s.Event.Node
+}
+
+func (s *Stop) String() string {
+	if loc := s.Location(); loc != nil {
+		return fmt.Sprintf("%v:%v: %v %v", loc.File, loc.Row, s.Event.Op, s.Node())
+	}
+	return fmt.Sprintf("%v %v", s.Event.Op, s.Node())
+}
+
+func rename(node interface{}, meta map[ast.Var]topdown.VarMetadata) interface{} {
+	ast.WalkTerms(node, func(term *ast.Term) bool {
+		if v, ok := term.Value.(ast.Var); ok {
+			if m, ok := meta[v]; ok {
+				term.Value = m.Name
+			}
+		}
+		return false
+	})
+	return node
+}
+
+// EvalFunc evaluates a query with the tracer. Implementations should pass
+// ctx to the evaluator so that evaluation stops when the session is aborted.
+type EvalFunc func(ctx context.Context, tracer topdown.Tracer) error
+
+type mode int
+
+const (
+	modeStep mode = iota
+	modeNext
+	modeOut
+	modeContinue
+	modeRun
+)
+
+type command struct {
+	mode  mode
+	depth int
+}
+
+// Session controls the evaluation of a query. Session methods must not be
+// called concurrently.
+type Session struct {
+	ctx    context.Context
+	cancel context.CancelFunc
+	eval   EvalFunc
+
+	mtx         sync.Mutex
+	breakpoints []Breakpoint
+
+	// The following fields are only accessed by the evaluation goroutine
+	// after it has been started.
+	cmd     command
+	depths  map[uint64]int
+	parents map[uint64]uint64
+	latest  map[uint64]*topdown.Event
+
+	stops  chan *Stop
+	resume chan command
+	done   chan struct{}
+	err    error
+
+	started bool
+	current *Stop
+}
+
+// NewSession returns a new session that evaluates with eval. Evaluation
+// starts when Step, Next, Out, or Continue is called for the first time.
+func NewSession(ctx context.Context, eval EvalFunc) *Session {
+	ctx, cancel := context.WithCancel(ctx)
+	return &Session{
+		ctx:     ctx,
+		cancel:  cancel,
+		eval:    eval,
+		depths:  map[uint64]int{},
+		parents: map[uint64]uint64{},
+		latest:  map[uint64]*topdown.Event{},
+		stops:   make(chan *Stop),
+		resume:  make(chan command),
+		done:    make(chan struct{}),
+	}
+}
+
+// SetBreakpoints replaces the session's breakpoints. Breakpoints can be set
+// while evaluation is paused.
+func (s *Session) SetBreakpoints(breakpoints []Breakpoint) {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	s.breakpoints = append([]Breakpoint(nil), breakpoints...)
+}
+
+// Current returns the event that evaluation is paused on. If evaluation has
+// not started or has finished, Current returns nil.
+func (s *Session) Current() *Stop {
+	return s.current
+}
+
+// Step resumes evaluation until the next event.
+func (s *Session) Step() (*Stop, error) {
+	return s.run(command{mode: modeStep})
+}
+
+// Next resumes evaluation until the next event in the current query or an
+// enclosing query, i.e., it steps over rules and functions that are called.
+func (s *Session) Next() (*Stop, error) {
+	return s.run(command{mode: modeNext, depth: s.depth()})
+}
+
+// Out resumes evaluation until the next event in an enclosing query, i.e., it
+// steps out of the current rule or function.
+func (s *Session) Out() (*Stop, error) {
+	return s.run(command{mode: modeOut, depth: s.depth()})
+}
+
+// Continue resumes evaluation until a breakpoint is hit.
+func (s *Session) Continue() (*Stop, error) {
+	return s.run(command{mode: modeContinue})
+}
+
+// Abort cancels evaluation and waits for it to finish. Evaluation does not
+// pause again after Abort is called.
+func (s *Session) Abort() {
+	s.cancel()
+	if !s.started {
+		s.started = true
+		s.err = s.ctx.Err()
+		close(s.done)
+	} else if s.current != nil {
+		s.resume <- command{mode: modeRun}
+	}
+	<-s.done
+	s.current = nil
+}
+
+// Done returns true if evaluation has finished.
+func (s *Session) Done() bool {
+	select {
+	case <-s.done:
+		return true
+	default:
+		return false
+	}
+}
+
+// Err returns the error returned by evaluation once it has finished.
+func (s *Session) Err() error {
+	if s.Done() {
+		return s.err
+	}
+	return nil
+}
+
+func (s *Session) depth() int {
+	if s.current == nil {
+		return 0
+	}
+	return s.current.Depth
+}
+
+// run resumes evaluation with cmd and waits until evaluation pauses or
+// finishes. If evaluation finishes, the returned stop is nil and the error
+// returned by evaluation is returned.
+func (s *Session) run(cmd command) (*Stop, error) {
+
+	if s.Done() {
+		return nil, s.err
+	}
+
+	if !s.started {
+		s.started = true
+		s.cmd = cmd
+		go func() {
+			s.err = s.eval(s.ctx, s)
+			close(s.done)
+		}()
+	} else {
+		s.resume <- cmd
+	}
+
+	select {
+	case stop := <-s.stops:
+		s.current = stop
+		return stop, nil
+	case <-s.done:
+		s.current = nil
+		return nil, s.err
+	}
+}
+
+// Enabled implements the topdown.Tracer interface.
+func (s *Session) Enabled() bool {
+	return true
+}
+
+// Trace implements the topdown.Tracer interface. Trace blocks while
+// evaluation is paused.
+func (s *Session) Trace(event *topdown.Event) {
+
+	depth := s.depths[event.QueryID]
+	if depth == 0 {
+		depth = s.depths[event.ParentID] + 1
+		s.depths[event.QueryID] = depth
+	}
+
+	// Index events describe rule lookups rather than evaluation steps.
+	if s.cmd.mode == modeRun || event.Op == topdown.IndexOp {
+		return
+	}
+
+	s.parents[event.QueryID] = event.ParentID
+	s.latest[event.QueryID] = event
+
+	stop := &Stop{Event: event, Depth: depth}
+
+	s.mtx.Lock()
+	for i := range s.breakpoints {
+		if s.breakpoints[i].matches(event) {
+			bp := s.breakpoints[i]
+			stop.Breakpoint = &bp
+			break
+		}
+	}
+	s.mtx.Unlock()
+
+	if stop.Breakpoint == nil {
+		switch s.cmd.mode {
+		case modeContinue:
+			return
+		case modeNext:
+			if depth > s.cmd.depth {
+				return
+			}
+		case modeOut:
+			if depth >= s.cmd.depth {
+				return
+			}
+		}
+	}
+
+	for qid, ok := event.QueryID, true; ok; {
+		stop.Stack = append(stop.Stack, s.latest[qid])
+		if qid == s.parents[qid] {
+			break
+		}
+		qid = s.parents[qid]
+		_, ok = s.latest[qid]
+	}
+
+	s.stops <- stop
+	s.cmd = <-s.resume
+}
@@ -0,0 +1,221 @@
+// Copyright 2020 The OPA Authors.  All rights reserved.
+// Use of this source code is governed by an Apache2
+// license that can be found in the LICENSE file.
+
+package debug
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"testing"
+
+	"github.com/open-policy-agent/opa/ast"
+	"github.com/open-policy-agent/opa/rego"
+	"github.com/open-policy-agent/opa/topdown"
+)
+
+const testModule = `package authz
+
+allow {
+	user := input.user
+	is_admin(user)
+}
+
+is_admin(user) {
+	data.admins[_] = user
+}`
+
+func newTestSession(t *testing.T, input interface{}) (*Session, *rego.ResultSet) {
+	t.Helper()
+
+	compiler := ast.MustCompileModules(map[string]string{"authz.rego": testModule})
+
+	var rs rego.ResultSet
+
+	session := NewSession(context.Background(), func(ctx context.Context, tracer topdown.Tracer) error {
+		var err error
+		rs, err = rego.New(
+			rego.Query("data.authz.allow"),
+			rego.Compiler(compiler),
+			rego.Input(input),
+			rego.Tracer(tracer),
+		).Eval(ctx)
+		return err
+	})
+
+	return session, &rs
+}
+
+func TestParseBreakpoint(t *testing.T) {
+
+	tests := []struct {
+		input   string
+		exp     string
+		wantErr bool
+	}{
+		{"authz.rego:12", "authz.rego:12", false},
+		{"data.authz.allow", "data.authz.allow", false},
+		{`data.authz["allow"]`, "data.authz.allow", false},
+		{"authz.rego:x", "", true},
+		{"authz.rego:0", "", true},
+		{"input.x", "", true},
+		{"data.", "", true},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.input, func(t *testing.T) {
+			bp, err := ParseBreakpoint(tc.input)
+			if tc.wantErr {
+				if err == nil {
+					t.Fatal("Expected error")
+				}
+				return
+			} else if err != nil {
+				t.Fatal(err)
+			}
+			if bp.String() != tc.exp {
+				t.Fatalf("Expected %v but got %v", tc.exp, bp)
+			}
+		})
+	}
+}
+
+func TestSessionBreakpoints(t *testing.T) {
+
+	session, rs := newTestSession(t, map[string]interface{}{"user": "bob"})
+
+	session.SetBreakpoints([]Breakpoint{
+		{Rule: ast.MustParseRef("data.authz.is_admin")},
+		{File: "authz.rego", Row: 9},
+	})
+
+	stop, err := session.Continue()
+	if err != nil {
+		t.Fatal(err)
+	} else if stop == nil || stop.Breakpoint == nil || stop.Breakpoint.String() != "data.authz.is_admin" {
+		t.Fatalf("Expected to stop at rule breakpoint but got: %v", stop)
+	} else if stop.String() != "authz.rego:8: Enter data.authz.is_admin" {
+		t.Fatalf("Unexpected stop: %v", stop)
+	}
+
+	var stack []string
+	for _, event := range stop.Stack {
+		stack = append(stack, (&Stop{Event: event}).String())
+	}
+
+	expStack := []string{
+		"authz.rego:8: Enter data.authz.is_admin",
+		"authz.rego:5: Eval data.authz.is_admin(user)",
+		":1: Eval data.authz.allow = _",
+	}
+
+	if !reflect.DeepEqual(stack, expStack) {
+		t.Fatalf("Expected stack %v but got %v", expStack, stack)
+	}
+
+	stop, err = session.Continue()
+	if err != nil {
+		t.Fatal(err)
+	} else if stop == nil || stop.Breakpoint == nil || stop.Breakpoint.String() != "authz.rego:9" {
+		t.Fatalf("Expected to stop at location breakpoint but got: %v", stop)
+	} else if stop.String() != "authz.rego:9: Eval data.admins[_] = user" {
+		t.Fatalf("Unexpected stop: %v", stop)
+	}
+
+	bindings := stop.Bindings()
+	if len(bindings) != 1 || bindings[0].Name != "user" || bindings[0].Value.Compare(ast.String("bob")) != 0 {
+		t.Fatalf("Unexpected bindings: %v", bindings)
+	}
+
+	if exp := `data.admins[_] = "bob"`; stop.Resolved().String() != exp {
+		t.Fatalf("Expected resolved expression %v but got %v", exp, stop.Resolved())
+	}
+
+	stop, err = session.Continue()
+	if err != nil || stop != nil {
+		t.Fatalf("Expected evaluation to finish but got: %v, %v", stop, err)
+	} else if !session.Done() || len(*rs) != 0 {
+		t.Fatalf("Expected undefined result but got: %v", *rs)
+	}
+
+	if stop, err := session.Step(); stop != nil || err != nil {
+		t.Fatalf("Expected finished session but got: %v, %v", stop, err)
+	}
+}
+
+func TestSessionStepping(t *testing.T) {
+
+	session, _ := newTestSession(t, map[string]interface{}{"user": "bob"})
+
+	var stops []string
+
+	record := func(stop *Stop, err error) {
+		t.Helper()
+		if err != nil {
+			t.Fatal(err)
+		} else if stop == nil {
+			t.Fatal("Unexpected end of evaluation")
+		}
+		stops = append(stops, fmt.Sprintf("%d %v", stop.Depth, stop))
+	}
+
+	session.SetBreakpoints([]Breakpoint{{Rule: ast.MustParseRef("data.authz.allow")}})
+
+	record(session.Continue()) // enter allow
+	record(session.Step())     // eval user := input.user
+	record(session.Next())     // eval is_admin(user), skipping nothing
+	record(session.Step())     // enter is_admin
+	record(session.Out())      // fail is_admin(user) in allow
+
+	exp := []string{
+		"2 authz.rego:3: Enter data.authz.allow",
+		"2 authz.rego:4: Eval user = input.user",
+		"2 authz.rego:5: Eval data.authz.is_admin(user)",
+		"3 authz.rego:8: Enter data.authz.is_admin",
+		"2 authz.rego:5: Fail data.authz.is_admin(user)",
+	}
+
+	if len(stops) != len(exp) {
+		t.Fatalf("Expected stops:\n%v\n\nGot:\n%v", exp, stops)
+	}
+
+	for i := range exp {
+		if stops[i] != exp[i] {
+			t.Fatalf("Expected stop %d to be %q but got %q", i, exp[i], stops[i])
+		}
+	}
+
+	session.Abort()
+
+	if !session.Done() || session.Current() != nil {
+		t.Fatal("Expected aborted session to be done")
+	}
+}
+
+func TestSessionAbort(t *testing.T) {
+
+	session, _ := newTestSession(t, nil)
+
+	if stop, err := session.Step(); err != nil || stop == nil {
+		t.Fatalf("Expected stop but got: %v, %v", stop, err)
+	}
+
+	session.Abort()
+
+	if !session.Done() {
+		t.Fatal("Expected aborted session to be done")
+	}
+
+	if stop, _ := session.Continue(); stop != nil {
+		t.Fatalf("Expected aborted session not to stop but got: %v", stop)
+	}
+
+	// Sessions that never started can be aborted.
+	session, _ = newTestSession(t, nil)
+	session.Abort()
+
+	if stop, err := session.Step(); stop != nil || err != context.Canceled {
+		t.Fatalf("Expected canceled session but got: %v, %v", stop, err)
+	}
+}
@@ -8,6 +8,7 @@ import (
 	"bytes"
 	"encoding/base64"
 	"encoding/json"
+	"encoding/pem"
 	"fmt"
 	"net/url"
 	"strings"
@@ -34,6 +35,37 @@ func builtinJSONMarshal(a ast.Value) (ast.Value, error) {
 	return ast.String(string(bs)), nil
 }
 
+func builtinJSONMarshalCanonical(a ast.Value) (ast.Value, error) {
+
+	sorted, err := ast.Transform(sortedSets{}, a)
+	if err != nil {
+		return nil, err
+	}
+
+	asJSON, err := ast.JSON(sorted.(ast.Value))
+	if err != nil {
+		return nil, err
+	}
+
+	bs, err := util.MarshalCanonicalJSON(asJSON)
+	if err != nil {
+		return nil, err
+	}
+
+	return ast.String(string(bs)), nil
+}
+
+// sortedSets replaces sets with sorted arrays so that the serialization of a
+// set does not depend on the order its elements were inserted in.
+type sortedSets struct{}
+
+func (sortedSets) Transform(x interface{}) (interface{}, error) {
+	if s, ok := x.(ast.Set); ok {
+		return s.Sorted(), nil
+	}
+	return x, nil
+}
+
 func builtinJSONUnmarshal(a ast.Value) (ast.Value, error) {
 
 	str, err := builtins.StringOperand(a, 1)
@@ -78,6 +110,15 @@ func builtinBase64UrlEncode(a ast.Value) (ast.Value, error) {
 	return ast.String(base64.URLEncoding.EncodeToString([]byte(str))), nil
 }
 
+func builtinBase64UrlEncodeNoPad(a ast.Value) (ast.Value, error) {
+	str, err := builtins.StringOperand(a, 1)
+	if err != nil {
+		return nil, err
+	}
+
+	return ast.String(base64.RawURLEncoding.EncodeToString([]byte(str))), nil
+}
+
 func builtinBase64UrlDecode(a ast.Value) (ast.Value, error) {
 	str, err := builtins.StringOperand(a, 1)
 	if err != nil {
@@ -202,13 +243,61 @@ func builtinYAMLUnmarshal(a ast.Value) (ast.Value, error) {
 	return ast.InterfaceToValue(val)
 }
 
+// builtinPEMDecode returns the PEM blocks contained in the input string. The
+// block contents are base64 encoded so that they can be passed to other
+// built-ins (e.g., crypto.x509.parse_certificates).
+func builtinPEMDecode(a ast.Value) (ast.Value, error) {
+	str, err := builtins.StringOperand(a, 1)
+	if err != nil {
+		return nil, err
+	}
+
+	blocks := decodePEMBlocks([]byte(str))
+	if len(blocks) == 0 {
+		return nil, fmt.Errorf("no PEM blocks found")
+	}
+
+	result := make(ast.Array, len(blocks))
+
+	for i, block := range blocks {
+		headers := ast.NewObject()
+		for k, v := range block.Headers {
+			headers.Insert(ast.StringTerm(k), ast.StringTerm(v))
+		}
+		result[i] = ast.ObjectTerm(
+			ast.Item(ast.StringTerm("type"), ast.StringTerm(block.Type)),
+			ast.Item(ast.StringTerm("headers"), ast.NewTerm(headers)),
+			ast.Item(ast.StringTerm("bytes"), ast.StringTerm(base64.StdEncoding.EncodeToString(block.Bytes))),
+		)
+	}
+
+	return result, nil
+}
+
+// decodePEMBlocks returns all PEM blocks in bs. Data before, between, and
+// after blocks is ignored.
+func decodePEMBlocks(bs []byte) []*pem.Block {
+	var blocks []*pem.Block
+	for {
+		var block *pem.Block
+		block, bs = pem.Decode(bs)
+		if block == nil {
+			return blocks
+		}
+		blocks = append(blocks, block)
+	}
+}
+
 func init() {
 	RegisterFunctionalBuiltin1(ast.JSONMarshal.Name, builtinJSONMarshal)
+	RegisterFunctionalBuiltin1(ast.JSONMarshalCanonical.Name, builtinJSONMarshalCanonical)
 	RegisterFunctionalBuiltin1(ast.JSONUnmarshal.Name, builtinJSONUnmarshal)
 	RegisterFunctionalBuiltin1(ast.Base64Encode.Name, builtinBase64Encode)
 	RegisterFunctionalBuiltin1(ast.Base64Decode.Name, builtinBase64Decode)
 	RegisterFunctionalBuiltin1(ast.Base64UrlEncode.Name, builtinBase64UrlEncode)
 	RegisterFunctionalBuiltin1(ast.Base64UrlDecode.Name, builtinBase64UrlDecode)
+	RegisterFunctionalBuiltin1(ast.Base64UrlEncodeNoPad.Name, builtinBase64UrlEncodeNoPad)
+	RegisterFunctionalBuiltin1(ast.PEMDecode.Name, builtinPEMDecode)
 	RegisterFunctionalBuiltin1(ast.URLQueryDecode.Name, builtinURLQueryDecode)
 	RegisterFunctionalBuiltin1(ast.URLQueryEncode.Name, builtinURLQueryEncode)
 	RegisterFunctionalBuiltin1(ast.URLQueryEncodeObject.Name, builtinURLQueryEncodeObject)
@@ -43,6 +43,10 @@ const (
 
 	// WithMergeErr indicates that the real and replacement data could not be merged.
 	WithMergeErr string = "eval_with_merge_error"
+
+	// ResultLimitErr indicates evaluation stopped because the query produced
+	// more results than the configured limit.
+	ResultLimitErr string = "eval_result_limit_error"
 )
 
 // IsError returns true if the err is an Error.
@@ -70,6 +74,22 @@ func (e *Error) Error() string {
 	return msg
 }
 
+// IsResultLimit returns true if err was caused by the query producing too many
+// results.
+func IsResultLimit(err error) bool {
+	if e, ok := err.(*Error); ok {
+		return e.Code == ResultLimitErr
+	}
+	return false
+}
+
+func resultLimitErr(limit int) error {
+	return &Error{
+		Code:    ResultLimitErr,
+		Message: fmt.Sprintf("query produced more than %d results", limit),
+	}
+}
+
 func functionConflictErr(loc *ast.Location) error {
 	return &Error{
 		Code:     ConflictErr,
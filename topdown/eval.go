@@ -3,6 +3,7 @@ package topdown
 import (
 	"context"
 	"fmt"
+	"io"
 	"sort"
 	"strconv"
 	"strings"
@@ -47,6 +48,7 @@ type eval struct {
 	input           *ast.Term
 	data            *ast.Term
 	targetStack     *refStack
+	functionMocks   *functionMocksStack
 	tracers         []Tracer
 	instr           *Instrumentation
 	builtins        map[string]*Builtin
@@ -59,6 +61,10 @@ type eval struct {
 	disableInlining []ast.Ref
 	genvarprefix    string
 	runtime         *ast.Term
+	time            *ast.Term
+	seed            io.Reader
+	interQueryCache *builtins.InterQueryCache
+	counters        *evalCounters
 }
 
 func (e *eval) Run(iter evalIterator) error {
@@ -101,7 +107,7 @@ func (e *eval) child(query ast.Body) *eval {
 	cpy.index = 0
 	cpy.query = query
 	cpy.queryID = cpy.queryIDFact.Next()
-	cpy.bindings = newBindings(cpy.queryID, e.instr)
+	cpy.bindings = newBindings(cpy.queryID, e.instr, e.bindings.plugs)
 	cpy.parent = e
 	return &cpy
 }
@@ -223,6 +229,7 @@ func (e *eval) evalExpr(iter evalIterator) error {
 
 	expr := e.query[e.index]
 
+	e.counters.exprs++
 	e.traceEval(expr)
 
 	if len(expr.With) > 0 {
@@ -334,11 +341,15 @@ func (e *eval) evalWith(iter evalIterator) error {
 
 	pairsInput := [][2]*ast.Term{}
 	pairsData := [][2]*ast.Term{}
+	pairsFunctions := [][2]*ast.Term{}
 	targets := []ast.Ref{}
 
 	for i := range expr.With {
 		plugged := e.bindings.Plug(expr.With[i].Value)
-		if isInputRef(expr.With[i].Target) {
+		if e.isFunction(expr.With[i].Target) {
+			pairsFunctions = append(pairsFunctions, [...]*ast.Term{expr.With[i].Target, plugged})
+			continue
+		} else if isInputRef(expr.With[i].Target) {
 			pairsInput = append(pairsInput, [...]*ast.Term{expr.With[i].Target, plugged})
 		} else if isDataRef(expr.With[i].Target) {
 			pairsData = append(pairsData, [...]*ast.Term{expr.With[i].Target, plugged})
@@ -365,12 +376,12 @@ func (e *eval) evalWith(iter evalIterator) error {
 		}
 	}
 
-	oldInput, oldData := e.evalWithPush(input, data, targets)
+	oldInput, oldData := e.evalWithPush(input, data, targets, pairsFunctions)
 
 	err = e.evalStep(func(e *eval) error {
 		e.evalWithPop(oldInput, oldData)
 		err := e.next(iter)
-		oldInput, oldData = e.evalWithPush(input, data, targets)
+		oldInput, oldData = e.evalWithPush(input, data, targets, pairsFunctions)
 		return err
 	})
 
@@ -379,7 +390,7 @@ func (e *eval) evalWith(iter evalIterator) error {
 	return err
 }
 
-func (e *eval) evalWithPush(input *ast.Term, data *ast.Term, targets []ast.Ref) (*ast.Term, *ast.Term) {
+func (e *eval) evalWithPush(input *ast.Term, data *ast.Term, targets []ast.Ref, mocks [][2]*ast.Term) (*ast.Term, *ast.Term) {
 
 	var oldInput *ast.Term
 
@@ -397,17 +408,84 @@ func (e *eval) evalWithPush(input *ast.Term, data *ast.Term, targets []ast.Ref)
 
 	e.virtualCache.Push()
 	e.targetStack.Push(targets)
+	e.functionMocks.Push(mocks)
 
 	return oldInput, oldData
 }
 
 func (e *eval) evalWithPop(input *ast.Term, data *ast.Term) {
+	e.functionMocks.Pop()
 	e.targetStack.Pop()
 	e.virtualCache.Pop()
 	e.data = data
 	e.input = input
 }
 
+// isFunction returns true if the with modifier target refers to a built-in
+// function or a function defined in policy.
+func (e *eval) isFunction(target *ast.Term) bool {
+	if isInputRef(target) {
+		return false
+	} else if isDataRef(target) {
+		return e.compiler.GetArity(target.Value.(ast.Ref)) > 0
+	}
+	return true
+}
+
+// evalCallMock evaluates a call to a function that has been replaced using
+// the with keyword. If the replacement is a function, it is called instead.
+// Otherwise, the replacement is the result of the call.
+func (e *eval) evalCallMock(mock *ast.Term, terms []*ast.Term, iter unifyIterator) error {
+
+	if isDataRef(mock) {
+		cpy := make([]*ast.Term, len(terms))
+		copy(cpy, terms)
+		cpy[0] = mock
+
+		// The replacement is evaluated with the replacement of the target
+		// disabled so that calls to the target from the replacement (or
+		// from replacements that refer back to the target) call the
+		// original function instead of recursing forever.
+		disabled := [][2]*ast.Term{{terms[0], nil}}
+		e.virtualCache.Push()
+		e.functionMocks.Push(disabled)
+
+		err := e.evalCall(cpy, func() error {
+			e.functionMocks.Pop()
+			e.virtualCache.Pop()
+			err := iter()
+			e.virtualCache.Push()
+			e.functionMocks.Push(disabled)
+			return err
+		})
+
+		e.functionMocks.Pop()
+		e.virtualCache.Pop()
+
+		return err
+	}
+
+	ref := terms[0].Value.(ast.Ref)
+
+	var arity int
+
+	if bi, _, ok := e.builtinFunc(ref.String()); ok {
+		arity = len(bi.Decl.Args())
+	} else {
+		arity = e.compiler.GetArity(ref)
+	}
+
+	if len(terms) == arity+2 {
+		return e.unify(terms[len(terms)-1], mock, iter)
+	}
+
+	if mock.Value.Compare(ast.Boolean(false)) == 0 {
+		return nil
+	}
+
+	return iter()
+}
+
 func (e *eval) evalNotPartial(iter evalIterator) error {
 
 	// Prepare query normally.
@@ -531,6 +609,10 @@ func (e *eval) evalCall(terms []*ast.Term, iter unifyIterator) error {
 
 	ref := terms[0].Value.(ast.Ref)
 
+	if mock, ok := e.functionMocks.Get(ref); ok {
+		return e.evalCallMock(mock, terms, iter)
+	}
+
 	if ref[0].Equal(ast.DefaultRootDocument) {
 		eval := evalFunc{
 			e:     e,
@@ -575,14 +657,19 @@ func (e *eval) evalCall(terms []*ast.Term, iter unifyIterator) error {
 	}
 
 	bctx := BuiltinContext{
-		Context:  e.ctx,
-		Cancel:   e.cancel,
-		Runtime:  e.runtime,
-		Cache:    e.builtinCache,
-		Location: e.query[e.index].Location,
-		Tracers:  e.tracers,
-		QueryID:  e.queryID,
-		ParentID: parentID,
+		Context:         e.ctx,
+		Cancel:          e.cancel,
+		Runtime:         e.runtime,
+		Store:           e.store,
+		Txn:             e.txn,
+		Time:            e.time,
+		Seed:            e.seed,
+		Cache:           e.builtinCache,
+		InterQueryCache: e.interQueryCache,
+		Location:        e.query[e.index].Location,
+		Tracers:         e.tracers,
+		QueryID:         e.queryID,
+		ParentID:        parentID,
 	}
 
 	eval := evalBuiltin{
@@ -1149,6 +1236,7 @@ func (e *eval) resolveReadFromStorage(ref ast.Ref, a ast.Value) (ast.Value, erro
 		return a, nil
 	}
 
+	e.counters.storageReads++
 	blob, err := e.store.Read(e.ctx, e.txn, path)
 	if err != nil {
 		if !storage.IsNotFound(err) {
@@ -1448,6 +1536,12 @@ func (e evalTree) enumerate(iter unifyIterator) error {
 				}
 			}
 		case ast.Object:
+			if e.node != nil {
+				// Base and virtual documents share this path. Enumerate the
+				// union of their keys in sorted order so that the results do
+				// not depend on map iteration order.
+				return e.enumerateKeys(iter, mergeKeys(doc, e.node.Sorted))
+			}
 			err := doc.Iter(func(k, _ *ast.Term) error {
 				return e.e.biunify(k, e.ref[e.pos], e.bindings, e.bindings, func() error {
 					return e.next(iter, k)
@@ -1472,7 +1566,11 @@ func (e evalTree) enumerate(iter unifyIterator) error {
 		return nil
 	}
 
-	for k := range e.node.Children {
+	return e.enumerateKeys(iter, e.node.Sorted)
+}
+
+func (e evalTree) enumerateKeys(iter unifyIterator, keys []ast.Value) error {
+	for _, k := range keys {
 		key := ast.NewTerm(k)
 		if err := e.e.biunify(key, e.ref[e.pos], e.bindings, e.bindings, func() error {
 			return e.next(iter, key)
@@ -1480,10 +1578,27 @@ func (e evalTree) enumerate(iter unifyIterator) error {
 			return err
 		}
 	}
-
 	return nil
 }
 
+// mergeKeys returns the sorted union of the object keys and the (sorted) rule
+// tree keys.
+func mergeKeys(obj ast.Object, sorted []ast.Value) []ast.Value {
+	keys := make([]ast.Value, 0, obj.Len()+len(sorted))
+	obj.Foreach(func(k, _ *ast.Term) {
+		keys = append(keys, k.Value)
+	})
+	for _, k := range sorted {
+		if obj.Get(ast.NewTerm(k)) == nil {
+			keys = append(keys, k)
+		}
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		return keys[i].Compare(keys[j]) < 0
+	})
+	return keys
+}
+
 func (e evalTree) extent() (*ast.Term, error) {
 	base, err := e.e.Resolve(e.plugged)
 	if err != nil {
@@ -1656,10 +1771,12 @@ func (e evalVirtualPartial) eval(iter unifyIterator) error {
 			cached := e.e.virtualCache.Get(path)
 
 			if cached != nil {
+				e.e.counters.virtualCacheHits++
 				e.e.instr.counterIncr(evalOpVirtualCacheHit)
 				return e.evalTerm(iter, cached, e.bindings)
 			}
 
+			e.e.counters.virtualCacheMisses++
 			e.e.instr.counterIncr(evalOpVirtualCacheMiss)
 			cacheKey = path
 		}
@@ -1671,8 +1788,19 @@ func (e evalVirtualPartial) eval(iter unifyIterator) error {
 		return e.partialEvalSupport(iter)
 	}
 
+	// Set rules may produce the same element many times. Keep track of the
+	// elements that have been delivered so that duplicates are discarded as
+	// they are produced instead of being passed to the iterator. During
+	// partial evaluation, each duplicate may depend on different unknowns so
+	// they must all be delivered.
+	var seen ast.Set
+
+	if e.ir.Kind == ast.PartialSetDoc && !e.e.partial() {
+		seen = ast.NewSet()
+	}
+
 	for _, rule := range e.ir.Rules {
-		if err := e.evalOneRule(iter, rule, cacheKey); err != nil {
+		if err := e.evalOneRule(iter, rule, cacheKey, seen); err != nil {
 			return err
 		}
 	}
@@ -1708,7 +1836,7 @@ func (e evalVirtualPartial) evalAllRules(iter unifyIterator, rules []*ast.Rule)
 	return e.e.biunify(result, e.rterm, e.bindings, e.bindings, iter)
 }
 
-func (e evalVirtualPartial) evalOneRule(iter unifyIterator, rule *ast.Rule, cacheKey ast.Ref) error {
+func (e evalVirtualPartial) evalOneRule(iter unifyIterator, rule *ast.Rule, cacheKey ast.Ref, seen ast.Set) error {
 
 	key := e.ref[e.pos+1]
 	child := e.e.child(rule.Body)
@@ -1731,6 +1859,16 @@ func (e evalVirtualPartial) evalOneRule(iter unifyIterator, rule *ast.Rule, cach
 				e.e.virtualCache.Put(cacheKey, result)
 			}
 
+			if seen != nil {
+				if elem := child.bindings.Plug(term); elem.IsGround() {
+					if seen.Contains(elem) {
+						child.traceRedo(rule)
+						return nil
+					}
+					seen.Add(elem)
+				}
+			}
+
 			term, termbindings := child.bindings.apply(term)
 			err := e.evalTerm(iter, term, termbindings)
 			if err != nil {
@@ -1892,10 +2030,12 @@ func (e evalVirtualComplete) eval(iter unifyIterator) error {
 func (e evalVirtualComplete) evalValue(iter unifyIterator) error {
 	cached := e.e.virtualCache.Get(e.plugged[:e.pos+1])
 	if cached != nil {
+		e.e.counters.virtualCacheHits++
 		e.e.instr.counterIncr(evalOpVirtualCacheHit)
 		return e.evalTerm(iter, cached, e.bindings)
 	}
 
+	e.e.counters.virtualCacheMisses++
 	e.e.instr.counterIncr(evalOpVirtualCacheMiss)
 
 	var prev *ast.Term
@@ -5,9 +5,12 @@
 package topdown
 
 import (
+	"context"
 	"testing"
 
 	"github.com/open-policy-agent/opa/ast"
+	"github.com/open-policy-agent/opa/storage"
+	"github.com/open-policy-agent/opa/storage/inmem"
 )
 
 func TestQueryIDFactory(t *testing.T) {
@@ -123,3 +126,53 @@ func TestRefContainsNonScalar(t *testing.T) {
 	}
 
 }
+
+func TestPartialSetDocDeduplication(t *testing.T) {
+
+	ctx := context.Background()
+	store := inmem.NewFromObject(map[string]interface{}{
+		"xs": []interface{}{"a", "b", "a", "a", "b"},
+	})
+
+	compiler := ast.MustCompileModules(map[string]string{
+		"test.rego": `package test
+
+		p[x] { data.xs[_] = x }
+		p[x] { x = "a" }
+		q["a"] { data.xs[_] = "a" }`,
+	})
+
+	cases := []struct {
+		note     string
+		query    string
+		expected int
+	}{
+		{note: "non-ground key", query: "data.test.p[x]", expected: 2},
+		{note: "ground key", query: `data.test.p["a"]`, expected: 1},
+		{note: "ground head", query: "data.test.q[x]", expected: 1},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.note, func(t *testing.T) {
+			txn := storage.NewTransactionOrDie(ctx, store)
+			defer store.Abort(ctx, txn)
+
+			var n int
+			err := NewQuery(ast.MustParseBody(tc.query)).
+				WithCompiler(compiler).
+				WithStore(store).
+				WithTransaction(txn).
+				Iter(ctx, func(QueryResult) error {
+					n++
+					return nil
+				})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if n != tc.expected {
+				t.Fatalf("Expected %d results but got %d", tc.expected, n)
+			}
+		})
+	}
+}
@@ -0,0 +1,81 @@
+// +build gofuzz
+
+package topdown
+
+import (
+	"context"
+	"regexp"
+	"time"
+
+	"github.com/open-policy-agent/opa/ast"
+	"github.com/open-policy-agent/opa/storage"
+	"github.com/open-policy-agent/opa/storage/inmem"
+	"github.com/open-policy-agent/opa/util"
+)
+
+// nested { and [ tokens cause the parse time to explode.
+// see: https://github.com/mna/pigeon/issues/75
+var blacklistRegexp = regexp.MustCompile(`[{(\[]{5,}`)
+
+var fuzzData = util.MustUnmarshalJSON([]byte(`{
+	"a": [1, 2, 3, 4],
+	"b": {"v1": "hello", "v2": "goodbye"},
+	"c": [{"x": [true, false, "foo"], "y": [null, 3.14159], "z": {"p": true, "q": false}}],
+	"d": {"e": ["bar", "baz"]}
+}`)).(map[string]interface{})
+
+// Fuzz evaluates data as a query against a fixed data document. Evaluation is
+// cancelled after one second so that inputs that do not terminate quickly
+// (e.g., large numeric ranges) are not reported as hangs.
+func Fuzz(data []byte) int {
+
+	if blacklistRegexp.Match(data) {
+		return -1
+	}
+
+	body, err := ast.ParseBody(string(data))
+	if err != nil {
+		return 0
+	}
+
+	// Skip queries that perform I/O.
+	var io bool
+	ast.WalkExprs(body, func(expr *ast.Expr) bool {
+		if expr.IsCall() && expr.Operator().Equal(ast.HTTPSend.Ref()) {
+			io = true
+		}
+		return io
+	})
+
+	if io {
+		return -1
+	}
+
+	compiler := ast.NewCompiler()
+	query, err := compiler.QueryCompiler().Compile(body)
+	if err != nil {
+		return 0
+	}
+
+	ctx := context.Background()
+	store := inmem.NewFromObject(fuzzData)
+	txn := storage.NewTransactionOrDie(ctx, store)
+	defer store.Abort(ctx, txn)
+
+	cancel := NewCancel()
+	timer := time.AfterFunc(time.Second, cancel.Cancel)
+	defer timer.Stop()
+
+	_, err = NewQuery(query).
+		WithCompiler(compiler).
+		WithStore(store).
+		WithTransaction(txn).
+		WithCancel(cancel).
+		Run(ctx)
+
+	if err != nil {
+		return 0
+	}
+
+	return 1
+}
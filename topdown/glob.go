@@ -1,48 +1,48 @@
 package topdown
 
 import (
-	"fmt"
-	"sync"
-
 	"github.com/gobwas/glob"
 
 	"github.com/open-policy-agent/opa/ast"
 	"github.com/open-policy-agent/opa/topdown/builtins"
 )
 
-var globCacheLock = sync.Mutex{}
-var globCache map[string]glob.Glob
+// globKey identifies a compiled glob pattern in the inter-query cache.
+type globKey struct {
+	pattern    string
+	delimiters string
+}
 
-func builtinGlobMatch(a, b, c ast.Value) (ast.Value, error) {
-	pattern, err := builtins.StringOperand(a, 1)
+func builtinGlobMatch(bctx BuiltinContext, operands []*ast.Term, iter func(*ast.Term) error) error {
+	pattern, err := builtins.StringOperand(operands[0].Value, 1)
 	if err != nil {
-		return nil, err
+		return handleBuiltinErr(ast.GlobMatch.Name, bctx.Location, err)
 	}
 
-	delimiters, err := builtins.RuneSliceOperand(b, 2)
+	delimiters, err := builtins.RuneSliceOperand(operands[1].Value, 2)
 	if err != nil {
-		return nil, err
+		return handleBuiltinErr(ast.GlobMatch.Name, bctx.Location, err)
 	}
 
-	match, err := builtins.StringOperand(c, 3)
+	match, err := builtins.StringOperand(operands[2].Value, 3)
 	if err != nil {
-		return nil, err
+		return handleBuiltinErr(ast.GlobMatch.Name, bctx.Location, err)
 	}
 
-	id := fmt.Sprintf("%s-%v", pattern, delimiters)
+	cache := interQueryCache(bctx)
+	key := globKey{pattern: string(pattern), delimiters: string(delimiters)}
 
-	globCacheLock.Lock()
-	defer globCacheLock.Unlock()
-	p, ok := globCache[id]
-	if !ok {
-		var err error
+	var p glob.Glob
+	if v, ok := cache.Get(key); ok {
+		p = v.(glob.Glob)
+	} else {
 		if p, err = glob.Compile(string(pattern), delimiters...); err != nil {
-			return nil, err
+			return handleBuiltinErr(ast.GlobMatch.Name, bctx.Location, err)
 		}
-		globCache[id] = p
+		cache.Put(key, p)
 	}
 
-	return ast.Boolean(p.Match(string(match))), nil
+	return iter(ast.BooleanTerm(p.Match(string(match))))
 }
 
 func builtinGlobQuoteMeta(a ast.Value) (ast.Value, error) {
@@ -55,7 +55,6 @@ func builtinGlobQuoteMeta(a ast.Value) (ast.Value, error) {
 }
 
 func init() {
-	globCache = map[string]glob.Glob{}
-	RegisterFunctionalBuiltin3(ast.GlobMatch.Name, builtinGlobMatch)
+	RegisterBuiltinFunc(ast.GlobMatch.Name, builtinGlobMatch)
 	RegisterFunctionalBuiltin1(ast.GlobQuoteMeta.Name, builtinGlobQuoteMeta)
 }
@@ -0,0 +1,109 @@
+// Copyright 2020 The OPA Authors.  All rights reserved.
+// Use of this source code is governed by an Apache2
+// license that can be found in the LICENSE file.
+
+package topdown
+
+import (
+	"github.com/open-policy-agent/opa/ast"
+	"github.com/open-policy-agent/opa/topdown/builtins"
+)
+
+// builtinReachable returns the set of nodes reachable from the initial nodes
+// (including the initial nodes themselves).
+func builtinReachable(a, b ast.Value) (ast.Value, error) {
+
+	graph, err := builtins.ObjectOperand(a, 1)
+	if err != nil {
+		return nil, err
+	}
+
+	var initial []*ast.Term
+
+	switch b := b.(type) {
+	case ast.Set:
+		initial = b.Slice()
+	case ast.Array:
+		initial = b
+	default:
+		return nil, builtins.NewOperandTypeErr(2, b, "set", "array")
+	}
+
+	result := ast.NewSet()
+	return result, reachable(graph, initial, result)
+}
+
+// builtinTransitiveClosure returns an object that maps each node in the graph
+// to the set of nodes reachable from it by following one or more edges.
+func builtinTransitiveClosure(a ast.Value) (ast.Value, error) {
+
+	graph, err := builtins.ObjectOperand(a, 1)
+	if err != nil {
+		return nil, err
+	}
+
+	result := ast.NewObject()
+
+	err = graph.Iter(func(k, v *ast.Term) error {
+		edges, err := graphEdges(graph, v)
+		if err != nil {
+			return err
+		}
+		closure := ast.NewSet()
+		if err := reachable(graph, edges, closure); err != nil {
+			return err
+		}
+		result.Insert(k, ast.NewTerm(closure))
+		return nil
+	})
+
+	return result, err
+}
+
+// reachable adds the nodes in queue and all nodes reachable from them to
+// visited. Nodes that are not keys in graph have no outgoing edges.
+func reachable(graph ast.Object, queue []*ast.Term, visited ast.Set) error {
+
+	queue = append([]*ast.Term(nil), queue...)
+
+	for len(queue) > 0 {
+		node := queue[0]
+		queue = queue[1:]
+
+		if visited.Contains(node) {
+			continue
+		}
+
+		visited.Add(node)
+
+		edges, err := graphEdges(graph, graph.Get(node))
+		if err != nil {
+			return err
+		}
+
+		queue = append(queue, edges...)
+	}
+
+	return nil
+}
+
+func graphEdges(graph ast.Object, edges *ast.Term) ([]*ast.Term, error) {
+	if edges == nil {
+		return nil, nil
+	}
+	switch v := edges.Value.(type) {
+	case ast.Set:
+		return v.Slice(), nil
+	case ast.Array:
+		return v, nil
+	case ast.Null:
+		return nil, nil
+	default:
+		return nil, builtins.NewOperandElementErr(1, graph, v, "set", "array", "null")
+	}
+}
+
+func init() {
+	RegisterFunctionalBuiltin2(ast.GraphReachable.Name, builtinReachable)
+	RegisterFunctionalBuiltin1(ast.GraphTransitiveClosure.Name, builtinTransitiveClosure)
+}
@@ -0,0 +1,60 @@
+// Copyright 2020 The OPA Authors.  All rights reserved.
+// Use of this source code is governed by an Apache2
+// license that can be found in the LICENSE file.
+
+package topdown
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestGraphReachable(t *testing.T) {
+
+	org := `{
+		"admins": ["eng", "ops"],
+		"eng": {"backend", "frontend"},
+		"backend": ["db"],
+		"db": null,
+		"ops": ["admins"]
+	}`
+
+	tests := []struct {
+		note     string
+		rules    []string
+		expected interface{}
+	}{
+		{"empty graph", []string{`p = x { graph.reachable({}, {"a"}, x) }`}, `["a"]`},
+		{"no initial nodes", []string{fmt.Sprintf(`p = x { graph.reachable(%v, set(), x) }`, org)}, `[]`},
+		{"set initial", []string{fmt.Sprintf(`p[y] { graph.reachable(%v, {"eng"}, x); x[y] }`, org)}, `["backend", "db", "eng", "frontend"]`},
+		{"array initial with cycle", []string{fmt.Sprintf(`p[y] { graph.reachable(%v, ["ops"], x); x[y] }`, org)}, `["admins", "backend", "db", "eng", "frontend", "ops"]`},
+		{"non-string nodes", []string{`p[y] { graph.reachable({1: [2], 2: [[3]]}, [1], x); x[y] }`}, `[1, 2, [3]]`},
+		{"bad edges", []string{`p = x { edges = json.unmarshal("{\"a\": \"b\"}"); graph.reachable(edges, ["a"], x) }`}, fmt.Errorf("operand 1 must be object of (any of) {set, array, null} but got object containing string")},
+		{"bad initial", []string{`p = x { initial = json.unmarshal("\"a\""); graph.reachable({}, initial, x) }`}, fmt.Errorf("operand 2 must be one of {set, array}")},
+	}
+
+	data := loadSmallTestData()
+
+	for _, tc := range tests {
+		runTopDownTestCase(t, data, tc.note, tc.rules, tc.expected)
+	}
+}
+
+func TestGraphTransitiveClosure(t *testing.T) {
+
+	tests := []struct {
+		note     string
+		rules    []string
+		expected interface{}
+	}{
+		{"empty graph", []string{`p = x { graph.transitive_closure({}, x) }`}, `{}`},
+		{"chain", []string{`p[[k, y]] { graph.transitive_closure({"a": ["b"], "b": {"c"}, "c": null}, x); x[k][y] }`}, `[["a", "b"], ["a", "c"], ["b", "c"]]`},
+		{"cycle", []string{`p[[k, y]] { graph.transitive_closure({"a": ["b"], "b": ["a"]}, x); x[k][y] }`}, `[["a", "a"], ["a", "b"], ["b", "a"], ["b", "b"]]`},
+	}
+
+	data := loadSmallTestData()
+
+	for _, tc := range tests {
+		runTopDownTestCase(t, data, tc.note, tc.rules, tc.expected)
+	}
+}
@@ -22,6 +22,7 @@ import (
 
 	"github.com/open-policy-agent/opa/ast"
 	"github.com/open-policy-agent/opa/topdown/builtins"
+	"github.com/open-policy-agent/opa/util"
 )
 
 const defaultHTTPRequestTimeout = time.Second * 5
@@ -116,7 +117,7 @@ func addHeaders(req *http.Request, headers map[string]interface{}) (bool, error)
 		if ok {
 			req.Header.Add(k, header)
 		} else {
-			return false, fmt.Errorf("invalid type for headers value")
+			return false, fmt.Errorf("invalid type for headers value %q: must be string but got %v", k, util.TypeName(v))
 		}
 	}
 	return true, nil
@@ -216,7 +217,7 @@ func executeHTTPRequest(bctx BuiltinContext, obj ast.Object) (ast.Value, error)
 			var ok bool
 			customHeaders, ok = headersValInterface.(map[string]interface{})
 			if !ok {
-				return nil, fmt.Errorf("invalid type for headers key")
+				return nil, fmt.Errorf("invalid type for headers key: must be object but got %v", ast.TypeName(headersVal))
 			}
 		default:
 			return nil, fmt.Errorf("invalid parameter %q", key)
@@ -174,6 +174,10 @@ func TestHTTPCustomHeaders(t *testing.T) {
 			`p = x { http.send({"method": "get", "url": "%s", "headers": {"X-Foo": "ISO-8859-1,utf-8;q=0.7,*;q=0.7", "X-Opa": "server"}}, x) }`, ts.URL)}, s},
 		{"http.send custom UA", []string{fmt.Sprintf(
 			`p = x { http.send({"method": "get", "url": "%s", "headers": {"User-Agent": "AuthZPolicy/0.0.1", "X-Opa": "server"}}, x) }`, ts.URL)}, s2},
+		{"http.send bad header value", []string{fmt.Sprintf(
+			`p = x { http.send({"method": "get", "url": "%s", "headers": {"X-Opa": 1}}, x) }`, ts.URL)}, fmt.Errorf(`invalid type for headers value "X-Opa": must be string but got number`)},
+		{"http.send bad headers", []string{fmt.Sprintf(
+			`p = x { http.send({"method": "get", "url": "%s", "headers": ["X-Opa"]}, x) }`, ts.URL)}, fmt.Errorf(`invalid type for headers key: must be object but got array`)},
 	}
 
 	data := loadSmallTestData()
@@ -8,6 +8,8 @@ import "github.com/open-policy-agent/opa/metrics"
 
 const (
 	evalOpPlug                  = "eval_op_plug"
+	evalOpPlugCacheHit          = "eval_op_plug_cache_hit"
+	evalOpPlugCacheMiss         = "eval_op_plug_cache_miss"
 	evalOpResolve               = "eval_op_resolve"
 	evalOpRuleIndex             = "eval_op_rule_index"
 	evalOpBuiltinCall           = "eval_op_builtin_call"
@@ -57,3 +59,23 @@ func (instr *Instrumentation) counterIncr(name string) {
 	}
 	instr.m.Counter(name).Incr()
 }
+
+// evalCounters records cheap counters during evaluation. Unlike
+// instrumentation, the counters are always collected and they are added to
+// the query metrics once evaluation finishes.
+type evalCounters struct {
+	exprs              uint64
+	storageReads       uint64
+	virtualCacheHits   uint64
+	virtualCacheMisses uint64
+}
+
+func (c *evalCounters) flush(m metrics.Metrics) {
+	if m == nil {
+		return
+	}
+	metrics.Add(m.Counter(metrics.RegoEvalExprs), c.exprs)
+	metrics.Add(m.Counter(metrics.RegoEvalStorageReads), c.storageReads)
+	metrics.Add(m.Counter(metrics.RegoEvalVirtualCacheHits), c.virtualCacheHits)
+	metrics.Add(m.Counter(metrics.RegoEvalVirtualCacheMisses), c.virtualCacheMisses)
+}
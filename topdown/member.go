@@ -0,0 +1,57 @@
+// Copyright 2019 The OPA Authors.  All rights reserved.
+// Use of this source code is governed by an Apache2
+// license that can be found in the LICENSE file.
+
+package topdown
+
+import (
+	"github.com/open-policy-agent/opa/ast"
+)
+
+// builtinMember implements the "in" operator. Values that are not
+// collections do not contain any elements.
+func builtinMember(_ BuiltinContext, operands []*ast.Term, iter func(*ast.Term) error) error {
+	value, collection := operands[0], operands[1]
+	switch c := collection.Value.(type) {
+	case ast.Array:
+		for i := range c {
+			if c[i].Equal(value) {
+				return iter(ast.BooleanTerm(true))
+			}
+		}
+	case ast.Object:
+		found := c.Until(func(_, v *ast.Term) bool {
+			return v.Equal(value)
+		})
+		return iter(ast.BooleanTerm(found))
+	case ast.Set:
+		return iter(ast.BooleanTerm(c.Contains(value)))
+	}
+	return iter(ast.BooleanTerm(false))
+}
+
+// builtinMemberWithKey implements the "in" operator with a key. The keys of
+// set elements are the elements themselves.
+func builtinMemberWithKey(_ BuiltinContext, operands []*ast.Term, iter func(*ast.Term) error) error {
+	key, value, collection := operands[0], operands[1], operands[2]
+	switch c := collection.Value.(type) {
+	case ast.Array:
+		if n, ok := key.Value.(ast.Number); ok {
+			if i, ok := n.Int(); ok && i >= 0 && i < len(c) {
+				return iter(ast.BooleanTerm(c[i].Equal(value)))
+			}
+		}
+	case ast.Object:
+		if v := c.Get(key); v != nil {
+			return iter(ast.BooleanTerm(v.Equal(value)))
+		}
+	case ast.Set:
+		return iter(ast.BooleanTerm(key.Equal(value) && c.Contains(value)))
+	}
+	return iter(ast.BooleanTerm(false))
+}
+
+func init() {
+	RegisterBuiltinFunc(ast.Member.Name, builtinMember)
+	RegisterBuiltinFunc(ast.MemberWithKey.Name, builtinMemberWithKey)
+}
@@ -0,0 +1,94 @@
+// Copyright 2020 The OPA Authors.  All rights reserved.
+// Use of this source code is governed by an Apache2
+// license that can be found in the LICENSE file.
+
+package topdown
+
+import (
+	"fmt"
+	"math/big"
+	"regexp"
+	"strings"
+
+	"github.com/open-policy-agent/opa/ast"
+	"github.com/open-policy-agent/opa/topdown/builtins"
+)
+
+// unitSuffixes lists the quantity suffixes recognized by units.parse. Binary
+// suffixes are listed first so that they take precedence over the decimal
+// suffix they end with (e.g., "Mi" vs "i").
+var unitSuffixes = []struct {
+	suffix string
+	value  *big.Rat
+}{
+	{"Ki", new(big.Rat).SetInt64(1 << 10)},
+	{"Mi", new(big.Rat).SetInt64(1 << 20)},
+	{"Gi", new(big.Rat).SetInt64(1 << 30)},
+	{"Ti", new(big.Rat).SetInt64(1 << 40)},
+	{"Pi", new(big.Rat).SetInt64(1 << 50)},
+	{"Ei", new(big.Rat).SetInt64(1 << 60)},
+	{"n", big.NewRat(1, 1e9)},
+	{"u", big.NewRat(1, 1e6)},
+	{"m", big.NewRat(1, 1e3)},
+	{"k", new(big.Rat).SetInt64(1e3)},
+	{"K", new(big.Rat).SetInt64(1e3)},
+	{"M", new(big.Rat).SetInt64(1e6)},
+	{"G", new(big.Rat).SetInt64(1e9)},
+	{"T", new(big.Rat).SetInt64(1e12)},
+	{"P", new(big.Rat).SetInt64(1e15)},
+	{"E", new(big.Rat).SetInt64(1e18)},
+}
+
+var unitAmountRegexp = regexp.MustCompile(`^[+-]?([0-9]+(\.[0-9]*)?|\.[0-9]+)([eE][+-]?[0-9]+)?$`)
+
+func parseUnitsError(msg string) error {
+	return fmt.Errorf("%s error: %s", ast.UnitsParse.Name, msg)
+}
+
+func builtinUnits(a ast.Value) (ast.Value, error) {
+
+	s, err := builtins.StringOperand(a, 1)
+	if err != nil {
+		return nil, err
+	}
+
+	amount, multiplier := string(s), big.NewRat(1, 1)
+
+	for _, u := range unitSuffixes {
+		if strings.HasSuffix(amount, u.suffix) {
+			amount, multiplier = strings.TrimSuffix(amount, u.suffix), u.value
+			break
+		}
+	}
+
+	if amount == "" {
+		return nil, parseUnitsError("no amount provided")
+	}
+
+	if !unitAmountRegexp.MatchString(amount) {
+		return nil, parseUnitsError(fmt.Sprintf("could not parse amount %q", string(s)))
+	}
+
+	r, ok := new(big.Rat).SetString(amount)
+	if !ok {
+		return nil, parseUnitsError(fmt.Sprintf("could not parse amount %q", string(s)))
+	}
+
+	return ratToNumber(r.Mul(r, multiplier)), nil
+}
+
+// ratToNumber converts r to a number. The amounts accepted by units.parse
+// always have a finite decimal representation so no precision is lost.
+func ratToNumber(r *big.Rat) ast.Number {
+	if r.IsInt() {
+		return builtins.IntToNumber(r.Num())
+	}
+	// The denominator is a product of powers of 2 and 5 so its bit length is
+	// a safe upper bound on the number of fractional digits.
+	s := r.FloatString(r.Denom().BitLen())
+	return ast.Number(strings.TrimRight(s, "0"))
+}
+
+func init() {
+	RegisterFunctionalBuiltin1(ast.UnitsParse.Name, builtinUnits)
+}
@@ -0,0 +1,42 @@
+// Copyright 2020 The OPA Authors.  All rights reserved.
+// Use of this source code is governed by an Apache2
+// license that can be found in the LICENSE file.
+
+package topdown
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestUnitsParse(t *testing.T) {
+
+	tests := []struct {
+		note     string
+		rules    []string
+		expected interface{}
+	}{
+		{"plain number", []string{`p = x { x = units.parse("12345") }`}, `12345`},
+		{"decimal", []string{`p = x { x = units.parse("1.5") }`}, `1.5`},
+		{"exponent", []string{`p = x { x = units.parse("1e3") }`}, `1000`},
+		{"binary mebi", []string{`p = x { x = units.parse("512Mi") }`}, `536870912`},
+		{"binary fractional", []string{`p = x { x = units.parse("1.5Gi") }`}, `1610612736`},
+		{"decimal giga", []string{`p = x { x = units.parse("2G") }`}, `2000000000`},
+		{"decimal kilo lowercase", []string{`p = x { x = units.parse("10k") }`}, `10000`},
+		{"exa", []string{`p = x { x = units.parse("1E") }`}, `1000000000000000000`},
+		{"milli", []string{`p = x { x = units.parse("250m") }`}, `0.25`},
+		{"nano", []string{`p = x { x = units.parse("3n") }`}, `0.000000003`},
+		{"negative", []string{`p = x { x = units.parse("-0.5Ki") }`}, `-512`},
+		{"compare", []string{`p { units.parse("500m") < units.parse("1") }`}, `true`},
+		{"no amount", []string{`p = x { x = units.parse("Mi") }`}, fmt.Errorf("units.parse error: no amount provided")},
+		{"unknown suffix", []string{`p = x { x = units.parse("8g") }`}, fmt.Errorf(`units.parse error: could not parse amount "8g"`)},
+		{"spaces", []string{`p = x { x = units.parse("8 Mi") }`}, fmt.Errorf(`units.parse error: could not parse amount "8 Mi"`)},
+		{"dangling exponent", []string{`p = x { x = units.parse("5e") }`}, fmt.Errorf(`units.parse error: could not parse amount "5e"`)},
+	}
+
+	data := loadSmallTestData()
+
+	for _, tc := range tests {
+		runTopDownTestCase(t, data, tc.note, tc.rules, tc.expected)
+	}
+}
@@ -2,7 +2,9 @@ package topdown
 
 import (
 	"context"
+	"io"
 	"sort"
+	"time"
 
 	"github.com/open-policy-agent/opa/ast"
 	"github.com/open-policy-agent/opa/metrics"
@@ -37,6 +39,10 @@ type Query struct {
 	runtime          *ast.Term
 	builtins         map[string]*Builtin
 	indexing         bool
+	time             *ast.Term
+	seed             io.Reader
+	interQueryCache  *builtins.InterQueryCache
+	resultLimit      int
 }
 
 // Builtin represents a built-in function that queries can call.
@@ -99,7 +105,9 @@ func (q *Query) WithTracer(tracer Tracer) *Query {
 	return q
 }
 
-// WithMetrics sets the metrics collection to add evaluation metrics to. This
+// WithMetrics sets the metrics collection to add evaluation metrics to. In
+// addition to timers, evaluation adds counters for the number of expressions
+// evaluated, storage reads, and virtual document cache hits and misses. This
 // is optional.
 func (q *Query) WithMetrics(m metrics.Metrics) *Query {
 	q.metrics = m
@@ -144,6 +152,30 @@ func (q *Query) WithRuntime(runtime *ast.Term) *Query {
 	return q
 }
 
+// WithTime sets the time returned by the time.now_ns built-in function. By
+// default, the current time is used.
+func (q *Query) WithTime(t time.Time) *Query {
+	q.time = ast.NewTerm(ast.Number(int64ToJSONNumber(t.UnixNano())))
+	return q
+}
+
+// WithSeed sets the source of randomness used by built-in functions that
+// generate random values (e.g., rand.intn and uuid.rfc4122). By default,
+// crypto/rand is used. Supplying a deterministic source together with a fixed
+// time makes evaluation reproducible.
+func (q *Query) WithSeed(r io.Reader) *Query {
+	q.seed = r
+	return q
+}
+
+// WithInterQueryBuiltinCache sets the cache that built-in functions use to
+// share state (e.g., compiled regular expressions) across queries. If the
+// cache is not set, a process-wide default cache is used.
+func (q *Query) WithInterQueryBuiltinCache(c *builtins.InterQueryCache) *Query {
+	q.interQueryCache = c
+	return q
+}
+
 // WithBuiltins adds a set of built-in functions that can be called by the
 // query.
 func (q *Query) WithBuiltins(builtins map[string]*Builtin) *Query {
@@ -151,6 +183,15 @@ func (q *Query) WithBuiltins(builtins map[string]*Builtin) *Query {
 	return q
 }
 
+// WithResultLimit sets the maximum number of results the query may produce.
+// If evaluation produces more results, it stops and returns an error with the
+// ResultLimitErr code. If the limit is zero or negative, results are not
+// limited.
+func (q *Query) WithResultLimit(n int) *Query {
+	q.resultLimit = n
+	return q
+}
+
 // WithIndexing will enable or disable using rule indexing for the evaluation
 // of the query. The default is enabled.
 func (q *Query) WithIndexing(enabled bool) *Query {
@@ -170,7 +211,7 @@ func (q *Query) PartialRun(ctx context.Context) (partials []ast.Body, support []
 		q.partialNamespace = "partial" // lazily initialize partial namespace
 	}
 	f := &queryIDFactory{}
-	b := newBindings(0, q.instr)
+	b := newBindings(0, q.instr, newPlugCache(defaultPlugCacheSize))
 	e := &eval{
 		ctx:             ctx,
 		cancel:          q.cancel,
@@ -183,6 +224,7 @@ func (q *Query) PartialRun(ctx context.Context) (partials []ast.Body, support []
 		store:           q.store,
 		baseCache:       newBaseCache(),
 		targetStack:     newRefStack(),
+		functionMocks:   newFunctionMocksStack(),
 		txn:             q.txn,
 		input:           q.input,
 		tracers:         q.tracers,
@@ -198,10 +240,15 @@ func (q *Query) PartialRun(ctx context.Context) (partials []ast.Body, support []
 		genvarprefix:    q.genvarprefix,
 		runtime:         q.runtime,
 		indexing:        q.indexing,
+		time:            q.time,
+		seed:            q.seed,
+		interQueryCache: q.interQueryCache,
+		counters:        &evalCounters{},
 	}
 	e.caller = e
 	q.startTimer(metrics.RegoPartialEval)
 	defer q.stopTimer(metrics.RegoPartialEval)
+	defer e.counters.flush(q.metrics)
 
 	livevars := ast.NewVarSet()
 
@@ -240,6 +287,10 @@ func (q *Query) PartialRun(ctx context.Context) (partials []ast.Body, support []
 			body.Append(bindingExprs[i])
 		}
 
+		if q.resultLimit > 0 && len(partials) >= q.resultLimit {
+			return resultLimitErr(q.resultLimit)
+		}
+
 		partials = append(partials, applyCopyPropagation(p, e.instr, body))
 		return nil
 	})
@@ -262,33 +313,79 @@ func (q *Query) Run(ctx context.Context) (QueryResultSet, error) {
 // Iter executes the query and invokes the iter function with query results
 // produced by evaluating the query.
 func (q *Query) Iter(ctx context.Context, iter func(QueryResult) error) error {
+	return q.iter(q.newEval(ctx, q.input, newBaseCache(), builtins.Cache{}, newPlugCache(defaultPlugCacheSize)), iter)
+}
+
+// IterBatch executes the query once for each of the inputs and invokes the
+// iter function with the index of the input and the query results produced by
+// evaluating the query against it. The input set on the query is ignored.
+// Caches that do not depend on the input (i.e., base documents read from
+// storage, built-in function results, and the plug cache) are shared across
+// the inputs so evaluating a batch is cheaper than evaluating the query once
+// per input. If evaluation fails for an input, the remaining inputs are not
+// evaluated.
+func (q *Query) IterBatch(ctx context.Context, inputs []*ast.Term, iter func(int, QueryResult) error) error {
+	baseCache := newBaseCache()
+	builtinCache := builtins.Cache{}
+	plugs := newPlugCache(defaultPlugCacheSize)
+
+	for i := range inputs {
+		// Plugged terms are cached per binding list so they cannot be reused
+		// across inputs.
+		plugs.Invalidate()
+		e := q.newEval(ctx, inputs[i], baseCache, builtinCache, plugs)
+		if err := q.iter(e, func(qr QueryResult) error {
+			return iter(i, qr)
+		}); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (q *Query) newEval(ctx context.Context, input *ast.Term, baseCache *baseCache, builtinCache builtins.Cache, plugs *plugCache) *eval {
 	f := &queryIDFactory{}
 	e := &eval{
-		ctx:           ctx,
-		cancel:        q.cancel,
-		query:         q.query,
-		queryCompiler: q.queryCompiler,
-		queryIDFact:   f,
-		queryID:       f.Next(),
-		bindings:      newBindings(0, q.instr),
-		compiler:      q.compiler,
-		store:         q.store,
-		baseCache:     newBaseCache(),
-		targetStack:   newRefStack(),
-		txn:           q.txn,
-		input:         q.input,
-		tracers:       q.tracers,
-		instr:         q.instr,
-		builtins:      q.builtins,
-		builtinCache:  builtins.Cache{},
-		virtualCache:  newVirtualCache(),
-		genvarprefix:  q.genvarprefix,
-		runtime:       q.runtime,
-		indexing:      q.indexing,
+		ctx:             ctx,
+		cancel:          q.cancel,
+		query:           q.query,
+		queryCompiler:   q.queryCompiler,
+		queryIDFact:     f,
+		queryID:         f.Next(),
+		bindings:        newBindings(0, q.instr, plugs),
+		compiler:        q.compiler,
+		store:           q.store,
+		baseCache:       baseCache,
+		targetStack:     newRefStack(),
+		functionMocks:   newFunctionMocksStack(),
+		txn:             q.txn,
+		input:           input,
+		tracers:         q.tracers,
+		instr:           q.instr,
+		builtins:        q.builtins,
+		builtinCache:    builtinCache,
+		virtualCache:    newVirtualCache(),
+		genvarprefix:    q.genvarprefix,
+		runtime:         q.runtime,
+		indexing:        q.indexing,
+		time:            q.time,
+		seed:            q.seed,
+		interQueryCache: q.interQueryCache,
+		counters:        &evalCounters{},
 	}
 	e.caller = e
+	return e
+}
+
+func (q *Query) iter(e *eval, iter func(QueryResult) error) error {
 	q.startTimer(metrics.RegoQueryEval)
+	var n int
 	err := e.Run(func(e *eval) error {
+		n++
+		if q.resultLimit > 0 && n > q.resultLimit {
+			return resultLimitErr(q.resultLimit)
+		}
 		qr := QueryResult{}
 		e.bindings.Iter(nil, func(k, v *ast.Term) error {
 			qr[k.Value.(ast.Var)] = v
@@ -297,6 +394,7 @@ func (q *Query) Iter(ctx context.Context, iter func(QueryResult) error) error {
 		return iter(qr)
 	})
 	q.stopTimer(metrics.RegoQueryEval)
+	e.counters.flush(q.metrics)
 	return err
 }
 
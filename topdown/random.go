@@ -0,0 +1,107 @@
+// Copyright 2019 The OPA Authors.  All rights reserved.
+// Use of this source code is governed by an Apache2
+// license that can be found in the LICENSE file.
+
+package topdown
+
+import (
+	"crypto/rand"
+	"encoding/binary"
+	"fmt"
+	"io"
+	mathrand "math/rand"
+
+	"github.com/open-policy-agent/opa/ast"
+	"github.com/open-policy-agent/opa/topdown/builtins"
+)
+
+type randIntnKey string
+
+type uuidKey string
+
+func builtinRandIntn(bctx BuiltinContext, args []*ast.Term, iter func(*ast.Term) error) error {
+
+	str, err := builtins.StringOperand(args[0].Value, 1)
+	if err != nil {
+		return err
+	}
+
+	n, err := builtins.IntOperand(args[1].Value, 2)
+	if err != nil {
+		return err
+	}
+
+	if n == 0 {
+		return iter(ast.IntNumberTerm(0))
+	}
+
+	if n < 0 {
+		n = -n
+	}
+
+	key := randIntnKey(fmt.Sprintf("%s-%d", str, n))
+
+	if exist, ok := bctx.Cache.Get(key); ok {
+		return iter(exist.(*ast.Term))
+	}
+
+	r, err := randomSource(bctx)
+	if err != nil {
+		return err
+	}
+
+	result := ast.IntNumberTerm(r.Intn(n))
+	bctx.Cache.Put(key, result)
+
+	return iter(result)
+}
+
+func builtinUUIDRFC4122(bctx BuiltinContext, args []*ast.Term, iter func(*ast.Term) error) error {
+
+	str, err := builtins.StringOperand(args[0].Value, 1)
+	if err != nil {
+		return err
+	}
+
+	key := uuidKey(str)
+
+	if exist, ok := bctx.Cache.Get(key); ok {
+		return iter(exist.(*ast.Term))
+	}
+
+	var u [16]byte
+
+	if _, err := io.ReadFull(seed(bctx), u[:]); err != nil {
+		return err
+	}
+
+	u[6] = (u[6] & 0x0f) | 0x40 // version 4
+	u[8] = (u[8] & 0x3f) | 0x80 // variant 10
+
+	result := ast.StringTerm(fmt.Sprintf("%x-%x-%x-%x-%x", u[0:4], u[4:6], u[6:8], u[8:10], u[10:]))
+	bctx.Cache.Put(key, result)
+
+	return iter(result)
+}
+
+// randomSource returns a pseudo-random number generator seeded from the
+// built-in context's source of randomness.
+func randomSource(bctx BuiltinContext) (*mathrand.Rand, error) {
+	var buf [8]byte
+	if _, err := io.ReadFull(seed(bctx), buf[:]); err != nil {
+		return nil, err
+	}
+	return mathrand.New(mathrand.NewSource(int64(binary.BigEndian.Uint64(buf[:])))), nil
+}
+
+func seed(bctx BuiltinContext) io.Reader {
+	if bctx.Seed != nil {
+		return bctx.Seed
+	}
+	return rand.Reader
+}
+
+func init() {
+	RegisterBuiltinFunc(ast.RandIntn.Name, builtinRandIntn)
+	RegisterBuiltinFunc(ast.UUIDRFC4122.Name, builtinUUIDRFC4122)
+}
@@ -0,0 +1,96 @@
+// Copyright 2019 The OPA Authors.  All rights reserved.
+// Use of this source code is governed by an Apache2
+// license that can be found in the LICENSE file.
+
+package topdown
+
+import (
+	"context"
+	"math/rand"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/open-policy-agent/opa/ast"
+)
+
+func TestRandIntn(t *testing.T) {
+
+	ctx := context.Background()
+	q := NewQuery(ast.MustParseBody(`rand.intn("a", 10, x); rand.intn("a", 10, y); rand.intn("b", -5, z); rand.intn("c", 0, w)`))
+
+	rs, err := q.Run(ctx)
+	if err != nil {
+		t.Fatal(err)
+	} else if len(rs) != 1 {
+		t.Fatal("Expected result set to contain exactly one result")
+	}
+
+	x, y := rs[0][ast.Var("x")], rs[0][ast.Var("y")]
+	if !x.Equal(y) {
+		t.Fatalf("Expected same number for same operands but got %v and %v", x, y)
+	}
+
+	for _, v := range []ast.Var{"x", "z"} {
+		n, ok := rs[0][v].Value.(ast.Number).Int()
+		if !ok || n < 0 || n >= 10 {
+			t.Fatalf("Unexpected number for %v: %v", v, rs[0][v])
+		}
+	}
+
+	if w := rs[0][ast.Var("w")]; !w.Equal(ast.IntNumberTerm(0)) {
+		t.Fatalf("Expected zero but got %v", w)
+	}
+}
+
+func TestUUIDRFC4122(t *testing.T) {
+
+	ctx := context.Background()
+	q := NewQuery(ast.MustParseBody(`uuid.rfc4122("a", x); uuid.rfc4122("a", y); uuid.rfc4122("b", z)`))
+
+	rs, err := q.Run(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	x, y, z := rs[0][ast.Var("x")], rs[0][ast.Var("y")], rs[0][ast.Var("z")]
+
+	if !x.Equal(y) || x.Equal(z) {
+		t.Fatalf("Expected UUIDs to be identified by operand but got %v, %v, %v", x, y, z)
+	}
+
+	pattern := regexp.MustCompile(`^[0-9a-f]{8}-[0-9a-f]{4}-4[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}$`)
+
+	if !pattern.MatchString(string(x.Value.(ast.String))) {
+		t.Fatalf("Expected version 4 UUID but got %v", x)
+	}
+}
+
+func TestDeterministicEvaluation(t *testing.T) {
+
+	ctx := context.Background()
+	now := time.Date(2019, 11, 1, 0, 0, 0, 0, time.UTC)
+
+	eval := func() QueryResult {
+		q := NewQuery(ast.MustParseBody(`time.now_ns(t); rand.intn("a", 1000000, x); uuid.rfc4122("b", y)`)).
+			WithTime(now).
+			WithSeed(rand.New(rand.NewSource(7)))
+		rs, err := q.Run(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return rs[0]
+	}
+
+	a, b := eval(), eval()
+
+	for _, v := range []ast.Var{"t", "x", "y"} {
+		if !a[v].Equal(b[v]) {
+			t.Fatalf("Expected %v to be equal across evaluations but got %v and %v", v, a[v], b[v])
+		}
+	}
+
+	if exp := ast.IntNumberTerm(int(now.UnixNano())); !a["t"].Equal(exp) {
+		t.Fatalf("Expected time %v but got %v", exp, a["t"])
+	}
+}
@@ -7,7 +7,6 @@ package topdown
 import (
 	"fmt"
 	"regexp"
-	"sync"
 
 	"github.com/yashtewari/glob-intersection"
 
@@ -15,67 +14,75 @@ import (
 	"github.com/open-policy-agent/opa/topdown/builtins"
 )
 
-var regexpCacheLock = sync.Mutex{}
-var regexpCache map[string]*regexp.Regexp
+// regexpKey and regexpTemplateKey identify compiled patterns in the
+// inter-query cache. Templates are keyed separately because the same pattern
+// string compiles differently depending on the delimiters.
+type regexpKey string
 
-func builtinRegexMatch(a, b ast.Value) (ast.Value, error) {
-	s1, err := builtins.StringOperand(a, 1)
+type regexpTemplateKey struct {
+	pattern    string
+	delimStart byte
+	delimEnd   byte
+}
+
+func builtinRegexMatch(bctx BuiltinContext, operands []*ast.Term, iter func(*ast.Term) error) error {
+	s1, err := builtins.StringOperand(operands[0].Value, 1)
 	if err != nil {
-		return nil, err
+		return handleBuiltinErr(ast.RegexMatch.Name, bctx.Location, err)
 	}
-	s2, err := builtins.StringOperand(b, 2)
+	s2, err := builtins.StringOperand(operands[1].Value, 2)
 	if err != nil {
-		return nil, err
+		return handleBuiltinErr(ast.RegexMatch.Name, bctx.Location, err)
 	}
-	re, err := getRegexp(string(s1))
+	re, err := getRegexp(bctx, string(s1))
 	if err != nil {
-		return nil, err
+		return handleBuiltinErr(ast.RegexMatch.Name, bctx.Location, err)
 	}
-	return ast.Boolean(re.Match([]byte(s2))), nil
+	return iter(ast.BooleanTerm(re.Match([]byte(s2))))
 }
 
-func builtinRegexMatchTemplate(a, b, c, d ast.Value) (ast.Value, error) {
-	pattern, err := builtins.StringOperand(a, 1)
+func builtinRegexMatchTemplate(bctx BuiltinContext, operands []*ast.Term, iter func(*ast.Term) error) error {
+	pattern, err := builtins.StringOperand(operands[0].Value, 1)
 	if err != nil {
-		return nil, err
+		return handleBuiltinErr(ast.RegexTemplateMatch.Name, bctx.Location, err)
 	}
-	match, err := builtins.StringOperand(b, 2)
+	match, err := builtins.StringOperand(operands[1].Value, 2)
 	if err != nil {
-		return nil, err
+		return handleBuiltinErr(ast.RegexTemplateMatch.Name, bctx.Location, err)
 	}
-	start, err := builtins.StringOperand(c, 3)
+	start, err := builtins.StringOperand(operands[2].Value, 3)
 	if err != nil {
-		return nil, err
+		return handleBuiltinErr(ast.RegexTemplateMatch.Name, bctx.Location, err)
 	}
-	end, err := builtins.StringOperand(d, 4)
+	end, err := builtins.StringOperand(operands[3].Value, 4)
 	if err != nil {
-		return nil, err
+		return handleBuiltinErr(ast.RegexTemplateMatch.Name, bctx.Location, err)
 	}
 	if len(start) != 1 {
-		return nil, fmt.Errorf("start delimiter has to be exactly one character long but is %d long", len(start))
+		return handleBuiltinErr(ast.RegexTemplateMatch.Name, bctx.Location, fmt.Errorf("start delimiter has to be exactly one character long but is %d long", len(start)))
 	}
 	if len(end) != 1 {
-		return nil, fmt.Errorf("end delimiter has to be exactly one character long but is %d long", len(start))
+		return handleBuiltinErr(ast.RegexTemplateMatch.Name, bctx.Location, fmt.Errorf("end delimiter has to be exactly one character long but is %d long", len(end)))
 	}
-	re, err := getRegexpTemplate(string(pattern), string(start)[0], string(end)[0])
+	re, err := getRegexpTemplate(bctx, string(pattern), string(start)[0], string(end)[0])
 	if err != nil {
-		return nil, err
+		return handleBuiltinErr(ast.RegexTemplateMatch.Name, bctx.Location, err)
 	}
-	return ast.Boolean(re.MatchString(string(match))), nil
+	return iter(ast.BooleanTerm(re.MatchString(string(match))))
 }
 
-func builtinRegexSplit(a, b ast.Value) (ast.Value, error) {
-	s1, err := builtins.StringOperand(a, 1)
+func builtinRegexSplit(bctx BuiltinContext, operands []*ast.Term, iter func(*ast.Term) error) error {
+	s1, err := builtins.StringOperand(operands[0].Value, 1)
 	if err != nil {
-		return nil, err
+		return handleBuiltinErr(ast.RegexSplit.Name, bctx.Location, err)
 	}
-	s2, err := builtins.StringOperand(b, 2)
+	s2, err := builtins.StringOperand(operands[1].Value, 2)
 	if err != nil {
-		return nil, err
+		return handleBuiltinErr(ast.RegexSplit.Name, bctx.Location, err)
 	}
-	re, err := getRegexp(string(s1))
+	re, err := getRegexp(bctx, string(s1))
 	if err != nil {
-		return nil, err
+		return handleBuiltinErr(ast.RegexSplit.Name, bctx.Location, err)
 	}
 
 	elems := re.Split(string(s2), -1)
@@ -83,36 +90,33 @@ func builtinRegexSplit(a, b ast.Value) (ast.Value, error) {
 	for i := range arr {
 		arr[i] = ast.StringTerm(elems[i])
 	}
-	return arr, nil
+	return iter(ast.NewTerm(arr))
 }
 
-func getRegexp(pat string) (*regexp.Regexp, error) {
-	regexpCacheLock.Lock()
-	defer regexpCacheLock.Unlock()
-	re, ok := regexpCache[pat]
-	if !ok {
-		var err error
-		re, err = regexp.Compile(string(pat))
-		if err != nil {
-			return nil, err
-		}
-		regexpCache[pat] = re
+func getRegexp(bctx BuiltinContext, pat string) (*regexp.Regexp, error) {
+	cache := interQueryCache(bctx)
+	if re, ok := cache.Get(regexpKey(pat)); ok {
+		return re.(*regexp.Regexp), nil
 	}
+	re, err := regexp.Compile(pat)
+	if err != nil {
+		return nil, err
+	}
+	cache.Put(regexpKey(pat), re)
 	return re, nil
 }
 
-func getRegexpTemplate(pat string, delimStart, delimEnd byte) (*regexp.Regexp, error) {
-	regexpCacheLock.Lock()
-	defer regexpCacheLock.Unlock()
-	re, ok := regexpCache[pat]
-	if !ok {
-		var err error
-		re, err = compileRegexTemplate(string(pat), delimStart, delimEnd)
-		if err != nil {
-			return nil, err
-		}
-		regexpCache[pat] = re
+func getRegexpTemplate(bctx BuiltinContext, pat string, delimStart, delimEnd byte) (*regexp.Regexp, error) {
+	cache := interQueryCache(bctx)
+	key := regexpTemplateKey{pattern: pat, delimStart: delimStart, delimEnd: delimEnd}
+	if re, ok := cache.Get(key); ok {
+		return re.(*regexp.Regexp), nil
 	}
+	re, err := compileRegexTemplate(pat, delimStart, delimEnd)
+	if err != nil {
+		return nil, err
+	}
+	cache.Put(key, re)
 	return re, nil
 }
 
@@ -132,22 +136,22 @@ func builtinGlobsMatch(a, b ast.Value) (ast.Value, error) {
 	return ast.Boolean(ne), nil
 }
 
-func builtinRegexFind(a, b, c ast.Value) (ast.Value, error) {
-	s1, err := builtins.StringOperand(a, 1)
+func builtinRegexFind(bctx BuiltinContext, operands []*ast.Term, iter func(*ast.Term) error) error {
+	s1, err := builtins.StringOperand(operands[0].Value, 1)
 	if err != nil {
-		return nil, err
+		return handleBuiltinErr(ast.RegexFind.Name, bctx.Location, err)
 	}
-	s2, err := builtins.StringOperand(b, 2)
+	s2, err := builtins.StringOperand(operands[1].Value, 2)
 	if err != nil {
-		return nil, err
+		return handleBuiltinErr(ast.RegexFind.Name, bctx.Location, err)
 	}
-	n, err := builtins.IntOperand(c, 3)
+	n, err := builtins.IntOperand(operands[2].Value, 3)
 	if err != nil {
-		return nil, err
+		return handleBuiltinErr(ast.RegexFind.Name, bctx.Location, err)
 	}
-	re, err := getRegexp(string(s1))
+	re, err := getRegexp(bctx, string(s1))
 	if err != nil {
-		return nil, err
+		return handleBuiltinErr(ast.RegexFind.Name, bctx.Location, err)
 	}
 
 	elems := re.FindAllString(string(s2), n)
@@ -155,26 +159,26 @@ func builtinRegexFind(a, b, c ast.Value) (ast.Value, error) {
 	for i := range arr {
 		arr[i] = ast.StringTerm(elems[i])
 	}
-	return arr, nil
+	return iter(ast.NewTerm(arr))
 }
 
-func builtinRegexFindAllStringSubmatch(a, b, c ast.Value) (ast.Value, error) {
-	s1, err := builtins.StringOperand(a, 1)
+func builtinRegexFindAllStringSubmatch(bctx BuiltinContext, operands []*ast.Term, iter func(*ast.Term) error) error {
+	s1, err := builtins.StringOperand(operands[0].Value, 1)
 	if err != nil {
-		return nil, err
+		return handleBuiltinErr(ast.RegexFindAllStringSubmatch.Name, bctx.Location, err)
 	}
-	s2, err := builtins.StringOperand(b, 2)
+	s2, err := builtins.StringOperand(operands[1].Value, 2)
 	if err != nil {
-		return nil, err
+		return handleBuiltinErr(ast.RegexFindAllStringSubmatch.Name, bctx.Location, err)
 	}
-	n, err := builtins.IntOperand(c, 3)
+	n, err := builtins.IntOperand(operands[2].Value, 3)
 	if err != nil {
-		return nil, err
+		return handleBuiltinErr(ast.RegexFindAllStringSubmatch.Name, bctx.Location, err)
 	}
 
-	re, err := getRegexp(string(s1))
+	re, err := getRegexp(bctx, string(s1))
 	if err != nil {
-		return nil, err
+		return handleBuiltinErr(ast.RegexFindAllStringSubmatch.Name, bctx.Location, err)
 	}
 	matches := re.FindAllStringSubmatch(string(s2), n)
 
@@ -187,15 +191,14 @@ func builtinRegexFindAllStringSubmatch(a, b, c ast.Value) (ast.Value, error) {
 		outer[i] = ast.ArrayTerm(inner...)
 	}
 
-	return outer, nil
+	return iter(ast.NewTerm(outer))
 }
 
 func init() {
-	regexpCache = map[string]*regexp.Regexp{}
-	RegisterFunctionalBuiltin2(ast.RegexMatch.Name, builtinRegexMatch)
-	RegisterFunctionalBuiltin2(ast.RegexSplit.Name, builtinRegexSplit)
+	RegisterBuiltinFunc(ast.RegexMatch.Name, builtinRegexMatch)
+	RegisterBuiltinFunc(ast.RegexSplit.Name, builtinRegexSplit)
 	RegisterFunctionalBuiltin2(ast.GlobsMatch.Name, builtinGlobsMatch)
-	RegisterFunctionalBuiltin4(ast.RegexTemplateMatch.Name, builtinRegexMatchTemplate)
-	RegisterFunctionalBuiltin3(ast.RegexFind.Name, builtinRegexFind)
-	RegisterFunctionalBuiltin3(ast.RegexFindAllStringSubmatch.Name, builtinRegexFindAllStringSubmatch)
+	RegisterBuiltinFunc(ast.RegexTemplateMatch.Name, builtinRegexMatchTemplate)
+	RegisterBuiltinFunc(ast.RegexFind.Name, builtinRegexFind)
+	RegisterBuiltinFunc(ast.RegexFindAllStringSubmatch.Name, builtinRegexFindAllStringSubmatch)
 }
@@ -1,6 +1,12 @@
 package topdown
 
-import "testing"
+import (
+	"context"
+	"testing"
+
+	"github.com/open-policy-agent/opa/ast"
+	"github.com/open-policy-agent/opa/topdown/builtins"
+)
 
 func TestRegexMatchTemplate(t *testing.T) {
 	tests := []struct {
@@ -52,3 +58,34 @@ func TestRegexFindAllStringSubmatch(t *testing.T) {
 		runTopDownTestCase(t, map[string]interface{}{}, tc.note, tc.rules, tc.expected)
 	}
 }
+
+func TestRegexInterQueryCache(t *testing.T) {
+
+	ctx := context.Background()
+	query := ast.MustParseBody(`re_match("a.c", "abc"); regex.template_match("a.c", "a.c", "{", "}", true); glob.match("a.c", ["."], "a.c")`)
+
+	c1, c2 := builtins.NewInterQueryCache(0), builtins.NewInterQueryCache(0)
+
+	for i := 0; i < 2; i++ {
+		rs, err := NewQuery(query).WithInterQueryBuiltinCache(c1).Run(ctx)
+		if err != nil {
+			t.Fatal(err)
+		} else if len(rs) != 1 {
+			t.Fatalf("Expected one result but got: %v", rs)
+		}
+	}
+
+	// The plain and template patterns must not share an entry even though the
+	// pattern strings are identical.
+	if c1.Len() != 3 {
+		t.Fatalf("Expected 3 cached patterns but got %d", c1.Len())
+	}
+
+	if c2.Len() != 0 {
+		t.Fatalf("Expected unused cache to be empty but got %d entries", c2.Len())
+	}
+
+	if _, ok := c1.Get(regexpKey("a.c")); !ok {
+		t.Fatal("Expected compiled regexp in cache")
+	}
+}
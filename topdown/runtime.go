@@ -4,7 +4,13 @@
 
 package topdown
 
-import "github.com/open-policy-agent/opa/ast"
+import (
+	"encoding/json"
+	"strconv"
+
+	"github.com/open-policy-agent/opa/ast"
+	"github.com/open-policy-agent/opa/storage"
+)
 
 func builtinOPARuntime(bctx BuiltinContext, _ []*ast.Term, iter func(*ast.Term) error) error {
 
@@ -15,6 +21,23 @@ func builtinOPARuntime(bctx BuiltinContext, _ []*ast.Term, iter func(*ast.Term)
 	return iter(bctx.Runtime)
 }
 
+func builtinOPAStoreRevision(bctx BuiltinContext, _ []*ast.Term, iter func(*ast.Term) error) error {
+
+	if bctx.Store == nil {
+		return nil
+	}
+
+	rev, ok, err := storage.Revision(bctx.Context, bctx.Store, bctx.Txn)
+	if err != nil {
+		return handleBuiltinErr(ast.OPAStoreRevision.Name, bctx.Location, err)
+	} else if !ok {
+		return nil
+	}
+
+	return iter(ast.NumberTerm(json.Number(strconv.FormatUint(rev, 10))))
+}
+
 func init() {
 	RegisterBuiltinFunc(ast.OPARuntime.Name, builtinOPARuntime)
+	RegisterBuiltinFunc(ast.OPAStoreRevision.Name, builtinOPAStoreRevision)
 }
@@ -8,6 +8,8 @@ import (
 	"testing"
 
 	"github.com/open-policy-agent/opa/ast"
+	"github.com/open-policy-agent/opa/storage"
+	"github.com/open-policy-agent/opa/storage/inmem"
 )
 
 func TestOPARuntime(t *testing.T) {
@@ -44,3 +46,47 @@ func TestOPARuntime(t *testing.T) {
 	}
 
 }
+
+func TestOPAStoreRevision(t *testing.T) {
+
+	ctx := context.Background()
+	q := NewQuery(ast.MustParseBody("opa.store_revision(x)")) // no store
+	rs, err := q.Run(ctx)
+	if err != nil {
+		t.Fatal(err)
+	} else if len(rs) != 0 {
+		t.Fatalf("Expected undefined result but got: %v", rs)
+	}
+
+	store := inmem.New()
+
+	for i := 0; i < 2; i++ {
+		err := storage.Txn(ctx, store, storage.WriteParams, func(txn storage.Transaction) error {
+			return store.Write(ctx, txn, storage.AddOp, storage.MustParsePath("/x"), i)
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	txn := storage.NewTransactionOrDie(ctx, store)
+	defer store.Abort(ctx, txn)
+
+	q = NewQuery(ast.MustParseBody("opa.store_revision(x)")).
+		WithCompiler(ast.NewCompiler()).
+		WithStore(store).
+		WithTransaction(txn)
+	rs, err = q.Run(ctx)
+	if err != nil {
+		t.Fatal(err)
+	} else if len(rs) != 1 {
+		t.Fatal("Expected result set to contain exactly one result")
+	}
+
+	term := rs[0][ast.Var("x")]
+	exp := ast.IntNumberTerm(2)
+
+	if ast.Compare(term, exp) != 0 {
+		t.Fatalf("Expected %v but got %v", exp, term)
+	}
+}
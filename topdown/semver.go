@@ -0,0 +1,57 @@
+// Copyright 2020 The OPA Authors.  All rights reserved.
+// Use of this source code is governed by an Apache2
+// license that can be found in the LICENSE file.
+
+package topdown
+
+import (
+	"fmt"
+
+	"github.com/open-policy-agent/opa/ast"
+	"github.com/open-policy-agent/opa/internal/semver"
+	"github.com/open-policy-agent/opa/topdown/builtins"
+)
+
+func builtinSemVerCompare(a, b ast.Value) (ast.Value, error) {
+
+	v1, err := semverOperand(a, 1)
+	if err != nil {
+		return nil, err
+	}
+
+	v2, err := semverOperand(b, 2)
+	if err != nil {
+		return nil, err
+	}
+
+	return ast.IntNumberTerm(v1.Compare(v2)).Value, nil
+}
+
+func builtinSemVerIsValid(a ast.Value) (ast.Value, error) {
+	s, ok := a.(ast.String)
+	if !ok {
+		return ast.Boolean(false), nil
+	}
+	_, err := semver.Parse(string(s))
+	return ast.Boolean(err == nil), nil
+}
+
+func semverOperand(x ast.Value, pos int) (*semver.Version, error) {
+
+	s, err := builtins.StringOperand(x, pos)
+	if err != nil {
+		return nil, err
+	}
+
+	v, err := semver.Parse(string(s))
+	if err != nil {
+		return nil, fmt.Errorf("operand %d: string %v is not a valid SemVer", pos, s)
+	}
+
+	return v, nil
+}
+
+func init() {
+	RegisterFunctionalBuiltin2(ast.SemVerCompare.Name, builtinSemVerCompare)
+	RegisterFunctionalBuiltin1(ast.SemVerIsValid.Name, builtinSemVerIsValid)
+}
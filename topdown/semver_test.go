@@ -0,0 +1,57 @@
+// Copyright 2020 The OPA Authors.  All rights reserved.
+// Use of this source code is governed by an Apache2
+// license that can be found in the LICENSE file.
+
+package topdown
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestSemVerIsValid(t *testing.T) {
+
+	tests := []struct {
+		note     string
+		rules    []string
+		expected interface{}
+	}{
+		{"valid", []string{`p = x { x = semver.is_valid("1.0.0") }`}, `true`},
+		{"pre-release and metadata", []string{`p = x { x = semver.is_valid("1.0.0-rc.1+build.5") }`}, `true`},
+		{"prefix", []string{`p = x { x = semver.is_valid("v1.0.0") }`}, `false`},
+		{"missing patch", []string{`p = x { x = semver.is_valid("1.0") }`}, `false`},
+		{"leading zero", []string{`p = x { x = semver.is_valid("1.01.0") }`}, `false`},
+		{"non-string", []string{`p = x { x = semver.is_valid(1) }`}, `false`},
+		{"array", []string{`p = x { x = semver.is_valid(["1.0.0"]) }`}, `false`},
+	}
+
+	data := loadSmallTestData()
+
+	for _, tc := range tests {
+		runTopDownTestCase(t, data, tc.note, tc.rules, tc.expected)
+	}
+}
+
+func TestSemVerCompare(t *testing.T) {
+
+	tests := []struct {
+		note     string
+		rules    []string
+		expected interface{}
+	}{
+		{"equal", []string{`p = x { x = semver.compare("1.0.0", "1.0.0") }`}, `0`},
+		{"less", []string{`p = x { x = semver.compare("1.9.0", "1.10.0") }`}, `-1`},
+		{"greater", []string{`p = x { x = semver.compare("2.0.0", "1.10.0") }`}, `1`},
+		{"pre-release lower", []string{`p = x { x = semver.compare("1.0.0-rc.1", "1.0.0") }`}, `-1`},
+		{"pre-release numeric", []string{`p = x { x = semver.compare("1.0.0-beta.11", "1.0.0-beta.2") }`}, `1`},
+		{"metadata ignored", []string{`p = x { x = semver.compare("1.0.0+a", "1.0.0+b") }`}, `0`},
+		{"invalid first", []string{`p = x { x = semver.compare("1.0", "1.0.0") }`}, fmt.Errorf("operand 1: string \"1.0\" is not a valid SemVer")},
+		{"invalid second", []string{`p = x { x = semver.compare("1.0.0", "latest") }`}, fmt.Errorf("operand 2: string \"latest\" is not a valid SemVer")},
+	}
+
+	data := loadSmallTestData()
+
+	for _, tc := range tests {
+		runTopDownTestCase(t, data, tc.note, tc.rules, tc.expected)
+	}
+}
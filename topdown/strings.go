@@ -8,6 +8,7 @@ import (
 	"errors"
 	"fmt"
 	"strings"
+	"unicode/utf8"
 
 	"github.com/open-policy-agent/opa/ast"
 	"github.com/open-policy-agent/opa/topdown/builtins"
@@ -93,17 +94,25 @@ func builtinIndexOf(a, b ast.Value) (ast.Value, error) {
 		return nil, err
 	}
 
+	// Report the index in runes (not bytes) so that it can be used with
+	// substring.
 	index := strings.Index(string(base), string(search))
+	if index > 0 {
+		index = utf8.RuneCountInString(string(base[:index]))
+	}
 	return ast.IntNumberTerm(index).Value, nil
 }
 
 func builtinSubstring(a, b, c ast.Value) (ast.Value, error) {
 
-	base, err := builtins.StringOperand(a, 1)
+	s, err := builtins.StringOperand(a, 1)
 	if err != nil {
 		return nil, err
 	}
 
+	// Offsets and lengths are in runes (not bytes).
+	base := []rune(string(s))
+
 	startIndex, err := builtins.IntOperand(b, 2)
 	if err != nil {
 		return nil, err
@@ -118,18 +127,16 @@ func builtinSubstring(a, b, c ast.Value) (ast.Value, error) {
 		return nil, err
 	}
 
-	var s ast.String
 	if length < 0 {
-		s = ast.String(base[startIndex:])
-	} else {
-		upto := startIndex + length
-		if len(base) < upto {
-			upto = len(base)
-		}
-		s = ast.String(base[startIndex:upto])
+		return ast.String(base[startIndex:]), nil
+	}
+
+	upto := startIndex + length
+	if len(base) < upto {
+		upto = len(base)
 	}
 
-	return s, nil
+	return ast.String(base[startIndex:upto]), nil
 }
 
 func builtinContains(a, b ast.Value) (ast.Value, error) {
@@ -355,6 +362,8 @@ func builtinSprintf(a, b ast.Value) (ast.Value, error) {
 		case ast.Number:
 			if n, ok := v.Int(); ok {
 				args[i] = n
+			} else if b, err := builtins.NumberToInt(v); err == nil {
+				args[i] = b
 			} else if f, ok := v.Float64(); ok {
 				args[i] = f
 			} else {
@@ -24,6 +24,10 @@ var tzCacheMutex *sync.Mutex
 
 func builtinTimeNowNanos(bctx BuiltinContext, _ []*ast.Term, iter func(*ast.Term) error) error {
 
+	if bctx.Time != nil {
+		return iter(bctx.Time)
+	}
+
 	exist, ok := bctx.Cache.Get(nowKey)
 	var now *ast.Term
 
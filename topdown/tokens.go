@@ -16,6 +16,7 @@ import (
 	"encoding/pem"
 	"fmt"
 	"math/big"
+	"sort"
 	"strconv"
 	"strings"
 	"time"
@@ -118,19 +119,23 @@ func builtinJWTDecode(a ast.Value) (ast.Value, error) {
 }
 
 // Implements RS256 JWT signature verification
-func builtinJWTVerifyRS256(a ast.Value, b ast.Value) (ast.Value, error) {
-	return builtinJWTVerifyRSA(a, b, func(publicKey *rsa.PublicKey, digest []byte, signature []byte) error {
+func builtinJWTVerifyRS256(bctx BuiltinContext, operands []*ast.Term, iter func(*ast.Term) error) error {
+	result, err := builtinJWTVerifyRSA(interQueryCache(bctx), operands[0].Value, operands[1].Value, func(publicKey *rsa.PublicKey, digest []byte, signature []byte) error {
 		return rsa.VerifyPKCS1v15(
 			publicKey,
 			crypto.SHA256,
 			digest,
 			signature)
 	})
+	if err != nil {
+		return handleBuiltinErr(ast.JWTVerifyRS256.Name, bctx.Location, err)
+	}
+	return iter(ast.NewTerm(result))
 }
 
 // Implements PS256 JWT signature verification
-func builtinJWTVerifyPS256(a ast.Value, b ast.Value) (ast.Value, error) {
-	return builtinJWTVerifyRSA(a, b, func(publicKey *rsa.PublicKey, digest []byte, signature []byte) error {
+func builtinJWTVerifyPS256(bctx BuiltinContext, operands []*ast.Term, iter func(*ast.Term) error) error {
+	result, err := builtinJWTVerifyRSA(interQueryCache(bctx), operands[0].Value, operands[1].Value, func(publicKey *rsa.PublicKey, digest []byte, signature []byte) error {
 		return rsa.VerifyPSS(
 			publicKey,
 			crypto.SHA256,
@@ -138,11 +143,15 @@ func builtinJWTVerifyPS256(a ast.Value, b ast.Value) (ast.Value, error) {
 			signature,
 			nil)
 	})
+	if err != nil {
+		return handleBuiltinErr(ast.JWTVerifyPS256.Name, bctx.Location, err)
+	}
+	return iter(ast.NewTerm(result))
 }
 
 // Implements RSA JWT signature verification.
-func builtinJWTVerifyRSA(a ast.Value, b ast.Value, verify func(publicKey *rsa.PublicKey, digest []byte, signature []byte) error) (ast.Value, error) {
-	return builtinJWTVerify(a, b, func(publicKey interface{}, digest []byte, signature []byte) error {
+func builtinJWTVerifyRSA(cache *builtins.InterQueryCache, a ast.Value, b ast.Value, verify func(publicKey *rsa.PublicKey, digest []byte, signature []byte) error) (ast.Value, error) {
+	return builtinJWTVerify(cache, a, b, func(publicKey interface{}, digest []byte, signature []byte) error {
 		publicKeyRsa, ok := publicKey.(*rsa.PublicKey)
 		if !ok {
 			return fmt.Errorf("incorrect public key type")
@@ -152,8 +161,8 @@ func builtinJWTVerifyRSA(a ast.Value, b ast.Value, verify func(publicKey *rsa.Pu
 }
 
 // Implements ES256 JWT signature verification.
-func builtinJWTVerifyES256(a ast.Value, b ast.Value) (ast.Value, error) {
-	return builtinJWTVerify(a, b, func(publicKey interface{}, digest []byte, signature []byte) error {
+func builtinJWTVerifyES256(bctx BuiltinContext, operands []*ast.Term, iter func(*ast.Term) error) error {
+	result, err := builtinJWTVerify(interQueryCache(bctx), operands[0].Value, operands[1].Value, func(publicKey interface{}, digest []byte, signature []byte) error {
 		publicKeyEcdsa, ok := publicKey.(*ecdsa.PublicKey)
 		if !ok {
 			return fmt.Errorf("incorrect public key type")
@@ -167,11 +176,34 @@ func builtinJWTVerifyES256(a ast.Value, b ast.Value) (ast.Value, error) {
 		}
 		return fmt.Errorf("ECDSA signature verification error")
 	})
+	if err != nil {
+		return handleBuiltinErr(ast.JWTVerifyES256.Name, bctx.Location, err)
+	}
+	return iter(ast.NewTerm(result))
+}
+
+// jwtKeysKey identifies the public keys parsed from a certificate or JWK key
+// (set) in the inter-query cache.
+type jwtKeysKey string
+
+// getKeyFromCertOrJWK returns the public keys found in a X.509 certificate or
+// JWK key(s). The parsed keys are cached so that tokens verified with the same
+// certificate or keys in other queries do not parse them again.
+func getKeyFromCertOrJWK(cache *builtins.InterQueryCache, certificate string) ([]interface{}, error) {
+	if keys, ok := cache.Get(jwtKeysKey(certificate)); ok {
+		return keys.([]interface{}), nil
+	}
+	keys, err := parseKeysFromCertOrJWK(certificate)
+	if err != nil {
+		return nil, err
+	}
+	cache.Put(jwtKeysKey(certificate), keys)
+	return keys, nil
 }
 
-// getKeyFromCertOrJWK returns the public key found in a X.509 certificate or JWK key(s).
+// parseKeysFromCertOrJWK returns the public key found in a X.509 certificate or JWK key(s).
 // A valid PEM block is never valid JSON (and vice versa), hence can try parsing both.
-func getKeyFromCertOrJWK(certificate string) ([]interface{}, error) {
+func parseKeysFromCertOrJWK(certificate string) ([]interface{}, error) {
 	if block, rest := pem.Decode([]byte(certificate)); block != nil {
 		if block.Type != "CERTIFICATE" {
 			return nil, fmt.Errorf("failed to find a PEM certificate block")
@@ -207,7 +239,7 @@ func getKeyFromCertOrJWK(certificate string) ([]interface{}, error) {
 }
 
 // Implements JWT signature verification.
-func builtinJWTVerify(a ast.Value, b ast.Value, verify func(publicKey interface{}, digest []byte, signature []byte) error) (ast.Value, error) {
+func builtinJWTVerify(cache *builtins.InterQueryCache, a ast.Value, b ast.Value, verify func(publicKey interface{}, digest []byte, signature []byte) error) (ast.Value, error) {
 	token, err := decodeJWT(a)
 	if err != nil {
 		return nil, err
@@ -218,7 +250,7 @@ func builtinJWTVerify(a ast.Value, b ast.Value, verify func(publicKey interface{
 		return nil, err
 	}
 
-	keys, err := getKeyFromCertOrJWK(string(s))
+	keys, err := getKeyFromCertOrJWK(cache, string(s))
 	if err != nil {
 		return nil, err
 	}
@@ -299,6 +331,9 @@ type tokenConstraints struct {
 	// The time to validate against, or -1 if no constraint set.
 	// (If unset, the current time will be used.)
 	time int64
+
+	// The cache that parsed keys are stored in.
+	cache *builtins.InterQueryCache
 }
 
 // tokenConstraintHandler is the handler type for JWT verification constraints.
@@ -330,7 +365,7 @@ func tokenConstraintCert(value ast.Value, constraints *tokenConstraints) (err er
 		return fmt.Errorf("cert constraint: must be a string")
 	}
 
-	constraints.keys, err = getKeyFromCertOrJWK(string(s))
+	constraints.keys, err = getKeyFromCertOrJWK(constraints.cache, string(s))
 	return
 }
 
@@ -368,8 +403,9 @@ func tokenConstraintString(name string, value ast.Value, where *string) (err err
 }
 
 // parseTokenConstraints parses the constraints argument.
-func parseTokenConstraints(a ast.Value) (constraints tokenConstraints, err error) {
+func parseTokenConstraints(cache *builtins.InterQueryCache, a ast.Value) (constraints tokenConstraints, err error) {
 	constraints.time = -1
+	constraints.cache = cache
 	var o ast.Object
 	var ok bool
 	if o, ok = a.(ast.Object); !ok {
@@ -708,16 +744,43 @@ func commonBuiltinJWTEncodeSign(inputHeaders, jwsPayload, jwkSrc string) (v ast.
 
 func builtinJWTEncodeSign(a ast.Value, b ast.Value, c ast.Value) (v ast.Value, err error) {
 
-	jwkSrc := c.String()
+	jwkSrc := sortObjectKeys(c).String()
 
-	inputHeaders := a.String()
+	inputHeaders := sortObjectKeys(a).String()
 
-	jwsPayload := b.String()
+	jwsPayload := sortObjectKeys(b).String()
 
 	return commonBuiltinJWTEncodeSign(inputHeaders, jwsPayload, jwkSrc)
 
 }
 
+// sortObjectKeys returns a copy of v where object keys are inserted in sorted
+// order so that the serialized form of v does not depend on insertion order.
+func sortObjectKeys(v ast.Value) ast.Value {
+	switch v := v.(type) {
+	case ast.Object:
+		keys := make([]*ast.Term, 0, v.Len())
+		v.Foreach(func(k, _ *ast.Term) {
+			keys = append(keys, k)
+		})
+		sort.Slice(keys, func(i, j int) bool {
+			return keys[i].Value.Compare(keys[j].Value) < 0
+		})
+		result := ast.NewObject()
+		for _, k := range keys {
+			result.Insert(k, ast.NewTerm(sortObjectKeys(v.Get(k).Value)))
+		}
+		return result
+	case ast.Array:
+		result := make(ast.Array, len(v))
+		for i := range v {
+			result[i] = ast.NewTerm(sortObjectKeys(v[i].Value))
+		}
+		return result
+	}
+	return v
+}
+
 func builtinJWTEncodeSignRaw(a ast.Value, b ast.Value, c ast.Value) (v ast.Value, err error) {
 
 	jwkSrc, err := builtins.StringOperand(c, 1)
@@ -736,7 +799,15 @@ func builtinJWTEncodeSignRaw(a ast.Value, b ast.Value, c ast.Value) (v ast.Value
 }
 
 // Implements full JWT decoding, validation and verification.
-func builtinJWTDecodeVerify(a ast.Value, b ast.Value) (v ast.Value, err error) {
+func builtinJWTDecodeVerify(bctx BuiltinContext, operands []*ast.Term, iter func(*ast.Term) error) error {
+	result, err := jwtDecodeVerify(interQueryCache(bctx), operands[0].Value, operands[1].Value)
+	if err != nil {
+		return handleBuiltinErr(ast.JWTDecodeVerify.Name, bctx.Location, err)
+	}
+	return iter(ast.NewTerm(result))
+}
+
+func jwtDecodeVerify(cache *builtins.InterQueryCache, a ast.Value, b ast.Value) (v ast.Value, err error) {
 	// io.jwt.decode_verify(string, constraints, [valid, header, payload])
 	//
 	// If valid is true then the signature verifies and all constraints are met.
@@ -749,7 +820,7 @@ func builtinJWTDecodeVerify(a ast.Value, b ast.Value) (v ast.Value, err error) {
 	arr[1] = ast.NewTerm(ast.NewObject())
 	arr[2] = ast.NewTerm(ast.NewObject())
 	var constraints tokenConstraints
-	if constraints, err = parseTokenConstraints(b); err != nil {
+	if constraints, err = parseTokenConstraints(cache, b); err != nil {
 		return
 	}
 	if err = constraints.validate(); err != nil {
@@ -948,11 +1019,11 @@ func getInputSHA(input []byte) (hash []byte) {
 
 func init() {
 	RegisterFunctionalBuiltin1(ast.JWTDecode.Name, builtinJWTDecode)
-	RegisterFunctionalBuiltin2(ast.JWTVerifyRS256.Name, builtinJWTVerifyRS256)
-	RegisterFunctionalBuiltin2(ast.JWTVerifyPS256.Name, builtinJWTVerifyPS256)
-	RegisterFunctionalBuiltin2(ast.JWTVerifyES256.Name, builtinJWTVerifyES256)
+	RegisterBuiltinFunc(ast.JWTVerifyRS256.Name, builtinJWTVerifyRS256)
+	RegisterBuiltinFunc(ast.JWTVerifyPS256.Name, builtinJWTVerifyPS256)
+	RegisterBuiltinFunc(ast.JWTVerifyES256.Name, builtinJWTVerifyES256)
 	RegisterFunctionalBuiltin2(ast.JWTVerifyHS256.Name, builtinJWTVerifyHS256)
-	RegisterFunctionalBuiltin2(ast.JWTDecodeVerify.Name, builtinJWTDecodeVerify)
+	RegisterBuiltinFunc(ast.JWTDecodeVerify.Name, builtinJWTDecodeVerify)
 	RegisterFunctionalBuiltin3(ast.JWTEncodeSignRaw.Name, builtinJWTEncodeSignRaw)
 	RegisterFunctionalBuiltin3(ast.JWTEncodeSign.Name, builtinJWTEncodeSign)
 }
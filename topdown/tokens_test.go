@@ -16,6 +16,7 @@ import (
 	"github.com/open-policy-agent/opa/ast"
 	"github.com/open-policy-agent/opa/storage"
 	"github.com/open-policy-agent/opa/storage/inmem"
+	"github.com/open-policy-agent/opa/topdown/builtins"
 	"github.com/open-policy-agent/opa/topdown/internal/jwx/jwk"
 	"github.com/open-policy-agent/opa/topdown/internal/jwx/jws"
 )
@@ -25,7 +26,7 @@ func TestParseTokenConstraints(t *testing.T) {
 		var constraints tokenConstraints
 		var err error
 		c := ast.NewObject()
-		constraints, err = parseTokenConstraints(c)
+		constraints, err = parseTokenConstraints(nil, c)
 		if err != nil {
 			t.Fatalf("parseTokenConstraints: %v", err)
 		}
@@ -41,7 +42,7 @@ func TestParseTokenConstraints(t *testing.T) {
 		var err error
 		c := ast.NewObject()
 		c.Insert(ast.StringTerm("alg"), ast.StringTerm("RS256"))
-		constraints, err = parseTokenConstraints(c)
+		constraints, err = parseTokenConstraints(nil, c)
 		if err != nil {
 			t.Fatalf("parseTokenConstraints: %v", err)
 		}
@@ -63,7 +64,7 @@ VR0jBBgwFoAUElRjSoVgKjUqY5AXz2o74cLzzS8wDwYDVR0TAQH/BAUwAwEB/zAK
 BggqhkjOPQQDAgNIADBFAiEA4yQ/88ZrUX68c6kOe9G11u8NUaUzd8pLOtkKhniN
 OHoCIHmNX37JOqTcTzGn2u9+c8NlnvZ0uDvsd1BmKPaUmjmm
 -----END CERTIFICATE-----`))
-		constraints, err = parseTokenConstraints(c)
+		constraints, err = parseTokenConstraints(nil, c)
 		if err != nil {
 			t.Fatalf("parseTokenConstraints: %v", err)
 		}
@@ -104,7 +105,7 @@ OHoCIHmNX37JOqTcTzGn2u9+c8NlnvZ0uDvsd1BmKPaUmjmm
 	]
 }
 `))
-		constraints, err = parseTokenConstraints(c)
+		constraints, err = parseTokenConstraints(nil, c)
 		if err != nil {
 			t.Fatalf("parseTokenConstraints: %v", err)
 		}
@@ -122,7 +123,7 @@ OHoCIHmNX37JOqTcTzGn2u9+c8NlnvZ0uDvsd1BmKPaUmjmm
 		var err error
 		c := ast.NewObject()
 		c.Insert(ast.StringTerm("hatever"), ast.StringTerm("junk"))
-		_, err = parseTokenConstraints(c)
+		_, err = parseTokenConstraints(nil, c)
 		if err == nil {
 			t.Fatalf("parseTokenConstraints: %v", err)
 		}
@@ -130,7 +131,7 @@ OHoCIHmNX37JOqTcTzGn2u9+c8NlnvZ0uDvsd1BmKPaUmjmm
 	t.Run("IllFormed", func(t *testing.T) {
 		var err error
 		c := ast.Array{ast.StringTerm("alg")}
-		_, err = parseTokenConstraints(c)
+		_, err = parseTokenConstraints(nil, c)
 		if err == nil {
 			t.Fatalf("parseTokenConstraints: %v", err)
 		}
@@ -474,6 +475,35 @@ func TestTopDownJWTEncodeSignRaw(t *testing.T) {
 
 }
 
+func TestJWTEncodeSignKeyOrder(t *testing.T) {
+
+	key := ast.MustParseTerm(`{"kty": "oct", "k": "AyM1SysPpbyDfgZld3umj1qzKObwVMkoqQ-EstJQLr_T-1qS0gZH75aKtMN3Yj0iPS4hcgUuTwjAzZr1Z9CAow"}`).Value
+
+	// The objects contain the same keys inserted in different orders. The
+	// encoded token must not depend on the insertion order.
+	headers := []string{`{"typ": "JWT", "alg": "HS256"}`, `{"alg": "HS256", "typ": "JWT"}`}
+	payloads := []string{`{"iss": "joe", "exp": 1300819380}`, `{"exp": 1300819380, "iss": "joe"}`}
+
+	var tokens []ast.Value
+
+	for i := range headers {
+		token, err := builtinJWTEncodeSign(ast.MustParseTerm(headers[i]).Value, ast.MustParseTerm(payloads[i]).Value, key)
+		if err != nil {
+			t.Fatal(err)
+		}
+		tokens = append(tokens, token)
+	}
+
+	if tokens[0].Compare(tokens[1]) != 0 {
+		t.Fatalf("Expected identical tokens but got %v and %v", tokens[0], tokens[1])
+	}
+
+	exp := base64.RawURLEncoding.EncodeToString([]byte(`{"exp": 1300819380, "iss": "joe"}`))
+	if parts := strings.Split(string(tokens[0].(ast.String)), "."); len(parts) != 3 || parts[1] != exp {
+		t.Fatalf("Expected payload %v but got %v", exp, tokens[0])
+	}
+}
+
 func TestTopDownJWTEncodeSignES256(t *testing.T) {
 
 	const examplePayload = `{"iss":"joe",` + "\r\n" + ` "exp":1300819380,` + "\r\n" + ` "http://example.com/is_root":true}`
@@ -1046,6 +1076,31 @@ func TestTopDownJWTVerifyRSA(t *testing.T) {
 	}
 }
 
+func TestJWTVerifyInterQueryCache(t *testing.T) {
+
+	ctx := context.Background()
+	query := ast.MustParseBody(fmt.Sprintf(`io.jwt.verify_rs256("eyJhbGciOiJSUzI1NiJ9.e30.Yw", "%[1]s", x); io.jwt.decode_verify("eyJhbGciOiJSUzI1NiJ9.e30.Yw", {"cert": "%[1]s"}, y)`, certPem))
+
+	cache := builtins.NewInterQueryCache(0)
+
+	for i := 0; i < 2; i++ {
+		rs, err := NewQuery(query).WithInterQueryBuiltinCache(cache).Run(ctx)
+		if err != nil {
+			t.Fatal(err)
+		} else if len(rs) != 1 {
+			t.Fatalf("Expected one result but got: %v", rs)
+		}
+	}
+
+	if cache.Len() != 1 {
+		t.Fatalf("Expected 1 cached entry but got %d", cache.Len())
+	}
+
+	if _, ok := cache.Get(jwtKeysKey(strings.Replace(certPem, `\n`, "\n", -1))); !ok {
+		t.Fatal("Expected parsed keys in cache")
+	}
+}
+
 func TestTopDownJWTVerifyHS256(t *testing.T) {
 	params := []struct {
 		note   string
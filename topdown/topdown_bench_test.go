@@ -7,6 +7,7 @@ package topdown
 import (
 	"bytes"
 	"context"
+	"encoding/json"
 	"fmt"
 	"sync"
 	"testing"
@@ -337,6 +338,103 @@ func generateVirtualDocsBenchmarkData(numTotalRules, numHitRules int) (*ast.Modu
 	return ast.MustParseModule(buf.String()), input
 }
 
+func BenchmarkDeepRefJoin10(b *testing.B) {
+	runDeepRefJoinBenchmark(b, 10)
+}
+
+func BenchmarkDeepRefJoin20(b *testing.B) {
+	runDeepRefJoinBenchmark(b, 20)
+}
+
+// runDeepRefJoinBenchmark evaluates a join between two nested documents that
+// plugs the same composite values on every iteration.
+func runDeepRefJoinBenchmark(b *testing.B, n int) {
+
+	c := make([]interface{}, n)
+	d := make(map[string]interface{}, n)
+
+	for i := 0; i < n; i++ {
+		row := make(map[string]interface{}, n)
+		for j := 0; j < n; j++ {
+			row[fmt.Sprint(j)] = []interface{}{json.Number(fmt.Sprint(i)), json.Number(fmt.Sprint(j))}
+		}
+		c[i] = row
+		d[fmt.Sprint(i)] = []interface{}{[]interface{}{json.Number(fmt.Sprint(i)), json.Number(fmt.Sprint(i))}}
+	}
+
+	ctx := context.Background()
+	store := inmem.NewFromObject(map[string]interface{}{"c": c, "d": d})
+	compiler := ast.MustCompileModules(map[string]string{
+		"test.rego": `package test
+
+		p[[i, j, x, y]] { data.c[i][j][k] = data.d[x][y][k]; [data.c[i][j], data.d[x][y]] = z }`,
+	})
+
+	query := ast.MustParseBody("data.test.p = x")
+
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+
+		err := storage.Txn(ctx, store, storage.TransactionParams{}, func(txn storage.Transaction) error {
+			q := NewQuery(query).
+				WithCompiler(compiler).
+				WithStore(store).
+				WithTransaction(txn)
+			_, err := q.Run(ctx)
+			return err
+		})
+
+		if err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+// BenchmarkLargeObjectInput looks up keys in and unifies against an object
+// with 10,000 keys provided as input.
+func BenchmarkLargeObjectInput(b *testing.B) {
+
+	obj := ast.NewObject()
+	for i := 0; i < 10000; i++ {
+		obj.Insert(ast.StringTerm(fmt.Sprintf("key%d", i)), ast.IntNumberTerm(i))
+	}
+
+	input := ast.NewObject(
+		[2]*ast.Term{ast.StringTerm("obj"), ast.NewTerm(obj)},
+		[2]*ast.Term{ast.StringTerm("copy"), ast.NewTerm(obj.Copy())},
+	)
+
+	ctx := context.Background()
+	compiler := ast.NewCompiler()
+
+	if compiler.Compile(nil); compiler.Failed() {
+		b.Fatal(compiler.Errors)
+	}
+
+	tests := map[string]string{
+		"ref":   `input.obj.key9999 = 9999; input.obj.key0 = 0`,
+		"unify": `input.obj = input.copy`,
+	}
+
+	for note, q := range tests {
+		query := ast.MustParseBody(q)
+		b.Run(note, func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				rs, err := NewQuery(query).
+					WithCompiler(compiler).
+					WithInput(ast.NewTerm(input)).
+					Run(ctx)
+				if err != nil {
+					b.Fatal(err)
+				} else if len(rs) != 1 {
+					b.Fatal("expected one result")
+				}
+			}
+		})
+	}
+}
+
 func BenchmarkPartialEval(b *testing.B) {
 	sizes := []int{1, 10, 100, 1000}
 	for _, n := range sizes {
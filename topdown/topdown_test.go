@@ -410,6 +410,69 @@ func TestTopDownComparisonExpr(t *testing.T) {
 	}
 }
 
+func TestTopDownMembership(t *testing.T) {
+
+	tests := []struct {
+		note     string
+		rules    []string
+		expected interface{}
+	}{
+		{"array", []string{`p { 2 in [1, 2, 3] }`}, "true"},
+		{"set", []string{`p { "b" in {"a", "b"} }`}, "true"},
+		{"object values", []string{`p { "x" in {"a": "x"} }`}, "true"},
+		{"object keys", []string{`p { "a" in {"a": "x"} }`}, ""},
+		{"not in", []string{`p { not 4 in [1, 2, 3] }`}, "true"},
+		{"scalar", []string{`p { 1 in 1 }`}, ""},
+		{"value", []string{`p = x { x := 4 in [1, 2, 3] }`}, "false"},
+		{"refs", []string{`p[x] { a[_] = x; x in c[0].x }`}, "[]"},
+		{"some in array", []string{`p[x] { some x in a; x > 2 }`}, "[3, 4]"},
+		{"some in set", []string{`p[x] { some x in {1, 2}; x > 1 }`}, "[2]"},
+		{"some in object", []string{`p[x] { some x in b }`}, `["hello", "goodbye"]`},
+		{"some in with key", []string{`p[[k, v]] { some k, v in a; v > 2 }`}, "[[2, 3], [3, 4]]"},
+		{"some in object with key", []string{`p[k] { some k, "hello" in b }`}, `["v1"]`},
+		{"some in set with key", []string{`p[[k, v]] { some k, v in {"x"} }`}, `[["x", "x"]]`},
+		{"some in pattern", []string{`p[x] { some [x, 1] in [[1, 1], [2, 2], [3, 1]] }`}, "[1, 3]"},
+		{"some in shadowing", []string{`p[x] { some x in [10] }`, `x = 1 { true }`}, "[10]"},
+		{"some in comprehension", []string{`p = xs { xs := [x | some x in a; x < 3] }`}, "[1, 2]"},
+		{"some in ref to rule", []string{`p[x] { some x in q }`, `q = [1, 2] { true }`}, "[1, 2]"},
+		{"member with key", []string{`p { internal.member_3(1, 2, [1, 2]); not internal.member_3(0, 2, [1, 2]) }`}, "true"},
+	}
+
+	data := loadSmallTestData()
+
+	for _, tc := range tests {
+		runTopDownTestCase(t, data, tc.note, tc.rules, tc.expected)
+	}
+}
+
+func TestTopDownEvery(t *testing.T) {
+
+	tests := []struct {
+		note     string
+		rules    []string
+		expected interface{}
+	}{
+		{"array", []string{`p { every x in a { x > 0 } }`}, "true"},
+		{"array false", []string{`p { every x in a { x > 1 } }`}, ""},
+		{"object", []string{`p { every k, v in b { startswith(k, "v"); is_string(v) } }`}, "true"},
+		{"set", []string{`p { every x in {1, 2} { x < 3 } }`}, "true"},
+		{"empty", []string{`p { every x in [] { false } }`}, "true"},
+		{"undefined domain", []string{`p { every x in input.missing { true } }`}, ""},
+		{"closure", []string{`p[y] { a[_] = y; every x in a { x >= y } }`}, "[1]"},
+		{"nested", []string{`p { every x in c[0].x { every y in x { y != null } } }`}, ""},
+		{"negated", []string{`p { not q }`, `q { every x in a { x > 2 } }`}, "true"},
+		{"key and value", []string{`p { every i, x in a { x == i + 1 } }`}, "true"},
+		{"iteration in body", []string{`p { every x in a { some y in a; y > x } }`}, ""},
+		{"local vars", []string{`p = x { every x in a { x > 0 }; x := 7 }`}, "7"},
+	}
+
+	data := loadSmallTestData()
+
+	for _, tc := range tests {
+		runTopDownTestCase(t, data, tc.note, tc.rules, tc.expected)
+	}
+}
+
 func TestTopDownVirtualDocs(t *testing.T) {
 
 	tests := []struct {
@@ -760,6 +823,45 @@ p[x] = y { data.enum_errors.a[x] = y }`,
 	assertTopDownWithPath(t, compiler, store, "enumerate virtual errors", []string{"enum_errors", "caller", "p"}, `{}`, fmt.Errorf("divide by zero"))
 }
 
+func TestTopDownDynamicDispatch(t *testing.T) {
+
+	data := map[string]interface{}{
+		"policies": map[string]interface{}{
+			"d": map[string]interface{}{"allow": true},
+			"a": map[string]interface{}{"owner": "alice"},
+		},
+	}
+
+	compiler := compileModules([]string{
+		`package policies.a
+
+		allow { input.x = 1 }`,
+		`package policies.b
+
+		allow = false`,
+		`package policies.c
+
+		allow { input.x = 1 }
+		owner = "carol"`,
+		`package policies.team.e
+
+		allow = true`,
+		`package meta
+
+		decisions[name] = x { x = data.policies[name].allow }
+		allowed = [name | data.policies[name].allow]
+		owners = [[name, owner] | owner = data.policies[name].owner]
+		nested[[x, y]] { data.policies[x][y].allow }`,
+	})
+
+	store := inmem.NewFromObject(data)
+
+	assertTopDownWithPath(t, compiler, store, "dispatch: object", []string{"meta", "decisions"}, `{"x": 1}`, `{"a": true, "b": false, "c": true, "d": true}`)
+	assertTopDownWithPath(t, compiler, store, "dispatch: ordered", []string{"meta", "allowed"}, `{"x": 1}`, `["a", "c", "d"]`)
+	assertTopDownWithPath(t, compiler, store, "dispatch: base and virtual", []string{"meta", "owners"}, `{}`, `[["a", "alice"], ["c", "carol"]]`)
+	assertTopDownWithPath(t, compiler, store, "dispatch: nested", []string{"meta", "nested"}, `{}`, `[["team", "e"]]`)
+}
+
 func TestTopDownFix1863(t *testing.T) {
 
 	compiler := ast.MustCompileModules(map[string]string{
@@ -1342,12 +1444,15 @@ func TestTopDownStrings(t *testing.T) {
 		{"concat: ref dest (2)", []string{`p = true { not concat("", ["b", "a", "r"], c[0].x[2]) }`}, "true"},
 		{"indexof", []string{`p = x { indexof("abcdefgh", "cde", x) }`}, "2"},
 		{"indexof: not found", []string{`p = x { indexof("abcdefgh", "xyz", x) }`}, "-1"},
+		{"indexof: unicode", []string{`p = x { indexof("héllo, 世界", "界", x) }`}, "8"},
 		{"substring", []string{`p = x { substring("abcdefgh", 2, 3, x) }`}, `"cde"`},
 		{"substring: remainder", []string{`p = x { substring("abcdefgh", 2, -1, x) }`}, `"cdefgh"`},
 		{"substring: too long", []string{`p = x { substring("abcdefgh", 2, 10000, x) }`}, `"cdefgh"`},
 		{"substring: offset negative", []string{`p = x { substring("aaa", -1, -1, x) }`}, fmt.Errorf("negative offset")},
 		{"substring: offset too long", []string{`p = x { substring("aaa", 3, -1, x) }`}, `""`},
 		{"substring: offset too long 2", []string{`p = x { substring("aaa", 4, -1, x) }`}, `""`},
+		{"substring: unicode", []string{`p = x { substring("héllo, 世界", 1, 4, x) }`}, `"éllo"`},
+		{"substring: unicode remainder", []string{`p = x { substring("héllo, 世界", 7, -1, x) }`}, `"世界"`},
 		{"contains", []string{`p = true { contains("abcdefgh", "defg") }`}, "true"},
 		{"contains: undefined", []string{`p = true { contains("abcdefgh", "ac") }`}, ""},
 		{"startswith", []string{`p = true { startswith("abcdefgh", "abcd") }`}, "true"},
@@ -1356,6 +1461,8 @@ func TestTopDownStrings(t *testing.T) {
 		{"endswith: undefined", []string{`p = true { endswith("abcdefgh", "fg") }`}, ""},
 		{"lower", []string{`p = x { lower("AbCdEf", x) }`}, `"abcdef"`},
 		{"upper", []string{`p = x { upper("AbCdEf", x) }`}, `"ABCDEF"`},
+		{"lower: unicode", []string{`p = x { lower("ÄÖÜ ΣΑΣ", x) }`}, `"äöü σασ"`},
+		{"upper: unicode", []string{`p = x { upper("ñandú ÿ", x) }`}, `"ÑANDÚ Ÿ"`},
 		{"split: empty string", []string{`p = x { split("", ".", [x]) }`}, `""`},
 		{"split: one", []string{`p = x { split("foo", ".", [x]) }`}, `"foo"`},
 		{"split: many", []string{`p = [x,y] { split("foo.bar.baz", ".", [x,"bar",y]) }`}, `["foo","baz"]`},
@@ -1376,6 +1483,11 @@ func TestTopDownStrings(t *testing.T) {
 		{"sprintf: float", []string{`p = x { sprintf("hi %.2f", [3.1415], x) }`}, `"hi 3.14"`},
 		{"sprintf: float too big", []string{`p = x { sprintf("hi %v", [2e308], x) }`}, `"hi 2e+308"`},
 		{"sprintf: bool", []string{`p = x { sprintf("hi %s", [true], x) }`}, `"hi true"`},
+		{"sprintf: bool value", []string{`p = x { sprintf("hi %v", [false], x) }`}, `"hi false"`},
+		{"sprintf: big int", []string{`p = x { sprintf("hi %d", [1180591620717411303424], x) }`}, `"hi 1180591620717411303424"`},
+		{"sprintf: null", []string{`p = x { sprintf("hi %v", [null], x) }`}, `"hi null"`},
+		{"sprintf: object", []string{`p = x { sprintf("hi %v", [{"a": "b"}], x) }`}, `"hi {\"a\": \"b\"}"`},
+		{"sprintf: deny message", []string{`p[msg] { data.c[i].x[j] = v; msg := sprintf("c[%d].x[%d] is %v", [i, j, v]) }`}, `["c[0].x[0] is true", "c[0].x[1] is false", "c[0].x[2] is foo"]`},
 		{"sprintf: composite", []string{`p = x { sprintf("hi %v", [["there", 5, 3.14]], x) }`}, `"hi [\"there\", 5, 3.14]"`},
 	}
 
@@ -1394,6 +1506,9 @@ func TestTopDownJSONBuiltins(t *testing.T) {
 		expected interface{}
 	}{
 		{"marshal", []string{`p = x { json.marshal([{"foo": {1,2,3}}], x) }`}, `"[{\"foo\":[1,2,3]}]"`},
+		{"marshal non-string key", []string{`p = x { json.marshal({1: 2}, x) }`}, &Error{Code: BuiltinErr, Message: "json.marshal: object value has non-string key (number)"}},
+		{"marshal canonical", []string{`p = x { json.marshal_canonical({"b": [1.50, {"d", "c", {{2, 1}}}], "a": 1e2}, x) }`}, `"{\"a\":100,\"b\":[1.5,[\"c\",\"d\",[[1,2]]]]}"`},
+		{"marshal canonical non-string key", []string{`p = x { json.marshal_canonical({1: 2}, x) }`}, &Error{Code: BuiltinErr, Message: "json.marshal_canonical: object value has non-string key (number)"}},
 		{"unmarshal", []string{`p = x { json.unmarshal("[{\"foo\":[1,2,3]}]", x) }`}, `[{"foo": [1,2,3]}]"`},
 		{"unmarshal-non-string", []string{`p = x { json.unmarshal(data.a[0], x) }`}, fmt.Errorf("operand 1 must be string but got number")},
 		{"yaml round-trip", []string{`p = y { yaml.marshal([{"foo": {1,2,3}}], x); yaml.unmarshal(x, y) }`}, `[{"foo": [1,2,3]}]`},
@@ -1439,6 +1554,8 @@ func TestTopDownBase64UrlBuiltins(t *testing.T) {
 		{"encode-2", []string{`p = x { base64url.encode("there", x) }`}, `"dGhlcmU="`},
 		{"decode-1", []string{`p = x { base64url.decode("aGVsbG8=", x) }`}, `"hello"`},
 		{"decode-2", []string{`p = x { base64url.decode("dGhlcmU=", x) }`}, `"there"`},
+		{"encode-no-pad", []string{`p = x { base64url.encode_no_pad("hello", x) }`}, `"aGVsbG8"`},
+		{"decode-no-pad", []string{`p = x { base64url.decode(base64url.encode_no_pad("hello?"), x) }`}, `"hello?"`},
 	}
 
 	data := loadSmallTestData()
@@ -2333,6 +2450,90 @@ func TestTopDownWithKeyword(t *testing.T) {
 			setl[x] { data.foo[x] }`},
 			rules: []string{`p = true { data.ex.setl[1] with data.foo as {1} }`},
 		},
+		{
+			note: "with mock built-in value",
+			exp:  `{"status_code": 200, "body": {"allow": true}}`,
+			modules: []string{`package ex
+			resp = http.send({"method": "get", "url": "http://localhost:9999"})`},
+			rules: []string{`p = x { x = data.ex.resp with http.send as {"status_code": 200, "body": {"allow": true}} }`},
+		},
+		{
+			note: "with mock built-in function",
+			exp:  `"http://localhost:9999/ok"`,
+			modules: []string{`package ex
+			resp = http.send({"method": "get", "url": "http://localhost:9999"})
+			mock_send(req) = concat("/", [req.url, "ok"])`},
+			rules: []string{`p = x { x = data.ex.resp with http.send as data.ex.mock_send }`},
+		},
+		{
+			note: "with mock built-in no args",
+			exp:  `2020`,
+			modules: []string{`package ex
+			year = y { [y, _, _] = time.date(time.now_ns()) }`},
+			rules: []string{`p = x { x = data.ex.year with time.now_ns as 1580000000000000000 }`},
+		},
+		{
+			note:  "with mock built-in var",
+			exp:   `7`,
+			rules: []string{`p = x { x = count([1, 2]) with count as 7 }`},
+		},
+		{
+			note: "with mock built-in boolean",
+			exp:  `[true, true]`,
+			modules: []string{`package ex
+			prefixed { startswith(input.name, "foo") }`},
+			rules: []string{`p = [x, y] { x = data.ex.prefixed with input.name as "bar" with startswith as true; y = data.ex.prefixed with input.name as "foo" }`},
+		},
+		{
+			note: "with mock built-in false",
+			exp:  `true`,
+			modules: []string{`package ex
+			prefixed { startswith(input.name, "foo") }`},
+			rules: []string{`p { not data.ex.prefixed with input.name as "foo" with startswith as false }`},
+		},
+		{
+			note: "with mock function value",
+			exp:  `["a", "b"]`,
+			modules: []string{`package ex
+			allowed(x) { x == "a" }
+			names[x] { x = input[_]; allowed(x) }`},
+			rules: []string{`p = x { x = data.ex.names with input as ["a", "b"] with data.ex.allowed as true }`},
+		},
+		{
+			note: "with mock function replacement",
+			exp:  `[[6, 9], [4, 6]]`,
+			modules: []string{`package ex
+			double(x) = y { y = x * 2 }
+			triple(x) = y { y = x * 3 }
+			doubled = [y | y = double(input[_])]`},
+			rules: []string{`p = [x, y] { x = data.ex.doubled with input as [2, 3] with data.ex.double as data.ex.triple; y = data.ex.doubled with input as [2, 3] }`},
+		},
+		{
+			note: "with mock function invalidate virtual cache",
+			exp:  `[true, false]`,
+			modules: []string{`package ex
+			allowed(x) { x == "a" }
+			allow { allowed(input) }`},
+			rules: []string{`p = [x, y] { x = data.ex.allow with input as "b" with data.ex.allowed as true; y = false; not data.ex.allow with input as "b" }`},
+		},
+		{
+			note: "with mock function replacement calls target",
+			exp:  `"f"`,
+			modules: []string{`package ex
+			f(x) = "f"
+			g(x) = y { y = f(x) }
+			q = f(1)`},
+			rules: []string{`p = x { x = data.ex.q with data.ex.f as data.ex.g }`},
+		},
+		{
+			note: "with mock function nested cycle",
+			exp:  `"f"`,
+			modules: []string{`package ex
+			f(x) = "f"
+			g(x) = "g"
+			h(x) = y { y = f(x) with data.ex.g as data.ex.f }`},
+			rules: []string{`p = x { x = data.ex.h(1) with data.ex.f as data.ex.g }`},
+		},
 	}
 
 	for _, tc := range tests {
@@ -2340,6 +2541,38 @@ func TestTopDownWithKeyword(t *testing.T) {
 	}
 }
 
+func TestTopDownRuleHeadRefs(t *testing.T) {
+
+	module := `package ex
+
+	fruit.apple.color = "red"
+	fruit.apple.ripe { input.ripe }
+	fruit.banana.color = "yellow"
+	fruit.banana.color = "yellow" { true }
+	fruit.cherry.color = "red" { false } else = "black" { true }
+	conflict.a.b = 1
+	conflict.a.b = 2
+	`
+
+	tests := []struct {
+		note     string
+		rules    []string
+		input    string
+		expected interface{}
+	}{
+		{"full extent", []string{`p = x { x = data.ex.fruit }`}, "", `{"apple": {"color": "red"}, "banana": {"color": "yellow"}, "cherry": {"color": "black"}}`},
+		{"input", []string{`p = x { x = data.ex.fruit.apple }`}, `{"ripe": true}`, `{"color": "red", "ripe": true}`},
+		{"leaf", []string{`p = x { x = data.ex.fruit.cherry.color }`}, "", `"black"`},
+		{"undefined", []string{`p = x { x = data.ex.fruit.apple.ripe }`}, "", ""},
+		{"iteration", []string{`p[k] { data.ex.fruit[k].color = "red" }`}, "", `["apple"]`},
+		{"conflict", []string{`p = x { x = data.ex.conflict.a.b }`}, "", completeDocConflictErr(nil)},
+	}
+
+	for _, tc := range tests {
+		runTopDownTestCaseWithModules(t, map[string]interface{}{}, tc.note, tc.rules, []string{module}, tc.input, tc.expected)
+	}
+}
+
 func TestTopDownElseKeyword(t *testing.T) {
 	tests := []struct {
 		note     string
@@ -2752,9 +2985,11 @@ func compileRules(imports []string, input []string, modules []string) (*ast.Comp
 		Imports: is,
 	}
 
+	// Test rules are parsed individually so the future keywords cannot be
+	// imported.
 	rules := []*ast.Rule{}
 	for i := range input {
-		rules = append(rules, ast.MustParseRule(input[i]))
+		rules = append(rules, ast.MustParseRuleWithOpts(input[i], ast.ParserOptions{AllFutureKeywords: true}))
 		rules[i].Module = m
 	}
 
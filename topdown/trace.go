@@ -5,8 +5,10 @@
 package topdown
 
 import (
+	"encoding/json"
 	"fmt"
 	"io"
+	"sort"
 	"strings"
 
 	"github.com/open-policy-agent/opa/ast"
@@ -159,6 +161,130 @@ func PrettyTrace(w io.Writer, trace []*Event) {
 	}
 }
 
+// JSONTrace writes the trace to the writer as a stream of JSON objects, one
+// event per line. Variables rewritten by the compiler are reported with their
+// original names.
+func JSONTrace(w io.Writer, trace []*Event) error {
+	enc := json.NewEncoder(w)
+	for _, event := range trace {
+		if err := enc.Encode(newJSONEvent(event)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+type jsonEvent struct {
+	Op       string        `json:"op"`
+	QueryID  uint64        `json:"query_id"`
+	ParentID uint64        `json:"parent_id"`
+	Type     string        `json:"type,omitempty"`
+	Node     interface{}   `json:"node,omitempty"`
+	Location *ast.Location `json:"location,omitempty"`
+	Locals   []jsonBinding `json:"locals,omitempty"`
+	Message  string        `json:"message,omitempty"`
+}
+
+type jsonBinding struct {
+	Key   *ast.Term `json:"key"`
+	Value *ast.Term `json:"value"`
+}
+
+func newJSONEvent(event *Event) jsonEvent {
+
+	result := jsonEvent{
+		Op:       strings.ToLower(string(event.Op)),
+		QueryID:  event.QueryID,
+		ParentID: event.ParentID,
+		Location: event.Location,
+		Message:  event.Message,
+	}
+
+	if event.Node != nil {
+		result.Type = ast.TypeName(event.Node)
+		result.Node = rewrite(event).Node
+	}
+
+	if event.Locals != nil {
+		event.Locals.Iter(func(k, v ast.Value) bool {
+			if v, ok := k.(ast.Var); ok {
+				if meta, ok := event.LocalMetadata[v]; ok {
+					k = meta.Name
+				}
+			}
+			result.Locals = append(result.Locals, jsonBinding{Key: ast.NewTerm(k), Value: ast.NewTerm(v)})
+			return false
+		})
+	}
+
+	return result
+}
+
+// FoldedTrace writes the trace to the writer in the folded stack format
+// consumed by flame graph tools (e.g., flamegraph.pl). Each line contains a
+// stack of the rules and functions being evaluated, rooted at the query,
+// followed by the number of expressions evaluated with that stack. Queries
+// that do not belong to a rule (e.g., comprehensions) are folded into the
+// enclosing frame.
+func FoldedTrace(w io.Writer, trace []*Event) error {
+
+	stacks := map[uint64]string{}
+	counts := map[string]int{}
+
+	for _, event := range trace {
+
+		stack, ok := stacks[event.QueryID]
+		if !ok {
+			parent, hasParent := stacks[event.ParentID]
+			hasParent = hasParent && event.ParentID != event.QueryID
+			if rule, isRule := event.Node.(*ast.Rule); isRule && event.Op == EnterOp {
+				stack = foldedFrame(rule.Path().String())
+				if hasParent {
+					stack = parent + ";" + stack
+				}
+			} else if hasParent {
+				stack = parent
+			} else {
+				stack = foldedFrame(fmt.Sprint(rewrite(event).Node))
+			}
+			stacks[event.QueryID] = stack
+		}
+
+		if event.Op == EvalOp {
+			counts[stack]++
+		}
+	}
+
+	keys := make([]string, 0, len(counts))
+	for k := range counts {
+		keys = append(keys, k)
+	}
+
+	sort.Strings(keys)
+
+	for _, k := range keys {
+		if _, err := fmt.Fprintf(w, "%v %d\n", k, counts[k]); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// foldedFrame returns the name of a frame in the folded stack format. Frames
+// are separated by semicolons and stacks by newlines so both are replaced.
+func foldedFrame(s string) string {
+	return strings.Map(func(r rune) rune {
+		switch r {
+		case ';':
+			return ','
+		case '\n', '\t':
+			return ' '
+		}
+		return r
+	}, s)
+}
+
 func formatEvent(event *Event, depth int) string {
 	padding := formatEventPadding(event, depth)
 	if event.Op == NoteOp {
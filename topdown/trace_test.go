@@ -340,3 +340,71 @@ func TestTraceRewrittenQueryVars(t *testing.T) {
 		t.Errorf("Expected %v but got %v", exp, node)
 	}
 }
+
+func TestFoldedTrace(t *testing.T) {
+	module := `package test
+
+	p = true { q[x]; f(x) }
+	q[x] { x = data.a[_]; count([y | y = data.a[_]]) > 0 }
+	f(x) { x > 1 }`
+
+	ctx := context.Background()
+	compiler := compileModules([]string{module})
+	data := loadSmallTestData()
+	store := inmem.NewFromObject(data)
+	txn := storage.NewTransactionOrDie(ctx, store)
+	defer store.Abort(ctx, txn)
+
+	tracer := NewBufferTracer()
+	query := NewQuery(ast.MustParseBody("data.test.p = _")).
+		WithCompiler(compiler).
+		WithStore(store).
+		WithTransaction(txn).
+		WithTracer(tracer)
+
+	if _, err := query.Run(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	var buf bytes.Buffer
+	if err := FoldedTrace(&buf, *tracer); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := `data.test.p = _ 1
+data.test.p = _;data.test.p 5
+data.test.p = _;data.test.p;data.test.f 4
+data.test.p = _;data.test.p;data.test.q 17
+`
+
+	if buf.String() != expected {
+		t.Fatalf("Expected:\n\n%v\nGot:\n\n%v", expected, buf.String())
+	}
+}
+
+func TestJSONTrace(t *testing.T) {
+
+	x := ast.MustParseBody(`x = 1`)[0]
+	locals := ast.NewValueMap()
+	locals.Put(ast.Var("__local0__"), ast.Number("1"))
+
+	trace := []*Event{
+		{Op: EnterOp, Node: ast.MustParseBody(`x = 1`), QueryID: 1, ParentID: 1},
+		{Op: EvalOp, Node: x, QueryID: 1, ParentID: 1, Locals: locals, LocalMetadata: map[ast.Var]VarMetadata{"__local0__": {Name: "y"}}},
+		{Op: NoteOp, QueryID: 1, ParentID: 1, Message: "hello"},
+	}
+
+	var buf bytes.Buffer
+	if err := JSONTrace(&buf, trace); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := `{"op":"enter","query_id":1,"parent_id":1,"type":"body","node":[{"index":0,"terms":[{"type":"ref","value":[{"type":"var","value":"eq"}]},{"type":"var","value":"x"},{"type":"number","value":1}]}]}
+{"op":"eval","query_id":1,"parent_id":1,"type":"expr","node":{"index":0,"terms":[{"type":"ref","value":[{"type":"var","value":"eq"}]},{"type":"var","value":"x"},{"type":"number","value":1}]},"locals":[{"key":{"type":"var","value":"y"},"value":{"type":"number","value":1}}]}
+{"op":"note","query_id":1,"parent_id":1,"message":"hello"}
+`
+
+	if buf.String() != expected {
+		t.Fatalf("Expected:\n\n%v\nGot:\n\n%v", expected, buf.String())
+	}
+}
@@ -0,0 +1,199 @@
+// Copyright 2020 The OPA Authors.  All rights reserved.
+// Use of this source code is governed by an Apache2
+// license that can be found in the LICENSE file.
+
+package util
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"unicode/utf16"
+	"unicode/utf8"
+)
+
+// MarshalCanonicalJSON returns the canonical JSON encoding of x as defined by
+// the JSON Canonicalization Scheme (RFC 8785): object keys are sorted by their
+// UTF-16 code units, no whitespace is emitted, strings use the minimal set of
+// escapes, and numbers are serialized like ECMAScript's Number.prototype.toString.
+// Equal documents therefore produce identical bytes, which makes the encoding
+// suitable for hashing, signing, and comparing documents.
+//
+// Numbers are converted to IEEE 754 double precision values before they are
+// serialized, so integers with magnitudes greater than 2^53 may lose precision.
+// Values that are not JSON values (e.g., structs) are serialized with
+// encoding/json first.
+func MarshalCanonicalJSON(x interface{}) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := writeCanonicalJSON(&buf, x); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func writeCanonicalJSON(buf *bytes.Buffer, x interface{}) error {
+	switch x := x.(type) {
+	case nil:
+		buf.WriteString("null")
+	case bool:
+		if x {
+			buf.WriteString("true")
+		} else {
+			buf.WriteString("false")
+		}
+	case json.Number:
+		f, err := strconv.ParseFloat(string(x), 64)
+		if err != nil {
+			return fmt.Errorf("illegal number: %v", x)
+		}
+		return writeCanonicalNumber(buf, f)
+	case float64:
+		return writeCanonicalNumber(buf, x)
+	case int:
+		return writeCanonicalNumber(buf, float64(x))
+	case int64:
+		return writeCanonicalNumber(buf, float64(x))
+	case string:
+		return writeCanonicalString(buf, x)
+	case []interface{}:
+		buf.WriteByte('[')
+		for i := range x {
+			if i > 0 {
+				buf.WriteByte(',')
+			}
+			if err := writeCanonicalJSON(buf, x[i]); err != nil {
+				return err
+			}
+		}
+		buf.WriteByte(']')
+	case map[string]interface{}:
+		keys := make([]string, 0, len(x))
+		for k := range x {
+			keys = append(keys, k)
+		}
+		sort.Slice(keys, func(i, j int) bool {
+			return compareUTF16(keys[i], keys[j]) < 0
+		})
+		buf.WriteByte('{')
+		for i, k := range keys {
+			if i > 0 {
+				buf.WriteByte(',')
+			}
+			if err := writeCanonicalString(buf, k); err != nil {
+				return err
+			}
+			buf.WriteByte(':')
+			if err := writeCanonicalJSON(buf, x[k]); err != nil {
+				return err
+			}
+		}
+		buf.WriteByte('}')
+	default:
+		bs, err := json.Marshal(x)
+		if err != nil {
+			return err
+		}
+		var y interface{}
+		if err := UnmarshalJSON(bs, &y); err != nil {
+			return err
+		}
+		return writeCanonicalJSON(buf, y)
+	}
+	return nil
+}
+
+// writeCanonicalNumber writes f in the ECMAScript format. The format is the
+// same as encoding/json's except that exponents never have leading zeros.
+func writeCanonicalNumber(buf *bytes.Buffer, f float64) error {
+	if math.IsInf(f, 0) || math.IsNaN(f) {
+		return fmt.Errorf("illegal number: %v", f)
+	}
+
+	if f == 0 {
+		buf.WriteByte('0')
+		return nil
+	}
+
+	format := byte('f')
+	if abs := math.Abs(f); abs < 1e-6 || abs >= 1e21 {
+		format = 'e'
+	}
+
+	s := strconv.FormatFloat(f, format, -1, 64)
+
+	if format == 'e' {
+		// Convert e-07 to e-7.
+		if n := len(s); n >= 4 && s[n-4] == 'e' && s[n-3] == '-' && s[n-2] == '0' {
+			s = s[:n-2] + s[n-1:]
+		}
+	}
+
+	buf.WriteString(s)
+	return nil
+}
+
+const hexDigits = "0123456789abcdef"
+
+// writeCanonicalString writes s with only the escapes required by JSON:
+// quotation marks, backslashes, and control characters.
+func writeCanonicalString(buf *bytes.Buffer, s string) error {
+	if !utf8.ValidString(s) {
+		return fmt.Errorf("illegal string: invalid UTF-8: %q", s)
+	}
+	buf.WriteByte('"')
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch c {
+		case '"':
+			buf.WriteString(`\"`)
+		case '\\':
+			buf.WriteString(`\\`)
+		case '\b':
+			buf.WriteString(`\b`)
+		case '\f':
+			buf.WriteString(`\f`)
+		case '\n':
+			buf.WriteString(`\n`)
+		case '\r':
+			buf.WriteString(`\r`)
+		case '\t':
+			buf.WriteString(`\t`)
+		default:
+			if c < 0x20 {
+				buf.WriteString(`\u00`)
+				buf.WriteByte(hexDigits[c>>4])
+				buf.WriteByte(hexDigits[c&0xF])
+			} else {
+				buf.WriteByte(c)
+			}
+		}
+	}
+	buf.WriteByte('"')
+	return nil
+}
+
+// compareUTF16 compares a and b by their UTF-16 code units. This differs from
+// comparing the strings' bytes for characters outside the basic multilingual
+// plane.
+func compareUTF16(a, b string) int {
+	x := utf16.Encode([]rune(a))
+	y := utf16.Encode([]rune(b))
+	for i := 0; i < len(x) && i < len(y); i++ {
+		if x[i] != y[i] {
+			if x[i] < y[i] {
+				return -1
+			}
+			return 1
+		}
+	}
+	switch {
+	case len(x) < len(y):
+		return -1
+	case len(x) > len(y):
+		return 1
+	}
+	return 0
+}
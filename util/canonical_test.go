@@ -0,0 +1,94 @@
+// Copyright 2020 The OPA Authors.  All rights reserved.
+// Use of this source code is governed by an Apache2
+// license that can be found in the LICENSE file.
+
+package util_test
+
+import (
+	"encoding/json"
+	"math"
+	"strings"
+	"testing"
+
+	"github.com/open-policy-agent/opa/util"
+)
+
+func TestMarshalCanonicalJSON(t *testing.T) {
+
+	tests := []struct {
+		note  string
+		input string
+		exp   string
+	}{
+		{"scalars", `[null, true, false, "x"]`, `[null,true,false,"x"]`},
+		{"whitespace", ` { "a" : [ 1 , 2 ] } `, `{"a":[1,2]}`},
+		{"sorted keys", `{"b": 1, "a": {"d": 2, "c": 3}}`, `{"a":{"c":3,"d":2},"b":1}`},
+		{"utf-16 key order", `{"\u20ac": 1, "\r": 2, "\ufb33": 3, "1": 4, "\ud83d\ude00": 5, "\u0080": 6, "\u00f6": 7}`,
+			"{\"\\r\":2,\"1\":4,\"\u0080\":6,\"\u00f6\":7,\"\u20ac\":1,\"\U0001F600\":5,\"\ufb33\":3}"},
+		{"numbers", `[1e+30, 4.50, 0.002, 1e-27, 333333333.33333329, -0, 0.000001, 1e-7, 5e-324, 1.7976931348623157e308, 295147905179352830000, 100]`,
+			`[1e+30,4.5,0.002,1e-27,333333333.3333333,0,0.000001,1e-7,5e-324,1.7976931348623157e+308,295147905179352830000,100]`},
+		{"string escapes", `"\"\\\/\b\f\n\r\t\u0001\u001f<>&\u2028é"`, "\"\\\"\\\\/\\b\\f\\n\\r\\t\\u0001\\u001f<>&\u2028é\""},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.note, func(t *testing.T) {
+			var x interface{}
+			if err := util.UnmarshalJSON([]byte(tc.input), &x); err != nil {
+				t.Fatal(err)
+			}
+			bs, err := util.MarshalCanonicalJSON(x)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(bs) != tc.exp {
+				t.Fatalf("Expected %v but got %v", tc.exp, string(bs))
+			}
+			if !json.Valid(bs) {
+				t.Fatalf("Expected valid JSON but got %v", string(bs))
+			}
+		})
+	}
+}
+
+func TestMarshalCanonicalJSONGoValues(t *testing.T) {
+
+	x := struct {
+		B []int             `json:"b"`
+		A map[string]string `json:"a"`
+	}{
+		B: []int{3, 1},
+		A: map[string]string{"y": "1", "x": "2"},
+	}
+
+	bs, err := util.MarshalCanonicalJSON(x)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if exp := `{"a":{"x":"2","y":"1"},"b":[3,1]}`; string(bs) != exp {
+		t.Fatalf("Expected %v but got %v", exp, string(bs))
+	}
+}
+
+func TestMarshalCanonicalJSONErrors(t *testing.T) {
+
+	tests := []struct {
+		note  string
+		input interface{}
+		exp   string
+	}{
+		{"nan", math.NaN(), "illegal number"},
+		{"infinity", []interface{}{math.Inf(1)}, "illegal number"},
+		{"bad number", json.Number("x"), "illegal number"},
+		{"invalid utf-8", map[string]interface{}{"\xff": 1}, "invalid UTF-8"},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.note, func(t *testing.T) {
+			_, err := util.MarshalCanonicalJSON(tc.input)
+			if err == nil || !strings.Contains(err.Error(), tc.exp) {
+				t.Fatalf("Expected error containing %q but got: %v", tc.exp, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,392 @@
+// Copyright 2020 The OPA Authors.  All rights reserved.
+// Use of this source code is governed by an Apache2
+// license that can be found in the LICENSE file.
+
+package util
+
+import (
+	"encoding"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/open-policy-agent/opa/internal/jsonfield"
+)
+
+var (
+	jsonUnmarshalerType = reflect.TypeOf((*json.Unmarshaler)(nil)).Elem()
+	textUnmarshalerType = reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
+	numberType          = reflect.TypeOf(json.Number(""))
+)
+
+// Decode stores the JSON value x (e.g., a value returned by evaluation or
+// produced by UnmarshalJSON) in the value pointed to by v. The semantics are
+// the same as json.Unmarshal: struct fields are matched by their json tags
+// (or names, case-insensitively), values that implement json.Unmarshaler or
+// encoding.TextUnmarshaler decode themselves, and values that do not match
+// the destination type produce a *json.UnmarshalTypeError after the rest of
+// x has been decoded. Unlike json.Unmarshal, x is not serialized unless the
+// destination implements json.Unmarshaler. Empty interface destinations are
+// set to copies of the JSON values so numbers are decoded as json.Number.
+// Since x does not preserve the order of object keys, keys are decoded in
+// sorted order and keys that match a struct field exactly take precedence
+// over keys that match it case-insensitively.
+func Decode(x interface{}, v interface{}) error {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Ptr || rv.IsNil() {
+		return &json.InvalidUnmarshalError{Type: reflect.TypeOf(v)}
+	}
+	d := decoder{}
+	if err := d.decode(x, rv.Elem(), ""); err != nil {
+		return err
+	}
+	return d.typeErr
+}
+
+type decoder struct {
+	typeErr error
+	root    string // name of the struct that field paths start at
+}
+
+// mismatch records the first type error. Decoding continues so that as much
+// of the value as possible is stored. The value describes the JSON value,
+// e.g., "string" or "number 1.5". Objects are decoded in key order so the
+// first type error does not depend on map iteration order.
+func (d *decoder) mismatch(value string, t reflect.Type, field string) {
+	if d.typeErr == nil {
+		err := &json.UnmarshalTypeError{Value: value, Type: t, Field: field}
+		if field != "" {
+			err.Struct = d.root
+		}
+		d.typeErr = err
+	}
+}
+
+func (d *decoder) decode(x interface{}, v reflect.Value, field string) error {
+
+	if x == nil {
+		switch v.Kind() {
+		case reflect.Interface, reflect.Ptr, reflect.Map, reflect.Slice:
+			if u, ok := unmarshaler(v); ok {
+				return u.UnmarshalJSON([]byte("null"))
+			}
+			v.Set(reflect.Zero(v.Type()))
+		}
+		return nil
+	}
+
+	if u, ok := unmarshaler(v); ok {
+		bs, err := json.Marshal(x)
+		if err != nil {
+			return err
+		}
+		return u.UnmarshalJSON(bs)
+	}
+
+	if s, ok := x.(string); ok {
+		if u, ok := textUnmarshaler(v); ok {
+			return u.UnmarshalText([]byte(s))
+		}
+	}
+
+	switch v.Kind() {
+	case reflect.Ptr:
+		if v.IsNil() {
+			v.Set(reflect.New(v.Type().Elem()))
+		}
+		return d.decode(x, v.Elem(), field)
+	case reflect.Interface:
+		if v.NumMethod() != 0 {
+			d.mismatch(TypeName(x), v.Type(), field)
+			return nil
+		}
+		v.Set(reflect.ValueOf(DeepCopy(x)))
+		return nil
+	}
+
+	switch x := x.(type) {
+	case bool:
+		if v.Kind() != reflect.Bool {
+			d.mismatch(TypeName(x), v.Type(), field)
+			return nil
+		}
+		v.SetBool(x)
+	case json.Number:
+		return d.decodeNumber(string(x), x, v, field)
+	case float64:
+		return d.decodeNumber(strconv.FormatFloat(x, 'g', -1, 64), x, v, field)
+	case int:
+		return d.decodeNumber(strconv.Itoa(x), x, v, field)
+	case int64:
+		return d.decodeNumber(strconv.FormatInt(x, 10), x, v, field)
+	case string:
+		switch {
+		case v.Kind() == reflect.String && v.Type() != numberType:
+			v.SetString(x)
+		case v.Kind() == reflect.Slice && v.Type().Elem().Kind() == reflect.Uint8:
+			bs, err := base64.StdEncoding.DecodeString(x)
+			if err != nil {
+				return err
+			}
+			v.SetBytes(bs)
+		default:
+			d.mismatch(TypeName(x), v.Type(), field)
+		}
+	case []interface{}:
+		return d.decodeArray(x, v, field)
+	case map[string]interface{}:
+		return d.decodeObject(x, v, field)
+	default:
+		return fmt.Errorf("util: illegal value: %T", x)
+	}
+
+	return nil
+}
+
+func (d *decoder) decodeNumber(s string, x interface{}, v reflect.Value, field string) error {
+	switch v.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, err := strconv.ParseInt(s, 10, 64)
+		if err != nil || v.OverflowInt(n) {
+			d.mismatch("number "+s, v.Type(), field)
+			return nil
+		}
+		v.SetInt(n)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		n, err := strconv.ParseUint(s, 10, 64)
+		if err != nil || v.OverflowUint(n) {
+			d.mismatch("number "+s, v.Type(), field)
+			return nil
+		}
+		v.SetUint(n)
+	case reflect.Float32, reflect.Float64:
+		n, err := strconv.ParseFloat(s, v.Type().Bits())
+		if err != nil || v.OverflowFloat(n) {
+			d.mismatch("number "+s, v.Type(), field)
+			return nil
+		}
+		v.SetFloat(n)
+	case reflect.String:
+		if v.Type() != numberType {
+			d.mismatch(TypeName(x), v.Type(), field)
+			return nil
+		}
+		v.SetString(s)
+	default:
+		d.mismatch(TypeName(x), v.Type(), field)
+	}
+	return nil
+}
+
+func (d *decoder) decodeArray(x []interface{}, v reflect.Value, field string) error {
+	switch v.Kind() {
+	case reflect.Slice:
+		s := reflect.MakeSlice(v.Type(), len(x), len(x))
+		for i := range x {
+			if err := d.decode(x[i], s.Index(i), field); err != nil {
+				return err
+			}
+		}
+		v.Set(s)
+	case reflect.Array:
+		for i := 0; i < v.Len(); i++ {
+			if i < len(x) {
+				if err := d.decode(x[i], v.Index(i), field); err != nil {
+					return err
+				}
+			} else {
+				v.Index(i).Set(reflect.Zero(v.Type().Elem()))
+			}
+		}
+	default:
+		d.mismatch(TypeName(x), v.Type(), field)
+	}
+	return nil
+}
+
+func (d *decoder) decodeObject(x map[string]interface{}, v reflect.Value, field string) error {
+	switch v.Kind() {
+	case reflect.Map:
+		return d.decodeMap(x, v, field)
+	case reflect.Struct:
+		return d.decodeStruct(x, v, field)
+	default:
+		d.mismatch(TypeName(x), v.Type(), field)
+	}
+	return nil
+}
+
+func (d *decoder) decodeMap(x map[string]interface{}, v reflect.Value, field string) error {
+	t := v.Type()
+	kt := t.Key()
+
+	switch kt.Kind() {
+	case reflect.String,
+		reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+	default:
+		if !reflect.PtrTo(kt).Implements(textUnmarshalerType) {
+			d.mismatch(TypeName(x), t, field)
+			return nil
+		}
+	}
+
+	if v.IsNil() {
+		v.Set(reflect.MakeMapWithSize(t, len(x)))
+	}
+
+	for _, k := range sortedKeys(x) {
+		e := x[k]
+		key := reflect.New(kt).Elem()
+
+		if u, ok := key.Addr().Interface().(encoding.TextUnmarshaler); ok && kt.Kind() != reflect.String {
+			if err := u.UnmarshalText([]byte(k)); err != nil {
+				return err
+			}
+		} else {
+			switch kt.Kind() {
+			case reflect.String:
+				key.SetString(k)
+			case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+				n, err := strconv.ParseInt(k, 10, 64)
+				if err != nil || key.OverflowInt(n) {
+					d.mismatch("number "+k, kt, field)
+					continue
+				}
+				key.SetInt(n)
+			default:
+				n, err := strconv.ParseUint(k, 10, 64)
+				if err != nil || key.OverflowUint(n) {
+					d.mismatch("number "+k, kt, field)
+					continue
+				}
+				key.SetUint(n)
+			}
+		}
+
+		elem := reflect.New(t.Elem()).Elem()
+		if err := d.decode(e, elem, field); err != nil {
+			return err
+		}
+
+		v.SetMapIndex(key, elem)
+	}
+
+	return nil
+}
+
+func (d *decoder) decodeStruct(x map[string]interface{}, v reflect.Value, field string) error {
+	fields := jsonfield.Fields(v.Type())
+
+	if field == "" {
+		d.root = v.Type().Name()
+		if d.root == "" {
+			d.root = v.Type().String()
+		}
+	}
+
+	// Keys that match fields case-insensitively are decoded before exact
+	// matches so that exact matches take precedence when both are present.
+	keys := sortedKeys(x)
+	sort.SliceStable(keys, func(i, j int) bool {
+		return !isExactField(fields, keys[i]) && isExactField(fields, keys[j])
+	})
+
+	for _, k := range keys {
+		e := x[k]
+		f, ok := lookupField(fields, k)
+		if !ok {
+			continue
+		}
+
+		fv, ok := f.Alloc(v)
+		if !ok {
+			continue
+		}
+
+		path := f.Name
+		if field != "" {
+			path = field + "." + f.Name
+		}
+
+		// Fields with the string option contain JSON values encoded as
+		// strings, e.g., "1" for an int or "\"x\"" for a string.
+		if s, ok := e.(string); ok && f.AsString {
+			if err := UnmarshalJSON([]byte(s), &e); err != nil {
+				return fmt.Errorf("json: invalid use of ,string struct tag, trying to unmarshal %q into %v", s, fv.Type())
+			}
+		}
+
+		if err := d.decode(e, fv, path); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func sortedKeys(x map[string]interface{}) []string {
+	keys := make([]string, 0, len(x))
+	for k := range x {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func isExactField(fields []jsonfield.Field, key string) bool {
+	for _, f := range fields {
+		if f.Name == key {
+			return true
+		}
+	}
+	return false
+}
+
+// lookupField returns the field for the key. Exact matches are preferred over
+// case-insensitive matches.
+func lookupField(fields []jsonfield.Field, key string) (jsonfield.Field, bool) {
+	for _, f := range fields {
+		if f.Name == key {
+			return f, true
+		}
+	}
+	for _, f := range fields {
+		if strings.EqualFold(f.Name, key) {
+			return f, true
+		}
+	}
+	return jsonfield.Field{}, false
+}
+
+// unmarshaler returns the json.Unmarshaler implemented by v (or a pointer to
+// v) if one exists.
+func unmarshaler(v reflect.Value) (json.Unmarshaler, bool) {
+	if v.Kind() != reflect.Ptr && v.CanAddr() && v.Addr().Type().Implements(jsonUnmarshalerType) {
+		return v.Addr().Interface().(json.Unmarshaler), true
+	}
+	if v.Kind() == reflect.Ptr && v.Type().Implements(jsonUnmarshalerType) {
+		if v.IsNil() {
+			v.Set(reflect.New(v.Type().Elem()))
+		}
+		return v.Interface().(json.Unmarshaler), true
+	}
+	return nil, false
+}
+
+func textUnmarshaler(v reflect.Value) (encoding.TextUnmarshaler, bool) {
+	if v.Kind() != reflect.Ptr && v.CanAddr() && v.Addr().Type().Implements(textUnmarshalerType) {
+		return v.Addr().Interface().(encoding.TextUnmarshaler), true
+	}
+	if v.Kind() == reflect.Ptr && v.Type().Implements(textUnmarshalerType) {
+		if v.IsNil() {
+			v.Set(reflect.New(v.Type().Elem()))
+		}
+		return v.Interface().(encoding.TextUnmarshaler), true
+	}
+	return nil, false
+}
@@ -0,0 +1,190 @@
+// Copyright 2020 The OPA Authors.  All rights reserved.
+// Use of this source code is governed by an Apache2
+// license that can be found in the LICENSE file.
+
+package util
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+type decodeTestLevel int
+
+func (l *decodeTestLevel) UnmarshalText(bs []byte) error {
+	switch string(bs) {
+	case "low":
+		*l = 1
+	case "high":
+		*l = 2
+	}
+	return nil
+}
+
+type decodeTestBase struct {
+	ID   string `json:"id"`
+	Kind string
+}
+
+type decodeTestDoc struct {
+	decodeTestBase
+	Name     string                     `json:"name"`
+	Count    int                        `json:"count,string"`
+	Ratio    float32                    `json:"ratio"`
+	Big      uint64                     `json:"big"`
+	Tags     []string                   `json:"tags"`
+	Pair     [2]int                     `json:"pair"`
+	Labels   map[string]string          `json:"labels"`
+	Scores   map[int]bool               `json:"scores"`
+	Levels   map[decodeTestLevel]string `json:"levels"`
+	Level    decodeTestLevel            `json:"level"`
+	Payload  []byte                     `json:"payload"`
+	Child    *decodeTestDoc             `json:"child"`
+	Any      interface{}                `json:"any"`
+	Number   json.Number                `json:"number"`
+	Time     time.Time                  `json:"time"`
+	Raw      json.RawMessage            `json:"raw"`
+	Nullable *string                    `json:"nullable"`
+	Skipped  string                     `json:"-"`
+}
+
+func TestDecode(t *testing.T) {
+
+	input := `{
+		"id": "x",
+		"kind": "case-insensitive",
+		"name": "test",
+		"count": "12",
+		"ratio": 0.5,
+		"big": 18446744073709551615,
+		"tags": ["a", "b"],
+		"pair": [1, 2, 3],
+		"labels": {"a": "b"},
+		"scores": {"1": true},
+		"levels": {"high": "h"},
+		"level": "low",
+		"payload": "aGVsbG8=",
+		"child": {"name": "child", "tags": []},
+		"any": {"x": [1, 2.5, null]},
+		"number": 1e3,
+		"time": "2020-01-02T03:04:05Z",
+		"raw": {"y": true},
+		"nullable": null,
+		"Skipped": "skipped",
+		"extra": "ignored"
+	}`
+
+	var x interface{}
+	if err := UnmarshalJSON([]byte(input), &x); err != nil {
+		t.Fatal(err)
+	}
+
+	var exp decodeTestDoc
+	decoder := json.NewDecoder(strings.NewReader(input))
+	decoder.UseNumber()
+	if err := decoder.Decode(&exp); err != nil {
+		t.Fatal(err)
+	}
+
+	var result decodeTestDoc
+	if err := Decode(x, &result); err != nil {
+		t.Fatal(err)
+	}
+
+	// Raw messages are re-encoded so only compare their values.
+	if Compare(MustUnmarshalJSON(result.Raw), MustUnmarshalJSON(exp.Raw)) != 0 {
+		t.Fatalf("Expected raw %s but got %s", exp.Raw, result.Raw)
+	}
+
+	result.Raw, exp.Raw = nil, nil
+
+	if !reflect.DeepEqual(result, exp) {
+		t.Fatalf("Expected:\n\n%+v\n\nGot:\n\n%+v", exp, result)
+	}
+
+	// The decoded value must not share the maps and slices of x.
+	result.Any.(map[string]interface{})["x"].([]interface{})[0] = "changed"
+
+	if x.(map[string]interface{})["any"].(map[string]interface{})["x"].([]interface{})[0] != json.Number("1") {
+		t.Fatal("Expected input to be unmodified")
+	}
+}
+
+type decodeCounter struct {
+	Count int
+}
+
+type decodeNested struct {
+	A struct{ B int }
+}
+
+func TestDecodeErrors(t *testing.T) {
+
+	tests := []struct {
+		note  string
+		input string
+		dest  interface{}
+		err   string
+	}{
+		{"type mismatch", `{"count": true}`, &decodeCounter{}, "json: cannot unmarshal boolean into Go struct field decodeCounter.Count of type int"},
+		{"type mismatch unnamed", `{"count": true}`, &struct{ Count int }{}, "json: cannot unmarshal boolean into Go struct field struct { Count int }.Count of type int"},
+		{"overflow", `300`, new(int8), "json: cannot unmarshal number 300 into Go value of type int8"},
+		{"fraction", `1.5`, new(int), "json: cannot unmarshal number 1.5 into Go value of type int"},
+		{"array into struct", `[1]`, &struct{}{}, "json: cannot unmarshal array into Go value of type struct {}"},
+		{"nested field", `{"a": {"b": "x"}}`, &decodeNested{}, "Go struct field decodeNested.A.B of type int"},
+		{"slice element field", `[{"count": "x"}]`, &[]decodeCounter{}, "Go struct field decodeCounter.Count of type int"},
+		{"not a pointer", `1`, 1, "json: Unmarshal(non-pointer int)"},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.note, func(t *testing.T) {
+			err := Decode(MustUnmarshalJSON([]byte(tc.input)), tc.dest)
+			if err == nil || !strings.Contains(err.Error(), tc.err) {
+				t.Fatalf("Expected error containing %q but got: %v", tc.err, err)
+			}
+		})
+	}
+}
+
+func TestDecodeDeterministic(t *testing.T) {
+
+	x := MustUnmarshalJSON([]byte(`{"d": "x", "c": "x", "b": "x", "a": "x", "NAME": "x", "name": "y", "Name": "z"}`))
+
+	for i := 0; i < 20; i++ {
+		var result struct {
+			A, B, C, D int
+			Name       string
+		}
+
+		err := Decode(x, &result)
+
+		if e, ok := err.(*json.UnmarshalTypeError); !ok || e.Field != "A" {
+			t.Fatalf("Expected type error for first key but got: %v", err)
+		}
+
+		if result.Name != "z" {
+			t.Fatalf("Expected exact match to take precedence but got: %v", result.Name)
+		}
+	}
+}
+
+func TestDecodeContinuesAfterTypeError(t *testing.T) {
+
+	var result struct {
+		A int
+		B string
+	}
+
+	err := Decode(MustUnmarshalJSON([]byte(`{"A": "x", "B": "y"}`)), &result)
+
+	if _, ok := err.(*json.UnmarshalTypeError); !ok {
+		t.Fatalf("Expected type error but got: %v", err)
+	}
+
+	if result.B != "y" {
+		t.Fatalf("Expected B to be decoded but got: %+v", result)
+	}
+}
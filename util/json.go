@@ -7,6 +7,7 @@ package util
 import (
 	"bytes"
 	"encoding/json"
+	"fmt"
 	"io"
 	"reflect"
 
@@ -34,6 +35,63 @@ func NewJSONDecoder(r io.Reader) *json.Decoder {
 	return decoder
 }
 
+// UnmarshalJSONIncremental parses a single JSON value from r and returns the
+// result. Unlike json.Decoder, the value is built token by token so the
+// encoded value is never buffered in memory. This makes it suitable for very
+// large values at the cost of slower decoding. Numbers are decoded as
+// json.Number.
+func UnmarshalJSONIncremental(r io.Reader) (interface{}, error) {
+	return decodeIncremental(NewJSONDecoder(r))
+}
+
+func decodeIncremental(decoder *json.Decoder) (interface{}, error) {
+
+	tok, err := decoder.Token()
+	if err != nil {
+		return nil, err
+	}
+
+	delim, ok := tok.(json.Delim)
+	if !ok {
+		return tok, nil
+	}
+
+	switch delim {
+	case '{':
+		obj := map[string]interface{}{}
+		for decoder.More() {
+			key, err := decoder.Token()
+			if err != nil {
+				return nil, err
+			}
+			value, err := decodeIncremental(decoder)
+			if err != nil {
+				return nil, err
+			}
+			obj[key.(string)] = value
+		}
+		if _, err := decoder.Token(); err != nil {
+			return nil, err
+		}
+		return obj, nil
+	case '[':
+		arr := []interface{}{}
+		for decoder.More() {
+			value, err := decodeIncremental(decoder)
+			if err != nil {
+				return nil, err
+			}
+			arr = append(arr, value)
+		}
+		if _, err := decoder.Token(); err != nil {
+			return nil, err
+		}
+		return arr, nil
+	}
+
+	return nil, fmt.Errorf("unexpected delimiter %v", delim)
+}
+
 // MustUnmarshalJSON parse the JSON encoded data and returns the result.
 //
 // If the data cannot be decoded, this function will panic. This function is for
@@ -97,3 +155,43 @@ func Unmarshal(bs []byte, v interface{}) error {
 	}
 	return UnmarshalJSON(bs, v)
 }
+
+// DeepCopy returns a copy of the JSON value x that does not share any maps or
+// slices with x.
+func DeepCopy(x interface{}) interface{} {
+	switch x := x.(type) {
+	case []interface{}:
+		cpy := make([]interface{}, len(x))
+		for i := range x {
+			cpy[i] = DeepCopy(x[i])
+		}
+		return cpy
+	case map[string]interface{}:
+		cpy := make(map[string]interface{}, len(x))
+		for k, v := range x {
+			cpy[k] = DeepCopy(v)
+		}
+		return cpy
+	}
+	return x
+}
+
+// TypeName returns the language-level type name of the JSON value x, e.g.,
+// "boolean" or "object".
+func TypeName(x interface{}) string {
+	switch x.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "boolean"
+	case json.Number, float64, float32, int, int64, int32, uint, uint64, uint32:
+		return "number"
+	case string:
+		return "string"
+	case []interface{}:
+		return "array"
+	case map[string]interface{}:
+		return "object"
+	}
+	return fmt.Sprintf("%T", x)
+}
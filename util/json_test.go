@@ -8,6 +8,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"reflect"
+	"strings"
 	"testing"
 
 	"github.com/open-policy-agent/opa/util"
@@ -98,3 +99,69 @@ func TestReference(t *testing.T) {
 		})
 	}
 }
+
+func TestUnmarshalJSONIncremental(t *testing.T) {
+
+	tests := []string{
+		`null`,
+		`true`,
+		`"foo"`,
+		`1.5e10`,
+		`[]`,
+		`{}`,
+		`{"a": [1, {"b": null}, "c"], "d": {"e": {"f": [[]]}}, "a": false}`,
+	}
+
+	for _, tc := range tests {
+		var exp interface{}
+		if err := util.UnmarshalJSON([]byte(tc), &exp); err != nil {
+			t.Fatal(err)
+		}
+		result, err := util.UnmarshalJSONIncremental(strings.NewReader(tc))
+		if err != nil {
+			t.Fatalf("%v: unexpected error: %v", tc, err)
+		} else if !reflect.DeepEqual(result, exp) {
+			t.Fatalf("%v: expected %v but got %v", tc, exp, result)
+		}
+	}
+
+	for _, tc := range []string{``, `{"a": 1`, `[1,]`, `{"a" 1}`} {
+		if _, err := util.UnmarshalJSONIncremental(strings.NewReader(tc)); err == nil {
+			t.Fatalf("%v: expected error", tc)
+		}
+	}
+}
+
+func TestTypeName(t *testing.T) {
+
+	tests := map[string]string{
+		`null`:       "null",
+		`true`:       "boolean",
+		`1.5`:        "number",
+		`"foo"`:      "string",
+		`[1]`:        "array",
+		`{"a": "b"}`: "object",
+	}
+
+	for tc, exp := range tests {
+		if result := util.TypeName(util.MustUnmarshalJSON([]byte(tc))); result != exp {
+			t.Errorf("%v: expected %v but got %v", tc, exp, result)
+		}
+	}
+}
+
+func TestDeepCopy(t *testing.T) {
+
+	orig := util.MustUnmarshalJSON([]byte(`{"a": [1, {"b": "c"}], "d": true}`))
+	exp := util.MustUnmarshalJSON([]byte(`{"a": [1, {"b": "c"}], "d": true}`))
+
+	cpy := util.DeepCopy(orig).(map[string]interface{})
+
+	cpy["d"] = false
+	cpy["a"].([]interface{})[0] = 2
+	cpy["a"].([]interface{})[1].(map[string]interface{})["b"] = "modified"
+
+	if !reflect.DeepEqual(orig, exp) {
+		t.Fatalf("Expected %v but got %v", exp, orig)
+	}
+}
@@ -0,0 +1,230 @@
+// Copyright 2019 The OPA Authors.  All rights reserved.
+// Use of this source code is governed by an Apache2
+// license that can be found in the LICENSE file.
+
+package watch
+
+import (
+	"context"
+	"sort"
+	"sync"
+
+	"github.com/open-policy-agent/opa/ast"
+	"github.com/open-policy-agent/opa/dependencies"
+	"github.com/open-policy-agent/opa/metrics"
+	"github.com/open-policy-agent/opa/storage"
+	"github.com/open-policy-agent/opa/topdown"
+)
+
+const (
+	counterVirtualDocReuse = "watch_virtual_doc_reuse"
+	counterVirtualDocEval  = "watch_virtual_doc_eval"
+)
+
+// view maintains the values of the virtual documents that a watched query
+// depends on. When data changes, only the virtual documents whose base
+// dependencies overlap the changed paths are invalidated. On re-evaluation,
+// the values of the remaining documents are supplied to the evaluation engine
+// with the "with" keyword so that their rules are not evaluated again.
+type view struct {
+	docs []*viewDoc // ordered so that documents follow their dependencies
+	mtx  sync.Mutex
+}
+
+type viewDoc struct {
+	path    ast.Ref
+	base    []ast.Ref // base documents this document depends on (transitively)
+	virtual []ast.Ref // virtual documents this document depends on (transitively)
+	value   *ast.Term // nil if the document is undefined or has not been evaluated
+	dirty   bool
+}
+
+func newView(compiler *ast.Compiler, query ast.Body) (*view, error) {
+
+	refs, err := dependencies.Virtual(compiler, query)
+	if err != nil {
+		return nil, err
+	}
+
+	v := &view{}
+
+	for _, ref := range refs {
+
+		rules := compiler.GetRulesExact(ref)
+
+		// Functions cannot be replaced with the "with" keyword so they are
+		// always evaluated in place.
+		if len(rules) == 0 || len(rules[0].Head.Args) > 0 {
+			continue
+		}
+
+		doc := &viewDoc{path: ref, dirty: true}
+
+		for _, rule := range rules {
+			base, err := dependencies.Base(compiler, rule)
+			if err != nil {
+				return nil, err
+			}
+			virtual, err := dependencies.Virtual(compiler, rule)
+			if err != nil {
+				return nil, err
+			}
+			doc.base = append(doc.base, base...)
+			doc.virtual = append(doc.virtual, virtual...)
+		}
+
+		v.docs = append(v.docs, doc)
+	}
+
+	// Dependencies are transitive so a document always depends on more virtual
+	// documents than any of the documents it depends on.
+	sort.SliceStable(v.docs, func(i, j int) bool {
+		return len(v.docs[i].virtual) < len(v.docs[j].virtual)
+	})
+
+	return v, nil
+}
+
+// invalidate marks the documents that depend on any of the changed paths as
+// dirty. The caller must not hold the view lock.
+func (v *view) invalidate(changed []ast.Ref) {
+	v.mtx.Lock()
+	defer v.mtx.Unlock()
+
+	for _, doc := range v.docs {
+		if !doc.dirty && overlaps(doc.base, changed) {
+			doc.dirty = true
+		}
+	}
+}
+
+// refresh re-evaluates the dirty documents in dependency order. Errors are
+// not reported here; the affected documents are left undefined so that the
+// error is surfaced when the query itself is evaluated.
+func (v *view) refresh(ctx context.Context, compiler *ast.Compiler, store storage.Store, txn storage.Transaction, runtime *ast.Term, m metrics.Metrics) {
+
+	v.mtx.Lock()
+	var stale []*viewDoc
+	for _, doc := range v.docs {
+		if doc.dirty {
+			doc.dirty = false
+			doc.value = nil
+			stale = append(stale, doc)
+		}
+	}
+	v.mtx.Unlock()
+
+	for i := 0; i < len(v.docs)-len(stale); i++ {
+		m.Counter(counterVirtualDocReuse).Incr()
+	}
+
+	for _, doc := range stale {
+		m.Counter(counterVirtualDocEval).Incr()
+		value, err := v.eval(ctx, compiler, store, txn, runtime, doc)
+		if err != nil {
+			continue
+		}
+		v.mtx.Lock()
+		doc.value = value
+		v.mtx.Unlock()
+	}
+}
+
+// eval returns the value of the document or nil if the document is undefined.
+func (v *view) eval(ctx context.Context, compiler *ast.Compiler, store storage.Store, txn storage.Transaction, runtime *ast.Term, doc *viewDoc) (*ast.Term, error) {
+
+	x := ast.VarTerm("x")
+	expr := ast.Equality.Expr(x, ast.NewTerm(doc.path))
+	expr.With = v.withs(doc.virtual)
+
+	compiled, err := compiler.QueryCompiler().Compile(ast.NewBody(expr))
+	if err != nil {
+		return nil, err
+	}
+
+	qrs, err := topdown.NewQuery(compiled).
+		WithCompiler(compiler).
+		WithStore(store).
+		WithTransaction(txn).
+		WithRuntime(runtime).
+		Run(ctx)
+	if err != nil || len(qrs) == 0 {
+		return nil, err
+	}
+
+	return qrs[0][x.Value.(ast.Var)], nil
+}
+
+// rewrite returns a copy of the query where each expression replaces the
+// virtual documents that have up-to-date values. Expressions that already
+// contain with modifiers are not rewritten.
+func (v *view) rewrite(query ast.Body) ast.Body {
+
+	paths := make([]ast.Ref, len(v.docs))
+	for i := range v.docs {
+		paths[i] = v.docs[i].path
+	}
+
+	withs := v.withs(paths)
+	if len(withs) == 0 {
+		return query
+	}
+
+	result := make(ast.Body, len(query))
+
+	for i := range query {
+		result[i] = query[i]
+		if len(query[i].With) == 0 {
+			expr := query[i].Copy()
+			expr.With = withs
+			result[i] = expr
+		}
+	}
+
+	return result
+}
+
+// withs returns with modifiers for the clean documents included in refs.
+func (v *view) withs(refs []ast.Ref) []*ast.With {
+	v.mtx.Lock()
+	defer v.mtx.Unlock()
+
+	var result []*ast.With
+
+	for _, doc := range v.docs {
+		if doc.dirty || doc.value == nil {
+			continue
+		}
+		if !containsRef(refs, doc.path) {
+			continue
+		}
+		result = append(result, &ast.With{
+			Target: ast.NewTerm(doc.path),
+			Value:  doc.value,
+		})
+	}
+
+	return result
+}
+
+func containsRef(refs []ast.Ref, ref ast.Ref) bool {
+	for _, r := range refs {
+		if r.Equal(ref) {
+			return true
+		}
+	}
+	return false
+}
+
+// overlaps returns true if any ref in a is a prefix of any ref in b or vice
+// versa.
+func overlaps(a, b []ast.Ref) bool {
+	for _, x := range a {
+		for _, y := range b {
+			if x.HasPrefix(y) || y.HasPrefix(x) {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -37,9 +37,12 @@ type Watcher struct {
 type Handle struct {
 	C <-chan Event
 
-	instrument bool      // whether this query should be instrumented
-	query      string    // the original query, used for migration
-	runtime    *ast.Term // runtime info to provide to evaluation engine
+	instrument  bool      // whether this query should be instrumented
+	incremental bool      // whether virtual documents should be materialized
+	query       string    // the original query, used for migration
+	parsed      ast.Body  // the parsed query, set when the handle is registered
+	runtime     *ast.Term // runtime info to provide to evaluation engine
+	view        *view     // materialized virtual documents if incremental is set
 
 	out    chan Event // out is the same channel as C, but without directional constraints
 	notify signal     // channel to receive new data change alerts on.
@@ -110,6 +113,16 @@ func (h *Handle) WithInstrumentation(yes bool) *Handle {
 	return h
 }
 
+// WithIncremental enables incremental re-evaluation of the query. The values
+// of the virtual documents the query depends on are kept between evaluations
+// and when data changes, only the documents that depend on the changed paths
+// are re-evaluated. Queries that depend on non-deterministic built-in
+// functions (e.g., time.now_ns) should not enable incremental re-evaluation.
+func (h *Handle) WithIncremental(yes bool) *Handle {
+	h.incremental = yes
+	return h
+}
+
 // WithRuntime sets the runtime data to provide to the evaluation engine.
 func (h *Handle) WithRuntime(term *ast.Term) *Handle {
 	h.runtime = term
@@ -223,6 +236,15 @@ func (w *Watcher) registerHandle(h *Handle) error {
 		panic(err)
 	}
 
+	h.view = nil
+	if h.incremental {
+		h.view, err = newView(w.compiler, compiled)
+		if err != nil {
+			return err
+		}
+	}
+
+	h.parsed = parsed
 	h.watcher = w
 	w.handles[h] = struct{}{}
 	for _, r := range refs {
@@ -251,19 +273,22 @@ func (h *Handle) deliver() {
 			t := topdown.NewBufferTracer()
 
 			h.l.Lock()
-			r := rego.New(
-				rego.Compiler(h.watcher.compiler),
-				rego.Store(h.watcher.store),
-				rego.Query(h.query),
-				rego.Metrics(m),
-				rego.Tracer(t),
-				rego.Instrument(h.instrument),
-				rego.Runtime(h.runtime),
-			)
+			compiler := h.watcher.compiler
+			store := h.watcher.store
 			ctx := h.watcher.ctx
+			view := h.view
+			parsed := h.parsed
 			h.l.Unlock()
 
-			result, err := r.Eval(ctx)
+			var result rego.ResultSet
+			var err error
+
+			if view == nil {
+				result, err = h.eval(ctx, compiler, store, m, t, rego.Query(h.query))
+			} else {
+				result, err = h.evalIncremental(ctx, compiler, store, m, t, view, parsed)
+			}
+
 			h.out <- Event{
 				Query: h.query,
 				Value: result,
@@ -278,6 +303,35 @@ func (h *Handle) deliver() {
 	}
 }
 
+func (h *Handle) eval(ctx context.Context, compiler *ast.Compiler, store storage.Store, m metrics.Metrics, t *topdown.BufferTracer, opts ...func(*rego.Rego)) (rego.ResultSet, error) {
+	r := rego.New(append([]func(*rego.Rego){
+		rego.Compiler(compiler),
+		rego.Store(store),
+		rego.Metrics(m),
+		rego.Tracer(t),
+		rego.Instrument(h.instrument),
+		rego.Runtime(h.runtime),
+	}, opts...)...)
+	return r.Eval(ctx)
+}
+
+// evalIncremental refreshes the invalidated virtual documents and then
+// evaluates the query with the materialized values. All evaluation happens
+// inside of a single read transaction so that the documents are consistent.
+func (h *Handle) evalIncremental(ctx context.Context, compiler *ast.Compiler, store storage.Store, m metrics.Metrics, t *topdown.BufferTracer, view *view, parsed ast.Body) (rego.ResultSet, error) {
+
+	txn, err := store.NewTransaction(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	defer store.Abort(ctx, txn)
+
+	view.refresh(ctx, compiler, store, txn, h.runtime, m)
+
+	return h.eval(ctx, compiler, store, m, t, rego.ParsedQuery(view.rewrite(parsed)), rego.Transaction(txn))
+}
+
 func (w *Watcher) endQuery(h *Handle) {
 	w.l.Lock()
 	defer w.l.Unlock()
@@ -344,11 +398,20 @@ func (w *Watcher) notify(ctx context.Context, txn storage.Transaction, event sto
 	// is not sent, as there would be no reason to (the current changes will
 	// passively be included next time the watch is evaluated).
 
+	//
+	// Writes to a parent of a watched path (e.g., replacing data.a when the watch
+	// depends on data.a.b) also affect the watch.
+
+	changed := make([]ast.Ref, len(event.Data))
+	for i, d := range event.Data {
+		changed[i] = d.Path.Ref(ast.DefaultRootDocument)
+	}
+
 	notifySet := map[signal]struct{}{}
-	for _, d := range event.Data {
-		r := d.Path.Ref(ast.DefaultRootDocument)
+	for _, r := range changed {
 		for path, notifiers := range w.dataWatch {
-			if r.HasPrefix(ast.MustParseRef(path)) {
+			ref := ast.MustParseRef(path)
+			if r.HasPrefix(ref) || ref.HasPrefix(r) {
 				for notify := range notifiers {
 					notifySet[notify] = struct{}{}
 				}
@@ -356,6 +419,12 @@ func (w *Watcher) notify(ctx context.Context, txn storage.Transaction, event sto
 		}
 	}
 
+	for h := range w.handles {
+		if h.view != nil {
+			h.view.invalidate(changed)
+		}
+	}
+
 	for notify := range notifySet {
 		select {
 		case notify <- struct{}{}:
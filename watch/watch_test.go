@@ -11,6 +11,7 @@ import (
 	"fmt"
 	"math/rand"
 	"reflect"
+	"sort"
 	"sync"
 	"testing"
 	"time"
@@ -561,3 +562,100 @@ func randMerge(lists [][]transaction) (xacts []transaction) {
 	}
 	return xacts
 }
+
+func TestWatchIncremental(t *testing.T) {
+	ctx := context.Background()
+	store := inmem.NewFromObject(map[string]interface{}{
+		"users": map[string]interface{}{"alice": true, "bob": true},
+		"roles": map[string]interface{}{"alice": "admin", "bob": "dev"},
+	})
+
+	c := ast.NewCompiler()
+	c.Compile(map[string]*ast.Module{
+		"test": ast.MustParseModule(`package x
+
+		users[u] { data.users[u] }
+		admins[u] { users[u]; data.roles[u] == "admin" }
+		num_users = n { n := count(users) }`),
+	})
+	if c.Failed() {
+		t.Fatalf("compilation failed: %v", c.Errors.Error())
+	}
+
+	txn := storage.NewTransactionOrDie(ctx, store, storage.WriteParams)
+	watcher, err := New(ctx, store, c, txn)
+	if err != nil {
+		t.Fatalf("Failed to create watch: %v", err)
+	}
+	if err := store.Commit(ctx, txn); err != nil {
+		panic(err)
+	}
+
+	h := watcher.NewQuery("x = data.x.admins; y = data.x.num_users").WithIncremental(true)
+	if err := h.Start(); err != nil {
+		t.Fatalf("Unexpected error setting watch: %v", err)
+	}
+	defer h.Stop()
+
+	tests := []struct {
+		note   string
+		path   string
+		value  interface{}
+		admins []interface{}
+		num    json.Number
+		reused int
+		evals  int
+	}{
+		{"initial", "", nil, []interface{}{"alice"}, "2", 0, 3},
+		{"roles changed", "/roles/bob", "admin", []interface{}{"alice", "bob"}, "2", 2, 1},
+		{"users changed", "/users/carol", true, []interface{}{"alice", "bob"}, "3", 0, 3},
+		{"parent replaced", "/roles", map[string]interface{}{}, []interface{}{}, "3", 2, 1},
+	}
+
+	for _, tc := range tests {
+		if tc.path != "" {
+			txn := storage.NewTransactionOrDie(ctx, store, storage.WriteParams)
+			if err := store.Write(ctx, txn, storage.AddOp, storage.MustParsePath(tc.path), tc.value); err != nil {
+				t.Fatalf("%v: unexpected error writing to store: %v", tc.note, err)
+			}
+			if err := store.Commit(ctx, txn); err != nil {
+				t.Fatalf("%v: unexpected error committing to store: %v", tc.note, err)
+			}
+		}
+
+		e := <-h.C
+		if e.Error != nil {
+			t.Fatalf("%v: unexpected error: %v", tc.note, e.Error)
+		}
+
+		// The admins set is serialized in evaluation order, so sort it before
+		// comparing.
+		if len(e.Value) == 1 {
+			if admins, ok := e.Value[0].Bindings["x"].([]interface{}); ok {
+				sort.Slice(admins, func(i, j int) bool {
+					return util.Compare(admins[i], admins[j]) < 0
+				})
+			}
+		}
+
+		exp := rego.Vars{"x": tc.admins, "y": tc.num}
+		if len(e.Value) != 1 || !reflect.DeepEqual(e.Value[0].Bindings, exp) {
+			t.Fatalf("%v: expected %v but got %v", tc.note, exp, e.Value)
+		}
+
+		all := e.Metrics.All()
+		if reused := counterValue(all, counterVirtualDocReuse); reused != tc.reused {
+			t.Errorf("%v: expected %d reused documents but got %d", tc.note, tc.reused, reused)
+		}
+		if evals := counterValue(all, counterVirtualDocEval); evals != tc.evals {
+			t.Errorf("%v: expected %d evaluated documents but got %d", tc.note, tc.evals, evals)
+		}
+	}
+}
+
+func counterValue(all map[string]interface{}, name string) int {
+	if v, ok := all["counter_"+name]; ok {
+		return int(v.(uint64))
+	}
+	return 0
+}